@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"saas-server/models"
+	"saas-server/pkg/seed"
+)
+
+// loadOperation is one kind of request the load test sends, picked in proportion to weight
+type loadOperation struct {
+	name   string
+	weight int
+	run    func(c *loadClient, rng *rand.Rand) (int, error)
+}
+
+// loadClient sends requests to the server as one logged-in user. The session cookie is
+// marked Secure, so it is sent by hand rather than through a cookie jar, which would hold
+// it back on plain http
+type loadClient struct {
+	baseURL, email, password string
+	mindMapID                string
+	nodes                    []models.Node
+	http                     *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// loadStats collects the latency of every request by operation
+type loadStats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+// runLoad sends a mix of reads, edits and optionally generations to one map from many
+// workers at once and reports throughput and latency percentiles per operation
+func runLoad(args []string) {
+	flags := flag.NewFlagSet("load", flag.ExitOnError)
+	baseURL := flags.String("url", "http://localhost:8080", "server to load")
+	email := flags.String("email", "demo@example.com", "user to log in as")
+	password := flags.String("password", "demo-password", "password of the user")
+	mindMapID := flags.String("map", "", "map to edit, one the user can edit (required)")
+	workers := flags.Int("workers", 20, "concurrent simulated users")
+	duration := flags.Duration("duration", time.Minute, "how long to send traffic")
+	generate := flags.Int("generate", 0, "weight of AI generation requests; 0 leaves them out")
+	flags.Parse(args)
+
+	if *mindMapID == "" {
+		fmt.Fprintln(os.Stderr, "-map is required")
+		os.Exit(2)
+	}
+
+	client := &loadClient{
+		baseURL:   strings.TrimRight(*baseURL, "/"),
+		email:     *email,
+		password:  *password,
+		mindMapID: *mindMapID,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+	if err := client.login(); err != nil {
+		log.Fatal("Error logging in:", err)
+	}
+
+	// Load the map once so edits can target its existing nodes
+	var mindMap models.MindMapWithDetails
+	if status, err := client.do(http.MethodGet, "/api/mindmaps/"+client.mindMapID, nil, &mindMap); err != nil {
+		log.Fatalf("Error loading map (%d): %v", status, err)
+	}
+	if len(mindMap.Nodes) == 0 {
+		log.Fatal("The map has no nodes to edit")
+	}
+	client.nodes = mindMap.Nodes
+	log.Printf("Loaded map %q with %d nodes; running %d workers for %s", mindMap.Title, len(mindMap.Nodes), *workers, *duration)
+
+	operations := loadOperations(*generate)
+	totalWeight := 0
+	for _, op := range operations {
+		totalWeight += op.weight
+	}
+
+	stats := &loadStats{latencies: make(map[string][]time.Duration), errors: make(map[string]int)}
+	deadline := time.Now().Add(*duration)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+			for time.Now().Before(deadline) {
+				op := pickOperation(operations, totalWeight, rng)
+				start := time.Now()
+				status, err := op.run(client, rng)
+				stats.record(op.name, time.Since(start), err)
+				if err != nil && status == 0 {
+					log.Printf("[%s] %v", op.name, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats.report(os.Stdout, *duration)
+}
+
+// loadOperations returns the request mix: mostly sync checks, reads and edits, like a map
+// being worked on together, plus AI generations when their weight is above zero
+func loadOperations(generateWeight int) []loadOperation {
+	operations := []loadOperation{
+		{name: "sync_state", weight: 6, run: func(c *loadClient, rng *rand.Rand) (int, error) {
+			return c.do(http.MethodGet, "/api/mindmaps/"+c.mindMapID+"/sync-state", nil, nil)
+		}},
+		{name: "get_map", weight: 1, run: func(c *loadClient, rng *rand.Rand) (int, error) {
+			return c.do(http.MethodGet, "/api/mindmaps/"+c.mindMapID, nil, nil)
+		}},
+		{name: "update_node", weight: 6, run: func(c *loadClient, rng *rand.Rand) (int, error) {
+			node := c.nodes[rng.Intn(len(c.nodes))]
+			return c.do(http.MethodPut, "/api/nodes/"+node.ID, models.NodeUpdateRequest{
+				Content:   seed.Idea(rng),
+				PositionX: node.PositionX,
+				PositionY: node.PositionY,
+			}, nil)
+		}},
+		{name: "create_node", weight: 2, run: func(c *loadClient, rng *rand.Rand) (int, error) {
+			parent := c.nodes[rng.Intn(len(c.nodes))]
+			return c.do(http.MethodPost, "/api/nodes", models.NodeCreateRequest{
+				MindMapID: c.mindMapID,
+				ParentID:  &parent.ID,
+				Content:   seed.Idea(rng),
+				PositionX: parent.PositionX + float64(rng.Intn(200)-100),
+				PositionY: parent.PositionY + 150,
+				NodeType:  "idea",
+			}, nil)
+		}},
+		{name: "move_nodes", weight: 2, run: func(c *loadClient, rng *rand.Rand) (int, error) {
+			var req models.NodeBatchPositionUpdateRequest
+			for i := 0; i < 20; i++ {
+				node := c.nodes[rng.Intn(len(c.nodes))]
+				req.Positions = append(req.Positions, models.NodePositionUpdateRequest{
+					ID:        node.ID,
+					PositionX: node.PositionX + float64(rng.Intn(21)-10),
+					PositionY: node.PositionY + float64(rng.Intn(21)-10),
+				})
+			}
+			return c.do(http.MethodPost, "/api/nodes/positions", req, nil)
+		}},
+	}
+	if generateWeight > 0 {
+		operations = append(operations, loadOperation{name: "generate", weight: generateWeight, run: func(c *loadClient, rng *rand.Rand) (int, error) {
+			node := c.nodes[rng.Intn(len(c.nodes))]
+			return c.do(http.MethodPost, "/api/generate", map[string]interface{}{
+				"mind_map_id": c.mindMapID,
+				"node_id":     node.ID,
+				"topic":       node.Content,
+				"type":        "expand",
+				"count":       3,
+			}, nil)
+		}})
+	}
+	return operations
+}
+
+// pickOperation picks an operation at random in proportion to the weights
+func pickOperation(operations []loadOperation, totalWeight int, rng *rand.Rand) loadOperation {
+	n := rng.Intn(totalWeight)
+	for _, op := range operations {
+		if n < op.weight {
+			return op
+		}
+		n -= op.weight
+	}
+	return operations[len(operations)-1]
+}
+
+// login signs in and keeps the access token from the session cookie
+func (c *loadClient) login() error {
+	body, _ := json.Marshal(map[string]string{"email": c.email, "password": c.password})
+	resp, err := c.http.Post(c.baseURL+"/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "access_token" {
+			c.mu.Lock()
+			c.token = cookie.Value
+			c.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("no access token in the login response")
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response into out when
+// given. An expired session is renewed once. Returns the status code, or 0 if the request
+// could not be sent
+func (c *loadClient) do(method, path string, in, out interface{}) (int, error) {
+	status, err := c.send(method, path, in, out)
+	if status == http.StatusUnauthorized {
+		if err := c.login(); err != nil {
+			return 0, err
+		}
+		status, err = c.send(method, path, in, out)
+	}
+	return status, err
+}
+
+// send makes a single request
+func (c *loadClient) send(method, path string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.mu.Lock()
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: c.token})
+	c.mu.Unlock()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		message, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// record adds a request's latency, counting it as an error if it failed
+func (s *loadStats) record(name string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[name] = append(s.latencies[name], latency)
+	if err != nil {
+		s.errors[name]++
+	}
+}
+
+// report writes a table of requests, errors, throughput and latency percentiles per
+// operation
+func (s *loadStats) report(w io.Writer, duration time.Duration) {
+	names := make([]string, 0, len(s.latencies))
+	for name := range s.latencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "operation\trequests\terrors\treq/s\tp50\tp95\tp99\tmax")
+	for _, name := range names {
+		latencies := s.latencies[name]
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\n",
+			name,
+			len(latencies),
+			s.errors[name],
+			float64(len(latencies))/duration.Seconds(),
+			percentile(latencies, 0.50),
+			percentile(latencies, 0.95),
+			percentile(latencies, 0.99),
+			latencies[len(latencies)-1].Round(time.Millisecond),
+		)
+	}
+	table.Flush()
+}
+
+// percentile returns the latency below which the given share of sorted latencies fall
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i].Round(time.Millisecond)
+}
@@ -0,0 +1,110 @@
+// Command seed fills a database with large generated mind maps and drives concurrent
+// editing and generation traffic against a running server, so performance changes such
+// as new indexes or batch endpoints can be measured against realistic data.
+//
+//	go run ./cmd/seed maps -email demo@example.com -password demo-password -nodes 10000
+//	go run ./cmd/seed load -url http://localhost:8080 -email demo@example.com -password demo-password -map <id>
+//
+// The maps command writes straight to the database configured in .env, creating the user
+// when needed; the load command only talks to the server's HTTP API
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"saas-server/database"
+	"saas-server/pkg/seed"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "maps":
+		seedMaps(os.Args[2:])
+	case "load":
+		runLoad(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+// usage prints the subcommands and exits
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: seed maps|load [flags]; run seed <command> -h for the flags")
+	os.Exit(2)
+}
+
+// seedMaps generates maps and imports them for the given user
+func seedMaps(args []string) {
+	flags := flag.NewFlagSet("maps", flag.ExitOnError)
+	email := flags.String("email", "demo@example.com", "owner of the maps, created if missing")
+	password := flags.String("password", "demo-password", "password for a newly created owner")
+	maps := flags.Int("maps", 1, "number of maps to create")
+	nodes := flags.Int("nodes", 10000, "nodes per map, including the root")
+	fanOut := flags.Int("fan-out", 8, "maximum children per node")
+	title := flags.String("title", "", "map title, a random topic when empty")
+	randomSeed := flags.Int64("seed", 1, "random seed; the same seed generates the same maps")
+	flags.Parse(args)
+
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(); err != nil {
+			log.Printf("Warning: .env file not found, using system environment variables")
+		}
+	}
+
+	db, err := database.New(fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+	))
+	if err != nil {
+		log.Fatal("Error connecting to database:", err)
+	}
+	defer db.Close()
+
+	// Find or create the owner; created owners are verified so they can log in right away
+	user, err := db.GetUserByEmail(*email)
+	if errors.Is(err, sql.ErrNoRows) {
+		hashedPassword, hashErr := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+		if hashErr != nil {
+			log.Fatal("Error hashing password:", hashErr)
+		}
+		user, err = db.CreateUser(*email, string(hashedPassword), "Demo user", true)
+		if err == nil {
+			log.Printf("Created user %s", *email)
+		}
+	}
+	if err != nil {
+		log.Fatal("Error getting user:", err)
+	}
+
+	for i := 0; i < *maps; i++ {
+		rng := rand.New(rand.NewSource(*randomSeed + int64(i)))
+		opts := seed.DefaultOptions(*title, *nodes)
+		opts.MaxChildren = *fanOut
+		doc := seed.Document(rng, opts)
+
+		start := time.Now()
+		mindMap, _, err := db.ImportMindMapDocument(user.ID, doc)
+		if err != nil {
+			log.Fatal("Error importing map:", err)
+		}
+		fmt.Printf("%s\t%q\t%d nodes\t%d edges\t%s\n", mindMap.ID, mindMap.Title, len(doc.Nodes), len(doc.Edges), time.Since(start).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import "saas-server/models"
+
+// CreateAggregateMindMap creates the consolidated map of an aggregation with its root node
+// and a node for each cluster below the root, all within a single transaction so a failure
+// leaves no half-built map. Returns the map, with the cluster nodes in the order given
+func (db *DB) CreateAggregateMindMap(userID string, req models.MindMapCreateRequest, root models.NodeCreateRequest, clusters []models.NodeCreateRequest) (*models.MindMap, []models.Node, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	mindMap, err := createMindMapTx(tx, userID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root.MindMapID = mindMap.ID
+	root.ParentID = nil
+	rootNode, err := insertNodeTx(tx, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Each cluster node is attached to the root with an edge
+	nodes := make([]models.Node, 0, len(clusters))
+	for _, cluster := range clusters {
+		cluster.MindMapID = mindMap.ID
+		cluster.ParentID = &rootNode.ID
+		node, err := insertNodeTx(tx, cluster)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return mindMap, nodes, nil
+}
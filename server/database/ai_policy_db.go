@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// aiPolicyColumns lists the tenant AI policy columns in the order scanAIPolicy expects
+const aiPolicyColumns = `tenant_id, allowed_providers, allowed_regions, allow_inline_keys, updated_at`
+
+// scanAIPolicy scans a tenant AI policy row selected with aiPolicyColumns. NULL lists stay
+// nil so they inherit the server's default
+func scanAIPolicy(row rowScanner) (*models.TenantAIPolicy, error) {
+	var policy models.TenantAIPolicy
+	var inlineKeys sql.NullBool
+	var updatedAt time.Time
+	err := row.Scan(
+		&policy.TenantID,
+		pq.Array(&policy.AllowedProviders),
+		pq.Array(&policy.AllowedRegions),
+		&inlineKeys,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if inlineKeys.Valid {
+		policy.AllowInlineKeys = &inlineKeys.Bool
+	}
+	policy.UpdatedAt = &updatedAt
+	return &policy, nil
+}
+
+// GetAIPolicy retrieves the AI usage policy of a tenant. A tenant without one gets a
+// policy inheriting every setting
+func (db *DB) GetAIPolicy(tenantID string) (*models.TenantAIPolicy, error) {
+	policy, err := scanAIPolicy(db.QueryRow(`
+		SELECT `+aiPolicyColumns+`
+		FROM tenant_ai_policies
+		WHERE tenant_id = $1`, tenantID))
+	if err == sql.ErrNoRows {
+		return &models.TenantAIPolicy{TenantID: tenantID}, nil
+	}
+	return policy, err
+}
+
+// GetUserAIPolicy retrieves the AI usage policy of the tenant a user belongs to
+func (db *DB) GetUserAIPolicy(userID string) (*models.TenantAIPolicy, error) {
+	tenantID, err := db.GetUserTenantID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetAIPolicy(tenantID)
+}
+
+// SetAIPolicy replaces the AI usage policy of a tenant, recording who changed it
+func (db *DB) SetAIPolicy(tenantID, userID string, req models.TenantAIPolicyRequest) (*models.TenantAIPolicy, error) {
+	return scanAIPolicy(db.QueryRow(`
+		INSERT INTO tenant_ai_policies (tenant_id, allowed_providers, allowed_regions, allow_inline_keys, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET allowed_providers = $2, allowed_regions = $3, allow_inline_keys = $4, updated_by = $5
+		RETURNING `+aiPolicyColumns,
+		tenantID, nullStringArray(req.AllowedProviders), nullStringArray(req.AllowedRegions), req.AllowInlineKeys, userID))
+}
+
+// nullStringArray stores a nil list as NULL and any other, even an empty one, as an array
+func nullStringArray(values []string) interface{} {
+	if values == nil {
+		return nil
+	}
+	return pq.Array(values)
+}
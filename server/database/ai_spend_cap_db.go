@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+)
+
+// aiSpendCapColumns lists the AI spend cap columns in the order scanAISpendCap expects
+const aiSpendCapColumns = `mind_map_id, max_cost_usd, max_tokens, session_max_cost_usd, session_max_tokens, on_exceed, updated_at`
+
+// scanAISpendCap scans an AI spend cap row selected with aiSpendCapColumns
+func scanAISpendCap(row rowScanner) (*models.AISpendCap, error) {
+	var spendCap models.AISpendCap
+	var maxCost, sessionMaxCost sql.NullFloat64
+	var maxTokens, sessionMaxTokens sql.NullInt64
+	var updatedAt time.Time
+	if err := row.Scan(&spendCap.MindMapID, &maxCost, &maxTokens, &sessionMaxCost, &sessionMaxTokens, &spendCap.OnExceed, &updatedAt); err != nil {
+		return nil, err
+	}
+	spendCap.MaxCostUSD = nullFloatPtr(maxCost)
+	spendCap.MaxTokens = nullIntPtr(maxTokens)
+	spendCap.SessionMaxCostUSD = nullFloatPtr(sessionMaxCost)
+	spendCap.SessionMaxTokens = nullIntPtr(sessionMaxTokens)
+	spendCap.UpdatedAt = &updatedAt
+	return &spendCap, nil
+}
+
+// nullFloatPtr returns a nullable numeric column as a pointer, nil for NULL
+func nullFloatPtr(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	v := value.Float64
+	return &v
+}
+
+// GetAISpendCap retrieves the AI spend cap of a mind map. A map without one gets a cap
+// with every limit off
+func (db *DB) GetAISpendCap(mindMapID string) (*models.AISpendCap, error) {
+	spendCap, err := scanAISpendCap(db.QueryRow(`
+		SELECT `+aiSpendCapColumns+`
+		FROM ai_spend_caps
+		WHERE mind_map_id = $1`, mindMapID))
+	if err == sql.ErrNoRows {
+		return &models.AISpendCap{MindMapID: mindMapID, OnExceed: models.SpendCapRefuse}, nil
+	}
+	return spendCap, err
+}
+
+// SetAISpendCap replaces the AI spend cap of a mind map, recording who changed it
+func (db *DB) SetAISpendCap(mindMapID, userID string, req models.AISpendCapRequest) (*models.AISpendCap, error) {
+	return scanAISpendCap(db.QueryRow(`
+		INSERT INTO ai_spend_caps (mind_map_id, max_cost_usd, max_tokens, session_max_cost_usd, session_max_tokens, on_exceed, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (mind_map_id) DO UPDATE
+		SET max_cost_usd = $2, max_tokens = $3, session_max_cost_usd = $4, session_max_tokens = $5, on_exceed = $6, updated_by = $7
+		RETURNING `+aiSpendCapColumns,
+		mindMapID, req.MaxCostUSD, req.MaxTokens, req.SessionMaxCostUSD, req.SessionMaxTokens, req.OnExceed, userID))
+}
+
+// DeleteAISpendCap removes the AI spend cap of a mind map, if it has one
+func (db *DB) DeleteAISpendCap(mindMapID string) error {
+	_, err := db.Exec(`DELETE FROM ai_spend_caps WHERE mind_map_id = $1`, mindMapID)
+	return err
+}
+
+// GetMindMapAIUsage sums the AI usage of a mind map since a time by provider and model,
+// whoever made the calls. The buckets have no date. Read from the primary, as it is
+// checked against spend caps right before calls are made
+func (db *DB) GetMindMapAIUsage(mindMapID string, since time.Time) ([]models.AIUsageBucket, error) {
+	rows, err := db.Query(`
+		SELECT provider, model, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0)
+		FROM ai_usage
+		WHERE mind_map_id = $1 AND created_at >= $2
+		GROUP BY provider, model
+		ORDER BY provider, model`, mindMapID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.AIUsageBucket{}
+	for rows.Next() {
+		bucket := models.AIUsageBucket{MindMapID: &mindMapID}
+		if err := rows.Scan(&bucket.Provider, &bucket.Model, &bucket.Requests, &bucket.PromptTokens, &bucket.CompletionTokens); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
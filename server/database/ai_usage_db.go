@@ -0,0 +1,76 @@
+package database
+
+import (
+	"saas-server/models"
+	"time"
+)
+
+// RecordAIUsage stores the usage of one AI call
+func (db *DB) RecordAIUsage(usage *models.AIUsage) error {
+	return db.QueryRow(`
+		INSERT INTO ai_usage (user_id, mind_map_id, feature, provider, model, prompt_tokens, completion_tokens, latency_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING id, created_at`,
+		usage.UserID, usage.MindMapID, usage.Feature, usage.Provider, usage.Model,
+		usage.PromptTokens, usage.CompletionTokens, usage.LatencyMs,
+	).Scan(&usage.ID, &usage.CreatedAt)
+}
+
+// GetAIUsage sums a user's AI usage since a day by day, mind map and model, oldest day
+// first. Served from the replica
+func (db *DB) GetAIUsage(userID string, since time.Time) ([]models.AIUsageBucket, error) {
+	rows, err := db.reader().Query(`
+		SELECT to_char(u.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+			u.mind_map_id, m.title, u.provider, u.model,
+			COUNT(*), COALESCE(SUM(u.prompt_tokens), 0), COALESCE(SUM(u.completion_tokens), 0)
+		FROM ai_usage u
+		LEFT JOIN mind_maps m ON m.id = u.mind_map_id
+		WHERE u.user_id = $1 AND u.created_at >= $2
+		GROUP BY day, u.mind_map_id, m.title, u.provider, u.model
+		ORDER BY day, u.mind_map_id, u.model`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.AIUsageBucket{}
+	for rows.Next() {
+		var bucket models.AIUsageBucket
+		if err := rows.Scan(&bucket.Date, &bucket.MindMapID, &bucket.MindMapTitle, &bucket.Provider, &bucket.Model,
+			&bucket.Requests, &bucket.PromptTokens, &bucket.CompletionTokens); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// GetTenantAIUsage sums the AI usage of a tenant's users from one time up to another by
+// day, user and model, oldest day first. Usage counts towards the tenant its user belongs
+// to now. Served from the replica
+func (db *DB) GetTenantAIUsage(tenantID string, from, to time.Time) ([]models.TenantAIUsageBucket, error) {
+	rows, err := db.reader().Query(`
+		SELECT to_char(a.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+			a.user_id, u.email, a.provider, a.model,
+			COUNT(*), COALESCE(SUM(a.prompt_tokens), 0), COALESCE(SUM(a.completion_tokens), 0)
+		FROM ai_usage a
+		JOIN users u ON u.id = a.user_id
+		WHERE u.tenant_id = $1 AND a.created_at >= $2 AND a.created_at < $3
+		GROUP BY day, a.user_id, u.email, a.provider, a.model
+		ORDER BY day, u.email, a.provider, a.model`, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.TenantAIUsageBucket{}
+	for rows.Next() {
+		var bucket models.TenantAIUsageBucket
+		if err := rows.Scan(&bucket.Date, &bucket.UserID, &bucket.Email, &bucket.Provider, &bucket.Model,
+			&bucket.Requests, &bucket.PromptTokens, &bucket.CompletionTokens); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
@@ -64,11 +64,12 @@ func (db *DB) CreateAPIKey(userID string, req models.APIKeyCreateRequest) (*mode
 // GetAPIKeyByID gets an API key by ID
 func (db *DB) GetAPIKeyByID(id string) (*models.APIKeyResponse, error) {
 	var apiKey models.APIKeyResponse
+	scope, args := db.tenantScope("tenant_id", []interface{}{id})
 	err := db.QueryRow(
 		`SELECT id, user_id, service, is_active, created_at, updated_at
 		FROM api_keys
-		WHERE id = $1`,
-		id,
+		WHERE id = $1`+scope,
+		args...,
 	).Scan(
 		&apiKey.ID,
 		&apiKey.UserID,
@@ -91,11 +92,12 @@ func (db *DB) GetAPIKeyByID(id string) (*models.APIKeyResponse, error) {
 // GetAPIKeyByUserAndService gets an API key by user ID and service
 func (db *DB) GetAPIKeyByUserAndService(userID, service string) (*models.APIKey, error) {
 	var apiKey models.APIKey
+	scope, args := db.tenantScope("tenant_id", []interface{}{userID, service})
 	err := db.QueryRow(
 		`SELECT id, user_id, service, encrypted_key, is_active, created_at, updated_at
 		FROM api_keys
-		WHERE user_id = $1 AND service = $2`,
-		userID, service,
+		WHERE user_id = $1 AND service = $2`+scope,
+		args...,
 	).Scan(
 		&apiKey.ID,
 		&apiKey.UserID,
@@ -118,12 +120,13 @@ func (db *DB) GetAPIKeyByUserAndService(userID, service string) (*models.APIKey,
 
 // GetAPIKeysByUserID gets all API keys for a user
 func (db *DB) GetAPIKeysByUserID(userID string) ([]models.APIKeyResponse, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{userID})
 	rows, err := db.Query(
 		`SELECT id, user_id, service, is_active, created_at, updated_at
 		FROM api_keys
-		WHERE user_id = $1
+		WHERE user_id = $1`+scope+`
 		ORDER BY created_at DESC`,
-		userID,
+		args...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get API keys: %v", err)
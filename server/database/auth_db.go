@@ -190,16 +190,16 @@ func (db *DB) VerifyEmail(token string) error {
 
 	// Now check the token's status and get user info
 	var userID string
-	var expiresAt time.Time
+	var expired bool
 	var usedAt sql.NullTime
 	var emailVerified bool
 	query := `
-		SELECT evt.user_id, evt.expires_at, evt.used_at, u.email_verified
+		SELECT evt.user_id, evt.expires_at <= CURRENT_TIMESTAMP, evt.used_at, u.email_verified
 		FROM email_verification_tokens evt
 		JOIN users u ON u.id = evt.user_id
 		WHERE evt.token = $1
 	`
-	err = tx.QueryRow(query, token).Scan(&userID, &expiresAt, &usedAt, &emailVerified)
+	err = tx.QueryRow(query, token).Scan(&userID, &expired, &usedAt, &emailVerified)
 	if err != nil {
 		log.Printf("[DB] Error querying token details: %v", err)
 		if err == sql.ErrNoRows {
@@ -216,7 +216,7 @@ func (db *DB) VerifyEmail(token string) error {
 		return errors.New("token already used")
 	}
 
-	if time.Now().After(expiresAt) {
+	if expired {
 		return errors.New("token has expired")
 	}
 
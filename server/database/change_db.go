@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"saas-server/models"
+)
+
+// recordChange appends a change to a mind map's change log under the next sequence number
+// and queues it in the outbox for delivery to realtime subscribers. Incrementing the map's
+// counter locks its row, so sequence numbers are gap-free and a change is only visible once
+// every change before it has been committed. Changes to a map that no longer exists skip
+// the log and are queued with sequence number 0. The change is recorded in the transaction
+// of the write it describes, so the two commit or roll back together, and the caller
+// signals the dispatcher after committing
+func recordChange(tx *sql.Tx, mindMapID, eventType string, payload interface{}) (*models.MindMapChange, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH next AS (
+			UPDATE mind_maps SET change_seq = change_seq + 1
+			WHERE id = $1
+			RETURNING change_seq
+		), logged AS (
+			INSERT INTO mind_map_changes (mind_map_id, seq, event_type, payload, created_at)
+			SELECT $1, change_seq, $2, $3, NOW() FROM next
+			RETURNING seq
+		)
+		INSERT INTO outbox_events (mind_map_id, seq, event_type, payload, created_at)
+		SELECT $1, COALESCE((SELECT seq FROM logged), 0), $2, $3, NOW()
+		RETURNING seq, created_at`
+
+	change := models.MindMapChange{
+		MindMapID: mindMapID,
+		Type:      eventType,
+		Payload:   json.RawMessage(data),
+	}
+	err = tx.QueryRow(query, mindMapID, eventType, data).Scan(&change.Seq, &change.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// GetChangesSince retrieves up to limit changes of a mind map with a sequence number greater than since
+func (db *DB) GetChangesSince(mindMapID string, since int64, limit int) ([]models.MindMapChange, error) {
+	query := `
+		SELECT mind_map_id, seq, event_type, payload, created_at
+		FROM mind_map_changes
+		WHERE mind_map_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3`
+
+	rows, err := db.Query(query, mindMapID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.MindMapChange{}
+	for rows.Next() {
+		var change models.MindMapChange
+		var payload []byte
+		if err := rows.Scan(&change.MindMapID, &change.Seq, &change.Type, &payload, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		change.Payload = json.RawMessage(payload)
+		changes = append(changes, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// GetLatestChangeSeq returns the sequence number of the most recent change to a mind map
+func (db *DB) GetLatestChangeSeq(mindMapID string) (int64, error) {
+	var seq int64
+	err := db.QueryRow(`SELECT change_seq FROM mind_maps WHERE id = $1`, mindMapID).Scan(&seq)
+	return seq, err
+}
+
+// GetSyncState returns the latest change sequence, timestamps and counts of a mind map in
+// a single query. Returns ErrNotFound for a missing or deleted map
+func (db *DB) GetSyncState(mindMapID string) (*models.MindMapSyncState, error) {
+	query := `
+		SELECT m.change_seq, m.updated_at,
+			(SELECT MAX(n.updated_at) FROM nodes n WHERE n.mind_map_id = m.id),
+			(SELECT COUNT(*) FROM nodes n WHERE n.mind_map_id = m.id),
+			(SELECT COUNT(*) FROM edges e WHERE e.mind_map_id = m.id)
+		FROM mind_maps m
+		WHERE m.id = $1 AND m.status != 'deleted'`
+
+	state := models.MindMapSyncState{MindMapID: mindMapID}
+	var nodesUpdatedAt sql.NullTime
+	err := db.QueryRow(query, mindMapID).Scan(
+		&state.LatestSeq,
+		&state.UpdatedAt,
+		&nodesUpdatedAt,
+		&state.NodeCount,
+		&state.EdgeCount,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if nodesUpdatedAt.Valid {
+		state.NodesUpdatedAt = &nodesUpdatedAt.Time
+	}
+	return &state, nil
+}
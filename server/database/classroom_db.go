@@ -0,0 +1,129 @@
+package database
+
+import (
+	"fmt"
+	"saas-server/models"
+	"strings"
+)
+
+// CreateClassroomCopies clones a template map for each participant, shares each copy with
+// its participant and records the link back to the template, all within a single
+// transaction so a failure leaves no partial roster. Participants who already have a copy
+// of the template are skipped, so a roster can be sent again with new emails. Returns the
+// copies created
+func (db *DB) CreateClassroomCopies(templateID, userID string, emails []string) ([]models.ClassroomCopy, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var templateTitle string
+	err = tx.QueryRow(`SELECT title FROM mind_maps WHERE id = $1`, templateID).Scan(&templateTitle)
+	if err != nil {
+		return nil, err
+	}
+
+	copies := make([]models.ClassroomCopy, 0, len(emails))
+	for _, email := range emails {
+		email = strings.ToLower(email)
+
+		var exists bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM classroom_copies WHERE template_id = $1 AND participant_email = $2)`,
+			templateID, email,
+		).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			continue
+		}
+
+		var mindMap *models.MindMap
+		mindMap, err = cloneMindMapTx(tx, templateID, userID, fmt.Sprintf("%s - %s", templateTitle, email))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy template for %s: %v", email, err)
+		}
+
+		if _, err = shareMindMapTx(tx, mindMap.ID, email, "edit"); err != nil {
+			return nil, fmt.Errorf("failed to share copy with %s: %v", email, err)
+		}
+
+		classroomCopy := models.ClassroomCopy{
+			TemplateID:       templateID,
+			MindMapID:        mindMap.ID,
+			ParticipantEmail: email,
+			Title:            mindMap.Title,
+			UpdatedAt:        mindMap.UpdatedAt,
+		}
+		err = tx.QueryRow(`
+			INSERT INTO classroom_copies (template_id, mind_map_id, participant_email, created_at)
+			VALUES ($1, $2, $3, NOW())
+			RETURNING id, created_at`,
+			templateID, mindMap.ID, email,
+		).Scan(&classroomCopy.ID, &classroomCopy.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		// The copy starts with the same nodes as the template
+		err = tx.QueryRow(`SELECT COUNT(*) FROM nodes WHERE mind_map_id = $1`, mindMap.ID).Scan(&classroomCopy.NodeCount)
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, classroomCopy)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return copies, nil
+}
+
+// GetClassroomCopies retrieves all participant copies of a template map with their node counts
+func (db *DB) GetClassroomCopies(templateID string) ([]models.ClassroomCopy, error) {
+	query := `
+		SELECT c.id, c.template_id, c.mind_map_id, c.participant_email, m.title,
+		       (SELECT COUNT(*) FROM nodes n WHERE n.mind_map_id = c.mind_map_id) AS node_count,
+		       c.created_at, m.updated_at
+		FROM classroom_copies c
+		JOIN mind_maps m ON m.id = c.mind_map_id
+		WHERE c.template_id = $1 AND m.status != 'deleted'
+		ORDER BY c.participant_email ASC`
+
+	rows, err := db.Query(query, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var copies []models.ClassroomCopy
+	for rows.Next() {
+		var classroomCopy models.ClassroomCopy
+		err := rows.Scan(
+			&classroomCopy.ID,
+			&classroomCopy.TemplateID,
+			&classroomCopy.MindMapID,
+			&classroomCopy.ParticipantEmail,
+			&classroomCopy.Title,
+			&classroomCopy.NodeCount,
+			&classroomCopy.CreatedAt,
+			&classroomCopy.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, classroomCopy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return copies, nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// GroupNodes creates the group nodes of a mind map and moves each group's nodes, with their
+// subtrees, under it in a single transaction. The edges from the nodes' old parents are
+// replaced with edges from their group. Records a nodes.imported change for the group nodes
+// and a nodes.updated change for the moved nodes in the same transaction. Returns the
+// created group nodes in order
+func (db *DB) GroupNodes(mindMapID string, groups []models.NodeGroup) ([]models.Node, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]models.Node, 0, len(groups))
+	createdIDs := make([]string, 0, len(groups))
+	var movedIDs []string
+	for _, group := range groups {
+		group.Group.MindMapID = mindMapID
+		node, err := insertNodeTx(tx, group.Group)
+		if err != nil {
+			return nil, err
+		}
+		nodeIDs := pq.Array(group.NodeIDs)
+
+		statements := []struct {
+			query string
+			args  []interface{}
+		}{
+			{`DELETE FROM edges e
+			  USING nodes n
+			  WHERE n.id = ANY($2) AND n.mind_map_id = $1
+			  AND e.mind_map_id = $1 AND e.source_id = n.parent_id AND e.target_id = n.id`, []interface{}{mindMapID, nodeIDs}},
+			{`UPDATE nodes SET parent_id = $3, updated_at = NOW() WHERE id = ANY($2) AND mind_map_id = $1`, []interface{}{mindMapID, nodeIDs, node.ID}},
+			{`INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
+			  SELECT gen_random_uuid(), $1, $3, id, 'default', '{}', NOW()
+			  FROM nodes WHERE id = ANY($2) AND mind_map_id = $1`, []interface{}{mindMapID, nodeIDs, node.ID}},
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+				return nil, err
+			}
+		}
+		created = append(created, *node)
+		createdIDs = append(createdIDs, node.ID)
+		movedIDs = append(movedIDs, group.NodeIDs...)
+	}
+
+	if _, err := recordChange(tx, mindMapID, "nodes.imported", map[string][]string{"node_ids": createdIDs}); err != nil {
+		return nil, err
+	}
+	if _, err := recordChange(tx, mindMapID, "nodes.updated", map[string][]string{"node_ids": movedIDs}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return created, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"encoding/json"
+	"saas-server/models"
+)
+
+// GetMindMapCustomFields retrieves the custom field schema of a mind map
+func (db *DB) GetMindMapCustomFields(mindMapID string) ([]models.CustomField, error) {
+	var data []byte
+	if err := db.QueryRow(`SELECT custom_fields FROM mind_maps WHERE id = $1`, mindMapID).Scan(&data); err != nil {
+		return nil, err
+	}
+
+	fields := []models.CustomField{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// SetMindMapCustomFields replaces the custom field schema of a mind map, recording a
+// mind_map.fields_updated change in the same transaction
+func (db *DB) SetMindMapCustomFields(mindMapID string, fields []models.CustomField) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE mind_maps SET custom_fields = $2, updated_at = NOW() WHERE id = $1`, mindMapID, data)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	change := models.CustomFieldsResponse{MindMapID: mindMapID, Fields: fields}
+	if _, err := recordChange(tx, mindMapID, "mind_map.fields_updated", change); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
+	return nil
+}
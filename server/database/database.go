@@ -3,10 +3,11 @@ package database
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // ErrNotFound is returned when a requested resource is not found
@@ -15,14 +16,27 @@ var ErrNotFound = errors.New("resource not found")
 // DB wraps the sql.DB connection and provides database operations
 type DB struct {
 	*sql.DB
+	replica  *sql.DB       // Optional read replica, see AttachReplica
+	changes  chan struct{} // Signals committed changes to the outbox dispatcher
+	tenantID string        // Tenant reads are scoped to, see ForTenant
 }
 
-// New creates a new database connection and verifies it with a ping
+// New creates a new database connection and verifies it with a ping. Queries slower
+// than DB_SLOW_QUERY_MS are logged and statements running past DB_STATEMENT_TIMEOUT_MS
+// are cancelled
 func New(dataSourceName string) (*DB, error) {
-	db, err := sql.Open("postgres", dataSourceName)
+	connector, err := pq.NewConnector(dataSourceName)
 	if err != nil {
 		return nil, err
 	}
+	var base driver.Connector = connector
+	if timeout := statementTimeout(); timeout > 0 {
+		base = &statementTimeoutConnector{Connector: base, timeout: timeout}
+	}
+	if threshold := slowQueryThreshold(); threshold > 0 {
+		base = &slowQueryConnector{Connector: base, threshold: threshold}
+	}
+	db := sql.OpenDB(base)
 
 	// Configure connection pool
 	db.SetMaxOpenConns(25)                 // Maximum number of open connections to the database
@@ -33,6 +47,6 @@ func New(dataSourceName string) (*DB, error) {
 	if err = db.Ping(); err != nil {
 		return nil, err
 	}
-	return &DB{db}, nil
+	return &DB{DB: db, changes: make(chan struct{}, 1)}, nil
 }
 
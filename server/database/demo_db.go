@@ -0,0 +1,195 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"saas-server/models"
+	"time"
+)
+
+// ErrDemoLimitReached is returned when a demo map has as many nodes as it may, or a
+// client as many demo maps
+var ErrDemoLimitReached = errors.New("demo limit reached")
+
+// demoNodeColumns lists the demo node columns in the order scanDemoNode expects
+const demoNodeColumns = `id, parent_id, content, position_x, position_y, created_at`
+
+// scanDemoNode scans a demo node row selected with demoNodeColumns
+func scanDemoNode(row rowScanner) (*models.DemoNode, error) {
+	var node models.DemoNode
+	var parentID sql.NullString
+	err := row.Scan(&node.ID, &parentID, &node.Content, &node.PositionX, &node.PositionY, &node.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		node.ParentID = &parentID.String
+	}
+	return &node, nil
+}
+
+// CreateDemoMap creates a demo map expiring after ttl with a root node showing its title,
+// unless the client already has maxPerClient demo maps that have not expired, in which
+// case ErrDemoLimitReached is returned
+func (db *DB) CreateDemoMap(tokenHash, title, clientIP string, ttl time.Duration, maxPerClient int) (*models.DemoMap, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialize concurrent maps of the same client so the limit cannot be exceeded
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('demo:' || $1))`, clientIP); err != nil {
+		return nil, err
+	}
+	var active int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM demo_maps
+		WHERE client_ip = $1 AND expires_at > NOW()`, clientIP).Scan(&active); err != nil {
+		return nil, err
+	}
+	if active >= maxPerClient {
+		return nil, ErrDemoLimitReached
+	}
+
+	var demoMap models.DemoMap
+	err = tx.QueryRow(`
+		INSERT INTO demo_maps (token_hash, title, client_ip, created_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), NOW() + $4 * INTERVAL '1 second')
+		RETURNING id, title, created_at, expires_at`,
+		tokenHash, title, clientIP, int(ttl.Seconds()),
+	).Scan(&demoMap.ID, &demoMap.Title, &demoMap.CreatedAt, &demoMap.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	root, err := scanDemoNode(tx.QueryRow(`
+		INSERT INTO demo_nodes (demo_map_id, content, created_at)
+		VALUES ($1, $2, NOW())
+		RETURNING `+demoNodeColumns, demoMap.ID, title))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	demoMap.Nodes = []models.DemoNode{*root}
+	return &demoMap, nil
+}
+
+// GetDemoMap retrieves a demo map that has not expired with its nodes, the root first and
+// the others oldest first, by its ID and the hash of its token. Returns ErrNotFound if there is no such map
+func (db *DB) GetDemoMap(id, tokenHash string) (*models.DemoMap, error) {
+	var demoMap models.DemoMap
+	err := db.QueryRow(`
+		SELECT id, title, created_at, expires_at
+		FROM demo_maps
+		WHERE id = $1 AND token_hash = $2 AND expires_at > NOW()`, id, tokenHash,
+	).Scan(&demoMap.ID, &demoMap.Title, &demoMap.CreatedAt, &demoMap.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT `+demoNodeColumns+`
+		FROM demo_nodes
+		WHERE demo_map_id = $1
+		ORDER BY parent_id IS NOT NULL, created_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	demoMap.Nodes = []models.DemoNode{}
+	for rows.Next() {
+		node, err := scanDemoNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		demoMap.Nodes = append(demoMap.Nodes, *node)
+	}
+	return &demoMap, rows.Err()
+}
+
+// CreateDemoNodes adds nodes to a demo map under parents of the same map, unless the map
+// would then have more than maxNodes nodes, in which case none are added and
+// ErrDemoLimitReached is returned. Returns ErrNotFound if a parent is not a node of the map
+func (db *DB) CreateDemoNodes(demoMapID string, reqs []models.DemoNodeCreateRequest, maxNodes int) ([]models.DemoNode, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the map so concurrent additions cannot exceed the limit together
+	var count int
+	if err := tx.QueryRow(`SELECT id FROM demo_maps WHERE id = $1 FOR UPDATE`, demoMapID).Scan(new(string)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM demo_nodes WHERE demo_map_id = $1`, demoMapID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count+len(reqs) > maxNodes {
+		return nil, ErrDemoLimitReached
+	}
+
+	nodes := make([]models.DemoNode, 0, len(reqs))
+	for _, req := range reqs {
+		node, err := scanDemoNode(tx.QueryRow(`
+			INSERT INTO demo_nodes (demo_map_id, parent_id, content, position_x, position_y, created_at)
+			SELECT $1, id, $3, $4, $5, NOW()
+			FROM demo_nodes
+			WHERE id = $2 AND demo_map_id = $1
+			RETURNING `+demoNodeColumns,
+			demoMapID, req.ParentID, req.Content, req.PositionX, req.PositionY))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// DeleteDemoNode removes a node other than the root from a demo map, along with the
+// nodes under it. Returns ErrNotFound if the map has no such node
+func (db *DB) DeleteDemoNode(demoMapID, nodeID string) error {
+	result, err := db.Exec(`
+		DELETE FROM demo_nodes
+		WHERE id = $1 AND demo_map_id = $2 AND parent_id IS NOT NULL`, nodeID, demoMapID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteDemoMap removes a demo map and its nodes
+func (db *DB) DeleteDemoMap(id string) error {
+	_, err := db.Exec(`DELETE FROM demo_maps WHERE id = $1`, id)
+	return err
+}
+
+// DeleteExpiredDemoMaps removes the demo maps that have expired, with their nodes,
+// returning how many were removed
+func (db *DB) DeleteExpiredDemoMaps() (int64, error) {
+	result, err := db.Exec(`DELETE FROM demo_maps WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// documentColumns lists the document columns in the order scanDocument expects
+const documentColumns = `id, mind_map_id, user_id, filename, format, size_bytes, chunk_count, embedded, created_at`
+
+// scanDocument scans a document row selected with documentColumns
+func scanDocument(row rowScanner) (*models.Document, error) {
+	var document models.Document
+	var userID sql.NullString
+	err := row.Scan(
+		&document.ID,
+		&document.MindMapID,
+		&userID,
+		&document.Filename,
+		&document.Format,
+		&document.SizeBytes,
+		&document.ChunkCount,
+		&document.Embedded,
+		&document.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		document.UserID = &userID.String
+	}
+	return &document, nil
+}
+
+// CreateDocument stores a document uploaded to a mind map together with its chunks in a
+// single transaction, filling in the ID and creation time of the document
+func (db *DB) CreateDocument(document *models.Document, chunks []models.DocumentChunk) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	created, err := scanDocument(tx.QueryRow(`
+		INSERT INTO mind_map_documents (mind_map_id, user_id, filename, format, size_bytes, chunk_count, embedded, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING `+documentColumns,
+		document.MindMapID, document.UserID, document.Filename, document.Format,
+		document.SizeBytes, document.ChunkCount, document.Embedded))
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		var embedding interface{}
+		if chunk.Embedding != nil {
+			embedding = pq.Array(chunk.Embedding)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO document_chunks (document_id, mind_map_id, chunk_index, content, embedding)
+			VALUES ($1, $2, $3, $4, $5)`,
+			created.ID, created.MindMapID, chunk.Index, chunk.Content, embedding); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*document = *created
+	return nil
+}
+
+// GetDocuments lists the documents of a mind map, oldest first
+func (db *DB) GetDocuments(mindMapID string) ([]models.Document, error) {
+	rows, err := db.Query(`
+		SELECT `+documentColumns+`
+		FROM mind_map_documents
+		WHERE mind_map_id = $1
+		ORDER BY created_at, id`, mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []models.Document{}
+	for rows.Next() {
+		document, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, *document)
+	}
+	return documents, rows.Err()
+}
+
+// GetDocument retrieves a document by ID, returning ErrNotFound if there is none
+func (db *DB) GetDocument(id string) (*models.Document, error) {
+	return scanDocument(db.QueryRow(`
+		SELECT `+documentColumns+`
+		FROM mind_map_documents
+		WHERE id = $1`, id))
+}
+
+// DeleteDocument removes a document and its chunks
+func (db *DB) DeleteDocument(id string) error {
+	result, err := db.Exec(`DELETE FROM mind_map_documents WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CountDocuments returns how many documents a mind map has
+func (db *DB) CountDocuments(mindMapID string) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM mind_map_documents WHERE mind_map_id = $1`, mindMapID).Scan(&count)
+	return count, err
+}
+
+// GetDocumentChunks retrieves the chunks of every document of a mind map with their
+// embeddings, for ranking them against a query
+func (db *DB) GetDocumentChunks(mindMapID string) ([]models.DocumentChunk, error) {
+	rows, err := db.Query(`
+		SELECT c.id, c.document_id, d.filename, c.chunk_index, c.content, c.embedding
+		FROM document_chunks c
+		JOIN mind_map_documents d ON d.id = c.document_id
+		WHERE c.mind_map_id = $1
+		ORDER BY d.created_at, c.document_id, c.chunk_index`, mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []models.DocumentChunk{}
+	for rows.Next() {
+		var chunk models.DocumentChunk
+		var embedding pq.Float32Array
+		if err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.Filename, &chunk.Index, &chunk.Content, &embedding); err != nil {
+			return nil, err
+		}
+		if embedding != nil {
+			chunk.Embedding = []float32(embedding)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
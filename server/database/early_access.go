@@ -1,15 +1,12 @@
 package database
 
-import (
-	"saas-server/models"
-	"time"
-)
+import "saas-server/models"
 
 // CreateEarlyAccessEntry creates a new early access entry in the database
 func (db *DB) CreateEarlyAccessEntry(email, referrer string) error {
 	_, err := db.Exec(
-		"INSERT INTO early_access (email, referrer, created_at, updated_at) VALUES ($1, $2, $3, $3)",
-		email, referrer, time.Now(),
+		"INSERT INTO early_access (email, referrer, created_at, updated_at) VALUES ($1, $2, NOW(), NOW())",
+		email, referrer,
 	)
 	return err
 }
@@ -24,8 +21,8 @@ func (db *DB) EarlyAccessEmailExists(email string) (bool, error) {
 // UpdateEarlyAccessReferrer updates the referrer for an existing early access entry
 func (db *DB) UpdateEarlyAccessReferrer(email, referrer string) error {
 	_, err := db.Exec(
-		"UPDATE early_access SET referrer = $1, updated_at = $2 WHERE email = $3",
-		referrer, time.Now(), email,
+		"UPDATE early_access SET referrer = $1, updated_at = NOW() WHERE email = $2",
+		referrer, email,
 	)
 	return err
 }
@@ -4,15 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"saas-server/models"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-// CreateEdge creates a new edge in the database
+// CreateEdge creates a new edge in the database, recording an edge.created change in the
+// same transaction
 func (db *DB) CreateEdge(req models.EdgeCreateRequest) (*models.Edge, error) {
 	id := uuid.New().String()
-	now := time.Now()
 
 	// Convert JSON data to bytes for storage
 	var styleDataBytes []byte
@@ -24,13 +24,19 @@ func (db *DB) CreateEdge(req models.EdgeCreateRequest) (*models.Edge, error) {
 
 	query := `
 		INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
 		RETURNING id, mind_map_id, source_id, target_id, edge_type, style_data, created_at`
 
 	var edge models.Edge
 	var styleData []byte
 
-	err := db.QueryRow(
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
 		query,
 		id,
 		req.MindMapID,
@@ -38,7 +44,6 @@ func (db *DB) CreateEdge(req models.EdgeCreateRequest) (*models.Edge, error) {
 		req.TargetID,
 		req.EdgeType,
 		styleDataBytes,
-		now,
 	).Scan(
 		&edge.ID,
 		&edge.MindMapID,
@@ -55,17 +60,71 @@ func (db *DB) CreateEdge(req models.EdgeCreateRequest) (*models.Edge, error) {
 	// Convert SQL data to model format
 	edge.StyleData = json.RawMessage(styleData)
 
+	if _, err := recordChange(tx, edge.MindMapID, "edge.created", edge); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+
 	return &edge, nil
 }
 
 // GetEdgesByMindMapID retrieves all edges for a specific mind map
 func (db *DB) GetEdgesByMindMapID(mindMapID string) ([]models.Edge, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{mindMapID})
+	query := `
+		SELECT id, mind_map_id, source_id, target_id, edge_type, style_data, created_at
+		FROM edges
+		WHERE mind_map_id = $1` + scope
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []models.Edge
+	for rows.Next() {
+		var edge models.Edge
+		var styleData []byte
+
+		err := rows.Scan(
+			&edge.ID,
+			&edge.MindMapID,
+			&edge.SourceID,
+			&edge.TargetID,
+			&edge.EdgeType,
+			&styleData,
+			&edge.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Convert SQL data to model format
+		edge.StyleData = json.RawMessage(styleData)
+
+		edges = append(edges, edge)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return edges, nil
+}
+
+// GetEdgesByMindMapIDs retrieves the edges of several mind maps at once. Served from the replica
+func (db *DB) GetEdgesByMindMapIDs(mindMapIDs []string) ([]models.Edge, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{pq.Array(mindMapIDs)})
 	query := `
 		SELECT id, mind_map_id, source_id, target_id, edge_type, style_data, created_at
 		FROM edges
-		WHERE mind_map_id = $1`
+		WHERE mind_map_id = ANY($1)` + scope
 
-	rows, err := db.Query(query, mindMapID)
+	rows, err := db.reader().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -104,15 +163,16 @@ func (db *DB) GetEdgesByMindMapID(mindMapID string) ([]models.Edge, error) {
 
 // GetEdgeByID retrieves a specific edge by its ID
 func (db *DB) GetEdgeByID(id string) (*models.Edge, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{id})
 	query := `
 		SELECT id, mind_map_id, source_id, target_id, edge_type, style_data, created_at
 		FROM edges
-		WHERE id = $1`
+		WHERE id = $1` + scope
 
 	var edge models.Edge
 	var styleData []byte
 
-	err := db.QueryRow(query, id).Scan(
+	err := db.QueryRow(query, args...).Scan(
 		&edge.ID,
 		&edge.MindMapID,
 		&edge.SourceID,
@@ -131,44 +191,59 @@ func (db *DB) GetEdgeByID(id string) (*models.Edge, error) {
 	return &edge, nil
 }
 
-// DeleteEdge deletes an edge from the database
+// DeleteEdge deletes an edge from the database, recording an edge.deleted change in the
+// same transaction
 func (db *DB) DeleteEdge(id string) error {
-	query := `DELETE FROM edges WHERE id = $1`
-
-	result, err := db.Exec(query, id)
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rows == 0 {
-		return fmt.Errorf("edge not found")
-	}
-
-	return nil
+	return db.deleteEdges(
+		`DELETE FROM edges WHERE id = $1 RETURNING mind_map_id`,
+		[]interface{}{id},
+		map[string]string{"id": id},
+		fmt.Errorf("edge not found"),
+	)
 }
 
-// DeleteEdgeByNodes deletes an edge between two specific nodes
+// DeleteEdgeByNodes deletes an edge between two specific nodes, recording an edge.deleted
+// change in the same transaction
 func (db *DB) DeleteEdgeByNodes(sourceID, targetID string) error {
-	query := `DELETE FROM edges WHERE source_id = $1 AND target_id = $2`
+	return db.deleteEdges(
+		`DELETE FROM edges WHERE source_id = $1 AND target_id = $2 RETURNING mind_map_id`,
+		[]interface{}{sourceID, targetID},
+		map[string]string{"source_id": sourceID, "target_id": targetID},
+		fmt.Errorf("edge not found between the specified nodes"),
+	)
+}
 
-	result, err := db.Exec(query, sourceID, targetID)
+// deleteEdges runs a delete returning the mind map of each deleted edge and records an
+// edge.deleted change with the payload for every map affected. Returns notFound when
+// nothing was deleted
+func (db *DB) deleteEdges(query string, args []interface{}, payload map[string]string, notFound error) error {
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	mindMapIDs, err := queryNodeIDs(tx.Query(query, args...))
 	if err != nil {
 		return err
 	}
-
-	if rows == 0 {
-		return fmt.Errorf("edge not found between the specified nodes")
+	if len(mindMapIDs) == 0 {
+		return notFound
 	}
 
+	recorded := make(map[string]bool)
+	for _, mindMapID := range mindMapIDs {
+		if recorded[mindMapID] {
+			continue
+		}
+		recorded[mindMapID] = true
+		if _, err := recordChange(tx, mindMapID, "edge.deleted", payload); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
 	return nil
 }
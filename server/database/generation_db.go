@@ -0,0 +1,154 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+)
+
+// generationColumns lists the generation columns in the order scanGeneration expects
+const generationColumns = `id, mind_map_id, user_id, job_id, request, ideas, provider, model, created_at`
+
+// scanGeneration scans a generation row selected with generationColumns
+func scanGeneration(row rowScanner) (*models.Generation, error) {
+	var generation models.Generation
+	var userID, jobID sql.NullString
+	var request, ideas []byte
+	err := row.Scan(
+		&generation.ID,
+		&generation.MindMapID,
+		&userID,
+		&jobID,
+		&request,
+		&ideas,
+		&generation.Provider,
+		&generation.Model,
+		&generation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		generation.UserID = &userID.String
+	}
+	if jobID.Valid {
+		generation.JobID = &jobID.String
+	}
+	generation.Request = request
+	generation.Ideas = ideas
+	return &generation, nil
+}
+
+// CreateGeneration stores a generation of a mind map. The job ID is empty for generations
+// made within a request
+func (db *DB) CreateGeneration(generation *models.Generation) error {
+	var jobID sql.NullString
+	if generation.JobID != nil {
+		jobID = sql.NullString{String: *generation.JobID, Valid: true}
+	}
+	return db.QueryRow(`
+		INSERT INTO generations (mind_map_id, user_id, job_id, request, ideas, provider, model, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING id, created_at`,
+		generation.MindMapID, generation.UserID, jobID, []byte(generation.Request), []byte(generation.Ideas),
+		generation.Provider, generation.Model,
+	).Scan(&generation.ID, &generation.CreatedAt)
+}
+
+// GetGenerations retrieves up to limit generations of a mind map made before the given
+// time, newest first. A zero time starts from the newest. Served from the replica
+func (db *DB) GetGenerations(mindMapID string, before time.Time, limit int) ([]models.Generation, error) {
+	if before.IsZero() {
+		before = time.Now().Add(time.Hour)
+	}
+	rows, err := db.reader().Query(`
+		SELECT `+generationColumns+`
+		FROM generations
+		WHERE mind_map_id = $1 AND created_at < $2
+		ORDER BY created_at DESC, id
+		LIMIT $3`, mindMapID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	generations := []models.Generation{}
+	for rows.Next() {
+		generation, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		generations = append(generations, *generation)
+	}
+	return generations, rows.Err()
+}
+
+// ideaFeedbackColumns lists the idea feedback columns in the order scanIdeaFeedback expects
+const ideaFeedbackColumns = `id, mind_map_id, user_id, generation_id, title, content, verdict, created_at, updated_at`
+
+// scanIdeaFeedback scans an idea feedback row selected with ideaFeedbackColumns
+func scanIdeaFeedback(row rowScanner) (*models.IdeaFeedback, error) {
+	var feedback models.IdeaFeedback
+	var generationID sql.NullString
+	err := row.Scan(
+		&feedback.ID,
+		&feedback.MindMapID,
+		&feedback.UserID,
+		&generationID,
+		&feedback.Title,
+		&feedback.Content,
+		&feedback.Verdict,
+		&feedback.CreatedAt,
+		&feedback.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if generationID.Valid {
+		feedback.GenerationID = &generationID.String
+	}
+	return &feedback, nil
+}
+
+// SaveIdeaFeedback records a user's verdict on an idea of a mind map, replacing their
+// earlier verdict on the same idea. Returns ErrNotFound if a generation is given that is
+// not one of the map's
+func (db *DB) SaveIdeaFeedback(userID string, req models.IdeaFeedbackRequest) (*models.IdeaFeedback, error) {
+	return scanIdeaFeedback(db.QueryRow(`
+		INSERT INTO idea_feedback (mind_map_id, user_id, generation_id, title, content, verdict, created_at, updated_at)
+		SELECT $1, $2, NULLIF($3, '')::uuid, $4, $5, $6, NOW(), NOW()
+		WHERE $3 = '' OR EXISTS (SELECT 1 FROM generations WHERE id = NULLIF($3, '')::uuid AND mind_map_id = $1)
+		ON CONFLICT (mind_map_id, user_id, md5(content)) DO UPDATE
+		SET generation_id = COALESCE(EXCLUDED.generation_id, idea_feedback.generation_id),
+			title = EXCLUDED.title, verdict = EXCLUDED.verdict
+		RETURNING `+ideaFeedbackColumns,
+		req.MindMapID, userID, req.GenerationID, req.Title, req.Content, req.Verdict))
+}
+
+// GetRecentIdeaFeedback retrieves up to limit of a mind map's latest feedback with the
+// given verdict, from any of its users, newest first
+func (db *DB) GetRecentIdeaFeedback(mindMapID, verdict string, limit int) ([]models.IdeaFeedback, error) {
+	rows, err := db.Query(`
+		SELECT `+ideaFeedbackColumns+`
+		FROM idea_feedback
+		WHERE mind_map_id = $1 AND verdict = $2
+		ORDER BY updated_at DESC, id
+		LIMIT $3`, mindMapID, verdict, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feedback := []models.IdeaFeedback{}
+	for rows.Next() {
+		item, err := scanIdeaFeedback(rows)
+		if err != nil {
+			return nil, err
+		}
+		feedback = append(feedback, *item)
+	}
+	return feedback, rows.Err()
+}
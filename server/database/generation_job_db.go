@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+)
+
+// generationJobColumns lists the generation job columns in the order scanGenerationJob
+// expects
+const generationJobColumns = `id, user_id, mind_map_id, status, request, result, COALESCE(error, ''),
+	completed_steps, total_steps, attempts, created_at, started_at, finished_at`
+
+// scanGenerationJob scans a generation job row selected with generationJobColumns
+func scanGenerationJob(row rowScanner, extra ...interface{}) (*models.GenerationJob, error) {
+	var job models.GenerationJob
+	var result []byte
+	dest := []interface{}{
+		&job.ID,
+		&job.UserID,
+		&job.MindMapID,
+		&job.Status,
+		&job.Request,
+		&result,
+		&job.Error,
+		&job.CompletedSteps,
+		&job.TotalSteps,
+		&job.Attempts,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Result = result
+	return &job, nil
+}
+
+// CreateGenerationJob queues a generation job. An API key given with the request is
+// stored encrypted until the job finishes
+func (db *DB) CreateGenerationJob(userID, mindMapID string, request []byte, apiKey string, totalSteps int) (*models.GenerationJob, error) {
+	var encryptedKey sql.NullString
+	if apiKey != "" {
+		encrypted, err := encryptAPIKey(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		encryptedKey = sql.NullString{String: encrypted, Valid: true}
+	}
+
+	return scanGenerationJob(db.QueryRow(`
+		INSERT INTO generation_jobs (user_id, mind_map_id, request, encrypted_api_key, total_steps, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING `+generationJobColumns, userID, mindMapID, request, encryptedKey, totalSteps))
+}
+
+// GetGenerationJob retrieves a generation job of a user. Returns ErrNotFound if the job
+// does not exist or belongs to someone else
+func (db *DB) GetGenerationJob(userID, jobID string) (*models.GenerationJob, error) {
+	return scanGenerationJob(db.QueryRow(`
+		SELECT `+generationJobColumns+`
+		FROM generation_jobs
+		WHERE id = $1 AND user_id = $2`, jobID, userID))
+}
+
+// ClaimGenerationJob marks the oldest queued generation job as running and returns it with
+// its decrypted API key, or ErrNotFound if none is queued. The job is claimed with a row
+// lock that other instances skip, so each job runs once
+func (db *DB) ClaimGenerationJob() (*models.GenerationJob, string, error) {
+	var encryptedKey sql.NullString
+	job, err := scanGenerationJob(db.QueryRow(`
+		UPDATE generation_jobs
+		SET status = 'running', started_at = NOW(), attempts = attempts + 1, completed_steps = 0
+		WHERE id = (
+			SELECT id FROM generation_jobs
+			WHERE status = 'queued'
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+generationJobColumns+`, encrypted_api_key`), &encryptedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	if !encryptedKey.Valid {
+		return job, "", nil
+	}
+	apiKey, err := decryptAPIKey(encryptedKey.String)
+	if err != nil {
+		return job, "", err
+	}
+	return job, apiKey, nil
+}
+
+// UpdateGenerationJobProgress records how many of a running job's AI calls have been made
+func (db *DB) UpdateGenerationJobProgress(jobID string, completedSteps int) error {
+	_, err := db.Exec(`
+		UPDATE generation_jobs SET completed_steps = $2
+		WHERE id = $1 AND status = 'running'`, jobID, completedSteps)
+	return err
+}
+
+// FinishGenerationJob marks a running job as succeeded with its result, or as failed when
+// errMessage is not empty, and forgets its API key. A job that succeeded has completed all
+// its steps, even if it needed fewer AI calls than estimated
+func (db *DB) FinishGenerationJob(jobID string, result []byte, errMessage string) error {
+	status := models.GenerationJobSucceeded
+	if errMessage != "" {
+		status = models.GenerationJobFailed
+		result = nil
+	}
+	_, err := db.Exec(`
+		UPDATE generation_jobs
+		SET status = $2, result = $3, error = NULLIF($4, ''), finished_at = NOW(), encrypted_api_key = NULL,
+			completed_steps = CASE WHEN $2 = 'succeeded' THEN total_steps ELSE completed_steps END
+		WHERE id = $1 AND status = 'running'`, jobID, status, result, errMessage)
+	return err
+}
+
+// RequeueStaleGenerationJobs queues jobs that have been running for longer than staleAfter
+// again, as the instance running them stopped, and fails those that already had
+// maxAttempts. Returns how many jobs were queued again
+func (db *DB) RequeueStaleGenerationJobs(staleAfter time.Duration, maxAttempts int) (int64, error) {
+	_, err := db.Exec(`
+		UPDATE generation_jobs
+		SET status = 'failed', error = 'The job was interrupted too often', finished_at = NOW(), encrypted_api_key = NULL
+		WHERE status = 'running' AND started_at < NOW() - $1 * INTERVAL '1 millisecond' AND attempts >= $2`,
+		staleAfter.Milliseconds(), maxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		UPDATE generation_jobs SET status = 'queued'
+		WHERE status = 'running' AND started_at < NOW() - $1 * INTERVAL '1 millisecond'`,
+		staleAfter.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteFinishedGenerationJobs removes jobs that finished before the given time
+func (db *DB) DeleteFinishedGenerationJobs(before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM generation_jobs WHERE finished_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"saas-server/models"
+)
+
+// ApplyIdeas merges ideas into the nodes they duplicate and creates the nodes of the rest in
+// a single transaction, so a failure leaves the map as it was. Nodes must be ordered so
+// that parents come before their children. Records a change for each updated and created
+// node and each created edge in the transaction. Returns the merged nodes, the created
+// nodes and their edges, in order
+func (db *DB) ApplyIdeas(mindMapID string, merges []models.IdeaMerge, nodes []models.IdeaNode) ([]models.Node, []models.Node, []models.Edge, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer tx.Rollback()
+
+	merged := make([]models.Node, 0, len(merges))
+	for _, merge := range merges {
+		node, err := scanNode(tx.QueryRow(`
+			UPDATE nodes SET content = $3, updated_at = NOW()
+			WHERE id = $1 AND mind_map_id = $2
+			RETURNING `+nodeColumns, merge.NodeID, mindMapID, merge.Content))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to merge into node %s: %v", merge.NodeID, err)
+		}
+		if err := syncNodeLinks(tx, node.ID, node.MindMapID, node.Content, node.NodeType, node.Metadata); err != nil {
+			return nil, nil, nil, err
+		}
+		masked := *node
+		masked.MaskAttribution()
+		if _, err := recordChange(tx, mindMapID, "node.updated", masked); err != nil {
+			return nil, nil, nil, err
+		}
+		merged = append(merged, *node)
+	}
+
+	created := make([]models.Node, 0, len(nodes))
+	edges := make([]models.Edge, 0, len(nodes))
+	for _, idea := range nodes {
+		idea.Node.MindMapID = mindMapID
+		node, err := insertNodeRowTx(tx, idea.ID, idea.Node)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		masked := *node
+		masked.MaskAttribution()
+		if _, err := recordChange(tx, mindMapID, "node.created", masked); err != nil {
+			return nil, nil, nil, err
+		}
+		created = append(created, *node)
+
+		if idea.Node.ParentID == nil {
+			continue
+		}
+		edge, err := insertEdgeTx(tx, mindMapID, *idea.Node.ParentID, node.ID, idea.EdgeType)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if _, err := recordChange(tx, mindMapID, "edge.created", edge); err != nil {
+			return nil, nil, nil, err
+		}
+		edges = append(edges, *edge)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, nil, err
+	}
+	db.signalChange()
+	return merged, created, edges, nil
+}
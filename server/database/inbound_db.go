@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// inboundAddressColumns lists the inbound address columns in the order scanInboundAddress expects
+const inboundAddressColumns = `user_id, token, mind_map_id, allowed_senders, parse_mode, created_at, updated_at`
+
+// scanInboundAddress scans an inbound address row selected with inboundAddressColumns
+func scanInboundAddress(row rowScanner) (*models.InboundAddress, error) {
+	var address models.InboundAddress
+	var mindMapID sql.NullString
+	err := row.Scan(
+		&address.UserID,
+		&address.Token,
+		&mindMapID,
+		pq.Array(&address.AllowedSenders),
+		&address.ParseMode,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mindMapID.Valid {
+		address.MindMapID = &mindMapID.String
+	}
+	if address.AllowedSenders == nil {
+		address.AllowedSenders = []string{}
+	}
+	return &address, nil
+}
+
+// GetInboundAddress retrieves the inbound address of a user
+func (db *DB) GetInboundAddress(userID string) (*models.InboundAddress, error) {
+	query := `
+		SELECT ` + inboundAddressColumns + `
+		FROM inbound_addresses
+		WHERE user_id = $1`
+
+	return scanInboundAddress(db.QueryRow(query, userID))
+}
+
+// GetInboundAddressByToken retrieves the inbound address with the given secret token
+func (db *DB) GetInboundAddressByToken(token string) (*models.InboundAddress, error) {
+	query := `
+		SELECT ` + inboundAddressColumns + `
+		FROM inbound_addresses
+		WHERE token = $1`
+
+	return scanInboundAddress(db.QueryRow(query, token))
+}
+
+// SaveInboundAddress creates or replaces the settings of a user's inbound address. The
+// token is only used when the address is created; an existing address keeps its token
+func (db *DB) SaveInboundAddress(userID, token string, req models.InboundAddressRequest) (*models.InboundAddress, error) {
+	query := `
+		INSERT INTO inbound_addresses (user_id, token, mind_map_id, allowed_senders, parse_mode, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET mind_map_id = EXCLUDED.mind_map_id,
+		    allowed_senders = EXCLUDED.allowed_senders,
+		    parse_mode = EXCLUDED.parse_mode,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING ` + inboundAddressColumns
+
+	return scanInboundAddress(db.QueryRow(
+		query,
+		userID,
+		token,
+		req.MindMapID,
+		pq.Array(req.AllowedSenders),
+		req.ParseMode,
+	))
+}
+
+// RotateInboundToken replaces the secret token of a user's inbound address
+func (db *DB) RotateInboundToken(userID, token string) (*models.InboundAddress, error) {
+	query := `
+		UPDATE inbound_addresses
+		SET token = $2, updated_at = NOW()
+		WHERE user_id = $1
+		RETURNING ` + inboundAddressColumns
+
+	return scanInboundAddress(db.QueryRow(query, userID, token))
+}
+
+// DeleteInboundAddress removes a user's inbound address
+func (db *DB) DeleteInboundAddress(userID string) error {
+	result, err := db.Exec(`DELETE FROM inbound_addresses WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordInboundDelivery records the provider token of a signed inbound email, returning
+// false if the token was already recorded. Tokens older than keep are pruned first
+func (db *DB) RecordInboundDelivery(token string, keep time.Duration) (bool, error) {
+	query := `
+		WITH pruned AS (
+			DELETE FROM inbound_deliveries
+			WHERE received_at < NOW() - make_interval(secs => $2)
+		)
+		INSERT INTO inbound_deliveries (token, received_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (token) DO NOTHING`
+
+	result, err := db.Exec(query, token, keep.Seconds())
+	if err != nil {
+		return false, err
+	}
+	recorded, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return recorded == 1, nil
+}
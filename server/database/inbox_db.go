@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// inboxTitle is the title and root content of a newly provisioned inbox
+const inboxTitle = "Inbox"
+
+// GetOrCreateInbox returns the user's inbox, creating it with a root node the first time.
+// Reports whether the inbox was created
+func (db *DB) GetOrCreateInbox(userID string) (*models.MindMap, bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	mindMap, err := scanMindMap(tx.QueryRow(`
+		INSERT INTO mind_maps (id, user_id, title, description, is_inbox, created_at, updated_at, status)
+		VALUES ($1, $2, $3, $4, true, NOW(), NOW(), $5)
+		ON CONFLICT (user_id) WHERE is_inbox AND status != 'deleted' DO NOTHING
+		RETURNING `+mindMapColumns,
+		uuid.New().String(),
+		userID,
+		inboxTitle,
+		"Quick captures waiting to be sorted into other maps",
+		models.MindMapStatusActive,
+	))
+	if err == sql.ErrNoRows {
+		// The inbox already exists
+		mindMap, err = db.GetInbox(userID)
+		return mindMap, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = insertNodeTx(tx, models.NodeCreateRequest{
+		MindMapID: mindMap.ID,
+		Content:   inboxTitle,
+		NodeType:  "root",
+		CreatedBy: userID,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return mindMap, true, nil
+}
+
+// GetInbox retrieves the user's inbox
+func (db *DB) GetInbox(userID string) (*models.MindMap, error) {
+	query := `
+		SELECT ` + mindMapColumns + `
+		FROM mind_maps
+		WHERE user_id = $1 AND is_inbox AND status != 'deleted'`
+
+	mindMap, err := scanMindMap(db.QueryRow(query, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return mindMap, err
+}
+
+// MoveNodesToMindMap moves nodes together with their subtrees from one mind map to another
+// in a single transaction. The moved branches are attached to parentID, or become roots
+// when it is nil; edges crossing out of a branch are dropped and the rest move along, as
+// do votes and link index entries. Positions of moved nodes are set from positions. Records
+// a node.deleted change in the source map for each moved node and a nodes.imported change
+// in the target map in the same transaction. Returns the IDs of every moved node
+func (db *DB) MoveNodesToMindMap(sourceID, targetID string, nodeIDs []string, parentID *string, positions []models.NodePositionUpdateRequest) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// A node is moved when it or one of its ancestors is selected
+	movedIDs, err := queryNodeIDs(tx.Query(`
+		SELECT id FROM nodes WHERE mind_map_id = $1 AND path && $2::uuid[]`, sourceID, pq.Array(nodeIDs)))
+	if err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return movedIDs, nil
+	}
+	moved := pq.Array(movedIDs)
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Edges between a moved node and one that stays behind cannot follow
+		{`DELETE FROM edges
+		  WHERE mind_map_id = $1
+		  AND ((source_id = ANY($2)) != (target_id = ANY($2)))`, []interface{}{sourceID, moved}},
+		{`UPDATE edges SET mind_map_id = $2 WHERE mind_map_id = $1 AND source_id = ANY($3)`, []interface{}{sourceID, targetID, moved}},
+		{`UPDATE nodes SET mind_map_id = $2, updated_at = NOW() WHERE id = ANY($1)`, []interface{}{moved, targetID}},
+		{`UPDATE nodes SET parent_id = $3 WHERE id = ANY($1) AND id = ANY($2)`, []interface{}{pq.Array(nodeIDs), moved, parentID}},
+		{`UPDATE node_votes SET mind_map_id = $2 WHERE node_id = ANY($1)`, []interface{}{moved, targetID}},
+		{`UPDATE node_links SET source_mind_map_id = $2 WHERE source_node_id = ANY($1)`, []interface{}{moved, targetID}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, position := range positions {
+		_, err := tx.Exec(`UPDATE nodes SET position_x = $3, position_y = $4 WHERE id = $1 AND mind_map_id = $2`,
+			position.ID, targetID, position.PositionX, position.PositionY)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if parentID != nil {
+		for _, nodeID := range nodeIDs {
+			_, err := tx.Exec(`
+				INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
+				SELECT $1, $2, $3, id, $5, $6, NOW() FROM nodes WHERE id = $4 AND mind_map_id = $2`,
+				uuid.New().String(),
+				targetID,
+				*parentID,
+				nodeID,
+				"default",
+				[]byte("{}"),
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, id := range movedIDs {
+		if _, err := recordChange(tx, sourceID, "node.deleted", map[string]string{"id": id}); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := recordChange(tx, targetID, "nodes.imported", map[string][]string{"node_ids": movedIDs}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return movedIDs, nil
+}
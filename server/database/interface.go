@@ -49,4 +49,8 @@ type DBInterface interface {
 	UpdateUserSubscription(userID string, subscriptionID int, status string, productID int, variantID int, renewalDate *time.Time, endDate *time.Time) error
 	StoreEmailVerificationToken(token, userID, email string, expiresAt time.Time) error
 	VerifyEmail(token string) error
+
+	// Single sign-on and provisioning operations
+	SSORequired(email string) (bool, error)
+	IsUserDeactivated(userID string) (bool, error)
 }
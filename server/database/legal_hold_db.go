@@ -0,0 +1,251 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrLegalHoldExists is returned when a user already has an active legal hold
+var ErrLegalHoldExists = errors.New("user already has an active legal hold")
+
+// legalHoldColumns lists the legal hold columns, joined with the held user's email, in the
+// order scanLegalHold expects
+const legalHoldColumns = `h.id, h.tenant_id, h.user_id, COALESCE(u.email, ''), h.reason,
+	h.created_by, h.created_at, h.released_by, h.released_at`
+
+// scanLegalHold scans a legal hold row selected with legalHoldColumns
+func scanLegalHold(row rowScanner) (*models.LegalHold, error) {
+	var hold models.LegalHold
+	var createdBy, releasedBy sql.NullString
+	err := row.Scan(
+		&hold.ID,
+		&hold.TenantID,
+		&hold.UserID,
+		&hold.UserEmail,
+		&hold.Reason,
+		&createdBy,
+		&hold.CreatedAt,
+		&releasedBy,
+		&hold.ReleasedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		hold.CreatedBy = &createdBy.String
+	}
+	if releasedBy.Valid {
+		hold.ReleasedBy = &releasedBy.String
+	}
+	return &hold, nil
+}
+
+// CreateLegalHold places a legal hold on a user of a tenant and records it in the audit
+// log in the same transaction, so no hold goes unlogged. The audit event's target is set
+// to the new hold. Returns ErrNotFound if the user does not belong to the tenant and
+// ErrLegalHoldExists if they are already held
+func (db *DB) CreateLegalHold(tenantID, userID, reason, createdBy string, audit *models.AuditEvent) (*models.LegalHold, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := scanLegalHold(tx.QueryRow(`
+		WITH created AS (
+			INSERT INTO legal_holds (tenant_id, user_id, reason, created_by, created_at)
+			SELECT $1, id, $3, $4, NOW() FROM users WHERE id = $2 AND tenant_id = $1
+			RETURNING *
+		)
+		SELECT `+legalHoldColumns+`
+		FROM created h
+		LEFT JOIN users u ON u.id = h.user_id`, tenantID, userID, reason, createdBy))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		tx.Rollback()
+		return nil, ErrLegalHoldExists
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	audit.TargetID = hold.ID
+	if err := recordAuditEvent(tx, audit); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// GetLegalHold retrieves a legal hold of a tenant. Returns ErrNotFound if it does not exist
+func (db *DB) GetLegalHold(tenantID, holdID string) (*models.LegalHold, error) {
+	return scanLegalHold(db.QueryRow(`
+		SELECT `+legalHoldColumns+`
+		FROM legal_holds h
+		LEFT JOIN users u ON u.id = h.user_id
+		WHERE h.id = $1 AND h.tenant_id = $2`, holdID, tenantID))
+}
+
+// GetLegalHolds lists the legal holds of a tenant, newest first, leaving out released ones
+// unless includeReleased is set
+func (db *DB) GetLegalHolds(tenantID string, includeReleased bool) ([]models.LegalHold, error) {
+	rows, err := db.Query(`
+		SELECT `+legalHoldColumns+`
+		FROM legal_holds h
+		LEFT JOIN users u ON u.id = h.user_id
+		WHERE h.tenant_id = $1 AND ($2 OR h.released_at IS NULL)
+		ORDER BY h.created_at DESC, h.id`, tenantID, includeReleased)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []models.LegalHold{}
+	for rows.Next() {
+		hold, err := scanLegalHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+	return holds, rows.Err()
+}
+
+// ReleaseLegalHold releases an active legal hold of a tenant and records it in the audit
+// log in the same transaction. Returns ErrNotFound if the hold does not exist or was
+// already released
+func (db *DB) ReleaseLegalHold(tenantID, holdID, releasedBy string, audit *models.AuditEvent) (*models.LegalHold, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := scanLegalHold(tx.QueryRow(`
+		WITH released AS (
+			UPDATE legal_holds SET released_by = $3, released_at = NOW()
+			WHERE id = $1 AND tenant_id = $2 AND released_at IS NULL
+			RETURNING *
+		)
+		SELECT `+legalHoldColumns+`
+		FROM released h
+		LEFT JOIN users u ON u.id = h.user_id`, holdID, tenantID, releasedBy))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := recordAuditEvent(tx, audit); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// GetUserMindMapsWithDetails retrieves every mind map a user owns, with its nodes, edges
+// and custom fields, whatever its status, oldest first. Deleted maps still in the trash
+// are included
+func (db *DB) GetUserMindMapsWithDetails(userID string) ([]*models.MindMapWithDetails, error) {
+	rows, err := db.Query(`
+		SELECT `+mindMapColumns+`
+		FROM mind_maps
+		WHERE user_id = $1
+		ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var mindMaps []*models.MindMap
+	for rows.Next() {
+		mindMap, err := scanMindMap(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		mindMaps = append(mindMaps, mindMap)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	details := make([]*models.MindMapWithDetails, 0, len(mindMaps))
+	for _, mindMap := range mindMaps {
+		detail, err := db.mindMapDetails(mindMap)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+// RecordAuditEvent appends an event to its tenant's audit log
+func (db *DB) RecordAuditEvent(event *models.AuditEvent) error {
+	return recordAuditEvent(db, event)
+}
+
+// recordAuditEvent records an audit event using either the connection pool or a
+// transaction, so an event can commit together with the change it describes
+func recordAuditEvent(q queryRower, event *models.AuditEvent) error {
+	details := []byte(event.Details)
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+	return q.QueryRow(`
+		INSERT INTO audit_events (tenant_id, actor_id, action, target_type, target_id, details, ip_address, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NOW())
+		RETURNING id, created_at`,
+		event.TenantID, event.ActorID, event.Action, event.TargetType, event.TargetID, details,
+		event.IPAddress, event.UserAgent,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// GetAuditEvents retrieves up to limit audit events of a tenant older than the event with
+// the given ID, newest first. A beforeID of 0 starts from the newest
+func (db *DB) GetAuditEvents(tenantID string, beforeID int64, limit int) ([]models.AuditEvent, error) {
+	rows, err := db.Query(`
+		SELECT id, tenant_id, actor_id, action, target_type, target_id, details,
+			COALESCE(ip_address, ''), COALESCE(user_agent, ''), created_at
+		FROM audit_events
+		WHERE tenant_id = $1 AND ($2::bigint = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`, tenantID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var actorID sql.NullString
+		var details []byte
+		if err := rows.Scan(&event.ID, &event.TenantID, &actorID, &event.Action, &event.TargetType, &event.TargetID,
+			&details, &event.IPAddress, &event.UserAgent, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			event.ActorID = &actorID.String
+		}
+		event.Details = details
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// legalHoldExcluded is the condition leaving out mind maps whose owner is under an active
+// legal hold, for the retention rules that destroy data
+const legalHoldExcluded = `NOT EXISTS (
+			SELECT 1 FROM legal_holds lh WHERE lh.user_id = mind_maps.user_id AND lh.released_at IS NULL
+		)`
@@ -88,7 +88,12 @@ func (m *MigrationManager) RunMigrations() error {
 			return fmt.Errorf("error starting transaction for %s: %v", migration, err)
 		}
 
-		// Execute migration
+		// Execute migration; backfills on large tables may need longer than the
+		// statement timeout requests run with
+		if _, err := tx.Exec("SET LOCAL statement_timeout = 0"); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error disabling statement timeout for %s: %v", migration, err)
+		}
 		if _, err := tx.Exec(string(content)); err != nil {
 			tx.Rollback()
 			return fmt.Errorf("error executing migration %s: %v", migration, err)
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_classroom_copies_template_id;
+DROP INDEX IF EXISTS idx_mind_map_shares_email;
+
+-- Drop tables
+DROP TABLE IF EXISTS classroom_copies;
+DROP TABLE IF EXISTS mind_map_shares;
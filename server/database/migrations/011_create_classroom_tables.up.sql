@@ -0,0 +1,26 @@
+-- Create mind_map_shares table for granting other users access to a mind map
+CREATE TABLE IF NOT EXISTS mind_map_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mind_map_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    permission VARCHAR(20) NOT NULL DEFAULT 'edit',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT unique_mind_map_share UNIQUE(mind_map_id, email)
+);
+
+-- Create classroom_copies table linking a template map to its per-participant copies
+CREATE TABLE IF NOT EXISTS classroom_copies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id UUID NOT NULL,
+    mind_map_id UUID NOT NULL,
+    participant_email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_template FOREIGN KEY (template_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT unique_classroom_participant UNIQUE(template_id, participant_email)
+);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_mind_map_shares_email ON mind_map_shares(email);
+CREATE INDEX IF NOT EXISTS idx_classroom_copies_template_id ON classroom_copies(template_id);
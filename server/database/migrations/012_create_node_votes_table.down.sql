@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_node_votes_map_user;
+DROP INDEX IF EXISTS idx_node_votes_node_id;
+
+-- Drop table
+DROP TABLE IF EXISTS node_votes;
+
+-- Remove vote limit column
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS vote_limit;
@@ -0,0 +1,18 @@
+-- Add per-user vote limit to mind_maps for dot voting
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS vote_limit INTEGER NOT NULL DEFAULT 3;
+
+-- Create node_votes table, one row per dot placed on a node
+CREATE TABLE IF NOT EXISTS node_votes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    node_id UUID NOT NULL,
+    mind_map_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_node FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_node_votes_node_id ON node_votes(node_id);
+CREATE INDEX IF NOT EXISTS idx_node_votes_map_user ON node_votes(mind_map_id, user_id);
@@ -0,0 +1,5 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_brainstorm_sessions_mind_map_id;
+
+-- Drop table
+DROP TABLE IF EXISTS brainstorm_sessions;
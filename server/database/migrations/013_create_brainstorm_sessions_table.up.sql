@@ -0,0 +1,19 @@
+-- Create brainstorm_sessions table for timed facilitation sessions on a mind map
+CREATE TABLE IF NOT EXISTS brainstorm_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mind_map_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    mode VARCHAR(20) NOT NULL DEFAULT 'ideation', -- ideation or voting
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    participants TEXT[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMP WITH TIME ZONE,
+    stopped_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create index for looking up the current session of a map
+CREATE INDEX IF NOT EXISTS idx_brainstorm_sessions_mind_map_id ON brainstorm_sessions(mind_map_id, started_at DESC);
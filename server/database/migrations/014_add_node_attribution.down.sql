@@ -0,0 +1,11 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_nodes_session_id;
+
+-- Remove authorship reveal columns
+ALTER TABLE brainstorm_sessions DROP COLUMN IF EXISTS revealed_at;
+ALTER TABLE brainstorm_sessions DROP COLUMN IF EXISTS authorship_revealed;
+
+-- Remove attribution columns
+ALTER TABLE nodes DROP COLUMN IF EXISTS session_id;
+ALTER TABLE nodes DROP COLUMN IF EXISTS anonymous;
+ALTER TABLE nodes DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,11 @@
+-- Record who created each node and whether it was contributed anonymously
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS anonymous BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES brainstorm_sessions(id) ON DELETE SET NULL;
+
+-- Track whether the facilitator has revealed authorship of anonymous contributions
+ALTER TABLE brainstorm_sessions ADD COLUMN IF NOT EXISTS authorship_revealed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE brainstorm_sessions ADD COLUMN IF NOT EXISTS revealed_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index for looking up contributions by session
+CREATE INDEX IF NOT EXISTS idx_nodes_session_id ON nodes(session_id);
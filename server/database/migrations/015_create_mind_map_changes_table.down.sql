@@ -0,0 +1,8 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_mind_map_changes_created_at;
+
+-- Drop table
+DROP TABLE IF EXISTS mind_map_changes;
+
+-- Remove change sequence column
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS change_seq;
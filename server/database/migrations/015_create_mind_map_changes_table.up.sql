@@ -0,0 +1,16 @@
+-- Track the latest change sequence number of each mind map
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS change_seq BIGINT NOT NULL DEFAULT 0;
+
+-- Create mind_map_changes table holding the ordered change log used for realtime resync
+CREATE TABLE IF NOT EXISTS mind_map_changes (
+    mind_map_id UUID NOT NULL,
+    seq BIGINT NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (mind_map_id, seq),
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE
+);
+
+-- Create index for pruning old changes
+CREATE INDEX IF NOT EXISTS idx_mind_map_changes_created_at ON mind_map_changes(created_at);
@@ -0,0 +1,8 @@
+-- Create mind_map_thumbnails table holding the rendered preview of each mind map
+CREATE TABLE IF NOT EXISTS mind_map_thumbnails (
+    mind_map_id UUID PRIMARY KEY,
+    svg TEXT NOT NULL,
+    change_seq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE
+);
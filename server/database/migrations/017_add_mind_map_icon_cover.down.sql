@@ -0,0 +1,3 @@
+-- Remove icon and cover image columns
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS cover_image;
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS icon;
@@ -0,0 +1,3 @@
+-- Add emoji icon and cover image shown on the dashboard
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS icon VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS cover_image TEXT NOT NULL DEFAULT '';
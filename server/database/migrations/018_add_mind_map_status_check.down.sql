@@ -0,0 +1,2 @@
+-- Drop status constraint
+ALTER TABLE mind_maps DROP CONSTRAINT IF EXISTS chk_mind_map_status;
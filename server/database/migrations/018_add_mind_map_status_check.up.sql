@@ -0,0 +1,6 @@
+-- Normalize statuses written before the status values were enforced
+UPDATE mind_maps SET status = 'active' WHERE status NOT IN ('active', 'archived', 'deleted');
+
+-- Restrict status to the known values
+ALTER TABLE mind_maps DROP CONSTRAINT IF EXISTS chk_mind_map_status;
+ALTER TABLE mind_maps ADD CONSTRAINT chk_mind_map_status CHECK (status IN ('active', 'archived', 'deleted'));
@@ -0,0 +1,2 @@
+-- Drop trigram index; the extension is left in place as other objects may use it
+DROP INDEX IF EXISTS idx_nodes_content_trgm;
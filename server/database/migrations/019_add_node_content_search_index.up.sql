@@ -0,0 +1,5 @@
+-- Enable trigram matching for substring search on node content
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Create trigram index so ILIKE searches stay fast on large maps
+CREATE INDEX IF NOT EXISTS idx_nodes_content_trgm ON nodes USING gin (content gin_trgm_ops);
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_node_links_target_mind_map_id;
+DROP INDEX IF EXISTS idx_node_links_target_node_id;
+DROP INDEX IF EXISTS idx_node_links_source_node_id;
+
+-- Drop table
+DROP TABLE IF EXISTS node_links;
@@ -0,0 +1,17 @@
+-- Create node_links table indexing references from node content to other maps and nodes
+CREATE TABLE IF NOT EXISTS node_links (
+    source_node_id UUID NOT NULL,
+    source_mind_map_id UUID NOT NULL,
+    target_mind_map_id UUID NOT NULL,
+    target_node_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_source_node FOREIGN KEY (source_node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+    CONSTRAINT fk_source_mind_map FOREIGN KEY (source_mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_target_mind_map FOREIGN KEY (target_mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_target_node FOREIGN KEY (target_node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+-- Create indexes for looking up links by either end
+CREATE INDEX IF NOT EXISTS idx_node_links_source_node_id ON node_links(source_node_id);
+CREATE INDEX IF NOT EXISTS idx_node_links_target_node_id ON node_links(target_node_id);
+CREATE INDEX IF NOT EXISTS idx_node_links_target_mind_map_id ON node_links(target_mind_map_id);
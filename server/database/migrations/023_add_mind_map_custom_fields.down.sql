@@ -0,0 +1,2 @@
+-- Remove the custom field schema
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS custom_fields;
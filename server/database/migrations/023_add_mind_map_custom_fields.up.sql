@@ -0,0 +1,2 @@
+-- Add the custom field schema that node metadata in each mind map is validated against
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '[]';
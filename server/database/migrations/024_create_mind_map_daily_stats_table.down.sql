@@ -0,0 +1,2 @@
+-- Drop table
+DROP TABLE IF EXISTS mind_map_daily_stats;
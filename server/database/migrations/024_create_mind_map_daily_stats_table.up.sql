@@ -0,0 +1,10 @@
+-- Create mind_map_daily_stats table holding one size snapshot per mind map per day
+CREATE TABLE IF NOT EXISTS mind_map_daily_stats (
+    mind_map_id UUID NOT NULL,
+    day DATE NOT NULL,
+    node_count INTEGER NOT NULL,
+    edge_count INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (mind_map_id, day),
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE CASCADE
+);
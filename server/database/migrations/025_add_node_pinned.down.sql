@@ -0,0 +1,2 @@
+-- Remove the pinned flag
+ALTER TABLE nodes DROP COLUMN IF EXISTS pinned;
@@ -0,0 +1,2 @@
+-- Add the pinned flag that keeps a node in place when the map is re-laid out
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
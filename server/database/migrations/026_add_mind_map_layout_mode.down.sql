@@ -0,0 +1,2 @@
+-- Remove the layout mode
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS layout_mode;
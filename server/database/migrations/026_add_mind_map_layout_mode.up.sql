@@ -0,0 +1,2 @@
+-- Add the layout mode chosen for each mind map, used by re-layout and drawing exports
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS layout_mode VARCHAR(20) NOT NULL DEFAULT '';
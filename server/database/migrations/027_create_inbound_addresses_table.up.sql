@@ -0,0 +1,13 @@
+-- Create inbound_addresses table holding each user's secret address for creating maps
+-- from emails and webhook payloads
+CREATE TABLE IF NOT EXISTS inbound_addresses (
+    user_id UUID PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    mind_map_id UUID,
+    allowed_senders TEXT[] NOT NULL DEFAULT '{}',
+    parse_mode VARCHAR(20) NOT NULL DEFAULT 'lines',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_mind_map FOREIGN KEY (mind_map_id) REFERENCES mind_maps(id) ON DELETE SET NULL
+);
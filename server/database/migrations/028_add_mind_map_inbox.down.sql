@@ -0,0 +1,3 @@
+-- Remove the inbox marker
+DROP INDEX IF EXISTS idx_mind_maps_user_inbox;
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS is_inbox;
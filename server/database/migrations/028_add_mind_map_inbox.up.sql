@@ -0,0 +1,5 @@
+-- Mark each user's inbox, the default map quick captures go to
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS is_inbox BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A user has at most one inbox that is not deleted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mind_maps_user_inbox ON mind_maps(user_id) WHERE is_inbox AND status != 'deleted';
@@ -0,0 +1,9 @@
+-- Create translation_cache table so repeated labels are only sent to the model once per
+-- language. Entries are keyed by the SHA-256 of the source text
+CREATE TABLE IF NOT EXISTS translation_cache (
+    source_hash CHAR(64) NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    translated TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (source_hash, language)
+);
@@ -0,0 +1,3 @@
+-- Remove the icon and priority columns
+ALTER TABLE nodes DROP COLUMN IF EXISTS priority;
+ALTER TABLE nodes DROP COLUMN IF EXISTS icon;
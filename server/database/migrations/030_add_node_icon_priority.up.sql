@@ -0,0 +1,13 @@
+-- Add first-class icon and priority columns to nodes so they can be filtered and sorted
+-- server-side, carrying over values previously kept in metadata
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS icon VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT ''
+    CHECK (priority IN ('', 'low', 'medium', 'high', 'urgent'));
+
+UPDATE nodes
+SET priority = LOWER(metadata->>'priority')
+WHERE LOWER(metadata->>'priority') IN ('low', 'medium', 'high', 'urgent');
+
+UPDATE nodes
+SET icon = metadata->>'icon'
+WHERE COALESCE(metadata->>'icon', '') != '' AND LENGTH(metadata->>'icon') <= 10;
@@ -0,0 +1,16 @@
+-- Remove the updated_at triggers and their function
+DO $$
+DECLARE
+    table_name TEXT;
+BEGIN
+    FOREACH table_name IN ARRAY ARRAY[
+        'users', 'orders', 'subscriptions', 'early_access', 'newsletter_subscriptions',
+        'mind_maps', 'nodes', 'api_keys', 'brainstorm_sessions', 'mind_map_thumbnails',
+        'saved_filters', 'node_templates', 'inbound_addresses'
+    ] LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS set_updated_at ON %I', table_name);
+    END LOOP;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS set_updated_at();
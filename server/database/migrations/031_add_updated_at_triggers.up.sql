@@ -0,0 +1,35 @@
+-- Keep updated_at on the database clock. Any update that does not set updated_at itself
+-- gets the transaction time, so row timestamps order consistently with each other.
+-- The trigger cannot tell an edit from a data migration, so writes that are not edits,
+-- such as backfills of new columns, must opt out or every row looks modified at migration
+-- time: either disable the trigger around them, or run them in a transaction that has
+-- SET LOCAL app.keep_updated_at = 'on', which keeps the row's updated_at as it was
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('app.keep_updated_at', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+    IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+        NEW.updated_at := NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    table_name TEXT;
+BEGIN
+    FOREACH table_name IN ARRAY ARRAY[
+        'users', 'orders', 'subscriptions', 'early_access', 'newsletter_subscriptions',
+        'mind_maps', 'nodes', 'api_keys', 'brainstorm_sessions', 'mind_map_thumbnails',
+        'saved_filters', 'node_templates', 'inbound_addresses'
+    ] LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS set_updated_at ON %I', table_name);
+        EXECUTE format(
+            'CREATE TRIGGER set_updated_at BEFORE UPDATE ON %I FOR EACH ROW EXECUTE FUNCTION set_updated_at()',
+            table_name
+        );
+    END LOOP;
+END;
+$$;
@@ -0,0 +1,3 @@
+-- Remove the mind map folder
+DROP INDEX IF EXISTS idx_mind_maps_user_folder;
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS folder;
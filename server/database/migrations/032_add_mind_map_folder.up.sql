@@ -0,0 +1,4 @@
+-- File mind maps into dashboard folders, named by a path such as "Work/Clients"
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS folder VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_mind_maps_user_folder ON mind_maps(user_id, folder);
@@ -0,0 +1,2 @@
+-- Remove the export setting
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS allow_export;
@@ -0,0 +1,2 @@
+-- Let owners stop the people a map is shared with from exporting or duplicating it
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS allow_export BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,2 @@
+-- Remove the PII redaction setting
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS redact_pii;
@@ -0,0 +1,2 @@
+-- Let owners have personal data redacted from a map's content before it is sent to AI providers
+ALTER TABLE mind_maps ADD COLUMN IF NOT EXISTS redact_pii BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- Restore the single-column indexes the composite ones replaced
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+CREATE INDEX IF NOT EXISTS idx_mind_maps_user_id ON mind_maps(user_id);
+DROP INDEX IF EXISTS idx_mind_maps_user_status_updated;
+CREATE INDEX IF NOT EXISTS idx_edges_mind_map_id ON edges(mind_map_id);
+DROP INDEX IF EXISTS idx_edges_mind_map_source_target;
@@ -0,0 +1,15 @@
+-- Indexes for the map listing and detail queries that slow down on large accounts.
+-- nodes(mind_map_id) and nodes(parent_id) already exist and are kept; api_keys(user_id, service)
+-- is covered by the unique_user_service constraint, which makes idx_api_keys_user_id redundant
+CREATE INDEX IF NOT EXISTS idx_nodes_mind_map_id ON nodes(mind_map_id);
+CREATE INDEX IF NOT EXISTS idx_nodes_parent_id ON nodes(parent_id);
+
+-- Edges of a map, and the edge between two of its nodes; replaces the mind_map_id index
+CREATE INDEX IF NOT EXISTS idx_edges_mind_map_source_target ON edges(mind_map_id, source_id, target_id);
+DROP INDEX IF EXISTS idx_edges_mind_map_id;
+
+-- A user's maps by status, newest first, as the dashboard lists them; replaces the user_id index
+CREATE INDEX IF NOT EXISTS idx_mind_maps_user_status_updated ON mind_maps(user_id, status, updated_at DESC);
+DROP INDEX IF EXISTS idx_mind_maps_user_id;
+
+DROP INDEX IF EXISTS idx_api_keys_user_id;
@@ -0,0 +1,8 @@
+-- Remove node paths and the triggers maintaining them
+DROP TRIGGER IF EXISTS move_node_subtree_paths ON nodes;
+DROP TRIGGER IF EXISTS set_node_path_on_move ON nodes;
+DROP TRIGGER IF EXISTS set_node_path ON nodes;
+DROP FUNCTION IF EXISTS move_node_subtree_paths();
+DROP FUNCTION IF EXISTS set_node_path();
+DROP INDEX IF EXISTS idx_nodes_path;
+ALTER TABLE nodes DROP COLUMN IF EXISTS path;
@@ -0,0 +1,61 @@
+-- Materialized path of every node: the IDs from its root down to the node itself. Kept up
+-- to date by triggers whenever a node is inserted or its parent changes, so subtree and
+-- ancestor queries are a single indexed lookup instead of a recursive walk of parent_id
+ALTER TABLE nodes ADD COLUMN path UUID[] NOT NULL DEFAULT '{}';
+
+-- Filling in the path of existing nodes is not an edit, so their updated_at is kept
+ALTER TABLE nodes DISABLE TRIGGER set_updated_at;
+
+WITH RECURSIVE paths AS (
+    SELECT id, ARRAY[id] AS path FROM nodes WHERE parent_id IS NULL
+    UNION ALL
+    SELECT n.id, p.path || n.id FROM nodes n JOIN paths p ON n.parent_id = p.id
+)
+UPDATE nodes SET path = paths.path FROM paths WHERE nodes.id = paths.id;
+
+-- Nodes on a parent cycle have no root to start from; they get a path of their own
+UPDATE nodes SET path = ARRAY[id] WHERE path = '{}';
+
+ALTER TABLE nodes ENABLE TRIGGER set_updated_at;
+
+CREATE INDEX idx_nodes_path ON nodes USING GIN (path);
+
+-- Set the path of a new or re-parented node from its parent's. A parent that is already
+-- below the node would make a cycle, so the node is then treated as a root
+CREATE OR REPLACE FUNCTION set_node_path() RETURNS TRIGGER AS $$
+DECLARE
+    parent_path UUID[];
+BEGIN
+    IF NEW.parent_id IS NOT NULL THEN
+        SELECT path INTO parent_path FROM nodes WHERE id = NEW.parent_id;
+    END IF;
+    IF parent_path IS NULL OR NEW.id = ANY(parent_path) THEN
+        NEW.path := ARRAY[NEW.id];
+    ELSE
+        NEW.path := parent_path || NEW.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Carry a re-parented node's new path down to everything below it. The nodes below are
+-- not edited by the move, so they keep their updated_at
+CREATE OR REPLACE FUNCTION move_node_subtree_paths() RETURNS TRIGGER AS $$
+DECLARE
+    keep_updated_at TEXT := current_setting('app.keep_updated_at', true);
+BEGIN
+    PERFORM set_config('app.keep_updated_at', 'on', true);
+    UPDATE nodes
+    SET path = NEW.path || path[array_length(OLD.path, 1) + 1:]
+    WHERE path @> ARRAY[NEW.id] AND id <> NEW.id;
+    PERFORM set_config('app.keep_updated_at', COALESCE(keep_updated_at, ''), true);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_node_path BEFORE INSERT ON nodes
+    FOR EACH ROW EXECUTE FUNCTION set_node_path();
+CREATE TRIGGER set_node_path_on_move BEFORE UPDATE OF parent_id ON nodes
+    FOR EACH ROW WHEN (NEW.parent_id IS DISTINCT FROM OLD.parent_id) EXECUTE FUNCTION set_node_path();
+CREATE TRIGGER move_node_subtree_paths AFTER UPDATE OF parent_id ON nodes
+    FOR EACH ROW WHEN (NEW.parent_id IS DISTINCT FROM OLD.parent_id) EXECUTE FUNCTION move_node_subtree_paths();
@@ -0,0 +1,4 @@
+-- Drop the outbox of change events
+DROP INDEX IF EXISTS idx_outbox_events_published_at;
+DROP INDEX IF EXISTS idx_outbox_events_unpublished;
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,19 @@
+-- Outbox of change events waiting to be delivered to realtime subscribers. Rows are written
+-- in the same transaction as the change they describe and published by a dispatcher, so an
+-- event is never lost after its change commits nor sent for a change that rolled back.
+-- There is no foreign key to mind_maps so a map's deleted event outlives the map
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    mind_map_id UUID NOT NULL,
+    seq BIGINT NOT NULL DEFAULT 0,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create index for the dispatcher's scan of unpublished events
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
+
+-- Create index for pruning published events
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events(published_at);
@@ -0,0 +1,4 @@
+-- Drop the AI usage records
+DROP INDEX IF EXISTS idx_ai_usage_mind_map_id;
+DROP INDEX IF EXISTS idx_ai_usage_user_created_at;
+DROP TABLE IF EXISTS ai_usage;
@@ -0,0 +1,20 @@
+-- Create ai_usage table recording the tokens, model and latency of every AI call, so usage
+-- can be audited and billed. There is no foreign key to mind_maps so usage stays
+-- attributed to a map after the map is deleted
+CREATE TABLE IF NOT EXISTS ai_usage (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    mind_map_id UUID,
+    feature VARCHAR(32) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    model VARCHAR(255) NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes for usage by user over time and by mind map
+CREATE INDEX IF NOT EXISTS idx_ai_usage_user_created_at ON ai_usage(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_usage_mind_map_id ON ai_usage(mind_map_id);
@@ -0,0 +1,23 @@
+-- Remove tenants and the tenant columns
+DROP TRIGGER IF EXISTS set_tenant ON edges;
+DROP TRIGGER IF EXISTS set_tenant ON nodes;
+DROP TRIGGER IF EXISTS set_tenant ON api_keys;
+DROP TRIGGER IF EXISTS set_tenant ON mind_maps;
+DROP FUNCTION IF EXISTS set_tenant_from_mind_map();
+DROP FUNCTION IF EXISTS set_tenant_from_user();
+
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS fk_api_keys_user_tenant;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE edges DROP CONSTRAINT IF EXISTS fk_edges_mind_map_tenant;
+ALTER TABLE edges DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE nodes DROP CONSTRAINT IF EXISTS fk_nodes_mind_map_tenant;
+ALTER TABLE nodes DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS idx_mind_maps_tenant_id;
+ALTER TABLE mind_maps DROP CONSTRAINT IF EXISTS fk_mind_maps_user_tenant;
+ALTER TABLE mind_maps DROP CONSTRAINT IF EXISTS mind_maps_id_tenant_key;
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS tenant_id;
+DROP INDEX IF EXISTS idx_users_tenant_id;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_id_tenant_key;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,80 @@
+-- Tenants are the organizations whose data is kept apart. Every user belongs to one, and
+-- mind maps, nodes, edges and API keys carry their owner's tenant so queries can be scoped
+-- to it. Composite foreign keys keep a row's tenant equal to its parent's: a node cannot
+-- be moved into another tenant's map, and moving a user to another tenant carries their
+-- maps, nodes, edges and keys along. Existing users join the default tenant, so nothing
+-- changes for them until they are moved to one of their own
+CREATE TABLE IF NOT EXISTS tenants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO tenants (id, name) VALUES ('00000000-0000-0000-0000-000000000000', 'Default')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000'
+    REFERENCES tenants(id);
+ALTER TABLE users ADD CONSTRAINT users_id_tenant_key UNIQUE (id, tenant_id);
+CREATE INDEX idx_users_tenant_id ON users(tenant_id);
+
+-- Filling in the tenant of existing rows is not an edit, so their updated_at is kept
+ALTER TABLE mind_maps ADD COLUMN tenant_id UUID;
+ALTER TABLE mind_maps DISABLE TRIGGER set_updated_at;
+UPDATE mind_maps m SET tenant_id = u.tenant_id FROM users u WHERE u.id = m.user_id;
+ALTER TABLE mind_maps ENABLE TRIGGER set_updated_at;
+ALTER TABLE mind_maps ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE mind_maps ADD CONSTRAINT mind_maps_id_tenant_key UNIQUE (id, tenant_id);
+ALTER TABLE mind_maps ADD CONSTRAINT fk_mind_maps_user_tenant FOREIGN KEY (user_id, tenant_id)
+    REFERENCES users(id, tenant_id) ON UPDATE CASCADE;
+CREATE INDEX idx_mind_maps_tenant_id ON mind_maps(tenant_id);
+
+ALTER TABLE nodes ADD COLUMN tenant_id UUID;
+ALTER TABLE nodes DISABLE TRIGGER set_updated_at;
+UPDATE nodes n SET tenant_id = m.tenant_id FROM mind_maps m WHERE m.id = n.mind_map_id;
+ALTER TABLE nodes ENABLE TRIGGER set_updated_at;
+ALTER TABLE nodes ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE nodes ADD CONSTRAINT fk_nodes_mind_map_tenant FOREIGN KEY (mind_map_id, tenant_id)
+    REFERENCES mind_maps(id, tenant_id) ON UPDATE CASCADE;
+
+ALTER TABLE edges ADD COLUMN tenant_id UUID;
+UPDATE edges e SET tenant_id = m.tenant_id FROM mind_maps m WHERE m.id = e.mind_map_id;
+ALTER TABLE edges ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE edges ADD CONSTRAINT fk_edges_mind_map_tenant FOREIGN KEY (mind_map_id, tenant_id)
+    REFERENCES mind_maps(id, tenant_id) ON UPDATE CASCADE;
+
+ALTER TABLE api_keys ADD COLUMN tenant_id UUID;
+ALTER TABLE api_keys DISABLE TRIGGER set_updated_at;
+UPDATE api_keys k SET tenant_id = u.tenant_id FROM users u WHERE u.id = k.user_id;
+ALTER TABLE api_keys ENABLE TRIGGER set_updated_at;
+ALTER TABLE api_keys ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE api_keys ADD CONSTRAINT fk_api_keys_user_tenant FOREIGN KEY (user_id, tenant_id)
+    REFERENCES users(id, tenant_id) ON UPDATE CASCADE;
+
+-- Fill in the tenant of new rows from their owner or mind map, so inserts need not name it
+CREATE OR REPLACE FUNCTION set_tenant_from_user() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.tenant_id IS NULL THEN
+        SELECT tenant_id INTO NEW.tenant_id FROM users WHERE id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_tenant_from_mind_map() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.tenant_id IS NULL THEN
+        SELECT tenant_id INTO NEW.tenant_id FROM mind_maps WHERE id = NEW.mind_map_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_tenant BEFORE INSERT ON mind_maps
+    FOR EACH ROW EXECUTE FUNCTION set_tenant_from_user();
+CREATE TRIGGER set_tenant BEFORE INSERT ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION set_tenant_from_user();
+CREATE TRIGGER set_tenant BEFORE INSERT ON nodes
+    FOR EACH ROW EXECUTE FUNCTION set_tenant_from_mind_map();
+CREATE TRIGGER set_tenant BEFORE INSERT ON edges
+    FOR EACH ROW EXECUTE FUNCTION set_tenant_from_mind_map();
@@ -0,0 +1,5 @@
+-- Remove single sign-on connections and tenant roles
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_role;
+DROP TABLE IF EXISTS sso_identities;
+DROP TABLE IF EXISTS sso_connection_domains;
+DROP TABLE IF EXISTS sso_connections;
@@ -0,0 +1,44 @@
+-- Single sign-on connections let a tenant sign its users in through its own OpenID Connect
+-- identity provider. Users whose email domain belongs to a connection are assigned to its
+-- tenant on sign-in, their role in the tenant follows the groups the provider reports, and
+-- an enforced connection stops them from signing in any other way
+CREATE TABLE IF NOT EXISTS sso_connections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    issuer TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    encrypted_client_secret TEXT NOT NULL,
+    groups_claim VARCHAR(100) NOT NULL DEFAULT 'groups',
+    group_roles JSONB NOT NULL DEFAULT '{}',
+    default_role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (default_role IN ('admin', 'member', 'viewer')),
+    enforced BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_sso_connections_tenant_id ON sso_connections(tenant_id);
+
+-- Each email domain signs in through at most one connection
+CREATE TABLE IF NOT EXISTS sso_connection_domains (
+    domain VARCHAR(255) PRIMARY KEY,
+    connection_id UUID NOT NULL REFERENCES sso_connections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sso_connection_domains_connection_id ON sso_connection_domains(connection_id);
+
+-- Identities link a provider's subject to the user it was provisioned as, so a user whose
+-- email changes at the provider keeps their account
+CREATE TABLE IF NOT EXISTS sso_identities (
+    connection_id UUID NOT NULL REFERENCES sso_connections(id) ON DELETE CASCADE,
+    subject TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (connection_id, subject)
+);
+
+CREATE INDEX idx_sso_identities_user_id ON sso_identities(user_id);
+
+ALTER TABLE users ADD COLUMN tenant_role VARCHAR(20) NOT NULL DEFAULT 'member'
+    CHECK (tenant_role IN ('admin', 'member', 'viewer'));
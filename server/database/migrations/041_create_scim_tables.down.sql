@@ -0,0 +1,5 @@
+-- Remove SCIM groups, tokens and user deactivation
+DROP TABLE IF EXISTS scim_group_members;
+DROP TABLE IF EXISTS scim_groups;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+DROP TABLE IF EXISTS scim_tokens;
@@ -0,0 +1,29 @@
+-- SCIM lets a tenant's identity provider create, update and deactivate its users and
+-- groups. The provider authenticates with a bearer token of the tenant, stored hashed.
+-- Deactivated users keep their data but cannot sign in or use existing sessions, and the
+-- roles of group members follow the tenant's SSO group-to-role mapping
+CREATE TABLE IF NOT EXISTS scim_tokens (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    display_name VARCHAR(255) NOT NULL,
+    external_id TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT unique_scim_group_name UNIQUE (tenant_id, display_name)
+);
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX idx_scim_group_members_user_id ON scim_group_members(user_id);
@@ -0,0 +1,2 @@
+-- Remove generation jobs
+DROP TABLE IF EXISTS generation_jobs;
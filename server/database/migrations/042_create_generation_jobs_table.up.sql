@@ -0,0 +1,23 @@
+-- Generation jobs run idea generations, possibly several levels deep, in the background
+-- while the client polls for their status. A job's inline API key is kept encrypted until
+-- it finishes. Jobs are claimed with row locks, so each runs on one instance, and a job
+-- left running by an instance that stopped is queued again
+CREATE TABLE IF NOT EXISTS generation_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mind_map_id UUID NOT NULL REFERENCES mind_maps(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    request JSONB NOT NULL,
+    encrypted_api_key TEXT,
+    result JSONB,
+    error TEXT,
+    completed_steps INTEGER NOT NULL DEFAULT 0,
+    total_steps INTEGER NOT NULL DEFAULT 1,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_generation_jobs_pending ON generation_jobs(created_at) WHERE status IN ('queued', 'running');
+CREATE INDEX idx_generation_jobs_user_id ON generation_jobs(user_id, created_at);
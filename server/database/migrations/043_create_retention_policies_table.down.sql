@@ -0,0 +1,6 @@
+-- Remove the trash timestamp and retention policies
+DROP INDEX IF EXISTS idx_mind_maps_deleted_at;
+DROP TRIGGER IF EXISTS set_deleted_at ON mind_maps;
+DROP FUNCTION IF EXISTS set_deleted_at();
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS deleted_at;
+DROP TABLE IF EXISTS retention_policies;
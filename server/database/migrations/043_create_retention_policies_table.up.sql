@@ -0,0 +1,41 @@
+-- Retention policies of tenants. Each rule is optional and off while NULL: deleted mind
+-- maps are purged for good purge_trash_after_days after they were deleted, maps without
+-- activity for archive_after_days are archived, and each map keeps only its latest
+-- history_depth change log entries
+CREATE TABLE IF NOT EXISTS retention_policies (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    purge_trash_after_days INTEGER CHECK (purge_trash_after_days > 0),
+    archive_after_days INTEGER CHECK (archive_after_days > 0),
+    history_depth INTEGER CHECK (history_depth > 0),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER set_updated_at BEFORE UPDATE ON retention_policies
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- When a mind map was moved to the trash. Every write that deletes a map sets its status,
+-- so a trigger keeps the timestamp rather than each of them. Maps deleted before now count
+-- as deleted at their last update, which the backfill must not move
+ALTER TABLE mind_maps ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE mind_maps DISABLE TRIGGER set_updated_at;
+UPDATE mind_maps SET deleted_at = updated_at WHERE status = 'deleted';
+ALTER TABLE mind_maps ENABLE TRIGGER set_updated_at;
+
+CREATE OR REPLACE FUNCTION set_deleted_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status = 'deleted' AND OLD.status IS DISTINCT FROM 'deleted' THEN
+        NEW.deleted_at := NOW();
+    ELSIF NEW.status != 'deleted' THEN
+        NEW.deleted_at := NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_deleted_at BEFORE UPDATE OF status ON mind_maps
+    FOR EACH ROW EXECUTE FUNCTION set_deleted_at();
+
+-- Create index for finding the trash to purge
+CREATE INDEX IF NOT EXISTS idx_mind_maps_deleted_at ON mind_maps(tenant_id, deleted_at) WHERE status = 'deleted';
@@ -0,0 +1,2 @@
+-- Remove generation history
+DROP TABLE IF EXISTS generations;
@@ -0,0 +1,17 @@
+-- Generations keep every idea generation of a mind map, with the request that produced it
+-- (without its API key) and the ideas it returned, so users can revisit, re-run or
+-- re-insert past brainstorms. A generation outlives the user who made it
+CREATE TABLE IF NOT EXISTS generations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mind_map_id UUID NOT NULL REFERENCES mind_maps(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    job_id UUID,
+    request JSONB NOT NULL,
+    ideas JSONB NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for listing a map's generations, newest first
+CREATE INDEX IF NOT EXISTS idx_generations_mind_map_id ON generations(mind_map_id, created_at DESC);
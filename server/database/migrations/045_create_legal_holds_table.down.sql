@@ -0,0 +1,5 @@
+-- Remove the audit log and legal holds
+DROP TABLE IF EXISTS audit_events;
+DROP TRIGGER IF EXISTS prevent_held_delete ON mind_maps;
+DROP FUNCTION IF EXISTS prevent_held_mind_map_delete();
+DROP TABLE IF EXISTS legal_holds;
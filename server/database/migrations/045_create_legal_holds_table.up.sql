@@ -0,0 +1,49 @@
+-- Legal holds preserve a user's mind maps for litigation or investigations. While a user
+-- has an active hold their maps can still be moved to the trash but never deleted for
+-- good: retention purges skip them and a trigger rejects any other permanent delete,
+-- including the cascade from deleting the user. Releasing a hold keeps its row as a record,
+-- and holds name their user without a foreign key so the record outlives them too
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    released_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    released_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A user has at most one active hold
+CREATE UNIQUE INDEX IF NOT EXISTS idx_legal_holds_active_user ON legal_holds(user_id) WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_legal_holds_tenant_id ON legal_holds(tenant_id, created_at);
+
+CREATE OR REPLACE FUNCTION prevent_held_mind_map_delete() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM legal_holds WHERE user_id = OLD.user_id AND released_at IS NULL) THEN
+        RAISE EXCEPTION 'mind map % is under legal hold', OLD.id USING ERRCODE = 'P0001';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_held_delete BEFORE DELETE ON mind_maps
+    FOR EACH ROW EXECUTE FUNCTION prevent_held_mind_map_delete();
+
+-- Audit log of what organization admins did with their members' data. Entries outlive the
+-- admins and users they name
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    actor_id UUID,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for reading a tenant's audit log, newest first
+CREATE INDEX IF NOT EXISTS idx_audit_events_tenant_id ON audit_events(tenant_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- Remove idea feedback
+DROP TABLE IF EXISTS idea_feedback;
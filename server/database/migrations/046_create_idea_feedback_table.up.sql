@@ -0,0 +1,22 @@
+-- Feedback on generated ideas: whether a user accepted or rejected them. A map's most
+-- recent examples of each are sent with its next generations to steer them. A user's
+-- verdict on an idea replaces their earlier one, ideas being told apart by their content
+CREATE TABLE IF NOT EXISTS idea_feedback (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mind_map_id UUID NOT NULL REFERENCES mind_maps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    generation_id UUID REFERENCES generations(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    verdict VARCHAR(10) NOT NULL CHECK (verdict IN ('accepted', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idea_feedback_idea ON idea_feedback(mind_map_id, user_id, md5(content));
+
+-- Create index for picking a map's recent examples of each verdict
+CREATE INDEX IF NOT EXISTS idx_idea_feedback_recent ON idea_feedback(mind_map_id, verdict, updated_at DESC);
+
+CREATE TRIGGER set_updated_at BEFORE UPDATE ON idea_feedback
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,3 @@
+-- Remove AI spend caps
+DROP INDEX IF EXISTS idx_ai_usage_mind_map_created_at;
+DROP TABLE IF EXISTS ai_spend_caps;
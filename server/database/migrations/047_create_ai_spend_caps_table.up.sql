@@ -0,0 +1,22 @@
+-- AI spend caps of mind maps, set by their owners. Each limit is optional and off while
+-- NULL: the map's AI usage, or that of each brainstorm session on it, may not go beyond
+-- the estimated cost in US dollars or the total tokens. Once a limit is reached further
+-- generations are refused, or with on_exceed 'downgrade' made with the provider's
+-- cheapest model
+CREATE TABLE IF NOT EXISTS ai_spend_caps (
+    mind_map_id UUID PRIMARY KEY REFERENCES mind_maps(id) ON DELETE CASCADE,
+    max_cost_usd NUMERIC(12, 4) CHECK (max_cost_usd > 0),
+    max_tokens BIGINT CHECK (max_tokens > 0),
+    session_max_cost_usd NUMERIC(12, 4) CHECK (session_max_cost_usd > 0),
+    session_max_tokens BIGINT CHECK (session_max_tokens > 0),
+    on_exceed VARCHAR(10) NOT NULL DEFAULT 'refuse' CHECK (on_exceed IN ('refuse', 'downgrade')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER set_updated_at BEFORE UPDATE ON ai_spend_caps
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Create index for summing a map's usage since a session started
+CREATE INDEX IF NOT EXISTS idx_ai_usage_mind_map_created_at ON ai_usage(mind_map_id, created_at);
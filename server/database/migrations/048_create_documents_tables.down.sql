@@ -0,0 +1,3 @@
+-- Remove uploaded documents
+DROP TABLE IF EXISTS document_chunks;
+DROP TABLE IF EXISTS mind_map_documents;
@@ -0,0 +1,29 @@
+-- Documents uploaded to mind maps to ground generation in. Only their text is kept, split
+-- into chunks with an embedding each. Chunks embedded without an API key have no
+-- embedding and are matched by their words instead
+CREATE TABLE IF NOT EXISTS mind_map_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mind_map_id UUID NOT NULL REFERENCES mind_maps(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    chunk_count INTEGER NOT NULL,
+    embedded BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mind_map_documents_mind_map_id ON mind_map_documents(mind_map_id, created_at);
+
+CREATE TABLE IF NOT EXISTS document_chunks (
+    id BIGSERIAL PRIMARY KEY,
+    document_id UUID NOT NULL REFERENCES mind_map_documents(id) ON DELETE CASCADE,
+    mind_map_id UUID NOT NULL REFERENCES mind_maps(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    embedding REAL[],
+    UNIQUE (document_id, chunk_index)
+);
+
+-- Create index for retrieving the chunks of a map
+CREATE INDEX IF NOT EXISTS idx_document_chunks_mind_map_id ON document_chunks(mind_map_id);
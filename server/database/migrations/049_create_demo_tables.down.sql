@@ -0,0 +1,3 @@
+-- Remove demo maps
+DROP TABLE IF EXISTS demo_nodes;
+DROP TABLE IF EXISTS demo_maps;
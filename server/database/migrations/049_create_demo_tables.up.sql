@@ -0,0 +1,27 @@
+-- Ephemeral maps of anonymous visitors trying the app without signing up. They are kept
+-- apart from mind_maps so no other feature sees them, are reached with a secret token
+-- stored as its SHA-256 hash, and are purged once expires_at has passed
+CREATE TABLE IF NOT EXISTS demo_maps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Nodes of demo maps; the first node of a map is its root
+CREATE TABLE IF NOT EXISTS demo_nodes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    demo_map_id UUID NOT NULL REFERENCES demo_maps(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES demo_nodes(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    position_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+    position_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for purging expired maps, limiting maps per client and listing nodes
+CREATE INDEX IF NOT EXISTS idx_demo_maps_expires_at ON demo_maps(expires_at);
+CREATE INDEX IF NOT EXISTS idx_demo_maps_client_ip ON demo_maps(client_ip);
+CREATE INDEX IF NOT EXISTS idx_demo_nodes_demo_map_id ON demo_nodes(demo_map_id);
@@ -0,0 +1,5 @@
+-- Restore the public flag, keeping only public maps public
+DROP INDEX IF EXISTS idx_mind_maps_public;
+ALTER TABLE mind_maps ADD COLUMN is_public BOOLEAN DEFAULT FALSE;
+UPDATE mind_maps SET is_public = (visibility = 'public');
+ALTER TABLE mind_maps DROP COLUMN IF EXISTS visibility;
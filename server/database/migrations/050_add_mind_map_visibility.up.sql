@@ -0,0 +1,14 @@
+-- Replace the public flag of mind maps with a visibility level: private maps are seen by
+-- their owner and the people they are shared with, link maps by anyone signed in who has
+-- the link, workspace maps by everyone in the owner's organization and public maps by
+-- everyone, and they are listed in the public gallery. Public maps stay public
+ALTER TABLE mind_maps ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('private', 'link', 'workspace', 'public'));
+-- Carrying the flag over is not an edit, so maps keep their updated_at, which the public
+-- gallery is ordered by
+ALTER TABLE mind_maps DISABLE TRIGGER set_updated_at;
+UPDATE mind_maps SET visibility = 'public' WHERE is_public;
+ALTER TABLE mind_maps ENABLE TRIGGER set_updated_at;
+ALTER TABLE mind_maps DROP COLUMN is_public;
+
+CREATE INDEX idx_mind_maps_public ON mind_maps(updated_at DESC) WHERE visibility = 'public';
@@ -0,0 +1,2 @@
+-- Remove node embeddings; the vector extension is left installed
+DROP TABLE IF EXISTS node_embeddings;
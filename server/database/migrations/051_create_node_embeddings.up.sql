@@ -0,0 +1,18 @@
+-- Embeddings of node content for semantic search, kept up to date by a background job.
+-- content_hash is the MD5 of the content that was embedded, so nodes whose content changed
+-- since are embedded again. A row without an embedding records that the content could not
+-- be embedded, for instance because the map's owner has no API key; those nodes are
+-- retried when the content changes or the owner searches
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS node_embeddings (
+    node_id UUID PRIMARY KEY REFERENCES nodes(id) ON DELETE CASCADE,
+    content_hash CHAR(32) NOT NULL,
+    model VARCHAR(64),
+    embedding vector(1536),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for nearest-neighbour search by cosine distance
+CREATE INDEX IF NOT EXISTS idx_node_embeddings_embedding ON node_embeddings
+    USING hnsw (embedding vector_cosine_ops);
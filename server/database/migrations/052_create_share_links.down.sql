@@ -0,0 +1,3 @@
+-- Remove share links and their view sessions
+DROP TABLE IF EXISTS share_link_sessions;
+DROP TABLE IF EXISTS share_links;
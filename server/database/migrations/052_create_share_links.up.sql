@@ -0,0 +1,25 @@
+-- Share links let anyone with the link view a mind map without signing in. Only a hash of
+-- the link's token is kept. A link can have a password, stored as a bcrypt hash, and an
+-- expiry; viewers of a link with a password trade it for a short-lived view session
+CREATE TABLE IF NOT EXISTS share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mind_map_id UUID NOT NULL REFERENCES mind_maps(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    password_hash VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for listing the links of a map
+CREATE INDEX IF NOT EXISTS idx_share_links_mind_map_id ON share_links(mind_map_id, created_at);
+
+CREATE TABLE IF NOT EXISTS share_link_sessions (
+    token_hash CHAR(64) PRIMARY KEY,
+    share_link_id UUID NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for purging expired sessions
+CREATE INDEX IF NOT EXISTS idx_share_link_sessions_expires_at ON share_link_sessions(expires_at);
@@ -0,0 +1,3 @@
+-- Remove share link analytics and revocation
+DROP TABLE IF EXISTS share_link_events;
+ALTER TABLE share_links DROP COLUMN IF EXISTS revoked_at;
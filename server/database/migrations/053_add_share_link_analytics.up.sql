@@ -0,0 +1,15 @@
+-- Record how share links are used, so owners can spot a leaked link, and let them revoke
+-- links. Revoked links stop working but keep their history. Referrers are kept as the
+-- host of the referring page only
+ALTER TABLE share_links ADD COLUMN revoked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS share_link_events (
+    id BIGSERIAL PRIMARY KEY,
+    share_link_id UUID NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+    event_type VARCHAR(10) NOT NULL CHECK (event_type IN ('open', 'export')),
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for the analytics of a link
+CREATE INDEX IF NOT EXISTS idx_share_link_events_link_created ON share_link_events(share_link_id, created_at);
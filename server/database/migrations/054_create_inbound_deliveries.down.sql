@@ -0,0 +1,2 @@
+-- Remove the record of inbound deliveries
+DROP TABLE IF EXISTS inbound_deliveries;
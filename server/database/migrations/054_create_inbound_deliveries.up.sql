@@ -0,0 +1,9 @@
+-- Remember the provider tokens of signed inbound emails while their signatures are still
+-- accepted, so a captured delivery cannot be replayed to create its nodes again
+CREATE TABLE IF NOT EXISTS inbound_deliveries (
+    token VARCHAR(255) PRIMARY KEY,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for pruning old deliveries
+CREATE INDEX IF NOT EXISTS idx_inbound_deliveries_received_at ON inbound_deliveries(received_at);
@@ -0,0 +1,2 @@
+-- Remove tenant AI usage policies
+DROP TABLE IF EXISTS tenant_ai_policies;
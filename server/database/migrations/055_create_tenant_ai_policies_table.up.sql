@@ -0,0 +1,16 @@
+-- AI usage policies of tenants. Each setting overrides the server's AI_* default while set
+-- and inherits it while NULL: allowed_providers and allowed_regions limit where content may
+-- be sent, empty allowing any, and allow_inline_keys decides whether requests may bring
+-- their own API key
+CREATE TABLE IF NOT EXISTS tenant_ai_policies (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    allowed_providers TEXT[],
+    allowed_regions TEXT[],
+    allow_inline_keys BOOLEAN,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER set_updated_at BEFORE UPDATE ON tenant_ai_policies
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,8 @@
+-- Remove SAML connections and their metadata
+DELETE FROM sso_connections WHERE protocol = 'saml';
+
+ALTER TABLE sso_connections
+    DROP CONSTRAINT IF EXISTS sso_connections_saml_metadata,
+    DROP COLUMN IF EXISTS idp_metadata,
+    DROP COLUMN IF EXISTS idp_metadata_url,
+    DROP COLUMN IF EXISTS protocol;
@@ -0,0 +1,8 @@
+-- SAML connections sign users in through an identity provider described by its SAML
+-- metadata instead of OpenID Connect discovery. Their issuer is the provider's entity ID
+-- and they have no client ID or secret
+ALTER TABLE sso_connections
+    ADD COLUMN protocol VARCHAR(10) NOT NULL DEFAULT 'oidc' CHECK (protocol IN ('oidc', 'saml')),
+    ADD COLUMN idp_metadata_url TEXT,
+    ADD COLUMN idp_metadata TEXT,
+    ADD CONSTRAINT sso_connections_saml_metadata CHECK (protocol <> 'saml' OR idp_metadata IS NOT NULL);
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"errors"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrNotMindMapOwner is returned when a bulk operation names a map the user does not own
+var ErrNotMindMapOwner = errors.New("mind map is not owned by the user")
+
+// BulkUpdateMindMaps applies a bulk operation to mind maps owned by userID in a single
+// transaction. The maps are locked and their ownership checked first, so the operation
+// applies to all of them or none. Deleted and missing maps fail with ErrNotFound. Records a
+// mind_map.deleted or mind_map.updated change for each map in the same transaction. Returns
+// the updated maps, or only their IDs for deletes
+func (db *DB) BulkUpdateMindMaps(userID string, req models.MindMapBulkRequest) (*models.MindMapBulkResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := pq.Array(req.MindMapIDs)
+	rows, err := tx.Query(`
+		SELECT user_id
+		FROM mind_maps
+		WHERE id = ANY($1) AND status != 'deleted'
+		ORDER BY id
+		FOR UPDATE`, ids)
+	if err != nil {
+		return nil, err
+	}
+	found := 0
+	for rows.Next() {
+		var ownerID string
+		if err := rows.Scan(&ownerID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		found++
+		if ownerID != userID {
+			rows.Close()
+			return nil, ErrNotMindMapOwner
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if found != len(req.MindMapIDs) {
+		return nil, ErrNotFound
+	}
+
+	var query string
+	var value interface{}
+	switch req.Operation {
+	case models.MindMapBulkArchive:
+		query, value = `UPDATE mind_maps SET status = $2, updated_at = NOW() WHERE id = ANY($1)`, models.MindMapStatusArchived
+	case models.MindMapBulkDelete:
+		query, value = `UPDATE mind_maps SET status = $2, updated_at = NOW() WHERE id = ANY($1)`, models.MindMapStatusDeleted
+	case models.MindMapBulkMoveToFolder:
+		query, value = `UPDATE mind_maps SET folder = $2, updated_at = NOW() WHERE id = ANY($1)`, req.Folder
+	}
+
+	rows, err = tx.Query(query+` RETURNING `+mindMapColumns, ids, value)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.MindMapBulkResult{Operation: req.Operation, MindMapIDs: []string{}}
+	for rows.Next() {
+		mindMap, err := scanMindMap(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.MindMapIDs = append(result.MindMapIDs, mindMap.ID)
+		if req.Operation != models.MindMapBulkDelete {
+			result.MindMaps = append(result.MindMaps, *mindMap)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, id := range result.MindMapIDs {
+		if req.Operation == models.MindMapBulkDelete {
+			_, err = recordChange(tx, id, "mind_map.deleted", map[string]string{"id": id})
+		} else {
+			_, err = recordChange(tx, id, "mind_map.updated", result.MindMaps[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return result, nil
+}
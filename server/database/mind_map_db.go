@@ -5,79 +5,100 @@ import (
 	"encoding/json"
 	"fmt"
 	"saas-server/models"
-	"time"
+	"saas-server/pkg/fields"
+	"saas-server/pkg/graphclone"
 
 	"github.com/google/uuid"
 )
 
-// CreateMindMap creates a new mind map in the database
-func (db *DB) CreateMindMap(userID string, req models.MindMapCreateRequest) (*models.MindMap, error) {
-	id := uuid.New().String()
-	now := time.Now()
-
-	query := `
-		INSERT INTO mind_maps (id, user_id, title, description, is_public, created_at, updated_at, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, user_id, title, description, is_public, status, created_at, updated_at`
+// mindMapColumns lists the mind map columns in the order scanMindMap expects
+const mindMapColumns = `id, user_id, title, description, visibility, status, vote_limit, icon, cover_image, layout_mode, is_inbox, folder, allow_export, redact_pii, change_seq, created_at, updated_at`
 
+// scanMindMap scans a mind map row selected with mindMapColumns, followed by any extra columns
+func scanMindMap(row rowScanner, extra ...interface{}) (*models.MindMap, error) {
 	var mindMap models.MindMap
-	err := db.QueryRow(
-		query,
-		id,
-		userID,
-		req.Title,
-		req.Description,
-		req.IsPublic,
-		now,
-		now,
-		"active",
-	).Scan(
+	dest := []interface{}{
 		&mindMap.ID,
 		&mindMap.UserID,
 		&mindMap.Title,
 		&mindMap.Description,
-		&mindMap.IsPublic,
+		&mindMap.Visibility,
 		&mindMap.Status,
+		&mindMap.VoteLimit,
+		&mindMap.Icon,
+		&mindMap.CoverImage,
+		&mindMap.LayoutMode,
+		&mindMap.IsInbox,
+		&mindMap.Folder,
+		&mindMap.AllowExport,
+		&mindMap.RedactPII,
+		&mindMap.ChangeSeq,
 		&mindMap.CreatedAt,
 		&mindMap.UpdatedAt,
-	)
-	if err != nil {
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
 		return nil, err
 	}
 	return &mindMap, nil
 }
 
-// GetMindMapsByUserID retrieves all mind maps for a specific user
-func (db *DB) GetMindMapsByUserID(userID string) ([]models.MindMap, error) {
+// CreateMindMap creates a new mind map in the database
+func (db *DB) CreateMindMap(userID string, req models.MindMapCreateRequest) (*models.MindMap, error) {
+	return createMindMapTx(db, userID, req)
+}
+
+// createMindMapTx creates a mind map using either the connection pool or a transaction
+func createMindMapTx(q queryRower, userID string, req models.MindMapCreateRequest) (*models.MindMap, error) {
+	id := uuid.New().String()
+	if req.Visibility == "" {
+		req.Visibility = models.VisibilityPrivate
+	}
+
 	query := `
-		SELECT id, user_id, title, description, is_public, status, created_at, updated_at
+		INSERT INTO mind_maps (id, user_id, title, description, visibility, created_at, updated_at, status)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW(), $6)
+		RETURNING ` + mindMapColumns
+
+	return scanMindMap(q.QueryRow(
+		query,
+		id,
+		userID,
+		req.Title,
+		req.Description,
+		req.Visibility,
+		models.MindMapStatusActive,
+	))
+}
+
+// GetMindMapsByUserID retrieves all mind maps for a specific user along with their node
+// and edge counts, in a single query so listing does not need a query per map.
+// An empty status returns every map that is not deleted
+func (db *DB) GetMindMapsByUserID(userID, status string) ([]models.MindMapSummary, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{userID, status})
+	query := `
+		SELECT ` + mindMapColumns + `,
+			(SELECT COUNT(*) FROM nodes n WHERE n.mind_map_id = mind_maps.id) AS node_count,
+			(SELECT COUNT(*) FROM edges e WHERE e.mind_map_id = mind_maps.id) AS edge_count
 		FROM mind_maps
 		WHERE user_id = $1 AND status != 'deleted'
+		AND ($2 = '' OR status = $2)` + scope + `
 		ORDER BY updated_at DESC`
 
-	rows, err := db.Query(query, userID)
+	rows, err := db.reader().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var mindMaps []models.MindMap
+	var mindMaps []models.MindMapSummary
 	for rows.Next() {
-		var mindMap models.MindMap
-		err := rows.Scan(
-			&mindMap.ID,
-			&mindMap.UserID,
-			&mindMap.Title,
-			&mindMap.Description,
-			&mindMap.IsPublic,
-			&mindMap.Status,
-			&mindMap.CreatedAt,
-			&mindMap.UpdatedAt,
-		)
+		var summary models.MindMapSummary
+		mindMap, err := scanMindMap(rows, &summary.NodeCount, &summary.EdgeCount)
 		if err != nil {
 			return nil, err
 		}
-		mindMaps = append(mindMaps, mindMap)
+		summary.MindMap = *mindMap
+		mindMaps = append(mindMaps, summary)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -87,96 +108,81 @@ func (db *DB) GetMindMapsByUserID(userID string) ([]models.MindMap, error) {
 	return mindMaps, nil
 }
 
-// GetMindMapByID retrieves a specific mind map by its ID
-func (db *DB) GetMindMapByID(id string) (*models.MindMap, error) {
+// GetPublicMindMaps retrieves up to limit public active mind maps with their node and edge
+// counts, most recently updated first, for the public gallery
+func (db *DB) GetPublicMindMaps(limit int) ([]models.MindMapSummary, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{models.VisibilityPublic, models.MindMapStatusActive, limit})
 	query := `
-		SELECT id, user_id, title, description, is_public, status, created_at, updated_at
+		SELECT ` + mindMapColumns + `,
+			(SELECT COUNT(*) FROM nodes n WHERE n.mind_map_id = mind_maps.id) AS node_count,
+			(SELECT COUNT(*) FROM edges e WHERE e.mind_map_id = mind_maps.id) AS edge_count
 		FROM mind_maps
-		WHERE id = $1 AND status != 'deleted'`
+		WHERE visibility = $1 AND status = $2` + scope + `
+		ORDER BY updated_at DESC
+		LIMIT $3`
 
-	var mindMap models.MindMap
-	err := db.QueryRow(query, id).Scan(
-		&mindMap.ID,
-		&mindMap.UserID,
-		&mindMap.Title,
-		&mindMap.Description,
-		&mindMap.IsPublic,
-		&mindMap.Status,
-		&mindMap.CreatedAt,
-		&mindMap.UpdatedAt,
-	)
+	rows, err := db.reader().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &mindMap, nil
+	defer rows.Close()
+
+	mindMaps := []models.MindMapSummary{}
+	for rows.Next() {
+		var summary models.MindMapSummary
+		mindMap, err := scanMindMap(rows, &summary.NodeCount, &summary.EdgeCount)
+		if err != nil {
+			return nil, err
+		}
+		summary.MindMap = *mindMap
+		mindMaps = append(mindMaps, summary)
+	}
+	return mindMaps, rows.Err()
+}
+
+// GetMindMapByID retrieves a specific mind map by its ID
+func (db *DB) GetMindMapByID(id string) (*models.MindMap, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{id})
+	query := `
+		SELECT ` + mindMapColumns + `
+		FROM mind_maps
+		WHERE id = $1 AND status != 'deleted'` + scope
+
+	return scanMindMap(db.QueryRow(query, args...))
 }
 
 // GetMindMapWithDetails retrieves a mind map with all its nodes and edges
 func (db *DB) GetMindMapWithDetails(id string) (*models.MindMapWithDetails, error) {
-	// First get the mind map
-	mindMap, err := db.GetMindMapByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get all nodes for this mind map
-	nodesQuery := `
-		SELECT id, mind_map_id, parent_id, content, position_x, position_y, 
-		       node_type, style_data, metadata, created_at, updated_at
-		FROM nodes
-		WHERE mind_map_id = $1`
+	// Details are read-only, so serve them from the replica when there is one
+	reader := db.reader()
 
-	nodeRows, err := db.Query(nodesQuery, id)
+	// First get the mind map
+	mindMap, err := reader.GetMindMapByID(id)
 	if err != nil {
 		return nil, err
 	}
-	defer nodeRows.Close()
-
-	var nodes []models.Node
-	for nodeRows.Next() {
-		var node models.Node
-		var parentID sql.NullString
-		var styleData, metadata []byte
-
-		err := nodeRows.Scan(
-			&node.ID,
-			&node.MindMapID,
-			&parentID,
-			&node.Content,
-			&node.PositionX,
-			&node.PositionY,
-			&node.NodeType,
-			&styleData,
-			&metadata,
-			&node.CreatedAt,
-			&node.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
 
-		if parentID.Valid {
-			node.ParentID = &parentID.String
-		}
-
-		// Convert JSON data
-		node.StyleData = json.RawMessage(styleData)
-		node.Metadata = json.RawMessage(metadata)
+	return reader.mindMapDetails(mindMap)
+}
 
-		nodes = append(nodes, node)
-	}
+// mindMapDetails retrieves the nodes, edges and custom fields of a mind map
+func (db *DB) mindMapDetails(mindMap *models.MindMap) (*models.MindMapWithDetails, error) {
+	id := mindMap.ID
 
-	if err = nodeRows.Err(); err != nil {
+	// Get all nodes for this mind map
+	nodes, err := db.GetNodesByMindMapID(id)
+	if err != nil {
 		return nil, err
 	}
 
 	// Get all edges for this mind map
+	scope, args := db.tenantScope("tenant_id", []interface{}{id})
 	edgesQuery := `
 		SELECT id, mind_map_id, source_id, target_id, edge_type, style_data, created_at
 		FROM edges
-		WHERE mind_map_id = $1`
+		WHERE mind_map_id = $1` + scope
 
-	edgeRows, err := db.Query(edgesQuery, id)
+	edgeRows, err := db.Query(edgesQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -210,60 +216,130 @@ func (db *DB) GetMindMapWithDetails(id string) (*models.MindMapWithDetails, erro
 		return nil, err
 	}
 
+	// Get the custom field schema
+	customFields, err := db.GetMindMapCustomFields(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compute rollup fields on parent nodes
+	fields.ApplyRollups(customFields, nodes)
+
 	// Combine everything into the result
 	result := &models.MindMapWithDetails{
 		MindMap: *mindMap,
 		Nodes:   nodes,
 		Edges:   edges,
+		Fields:  customFields,
 	}
 
 	return result, nil
 }
 
-// UpdateMindMap updates a mind map's details
+// UpdateMindMap updates a mind map's details, recording a mind_map.updated change in the
+// same transaction
 func (db *DB) UpdateMindMap(id string, req models.MindMapUpdateRequest) error {
 	query := `
 		UPDATE mind_maps
 		SET title = COALESCE(NULLIF($2, ''), title),
 		    description = COALESCE(NULLIF($3, ''), description),
-		    is_public = $4,
+		    visibility = COALESCE($4, visibility),
 		    status = COALESCE(NULLIF($5, ''), status),
-		    updated_at = $6
-		WHERE id = $1 AND status != 'deleted'`
+		    updated_at = NOW(),
+		    vote_limit = COALESCE($6, vote_limit),
+		    icon = COALESCE($7, icon),
+		    cover_image = COALESCE($8, cover_image),
+		    layout_mode = COALESCE($9, layout_mode),
+		    allow_export = COALESCE($10, allow_export),
+		    redact_pii = COALESCE($11, redact_pii)
+		WHERE id = $1 AND status != 'deleted'
+		RETURNING ` + mindMapColumns
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := db.Exec(
+	mindMap, err := scanMindMap(tx.QueryRow(
 		query,
 		id,
 		req.Title,
 		req.Description,
-		req.IsPublic,
+		req.Visibility,
 		req.Status,
-		time.Now(),
-	)
+		req.VoteLimit,
+		req.Icon,
+		req.CoverImage,
+		req.LayoutMode,
+		req.AllowExport,
+		req.RedactPII,
+	))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("mind map not found or already deleted")
+	}
 	if err != nil {
 		return err
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
+	if _, err := recordChange(tx, id, "mind_map.updated", mindMap); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
+	db.signalChange()
+	return nil
+}
 
-	if rows == 0 {
-		return fmt.Errorf("mind map not found or already deleted")
+// SetMindMapStatus changes the status of a mind map that is not deleted, recording a
+// mind_map.updated change in the same transaction. Returns the updated map
+func (db *DB) SetMindMapStatus(id, status string) (*models.MindMap, error) {
+	query := `
+		UPDATE mind_maps
+		SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status != 'deleted'
+		RETURNING ` + mindMapColumns
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	return nil
+	mindMap, err := scanMindMap(tx.QueryRow(query, id, status))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := recordChange(tx, id, "mind_map.updated", mindMap); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return mindMap, nil
 }
 
-// DeleteMindMap soft deletes a mind map by setting its status to 'deleted'
+// DeleteMindMap soft deletes a mind map by setting its status to 'deleted', recording a
+// mind_map.deleted change in the same transaction
 func (db *DB) DeleteMindMap(id string) error {
 	query := `
 		UPDATE mind_maps
-		SET status = 'deleted', updated_at = $2
+		SET status = 'deleted', updated_at = NOW()
 		WHERE id = $1 AND status != 'deleted'`
 
-	result, err := db.Exec(query, id, time.Now())
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, id)
 	if err != nil {
 		return err
 	}
@@ -277,5 +353,217 @@ func (db *DB) DeleteMindMap(id string) error {
 		return fmt.Errorf("mind map not found or already deleted")
 	}
 
+	if _, err := recordChange(tx, id, "mind_map.deleted", map[string]string{"id": id}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
 	return nil
 }
+
+// CloneMindMap copies a mind map with all of its nodes and edges into a new map owned by userID
+func (db *DB) CloneMindMap(sourceID, userID, title string) (*models.MindMap, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	mindMap, err := cloneMindMapTx(tx, sourceID, userID, title)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return mindMap, nil
+}
+
+// cloneMindMapTx copies a mind map inside an existing transaction, assigning fresh IDs
+// to every node and edge and remapping parent, source and target references accordingly
+func cloneMindMapTx(tx *sql.Tx, sourceID, userID, title string) (*models.MindMap, error) {
+	var source models.MindMap
+	var customFields []byte
+	err := tx.QueryRow(`
+		SELECT id, title, description, icon, cover_image, layout_mode, custom_fields
+		FROM mind_maps
+		WHERE id = $1 AND status != 'deleted'`, sourceID,
+	).Scan(&source.ID, &source.Title, &source.Description, &source.Icon, &source.CoverImage, &source.LayoutMode, &customFields)
+	if err != nil {
+		return nil, err
+	}
+
+	if title == "" {
+		title = source.Title
+	}
+
+	mindMap, err := scanMindMap(tx.QueryRow(`
+		INSERT INTO mind_maps (id, user_id, title, description, icon, cover_image, layout_mode, created_at, updated_at, status, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), $8, $9)
+		RETURNING `+mindMapColumns,
+		uuid.New().String(),
+		userID,
+		title,
+		source.Description,
+		source.Icon,
+		source.CoverImage,
+		source.LayoutMode,
+		models.MindMapStatusActive,
+		customFields,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the source nodes so their IDs can be remapped
+	rows, err := tx.Query(`
+		SELECT id, parent_id, content, position_x, position_y, node_type, style_data, metadata,
+			pinned, icon, priority
+		FROM nodes
+		WHERE mind_map_id = $1`, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []models.Node
+	for rows.Next() {
+		var node models.Node
+		var parentID sql.NullString
+		var styleData, metadata []byte
+
+		if err := rows.Scan(
+			&node.ID,
+			&parentID,
+			&node.Content,
+			&node.PositionX,
+			&node.PositionY,
+			&node.NodeType,
+			&styleData,
+			&metadata,
+			&node.Pinned,
+			&node.Icon,
+			&node.Priority,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if parentID.Valid {
+			node.ParentID = &parentID.String
+		}
+		node.StyleData = json.RawMessage(styleData)
+		node.Metadata = json.RawMessage(metadata)
+
+		nodes = append(nodes, node)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make([]string, len(nodes))
+	for i, node := range nodes {
+		nodeIDs[i] = node.ID
+	}
+	remap := graphclone.ForMap(source.ID, mindMap.ID, nodeIDs)
+
+	// Point links, parents and edges at the copies rather than the source nodes
+	for i := range nodes {
+		nodes[i].Content = remap.Content(nodes[i].Content)
+		nodes[i].StyleData = remap.Metadata(nodes[i].StyleData)
+		nodes[i].Metadata = remap.Metadata(nodes[i].Metadata)
+	}
+
+	// Insert nodes without parents first so insertion order does not matter for the FK
+	idMap := remap.IDs()
+	for _, node := range nodes {
+		_, err := tx.Exec(`
+			INSERT INTO nodes (id, mind_map_id, parent_id, content, position_x, position_y,
+			                  node_type, style_data, metadata, pinned, icon, priority, created_at, updated_at)
+			VALUES ($1, $2, NULL, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())`,
+			idMap[node.ID],
+			mindMap.ID,
+			node.Content,
+			node.PositionX,
+			node.PositionY,
+			node.NodeType,
+			[]byte(node.StyleData),
+			[]byte(node.Metadata),
+			node.Pinned,
+			node.Icon,
+			node.Priority,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, node := range nodes {
+		newParentID := remap.ParentID(node.ParentID)
+		if newParentID == nil {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE nodes SET parent_id = $2 WHERE id = $1`, idMap[node.ID], *newParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Index the links of the copied nodes so the clone shows up in backlinks too
+	for _, node := range nodes {
+		if err := syncNodeLinks(tx, idMap[node.ID], mindMap.ID, node.Content, node.NodeType, node.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy edges between the cloned nodes
+	edgeRows, err := tx.Query(`
+		SELECT source_id, target_id, edge_type, style_data
+		FROM edges
+		WHERE mind_map_id = $1`, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []models.Edge
+	for edgeRows.Next() {
+		var edge models.Edge
+		var styleData []byte
+
+		if err := edgeRows.Scan(&edge.SourceID, &edge.TargetID, &edge.EdgeType, &styleData); err != nil {
+			edgeRows.Close()
+			return nil, err
+		}
+		edge.StyleData = json.RawMessage(styleData)
+
+		edges = append(edges, edge)
+	}
+	edgeRows.Close()
+	if err := edgeRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, edge := range edges {
+		sourceNodeID, targetNodeID, ok := remap.Edge(edge.SourceID, edge.TargetID)
+		if !ok {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+			uuid.New().String(),
+			mindMap.ID,
+			sourceNodeID,
+			targetNodeID,
+			edge.EdgeType,
+			[]byte(edge.StyleData),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mindMap, nil
+}
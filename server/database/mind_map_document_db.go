@@ -0,0 +1,103 @@
+package database
+
+import (
+	"saas-server/models"
+	"saas-server/pkg/export"
+
+	"github.com/google/uuid"
+)
+
+// ImportMindMapDocument creates a new mind map owned by userID from an export document in a
+// single transaction. Node and edge IDs are replaced, along with references to the document's
+// nodes in links and metadata; the returned map translates document node IDs to the new
+// ones. Everything else about content, layout, styles, metadata and timestamps is kept
+func (db *DB) ImportMindMapDocument(userID string, doc models.MindMapDocument) (*models.MindMap, map[string]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	mindMap, err := scanMindMap(tx.QueryRow(`
+		INSERT INTO mind_maps (id, user_id, title, description, icon, cover_image, vote_limit, created_at, updated_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), $8)
+		RETURNING `+mindMapColumns,
+		uuid.New().String(),
+		userID,
+		doc.MindMap.Title,
+		doc.MindMap.Description,
+		doc.MindMap.Icon,
+		doc.MindMap.CoverImage,
+		doc.MindMap.VoteLimit,
+		models.MindMapStatusActive,
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Point references inside the document at the imported nodes and map, and write each
+	// node once with its parent set, so the kept timestamps are not bumped by an update
+	imported, idMap := export.ImportDocument(doc, mindMap.ID)
+	for _, node := range imported.Nodes {
+		_, err := tx.Exec(`
+			INSERT INTO nodes (id, mind_map_id, parent_id, content, position_x, position_y,
+			                  node_type, style_data, metadata, created_by, pinned, icon, priority,
+			                  created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			node.ID,
+			mindMap.ID,
+			node.ParentID,
+			node.Content,
+			node.PositionX,
+			node.PositionY,
+			node.NodeType,
+			jsonObjectBytes(node.StyleData),
+			jsonObjectBytes(node.Metadata),
+			userID,
+			node.Pinned,
+			node.Icon,
+			node.Priority,
+			node.CreatedAt,
+			node.UpdatedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, node := range imported.Nodes {
+		if err := syncNodeLinks(tx, node.ID, mindMap.ID, node.Content, node.NodeType, node.Metadata); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, edge := range imported.Edges {
+		_, err := tx.Exec(`
+			INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			edge.ID,
+			mindMap.ID,
+			edge.SourceID,
+			edge.TargetID,
+			edge.EdgeType,
+			jsonObjectBytes(edge.StyleData),
+			edge.CreatedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return mindMap, idMap, nil
+}
+
+// jsonObjectBytes returns JSON data for a JSONB column, storing an empty object for missing data
+func jsonObjectBytes(data []byte) []byte {
+	if len(data) == 0 || string(data) == "null" {
+		return []byte("{}")
+	}
+	return data
+}
@@ -1,15 +1,12 @@
 package database
 
-import (
-	"saas-server/models"
-	"time"
-)
+import "saas-server/models"
 
 // CreateNewsletterSubscription creates a new newsletter subscription in the database
 func (db *DB) CreateNewsletterSubscription(email string) error {
 	_, err := db.Exec(
-		"INSERT INTO newsletter_subscriptions (email, subscribed, created_at, updated_at) VALUES ($1, $2, $3, $3)",
-		email, true, time.Now(),
+		"INSERT INTO newsletter_subscriptions (email, subscribed, created_at, updated_at) VALUES ($1, $2, NOW(), NOW())",
+		email, true,
 	)
 	return err
 }
@@ -24,8 +21,8 @@ func (db *DB) NewsletterEmailExists(email string) (bool, error) {
 // UpdateNewsletterSubscription updates the subscription status for an existing newsletter subscription
 func (db *DB) UpdateNewsletterSubscription(email string, subscribed bool) error {
 	_, err := db.Exec(
-		"UPDATE newsletter_subscriptions SET subscribed = $1, updated_at = $2 WHERE email = $3",
-		subscribed, time.Now(), email,
+		"UPDATE newsletter_subscriptions SET subscribed = $1, updated_at = NOW() WHERE email = $2",
+		subscribed, email,
 	)
 	return err
 }
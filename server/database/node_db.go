@@ -3,21 +3,75 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"saas-server/models"
-	"time"
 
 	"github.com/google/uuid"
 )
 
-// CreateNode creates a new node in the database
+// nodeColumns lists the node columns in the order scanNode expects, including the
+// computed vote count and whether an anonymous author is still hidden
+const nodeColumns = `id, mind_map_id, parent_id, content, position_x, position_y,
+	node_type, style_data, metadata,
+	(SELECT COUNT(*) FROM node_votes v WHERE v.node_id = nodes.id) AS vote_count,
+	created_by, anonymous, pinned, icon, priority,
+	anonymous AND NOT EXISTS (
+		SELECT 1 FROM brainstorm_sessions s
+		WHERE s.id = nodes.session_id AND s.authorship_revealed
+	) AS author_hidden,
+	created_at, updated_at`
+
+// scanNode scans a node row selected with nodeColumns
+func scanNode(row rowScanner) (*models.Node, error) {
+	var node models.Node
+	var parentID, createdBy sql.NullString
+	var styleData, metadata []byte
+
+	err := row.Scan(
+		&node.ID,
+		&node.MindMapID,
+		&parentID,
+		&node.Content,
+		&node.PositionX,
+		&node.PositionY,
+		&node.NodeType,
+		&styleData,
+		&metadata,
+		&node.VoteCount,
+		&createdBy,
+		&node.Anonymous,
+		&node.Pinned,
+		&node.Icon,
+		&node.Priority,
+		&node.AuthorHidden,
+		&node.CreatedAt,
+		&node.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert SQL data to model format
+	if parentID.Valid {
+		node.ParentID = &parentID.String
+	}
+	if createdBy.Valid {
+		node.CreatedBy = &createdBy.String
+	}
+	node.StyleData = json.RawMessage(styleData)
+	node.Metadata = json.RawMessage(metadata)
+
+	return &node, nil
+}
+
+// CreateNode creates a new node in the database, recording a node.created change in the
+// same transaction
 func (db *DB) CreateNode(req models.NodeCreateRequest) (*models.Node, error) {
 	id := uuid.New().String()
-	now := time.Now()
 
 	// Convert JSON data to bytes for storage
 	var styleDataBytes, metadataBytes []byte
-	var err error
 
 	if req.StyleData != nil {
 		styleDataBytes = []byte(req.StyleData)
@@ -33,21 +87,32 @@ func (db *DB) CreateNode(req models.NodeCreateRequest) (*models.Node, error) {
 
 	query := `
 		INSERT INTO nodes (id, mind_map_id, parent_id, content, position_x, position_y, 
-		                  node_type, style_data, metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, mind_map_id, parent_id, content, position_x, position_y, 
-		         node_type, style_data, metadata, created_at, updated_at`
-
-	var node models.Node
-	var parentID sql.NullString
-	var styleData, metadata []byte
+		                  node_type, style_data, metadata, created_by, anonymous, session_id,
+		                  icon, priority, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
+		RETURNING ` + nodeColumns
 
+	var parentID, createdBy, sessionID sql.NullString
 	if req.ParentID != nil {
 		parentID.String = *req.ParentID
 		parentID.Valid = true
 	}
+	if req.CreatedBy != "" {
+		createdBy.String = req.CreatedBy
+		createdBy.Valid = true
+	}
+	if req.SessionID != "" {
+		sessionID.String = req.SessionID
+		sessionID.Valid = true
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	err = db.QueryRow(
+	node, err := scanNode(tx.QueryRow(
 		query,
 		id,
 		req.MindMapID,
@@ -58,44 +123,39 @@ func (db *DB) CreateNode(req models.NodeCreateRequest) (*models.Node, error) {
 		req.NodeType,
 		styleDataBytes,
 		metadataBytes,
-		now,
-		now,
-	).Scan(
-		&node.ID,
-		&node.MindMapID,
-		&parentID,
-		&node.Content,
-		&node.PositionX,
-		&node.PositionY,
-		&node.NodeType,
-		&styleData,
-		&metadata,
-		&node.CreatedAt,
-		&node.UpdatedAt,
-	)
+		createdBy,
+		req.Anonymous,
+		sessionID,
+		req.Icon,
+		req.Priority,
+	))
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert SQL data to model format
-	if parentID.Valid {
-		node.ParentID = &parentID.String
+	masked := *node
+	masked.MaskAttribution()
+	if _, err := recordChange(tx, node.MindMapID, "node.created", masked); err != nil {
+		return nil, err
 	}
-	node.StyleData = json.RawMessage(styleData)
-	node.Metadata = json.RawMessage(metadata)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
 
-	return &node, nil
+	db.indexNodeLinks(node)
+	return node, nil
 }
 
 // GetNodesByMindMapID retrieves all nodes for a specific mind map
 func (db *DB) GetNodesByMindMapID(mindMapID string) ([]models.Node, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{mindMapID})
 	query := `
-		SELECT id, mind_map_id, parent_id, content, position_x, position_y, 
-		       node_type, style_data, metadata, created_at, updated_at
+		SELECT ` + nodeColumns + `
 		FROM nodes
-		WHERE mind_map_id = $1`
+		WHERE mind_map_id = $1` + scope
 
-	rows, err := db.Query(query, mindMapID)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,35 +163,11 @@ func (db *DB) GetNodesByMindMapID(mindMapID string) ([]models.Node, error) {
 
 	var nodes []models.Node
 	for rows.Next() {
-		var node models.Node
-		var parentID sql.NullString
-		var styleData, metadata []byte
-
-		err := rows.Scan(
-			&node.ID,
-			&node.MindMapID,
-			&parentID,
-			&node.Content,
-			&node.PositionX,
-			&node.PositionY,
-			&node.NodeType,
-			&styleData,
-			&metadata,
-			&node.CreatedAt,
-			&node.UpdatedAt,
-		)
+		node, err := scanNode(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		// Convert SQL data to model format
-		if parentID.Valid {
-			node.ParentID = &parentID.String
-		}
-		node.StyleData = json.RawMessage(styleData)
-		node.Metadata = json.RawMessage(metadata)
-
-		nodes = append(nodes, node)
+		nodes = append(nodes, *node)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -143,44 +179,22 @@ func (db *DB) GetNodesByMindMapID(mindMapID string) ([]models.Node, error) {
 
 // GetNodeByID retrieves a specific node by its ID
 func (db *DB) GetNodeByID(id string) (*models.Node, error) {
+	scope, args := db.tenantScope("tenant_id", []interface{}{id})
 	query := `
-		SELECT id, mind_map_id, parent_id, content, position_x, position_y, 
-		       node_type, style_data, metadata, created_at, updated_at
+		SELECT ` + nodeColumns + `
 		FROM nodes
-		WHERE id = $1`
-
-	var node models.Node
-	var parentID sql.NullString
-	var styleData, metadata []byte
-
-	err := db.QueryRow(query, id).Scan(
-		&node.ID,
-		&node.MindMapID,
-		&parentID,
-		&node.Content,
-		&node.PositionX,
-		&node.PositionY,
-		&node.NodeType,
-		&styleData,
-		&metadata,
-		&node.CreatedAt,
-		&node.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert SQL data to model format
-	if parentID.Valid {
-		node.ParentID = &parentID.String
-	}
-	node.StyleData = json.RawMessage(styleData)
-	node.Metadata = json.RawMessage(metadata)
+		WHERE id = $1` + scope
 
-	return &node, nil
+	return scanNode(db.QueryRow(query, args...))
 }
 
-// UpdateNode updates a node's details
+// ErrWriteConflict is returned when an update is based on a version that has since changed
+var ErrWriteConflict = errors.New("resource was modified by another write")
+
+// UpdateNode updates a node's details, recording a node.updated change in the same
+// transaction. With BaseUpdatedAt set, the update only applies if the node has not changed
+// since that version and ErrWriteConflict is returned otherwise. Position-only updates
+// keep updated_at and skip the check, so moves and edits never conflict
 func (db *DB) UpdateNode(id string, req models.NodeUpdateRequest) error {
 	// Convert JSON data to bytes for storage
 	var styleDataBytes, metadataBytes []byte
@@ -202,8 +216,11 @@ func (db *DB) UpdateNode(id string, req models.NodeUpdateRequest) error {
 		    node_type = COALESCE(NULLIF($5, ''), node_type),
 		    style_data = COALESCE($6, style_data),
 		    metadata = COALESCE($7, metadata),
-		    updated_at = $8
-		WHERE id = $1`
+		    pinned = COALESCE($9, pinned),
+		    icon = COALESCE($10, icon),
+		    priority = COALESCE($11, priority),
+		    updated_at = CASE WHEN $12 THEN updated_at ELSE NOW() END
+		WHERE id = $1 AND ($8::timestamptz IS NULL OR updated_at = $8)`
 
 	// Use zero values for float64 to indicate no update
 	var posX, posY *float64
@@ -214,7 +231,25 @@ func (db *DB) UpdateNode(id string, req models.NodeUpdateRequest) error {
 		posY = &req.PositionY
 	}
 
-	result, err := db.Exec(
+	baseUpdatedAt := req.BaseUpdatedAt
+	positionOnly := req.PositionOnly()
+	if positionOnly {
+		baseUpdatedAt = nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if positionOnly {
+		if _, err := tx.Exec(`SET LOCAL app.keep_updated_at = 'on'`); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(
 		query,
 		id,
 		req.Content,
@@ -223,7 +258,11 @@ func (db *DB) UpdateNode(id string, req models.NodeUpdateRequest) error {
 		req.NodeType,
 		styleDataBytes,
 		metadataBytes,
-		time.Now(),
+		baseUpdatedAt,
+		req.Pinned,
+		req.Icon,
+		req.Priority,
+		positionOnly,
 	)
 	if err != nil {
 		return err
@@ -235,35 +274,116 @@ func (db *DB) UpdateNode(id string, req models.NodeUpdateRequest) error {
 	}
 
 	if rows == 0 {
+		if baseUpdatedAt != nil {
+			var exists bool
+			if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM nodes WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				return ErrWriteConflict
+			}
+		}
 		return fmt.Errorf("node not found")
 	}
 
+	node, err := scanNode(tx.QueryRow(`SELECT `+nodeColumns+` FROM nodes WHERE id = $1`, id))
+	if err != nil {
+		return err
+	}
+	masked := *node
+	masked.MaskAttribution()
+	if _, err := recordChange(tx, node.MindMapID, "node.updated", masked); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
+
+	// Content, type or metadata may have changed the node's links
+	if req.Content != "" || req.NodeType != "" || req.Metadata != nil {
+		db.indexNodeLinks(node)
+	}
+
 	return nil
 }
 
-// DeleteNode deletes a node from the database
+// DeleteNode deletes a node from the database, recording a node.deleted change in the same
+// transaction
 func (db *DB) DeleteNode(id string) error {
-	query := `DELETE FROM nodes WHERE id = $1`
-
-	result, err := db.Exec(query, id)
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	var mindMapID string
+	err = tx.QueryRow(`DELETE FROM nodes WHERE id = $1 RETURNING mind_map_id`, id).Scan(&mindMapID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("node not found")
+	}
 	if err != nil {
 		return err
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("node not found")
+	if _, err := recordChange(tx, mindMapID, "node.deleted", map[string]string{"id": id}); err != nil {
+		return err
 	}
-
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
 	return nil
 }
 
-// BatchUpdateNodePositions updates the positions of multiple nodes in a single transaction
-func (db *DB) BatchUpdateNodePositions(positions []models.NodePositionUpdateRequest) error {
+// PreviewDeleteNode returns what deleting a node would remove through cascades, without
+// deleting anything
+func (db *DB) PreviewDeleteNode(id string) (*models.NodeDeletePreview, error) {
+	subtree := `
+		WITH subtree AS (
+			SELECT id FROM nodes WHERE path @> ARRAY[$1::uuid]
+		)`
+
+	preview := models.NodeDeletePreview{NodeID: id}
+	err := db.QueryRow(`SELECT mind_map_id FROM nodes WHERE id = $1`, id).Scan(&preview.MindMapID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	preview.DeletedNodeIDs, err = queryNodeIDs(db.Query(subtree+` SELECT id FROM subtree`, id))
+	if err != nil {
+		return nil, err
+	}
+	preview.DeletedEdgeIDs, err = queryNodeIDs(db.Query(subtree+`
+		SELECT e.id FROM edges e
+		WHERE e.source_id IN (SELECT id FROM subtree) OR e.target_id IN (SELECT id FROM subtree)
+		ORDER BY e.id`, id))
+	if err != nil {
+		return nil, err
+	}
+	preview.BrokenLinkIDs, err = queryNodeIDs(db.Query(subtree+`
+		SELECT DISTINCT l.source_node_id FROM node_links l
+		WHERE l.target_node_id IN (SELECT id FROM subtree)
+		AND l.source_node_id NOT IN (SELECT id FROM subtree)`, id))
+	if err != nil {
+		return nil, err
+	}
+	err = db.QueryRow(subtree+`
+		SELECT COUNT(*) FROM node_votes WHERE node_id IN (SELECT id FROM subtree)`, id).Scan(&preview.DeletedVotes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preview, nil
+}
+
+// BatchUpdateNodePositions updates the positions of multiple nodes of a mind map in a single
+// transaction, recording a nodes.moved change in it. Like position-only updates of a node,
+// moves keep updated_at
+func (db *DB) BatchUpdateNodePositions(mindMapID string, positions []models.NodePositionUpdateRequest) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -275,11 +395,14 @@ func (db *DB) BatchUpdateNodePositions(positions []models.NodePositionUpdateRequ
 		}
 	}()
 
+	if _, err = tx.Exec(`SET LOCAL app.keep_updated_at = 'on'`); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE nodes
 		SET position_x = $2,
-		    position_y = $3,
-		    updated_at = $4
+		    position_y = $3
 		WHERE id = $1`
 
 	stmt, err := tx.Prepare(query)
@@ -288,13 +411,19 @@ func (db *DB) BatchUpdateNodePositions(positions []models.NodePositionUpdateRequ
 	}
 	defer stmt.Close()
 
-	now := time.Now()
 	for _, pos := range positions {
-		_, err = stmt.Exec(pos.ID, pos.PositionX, pos.PositionY, now)
+		_, err = stmt.Exec(pos.ID, pos.PositionX, pos.PositionY)
 		if err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit()
+	if _, err = recordChange(tx, mindMapID, "nodes.moved", positions); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
+	return nil
 }
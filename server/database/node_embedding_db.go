@@ -0,0 +1,144 @@
+package database
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"saas-server/models"
+	"strconv"
+	"strings"
+)
+
+// staleEmbeddingCondition matches nodes, as n, whose embedding row, as e, is missing or was
+// made from other content
+const staleEmbeddingCondition = `(e.node_id IS NULL OR e.content_hash != md5(n.content))`
+
+// GetNodesToEmbed retrieves up to limit nodes of maps that are not deleted whose content
+// has changed since it was last embedded, or was never embedded, most recently updated
+// first
+func (db *DB) GetNodesToEmbed(limit int) ([]models.NodeToEmbed, error) {
+	return queryNodesToEmbed(db, `
+		SELECT n.id, n.mind_map_id, n.content
+		FROM nodes n
+		JOIN mind_maps m ON m.id = n.mind_map_id
+		LEFT JOIN node_embeddings e ON e.node_id = n.id
+		WHERE m.status != 'deleted' AND TRIM(n.content) != '' AND `+staleEmbeddingCondition+`
+		ORDER BY n.updated_at DESC
+		LIMIT $1`, limit)
+}
+
+// GetUserNodesToEmbed retrieves up to limit nodes of a user's own maps that have no
+// embedding of their current content, including those that could not be embedded before,
+// grouped by mind map. Also returns how many such nodes there are in all
+func (db *DB) GetUserNodesToEmbed(userID string, limit int) ([]models.NodeToEmbed, int, error) {
+	condition := `
+		FROM nodes n
+		JOIN mind_maps m ON m.id = n.mind_map_id
+		LEFT JOIN node_embeddings e ON e.node_id = n.id
+		WHERE m.user_id = $1 AND m.status != 'deleted' AND TRIM(n.content) != ''
+		AND (` + staleEmbeddingCondition + ` OR e.embedding IS NULL)`
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) `+condition, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	nodes, err := queryNodesToEmbed(db, `
+		SELECT n.id, n.mind_map_id, n.content `+condition+`
+		ORDER BY n.mind_map_id, n.updated_at DESC
+		LIMIT $2`, userID, limit)
+	return nodes, total, err
+}
+
+// queryNodesToEmbed runs a query selecting the ID, mind map and content of nodes
+func queryNodesToEmbed(db *DB, query string, args ...interface{}) ([]models.NodeToEmbed, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []models.NodeToEmbed
+	for rows.Next() {
+		var node models.NodeToEmbed
+		if err := rows.Scan(&node.ID, &node.MindMapID, &node.Content); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// SaveNodeEmbeddings stores the embeddings of nodes, replacing earlier ones, in a single
+// transaction. Nodes deleted in the meantime are skipped
+func (db *DB) SaveNodeEmbeddings(embeddings []models.NodeEmbedding) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, embedding := range embeddings {
+		hash := md5.Sum([]byte(embedding.Content))
+		var vector, model interface{}
+		if embedding.Embedding != nil {
+			vector, model = vectorLiteral(embedding.Embedding), embedding.Model
+		}
+		_, err := tx.Exec(`
+			INSERT INTO node_embeddings (node_id, content_hash, model, embedding, updated_at)
+			SELECT id, $2, $3, $4::vector, NOW() FROM nodes WHERE id = $1
+			ON CONFLICT (node_id) DO UPDATE
+			SET content_hash = EXCLUDED.content_hash,
+			    model = EXCLUDED.model,
+			    embedding = EXCLUDED.embedding,
+			    updated_at = NOW()`,
+			embedding.NodeID, hex.EncodeToString(hash[:]), model, vector)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SemanticSearch finds the nodes of the maps a user owns or has been shared with whose
+// embeddings are closest to the embedding of a query, up to limit of them, closest first.
+// Deleted maps are skipped
+func (db *DB) SemanticSearch(userID string, query []float64, limit int) ([]models.SemanticSearchResult, error) {
+	scope, args := db.tenantScope("m.tenant_id", []interface{}{vectorLiteral(query), userID, limit})
+	rows, err := db.reader().Query(`
+		SELECT n.id, n.mind_map_id, m.title, n.content, n.node_type, 1 - (e.embedding <=> $1::vector) AS score
+		FROM node_embeddings e
+		JOIN nodes n ON n.id = e.node_id
+		JOIN mind_maps m ON m.id = n.mind_map_id
+		WHERE e.embedding IS NOT NULL AND m.status != 'deleted' AND (
+			m.user_id = $2 OR m.id IN (
+				SELECT s.mind_map_id
+				FROM mind_map_shares s
+				JOIN users u ON LOWER(u.email) = s.email
+				WHERE u.id = $2
+			)
+		)`+scope+`
+		ORDER BY e.embedding <=> $1::vector
+		LIMIT $3`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.SemanticSearchResult{}
+	for rows.Next() {
+		var result models.SemanticSearchResult
+		if err := rows.Scan(&result.NodeID, &result.MindMapID, &result.MindMapTitle, &result.Content, &result.NodeType, &result.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// vectorLiteral formats an embedding in the text form of a pgvector vector
+func vectorLiteral(embedding []float64) string {
+	parts := make([]string, len(embedding))
+	for i, value := range embedding {
+		parts[i] = strconv.FormatFloat(value, 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"encoding/json"
+	"saas-server/models"
+
+	"github.com/google/uuid"
+)
+
+// ImportNodes creates the imported rows as nodes in a single transaction, linking each to
+// its parent with an edge, and records a nodes.imported change in the same transaction. Rows
+// must be ordered so that parents come before their children. The generated node IDs are
+// written back into the rows
+func (db *DB) ImportNodes(mindMapID, userID string, rows []models.NodeImportRow) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	created := make(map[int]string, len(rows))
+	nodeIDs := make([]string, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		row.NodeID = uuid.New().String()
+
+		var parentID *string
+		if row.ParentRow > 0 {
+			id := created[row.ParentRow]
+			parentID = &id
+		} else if row.ParentID != nil {
+			parentID = row.ParentID
+		}
+
+		tags := row.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		metadata, err := json.Marshal(map[string]interface{}{"tags": tags})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO nodes (id, mind_map_id, parent_id, content, position_x, position_y,
+			                  node_type, style_data, metadata, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())`,
+			row.NodeID,
+			mindMapID,
+			parentID,
+			row.Content,
+			row.PositionX,
+			row.PositionY,
+			row.NodeType,
+			[]byte("{}"),
+			metadata,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		if err := syncNodeLinks(tx, row.NodeID, mindMapID, row.Content, row.NodeType, metadata); err != nil {
+			return err
+		}
+		created[row.Row] = row.NodeID
+		nodeIDs = append(nodeIDs, row.NodeID)
+
+		if parentID != nil {
+			_, err = tx.Exec(`
+				INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+				uuid.New().String(),
+				mindMapID,
+				*parentID,
+				row.NodeID,
+				"default",
+				[]byte("{}"),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := recordChange(tx, mindMapID, "nodes.imported", map[string][]string{"node_ids": nodeIDs}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
+	return nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"saas-server/models"
+	"saas-server/pkg/links"
+
+	"github.com/lib/pq"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// syncNodeLinks replaces the indexed references of a node with those found in its current
+// content. References to maps or nodes that do not exist are skipped
+func syncNodeLinks(e execer, nodeID, mindMapID, content, nodeType string, metadata json.RawMessage) error {
+	if _, err := e.Exec(`DELETE FROM node_links WHERE source_node_id = $1`, nodeID); err != nil {
+		return err
+	}
+
+	for _, ref := range links.Extract(content, nodeType, metadata) {
+		if ref.NodeID == nodeID {
+			continue
+		}
+		var targetNodeID sql.NullString
+		if ref.NodeID != "" {
+			targetNodeID = sql.NullString{String: ref.NodeID, Valid: true}
+		}
+		_, err := e.Exec(`
+			INSERT INTO node_links (source_node_id, source_mind_map_id, target_mind_map_id, target_node_id, created_at)
+			SELECT $1, $2, $3, $4, NOW()
+			WHERE EXISTS (SELECT 1 FROM mind_maps WHERE id = $3)
+			AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM nodes WHERE id = $4 AND mind_map_id = $3))`,
+			nodeID,
+			mindMapID,
+			ref.MindMapID,
+			targetNodeID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexNodeLinks refreshes the links of a node that was saved outside a transaction.
+// The node itself is already stored, so failures are logged rather than returned
+func (db *DB) indexNodeLinks(node *models.Node) {
+	if err := syncNodeLinks(db, node.ID, node.MindMapID, node.Content, node.NodeType, node.Metadata); err != nil {
+		log.Printf("[Node Links] Error indexing links of node %s: %v", node.ID, err)
+	}
+}
+
+// GetBacklinks retrieves the nodes that reference a node along with the mind maps they
+// belong to, skipping deleted maps. Callers filter the result by access to those maps
+func (db *DB) GetBacklinks(nodeID string) ([]models.Backlink, []models.MindMap, error) {
+	query := `
+		SELECT ` + mindMapColumns + `, source_node_id, source_content
+		FROM mind_maps
+		JOIN (
+			SELECT l.source_node_id, l.source_mind_map_id, n.content AS source_content, n.created_at AS source_created_at
+			FROM node_links l
+			JOIN nodes n ON n.id = l.source_node_id
+			WHERE l.target_node_id = $1
+		) b ON b.source_mind_map_id = mind_maps.id
+		WHERE status != 'deleted'
+		ORDER BY updated_at DESC, source_created_at`
+
+	rows, err := db.reader().Query(query, nodeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var backlinks []models.Backlink
+	var mindMaps []models.MindMap
+	for rows.Next() {
+		var backlink models.Backlink
+		mindMap, err := scanMindMap(rows, &backlink.NodeID, &backlink.Content)
+		if err != nil {
+			return nil, nil, err
+		}
+		backlink.MindMapID = mindMap.ID
+		backlink.MindMapTitle = mindMap.Title
+		backlinks = append(backlinks, backlink)
+		mindMaps = append(mindMaps, *mindMap)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return backlinks, mindMaps, nil
+}
+
+// GetNodeLinksByMindMapIDs retrieves the links made by nodes in several mind maps. Served from the replica
+func (db *DB) GetNodeLinksByMindMapIDs(mindMapIDs []string) ([]models.NodeLink, error) {
+	query := `
+		SELECT source_node_id, source_mind_map_id, target_mind_map_id, target_node_id
+		FROM node_links
+		WHERE source_mind_map_id = ANY($1)`
+
+	rows, err := db.reader().Query(query, pq.Array(mindMapIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodeLinks []models.NodeLink
+	for rows.Next() {
+		var link models.NodeLink
+		var targetNodeID sql.NullString
+		if err := rows.Scan(&link.SourceNodeID, &link.SourceMindMapID, &link.TargetMindMapID, &targetNodeID); err != nil {
+			return nil, err
+		}
+		if targetNodeID.Valid {
+			link.TargetNodeID = &targetNodeID.String
+		}
+		nodeLinks = append(nodeLinks, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodeLinks, nil
+}
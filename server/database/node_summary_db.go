@@ -0,0 +1,47 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"saas-server/models"
+)
+
+// SetNodeSummary stores a summary of a node's descendants under "summary" in its metadata,
+// keeping the rest of the metadata, and records a node.updated change in the same
+// transaction. Returns the updated node, or ErrNotFound if there is no such node
+func (db *DB) SetNodeSummary(nodeID string, summary models.NodeSummary) (*models.Node, error) {
+	value, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	node, err := scanNode(tx.QueryRow(`
+		UPDATE nodes
+		SET metadata = jsonb_set(COALESCE(metadata, '{}'::jsonb), '{summary}', $2::jsonb),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+nodeColumns, nodeID, string(value)))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	masked := *node
+	masked.MaskAttribution()
+	if _, err := recordChange(tx, node.MindMapID, "node.updated", masked); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return node, nil
+}
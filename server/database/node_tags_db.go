@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// nodeTagsExpr is a node's tags as a JSON array, treating missing or malformed tags as empty
+const nodeTagsExpr = `(CASE WHEN jsonb_typeof(metadata->'tags') = 'array' THEN metadata->'tags' ELSE '[]'::jsonb END)`
+
+// AddNodeTag adds a tag to the given nodes of a mind map that do not have it yet, in one
+// statement, recording a nodes.updated change in its transaction. Returns the IDs of the
+// nodes that changed
+func (db *DB) AddNodeTag(mindMapID string, nodeIDs []string, tag string) ([]string, error) {
+	query := `
+		UPDATE nodes
+		SET metadata = jsonb_set(COALESCE(metadata, '{}'::jsonb), '{tags}', ` + nodeTagsExpr + ` || to_jsonb($3::text)),
+		    updated_at = NOW()
+		WHERE mind_map_id = $1 AND id = ANY($2) AND NOT ` + nodeTagsExpr + ` ? $3
+		RETURNING id`
+
+	return db.updateNodeTags(mindMapID, query, mindMapID, pq.Array(nodeIDs), tag)
+}
+
+// RemoveNodeTag removes a tag from the given nodes of a mind map, in one statement,
+// recording a nodes.updated change in its transaction. Returns the IDs of the nodes that
+// changed
+func (db *DB) RemoveNodeTag(mindMapID string, nodeIDs []string, tag string) ([]string, error) {
+	query := `
+		UPDATE nodes
+		SET metadata = jsonb_set(metadata, '{tags}', ` + nodeTagsExpr + ` - $3::text),
+		    updated_at = NOW()
+		WHERE mind_map_id = $1 AND id = ANY($2) AND ` + nodeTagsExpr + ` ? $3
+		RETURNING id`
+
+	return db.updateNodeTags(mindMapID, query, mindMapID, pq.Array(nodeIDs), tag)
+}
+
+// RenameTag renames a tag on every node of a mind map in one statement, keeping each tag's
+// position. Nodes that already have the new tag end up with a single copy, merging the two.
+// Records a nodes.updated change in its transaction. Returns the IDs of the nodes that
+// changed
+func (db *DB) RenameTag(mindMapID, from, to string) ([]string, error) {
+	query := `
+		UPDATE nodes
+		SET metadata = jsonb_set(metadata, '{tags}', (
+		        SELECT COALESCE(jsonb_agg(tag ORDER BY first_ord), '[]'::jsonb)
+		        FROM (
+		            SELECT tag, MIN(ord) AS first_ord
+		            FROM (
+		                SELECT CASE WHEN t.value = to_jsonb($2::text) THEN to_jsonb($3::text) ELSE t.value END AS tag,
+		                       t.ord
+		                FROM jsonb_array_elements(nodes.metadata->'tags') WITH ORDINALITY AS t(value, ord)
+		            ) renamed
+		            GROUP BY tag
+		        ) deduplicated
+		    )),
+		    updated_at = NOW()
+		WHERE mind_map_id = $1 AND jsonb_typeof(metadata->'tags') = 'array' AND metadata->'tags' ? $2
+		RETURNING id`
+
+	return db.updateNodeTags(mindMapID, query, mindMapID, from, to)
+}
+
+// updateNodeTags runs a tag update returning the IDs of the nodes it changed in a
+// transaction, recording a nodes.updated change with them unless it changed none
+func (db *DB) updateNodeTags(mindMapID, query string, args ...interface{}) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	nodeIDs, err := queryNodeIDs(tx.Query(query, args...))
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeIDs) == 0 {
+		return nodeIDs, nil
+	}
+
+	if _, err := recordChange(tx, mindMapID, "nodes.updated", map[string][]string{"node_ids": nodeIDs}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return nodeIDs, nil
+}
+
+// queryNodeIDs collects the node IDs returned by a query
+func queryNodeIDs(rows *sql.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodeIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		nodeIDs = append(nodeIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodeIDs, nil
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrNodeTemplateNameTaken is returned when a user already has a node template with the same name
+var ErrNodeTemplateNameTaken = errors.New("a node template with this name already exists")
+
+// nodeTemplateColumns lists the node template columns in the order scanNodeTemplate expects
+const nodeTemplateColumns = `id, user_id, name, description, content, node_type, style_data, metadata, created_at, updated_at`
+
+// scanNodeTemplate scans a node template row selected with nodeTemplateColumns
+func scanNodeTemplate(row rowScanner) (*models.NodeTemplate, error) {
+	var template models.NodeTemplate
+	var styleData, metadata []byte
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&template.Description,
+		&template.Content,
+		&template.NodeType,
+		&styleData,
+		&metadata,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrNodeTemplateNameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	template.StyleData = styleData
+	template.Metadata = metadata
+	return &template, nil
+}
+
+// CreateNodeTemplate stores a new node template for a user
+func (db *DB) CreateNodeTemplate(userID string, req models.NodeTemplateRequest) (*models.NodeTemplate, error) {
+	query := `
+		INSERT INTO node_templates (user_id, name, description, content, node_type, style_data, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING ` + nodeTemplateColumns
+
+	return scanNodeTemplate(db.QueryRow(
+		query,
+		userID,
+		req.Name,
+		req.Description,
+		req.Content,
+		req.NodeType,
+		jsonObjectBytes(req.StyleData),
+		jsonObjectBytes(req.Metadata),
+	))
+}
+
+// GetNodeTemplatesByUserID retrieves all node templates of a user ordered by name
+func (db *DB) GetNodeTemplatesByUserID(userID string) ([]models.NodeTemplate, error) {
+	query := `
+		SELECT ` + nodeTemplateColumns + `
+		FROM node_templates
+		WHERE user_id = $1
+		ORDER BY name`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.NodeTemplate{}
+	for rows.Next() {
+		template, err := scanNodeTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// GetNodeTemplate retrieves a node template belonging to a user
+func (db *DB) GetNodeTemplate(id, userID string) (*models.NodeTemplate, error) {
+	query := `
+		SELECT ` + nodeTemplateColumns + `
+		FROM node_templates
+		WHERE id = $1 AND user_id = $2`
+
+	return scanNodeTemplate(db.QueryRow(query, id, userID))
+}
+
+// UpdateNodeTemplate replaces the fields of a node template
+func (db *DB) UpdateNodeTemplate(id, userID string, req models.NodeTemplateRequest) (*models.NodeTemplate, error) {
+	query := `
+		UPDATE node_templates
+		SET name = $3, description = $4, content = $5, node_type = $6, style_data = $7, metadata = $8, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + nodeTemplateColumns
+
+	return scanNodeTemplate(db.QueryRow(
+		query,
+		id,
+		userID,
+		req.Name,
+		req.Description,
+		req.Content,
+		req.NodeType,
+		jsonObjectBytes(req.StyleData),
+		jsonObjectBytes(req.Metadata),
+	))
+}
+
+// DeleteNodeTemplate deletes a node template belonging to a user
+func (db *DB) DeleteNodeTemplate(id, userID string) error {
+	result, err := db.Exec(`DELETE FROM node_templates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"encoding/json"
+	"saas-server/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChangeSignal returns a channel that receives a value after changes are committed, so the
+// outbox dispatcher can deliver them right away instead of waiting for its next poll
+func (db *DB) ChangeSignal() <-chan struct{} {
+	return db.changes
+}
+
+// signalChange wakes the outbox dispatcher without waiting for it
+func (db *DB) signalChange() {
+	select {
+	case db.changes <- struct{}{}:
+	default:
+	}
+}
+
+// PublishPendingChanges hands up to limit unpublished outbox events to publish, oldest
+// first, and marks them published. Events are claimed with row locks that other dispatchers
+// skip, so each is published by one instance; one published right before a crash is
+// published again after it, which clients recognize by its sequence number. Returns how
+// many events were published
+func (db *DB) PublishPendingChanges(limit int, publish func(models.MindMapChange)) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, mind_map_id, seq, event_type, payload, created_at
+		FROM outbox_events
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var changes []models.MindMapChange
+	for rows.Next() {
+		var id int64
+		var change models.MindMapChange
+		var payload []byte
+		if err := rows.Scan(&id, &change.MindMapID, &change.Seq, &change.Type, &payload, &change.CreatedAt); err != nil {
+			return 0, err
+		}
+		change.Payload = json.RawMessage(payload)
+		ids = append(ids, id)
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, change := range changes {
+		publish(change)
+	}
+
+	if _, err := tx.Exec(`UPDATE outbox_events SET published_at = NOW() WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// PrunePublishedChanges deletes outbox events published before the given time. The change
+// log keeps them for resyncing clients, so the outbox only needs undelivered ones
+func (db *DB) PrunePublishedChanges(before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM outbox_events WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,18 @@
+package database
+
+// GetUserPlan returns the plan a user is on. Active, trialing and past-due subscriptions
+// count as paid, as do cancelled ones until their end date; everything else is free
+func (db *DB) GetUserPlan(userID string) (string, error) {
+	query := `
+		SELECT CASE
+			WHEN latest_status IN ('active', 'on_trial', 'past_due') THEN 'paid'
+			WHEN latest_status = 'cancelled' AND latest_end_date > NOW() THEN 'paid'
+			ELSE 'free'
+		END
+		FROM users
+		WHERE id = $1`
+
+	var plan string
+	err := db.QueryRow(query, userID).Scan(&plan)
+	return plan, err
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+)
+
+// ApplyProofreadSuggestions writes suggested content back to the nodes of a mind map in a
+// single transaction and reindexes their links. A node is only changed while its content
+// still matches the proofread original, so edits made in the meantime are not overwritten.
+// Records a node.updated change for each updated node in the same transaction. Returns the
+// updated nodes
+func (db *DB) ApplyProofreadSuggestions(mindMapID string, suggestions []models.ProofreadSuggestion) ([]models.Node, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	updated := make([]models.Node, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		node, err := scanNode(tx.QueryRow(`
+			UPDATE nodes
+			SET content = $3, updated_at = NOW()
+			WHERE id = $1 AND mind_map_id = $2 AND content = $4
+			RETURNING `+nodeColumns,
+			suggestion.NodeID, mindMapID, suggestion.Suggested, suggestion.Original))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := syncNodeLinks(tx, node.ID, node.MindMapID, node.Content, node.NodeType, node.Metadata); err != nil {
+			return nil, err
+		}
+		masked := *node
+		masked.MaskAttribution()
+		if _, err := recordChange(tx, mindMapID, "node.updated", masked); err != nil {
+			return nil, err
+		}
+		updated = append(updated, *node)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return updated, nil
+}
@@ -0,0 +1,36 @@
+package database
+
+// AttachReplica connects to a read replica. Read-heavy queries such as mind map
+// listings and details are served from it, everything else stays on the primary
+func (db *DB) AttachReplica(dataSourceName string) error {
+	replica, err := New(dataSourceName)
+	if err != nil {
+		return err
+	}
+	db.replica = replica.DB
+	return nil
+}
+
+// Primary returns a view of the database that serves every query from the primary.
+// Use it when a request must read its own writes despite replication lag
+func (db *DB) Primary() *DB {
+	return &DB{DB: db.DB, changes: db.changes, tenantID: db.tenantID}
+}
+
+// reader returns the database read-only queries should use: the replica when one is attached
+func (db *DB) reader() *DB {
+	if db.replica == nil {
+		return db
+	}
+	return &DB{DB: db.replica, tenantID: db.tenantID}
+}
+
+// Close closes the primary connection and the replica connection, if any
+func (db *DB) Close() error {
+	if db.replica != nil {
+		if err := db.replica.Close(); err != nil {
+			return err
+		}
+	}
+	return db.DB.Close()
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+)
+
+// retentionPolicyColumns lists the retention policy columns in the order
+// scanRetentionPolicy expects
+const retentionPolicyColumns = `tenant_id, purge_trash_after_days, archive_after_days, history_depth, updated_at`
+
+// scanRetentionPolicy scans a retention policy row selected with retentionPolicyColumns
+func scanRetentionPolicy(row rowScanner) (*models.RetentionPolicy, error) {
+	var policy models.RetentionPolicy
+	var purgeTrash, archive, historyDepth sql.NullInt64
+	var updatedAt time.Time
+	if err := row.Scan(&policy.TenantID, &purgeTrash, &archive, &historyDepth, &updatedAt); err != nil {
+		return nil, err
+	}
+	policy.PurgeTrashAfterDays = nullIntPtr(purgeTrash)
+	policy.ArchiveAfterDays = nullIntPtr(archive)
+	policy.HistoryDepth = nullIntPtr(historyDepth)
+	policy.UpdatedAt = &updatedAt
+	return &policy, nil
+}
+
+// nullIntPtr returns a nullable integer column as a pointer, nil for NULL
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
+
+// GetRetentionPolicy retrieves the retention policy of a tenant. A tenant without one gets
+// a policy with every rule off
+func (db *DB) GetRetentionPolicy(tenantID string) (*models.RetentionPolicy, error) {
+	policy, err := scanRetentionPolicy(db.QueryRow(`
+		SELECT `+retentionPolicyColumns+`
+		FROM retention_policies
+		WHERE tenant_id = $1`, tenantID))
+	if err == sql.ErrNoRows {
+		return &models.RetentionPolicy{TenantID: tenantID}, nil
+	}
+	return policy, err
+}
+
+// SetRetentionPolicy replaces the retention policy of a tenant, recording who changed it
+func (db *DB) SetRetentionPolicy(tenantID, userID string, req models.RetentionPolicyRequest) (*models.RetentionPolicy, error) {
+	return scanRetentionPolicy(db.QueryRow(`
+		INSERT INTO retention_policies (tenant_id, purge_trash_after_days, archive_after_days, history_depth, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET purge_trash_after_days = $2, archive_after_days = $3, history_depth = $4, updated_by = $5
+		RETURNING `+retentionPolicyColumns,
+		tenantID, req.PurgeTrashAfterDays, req.ArchiveAfterDays, req.HistoryDepth, userID))
+}
+
+// GetRetentionPolicies lists the retention policies that have at least one rule on
+func (db *DB) GetRetentionPolicies() ([]models.RetentionPolicy, error) {
+	rows, err := db.Query(`
+		SELECT ` + retentionPolicyColumns + `
+		FROM retention_policies
+		WHERE purge_trash_after_days IS NOT NULL OR archive_after_days IS NOT NULL OR history_depth IS NOT NULL
+		ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []models.RetentionPolicy{}
+	for rows.Next() {
+		policy, err := scanRetentionPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, rows.Err()
+}
+
+// PurgeDeletedMindMaps permanently removes a tenant's mind maps that were moved to the
+// trash before the given time, along with everything in them. Maps under a legal hold are
+// kept. Returns how many were purged
+func (db *DB) PurgeDeletedMindMaps(tenantID string, before time.Time) (int64, error) {
+	result, err := db.Exec(`
+		DELETE FROM mind_maps
+		WHERE tenant_id = $1 AND status = 'deleted' AND deleted_at < $2
+		AND `+legalHoldExcluded, tenantID, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ArchiveInactiveMindMaps archives a tenant's active mind maps that have not changed since
+// the given time. Inboxes are left active, as they keep receiving captured ideas. Returns
+// how many were archived
+func (db *DB) ArchiveInactiveMindMaps(tenantID string, before time.Time) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE mind_maps SET status = 'archived'
+		WHERE tenant_id = $1 AND status = 'active' AND NOT is_inbox AND updated_at < $2`, tenantID, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TrimMindMapChanges removes all but the latest depth change log entries of each of a
+// tenant's mind maps. Sequence numbers have no gaps, so the entries to keep are those
+// within depth of the map's latest. The history of maps under a legal hold is kept.
+// Returns how many entries were removed
+func (db *DB) TrimMindMapChanges(tenantID string, depth int) (int64, error) {
+	result, err := db.Exec(`
+		DELETE FROM mind_map_changes c
+		USING mind_maps
+		WHERE c.mind_map_id = mind_maps.id AND mind_maps.tenant_id = $1 AND c.seq <= mind_maps.change_seq - $2
+		AND `+legalHoldExcluded, tenantID, depth)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrSavedFilterNameTaken is returned when a user already has a saved filter with the same name
+var ErrSavedFilterNameTaken = errors.New("a saved filter with this name already exists")
+
+// savedFilterColumns lists the saved filter columns in the order scanSavedFilter expects
+const savedFilterColumns = `id, user_id, name, query, created_at, updated_at`
+
+// scanSavedFilter scans a saved filter row selected with savedFilterColumns
+func scanSavedFilter(row rowScanner) (*models.SavedFilter, error) {
+	var filter models.SavedFilter
+	err := row.Scan(
+		&filter.ID,
+		&filter.UserID,
+		&filter.Name,
+		&filter.Query,
+		&filter.CreatedAt,
+		&filter.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrSavedFilterNameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// CreateSavedFilter stores a new saved filter for a user
+func (db *DB) CreateSavedFilter(userID string, req models.SavedFilterRequest) (*models.SavedFilter, error) {
+	query := `
+		INSERT INTO saved_filters (user_id, name, query, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING ` + savedFilterColumns
+
+	return scanSavedFilter(db.QueryRow(query, userID, req.Name, req.Query))
+}
+
+// GetSavedFiltersByUserID retrieves all saved filters of a user ordered by name
+func (db *DB) GetSavedFiltersByUserID(userID string) ([]models.SavedFilter, error) {
+	query := `
+		SELECT ` + savedFilterColumns + `
+		FROM saved_filters
+		WHERE user_id = $1
+		ORDER BY name`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filters := []models.SavedFilter{}
+	for rows.Next() {
+		filter, err := scanSavedFilter(rows)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, *filter)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
+
+// GetSavedFilter retrieves a saved filter belonging to a user
+func (db *DB) GetSavedFilter(id, userID string) (*models.SavedFilter, error) {
+	query := `
+		SELECT ` + savedFilterColumns + `
+		FROM saved_filters
+		WHERE id = $1 AND user_id = $2`
+
+	return scanSavedFilter(db.QueryRow(query, id, userID))
+}
+
+// UpdateSavedFilter renames a saved filter or replaces its query
+func (db *DB) UpdateSavedFilter(id, userID string, req models.SavedFilterRequest) (*models.SavedFilter, error) {
+	query := `
+		UPDATE saved_filters
+		SET name = $3, query = $4, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + savedFilterColumns
+
+	return scanSavedFilter(db.QueryRow(query, id, userID, req.Name, req.Query))
+}
+
+// DeleteSavedFilter deletes a saved filter belonging to a user
+func (db *DB) DeleteSavedFilter(id, userID string) error {
+	result, err := db.Exec(`DELETE FROM saved_filters WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetAccessibleMindMaps retrieves every mind map a user owns or has been shared with,
+// excluding deleted maps. Served from the replica
+func (db *DB) GetAccessibleMindMaps(userID string) ([]models.MindMap, error) {
+	query := `
+		SELECT ` + mindMapColumns + `
+		FROM mind_maps
+		WHERE status != 'deleted' AND (
+			user_id = $1 OR id IN (
+				SELECT s.mind_map_id
+				FROM mind_map_shares s
+				JOIN users u ON LOWER(u.email) = s.email
+				WHERE u.id = $1
+			)
+		)
+		ORDER BY updated_at DESC`
+
+	rows, err := db.reader().Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mindMaps []models.MindMap
+	for rows.Next() {
+		mindMap, err := scanMindMap(rows)
+		if err != nil {
+			return nil, err
+		}
+		mindMaps = append(mindMaps, *mindMap)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mindMaps, nil
+}
+
+// GetNodesByMindMapIDs retrieves the nodes of several mind maps at once. Served from the replica
+func (db *DB) GetNodesByMindMapIDs(mindMapIDs []string) ([]models.Node, error) {
+	query := `
+		SELECT ` + nodeColumns + `
+		FROM nodes
+		WHERE mind_map_id = ANY($1)
+		ORDER BY mind_map_id, position_y, position_x`
+
+	rows, err := db.reader().Query(query, pq.Array(mindMapIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []models.Node
+	for rows.Next() {
+		node, err := scanNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
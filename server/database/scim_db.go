@@ -0,0 +1,338 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrSCIMGroupNameTaken is returned when a tenant already has a group with the same name
+var ErrSCIMGroupNameTaken = errors.New("a group with this name already exists")
+
+// SetSCIMToken stores the hash of a tenant's SCIM token, replacing its previous token.
+// Returns ErrNotFound if the tenant does not exist
+func (db *DB) SetSCIMToken(tenantID, tokenHash string) error {
+	_, err := db.Exec(`
+		INSERT INTO scim_tokens (tenant_id, token_hash, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET token_hash = $2, created_at = NOW()`,
+		tenantID, tokenHash)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetSCIMTokenTenantID returns the tenant a SCIM token hash belongs to
+func (db *DB) GetSCIMTokenTenantID(tokenHash string) (string, error) {
+	var tenantID string
+	err := db.QueryRow(`SELECT tenant_id FROM scim_tokens WHERE token_hash = $1`, tokenHash).Scan(&tenantID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return tenantID, err
+}
+
+// tenantMemberColumns lists the user columns in the order scanTenantMember expects
+const tenantMemberColumns = `id, email, name, deactivated_at IS NULL, created_at, updated_at`
+
+// scanTenantMember scans a user row selected with tenantMemberColumns
+func scanTenantMember(row rowScanner) (*models.TenantMember, error) {
+	var member models.TenantMember
+	err := row.Scan(&member.ID, &member.Email, &member.Name, &member.Active, &member.CreatedAt, &member.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetTenantMembers returns a page of a tenant's users, oldest first, and how many there
+// are in total. A non-empty email limits them to the user with that email
+func (db *DB) GetTenantMembers(tenantID, email string, offset, limit int) ([]models.TenantMember, int, error) {
+	var total int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM users
+		WHERE tenant_id = $1 AND ($2 = '' OR LOWER(email) = LOWER($2))`, tenantID, email).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT `+tenantMemberColumns+`
+		FROM users
+		WHERE tenant_id = $1 AND ($2 = '' OR LOWER(email) = LOWER($2))
+		ORDER BY created_at, id
+		OFFSET $3 LIMIT $4`, tenantID, email, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	members := []models.TenantMember{}
+	for rows.Next() {
+		member, err := scanTenantMember(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		members = append(members, *member)
+	}
+	return members, total, rows.Err()
+}
+
+// GetTenantMember retrieves a user of a tenant. Returns ErrNotFound if the user does not
+// exist or belongs to another tenant
+func (db *DB) GetTenantMember(tenantID, userID string) (*models.TenantMember, error) {
+	return scanTenantMember(db.QueryRow(`
+		SELECT `+tenantMemberColumns+` FROM users WHERE id = $1 AND tenant_id = $2`, userID, tenantID))
+}
+
+// UpdateTenantMember changes the name and email of a user of a tenant
+func (db *DB) UpdateTenantMember(tenantID, userID, name, email string) error {
+	result, err := db.Exec(`
+		UPDATE users SET name = $3, email = $4, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2`, userID, tenantID, name, email)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetTenantMemberActive activates or deactivates a user of a tenant. Deactivating also
+// revokes the user's refresh tokens; their access tokens stop working as the auth
+// middleware rejects deactivated users
+func (db *DB) SetTenantMemberActive(tenantID, userID string, active bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET deactivated_at = CASE WHEN $3 THEN NULL ELSE COALESCE(deactivated_at, NOW()) END, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2`, userID, tenantID, active)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	if !active {
+		if _, err := tx.Exec(`DELETE FROM refresh_tokens WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// IsUserDeactivated reports whether a user has been deactivated by their identity provider
+func (db *DB) IsUserDeactivated(userID string) (bool, error) {
+	var deactivated bool
+	err := db.QueryRow(`SELECT deactivated_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&deactivated)
+	if err == sql.ErrNoRows {
+		return false, ErrNotFound
+	}
+	return deactivated, err
+}
+
+// scimGroupQuery selects groups with their members in the order scanSCIMGroup expects;
+// callers append a WHERE clause before scimGroupGroupBy
+const scimGroupQuery = `
+	SELECT g.id, g.tenant_id, g.display_name, COALESCE(g.external_id, ''),
+		COALESCE(array_agg(u.id ORDER BY u.email) FILTER (WHERE u.id IS NOT NULL), '{}'),
+		COALESCE(array_agg(u.email ORDER BY u.email) FILTER (WHERE u.id IS NOT NULL), '{}'),
+		g.created_at, g.updated_at
+	FROM scim_groups g
+	LEFT JOIN scim_group_members m ON m.group_id = g.id
+	LEFT JOIN users u ON u.id = m.user_id`
+
+const scimGroupGroupBy = `
+	GROUP BY g.id`
+
+// scanSCIMGroup scans a group row selected with scimGroupQuery
+func scanSCIMGroup(row rowScanner) (*models.SCIMGroup, error) {
+	var group models.SCIMGroup
+	var userIDs, emails []string
+	err := row.Scan(
+		&group.ID,
+		&group.TenantID,
+		&group.DisplayName,
+		&group.ExternalID,
+		pq.Array(&userIDs),
+		pq.Array(&emails),
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	group.Members = make([]models.SCIMGroupMember, len(userIDs))
+	for i := range userIDs {
+		group.Members[i] = models.SCIMGroupMember{UserID: userIDs[i], Email: emails[i]}
+	}
+	return &group, nil
+}
+
+// CreateSCIMGroup creates a tenant's group. Returns ErrSCIMGroupNameTaken if the tenant
+// already has a group with the name
+func (db *DB) CreateSCIMGroup(tenantID, displayName, externalID string) (*models.SCIMGroup, error) {
+	var id string
+	err := db.QueryRow(`
+		INSERT INTO scim_groups (tenant_id, display_name, external_id, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NOW(), NOW())
+		RETURNING id`, tenantID, displayName, externalID).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrSCIMGroupNameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.GetSCIMGroup(tenantID, id)
+}
+
+// GetSCIMGroups returns a page of a tenant's groups, by name, and how many there are in
+// total. A non-empty name limits them to the group with that name
+func (db *DB) GetSCIMGroups(tenantID, displayName string, offset, limit int) ([]models.SCIMGroup, int, error) {
+	var total int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM scim_groups
+		WHERE tenant_id = $1 AND ($2 = '' OR display_name = $2)`, tenantID, displayName).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(scimGroupQuery+`
+		WHERE g.tenant_id = $1 AND ($2 = '' OR g.display_name = $2)`+scimGroupGroupBy+`
+		ORDER BY g.display_name, g.id
+		OFFSET $3 LIMIT $4`, tenantID, displayName, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	groups := []models.SCIMGroup{}
+	for rows.Next() {
+		group, err := scanSCIMGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, *group)
+	}
+	return groups, total, rows.Err()
+}
+
+// GetSCIMGroup retrieves a tenant's group with its members
+func (db *DB) GetSCIMGroup(tenantID, groupID string) (*models.SCIMGroup, error) {
+	return scanSCIMGroup(db.QueryRow(scimGroupQuery+`
+		WHERE g.id = $1 AND g.tenant_id = $2`+scimGroupGroupBy, groupID, tenantID))
+}
+
+// RenameSCIMGroup changes the name and external ID of a tenant's group
+func (db *DB) RenameSCIMGroup(tenantID, groupID, displayName, externalID string) error {
+	result, err := db.Exec(`
+		UPDATE scim_groups SET display_name = $3, external_id = NULLIF($4, ''), updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2`, groupID, tenantID, displayName, externalID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrSCIMGroupNameTaken
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddSCIMGroupMembers adds users to a group. Users outside the group's tenant are skipped
+func (db *DB) AddSCIMGroupMembers(groupID string, userIDs []string) error {
+	_, err := db.Exec(`
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT g.id, u.id
+		FROM scim_groups g
+		JOIN users u ON u.tenant_id = g.tenant_id
+		WHERE g.id = $1 AND u.id::text = ANY($2)
+		ON CONFLICT DO NOTHING`, groupID, pq.Array(userIDs))
+	return err
+}
+
+// RemoveSCIMGroupMembers removes users from a group
+func (db *DB) RemoveSCIMGroupMembers(groupID string, userIDs []string) error {
+	_, err := db.Exec(`
+		DELETE FROM scim_group_members WHERE group_id = $1 AND user_id::text = ANY($2)`,
+		groupID, pq.Array(userIDs))
+	return err
+}
+
+// ClearSCIMGroupMembers removes every user from a group
+func (db *DB) ClearSCIMGroupMembers(groupID string) error {
+	_, err := db.Exec(`DELETE FROM scim_group_members WHERE group_id = $1`, groupID)
+	return err
+}
+
+// DeleteSCIMGroup removes a tenant's group
+func (db *DB) DeleteSCIMGroup(tenantID, groupID string) error {
+	result, err := db.Exec(`DELETE FROM scim_groups WHERE id = $1 AND tenant_id = $2`, groupID, tenantID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetUserSCIMGroupNames returns the names of the groups a user is in
+func (db *DB) GetUserSCIMGroupNames(userID string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT g.display_name
+		FROM scim_group_members m
+		JOIN scim_groups g ON g.id = m.group_id
+		WHERE m.user_id = $1
+		ORDER BY g.display_name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
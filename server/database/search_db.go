@@ -0,0 +1,48 @@
+package database
+
+import (
+	"saas-server/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// likeEscaper escapes the LIKE wildcards so search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchNodes returns the nodes of a mind map whose content contains every term, ignoring
+// case, ordered top-to-bottom and left-to-right on the canvas. Served from the replica
+func (db *DB) SearchNodes(mindMapID string, terms []string, limit int) ([]models.Node, error) {
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		patterns[i] = "%" + likeEscaper.Replace(term) + "%"
+	}
+
+	query := `
+		SELECT ` + nodeColumns + `
+		FROM nodes
+		WHERE mind_map_id = $1 AND content ILIKE ALL($2)
+		ORDER BY position_y, position_x, id
+		LIMIT $3`
+
+	rows, err := db.reader().Query(query, mindMapID, pq.Array(patterns), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []models.Node
+	for rows.Next() {
+		node, err := scanNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
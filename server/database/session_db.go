@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sessionColumns lists the brainstorm_sessions columns in the order scanSession expects
+const sessionColumns = `id, mind_map_id, created_by, mode, anonymous, participants,
+	started_at, ends_at, stopped_at, authorship_revealed, revealed_at, created_at, updated_at`
+
+// scanSession scans a brainstorm session row selected with sessionColumns
+func scanSession(row rowScanner) (*models.BrainstormSession, error) {
+	var session models.BrainstormSession
+	var endsAt, stoppedAt, revealedAt sql.NullTime
+
+	err := row.Scan(
+		&session.ID,
+		&session.MindMapID,
+		&session.CreatedBy,
+		&session.Mode,
+		&session.Anonymous,
+		pq.Array(&session.Participants),
+		&session.StartedAt,
+		&endsAt,
+		&stoppedAt,
+		&session.AuthorshipRevealed,
+		&revealedAt,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if endsAt.Valid {
+		session.EndsAt = &endsAt.Time
+	}
+	if stoppedAt.Valid {
+		session.StoppedAt = &stoppedAt.Time
+	}
+	if revealedAt.Valid {
+		session.RevealedAt = &revealedAt.Time
+	}
+	if session.Participants == nil {
+		session.Participants = []string{}
+	}
+
+	return &session, nil
+}
+
+// CreateSession starts a new brainstorm session on a mind map, recording a session.started
+// change in the same transaction. A positive req.DurationMinutes makes the session end that
+// long after it starts
+func (db *DB) CreateSession(mindMapID, userID string, req models.SessionStartRequest) (*models.BrainstormSession, error) {
+	participants := req.Participants
+	if participants == nil {
+		participants = []string{}
+	}
+
+	query := `
+		INSERT INTO brainstorm_sessions (mind_map_id, created_by, mode, anonymous, participants,
+		                                 started_at, ends_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(),
+		        CASE WHEN $6 > 0 THEN NOW() + make_interval(mins => $6) END,
+		        NOW(), NOW())
+		RETURNING ` + sessionColumns
+
+	return db.writeSession("session.started", query,
+		mindMapID,
+		userID,
+		req.Mode,
+		req.Anonymous,
+		pq.Array(participants),
+		req.DurationMinutes,
+	)
+}
+
+// GetActiveSession retrieves the running session of a mind map, returning ErrNotFound if there is none
+func (db *DB) GetActiveSession(mindMapID string) (*models.BrainstormSession, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM brainstorm_sessions
+		WHERE mind_map_id = $1
+		  AND stopped_at IS NULL
+		  AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY started_at DESC
+		LIMIT 1`
+
+	session, err := scanSession(db.QueryRow(query, mindMapID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return session, err
+}
+
+// UpdateSession changes the mode or anonymity of a running session, recording a
+// session.updated change in the same transaction
+func (db *DB) UpdateSession(id string, req models.SessionUpdateRequest) (*models.BrainstormSession, error) {
+	query := `
+		UPDATE brainstorm_sessions
+		SET mode = COALESCE(NULLIF($2, ''), mode),
+		    anonymous = COALESCE($3, anonymous),
+		    updated_at = NOW()
+		WHERE id = $1 AND stopped_at IS NULL
+		RETURNING ` + sessionColumns
+
+	return db.writeSession("session.updated", query, id, req.Mode, req.Anonymous)
+}
+
+// StopSession ends a running session immediately, recording a session.stopped change in the
+// same transaction
+func (db *DB) StopSession(id string) (*models.BrainstormSession, error) {
+	query := `
+		UPDATE brainstorm_sessions
+		SET stopped_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND stopped_at IS NULL
+		RETURNING ` + sessionColumns
+
+	return db.writeSession("session.stopped", query, id)
+}
+
+// EndSession records a session.ended change for a timed session that has run out. The
+// session row is locked while checking it was not stopped early, so a concurrent stop
+// records session.stopped instead. Returns ErrNotFound if there is no such running session
+func (db *DB) EndSession(id string) (*models.BrainstormSession, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM brainstorm_sessions
+		WHERE id = $1 AND stopped_at IS NULL
+		FOR UPDATE`
+
+	return db.writeSession("session.ended", query, id)
+}
+
+// GetSessionByID retrieves a brainstorm session by its ID
+func (db *DB) GetSessionByID(id string) (*models.BrainstormSession, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM brainstorm_sessions
+		WHERE id = $1`
+
+	session, err := scanSession(db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return session, err
+}
+
+// GetLatestSession retrieves the most recently started session of a mind map, running or not
+func (db *DB) GetLatestSession(mindMapID string) (*models.BrainstormSession, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM brainstorm_sessions
+		WHERE mind_map_id = $1
+		ORDER BY started_at DESC
+		LIMIT 1`
+
+	session, err := scanSession(db.QueryRow(query, mindMapID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return session, err
+}
+
+// RevealSessionAuthorship unmasks the authors of anonymous contributions made during a
+// session, recording a session.authorship_revealed change in the same transaction
+func (db *DB) RevealSessionAuthorship(id string) (*models.BrainstormSession, error) {
+	query := `
+		UPDATE brainstorm_sessions
+		SET authorship_revealed = TRUE,
+		    revealed_at = COALESCE(revealed_at, NOW()),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + sessionColumns
+
+	return db.writeSession("session.authorship_revealed", query, id)
+}
+
+// writeSession runs a query returning a session row in a transaction, recording a change of
+// the given type with the session in its mind map's log. Returns ErrNotFound if the query
+// returns no row
+func (db *DB) writeSession(eventType, query string, args ...interface{}) (*models.BrainstormSession, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := scanSession(tx.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := recordChange(tx, session.MindMapID, eventType, session); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return session, nil
+}
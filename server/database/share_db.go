@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ShareMindMap grants the user with the given email access to a mind map,
+// updating the permission if the map is already shared with them
+func (db *DB) ShareMindMap(mindMapID, email, permission string) (*models.MindMapShare, error) {
+	return shareMindMapTx(db.DB, mindMapID, email, permission)
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// shareMindMapTx inserts or updates a share using either the connection pool or a transaction
+func shareMindMapTx(q queryRower, mindMapID, email, permission string) (*models.MindMapShare, error) {
+	query := `
+		INSERT INTO mind_map_shares (mind_map_id, email, permission, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (mind_map_id, email) DO UPDATE SET permission = EXCLUDED.permission
+		RETURNING id, mind_map_id, email, permission, created_at`
+
+	var share models.MindMapShare
+	err := q.QueryRow(query, mindMapID, strings.ToLower(email), permission).Scan(
+		&share.ID,
+		&share.MindMapID,
+		&share.Email,
+		&share.Permission,
+		&share.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetMindMapSharePermission returns the permission a user has been granted on a mind map,
+// or an empty string if the map has not been shared with them. Edit shares only grant
+// viewers of their tenant view access
+func (db *DB) GetMindMapSharePermission(mindMapID, userID string) (string, error) {
+	query := `
+		SELECT CASE WHEN s.permission = 'edit' AND u.tenant_role = 'viewer' THEN 'view' ELSE s.permission END
+		FROM mind_map_shares s
+		JOIN users u ON LOWER(u.email) = s.email
+		WHERE s.mind_map_id = $1 AND u.id = $2`
+
+	var permission string
+	err := db.QueryRow(query, mindMapID, userID).Scan(&permission)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return permission, nil
+}
+
+// InMindMapWorkspace reports whether a user belongs to the organization of a mind map's
+// owner. Users without an organization of their own share no workspace with anyone
+func (db *DB) InMindMapWorkspace(mindMapID, userID string) (bool, error) {
+	var member bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM mind_maps m
+			JOIN users u ON u.tenant_id = m.tenant_id
+			WHERE m.id = $1 AND u.id = $2 AND u.deactivated_at IS NULL AND m.tenant_id != $3
+		)`, mindMapID, userID, DefaultTenantID).Scan(&member)
+	return member, err
+}
+
+// GetMindMapPeopleNames returns the names of the owners of the given mind maps and of the
+// users the maps are shared with
+func (db *DB) GetMindMapPeopleNames(mindMapIDs []string) ([]string, error) {
+	query := `
+		SELECT u.name
+		FROM users u
+		JOIN mind_maps m ON m.user_id = u.id
+		WHERE m.id = ANY($1)
+		UNION
+		SELECT u.name
+		FROM users u
+		JOIN mind_map_shares s ON LOWER(u.email) = s.email
+		WHERE s.mind_map_id = ANY($1)`
+
+	rows, err := db.Query(query, pq.Array(mindMapIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+)
+
+// shareLinkColumns lists the share link columns in the order scanShareLink expects
+const shareLinkColumns = `id, mind_map_id, created_by, password_hash, expires_at, revoked_at, created_at`
+
+// scanShareLink scans a share link row selected with shareLinkColumns
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	var link models.ShareLink
+	var createdBy, passwordHash sql.NullString
+	var expiresAt, revokedAt sql.NullTime
+	err := row.Scan(&link.ID, &link.MindMapID, &createdBy, &passwordHash, &expiresAt, &revokedAt, &link.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		link.CreatedBy = &createdBy.String
+	}
+	link.PasswordHash = passwordHash.String
+	link.HasPassword = passwordHash.Valid
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		link.RevokedAt = &revokedAt.Time
+	}
+	return &link, nil
+}
+
+// CreateShareLink creates a share link of a mind map, stored by the hash of its token. An
+// empty password hash leaves the link without a password, and a nil expiry keeps it valid
+// until it is removed
+func (db *DB) CreateShareLink(mindMapID, createdBy, tokenHash, passwordHash string, expiresAt *time.Time) (*models.ShareLink, error) {
+	return scanShareLink(db.QueryRow(`
+		INSERT INTO share_links (mind_map_id, token_hash, created_by, password_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NOW())
+		RETURNING `+shareLinkColumns,
+		mindMapID, tokenHash, createdBy, passwordHash, expiresAt))
+}
+
+// GetShareLinks retrieves the share links of a mind map, newest first, including expired
+// and revoked ones
+func (db *DB) GetShareLinks(mindMapID string) ([]models.ShareLink, error) {
+	rows, err := db.Query(`
+		SELECT `+shareLinkColumns+`
+		FROM share_links
+		WHERE mind_map_id = $1
+		ORDER BY created_at DESC`, mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// GetShareLink retrieves a share link of a mind map. Returns ErrNotFound if there is none
+func (db *DB) GetShareLink(mindMapID, linkID string) (*models.ShareLink, error) {
+	return scanShareLink(db.QueryRow(`
+		SELECT `+shareLinkColumns+`
+		FROM share_links
+		WHERE id = $1 AND mind_map_id = $2`, linkID, mindMapID))
+}
+
+// GetShareLinkByToken retrieves the share link with the given token hash. Returns
+// ErrNotFound if there is none or it has expired or been revoked
+func (db *DB) GetShareLinkByToken(tokenHash string) (*models.ShareLink, error) {
+	return scanShareLink(db.QueryRow(`
+		SELECT `+shareLinkColumns+`
+		FROM share_links
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`, tokenHash))
+}
+
+// RevokeShareLink revokes a share link of a mind map and ends its view sessions in a single
+// transaction. Revoking a revoked link keeps its first revocation. Returns ErrNotFound if
+// the map has no such link
+func (db *DB) RevokeShareLink(mindMapID, linkID string) (*models.ShareLink, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	link, err := scanShareLink(tx.QueryRow(`
+		UPDATE share_links
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND mind_map_id = $2
+		RETURNING `+shareLinkColumns, linkID, mindMapID))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM share_link_sessions WHERE share_link_id = $1`, linkID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// RecordShareLinkEvent records a use of a share link with the host of the page that
+// referred it, empty for direct visits
+func (db *DB) RecordShareLinkEvent(linkID, eventType, referrer string) error {
+	_, err := db.Exec(`
+		INSERT INTO share_link_events (share_link_id, event_type, referrer, created_at)
+		VALUES ($1, $2, $3, NOW())`, linkID, eventType, referrer)
+	return err
+}
+
+// GetShareLinkAnalytics counts the uses of a share link since a time, in all, by referrer
+// and by day, along with when it was last used at all
+func (db *DB) GetShareLinkAnalytics(link models.ShareLink, since time.Time) (*models.ShareLinkAnalytics, error) {
+	analytics := &models.ShareLinkAnalytics{
+		ShareLink: link,
+		Since:     since,
+		Referrers: []models.ShareLinkReferrer{},
+		Daily:     []models.ShareLinkDay{},
+	}
+
+	var lastUsedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE event_type = 'open' AND created_at >= $2),
+			COUNT(*) FILTER (WHERE event_type = 'export' AND created_at >= $2),
+			MAX(created_at)
+		FROM share_link_events
+		WHERE share_link_id = $1`, link.ID, since).Scan(&analytics.Opens, &analytics.Exports, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		analytics.LastUsedAt = &lastUsedAt.Time
+	}
+
+	rows, err := db.Query(`
+		SELECT referrer,
+			COUNT(*) FILTER (WHERE event_type = 'open'),
+			COUNT(*) FILTER (WHERE event_type = 'export')
+		FROM share_link_events
+		WHERE share_link_id = $1 AND created_at >= $2
+		GROUP BY referrer
+		ORDER BY COUNT(*) DESC, referrer`, link.ID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var referrer models.ShareLinkReferrer
+		if err := rows.Scan(&referrer.Referrer, &referrer.Opens, &referrer.Exports); err != nil {
+			return nil, err
+		}
+		analytics.Referrers = append(analytics.Referrers, referrer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`
+		SELECT DATE_TRUNC('day', created_at) AS day,
+			COUNT(*) FILTER (WHERE event_type = 'open'),
+			COUNT(*) FILTER (WHERE event_type = 'export')
+		FROM share_link_events
+		WHERE share_link_id = $1 AND created_at >= $2
+		GROUP BY day
+		ORDER BY day`, link.ID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day models.ShareLinkDay
+		if err := rows.Scan(&day.Day, &day.Opens, &day.Exports); err != nil {
+			return nil, err
+		}
+		analytics.Daily = append(analytics.Daily, day)
+	}
+	return analytics, rows.Err()
+}
+
+// CreateShareLinkSession stores a view session of a share link, by the hash of its token,
+// expiring at the given time. Expired sessions of every link are purged along the way
+func (db *DB) CreateShareLinkSession(linkID, tokenHash string, expiresAt time.Time) error {
+	if _, err := db.Exec(`DELETE FROM share_link_sessions WHERE expires_at <= NOW()`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`
+		INSERT INTO share_link_sessions (token_hash, share_link_id, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())`, tokenHash, linkID, expiresAt)
+	return err
+}
+
+// HasShareLinkSession reports whether a share link has a view session with the given token
+// hash that has not expired
+func (db *DB) HasShareLinkSession(linkID, tokenHash string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM share_link_sessions
+			WHERE token_hash = $1 AND share_link_id = $2 AND expires_at > NOW()
+		)`, tokenHash, linkID).Scan(&exists)
+	return exists, err
+}
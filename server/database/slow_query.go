@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSlowQueryThreshold is how long a query may take before it is logged, unless
+// DB_SLOW_QUERY_MS overrides it
+const defaultSlowQueryThreshold = 500 * time.Millisecond
+
+// maxLoggedQueryLength caps how much of a slow query's SQL is logged
+const maxLoggedQueryLength = 500
+
+// slowQueryThreshold reads DB_SLOW_QUERY_MS; 0 turns slow query logging off
+func slowQueryThreshold() time.Duration {
+	value := os.Getenv("DB_SLOW_QUERY_MS")
+	if value == "" {
+		return defaultSlowQueryThreshold
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		log.Printf("[Slow Query] Invalid DB_SLOW_QUERY_MS %q, using %s", value, defaultSlowQueryThreshold)
+		return defaultSlowQueryThreshold
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// slowQueryConnector opens connections that log every query and statement slower than
+// the threshold, inside transactions too. Only the SQL is logged, never the arguments,
+// since those carry user content
+type slowQueryConnector struct {
+	driver.Connector
+	threshold time.Duration
+}
+
+func (c *slowQueryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryConn{Conn: conn, threshold: c.threshold}, nil
+}
+
+// slowQueryConn times queries on a driver connection, passing every optional driver
+// interface through to it
+type slowQueryConn struct {
+	driver.Conn
+	threshold time.Duration
+}
+
+func (c *slowQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer c.logIfSlow(query, time.Now())
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *slowQueryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer c.logIfSlow(query, time.Now())
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *slowQueryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *slowQueryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *slowQueryConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// logIfSlow logs the query if it has been running for longer than the threshold
+func (c *slowQueryConn) logIfSlow(query string, start time.Time) {
+	elapsed := time.Since(start)
+	if elapsed < c.threshold {
+		return
+	}
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLoggedQueryLength {
+		query = query[:maxLoggedQueryLength] + "…"
+	}
+	log.Printf("[Slow Query] %s: %s", elapsed.Round(time.Millisecond), query)
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"saas-server/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ErrSSODomainTaken is returned when a domain already signs in through another connection
+var ErrSSODomainTaken = errors.New("domain already belongs to an SSO connection")
+
+// ssoConnectionQuery selects connections with their domains in the order
+// scanSSOConnection expects; callers append a WHERE clause before ssoConnectionGroupBy
+const ssoConnectionQuery = `
+	SELECT c.id, c.tenant_id, c.name, c.protocol, c.issuer, c.client_id, c.encrypted_client_secret,
+		COALESCE(c.idp_metadata_url, ''), COALESCE(c.idp_metadata, ''),
+		COALESCE(array_agg(d.domain ORDER BY d.domain) FILTER (WHERE d.domain IS NOT NULL), '{}'),
+		c.groups_claim, c.group_roles, c.default_role, c.enforced, c.created_at, c.updated_at
+	FROM sso_connections c
+	LEFT JOIN sso_connection_domains d ON d.connection_id = c.id`
+
+const ssoConnectionGroupBy = `
+	GROUP BY c.id`
+
+// scanSSOConnection scans a connection row selected with ssoConnectionQuery
+func scanSSOConnection(row rowScanner) (*models.SSOConnection, error) {
+	var connection models.SSOConnection
+	var groupRoles []byte
+	err := row.Scan(
+		&connection.ID,
+		&connection.TenantID,
+		&connection.Name,
+		&connection.Protocol,
+		&connection.Issuer,
+		&connection.ClientID,
+		&connection.EncryptedClientSecret,
+		&connection.IDPMetadataURL,
+		&connection.IDPMetadata,
+		pq.Array(&connection.Domains),
+		&connection.GroupsClaim,
+		&groupRoles,
+		&connection.DefaultRole,
+		&connection.Enforced,
+		&connection.CreatedAt,
+		&connection.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(groupRoles, &connection.GroupRoles); err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
+
+// CreateSSOConnection stores a tenant's identity provider together with the email domains
+// that sign in through it. The client secret of an OpenID Connect provider is encrypted
+// like API keys. Returns ErrSSODomainTaken if one of the domains belongs to another
+// connection and ErrNotFound if the tenant does not exist
+func (db *DB) CreateSSOConnection(req models.SSOConnectionCreateRequest) (*models.SSOConnection, error) {
+	var encryptedSecret string
+	if req.ClientSecret != "" {
+		var err error
+		if encryptedSecret, err = encryptAPIKey(req.ClientSecret); err != nil {
+			return nil, err
+		}
+	}
+	groupRoles, err := json.Marshal(req.GroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRow(`
+		INSERT INTO sso_connections (tenant_id, name, protocol, issuer, client_id, encrypted_client_secret,
+			idp_metadata_url, idp_metadata, groups_claim, group_roles, default_role, enforced,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12, NOW(), NOW())
+		RETURNING id`,
+		req.TenantID, req.Name, req.Protocol, req.Issuer, req.ClientID, encryptedSecret,
+		req.IDPMetadataURL, req.IDPMetadata, req.GroupsClaim, groupRoles, req.DefaultRole, req.Enforced,
+	).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, domain := range req.Domains {
+		_, err = tx.Exec(`INSERT INTO sso_connection_domains (domain, connection_id) VALUES ($1, $2)`, domain, id)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrSSODomainTaken
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	connection, err := scanSSOConnection(tx.QueryRow(ssoConnectionQuery+` WHERE c.id = $1`+ssoConnectionGroupBy, id))
+	if err != nil {
+		return nil, err
+	}
+	return connection, tx.Commit()
+}
+
+// GetSSOConnections lists every SSO connection, oldest first
+func (db *DB) GetSSOConnections() ([]models.SSOConnection, error) {
+	rows, err := db.Query(ssoConnectionQuery + ssoConnectionGroupBy + ` ORDER BY c.created_at, c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	connections := []models.SSOConnection{}
+	for rows.Next() {
+		connection, err := scanSSOConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		connections = append(connections, *connection)
+	}
+	return connections, rows.Err()
+}
+
+// GetSSOConnectionByID retrieves an SSO connection
+func (db *DB) GetSSOConnectionByID(id string) (*models.SSOConnection, error) {
+	return scanSSOConnection(db.QueryRow(ssoConnectionQuery+` WHERE c.id = $1`+ssoConnectionGroupBy, id))
+}
+
+// GetSSOConnectionByEmail retrieves the SSO connection the domain of an email signs in
+// through. Returns ErrNotFound if the domain has none
+func (db *DB) GetSSOConnectionByEmail(email string) (*models.SSOConnection, error) {
+	query := ssoConnectionQuery + `
+		WHERE c.id = (SELECT connection_id FROM sso_connection_domains WHERE domain = $1)` + ssoConnectionGroupBy
+	return scanSSOConnection(db.QueryRow(query, EmailDomain(email)))
+}
+
+// GetSSOConnectionByTenant retrieves a tenant's oldest SSO connection, whose group-to-role
+// mapping applies to the groups its identity provider pushes. Returns ErrNotFound if the
+// tenant has none
+func (db *DB) GetSSOConnectionByTenant(tenantID string) (*models.SSOConnection, error) {
+	query := ssoConnectionQuery + ` WHERE c.tenant_id = $1` + ssoConnectionGroupBy + ` ORDER BY c.created_at, c.id LIMIT 1`
+	return scanSSOConnection(db.QueryRow(query, tenantID))
+}
+
+// DeleteSSOConnection removes an SSO connection with its domains and identities. Users it
+// provisioned keep their accounts and tenant
+func (db *DB) DeleteSSOConnection(id string) error {
+	result, err := db.Exec(`DELETE FROM sso_connections WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetSSOClientSecret decrypts the client secret of an SSO connection
+func (db *DB) GetSSOClientSecret(connection *models.SSOConnection) (string, error) {
+	return decryptAPIKey(connection.EncryptedClientSecret)
+}
+
+// SSORequired reports whether the domain of an email belongs to an enforced SSO
+// connection, so its users may not sign in with a password or another provider
+func (db *DB) SSORequired(email string) (bool, error) {
+	var required bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM sso_connection_domains d
+			JOIN sso_connections c ON c.id = d.connection_id
+			WHERE d.domain = $1 AND c.enforced
+		)`, EmailDomain(email)).Scan(&required)
+	return required, err
+}
+
+// GetSSOIdentityUserID returns the user a provider's subject was provisioned as. Returns
+// ErrNotFound if the subject has not signed in before
+func (db *DB) GetSSOIdentityUserID(connectionID, subject string) (string, error) {
+	var userID string
+	err := db.QueryRow(`
+		SELECT user_id FROM sso_identities WHERE connection_id = $1 AND subject = $2`,
+		connectionID, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return userID, err
+}
+
+// LinkSSOIdentity records that a provider's subject signed in as a user
+func (db *DB) LinkSSOIdentity(connectionID, subject, userID string) error {
+	_, err := db.Exec(`
+		INSERT INTO sso_identities (connection_id, subject, user_id, created_at, last_login_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (connection_id, subject) DO UPDATE SET user_id = $3, last_login_at = NOW()`,
+		connectionID, subject, userID)
+	return err
+}
+
+// SetUserTenantRole sets the role a user holds in their tenant
+func (db *DB) SetUserTenantRole(userID, role string) error {
+	_, err := db.Exec(`UPDATE users SET tenant_role = $2, updated_at = NOW() WHERE id = $1`, userID, role)
+	return err
+}
+
+// GetUserTenantRole returns the role a user holds in their tenant. Returns ErrNotFound if
+// the user does not exist
+func (db *DB) GetUserTenantRole(userID string) (string, error) {
+	var role string
+	err := db.QueryRow(`SELECT tenant_role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return role, err
+}
+
+// EmailDomain returns the lowercased domain of an email address, or an empty string if it
+// has none
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
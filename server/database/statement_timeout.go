@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultStatementTimeout is how long Postgres lets a statement run before cancelling it,
+// unless DB_STATEMENT_TIMEOUT_MS overrides it
+const defaultStatementTimeout = 30 * time.Second
+
+// statementTimeout reads DB_STATEMENT_TIMEOUT_MS; 0 lets statements run without a limit
+func statementTimeout() time.Duration {
+	value := os.Getenv("DB_STATEMENT_TIMEOUT_MS")
+	if value == "" {
+		return defaultStatementTimeout
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		log.Printf("[Statement Timeout] Invalid DB_STATEMENT_TIMEOUT_MS %q, using %s", value, defaultStatementTimeout)
+		return defaultStatementTimeout
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// statementTimeoutConnector opens connections on which Postgres cancels any statement that
+// runs longer than the timeout, so a pathological query cannot hold a connection forever
+type statementTimeoutConnector struct {
+	driver.Connector
+	timeout time.Duration
+}
+
+func (c *statementTimeoutConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		return conn, nil
+	}
+	if _, err := execer.ExecContext(ctx, fmt.Sprintf("SET statement_timeout = %d", c.timeout.Milliseconds()), nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
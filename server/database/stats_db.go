@@ -0,0 +1,66 @@
+package database
+
+import (
+	"saas-server/models"
+	"time"
+)
+
+// statsDateLayout is the format of snapshot days
+const statsDateLayout = "2006-01-02"
+
+// RecordDailyStats stores the current node and edge counts of every mind map that is not
+// deleted as the snapshot for day. Days that already have a snapshot are left alone, so
+// the job can run on several instances or be repeated. Returns the number of maps recorded
+func (db *DB) RecordDailyStats(day time.Time) (int64, error) {
+	query := `
+		INSERT INTO mind_map_daily_stats (mind_map_id, day, node_count, edge_count, created_at)
+		SELECT m.id, $1::date,
+			(SELECT COUNT(*) FROM nodes n WHERE n.mind_map_id = m.id),
+			(SELECT COUNT(*) FROM edges e WHERE e.mind_map_id = m.id),
+			NOW()
+		FROM mind_maps m
+		WHERE m.status != 'deleted' AND m.created_at < $1::date + 1
+		ON CONFLICT (mind_map_id, day) DO NOTHING`
+
+	result, err := db.Exec(query, day.UTC().Format(statsDateLayout))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetMindMapStatsHistory retrieves the daily snapshots of a mind map since a day, oldest first,
+// followed by today's live counts. Served from the replica
+func (db *DB) GetMindMapStatsHistory(mindMapID string, since time.Time) ([]models.MindMapStatsPoint, error) {
+	query := `
+		SELECT to_char(day, 'YYYY-MM-DD'), node_count, edge_count
+		FROM mind_map_daily_stats
+		WHERE mind_map_id = $1 AND day >= $2::date AND day < $3::date
+		UNION ALL
+		SELECT $3,
+			(SELECT COUNT(*) FROM nodes WHERE mind_map_id = $1),
+			(SELECT COUNT(*) FROM edges WHERE mind_map_id = $1)
+		ORDER BY 1`
+
+	today := time.Now().UTC().Format(statsDateLayout)
+	rows, err := db.reader().Query(query, mindMapID, since.UTC().Format(statsDateLayout), today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.MindMapStatsPoint{}
+	for rows.Next() {
+		var point models.MindMapStatsPoint
+		if err := rows.Scan(&point.Date, &point.NodeCount, &point.EdgeCount); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"saas-server/models"
+)
+
+// DefaultTenantID is the tenant users belong to until they are moved to one of their own
+const DefaultTenantID = "00000000-0000-0000-0000-000000000000"
+
+// ForTenant returns a view of the database whose tenant-owned reads only return rows of
+// the given tenant; rows of other tenants are reported as not found. An empty tenant
+// returns the database unscoped
+func (db *DB) ForTenant(tenantID string) *DB {
+	scoped := *db
+	scoped.tenantID = tenantID
+	return &scoped
+}
+
+// tenantScope builds the condition that limits a query to the tenant the database is
+// scoped to: " AND <column> = $n", with the tenant appended to args as parameter n. An
+// unscoped database gets an empty condition and args unchanged. Every read of mind maps,
+// nodes, edges and API keys appends it, so a handler that forgets an access check still
+// cannot return another tenant's rows
+func (db *DB) tenantScope(column string, args []interface{}) (string, []interface{}) {
+	if db.tenantID == "" {
+		return "", args
+	}
+	args = append(args, db.tenantID)
+	return fmt.Sprintf(" AND %s = $%d", column, len(args)), args
+}
+
+// GetUserTenantID returns the tenant a user belongs to. Returns ErrNotFound if the user
+// does not exist or has been deactivated
+func (db *DB) GetUserTenantID(userID string) (string, error) {
+	var tenantID string
+	err := db.QueryRow(`SELECT tenant_id FROM users WHERE id = $1 AND deactivated_at IS NULL`, userID).Scan(&tenantID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return tenantID, err
+}
+
+// CreateTenant creates a new, empty tenant
+func (db *DB) CreateTenant(name string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := db.QueryRow(`
+		INSERT INTO tenants (name, created_at)
+		VALUES ($1, NOW())
+		RETURNING id, name, created_at`, name).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// GetTenants lists every tenant, oldest first
+func (db *DB) GetTenants() ([]models.Tenant, error) {
+	rows, err := db.Query(`SELECT id, name, created_at FROM tenants ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []models.Tenant{}
+	for rows.Next() {
+		var tenant models.Tenant
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// MoveUserToTenant moves a user into a tenant. The foreign keys carry the mind maps,
+// nodes, edges and API keys the user owns along. Returns ErrNotFound if the user or the
+// tenant does not exist
+func (db *DB) MoveUserToTenant(userID, tenantID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The move cascades to the user's maps, nodes and keys, which are not edited by it
+	if _, err := tx.Exec(`SET LOCAL app.keep_updated_at = 'on'`); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		UPDATE users SET tenant_id = $2
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM tenants WHERE id = $2)`, userID, tenantID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
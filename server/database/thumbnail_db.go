@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"saas-server/models"
+	"time"
+)
+
+// SaveThumbnail stores the rendered preview of a mind map, replacing any older one
+func (db *DB) SaveThumbnail(thumbnail *models.MindMapThumbnail) error {
+	query := `
+		INSERT INTO mind_map_thumbnails (mind_map_id, svg, change_seq, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (mind_map_id) DO UPDATE
+		SET svg = EXCLUDED.svg, change_seq = EXCLUDED.change_seq, updated_at = EXCLUDED.updated_at
+		WHERE mind_map_thumbnails.change_seq <= EXCLUDED.change_seq
+		RETURNING updated_at`
+
+	err := db.QueryRow(query, thumbnail.MindMapID, thumbnail.SVG, thumbnail.ChangeSeq).Scan(&thumbnail.UpdatedAt)
+	if err == sql.ErrNoRows {
+		// A newer preview is already stored
+		return nil
+	}
+	return err
+}
+
+// GetThumbnail retrieves the stored preview of a mind map
+func (db *DB) GetThumbnail(mindMapID string) (*models.MindMapThumbnail, error) {
+	query := `
+		SELECT mind_map_id, svg, change_seq, updated_at
+		FROM mind_map_thumbnails
+		WHERE mind_map_id = $1`
+
+	var thumbnail models.MindMapThumbnail
+	err := db.QueryRow(query, mindMapID).Scan(
+		&thumbnail.MindMapID,
+		&thumbnail.SVG,
+		&thumbnail.ChangeSeq,
+		&thumbnail.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &thumbnail, nil
+}
+
+// GetStaleThumbnailMapIDs returns mind maps whose preview is missing or older than their
+// latest change, skipping maps changed within quietPeriod so bursts of edits render once
+func (db *DB) GetStaleThumbnailMapIDs(quietPeriod time.Duration, limit int) ([]string, error) {
+	query := `
+		SELECT m.id
+		FROM mind_maps m
+		LEFT JOIN mind_map_thumbnails t ON t.mind_map_id = m.id
+		WHERE m.status != 'deleted'
+		AND (t.mind_map_id IS NULL OR m.change_seq > t.change_seq)
+		AND NOT EXISTS (
+			SELECT 1 FROM mind_map_changes c
+			WHERE c.mind_map_id = m.id AND c.created_at > NOW() - make_interval(secs => $1)
+		)
+		ORDER BY m.updated_at
+		LIMIT $2`
+
+	rows, err := db.Query(query, quietPeriod.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"saas-server/models"
+
+	"github.com/google/uuid"
+)
+
+// SaveTableOfContents creates the table of contents node of a mind map under toc.ParentID,
+// or refreshes the content of the existing one, and replaces its entries with the given
+// link nodes in a single transaction, recording the changes in it. Entries are attached to
+// the table of contents with an edge each; their ParentID is ignored
+func (db *DB) SaveTableOfContents(toc models.NodeCreateRequest, entries []models.NodeCreateRequest) (*models.TOCResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &models.TOCResult{MindMapID: toc.MindMapID, Removed: []string{}}
+
+	existing, err := scanNode(tx.QueryRow(`
+		SELECT `+nodeColumns+`
+		FROM nodes
+		WHERE mind_map_id = $1 AND node_type = $2
+		ORDER BY created_at
+		LIMIT 1
+		FOR UPDATE`, toc.MindMapID, models.NodeTypeTOC))
+	switch {
+	case err == sql.ErrNoRows:
+		created, err := insertNodeTx(tx, toc)
+		if err != nil {
+			return nil, err
+		}
+		result.TOC, result.Created = *created, true
+	case err != nil:
+		return nil, err
+	default:
+		// Drop the old entries; their edges and links go with them
+		result.Removed, err = queryNodeIDs(tx.Query(`DELETE FROM nodes WHERE parent_id = $1 RETURNING id`, existing.ID))
+		if err != nil {
+			return nil, err
+		}
+		updated, err := scanNode(tx.QueryRow(`
+			UPDATE nodes
+			SET content = $2, updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+nodeColumns, existing.ID, toc.Content))
+		if err != nil {
+			return nil, err
+		}
+		result.TOC = *updated
+	}
+
+	result.Entries = make([]models.Node, 0, len(entries))
+	entryIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry.MindMapID = toc.MindMapID
+		entry.ParentID = &result.TOC.ID
+		node, err := insertNodeTx(tx, entry)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries = append(result.Entries, *node)
+		entryIDs = append(entryIDs, node.ID)
+	}
+
+	mindMapID := toc.MindMapID
+	for _, id := range result.Removed {
+		if _, err := recordChange(tx, mindMapID, "node.deleted", map[string]string{"id": id}); err != nil {
+			return nil, err
+		}
+	}
+	eventType := "node.updated"
+	if result.Created {
+		eventType = "node.created"
+	}
+	if _, err := recordChange(tx, mindMapID, eventType, result.TOC); err != nil {
+		return nil, err
+	}
+	if _, err := recordChange(tx, mindMapID, "nodes.imported", map[string][]string{"node_ids": entryIDs}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return result, nil
+}
+
+// insertNodeTx creates a node inside a transaction, indexing its links and attaching it
+// to its parent with an edge
+func insertNodeTx(tx *sql.Tx, req models.NodeCreateRequest) (*models.Node, error) {
+	node, err := insertNodeRowTx(tx, uuid.New().String(), req)
+	if err != nil {
+		return nil, err
+	}
+	if req.ParentID != nil {
+		if _, err := insertEdgeTx(tx, req.MindMapID, *req.ParentID, node.ID, "default"); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// insertNodeRowTx creates a node with the given ID inside a transaction and indexes its
+// links, leaving any edge from its parent to the caller
+func insertNodeRowTx(tx *sql.Tx, id string, req models.NodeCreateRequest) (*models.Node, error) {
+	styleData, metadata := []byte("{}"), []byte("{}")
+	if req.StyleData != nil {
+		styleData = []byte(req.StyleData)
+	}
+	if req.Metadata != nil {
+		metadata = []byte(req.Metadata)
+	}
+
+	node, err := scanNode(tx.QueryRow(`
+		INSERT INTO nodes (id, mind_map_id, parent_id, content, position_x, position_y,
+		                  node_type, style_data, metadata, created_by, icon, priority, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12, NOW(), NOW())
+		RETURNING `+nodeColumns,
+		id,
+		req.MindMapID,
+		req.ParentID,
+		req.Content,
+		req.PositionX,
+		req.PositionY,
+		req.NodeType,
+		styleData,
+		metadata,
+		req.CreatedBy,
+		req.Icon,
+		req.Priority,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if err := syncNodeLinks(tx, node.ID, node.MindMapID, node.Content, node.NodeType, node.Metadata); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// insertEdgeTx creates an edge of the given type between two nodes inside a transaction
+func insertEdgeTx(tx *sql.Tx, mindMapID, sourceID, targetID, edgeType string) (*models.Edge, error) {
+	edge := models.Edge{
+		ID:        uuid.New().String(),
+		MindMapID: mindMapID,
+		SourceID:  sourceID,
+		TargetID:  targetID,
+		EdgeType:  edgeType,
+		StyleData: json.RawMessage("{}"),
+	}
+	err := tx.QueryRow(`
+		INSERT INTO edges (id, mind_map_id, source_id, target_id, edge_type, style_data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING created_at`,
+		edge.ID,
+		edge.MindMapID,
+		edge.SourceID,
+		edge.TargetID,
+		edge.EdgeType,
+		[]byte(edge.StyleData),
+	).Scan(&edge.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &edge, nil
+}
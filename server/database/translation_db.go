@@ -0,0 +1,148 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"saas-server/models"
+
+	"github.com/lib/pq"
+)
+
+// TranslationHash is the translation cache key of a source text
+func TranslationHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetCachedTranslations looks up cached translations into a language, returning them by
+// source hash. Hashes without a cached translation are left out
+func (db *DB) GetCachedTranslations(language string, hashes []string) (map[string]string, error) {
+	rows, err := db.Query(`
+		SELECT source_hash, translated
+		FROM translation_cache
+		WHERE language = $1 AND source_hash = ANY($2)`, language, pq.Array(hashes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := make(map[string]string)
+	for rows.Next() {
+		var hash, translated string
+		if err := rows.Scan(&hash, &translated); err != nil {
+			return nil, err
+		}
+		translations[hash] = translated
+	}
+	return translations, rows.Err()
+}
+
+// SaveCachedTranslations stores translations into a language by source hash, replacing
+// earlier ones
+func (db *DB) SaveCachedTranslations(language string, translations map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for hash, translated := range translations {
+		_, err := tx.Exec(`
+			INSERT INTO translation_cache (source_hash, language, translated, created_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (source_hash, language) DO UPDATE SET translated = EXCLUDED.translated`,
+			hash, language, translated)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TranslateMindMap writes translated contents into a mind map in a single transaction.
+// When copyMap is set the map is first cloned for userID under title; otherwise the map
+// itself is retitled. Nodes are matched by their current content, so a node edited since the
+// contents were read is left alone. Translating in place records mind_map.updated and
+// nodes.updated changes in the same transaction. Returns the translated map and the IDs of
+// the changed nodes
+func (db *DB) TranslateMindMap(mindMapID, userID, title string, contents map[string]string, copyMap bool) (*models.MindMap, []string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var mindMap *models.MindMap
+	if copyMap {
+		mindMap, err = cloneMindMapTx(tx, mindMapID, userID, title)
+	} else {
+		mindMap, err = scanMindMap(tx.QueryRow(`
+			UPDATE mind_maps
+			SET title = $2, updated_at = NOW()
+			WHERE id = $1 AND status != 'deleted'
+			RETURNING `+mindMapColumns, mindMapID, title))
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Read the contents first so a translation that matches another node's original
+	// content is not translated twice
+	rows, err := tx.Query(`SELECT id, content FROM nodes WHERE mind_map_id = $1 FOR UPDATE`, mindMap.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pending [][2]string
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		if translated, ok := contents[content]; ok && translated != content {
+			pending = append(pending, [2]string{id, translated})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	nodeIDs := make([]string, 0, len(pending))
+	for _, update := range pending {
+		node, err := scanNode(tx.QueryRow(`
+			UPDATE nodes
+			SET content = $2, updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+nodeColumns, update[0], update[1]))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := syncNodeLinks(tx, node.ID, node.MindMapID, node.Content, node.NodeType, node.Metadata); err != nil {
+			return nil, nil, err
+		}
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+
+	// A copy is new, so nobody follows its changes yet
+	if !copyMap {
+		if _, err := recordChange(tx, mindMapID, "mind_map.updated", mindMap); err != nil {
+			return nil, nil, err
+		}
+		if len(nodeIDs) > 0 {
+			if _, err := recordChange(tx, mindMapID, "nodes.updated", map[string][]string{"node_ids": nodeIDs}); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	db.signalChange()
+	return mindMap, nodeIDs, nil
+}
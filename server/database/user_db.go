@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"saas-server/models"
-	"time"
 
 	"github.com/google/uuid"
 )
@@ -21,11 +20,10 @@ func (db *DB) CreateUser(email, password, name string, emailVerified bool) (*mod
 	}
 
 	id := uuid.New().String()
-	now := time.Now()
 
 	query := `
 		INSERT INTO users (id, email, password, name, email_verified, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
 		RETURNING id, email, password, name, email_verified, created_at, updated_at`
 
 	var user models.User
@@ -36,8 +34,6 @@ func (db *DB) CreateUser(email, password, name string, emailVerified bool) (*mod
 		password,
 		name,
 		emailVerified,
-		now,
-		now,
 	).Scan(
 		&user.ID,
 		&user.Email,
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"saas-server/models"
+)
+
+// ErrVoteLimitReached is returned when a user has already placed all of their votes on a mind map
+var ErrVoteLimitReached = errors.New("vote limit reached")
+
+// CastVote places one of the user's votes on a node, enforcing the map's per-user vote limit
+// and recording a vote.cast change in the same transaction
+func (db *DB) CastVote(nodeID, mindMapID, userID string, voteLimit int) (*models.NodeVote, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Serialize concurrent votes by the same user on the same map so the limit cannot be exceeded
+	if _, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2))`, mindMapID, userID); err != nil {
+		return nil, err
+	}
+
+	var used int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM node_votes WHERE mind_map_id = $1 AND user_id = $2`,
+		mindMapID, userID,
+	).Scan(&used)
+	if err != nil {
+		return nil, err
+	}
+	if used >= voteLimit {
+		err = ErrVoteLimitReached
+		return nil, err
+	}
+
+	var vote models.NodeVote
+	err = tx.QueryRow(`
+		INSERT INTO node_votes (node_id, mind_map_id, user_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, node_id, mind_map_id, user_id, created_at`,
+		nodeID, mindMapID, userID,
+	).Scan(&vote.ID, &vote.NodeID, &vote.MindMapID, &vote.UserID, &vote.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = recordChange(tx, mindMapID, "vote.cast", map[string]string{"node_id": nodeID}); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.signalChange()
+	return &vote, nil
+}
+
+// RemoveVote takes back one of the user's votes from a node, recording a vote.removed change
+// in the same transaction
+func (db *DB) RemoveVote(nodeID, userID string) error {
+	query := `
+		DELETE FROM node_votes
+		WHERE id = (
+			SELECT id FROM node_votes
+			WHERE node_id = $1 AND user_id = $2
+			ORDER BY created_at DESC
+			LIMIT 1
+		)
+		RETURNING mind_map_id`
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var mindMapID string
+	err = tx.QueryRow(query, nodeID, userID).Scan(&mindMapID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := recordChange(tx, mindMapID, "vote.removed", map[string]string{"node_id": nodeID}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.signalChange()
+	return nil
+}
+
+// CountUserVotes returns how many votes a user has placed on a mind map
+func (db *DB) CountUserVotes(mindMapID, userID string) (int, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM node_votes WHERE mind_map_id = $1 AND user_id = $2`,
+		mindMapID, userID,
+	).Scan(&count)
+	return count, err
+}
+
+// GetVoteResults retrieves the nodes of a mind map that received votes, ranked by vote count
+func (db *DB) GetVoteResults(mindMapID string) ([]models.VoteResult, error) {
+	query := `
+		SELECT RANK() OVER (ORDER BY COUNT(v.id) DESC) AS rank,
+		       n.id, n.content, COUNT(v.id) AS vote_count
+		FROM nodes n
+		JOIN node_votes v ON v.node_id = n.id
+		WHERE n.mind_map_id = $1
+		GROUP BY n.id, n.content
+		ORDER BY vote_count DESC, n.content ASC`
+
+	rows, err := db.Query(query, mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.VoteResult
+	for rows.Next() {
+		var result models.VoteResult
+		if err := rows.Scan(&result.Rank, &result.NodeID, &result.Content, &result.VoteCount); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
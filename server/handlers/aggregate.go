@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxAggregateMaps caps how many participant maps can be merged at once
+	maxAggregateMaps = 50
+	// maxAIClusterIdeas caps how many ideas are sent to the model for clustering
+	maxAIClusterIdeas = 300
+)
+
+// AggregateMindMaps handles POST /api/mindmaps/aggregate
+func (h *MindMapHandler) AggregateMindMaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.MindMapAggregateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if len(req.MindMapIDs) == 0 {
+		http.Error(w, "At least one mind map ID is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.MindMapIDs) > maxAggregateMaps {
+		http.Error(w, fmt.Sprintf("Cannot aggregate more than %d mind maps", maxAggregateMaps), http.StatusBadRequest)
+		return
+	}
+	if req.Title == "" {
+		req.Title = "Workshop debrief"
+	}
+
+	// Collect ideas from every participant map the user can read
+	var sources []models.AggregateSource
+	var mindMaps []*models.MindMap
+	for _, mindMapID := range req.MindMapIDs {
+		if _, err := uuid.Parse(mindMapID); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid mind map ID: %s", mindMapID), http.StatusBadRequest)
+			return
+		}
+
+		mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !canViewMindMap(h.DB, mindMap, userID) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !canExportMindMap(h.DB, mindMap, userID) {
+			http.Error(w, exportDisabledError, http.StatusForbidden)
+			return
+		}
+		mindMaps = append(mindMaps, mindMap)
+
+		nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for _, node := range nodes {
+			if strings.TrimSpace(node.Content) == "" {
+				continue
+			}
+			sources = append(sources, models.AggregateSource{
+				MindMapID: mindMapID,
+				NodeID:    node.ID,
+				Content:   node.Content,
+			})
+		}
+	}
+
+	// Group similar ideas, preferring AI clustering when a key is available
+	var clusters []models.AggregateCluster
+	aiClustering := false
+	if req.Deduplicate {
+		apiKey, err := resolveOpenAIKey(h.DB, userID, req.APIKey)
+		if err != nil {
+			log.Printf("[Aggregate] Using exact matching: %v", err)
+		}
+		if apiKey != "" && len(sources) <= maxAIClusterIdeas {
+			aiClusters, usage, err := clusterIdeasWithAI(r.Context(), apiKey, sources, piiRedactor(h.DB, mindMaps...))
+			recordAIUsage(h.DB, userID, "", aiFeatureAggregate, usage)
+			if err != nil {
+				log.Printf("[Aggregate] AI clustering failed, falling back to exact matching: %v", err)
+			} else {
+				clusters = aiClusters
+				aiClustering = true
+			}
+		}
+		if !aiClustering {
+			clusters = clusterIdeasByContent(sources)
+		}
+	} else {
+		for _, source := range sources {
+			clusters = append(clusters, models.AggregateCluster{
+				Label:   source.Content,
+				Sources: []models.AggregateSource{source},
+			})
+		}
+	}
+
+	// Arrange clusters in a circle around the root
+	radius := 200.0 + 10.0*float64(len(clusters))
+	clusterNodes := make([]models.NodeCreateRequest, len(clusters))
+	for i := range clusters {
+		angle := 2 * math.Pi * float64(i) / float64(len(clusters))
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"source_count": len(clusters[i].Sources),
+			"sources":      clusters[i].Sources,
+		})
+
+		clusterNodes[i] = models.NodeCreateRequest{
+			Content:   clusters[i].Label,
+			PositionX: radius * math.Cos(angle),
+			PositionY: radius * math.Sin(angle),
+			NodeType:  "idea",
+			Metadata:  metadata,
+		}
+	}
+
+	// Create the consolidated map with its root and cluster nodes
+	mindMap, nodes, err := h.DB.CreateAggregateMindMap(userID, models.MindMapCreateRequest{
+		Title:       req.Title,
+		Description: fmt.Sprintf("Aggregated from %d mind maps", len(req.MindMapIDs)),
+	}, models.NodeCreateRequest{
+		Content:  req.Title,
+		NodeType: "root",
+	}, clusterNodes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for i := range clusters {
+		clusters[i].NodeID = nodes[i].ID
+	}
+
+	// Return consolidated map
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.MindMapAggregateResponse{
+		MindMap:      *mindMap,
+		Clusters:     clusters,
+		AIClustering: aiClustering,
+	})
+}
+
+// clusterIdeasByContent groups ideas whose normalized content is identical
+func clusterIdeasByContent(sources []models.AggregateSource) []models.AggregateCluster {
+	var clusters []models.AggregateCluster
+	index := make(map[string]int)
+
+	for _, source := range sources {
+		key := strings.ToLower(strings.Join(strings.Fields(source.Content), " "))
+		if i, ok := index[key]; ok {
+			clusters[i].Sources = append(clusters[i].Sources, source)
+			continue
+		}
+		index[key] = len(clusters)
+		clusters = append(clusters, models.AggregateCluster{
+			Label:   strings.TrimSpace(source.Content),
+			Sources: []models.AggregateSource{source},
+		})
+	}
+
+	return clusters
+}
+
+// clusterIdeasWithAI asks the model to group semantically similar ideas. Ideas the model
+// leaves out of every group are kept as their own single-idea cluster. Also returns the
+// usage of the request
+func clusterIdeasWithAI(ctx context.Context, apiKey string, sources []models.AggregateSource, redactor *pii.Redactor) ([]models.AggregateCluster, aiUsage, error) {
+	var list strings.Builder
+	for i, source := range sources {
+		fmt.Fprintf(&list, "%d. %s\n", i+1, prompt.Line(redactor.Redact(source.Content)))
+	}
+
+	content, usage, err := openAIChatCompletion(
+		ctx,
+		apiKey,
+		prompt.System("You are a workshop facilitator consolidating brainstorm results. Group the numbered ideas that express the same or very similar thought. Respond only with a JSON array of objects with a short \"label\" summarizing the group and \"members\", the list of idea numbers in it."),
+		prompt.Delimit(prompt.Limit(list.String(), prompt.MaxContextLength)),
+		1500,
+	)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, usage, fmt.Errorf("no JSON array in clustering response")
+	}
+
+	var groups []struct {
+		Label   string `json:"label"`
+		Members []int  `json:"members"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &groups); err != nil {
+		return nil, usage, err
+	}
+
+	assigned := make([]bool, len(sources))
+	var clusters []models.AggregateCluster
+	for _, group := range groups {
+		cluster := models.AggregateCluster{Label: strings.TrimSpace(redactor.Restore(group.Label))}
+		for _, member := range group.Members {
+			i := member - 1
+			if i < 0 || i >= len(sources) || assigned[i] {
+				continue
+			}
+			assigned[i] = true
+			cluster.Sources = append(cluster.Sources, sources[i])
+		}
+		if len(cluster.Sources) == 0 {
+			continue
+		}
+		if cluster.Label == "" {
+			cluster.Label = cluster.Sources[0].Content
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	for i, source := range sources {
+		if !assigned[i] {
+			clusters = append(clusters, models.AggregateCluster{
+				Label:   source.Content,
+				Sources: []models.AggregateSource{source},
+			})
+		}
+	}
+
+	return clusters, usage, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultAIModels lists the models generation requests may pick per provider, unless
+// <PROVIDER>_MODELS replaces the list. The provider's default model is always allowed
+var defaultAIModels = map[string][]string{
+	aiProviderOpenAI:    {"gpt-4o-mini", "gpt-4o", "gpt-4.1-mini", "gpt-4.1", "gpt-3.5-turbo"},
+	aiProviderAnthropic: {"claude-3-5-haiku-latest", "claude-3-5-sonnet-latest", "claude-3-7-sonnet-latest"},
+}
+
+// aiDefaultModel returns the model a provider is used with when a request names none
+func aiDefaultModel(provider string) string {
+	switch provider {
+	case aiProviderAnthropic:
+		return anthropicModel()
+	case aiProviderOpenRouter:
+		return openRouterModel()
+	case aiProviderOllama:
+		return ollamaModel()
+	}
+	return openAIModel()
+}
+
+// allowedAIModels returns the models a request may pick for a provider. Without
+// OPENROUTER_MODELS any OpenRouter model ID is allowed, which is reported as nil
+func allowedAIModels(provider string) []string {
+	models := defaultAIModels[provider]
+	if value := os.Getenv(strings.ToUpper(provider) + "_MODELS"); value != "" {
+		models = nil
+		for _, model := range strings.Split(value, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+	} else if provider == aiProviderOpenRouter {
+		return nil
+	}
+	return appendMissing(models, aiDefaultModel(provider))
+}
+
+// checkAIModel returns an error describing why a requested model cannot be used with the
+// provider, or nil if it can
+func checkAIModel(provider, model string) error {
+	allowed := allowedAIModels(provider)
+	if allowed == nil {
+		if !openRouterModelPattern.MatchString(model) {
+			return fmt.Errorf("Model must be an OpenRouter model ID such as 'openai/gpt-4o-mini'")
+		}
+		return nil
+	}
+	for _, name := range allowed {
+		if name == model {
+			return nil
+		}
+	}
+	return fmt.Errorf("Model '%s' is not available for provider '%s'; choose one of: %s", model, provider, strings.Join(allowed, ", "))
+}
+
+// aiModel returns the model a request is made with: the requested one, or the provider's
+// default
+func aiModel(provider, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return aiDefaultModel(provider)
+}
+
+// appendMissing appends value to list unless it is already there
+func appendMissing(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"saas-server/database"
+	"strconv"
+	"strings"
+)
+
+// defaultAIRegion is where a hosted provider is assumed to process data unless its
+// <PROVIDER>_REGION variable says otherwise
+const defaultAIRegion = "us"
+
+// localAIRegion is the region of a self-hosted Ollama instance unless OLLAMA_REGION says
+// otherwise
+const localAIRegion = "local"
+
+// aiPolicyError is returned when a request would send content to an AI provider, region or
+// key the AI usage policy does not allow
+type aiPolicyError struct {
+	reason string
+}
+
+func (e *aiPolicyError) Error() string {
+	return "Not allowed by the AI usage policy: " + e.reason
+}
+
+// aiPolicy restricts which AI providers content may be sent to. Each tenant can set its
+// own; the settings a tenant leaves unset come from the server's default, configured with
+// AI_ALLOWED_PROVIDERS and AI_ALLOWED_REGIONS, comma-separated lists where empty allows
+// any, and AI_ALLOW_INLINE_KEYS, which decides whether requests may bring their own API key
+type aiPolicy struct {
+	providers  map[string]bool
+	regions    map[string]bool
+	inlineKeys bool
+}
+
+// defaultAIPolicy reads the server's default AI usage policy from the environment
+func defaultAIPolicy() aiPolicy {
+	policy := aiPolicy{
+		providers:  envSet("AI_ALLOWED_PROVIDERS"),
+		regions:    envSet("AI_ALLOWED_REGIONS"),
+		inlineKeys: true,
+	}
+	if value := os.Getenv("AI_ALLOW_INLINE_KEYS"); value != "" {
+		policy.inlineKeys, _ = strconv.ParseBool(value)
+	}
+	return policy
+}
+
+// loadAIPolicy returns the AI usage policy of the tenant a user belongs to, which is the
+// tenant of the requests made for them, over the server's default. Requests without a
+// user, or of a user who no longer exists, get the default
+func loadAIPolicy(db *database.DB, userID string) (aiPolicy, error) {
+	policy := defaultAIPolicy()
+	if userID == "" {
+		return policy, nil
+	}
+	tenantPolicy, err := db.GetUserAIPolicy(userID)
+	if errors.Is(err, database.ErrNotFound) {
+		return policy, nil
+	}
+	if err != nil {
+		return aiPolicy{}, fmt.Errorf("failed to get AI usage policy: %v", err)
+	}
+
+	if tenantPolicy.AllowedProviders != nil {
+		policy.providers = listSet(tenantPolicy.AllowedProviders)
+	}
+	if tenantPolicy.AllowedRegions != nil {
+		policy.regions = listSet(tenantPolicy.AllowedRegions)
+	}
+	if tenantPolicy.AllowInlineKeys != nil {
+		policy.inlineKeys = *tenantPolicy.AllowInlineKeys
+	}
+	return policy, nil
+}
+
+// aiProviderRegion returns the region a provider processes data in, from OPENAI_REGION,
+// ANTHROPIC_REGION or OLLAMA_REGION
+func aiProviderRegion(provider string) string {
+	if region := os.Getenv(strings.ToUpper(provider) + "_REGION"); region != "" {
+		return strings.ToLower(strings.TrimSpace(region))
+	}
+	if provider == aiProviderOllama {
+		return localAIRegion
+	}
+	return defaultAIRegion
+}
+
+// allows reports whether content may be sent to the provider, without saying why not
+func (p aiPolicy) allows(provider string) bool {
+	return p.checkProvider(provider) == nil
+}
+
+// checkProvider returns an aiPolicyError if the provider or its region is not allowed
+func (p aiPolicy) checkProvider(provider string) error {
+	if p.providers != nil && !p.providers[provider] {
+		return &aiPolicyError{reason: fmt.Sprintf("the %s provider is not allowed", provider)}
+	}
+	if region := aiProviderRegion(provider); p.regions != nil && !p.regions[region] {
+		return &aiPolicyError{reason: fmt.Sprintf("the %s provider processes data in region %q, which is not allowed", provider, region)}
+	}
+	return nil
+}
+
+// checkInlineKey returns an aiPolicyError if a request brought its own key and the policy
+// only allows stored and server keys
+func (p aiPolicy) checkInlineKey(override string) error {
+	if override != "" && !p.inlineKeys {
+		return &aiPolicyError{reason: "API keys can't be sent with requests; store the key in your account instead"}
+	}
+	return nil
+}
+
+// envSet parses a comma-separated environment variable into a lowercase set, or nil when
+// it is empty
+func envSet(name string) map[string]bool {
+	return listSet(strings.Split(os.Getenv(name), ","))
+}
+
+// listSet turns a list into a lowercase set, or nil when it has no values
+func listSet(values []string) map[string]bool {
+	var set map[string]bool
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			if set == nil {
+				set = make(map[string]bool)
+			}
+			set[value] = true
+		}
+	}
+	return set
+}
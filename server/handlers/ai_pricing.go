@@ -0,0 +1,58 @@
+package handlers
+
+import "strings"
+
+// aiPrice is what a model costs per million prompt and completion tokens, in US dollars
+type aiPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// aiPrices are the list prices of the models usage is estimated for, keyed by model name
+// prefix so dated versions such as "gpt-4o-2024-08-06" share their model's price.
+// OpenRouter models are looked up without their vendor prefix
+var aiPrices = map[string]aiPrice{
+	"gpt-4o-mini":            {prompt: 0.15, completion: 0.60},
+	"gpt-4o":                 {prompt: 2.50, completion: 10.00},
+	"gpt-4.1-nano":           {prompt: 0.10, completion: 0.40},
+	"gpt-4.1-mini":           {prompt: 0.40, completion: 1.60},
+	"gpt-4.1":                {prompt: 2.00, completion: 8.00},
+	"gpt-3.5-turbo":          {prompt: 0.50, completion: 1.50},
+	"text-embedding-3-small": {prompt: 0.02},
+	"text-embedding-3-large": {prompt: 0.13},
+	"claude-3-5-haiku":       {prompt: 0.80, completion: 4.00},
+	"claude-3.5-haiku":       {prompt: 0.80, completion: 4.00},
+	"claude-3-5-sonnet":      {prompt: 3.00, completion: 15.00},
+	"claude-3.5-sonnet":      {prompt: 3.00, completion: 15.00},
+	"claude-3-7-sonnet":      {prompt: 3.00, completion: 15.00},
+	"claude-3.7-sonnet":      {prompt: 3.00, completion: 15.00},
+	"claude-3-haiku":         {prompt: 0.25, completion: 1.25},
+	"claude-3-opus":          {prompt: 15.00, completion: 75.00},
+}
+
+// aiPriceOf returns the price of a provider's model. Models on a local Ollama instance and
+// free OpenRouter variants cost nothing; reports false for other models without a known
+// price
+func aiPriceOf(provider, model string) (aiPrice, bool) {
+	if provider == aiProviderOllama || (provider == aiProviderOpenRouter && strings.HasSuffix(model, ":free")) {
+		return aiPrice{}, true
+	}
+	if provider == aiProviderOpenRouter {
+		model = model[strings.Index(model, "/")+1:]
+	}
+
+	// The longest matching prefix wins, so "gpt-4o-mini" is not priced as "gpt-4o"
+	var price aiPrice
+	matched := ""
+	for prefix, candidate := range aiPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			price, matched = candidate, prefix
+		}
+	}
+	return price, matched != ""
+}
+
+// cost estimates what the given tokens cost at the price, in US dollars
+func (p aiPrice) cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.prompt + float64(completionTokens)*p.completion) / 1e6
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Defaults of the retry policy for AI provider requests
+const (
+	defaultAIMaxAttempts    = 3
+	defaultAIRetryBaseDelay = 500 * time.Millisecond
+	defaultAIRetryMaxDelay  = 8 * time.Second
+)
+
+// defaultAIRequestTimeout bounds an AI provider call, retries included, unless
+// AI_REQUEST_TIMEOUT_MS says otherwise
+const defaultAIRequestTimeout = 60 * time.Second
+
+// aiContext derives the context of an AI provider call from the context of the request
+// that needs it, so the call is cancelled when the client disconnects or the request's
+// deadline passes, and gives up after AI_REQUEST_TIMEOUT_MS even if neither happens. A
+// timeout of 0 leaves the call bounded by the request alone
+func aiContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := defaultAIRequestTimeout
+	if ms, err := strconv.Atoi(os.Getenv("AI_REQUEST_TIMEOUT_MS")); err == nil && ms >= 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	if timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// aiRetryPolicy decides how often and how long apart failed AI provider requests are
+// retried. It is configured with AI_MAX_ATTEMPTS, AI_RETRY_BASE_DELAY_MS and
+// AI_RETRY_MAX_DELAY_MS
+type aiRetryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// loadAIRetryPolicy reads the retry policy from the environment, keeping the default of
+// any setting that is unset or invalid
+func loadAIRetryPolicy() aiRetryPolicy {
+	policy := aiRetryPolicy{
+		maxAttempts: defaultAIMaxAttempts,
+		baseDelay:   defaultAIRetryBaseDelay,
+		maxDelay:    defaultAIRetryMaxDelay,
+	}
+	if attempts, err := strconv.Atoi(os.Getenv("AI_MAX_ATTEMPTS")); err == nil && attempts >= 1 {
+		policy.maxAttempts = attempts
+	}
+	if ms, err := strconv.Atoi(os.Getenv("AI_RETRY_BASE_DELAY_MS")); err == nil && ms >= 0 {
+		policy.baseDelay = time.Duration(ms) * time.Millisecond
+	}
+	if ms, err := strconv.Atoi(os.Getenv("AI_RETRY_MAX_DELAY_MS")); err == nil && ms >= 0 {
+		policy.maxDelay = time.Duration(ms) * time.Millisecond
+	}
+	return policy
+}
+
+// retryableStatus reports whether a provider response is worth retrying: rate limiting,
+// server errors and Anthropic's overloaded status
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// delay returns how long to wait before the given retry, counting from 1. A Retry-After
+// sent by the provider is honored up to the maximum delay; otherwise the delay grows
+// exponentially with full jitter, so clients throttled together do not retry together
+func (p aiRetryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(wait, p.maxDelay)
+		}
+	}
+	backoff := p.baseDelay << (retry - 1)
+	if backoff <= 0 || backoff > p.maxDelay {
+		backoff = p.maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// doAIRequest sends a request to an AI provider, retrying network errors, rate limiting
+// and server errors with backoff until the policy's attempts run out. The request body is
+// replayed from GetBody, which http.NewRequest sets for in-memory bodies. Returns the last
+// response, which the caller checks and closes as usual, or the last network error.
+// Waiting stops early when the request's context is done
+func doAIRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	policy := loadAIRetryPolicy()
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		retryable := err != nil || retryableStatus(resp.StatusCode)
+		if err != nil && req.Context().Err() != nil {
+			retryable = false
+		}
+		if !retryable || attempt >= policy.maxAttempts || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		wait := policy.delay(attempt, resp)
+		if err != nil {
+			log.Printf("[AI Retry] %s %s failed on attempt %d, retrying in %s: %v", req.Method, req.URL.Host, attempt, wait, err)
+		} else {
+			log.Printf("[AI Retry] %s %s returned %s on attempt %d, retrying in %s", req.Method, req.URL.Host, resp.Status, attempt, wait)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// AIErrorResponse is the body of an error response for a failed AI call
+type AIErrorResponse struct {
+	Error     string `json:"error"`
+	Kind      string `json:"kind"`      // "rate_limited", "timeout", "provider_unavailable", "provider_auth", "invalid_request" or "invalid_response"
+	Retryable bool   `json:"retryable"` // Whether trying again later may succeed
+}
+
+// classifyAIError describes a failed AI call for the client: the status to respond with
+// and an error naming what went wrong, once any retries have run out
+func classifyAIError(err error) (int, AIErrorResponse) {
+	var apiErr *apiError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.statusCode == http.StatusTooManyRequests:
+		return http.StatusTooManyRequests, AIErrorResponse{Error: apiErr.provider + " is rate limiting requests; try again later", Kind: "rate_limited", Retryable: true}
+	case errors.As(err, &apiErr) && (apiErr.statusCode == http.StatusUnauthorized || apiErr.statusCode == http.StatusForbidden):
+		return http.StatusBadGateway, AIErrorResponse{Error: apiErr.provider + " rejected the API key", Kind: "provider_auth"}
+	case errors.As(err, &apiErr) && apiErr.statusCode >= http.StatusInternalServerError:
+		return http.StatusBadGateway, AIErrorResponse{Error: apiErr.provider + " is unavailable: " + apiErr.status, Kind: "provider_unavailable", Retryable: true}
+	case errors.As(err, &apiErr):
+		return http.StatusBadGateway, AIErrorResponse{Error: err.Error(), Kind: "invalid_request"}
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return http.StatusGatewayTimeout, AIErrorResponse{Error: "The AI provider did not respond in time", Kind: "timeout", Retryable: true}
+	case errors.As(err, &netErr):
+		return http.StatusBadGateway, AIErrorResponse{Error: "Could not reach the AI provider: " + err.Error(), Kind: "provider_unavailable", Retryable: true}
+	}
+	return http.StatusBadGateway, AIErrorResponse{Error: err.Error(), Kind: "invalid_response"}
+}
+
+// sendAIError responds to a failed AI call with its classification, prefixing the error
+// with what failed
+func sendAIError(w http.ResponseWriter, action string, err error) {
+	if errors.Is(err, context.Canceled) {
+		// The client went away, so there is no one to respond to
+		log.Printf("[AI] %s: client disconnected", action)
+		return
+	}
+	status, response := classifyAIError(err)
+	response.Error = action + ": " + response.Error
+	sendJSONResponse(w, status, response)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"log"
+	"saas-server/database"
+	"saas-server/models"
+	"time"
+)
+
+// Features AI usage is recorded under
+const (
+	aiFeatureGenerate  = "generate"
+	aiFeatureLint      = "lint"
+	aiFeatureProofread = "proofread"
+	aiFeatureTranslate = "translate"
+	aiFeatureAggregate = "aggregate"
+	aiFeatureTriage    = "triage"
+	aiFeatureDocuments = "documents"
+	aiFeatureSummarize = "summarize"
+	aiFeatureCluster   = "cluster"
+	aiFeatureSearch    = "search"
+)
+
+// aiUsage is what one AI call used, as reported by the provider. The zero value stands for
+// a call that never got a reply, which has nothing to record
+type aiUsage struct {
+	provider         string
+	model            string
+	promptTokens     int
+	completionTokens int
+	latency          time.Duration
+}
+
+// recordAIUsage stores the usage of an AI call made for a user, and for a mind map unless
+// mindMapID is empty. Failing to store it is logged rather than failing the request
+func recordAIUsage(db *database.DB, userID, mindMapID, feature string, usage aiUsage) {
+	if usage.provider == "" {
+		return
+	}
+	record := &models.AIUsage{
+		UserID:           userID,
+		Feature:          feature,
+		Provider:         usage.provider,
+		Model:            usage.model,
+		PromptTokens:     usage.promptTokens,
+		CompletionTokens: usage.completionTokens,
+		LatencyMs:        int(usage.latency.Milliseconds()),
+	}
+	if mindMapID != "" {
+		record.MindMapID = &mindMapID
+	}
+	if err := db.RecordAIUsage(record); err != nil {
+		log.Printf("[AI Usage] Failed to record %s usage of %s for user %s: %v", feature, usage.model, userID, err)
+	}
+}
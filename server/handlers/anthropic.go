@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"saas-server/database"
+	"strings"
+	"time"
+)
+
+// AI providers a generation request can ask for
+const (
+	aiProviderOpenAI    = "openai"
+	aiProviderAnthropic = "anthropic"
+)
+
+// defaultAnthropicModel is the Claude model used unless ANTHROPIC_MODEL overrides it
+const defaultAnthropicModel = "claude-3-5-haiku-latest"
+
+// anthropicAPIVersion is the Messages API version requests are made against
+const anthropicAPIVersion = "2023-06-01"
+
+// resolveAnthropicKey picks the Anthropic API key for a request: an explicitly provided key
+// wins, then the user's stored "anthropic" key, falling back to the server-wide key.
+// Returns an aiPolicyError if the AI usage policy rules out Anthropic or the provided key
+func resolveAnthropicKey(db *database.DB, userID, override string) (string, error) {
+	return resolveProviderKey(db, userID, aiProviderAnthropic, override)
+}
+
+// anthropicModel returns the Claude model requests are made with by default
+func anthropicModel() string {
+	if model := os.Getenv("ANTHROPIC_MODEL"); model != "" {
+		return model
+	}
+	return defaultAnthropicModel
+}
+
+// anthropicJSON returns a function that sends prompts to the given Claude model and returns
+// a reply that is JSON following a schema, and its usage. The reply is requested as a
+// forced tool call, whose input the Messages API checks against the schema
+func anthropicJSON(model string) func(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+	return func(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+		return anthropicToolCall(ctx, model, apiKey, systemPrompt, userPrompt, maxTokens, schema)
+	}
+}
+
+// anthropicToolCall sends a system and user prompt to a model through the Anthropic
+// Messages API, making it call a tool with the schema as input, and returns that input and
+// the usage of the request
+func anthropicToolCall(ctx context.Context, model, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+	// Prepare the Anthropic API request
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model":  model,
+		"system": systemPrompt,
+		"messages": []map[string]string{
+			{
+				"role":    "user",
+				"content": userPrompt,
+			},
+		},
+		"tools": []map[string]interface{}{
+			{
+				"name":         schema.name,
+				"description":  schema.description,
+				"input_schema": schema.schema,
+			},
+		},
+		"tool_choice": map[string]string{
+			"type": "tool",
+			"name": schema.name,
+		},
+		"temperature": 0.7,
+		"max_tokens":  maxTokens,
+	})
+	if err != nil {
+		return "", aiUsage{}, err
+	}
+
+	// Make the API request
+	client := &http.Client{}
+	ctx, cancel := aiContext(ctx)
+	defer cancel()
+	apiReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", aiUsage{}, err
+	}
+
+	apiReq.Header.Set("Content-Type", "application/json")
+	apiReq.Header.Set("x-api-key", apiKey)
+	apiReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	start := time.Now()
+	resp, err := doAIRequest(client, apiReq)
+	if err != nil {
+		return "", aiUsage{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", aiUsage{}, &apiError{provider: "Anthropic", status: resp.Status, statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	// Parse the response, picking out the tool call
+	var apiResp struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return "", aiUsage{}, err
+	}
+
+	usage := aiUsage{
+		provider:         aiProviderAnthropic,
+		model:            model,
+		promptTokens:     apiResp.Usage.InputTokens,
+		completionTokens: apiResp.Usage.OutputTokens,
+		latency:          time.Since(start),
+	}
+
+	for _, block := range apiResp.Content {
+		if block.Type == "tool_use" && block.Name == schema.name {
+			return string(block.Input), usage, nil
+		}
+	}
+	return "", usage, fmt.Errorf("Anthropic did not call %s", schema.name)
+}
+
+// resolveAIProvider picks the provider and API key for a generation request. An explicit
+// provider only uses its own keys. Otherwise an explicitly provided key is an OpenAI key,
+// then the user's stored OpenAI, Anthropic and OpenRouter keys are tried before the
+// server-wide ones and finally a configured Ollama instance, skipping providers the AI usage policy rules
+// out. Returns an aiPolicyError if the policy rules out the explicit provider, the
+// provided key or every provider
+func resolveAIProvider(db *database.DB, userID, provider, override string) (string, string, error) {
+	switch provider {
+	case aiProviderOpenAI:
+		apiKey, err := resolveOpenAIKey(db, userID, override)
+		return aiProviderOpenAI, apiKey, err
+	case aiProviderAnthropic:
+		apiKey, err := resolveAnthropicKey(db, userID, override)
+		return aiProviderAnthropic, apiKey, err
+	case aiProviderOpenRouter:
+		apiKey, err := resolveProviderKey(db, userID, aiProviderOpenRouter, override)
+		return aiProviderOpenRouter, apiKey, err
+	}
+
+	policy, err := loadAIPolicy(db, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if provider == aiProviderOllama {
+		return aiProviderOllama, os.Getenv("OLLAMA_API_KEY"), policy.checkProvider(aiProviderOllama)
+	}
+	if override != "" {
+		apiKey, err := resolveOpenAIKey(db, userID, override)
+		return aiProviderOpenAI, apiKey, err
+	}
+
+	var allowed []string
+	for _, service := range []string{aiProviderOpenAI, aiProviderAnthropic, aiProviderOpenRouter} {
+		if policy.allows(service) {
+			allowed = append(allowed, service)
+		}
+	}
+	ollama := ollamaBaseURL() != "" && policy.allows(aiProviderOllama)
+	if len(allowed) == 0 && !ollama {
+		return "", "", &aiPolicyError{reason: "no configured AI provider is allowed"}
+	}
+
+	if userID != "" {
+		for _, service := range allowed {
+			userAPIKey, err := db.GetDecryptedAPIKey(userID, service)
+			if err == nil && userAPIKey != "" {
+				return service, userAPIKey, nil
+			}
+		}
+	}
+	for _, service := range allowed {
+		if apiKey := os.Getenv(strings.ToUpper(service) + "_API_KEY"); apiKey != "" {
+			return service, apiKey, nil
+		}
+	}
+	if ollama {
+		return aiProviderOllama, os.Getenv("OLLAMA_API_KEY"), nil
+	}
+	return allowed[len(allowed)-1], "", nil
+}
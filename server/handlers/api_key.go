@@ -45,6 +45,10 @@ func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Service is required", http.StatusBadRequest)
 		return
 	}
+	if !models.ValidAPIKeyService(req.Service) {
+		http.Error(w, "Service must be 'openai', 'anthropic' or 'openrouter'", http.StatusBadRequest)
+		return
+	}
 	if req.Key == "" {
 		http.Error(w, "Key is required", http.StatusBadRequest)
 		return
@@ -78,7 +82,7 @@ func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get API keys
-	apiKeys, err := h.DB.GetAPIKeysByUserID(userID)
+	apiKeys, err := tenantDB(h.DB, r).GetAPIKeysByUserID(userID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get API keys: %v", err), http.StatusInternalServerError)
 		return
@@ -111,7 +115,7 @@ func (h *APIKeyHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get API key
-	apiKey, err := h.DB.GetAPIKeyByID(apiKeyID)
+	apiKey, err := tenantDB(h.DB, r).GetAPIKeyByID(apiKeyID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get API key: %v", err), http.StatusInternalServerError)
 		return
@@ -150,7 +154,7 @@ func (h *APIKeyHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get API key to check ownership
-	apiKey, err := h.DB.GetAPIKeyByID(apiKeyID)
+	apiKey, err := tenantDB(h.DB, r).GetAPIKeyByID(apiKeyID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get API key: %v", err), http.StatusInternalServerError)
 		return
@@ -203,7 +207,7 @@ func (h *APIKeyHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get API key to check ownership
-	apiKey, err := h.DB.GetAPIKeyByID(apiKeyID)
+	apiKey, err := tenantDB(h.DB, r).GetAPIKeyByID(apiKeyID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get API key: %v", err), http.StatusInternalServerError)
 		return
@@ -248,7 +252,7 @@ func (h *APIKeyHandler) GetAPIKeyByService(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Get API key
-	apiKey, err := h.DB.GetAPIKeyByUserAndService(userID, service)
+	apiKey, err := tenantDB(h.DB, r).GetAPIKeyByUserAndService(userID, service)
 	if err != nil {
 		// If the API key doesn't exist, return an empty response
 		if strings.Contains(err.Error(), "not found") {
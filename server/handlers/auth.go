@@ -101,7 +101,7 @@ type GithubAuthRequest struct {
 // NewAuthHandler creates a new AuthHandler instance with the given database connection and JWT secret
 func NewAuthHandler(db database.DBInterface, jwtSecret string) *AuthHandler {
 	// Create rate limiter for auth endpoints - 5 attempts per minute
-	authLimiter := middleware.NewRateLimiter(time.Minute, 5)
+	authLimiter := middleware.NewRateLimiter("auth", time.Minute, 5)
 
 	return &AuthHandler{
 		db:                 db,
@@ -161,6 +161,10 @@ func (h *AuthHandler) GoogleAuth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.ssoRequired(w, userInfo.Email) {
+		return
+	}
+
 	// Verify the user exists or create a new one
 	user, err := h.db.GetUserByEmail(userInfo.Email)
 	if err != nil {
@@ -192,6 +196,21 @@ func (h *AuthHandler) GoogleAuth(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ssoRequired responds with 403 and returns true if the email's organization requires its
+// users to sign in through single sign-on rather than a password, Google or GitHub
+func (h *AuthHandler) ssoRequired(w http.ResponseWriter, email string) bool {
+	required, err := h.db.SSORequired(email)
+	if err != nil {
+		log.Printf("[Auth] Error checking whether SSO is required: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return true
+	}
+	if required {
+		sendErrorResponse(w, http.StatusForbidden, "Your organization requires single sign-on")
+	}
+	return required
+}
+
 // Register handles user registration endpoint (POST /auth/register)
 // It validates the request, checks for existing users, and creates a new user account
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -229,6 +248,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.ssoRequired(w, req.Email) {
+		return
+	}
+
 	// Check if user already exists
 	existingUser, err := h.db.GetUserByEmail(req.Email)
 	if err == nil && existingUser != nil {
@@ -286,6 +309,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.ssoRequired(w, req.Email) {
+		return
+	}
+
 	user, err := h.db.GetUserByEmail(req.Email)
 	if err != nil {
 		sendErrorResponse(w, http.StatusUnauthorized, "Invalid credentials")
@@ -358,7 +385,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Apply rate limiting - 3 attempts per 5 minutes
-	handler := createRateLimitedHandler(5*time.Minute, 3, refreshHandler)
+	handler := createRateLimitedHandler("auth-refresh", 5*time.Minute, 3, refreshHandler)
 	handler(w, r)
 }
 
@@ -773,6 +800,10 @@ func (h *AuthHandler) GithubAuth(w http.ResponseWriter, r *http.Request) {
 		githubUser.Name = githubUser.Login
 	}
 
+	if h.ssoRequired(w, githubUser.Email) {
+		return
+	}
+
 	// Verify the user exists or create a new one
 	user, err := h.db.GetUserByEmail(githubUser.Email)
 	if err != nil {
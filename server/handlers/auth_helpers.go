@@ -374,14 +374,24 @@ func checkCSRFToken(r *http.Request) error {
 }
 
 // createRateLimitedHandler creates a rate-limited version of the given handler
-func createRateLimitedHandler(duration time.Duration, limit int, handler http.HandlerFunc) http.HandlerFunc {
-	limiter := middleware.NewRateLimiter(duration, limit)
+func createRateLimitedHandler(name string, duration time.Duration, limit int, handler http.HandlerFunc) http.HandlerFunc {
+	limiter := middleware.NewRateLimiter(name, duration, limit)
 	return limiter.Limit(handler).ServeHTTP
 }
 
 // GenerateAuthResponse handles the common flow of generating tokens, storing refresh token,
-// setting cookies, and sending the auth response
+// setting cookies, and sending the auth response. Users deactivated by their identity
+// provider get a 403 instead of a session
 func (h *AuthHandler) GenerateAuthResponse(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	deactivated, err := h.db.IsUserDeactivated(user.ID)
+	if err != nil {
+		return fmt.Errorf("error checking whether user is deactivated: %w", err)
+	}
+	if deactivated {
+		sendErrorResponse(w, http.StatusForbidden, "Your account has been deactivated by your organization")
+		return nil
+	}
+
 	// Generate tokens
 	tokens, err := h.generateTokenPair(user.ID)
 	if err != nil {
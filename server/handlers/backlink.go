@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetNodeBacklinks handles GET /api/nodes/{id}/backlinks, listing the nodes in other maps
+// (or the same map) whose content links to the node. Only maps the user can view are included
+func (h *NodeHandler) GetNodeBacklinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract node ID from URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/nodes/")
+	if path == r.URL.Path || !strings.HasSuffix(path, "/backlinks") {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	nodeID := strings.TrimSuffix(path, "/backlinks")
+
+	// Parse node ID
+	if _, err := uuid.Parse(nodeID); err != nil {
+		http.Error(w, "Invalid node ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get node
+	node, err := tenantDB(h.DB, r).GetNodeByID(nodeID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get backlinks
+	backlinks, mindMaps, err := h.DB.GetBacklinks(nodeID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get backlinks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Drop references from maps the user cannot see, checking each map once
+	visible := make(map[string]bool)
+	response := models.BacklinksResponse{NodeID: nodeID, Backlinks: []models.Backlink{}}
+	for i, backlink := range backlinks {
+		canView, checked := visible[backlink.MindMapID]
+		if !checked {
+			canView = canViewMindMap(h.DB, &mindMaps[i], userID)
+			visible[backlink.MindMapID] = canView
+		}
+		if canView {
+			response.Backlinks = append(response.Backlinks, backlink)
+		}
+	}
+
+	// Return backlinks
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/realtime"
+	"saas-server/pkg/validation"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxCaptureBytes caps the size of a capture request
+	maxCaptureBytes = 64 << 10
+	// maxCaptureSelectionLength caps the length of a captured snippet
+	maxCaptureSelectionLength = 5000
+	// maxCaptureTitleLength caps the length of a captured page title
+	maxCaptureTitleLength = 500
+)
+
+// Placement of captured nodes: right of their parent, below its other children
+const (
+	captureLevelGap = 250
+	captureRowGap   = 70
+)
+
+// CaptureHandler creates resource nodes from snippets clipped in the browser
+type CaptureHandler struct {
+	DB  *database.DB
+	Hub *realtime.Hub
+}
+
+// NewCaptureHandler creates a new CaptureHandler
+func NewCaptureHandler(db *database.DB, hub *realtime.Hub) *CaptureHandler {
+	return &CaptureHandler{DB: db, Hub: hub}
+}
+
+// Capture handles POST /api/capture, the backend of the web clipper. It creates a resource
+// node holding the selected text, or the page title when nothing is selected, and keeps
+// the page URL and title in its metadata. Captures without a target go to the inbox
+func (h *CaptureHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.CaptureRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCaptureBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	req.URL = strings.TrimSpace(req.URL)
+	req.Title = strings.TrimSpace(req.Title)
+	req.Selection = strings.TrimSpace(req.Selection)
+	if err := validation.ValidateSourceURL(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Selection) > maxCaptureSelectionLength {
+		http.Error(w, fmt.Sprintf("Selection must be at most %d characters", maxCaptureSelectionLength), http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Title) > maxCaptureTitleLength {
+		http.Error(w, fmt.Sprintf("Title must be at most %d characters", maxCaptureTitleLength), http.StatusBadRequest)
+		return
+	}
+
+	// Resolve the target map from the parent node when one is given
+	if req.ParentID != nil {
+		if _, err := uuid.Parse(*req.ParentID); err != nil {
+			http.Error(w, "Invalid parent ID", http.StatusBadRequest)
+			return
+		}
+		parent, err := tenantDB(h.DB, r).GetNodeByID(*req.ParentID)
+		if err != nil {
+			http.Error(w, "Parent node not found", http.StatusNotFound)
+			return
+		}
+		req.MindMapID = parent.MindMapID
+	}
+	if req.MindMapID == "" {
+		// Without a target the capture goes to the user's inbox
+		inbox, _, err := h.DB.GetOrCreateInbox(userID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get inbox: %v", err), http.StatusInternalServerError)
+			return
+		}
+		req.MindMapID = inbox.ID
+	}
+	if _, err := uuid.Parse(req.MindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check if user can edit the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.captureNode(mindMap.ID, userID, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to capture: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created node
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// captureNode creates the resource node of a capture in a map the user can edit, attaching
+// it to the requested parent or else the map's first root, and broadcasts it
+func (h *CaptureHandler) captureNode(mindMapID, userID string, req models.CaptureRequest) (*models.CaptureResult, error) {
+	nodes, err := h.DB.GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the parent and put the node after its last child
+	var parent *models.Node
+	if req.ParentID != nil {
+		for i := range nodes {
+			if nodes[i].ID == *req.ParentID {
+				parent = &nodes[i]
+			}
+		}
+	} else if roots, err := outline.Build(nodes, outline.OrderPosition); err == nil && len(roots) > 0 {
+		parent = &roots[0].Node
+	}
+	x, y := nextChildPosition(nodes, parent)
+
+	content := req.Selection
+	if content == "" {
+		content = req.Title
+	}
+	if content == "" {
+		content = req.URL
+	}
+	metadata, err := json.Marshal(map[string]string{
+		"url":          req.URL,
+		"source_title": req.Title,
+		"captured_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodeReq := models.NodeCreateRequest{
+		MindMapID: mindMapID,
+		Content:   content,
+		PositionX: x,
+		PositionY: y,
+		NodeType:  models.NodeTypeResource,
+		Metadata:  metadata,
+		CreatedBy: userID,
+	}
+	if parent != nil {
+		nodeReq.ParentID = &parent.ID
+	}
+	node, err := h.DB.CreateNode(nodeReq)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.CaptureResult{Node: *node}
+
+	if parent != nil {
+		edge, err := h.DB.CreateEdge(models.EdgeCreateRequest{
+			MindMapID: mindMapID,
+			SourceID:  parent.ID,
+			TargetID:  node.ID,
+			EdgeType:  "default",
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Edge = edge
+	}
+	return result, nil
+}
+
+// nextChildPosition returns where a new child of parent goes: right of it, below its other
+// children. Without a parent the node starts at the origin
+func nextChildPosition(nodes []models.Node, parent *models.Node) (float64, float64) {
+	if parent == nil {
+		return 0, 0
+	}
+	x, y := parent.PositionX+captureLevelGap, parent.PositionY
+	last := math.Inf(-1)
+	for _, node := range nodes {
+		if node.ParentID != nil && *node.ParentID == parent.ID {
+			last = math.Max(last, node.PositionY)
+		}
+	}
+	if !math.IsInf(last, -1) {
+		y = last + captureRowGap
+	}
+	return x, y
+}
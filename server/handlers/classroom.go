@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/validation"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxClassroomRoster caps how many participant copies a single request may create
+const maxClassroomRoster = 200
+
+// ClassroomHandler handles classroom mode requests, where a facilitator
+// hands out a copy of a template map to every participant on a roster
+type ClassroomHandler struct {
+	DB *database.DB
+}
+
+// NewClassroomHandler creates a new ClassroomHandler
+func NewClassroomHandler(db *database.DB) *ClassroomHandler {
+	return &ClassroomHandler{DB: db}
+}
+
+// CreateClassroom handles POST /api/mindmaps/{id}/classroom
+func (h *ClassroomHandler) CreateClassroom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract template map ID from URL
+	templateID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	templateID = strings.TrimSuffix(templateID, "/classroom")
+	if templateID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse template map ID
+	if _, err := uuid.Parse(templateID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Only the owner of the template can run a classroom from it
+	template, err := tenantDB(h.DB, r).GetMindMapByID(templateID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if template.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.ClassroomCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate roster
+	if len(req.Emails) == 0 {
+		http.Error(w, "At least one participant email is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Emails) > maxClassroomRoster {
+		http.Error(w, fmt.Sprintf("Roster cannot exceed %d participants", maxClassroomRoster), http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]bool, len(req.Emails))
+	emails := make([]string, 0, len(req.Emails))
+	for _, email := range req.Emails {
+		email = strings.ToLower(validation.SanitizeInput(email, 255))
+		if !validation.ValidateEmail(email) {
+			http.Error(w, fmt.Sprintf("Invalid email address: %s", email), http.StatusBadRequest)
+			return
+		}
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+
+	// Clone the template for each participant in one go, so a failure creates no copies
+	copies, err := h.DB.CreateClassroomCopies(templateID, userID, emails)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create copies: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created copies
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(copies)
+}
+
+// GetClassroomDashboard handles GET /api/mindmaps/{id}/classroom
+func (h *ClassroomHandler) GetClassroomDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract template map ID from URL
+	templateID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	templateID = strings.TrimSuffix(templateID, "/classroom")
+	if templateID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse template map ID
+	if _, err := uuid.Parse(templateID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Only the facilitator who owns the template can view the dashboard
+	template, err := tenantDB(h.DB, r).GetMindMapByID(templateID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if template.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get participant copies
+	copies, err := h.DB.GetClassroomCopies(templateID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get classroom copies: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	dashboard := models.ClassroomDashboard{
+		TemplateID:   templateID,
+		Participants: len(copies),
+		Copies:       copies,
+	}
+	for _, classroomCopy := range copies {
+		dashboard.TotalNodeCount += classroomCopy.NodeCount
+	}
+
+	// Return dashboard
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
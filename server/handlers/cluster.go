@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/cluster"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/triage"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxClusterNodes caps how many nodes one clustering request compares
+	maxClusterNodes = 200
+	// defaultEmbeddingClusterThreshold is the cosine similarity of embeddings at which
+	// nodes are grouped by default
+	defaultEmbeddingClusterThreshold = 0.5
+	// defaultWordClusterThreshold is the word similarity at which nodes are grouped by
+	// default when there are no embeddings
+	defaultWordClusterThreshold = 0.2
+	// clusterGroupSpacing is the vertical distance between the group nodes of one request
+	clusterGroupSpacing = 80
+)
+
+// ClusterMindMap handles POST /api/mindmaps/{id}/cluster. The children of a node, the map's
+// root by default, are grouped by the similarity of their content: the cosine similarity
+// of their embeddings when an OpenAI key is available, otherwise the words they share.
+// Each cluster of two or more nodes is labelled, by the model when it can. With apply set,
+// which needs edit access, a group node named after each cluster is created under the node
+// and the cluster's nodes are moved under it. Summary, table of contents and group nodes
+// are left out
+func (h *MindMapHandler) ClusterMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/cluster")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body; an empty body uses the defaults
+	var req models.ClusterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Threshold != nil && (*req.Threshold < 0 || *req.Threshold > 1) {
+		http.Error(w, "Threshold must be between 0 and 1", http.StatusBadRequest)
+		return
+	}
+	if req.NodeID != "" {
+		if _, err := uuid.Parse(req.NodeID); err != nil {
+			http.Error(w, "Invalid node ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) || (req.Apply && !canEditMindMap(h.DB, mindMap, userID)) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Find the node whose children are clustered
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	parent := clusterParent(nodes, req.NodeID)
+	if parent == nil {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+	var members []models.Node
+	for _, child := range parent.Children {
+		switch child.Node.NodeType {
+		case models.NodeTypeSummary, models.NodeTypeTOC, models.NodeTypeGroup:
+			continue
+		}
+		if strings.TrimSpace(child.Node.Content) != "" {
+			members = append(members, child.Node)
+		}
+	}
+	if len(members) > maxClusterNodes {
+		http.Error(w, fmt.Sprintf("Cannot cluster more than %d nodes at once", maxClusterNodes), http.StatusBadRequest)
+		return
+	}
+
+	result := models.ClusterResult{
+		MindMapID:   mindMapID,
+		ParentID:    parent.Node.ID,
+		Method:      models.ClusterMethodWords,
+		Clusters:    []models.NodeCluster{},
+		Unclustered: []string{},
+	}
+
+	// Measure how similar the nodes are, by embeddings when possible
+	redactor := piiRedactor(h.DB, mindMap)
+	apiKey := h.clusterAPIKey(mindMap, userID)
+	similarity, embedded := h.clusterSimilarity(r.Context(), apiKey, mindMap, userID, members, redactor)
+	if embedded {
+		result.Method = models.ClusterMethodEmbeddings
+		result.Threshold = defaultEmbeddingClusterThreshold
+	} else {
+		result.Threshold = defaultWordClusterThreshold
+	}
+	if req.Threshold != nil {
+		result.Threshold = *req.Threshold
+	}
+
+	// Group them, labelling the clusters
+	var groups [][]int
+	for _, group := range cluster.Group(similarity, result.Threshold) {
+		if len(group) < 2 {
+			result.Unclustered = append(result.Unclustered, members[group[0]].ID)
+			continue
+		}
+		groups = append(groups, group)
+	}
+	labels := clusterLabels(members, groups)
+	if apiKey != "" && len(groups) > 0 {
+		aiLabels, usage, err := labelClustersWithAI(r.Context(), apiKey, members, groups, redactor)
+		recordAIUsage(h.DB, userID, mindMapID, aiFeatureCluster, usage)
+		if err != nil {
+			log.Printf("[Cluster] Labelling clusters of map %s by shared words: %v", mindMapID, err)
+		}
+		for i, label := range aiLabels {
+			if label != "" {
+				labels[i] = label
+			}
+		}
+	}
+	for i, group := range groups {
+		nodeCluster := models.NodeCluster{Label: labels[i], NodeIDs: make([]string, len(group))}
+		for j, member := range group {
+			nodeCluster.NodeIDs[j] = members[member].ID
+		}
+		result.Clusters = append(result.Clusters, nodeCluster)
+	}
+
+	// Create the group nodes and move the clusters under them
+	if req.Apply && len(result.Clusters) > 0 {
+		x, y := nextChildPosition(nodes, &parent.Node)
+		nodeGroups := make([]models.NodeGroup, len(result.Clusters))
+		for i, nodeCluster := range result.Clusters {
+			nodeGroups[i] = models.NodeGroup{
+				Group: models.NodeCreateRequest{
+					ParentID:  &parent.Node.ID,
+					Content:   nodeCluster.Label,
+					PositionX: x,
+					PositionY: y + float64(i)*clusterGroupSpacing,
+					NodeType:  models.NodeTypeGroup,
+					CreatedBy: userID,
+				},
+				NodeIDs: nodeCluster.NodeIDs,
+			}
+		}
+		created, err := h.DB.GroupNodes(mindMapID, nodeGroups)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to group nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for i := range created {
+			result.Clusters[i].GroupNode = &created[i]
+		}
+		result.Applied = true
+	}
+
+	// Return clusters
+	sendJSONResponse(w, http.StatusOK, result)
+}
+
+// clusterParent returns the outline item of the node whose children are clustered: the
+// given node, or without one the map's root. Returns nil if there is no such node
+func clusterParent(nodes []models.Node, nodeID string) *outline.Item {
+	if nodeID != "" {
+		return findOutlineItem(nodes, nodeID)
+	}
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil || len(roots) == 0 {
+		return nil
+	}
+	for _, root := range roots {
+		if root.Node.NodeType == "root" {
+			return root
+		}
+	}
+	return roots[0]
+}
+
+// clusterAPIKey returns the OpenAI key clustering a map uses, or an empty string when there
+// is none or the map's spend cap rules AI out, in which case clustering falls back to words
+func (h *MindMapHandler) clusterAPIKey(mindMap *models.MindMap, userID string) string {
+	apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+	if err != nil {
+		log.Printf("[Cluster] Clustering map %s by shared words: %v", mindMap.ID, err)
+		return ""
+	}
+	if apiKey == "" {
+		return ""
+	}
+	if _, err := enforceAISpendCap(h.DB, mindMap.ID, aiProviderOpenAI, openAIEmbeddingModel); err != nil {
+		log.Printf("[Cluster] Clustering map %s by shared words: %v", mindMap.ID, err)
+		return ""
+	}
+	return apiKey
+}
+
+// clusterSimilarity returns the similarity of every pair of nodes: the cosine similarity
+// of their embeddings, or with no key or when embedding fails the words they share.
+// Reports whether embeddings were used
+func (h *MindMapHandler) clusterSimilarity(ctx context.Context, apiKey string, mindMap *models.MindMap, userID string, nodes []models.Node, redactor *pii.Redactor) ([][]float64, bool) {
+	similarity := make([][]float64, len(nodes))
+	for i := range similarity {
+		similarity[i] = make([]float64, len(nodes))
+	}
+
+	if apiKey != "" && len(nodes) > 1 {
+		inputs := make([]string, len(nodes))
+		for i, node := range nodes {
+			inputs[i] = redactor.Redact(prompt.Line(node.Content))
+		}
+		embeddings, usage, err := openAIEmbeddings(ctx, apiKey, inputs)
+		recordAIUsage(h.DB, userID, mindMap.ID, aiFeatureCluster, usage)
+		if err == nil {
+			for i := range nodes {
+				for j := range nodes {
+					similarity[i][j] = triage.Cosine(embeddings[i], embeddings[j])
+				}
+			}
+			return similarity, true
+		}
+		log.Printf("[Cluster] Embeddings failed, clustering map %s by shared words: %v", mindMap.ID, err)
+	}
+
+	for i := range nodes {
+		for j := range nodes {
+			similarity[i][j] = triage.WordSimilarity(nodes[i].Content, nodes[j].Content)
+		}
+	}
+	return similarity, false
+}
+
+// clusterLabels names each group of nodes after the words its nodes share
+func clusterLabels(nodes []models.Node, groups [][]int) []string {
+	labels := make([]string, len(groups))
+	for i, group := range groups {
+		texts := make([]string, len(group))
+		for j, member := range group {
+			texts[j] = nodes[member].Content
+		}
+		labels[i] = cluster.Label(texts)
+	}
+	return labels
+}
+
+// labelClustersWithAI asks the model for a short label for each group of nodes. Groups the
+// model leaves out get an empty label. Also returns the usage of the request
+func labelClustersWithAI(ctx context.Context, apiKey string, nodes []models.Node, groups [][]int, redactor *pii.Redactor) ([]string, aiUsage, error) {
+	var list strings.Builder
+	for i, group := range groups {
+		fmt.Fprintf(&list, "Group %d:\n", i+1)
+		for _, member := range group {
+			fmt.Fprintf(&list, "- %s\n", prompt.Line(redactor.Redact(nodes[member].Content)))
+		}
+	}
+
+	content, usage, err := openAIChatCompletion(
+		ctx,
+		apiKey,
+		prompt.System("You name groups of related ideas from a mind map. For each numbered group, write a label of at most four words that captures what its ideas have in common. Respond only with a JSON array of objects with \"group\" (the group number) and \"label\"."),
+		prompt.Delimit(prompt.Limit(list.String(), prompt.MaxContextLength)),
+		800,
+	)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, usage, fmt.Errorf("no JSON array in cluster labels response")
+	}
+	var answers []struct {
+		Group int    `json:"group"`
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &answers); err != nil {
+		return nil, usage, err
+	}
+
+	labels := make([]string, len(groups))
+	for _, answer := range answers {
+		if answer.Group < 1 || answer.Group > len(groups) {
+			continue
+		}
+		labels[answer.Group-1] = prompt.Line(redactor.Restore(answer.Label))
+	}
+	return labels, usage, nil
+}
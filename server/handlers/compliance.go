@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/export"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits of legal holds and audit log pages
+const (
+	maxLegalHoldReasonLength = 2000
+	defaultAuditLogLimit     = 50
+	maxAuditLogLimit         = 200
+)
+
+// ComplianceHandler lets organization admins place legal holds on their members' mind
+// maps, export held maps and read the audit log of what admins did
+type ComplianceHandler struct {
+	DB *database.DB
+}
+
+// NewComplianceHandler creates a new ComplianceHandler
+func NewComplianceHandler(db *database.DB) *ComplianceHandler {
+	return &ComplianceHandler{DB: db}
+}
+
+// GetLegalHolds handles GET /api/org/legal-holds?include_released=true
+func (h *ComplianceHandler) GetLegalHolds(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	holds, err := h.DB.GetLegalHolds(tenantID, r.URL.Query().Get("include_released") == "true")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get legal holds: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return legal holds
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+// CreateLegalHold handles POST /api/org/legal-holds
+func (h *ComplianceHandler) CreateLegalHold(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.LegalHoldCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Reason) > maxLegalHoldReasonLength {
+		http.Error(w, fmt.Sprintf("Reason must be at most %d characters", maxLegalHoldReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	// Place the hold, logging it in the same transaction
+	userID, _ := r.Context().Value("userID").(string)
+	audit, err := auditEvent(r, tenantID, models.AuditLegalHoldCreated, "legal_hold", "", req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create legal hold: %v", err), http.StatusInternalServerError)
+		return
+	}
+	hold, err := h.DB.CreateLegalHold(tenantID, req.UserID, req.Reason, userID, audit)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "User not found in your organization", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, database.ErrLegalHoldExists) {
+		http.Error(w, "The user already has an active legal hold", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create legal hold: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created legal hold
+	sendJSONResponse(w, http.StatusCreated, hold)
+}
+
+// GetLegalHold handles GET /api/org/legal-holds/{id}
+func (h *ComplianceHandler) GetLegalHold(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+	holdID, ok := legalHoldID(w, r, "")
+	if !ok {
+		return
+	}
+
+	hold, err := h.DB.GetLegalHold(tenantID, holdID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Legal hold not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get legal hold: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return legal hold
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// ReleaseLegalHold handles DELETE /api/org/legal-holds/{id}. The hold is kept as a
+// record, marked as released
+func (h *ComplianceHandler) ReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+	holdID, ok := legalHoldID(w, r, "")
+	if !ok {
+		return
+	}
+
+	// Release the hold, logging it in the same transaction
+	userID, _ := r.Context().Value("userID").(string)
+	audit, err := auditEvent(r, tenantID, models.AuditLegalHoldReleased, "legal_hold", holdID, struct{}{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to release legal hold: %v", err), http.StatusInternalServerError)
+		return
+	}
+	hold, err := h.DB.ReleaseLegalHold(tenantID, holdID, userID, audit)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Active legal hold not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to release legal hold: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return released legal hold
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// ExportLegalHold handles GET /api/org/legal-holds/{id}/export, downloading a zip of every
+// mind map the held user owns, including those in the trash. The export is only sent once
+// it has been logged
+func (h *ComplianceHandler) ExportLegalHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+	holdID, ok := legalHoldID(w, r, "/export")
+	if !ok {
+		return
+	}
+
+	hold, err := h.DB.GetLegalHold(tenantID, holdID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Legal hold not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get legal hold: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported as an error response
+	mindMaps, err := h.DB.GetUserMindMapsWithDetails(hold.UserID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(string)
+	manifest := models.LegalHoldExportManifest{
+		HoldID:     hold.ID,
+		UserID:     hold.UserID,
+		UserEmail:  hold.UserEmail,
+		Reason:     hold.Reason,
+		ExportedBy: userID,
+		ExportedAt: time.Now().UTC(),
+	}
+	var buf bytes.Buffer
+	if err := export.LegalHoldArchive(&buf, manifest, mindMaps); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Log the export before anything is sent
+	audit, err := auditEvent(r, tenantID, models.AuditLegalHoldExported, "legal_hold", hold.ID, map[string]interface{}{
+		"user_id":   hold.UserID,
+		"mind_maps": len(mindMaps),
+		"bytes":     buf.Len(),
+	})
+	if err == nil {
+		err = h.DB.RecordAuditEvent(audit)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to log export: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return export as a download
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "legal-hold-"+hold.ID+".zip"))
+	w.Write(buf.Bytes())
+}
+
+// GetAuditLog handles GET /api/org/audit-log?limit=50&before=id, returning the
+// organization's audit events newest first. The next page starts before the ID of the
+// last event returned
+func (h *ComplianceHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	// Parse page
+	limit := defaultAuditLogLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLogLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxAuditLogLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	var before int64
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		before, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || before < 1 {
+			http.Error(w, "before must be an audit event ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get events, fetching one extra to know whether more remain
+	events, err := h.DB.GetAuditEvents(tenantID, before, limit+1)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	auditLog := models.AuditLog{Events: events}
+	if len(events) > limit {
+		auditLog.Events = events[:limit]
+		auditLog.HasMore = true
+	}
+
+	// Return audit log
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditLog)
+}
+
+// legalHoldID extracts the legal hold ID from /api/org/legal-holds/{id}{suffix},
+// responding with an error and returning false if it is missing or invalid
+func legalHoldID(w http.ResponseWriter, r *http.Request, suffix string) (string, bool) {
+	holdID := strings.TrimPrefix(r.URL.Path, "/api/org/legal-holds/")
+	holdID = strings.TrimSuffix(holdID, suffix)
+	if holdID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return "", false
+	}
+	if _, err := uuid.Parse(holdID); err != nil {
+		http.Error(w, "Invalid legal hold ID", http.StatusBadRequest)
+		return "", false
+	}
+	return holdID, true
+}
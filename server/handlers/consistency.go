@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"saas-server/database"
+	"saas-server/middleware"
+)
+
+// readDB returns the database a read-only request should use. Reads may be served by a
+// replica; a client that just wrote and must see its change can ask for the primary with
+// the X-Read-Consistency: strong header or the consistency=strong query parameter. Reads
+// are scoped to the tenant of the request, see tenantDB
+func readDB(db *database.DB, r *http.Request) *database.DB {
+	db = tenantDB(db, r)
+	if r.Header.Get("X-Read-Consistency") == "strong" || r.URL.Query().Get("consistency") == "strong" {
+		return db.Primary()
+	}
+	return db
+}
+
+// tenantDB returns the database scoped to the tenant of the authenticated user, so reads of
+// mind maps, nodes, edges and API keys cannot return another tenant's rows even if an
+// access check is missing. Unauthenticated requests, such as those of public share links,
+// get the unscoped database
+func tenantDB(db *database.DB, r *http.Request) *database.DB {
+	return db.ForTenant(middleware.GetTenantID(r.Context()))
+}
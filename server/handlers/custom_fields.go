@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/fields"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetCustomFields handles GET /api/mindmaps/{id}/fields
+func (h *MindMapHandler) GetCustomFields(w http.ResponseWriter, r *http.Request) {
+	mindMap, ok := h.customFieldsMindMap(w, r)
+	if !ok {
+		return
+	}
+
+	// Check if user has access
+	userID, _ := r.Context().Value("userID").(string)
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	schema, err := readDB(h.DB, r).GetMindMapCustomFields(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get custom fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return custom field schema
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CustomFieldsResponse{MindMapID: mindMap.ID, Fields: schema})
+}
+
+// UpdateCustomFields handles PUT /api/mindmaps/{id}/fields, replacing the custom field schema.
+// Only the owner can change it. Existing node values are kept as they are and checked
+// against the new schema the next time the node is written
+func (h *MindMapHandler) UpdateCustomFields(w http.ResponseWriter, r *http.Request) {
+	mindMap, ok := h.customFieldsMindMap(w, r)
+	if !ok {
+		return
+	}
+
+	// Check ownership
+	userID, _ := r.Context().Value("userID").(string)
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.CustomFieldsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Fields == nil {
+		req.Fields = []models.CustomField{}
+	}
+	if err := fields.ValidateSchema(req.Fields); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid custom fields: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.SetMindMapCustomFields(mindMap.ID, req.Fields); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update custom fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return custom field schema
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CustomFieldsResponse{MindMapID: mindMap.ID, Fields: req.Fields})
+}
+
+// customFieldsMindMap extracts the mind map ID of a custom fields request and loads the map,
+// writing an error response and returning false if that fails
+func (h *MindMapHandler) customFieldsMindMap(w http.ResponseWriter, r *http.Request) (*models.MindMap, bool) {
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/fields")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Get user ID from context
+	if _, ok := r.Context().Value("userID").(string); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// Get mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return mindMap, true
+}
+
+// checkNodeCustomFields validates node metadata against its mind map's custom field schema,
+// writing an error response and returning false if the metadata is rejected
+func checkNodeCustomFields(w http.ResponseWriter, db *database.DB, mindMapID string, metadata json.RawMessage) bool {
+	if metadata == nil {
+		return true
+	}
+
+	schema, err := db.GetMindMapCustomFields(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get custom fields: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	if err := fields.ValidateValues(schema, metadata); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid metadata: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// applyNodeRollups computes the rollup fields of a map's nodes, writing an error response
+// and returning false if the schema cannot be loaded
+func applyNodeRollups(w http.ResponseWriter, db *database.DB, mindMapID string, nodes []models.Node) bool {
+	schema, err := db.GetMindMapCustomFields(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get custom fields: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	fields.ApplyRollups(schema, nodes)
+	return true
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/middleware"
+	"saas-server/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits of demo maps, which anyone can create without an account
+const (
+	demoMapTTL            = 2 * time.Hour
+	maxDemoMapsPerClient  = 3
+	maxDemoNodes          = 50
+	maxDemoContentLength  = 500
+	maxDemoSuggestions    = 5
+	demoPurgeInterval     = 10 * time.Minute
+	demoSuggestionOffsetX = 250
+	demoSuggestionSpacing = 80
+)
+
+// demoTokenHeader is the header the token of a demo map is sent in
+const demoTokenHeader = "X-Demo-Token"
+
+// DemoHandler serves the ephemeral maps of anonymous visitors trying the app without
+// signing up. Demo maps live in their own tables, are reached with the token handed out
+// when they are created, never use AI and are purged when they expire
+type DemoHandler struct {
+	DB *database.DB
+}
+
+// NewDemoHandler creates a new DemoHandler
+func NewDemoHandler(db *database.DB) *DemoHandler {
+	return &DemoHandler{DB: db}
+}
+
+// StartPurgeJob starts the background job that removes expired demo maps
+func (h *DemoHandler) StartPurgeJob() {
+	go func() {
+		ticker := time.NewTicker(demoPurgeInterval)
+		defer ticker.Stop()
+		for {
+			deleted, err := h.DB.DeleteExpiredDemoMaps()
+			if err != nil {
+				log.Printf("[Demo] Error purging expired demo maps: %v", err)
+			} else if deleted > 0 {
+				log.Printf("[Demo] Purged %d expired demo maps", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// hashDemoToken returns the hash a demo map token is stored and looked up by
+func hashDemoToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateDemoMap handles POST /api/demo/maps. The response carries the token that the
+// other demo endpoints need in the X-Demo-Token header; it is not shown again
+func (h *DemoHandler) CreateDemoMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req models.DemoMapCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Title) > 255 {
+		http.Error(w, "Title must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	token := "demo_" + hex.EncodeToString(b)
+
+	demoMap, err := h.DB.CreateDemoMap(hashDemoToken(token), req.Title, middleware.ClientIP(r), demoMapTTL, maxDemoMapsPerClient)
+	if errors.Is(err, database.ErrDemoLimitReached) {
+		http.Error(w, fmt.Sprintf("At most %d demo maps can be open at a time; sign up to keep more", maxDemoMapsPerClient), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create demo map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created map with its token
+	sendJSONResponse(w, http.StatusCreated, models.DemoMapCreated{DemoMap: *demoMap, Token: token})
+}
+
+// HandleDemoMap handles the routes under /api/demo/maps/{id}: GET and DELETE on the map,
+// POST on /nodes, DELETE on /nodes/{nodeID} and POST on /suggest
+func (h *DemoHandler) HandleDemoMap(w http.ResponseWriter, r *http.Request) {
+	// Extract demo map ID and the rest of the URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/demo/maps/")
+	if path == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	demoMapID, rest, _ := strings.Cut(path, "/")
+
+	// Parse demo map ID
+	if _, err := uuid.Parse(demoMapID); err != nil {
+		http.Error(w, "Invalid demo map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get the map with its token
+	demoMap, err := h.DB.GetDemoMap(demoMapID, hashDemoToken(r.Header.Get(demoTokenHeader)))
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Demo map not found or expired", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get demo map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		// Return the map
+		sendJSONResponse(w, http.StatusOK, demoMap)
+	case rest == "" && r.Method == http.MethodDelete:
+		if err := h.DB.DeleteDemoMap(demoMap.ID); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to delete demo map: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case rest == "nodes" && r.Method == http.MethodPost:
+		h.createDemoNode(w, r, demoMap)
+	case strings.HasPrefix(rest, "nodes/") && r.Method == http.MethodDelete:
+		h.deleteDemoNode(w, demoMap, strings.TrimPrefix(rest, "nodes/"))
+	case rest == "suggest" && r.Method == http.MethodPost:
+		h.suggestDemoIdeas(w, r, demoMap)
+	case rest == "" || rest == "nodes" || strings.HasPrefix(rest, "nodes/") || rest == "suggest":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// createDemoNode adds a node to a demo map, under its root unless a parent is given
+func (h *DemoHandler) createDemoNode(w http.ResponseWriter, r *http.Request, demoMap *models.DemoMap) {
+	// Parse request body
+	var req models.DemoNodeCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Content) > maxDemoContentLength {
+		http.Error(w, fmt.Sprintf("Content must be at most %d characters", maxDemoContentLength), http.StatusBadRequest)
+		return
+	}
+	if req.ParentID == "" {
+		req.ParentID = demoMap.Nodes[0].ID
+	}
+
+	nodes, ok := h.createDemoNodes(w, demoMap, []models.DemoNodeCreateRequest{req})
+	if !ok {
+		return
+	}
+
+	// Return created node
+	sendJSONResponse(w, http.StatusCreated, nodes[0])
+}
+
+// suggestDemoIdeas adds ideas suggested without AI as children of a node of a demo map,
+// the root unless a node is given, the way the expand button of a real map adds
+// generated ideas
+func (h *DemoHandler) suggestDemoIdeas(w http.ResponseWriter, r *http.Request, demoMap *models.DemoMap) {
+	// Parse request body
+	var req models.DemoSuggestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Type != "" && req.Type != "new" && req.Type != "expand" && req.Type != "improve" && req.Type != "branch" {
+		http.Error(w, "Type must be 'new', 'expand', 'improve' or 'branch'", http.StatusBadRequest)
+		return
+	}
+	if req.Count <= 0 || req.Count > maxDemoSuggestions {
+		req.Count = maxDemoSuggestions
+	}
+	req.Count = min(req.Count, maxDemoNodes-len(demoMap.Nodes))
+	if req.Count <= 0 {
+		http.Error(w, fmt.Sprintf("Demo maps can have at most %d nodes; sign up to keep going", maxDemoNodes), http.StatusConflict)
+		return
+	}
+
+	parent := &demoMap.Nodes[0]
+	if req.NodeID != "" {
+		parent = nil
+		for i := range demoMap.Nodes {
+			if demoMap.Nodes[i].ID == req.NodeID {
+				parent = &demoMap.Nodes[i]
+				break
+			}
+		}
+		if parent == nil {
+			http.Error(w, "Node not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// Stack the ideas right of the node, below its other children
+	ideas := localIdeas(GenerationRequest{Topic: parent.Content, Type: req.Type, Count: req.Count})
+	y := parent.PositionY - float64(len(ideas)-1)*demoSuggestionSpacing/2
+	for _, node := range demoMap.Nodes {
+		if node.ParentID != nil && *node.ParentID == parent.ID {
+			y = max(y, node.PositionY+demoSuggestionSpacing)
+		}
+	}
+	reqs := make([]models.DemoNodeCreateRequest, len(ideas))
+	for i, idea := range ideas {
+		reqs[i] = models.DemoNodeCreateRequest{
+			ParentID:  parent.ID,
+			Content:   idea.Content,
+			PositionX: parent.PositionX + demoSuggestionOffsetX,
+			PositionY: y + float64(i)*demoSuggestionSpacing,
+		}
+	}
+
+	nodes, ok := h.createDemoNodes(w, demoMap, reqs)
+	if !ok {
+		return
+	}
+
+	// Return created nodes
+	sendJSONResponse(w, http.StatusCreated, nodes)
+}
+
+// createDemoNodes stores nodes of a demo map, responding with an error and returning
+// false if the map is full or a parent is not one of its nodes
+func (h *DemoHandler) createDemoNodes(w http.ResponseWriter, demoMap *models.DemoMap, reqs []models.DemoNodeCreateRequest) ([]models.DemoNode, bool) {
+	for _, req := range reqs {
+		if _, err := uuid.Parse(req.ParentID); err != nil {
+			http.Error(w, "Invalid parent ID", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	nodes, err := h.DB.CreateDemoNodes(demoMap.ID, reqs, maxDemoNodes)
+	if errors.Is(err, database.ErrDemoLimitReached) {
+		http.Error(w, fmt.Sprintf("Demo maps can have at most %d nodes; sign up to keep going", maxDemoNodes), http.StatusConflict)
+		return nil, false
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Parent node not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create node: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return nodes, true
+}
+
+// deleteDemoNode removes a node other than the root from a demo map, along with the nodes
+// under it
+func (h *DemoHandler) deleteDemoNode(w http.ResponseWriter, demoMap *models.DemoMap, nodeID string) {
+	// Parse node ID
+	if _, err := uuid.Parse(nodeID); err != nil {
+		http.Error(w, "Invalid node ID", http.StatusBadRequest)
+		return
+	}
+
+	err := h.DB.DeleteDemoNode(demoMap.ID, nodeID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete node: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
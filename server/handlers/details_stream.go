@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"saas-server/models"
+	"strconv"
+	"strings"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON, which clients send in
+// Accept to have large map details streamed
+const ndjsonContentType = "application/x-ndjson"
+
+// defaultDetailsStreamThreshold is how many nodes and edges together a map needs before its
+// details are streamed, unless DETAILS_STREAM_THRESHOLD overrides it
+const defaultDetailsStreamThreshold = 2000
+
+// detailsStreamBatchSize is how many nodes or edges each streamed line carries
+const detailsStreamBatchSize = 500
+
+// detailsChunk is one line of a streamed details response. The map header comes first,
+// then the nodes and edges in batches, and finally an end line with the totals, so a
+// client can tell a complete response from a cut-off one
+type detailsChunk struct {
+	Type      string               `json:"type"` // "map", "nodes", "edges" or "end"
+	MindMap   *models.MindMap      `json:"mind_map,omitempty"`
+	Fields    []models.CustomField `json:"fields,omitempty"`
+	Nodes     []models.Node        `json:"nodes,omitempty"`
+	Edges     []models.Edge        `json:"edges,omitempty"`
+	NodeCount *int                 `json:"node_count,omitempty"`
+	EdgeCount *int                 `json:"edge_count,omitempty"`
+}
+
+// detailsStreamThreshold reads DETAILS_STREAM_THRESHOLD; 0 streams every map to clients
+// that accept it
+func detailsStreamThreshold() int {
+	value := os.Getenv("DETAILS_STREAM_THRESHOLD")
+	if value == "" {
+		return defaultDetailsStreamThreshold
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 0 {
+		return defaultDetailsStreamThreshold
+	}
+	return threshold
+}
+
+// wantsDetailsStream reports whether the details of a map should be streamed: the client
+// accepts NDJSON and the map is large enough for it to be worthwhile. Other clients always
+// get a single JSON document
+func wantsDetailsStream(r *http.Request, details *models.MindMapWithDetails) bool {
+	if !strings.Contains(r.Header.Get("Accept"), ndjsonContentType) {
+		return false
+	}
+	return len(details.Nodes)+len(details.Edges) >= detailsStreamThreshold()
+}
+
+// streamDetails writes map details as NDJSON, flushing after every line so the client can
+// render each batch as it arrives
+func streamDetails(w http.ResponseWriter, details *models.MindMapWithDetails) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	write := func(chunk detailsChunk) bool {
+		if err := encoder.Encode(chunk); err != nil {
+			log.Printf("[Details Stream] Failed to write %s chunk of map %s: %v", chunk.Type, details.ID, err)
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	if !write(detailsChunk{Type: "map", MindMap: &details.MindMap, Fields: details.Fields}) {
+		return
+	}
+	for start := 0; start < len(details.Nodes); start += detailsStreamBatchSize {
+		end := min(start+detailsStreamBatchSize, len(details.Nodes))
+		if !write(detailsChunk{Type: "nodes", Nodes: details.Nodes[start:end]}) {
+			return
+		}
+	}
+	for start := 0; start < len(details.Edges); start += detailsStreamBatchSize {
+		end := min(start+detailsStreamBatchSize, len(details.Edges))
+		if !write(detailsChunk{Type: "edges", Edges: details.Edges[start:end]}) {
+			return
+		}
+	}
+	nodeCount, edgeCount := len(details.Nodes), len(details.Edges)
+	write(detailsChunk{Type: "end", NodeCount: &nodeCount, EdgeCount: &edgeCount})
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/document"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/triage"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Limits of uploaded documents
+const (
+	maxUploadBytes       = 10 << 20
+	maxDocumentChunks    = 300
+	maxMindMapDocuments  = 20
+	documentChunkSize    = 1500 // Bytes of text per chunk
+	groundingSources     = 5    // Chunks sent with a grounded generation
+	citationExcerptBytes = 200
+)
+
+// DocumentHandler handles documents uploaded to mind maps to ground idea generation in
+type DocumentHandler struct {
+	DB *database.DB
+}
+
+// NewDocumentHandler creates a new DocumentHandler
+func NewDocumentHandler(db *database.DB) *DocumentHandler {
+	return &DocumentHandler{DB: db}
+}
+
+// GetDocuments handles GET /api/mindmaps/{id}/documents
+func (h *DocumentHandler) GetDocuments(w http.ResponseWriter, r *http.Request) {
+	mindMap, _, ok := h.documentMindMap(w, r, false)
+	if !ok {
+		return
+	}
+
+	documents, err := h.DB.GetDocuments(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return documents
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(documents)
+}
+
+// UploadDocument handles POST /api/mindmaps/{id}/documents with a txt, md, pdf or docx
+// file in the multipart "file" field. The text is split into chunks that are embedded
+// with OpenAI when a key is available; without one, grounded generation matches chunks
+// by their words. Only the text is kept
+func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	mindMap, userID, ok := h.documentMindMap(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Read the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "A document is required in the 'file' field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	format, ok := document.FormatOf(header.Filename)
+	if !ok {
+		http.Error(w, "Document must be a .txt, .md, .pdf or .docx file", http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	if err != nil {
+		http.Error(w, "Failed to read document", http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxUploadBytes {
+		http.Error(w, fmt.Sprintf("Document must be at most %d MB", maxUploadBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	count, err := h.DB.CountDocuments(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to upload document: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if count >= maxMindMapDocuments {
+		http.Error(w, fmt.Sprintf("A mind map can have at most %d documents", maxMindMapDocuments), http.StatusConflict)
+		return
+	}
+
+	// Extract and chunk the text
+	text, err := document.Extract(format, data)
+	if errors.Is(err, document.ErrNoText) {
+		http.Error(w, "No text could be extracted from the document", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	texts := document.Chunk(text, documentChunkSize)
+	if len(texts) > maxDocumentChunks {
+		http.Error(w, fmt.Sprintf("Document is too long; it must have at most about %d characters of text", maxDocumentChunks*documentChunkSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	chunks := make([]models.DocumentChunk, len(texts))
+	for i, chunkText := range texts {
+		chunks[i] = models.DocumentChunk{Index: i, Content: chunkText}
+	}
+
+	// Embed the chunks, redacted, when OpenAI can be used
+	embedded := false
+	apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+	if err != nil {
+		log.Printf("[Documents] Storing %s without embeddings: %v", header.Filename, err)
+	}
+	if apiKey != "" {
+		redactor := piiRedactor(h.DB, mindMap)
+		inputs := make([]string, len(texts))
+		for i, chunkText := range texts {
+			inputs[i] = redactor.Redact(chunkText)
+		}
+		embeddings, usage, err := openAIEmbeddings(r.Context(), apiKey, inputs)
+		recordAIUsage(h.DB, userID, mindMap.ID, aiFeatureDocuments, usage)
+		if err != nil {
+			log.Printf("[Documents] Embedding %s failed, storing it without embeddings: %v", header.Filename, err)
+		} else {
+			for i, embedding := range embeddings {
+				chunks[i].Embedding = make([]float32, len(embedding))
+				for j, value := range embedding {
+					chunks[i].Embedding[j] = float32(value)
+				}
+			}
+			embedded = true
+		}
+	}
+
+	doc := &models.Document{
+		MindMapID:  mindMap.ID,
+		UserID:     &userID,
+		Filename:   filepath.Base(header.Filename),
+		Format:     format,
+		SizeBytes:  len(data),
+		ChunkCount: len(chunks),
+		Embedded:   embedded,
+	}
+	if err := h.DB.CreateDocument(doc, chunks); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to upload document: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created document
+	sendJSONResponse(w, http.StatusCreated, doc)
+}
+
+// DeleteDocument handles DELETE /api/documents/{id}. Nodes keep the citations they have
+func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract document ID from URL
+	documentID := strings.TrimPrefix(r.URL.Path, "/api/documents/")
+	if documentID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(documentID); err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user can edit the document's mind map
+	doc, err := h.DB.GetDocument(documentID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get document: %v", err), http.StatusInternalServerError)
+		return
+	}
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(doc.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.DB.DeleteDocument(documentID); err != nil && !errors.Is(err, database.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to delete document: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// documentMindMap gets the mind map of /api/mindmaps/{id}/documents and the user, checking
+// the user can view it or, with edit, edit it. Responds with an error and returns false if
+// either fails
+func (h *DocumentHandler) documentMindMap(w http.ResponseWriter, r *http.Request, edit bool) (*models.MindMap, string, bool) {
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/documents")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return nil, "", false
+	}
+	allowed := canViewMindMap(h.DB, mindMap, userID)
+	if edit {
+		allowed = canEditMindMap(h.DB, mindMap, userID)
+	}
+	if !allowed {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+	return mindMap, userID, true
+}
+
+// retrieveDocumentChunks returns the chunks of a mind map's documents closest to a query,
+// best first. The query is embedded, redacted, with OpenAI when a key is available and
+// compared with the embedded chunks; chunks without an embedding, or all of them when the
+// query cannot be embedded, are compared by their words
+func retrieveDocumentChunks(ctx context.Context, db *database.DB, userID, apiKeyOverride, mindMapID, query string, redactor *pii.Redactor) ([]models.DocumentChunk, error) {
+	chunks, err := db.GetDocumentChunks(mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return chunks, nil
+	}
+
+	embedded := false
+	for _, chunk := range chunks {
+		embedded = embedded || chunk.Embedding != nil
+	}
+	var queryEmbedding []float64
+	if embedded {
+		apiKey, err := resolveOpenAIKey(db, userID, apiKeyOverride)
+		if err != nil {
+			log.Printf("[Documents] Matching chunks of map %s by words: %v", mindMapID, err)
+		}
+		if apiKey != "" {
+			embeddings, usage, err := openAIEmbeddings(ctx, apiKey, []string{redactor.Redact(query)})
+			recordAIUsage(db, userID, mindMapID, aiFeatureDocuments, usage)
+			if err != nil {
+				log.Printf("[Documents] Embedding the query failed, matching chunks of map %s by words: %v", mindMapID, err)
+			} else {
+				queryEmbedding = embeddings[0]
+			}
+		}
+	}
+
+	scores := make([]float64, len(chunks))
+	for i, chunk := range chunks {
+		if queryEmbedding != nil && chunk.Embedding != nil {
+			embedding := make([]float64, len(chunk.Embedding))
+			for j, value := range chunk.Embedding {
+				embedding[j] = float64(value)
+			}
+			scores[i] = triage.Cosine(queryEmbedding, embedding)
+		} else {
+			scores[i] = triage.WordSimilarity(query, chunk.Content)
+		}
+	}
+	top := triage.Top(scores, groundingSources)
+	best := make([]models.DocumentChunk, len(top))
+	for i, index := range top {
+		best[i] = chunks[index]
+	}
+	return best, nil
+}
+
+// sourcesPrompt lists chunks as numbered sources for the delimited input of a generation,
+// redacted
+func sourcesPrompt(chunks []models.DocumentChunk, redactor *pii.Redactor) string {
+	var b strings.Builder
+	b.WriteString("Sources:")
+	for i, chunk := range chunks {
+		fmt.Fprintf(&b, "\n[%d] From %s:\n%s", i+1, prompt.Line(chunk.Filename), prompt.Clean(redactor.Redact(chunk.Content)))
+	}
+	return b.String()
+}
+
+// citations turns the source numbers an idea cites into citations of the chunks, ignoring
+// numbers of no source and repeats
+func citations(sources []int, chunks []models.DocumentChunk) []models.Citation {
+	var cited []models.Citation
+	seen := make(map[int]bool)
+	for _, source := range sources {
+		if source < 1 || source > len(chunks) || seen[source] {
+			continue
+		}
+		seen[source] = true
+		chunk := chunks[source-1]
+		excerpt := chunk.Content
+		if len(excerpt) > citationExcerptBytes {
+			cut := citationExcerptBytes
+			for cut > 0 && excerpt[cut]&0xC0 == 0x80 {
+				cut--
+			}
+			excerpt = excerpt[:cut] + "…"
+		}
+		cited = append(cited, models.Citation{
+			DocumentID: chunk.DocumentID,
+			Filename:   chunk.Filename,
+			ChunkIndex: chunk.Index,
+			Excerpt:    excerpt,
+		})
+	}
+	return cited
+}
+
+// groundedIdeasSchema is the JSON reply grounded idea generation asks providers for: the
+// ideas of ideasSchema with the numbers of the sources each draws on
+var groundedIdeasSchema = jsonSchema{
+	name:        "submit_ideas",
+	description: "Submit the generated ideas with the sources they draw on",
+	schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"ideas": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":      map[string]interface{}{"type": "string", "description": "A short name for the idea"},
+						"content":    map[string]interface{}{"type": "string", "description": "The idea in one or two sentences"},
+						"confidence": map[string]interface{}{"type": "number", "description": "How well the idea fits the topic, from 0 to 1"},
+						"sources": map[string]interface{}{
+							"type":        "array",
+							"description": "The numbers of the sources the idea draws on",
+							"items":       map[string]interface{}{"type": "integer"},
+						},
+					},
+					"required":             []string{"title", "content", "confidence", "sources"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"ideas"},
+		"additionalProperties": false,
+	},
+}
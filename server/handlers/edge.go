@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"saas-server/database"
 	"saas-server/models"
+	"saas-server/pkg/realtime"
 	"strings"
 
 	"github.com/google/uuid"
@@ -13,12 +14,13 @@ import (
 
 // EdgeHandler handles edge-related requests
 type EdgeHandler struct {
-	DB *database.DB
+	DB  *database.DB
+	Hub *realtime.Hub
 }
 
 // NewEdgeHandler creates a new EdgeHandler
-func NewEdgeHandler(db *database.DB) *EdgeHandler {
-	return &EdgeHandler{DB: db}
+func NewEdgeHandler(db *database.DB, hub *realtime.Hub) *EdgeHandler {
+	return &EdgeHandler{DB: db, Hub: hub}
 }
 
 // CreateEdge handles POST /api/edges
@@ -57,12 +59,12 @@ func (h *EdgeHandler) CreateEdge(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(req.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -109,18 +111,18 @@ func (h *EdgeHandler) GetEdgesByMindMap(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID && !mindMap.IsPublic {
+	if !canViewMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get edges
-	edges, err := h.DB.GetEdgesByMindMapID(mindMapID)
+	edges, err := tenantDB(h.DB, r).GetEdgesByMindMapID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get edges: %v", err), http.StatusInternalServerError)
 		return
@@ -159,19 +161,19 @@ func (h *EdgeHandler) GetEdge(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get edge
-	edge, err := h.DB.GetEdgeByID(edgeID)
+	edge, err := tenantDB(h.DB, r).GetEdgeByID(edgeID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get edge: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(edge.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(edge.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID && !mindMap.IsPublic {
+	if !canViewMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -209,19 +211,19 @@ func (h *EdgeHandler) DeleteEdge(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get edge
-	edge, err := h.DB.GetEdgeByID(edgeID)
+	edge, err := tenantDB(h.DB, r).GetEdgeByID(edgeID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get edge: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(edge.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(edge.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -272,19 +274,19 @@ func (h *EdgeHandler) DeleteEdgeByNodes(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Get source node to check mind map ownership
-	sourceNode, err := h.DB.GetNodeByID(req.SourceID)
+	sourceNode, err := tenantDB(h.DB, r).GetNodeByID(req.SourceID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get source node: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(sourceNode.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(sourceNode.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/export"
+	"saas-server/pkg/layout"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/tts"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// exportDisabledError is the response when the owner of a shared map has turned exports off
+const exportDisabledError = "The owner of this mind map does not allow exporting or duplicating it"
+
+// ExportMindMap handles GET /api/mindmaps/{id}/export?format=json|pptx|csv|xlsx|obsidian|svg|pdf|narration|mp3.
+// Drawings use the map's layout mode, or the one given with ?layout=, without saving it,
+// and can be recolored for printing and accessibility with
+// ?theme=monochrome|high-contrast|no-background.
+// Narration is a spoken walkthrough script, visiting branches depth-first or with
+// ?walk=breadth level by level; mp3 reads it out through the configured TTS provider.
+// PDFs are printed on ?paper=a4|a3|letter in ?orientation=portrait|landscape, chosen to
+// suit the map by default, shrunk to fit one page or with ?tile=true tiled at full size
+// across pages; by default the page is sized to the map
+func (h *MindMapHandler) ExportMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/export")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Validate export options
+	opts, ok := parseExportOptions(w, r)
+	if !ok {
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get mind map with details
+	mindMap, err := readDB(h.DB, r).GetMindMapWithDetails(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user has access
+	if !canViewMindMap(h.DB, &mindMap.MindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !canExportMindMap(h.DB, &mindMap.MindMap, userID) {
+		http.Error(w, exportDisabledError, http.StatusForbidden)
+		return
+	}
+	models.MaskNodeAttribution(mindMap.Nodes)
+
+	var speech tts.Provider
+	if opts.format == export.FormatMP3 {
+		// The OpenAI key is only needed, and the policy only applies, with OpenAI speech
+		apiKey, policyErr := resolveOpenAIKey(h.DB, userID, "")
+		speech, err = tts.FromEnv(apiKey)
+		if err != nil && policyErr != nil {
+			http.Error(w, policyErr.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Render into a buffer so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	err = renderExport(&buf, mindMap, opts, exportWatermark(h.DB, userID), speech)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return export as a download
+	w.Header().Set("Content-Type", opts.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename(mindMap.Title, opts.format)))
+	w.Write(buf.Bytes())
+}
+
+// exportOptions are the query options of an export
+type exportOptions struct {
+	format      string
+	contentType string
+	layoutMode  string // Empty for the map's own layout mode
+	order       string
+	walk        string
+	theme       string
+	page        export.PDFPage
+}
+
+// parseExportOptions reads and validates the export options of a request, responding with
+// an error and returning false if one is invalid
+func parseExportOptions(w http.ResponseWriter, r *http.Request) (exportOptions, bool) {
+	query := r.URL.Query()
+	opts := exportOptions{
+		format:     query.Get("format"),
+		layoutMode: query.Get("layout"),
+		order:      query.Get("order"),
+		walk:       query.Get("walk"),
+		theme:      query.Get("theme"),
+		page: export.PDFPage{
+			Paper:       query.Get("paper"),
+			Orientation: query.Get("orientation"),
+		},
+	}
+	if opts.format == "" {
+		opts.format = export.FormatJSON
+	}
+	var ok bool
+	opts.contentType, ok = export.ContentTypes[opts.format]
+	if !ok {
+		http.Error(w, "Format must be one of 'json', 'pptx', 'csv', 'xlsx', 'obsidian', 'svg', 'pdf', 'narration' or 'mp3'", http.StatusBadRequest)
+		return opts, false
+	}
+	if opts.layoutMode != "" && !layout.ValidMode(opts.layoutMode) {
+		http.Error(w, layoutModeError, http.StatusBadRequest)
+		return opts, false
+	}
+	if opts.order == "" {
+		opts.order = outline.OrderPosition
+	}
+	if !outline.ValidOrder(opts.order) {
+		http.Error(w, "Order must be one of 'position', 'created_at', 'content' or 'order'", http.StatusBadRequest)
+		return opts, false
+	}
+	if opts.walk == "" {
+		opts.walk = export.WalkDepth
+	}
+	if !export.ValidWalk(opts.walk) {
+		http.Error(w, "Walk must be 'depth' or 'breadth'", http.StatusBadRequest)
+		return opts, false
+	}
+	if opts.theme == "" {
+		opts.theme = export.ThemeDefault
+	}
+	if !export.ValidTheme(opts.theme) {
+		http.Error(w, "Theme must be one of 'default', 'monochrome', 'high-contrast' or 'no-background'", http.StatusBadRequest)
+		return opts, false
+	}
+	if opts.page.Paper == "" {
+		opts.page.Paper = export.PaperFit
+	}
+	if !export.ValidPaper(opts.page.Paper) {
+		http.Error(w, "Paper must be one of 'fit', 'a4', 'a3' or 'letter'", http.StatusBadRequest)
+		return opts, false
+	}
+	if opts.page.Orientation == "" {
+		opts.page.Orientation = export.OrientationAuto
+	}
+	if !export.ValidOrientation(opts.page.Orientation) {
+		http.Error(w, "Orientation must be 'auto', 'portrait' or 'landscape'", http.StatusBadRequest)
+		return opts, false
+	}
+	if value := query.Get("tile"); value != "" {
+		var err error
+		if opts.page.Tile, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Tile must be true or false", http.StatusBadRequest)
+			return opts, false
+		}
+	}
+	if opts.page.Tile && opts.page.Paper == export.PaperFit {
+		http.Error(w, "Tiling needs a paper size", http.StatusBadRequest)
+		return opts, false
+	}
+	return opts, true
+}
+
+// renderExport renders a mind map in the export format of opts. Drawings carry the
+// watermark, if any; speech reads out mp3 exports
+func renderExport(buf *bytes.Buffer, mindMap *models.MindMapWithDetails, opts exportOptions, watermark string, speech tts.Provider) error {
+	var err error
+	switch opts.format {
+	case export.FormatJSON:
+		err = json.NewEncoder(buf).Encode(export.Document(mindMap))
+	case export.FormatSVG, export.FormatPDF:
+		layoutMode := opts.layoutMode
+		if layoutMode == "" {
+			layoutMode = mindMap.LayoutMode
+		}
+		nodes := mindMap.Nodes
+		if layoutMode != "" {
+			nodes = applyLayout(layoutMode, mindMap.Nodes, mindMap.Edges)
+		}
+		if opts.format == export.FormatSVG {
+			err = export.SVG(buf, mindMap.Title, nodes, mindMap.Edges, watermark, opts.theme)
+		} else {
+			err = export.PDF(buf, mindMap.Title, nodes, mindMap.Edges, watermark, opts.theme, opts.page)
+		}
+	default:
+		var roots []*outline.Item
+		roots, err = outline.Build(mindMap.Nodes, opts.order)
+		if err != nil {
+			break
+		}
+		switch opts.format {
+		case export.FormatPPTX:
+			err = export.PPTX(buf, mindMap.Title, outline.Slides(roots))
+		case export.FormatCSV:
+			err = export.CSV(buf, export.NodeRows(roots))
+		case export.FormatXLSX:
+			err = export.XLSX(buf, mindMap.Title, export.NodeRows(roots))
+		case export.FormatObsidian:
+			err = export.Obsidian(buf, mindMap.Title, roots)
+		case export.FormatNarration:
+			_, err = buf.WriteString(export.Narration(mindMap.Title, roots, opts.walk))
+		case export.FormatMP3:
+			var audio []byte
+			audio, err = tts.Speak(speech, export.Narration(mindMap.Title, roots, opts.walk))
+			buf.Write(audio)
+		}
+	}
+	return err
+}
+
+// defaultExportWatermark is the footer drawn on free-plan image and PDF exports unless
+// EXPORT_WATERMARK overrides it
+const defaultExportWatermark = "Made with IdeaVisualMap"
+
+// exportWatermark returns the footer to draw on a user's image and PDF exports: the
+// configured watermark on the free plan and none on paid plans. Setting EXPORT_WATERMARK
+// to an empty value turns watermarks off
+func exportWatermark(db *database.DB, userID string) string {
+	watermark, ok := os.LookupEnv("EXPORT_WATERMARK")
+	if !ok {
+		watermark = defaultExportWatermark
+	}
+	if watermark == "" {
+		return ""
+	}
+
+	plan, err := db.GetUserPlan(userID)
+	if err != nil {
+		log.Printf("[Export] Error getting plan of user %s, exporting without a watermark: %v", userID, err)
+		return ""
+	}
+	if plan == models.PlanPaid {
+		return ""
+	}
+	return watermark
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Settings of the background generation job workers
+const (
+	defaultGenerationJobWorkers = 2
+	generationJobPollInterval   = 2 * time.Second
+	generationJobTimeout        = 15 * time.Minute   // Longest a job may run before it fails
+	generationJobStaleAfter     = 30 * time.Minute   // When a running job is assumed to be abandoned
+	generationJobMaxAttempts    = 3                  // Runs of a job before it is given up on
+	generationJobRetention      = 7 * 24 * time.Hour // How long finished jobs can be polled
+)
+
+// Limits of the levels a generation job generates
+const (
+	maxGenerationDepth        = 3
+	defaultGenerationChildren = 3
+	maxGenerationChildren     = 5
+)
+
+// AsyncGenerationRequest represents a request to generate ideas in the background. With a
+// depth above 1 every generated idea is expanded into sub-ideas, level by level
+type AsyncGenerationRequest struct {
+	GenerationRequest
+	Depth      int `json:"depth"`       // Levels of ideas to generate, 1 to 3 (default: 1)
+	ChildCount int `json:"child_count"` // Sub-ideas per idea below the first level (default: 3, max: 5)
+}
+
+// AsyncGenerationResponse represents the response to a queued generation job
+type AsyncGenerationResponse struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"` // Where to poll the job
+}
+
+// IdeaNode is a generated idea with the sub-ideas generated for it
+type IdeaNode struct {
+	Idea
+	Children []IdeaNode `json:"children,omitempty"`
+}
+
+// AsyncGenerationResult is the result of a generation job that succeeded
+type AsyncGenerationResult struct {
+	Ideas        []IdeaNode `json:"ideas"`
+	Provider     string     `json:"provider"`                // AI provider that generated the ideas
+	Model        string     `json:"model"`                   // Model that generated the ideas
+	GenerationID string     `json:"generation_id,omitempty"` // ID of the generation in the map's history
+}
+
+// GenerateIdeasAsync handles POST /api/generate/async
+func (h *IdeaGenerationHandler) GenerateIdeasAsync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req AsyncGenerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.validateGenerationRequest(w, r, userID, &req.GenerationRequest); !ok {
+		return
+	}
+
+	// Validate the levels to generate
+	if req.Depth <= 0 {
+		req.Depth = 1
+	}
+	if req.Depth > maxGenerationDepth {
+		http.Error(w, fmt.Sprintf("Depth must be between 1 and %d", maxGenerationDepth), http.StatusBadRequest)
+		return
+	}
+	if req.ChildCount <= 0 {
+		req.ChildCount = defaultGenerationChildren
+	}
+	if req.ChildCount > maxGenerationChildren {
+		req.ChildCount = maxGenerationChildren
+	}
+
+	// Queue the job, keeping the API key out of the stored request
+	apiKey := req.APIKey
+	req.APIKey = ""
+	request, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue generation job: %v", err), http.StatusInternalServerError)
+		return
+	}
+	job, err := h.DB.CreateGenerationJob(userID, req.MindMapID, request, apiKey, generationJobSteps(req.Count, req.ChildCount, req.Depth))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue generation job: %v", err), http.StatusInternalServerError)
+		return
+	}
+	select {
+	case h.jobQueued <- struct{}{}:
+	default:
+	}
+
+	// Return where to poll the job
+	statusURL := "/api/generate/jobs/" + job.ID
+	w.Header().Set("Location", statusURL)
+	sendJSONResponse(w, http.StatusAccepted, AsyncGenerationResponse{
+		JobID:     job.ID,
+		Status:    job.Status,
+		StatusURL: statusURL,
+	})
+}
+
+// GetGenerationJob handles GET /api/generate/jobs/{id}
+func (h *IdeaGenerationHandler) GetGenerationJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract job ID from URL
+	jobID := strings.TrimPrefix(r.URL.Path, "/api/generate/jobs/")
+	if jobID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(jobID); err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the job; other users' jobs are not found
+	job, err := h.DB.GetGenerationJob(userID, jobID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Generation job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get generation job: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return the job
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// StartJobWorker starts the workers that run queued generation jobs, GENERATION_JOB_WORKERS
+// of them (default 2; 0 leaves the jobs to other instances). It also hourly queues jobs
+// abandoned by a stopped instance again and removes finished jobs after a week
+func (h *IdeaGenerationHandler) StartJobWorker() {
+	workers := defaultGenerationJobWorkers
+	if count, err := strconv.Atoi(os.Getenv("GENERATION_JOB_WORKERS")); err == nil && count >= 0 {
+		workers = count
+	}
+	if workers == 0 {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(generationJobPollInterval)
+			defer ticker.Stop()
+			for {
+				h.runGenerationJobs()
+				select {
+				case <-ticker.C:
+				case <-h.jobQueued:
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			h.cleanupGenerationJobs()
+			<-ticker.C
+		}
+	}()
+}
+
+// runGenerationJobs runs queued generation jobs until none are left
+func (h *IdeaGenerationHandler) runGenerationJobs() {
+	for {
+		job, apiKey, err := h.DB.ClaimGenerationJob()
+		if errors.Is(err, database.ErrNotFound) {
+			return
+		}
+		if err != nil {
+			log.Printf("[GenerationJobs] Error claiming job: %v", err)
+			if job != nil {
+				h.finishGenerationJob(job.ID, nil, "The job's API key could not be read")
+				continue
+			}
+			return
+		}
+		h.runGenerationJob(job, apiKey)
+	}
+}
+
+// runGenerationJob generates the ideas of a claimed job and stores the outcome
+func (h *IdeaGenerationHandler) runGenerationJob(job *models.GenerationJob, apiKey string) {
+	var req AsyncGenerationRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		h.finishGenerationJob(job.ID, nil, "The job's request is invalid")
+		return
+	}
+	req.APIKey = apiKey
+	req.UserID = job.UserID
+
+	mindMap, err := h.DB.GetMindMapByID(job.MindMapID)
+	if err != nil {
+		h.finishGenerationJob(job.ID, nil, fmt.Sprintf("Failed to get mind map: %v", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), generationJobTimeout)
+	defer cancel()
+	result, err := h.generateIdeaTree(ctx, job, req, piiRedactor(h.DB, mindMap))
+	if err != nil {
+		h.finishGenerationJob(job.ID, nil, generationJobError(err))
+		return
+	}
+	result.GenerationID = recordGeneration(h.DB, job.UserID, job.MindMapID, job.ID, job.Request, result.Ideas, result.Provider, result.Model)
+	body, err := json.Marshal(result)
+	if err != nil {
+		h.finishGenerationJob(job.ID, nil, fmt.Sprintf("Failed to store ideas: %v", err))
+		return
+	}
+	h.finishGenerationJob(job.ID, body, "")
+}
+
+// generateIdeaTree generates the first level of a job's ideas and then expands every idea
+// level by level, all with the provider and model the first level was generated with.
+// Progress is recorded after each AI call
+func (h *IdeaGenerationHandler) generateIdeaTree(ctx context.Context, job *models.GenerationJob, req AsyncGenerationRequest, redactor *pii.Redactor) (*AsyncGenerationResult, error) {
+	result := &AsyncGenerationResult{}
+	steps := 0
+	generate := func(req GenerationRequest) ([]IdeaNode, error) {
+		ideas, provider, model, usage, err := h.generateIdeas(ctx, req, redactor)
+		recordAIUsage(h.DB, job.UserID, job.MindMapID, aiFeatureGenerate, usage)
+		if err != nil {
+			return nil, err
+		}
+		result.Provider, result.Model = provider, model
+
+		steps++
+		if err := h.DB.UpdateGenerationJobProgress(job.ID, steps); err != nil {
+			log.Printf("[GenerationJobs] Error recording progress of job %s: %v", job.ID, err)
+		}
+
+		nodes := make([]IdeaNode, len(ideas))
+		for i, idea := range ideas {
+			idea.Title = redactor.Restore(idea.Title)
+			idea.Content = redactor.Restore(idea.Content)
+			nodes[i] = IdeaNode{Idea: idea}
+		}
+		return nodes, nil
+	}
+
+	var expand func(nodes []IdeaNode, levels int) error
+	expand = func(nodes []IdeaNode, levels int) error {
+		if levels == 0 {
+			return nil
+		}
+		for i := range nodes {
+			child := req.GenerationRequest
+			child.Type = "expand"
+			child.Topic = nodes[i].Title
+			if child.Topic == "" {
+				child.Topic = nodes[i].Content
+			}
+			child.Context = nodes[i].Content
+			child.Count = req.ChildCount
+			child.Provider, child.Model = result.Provider, result.Model
+
+			children, err := generate(child)
+			if err != nil {
+				return err
+			}
+			nodes[i].Children = children
+			if err := expand(nodes[i].Children, levels-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ideas, err := generate(req.GenerationRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := expand(ideas, req.Depth-1); err != nil {
+		return nil, err
+	}
+	result.Ideas = ideas
+	return result, nil
+}
+
+// finishGenerationJob stores the outcome of a job, logging a failure to do so
+func (h *IdeaGenerationHandler) finishGenerationJob(jobID string, result []byte, errMessage string) {
+	if err := h.DB.FinishGenerationJob(jobID, result, errMessage); err != nil {
+		log.Printf("[GenerationJobs] Error finishing job %s: %v", jobID, err)
+	}
+}
+
+// cleanupGenerationJobs queues abandoned jobs again and removes old finished ones
+func (h *IdeaGenerationHandler) cleanupGenerationJobs() {
+	requeued, err := h.DB.RequeueStaleGenerationJobs(generationJobStaleAfter, generationJobMaxAttempts)
+	if err != nil {
+		log.Printf("[GenerationJobs] Error requeueing stale jobs: %v", err)
+	} else if requeued > 0 {
+		log.Printf("[GenerationJobs] Requeued %d stale jobs", requeued)
+	}
+
+	deleted, err := h.DB.DeleteFinishedGenerationJobs(time.Now().Add(-generationJobRetention))
+	if err != nil {
+		log.Printf("[GenerationJobs] Error deleting finished jobs: %v", err)
+	} else if deleted > 0 {
+		log.Printf("[GenerationJobs] Deleted %d finished jobs", deleted)
+	}
+}
+
+// generationJobSteps returns how many AI calls a job makes at most: one for the first
+// level and one for every idea of each level above the last
+func generationJobSteps(count, childCount, depth int) int {
+	steps, calls := 1, count
+	for level := 2; level <= depth; level++ {
+		steps += calls
+		calls *= childCount
+	}
+	return steps
+}
+
+// generationJobError describes why a job failed the way GenerateIdeas would respond
+func generationJobError(err error) string {
+	var policyErr *aiPolicyError
+	var capErr *aiSpendCapError
+	switch {
+	case errors.As(err, &policyErr), errors.As(err, &capErr):
+		return err.Error()
+	case errors.Is(err, errNoAIKey):
+		return "No API key is available for the AI provider"
+	}
+	_, response := classifyAIError(err)
+	return "Failed to generate ideas: " + response.Error
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Generation history page sizes
+const (
+	defaultGenerationsLimit = 20
+	maxGenerationsLimit     = 100
+)
+
+// GetGenerations handles GET /api/mindmaps/{id}/generations?limit=20&before=timestamp,
+// returning the map's past generations newest first. The next page starts before the
+// created_at of the last generation returned
+func (h *MindMapHandler) GetGenerations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/generations")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse page
+	limit := defaultGenerationsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxGenerationsLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxGenerationsLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	var before time.Time
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		before, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			http.Error(w, "before must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user may generate in the mind map, as the history is for re-running and
+	// re-inserting generations
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get generations, fetching one extra to know whether more remain
+	generations, err := readDB(h.DB, r).GetGenerations(mindMapID, before, limit+1)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get generations: %v", err), http.StatusInternalServerError)
+		return
+	}
+	history := models.GenerationHistory{
+		MindMapID:   mindMapID,
+		Generations: generations,
+	}
+	if len(generations) > limit {
+		history.Generations = generations[:limit]
+		history.HasMore = true
+	}
+
+	// Return generation history
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// recordGeneration stores a generation in its mind map's history and returns its ID. The
+// request must not carry an API key. Failing to store it is logged rather than failing
+// the generation, and returns an empty ID
+func recordGeneration(db *database.DB, userID, mindMapID, jobID string, request, ideas interface{}, provider, model string) string {
+	generation := &models.Generation{
+		MindMapID: mindMapID,
+		UserID:    &userID,
+		Provider:  provider,
+		Model:     model,
+	}
+	if jobID != "" {
+		generation.JobID = &jobID
+	}
+	var err error
+	if generation.Request, err = json.Marshal(request); err == nil {
+		generation.Ideas, err = json.Marshal(ideas)
+	}
+	if err == nil {
+		err = db.CreateGeneration(generation)
+	}
+	if err != nil {
+		log.Printf("[Generations] Error recording generation for map %s: %v", mindMapID, err)
+		return ""
+	}
+	return generation.ID
+}
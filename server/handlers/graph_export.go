@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/pkg/export"
+)
+
+// knowledgeGraphFilename is the download name of the knowledge graph export, without extension
+const knowledgeGraphFilename = "knowledge-graph"
+
+// ExportKnowledgeGraph handles GET /api/graph/export?format=json|graphml, merging every
+// mind map the user can access into one graph with cross-map links resolved
+func (h *MindMapHandler) ExportKnowledgeGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Validate export options
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatJSON
+	}
+	contentType, ok := export.GraphContentTypes[format]
+	if !ok {
+		http.Error(w, "Format must be one of 'json' or 'graphml'", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the user's mind maps and their contents
+	mindMaps, err := h.DB.GetAccessibleMindMaps(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+	mindMapIDs := make([]string, 0, len(mindMaps))
+	for _, mindMap := range mindMaps {
+		mindMapIDs = append(mindMapIDs, mindMap.ID)
+	}
+
+	nodes, err := h.DB.GetNodesByMindMapIDs(mindMapIDs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	edges, err := tenantDB(h.DB, r).GetEdgesByMindMapIDs(mindMapIDs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get edges: %v", err), http.StatusInternalServerError)
+		return
+	}
+	nodeLinks, err := h.DB.GetNodeLinksByMindMapIDs(mindMapIDs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node links: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported as an error response
+	graph := export.KnowledgeGraph(mindMaps, nodes, edges, nodeLinks)
+	var buf bytes.Buffer
+	switch format {
+	case export.FormatJSON:
+		err = json.NewEncoder(&buf).Encode(graph)
+	case export.FormatGraphML:
+		err = export.GraphML(&buf, graph)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export knowledge graph: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return export as a download
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename(knowledgeGraphFilename, format)))
+	w.Write(buf.Bytes())
+}
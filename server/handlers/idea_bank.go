@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"saas-server/pkg/brainstorm"
+	"strconv"
+)
+
+// IdeaBankHandler serves idea starters from the curated idea bank. It makes no AI or
+// database calls, so it works for anonymous visitors and while AI providers are down
+type IdeaBankHandler struct{}
+
+// NewIdeaBankHandler creates a new IdeaBankHandler
+func NewIdeaBankHandler() *IdeaBankHandler {
+	return &IdeaBankHandler{}
+}
+
+// IdeaCategory is a category of the idea bank
+type IdeaCategory struct {
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	Starters int    `json:"starters"` // Number of idea starters in the category
+}
+
+// IdeaStartersResponse is the response of GET /api/ideas/starters
+type IdeaStartersResponse struct {
+	Category IdeaCategory `json:"category"`
+	Ideas    []Idea       `json:"ideas"`
+}
+
+// GetCategories handles GET /api/ideas/categories
+func (h *IdeaBankHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	categories := []IdeaCategory{}
+	for _, category := range brainstorm.Categories() {
+		categories = append(categories, ideaCategory(category))
+	}
+
+	// Return categories
+	sendJSONResponse(w, http.StatusOK, categories)
+}
+
+// GetStarters handles GET /api/ideas/starters. ?category picks the category by slug;
+// otherwise it is chosen from ?topic, falling back to the general category. ?count sets
+// how many starters to return, 5 by default and at most 10
+func (h *IdeaBankHandler) GetStarters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	topic := query.Get("topic")
+	count := 5
+	if value := query.Get("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Count must be a positive number", http.StatusBadRequest)
+			return
+		}
+		count = min(parsed, 10)
+	}
+
+	category := brainstorm.Classify(topic)
+	if slug := query.Get("category"); slug != "" {
+		var ok bool
+		if category, ok = brainstorm.CategoryBySlug(slug); !ok {
+			http.Error(w, "Unknown category", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Return starters
+	sendJSONResponse(w, http.StatusOK, IdeaStartersResponse{
+		Category: ideaCategory(category),
+		Ideas:    suggestedIdeas(category.Pick(topic, count)),
+	})
+}
+
+// ideaCategory describes a category of the idea bank
+func ideaCategory(category brainstorm.Category) IdeaCategory {
+	return IdeaCategory{Slug: category.Slug, Name: category.Name, Starters: len(category.Starters)}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/triage"
+	"strings"
+)
+
+const (
+	// duplicateWordThreshold is the word similarity at which an idea duplicates a node
+	duplicateWordThreshold = 0.6
+	// duplicateEmbeddingThreshold is the cosine similarity of embeddings at which an idea
+	// duplicates a node
+	duplicateEmbeddingThreshold = 0.88
+	// maxDuplicateEmbeddingNodes caps how many nodes of a map are embedded to check ideas
+	// against; larger maps are checked by words only
+	maxDuplicateEmbeddingNodes = 300
+)
+
+// Ways of resolving an idea that duplicates a node
+const (
+	IdeaResolutionCreate = "create" // Create the idea as a node anyway
+	IdeaResolutionSkip   = "skip"   // Leave the idea out
+	IdeaResolutionMerge  = "merge"  // Append the idea to the content of the node it duplicates
+)
+
+// IdeaConflict is an idea that is close to a node already in the map
+type IdeaConflict struct {
+	Index       int     `json:"index"` // Position of the idea in the request
+	Content     string  `json:"content"`
+	NodeID      string  `json:"node_id"`
+	NodeContent string  `json:"node_content"`
+	Similarity  float64 `json:"similarity"`
+	Method      string  `json:"method"` // One of the ClusterMethod values
+}
+
+// IdeaResolution tells CreateNodesFromIdeas what to do with an idea that duplicates a node
+type IdeaResolution struct {
+	Index  int    `json:"index"`
+	Action string `json:"action"`            // One of the IdeaResolution values
+	NodeID string `json:"node_id,omitempty"` // Node to merge into, the one the conflict named by default
+}
+
+// IdeaConflictsResponse is the 409 response of CreateNodesFromIdeas when ideas duplicate
+// nodes and no resolution was given for them
+type IdeaConflictsResponse struct {
+	Conflicts []IdeaConflict `json:"conflicts"`
+}
+
+// findIdeaConflicts compares ideas with the content of a map's nodes and returns those that
+// duplicate a node, each with its closest match. Ideas are compared by the words they share
+// and, with an OpenAI key and a map small enough, by embeddings, and conflict when either
+// measure reaches its threshold
+func (h *IdeaGenerationHandler) findIdeaConflicts(ctx context.Context, mindMap *models.MindMap, userID string, ideas []Idea, nodes []models.Node, redactor *pii.Redactor) []IdeaConflict {
+	var existing []models.Node
+	for _, node := range nodes {
+		switch node.NodeType {
+		case models.NodeTypeSummary, models.NodeTypeTOC, models.NodeTypeGroup:
+			continue
+		}
+		if strings.TrimSpace(node.Content) != "" {
+			existing = append(existing, node)
+		}
+	}
+	conflicts := []IdeaConflict{}
+	if len(existing) == 0 || len(ideas) == 0 {
+		return conflicts
+	}
+
+	embeddings := h.duplicateEmbeddings(ctx, mindMap, userID, ideas, existing, redactor)
+	for i, idea := range ideas {
+		var conflict *IdeaConflict
+		for j, node := range existing {
+			score, method := triage.WordSimilarity(idea.Content, node.Content), models.ClusterMethodWords
+			if score < duplicateWordThreshold {
+				score, method = 0, ""
+			}
+			if embeddings != nil {
+				if cosine := triage.Cosine(embeddings[i], embeddings[len(ideas)+j]); cosine >= duplicateEmbeddingThreshold && cosine > score {
+					score, method = cosine, models.ClusterMethodEmbeddings
+				}
+			}
+			if method == "" || (conflict != nil && score <= conflict.Similarity) {
+				continue
+			}
+			conflict = &IdeaConflict{
+				Index:       i,
+				Content:     idea.Content,
+				NodeID:      node.ID,
+				NodeContent: node.Content,
+				Similarity:  min(score, 1),
+				Method:      method,
+			}
+		}
+		if conflict != nil {
+			conflicts = append(conflicts, *conflict)
+		}
+	}
+	return conflicts
+}
+
+// duplicateEmbeddings embeds the ideas followed by the nodes in one request, or returns nil
+// when there is no OpenAI key, the map's spend cap rules AI out, the map has too many nodes
+// or embedding fails, in which case duplicates are found by words alone
+func (h *IdeaGenerationHandler) duplicateEmbeddings(ctx context.Context, mindMap *models.MindMap, userID string, ideas []Idea, nodes []models.Node, redactor *pii.Redactor) [][]float64 {
+	if len(nodes) > maxDuplicateEmbeddingNodes {
+		return nil
+	}
+	apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+	if err != nil || apiKey == "" {
+		if err != nil {
+			log.Printf("[Duplicates] Checking ideas for map %s by words: %v", mindMap.ID, err)
+		}
+		return nil
+	}
+	if _, err := enforceAISpendCap(h.DB, mindMap.ID, aiProviderOpenAI, openAIEmbeddingModel); err != nil {
+		log.Printf("[Duplicates] Checking ideas for map %s by words: %v", mindMap.ID, err)
+		return nil
+	}
+
+	inputs := make([]string, 0, len(ideas)+len(nodes))
+	for _, idea := range ideas {
+		inputs = append(inputs, redactor.Redact(prompt.Line(idea.Content)))
+	}
+	for _, node := range nodes {
+		inputs = append(inputs, redactor.Redact(prompt.Line(node.Content)))
+	}
+	embeddings, usage, err := openAIEmbeddings(ctx, apiKey, inputs)
+	recordAIUsage(h.DB, userID, mindMap.ID, aiFeatureGenerate, usage)
+	if err != nil || len(embeddings) != len(inputs) {
+		log.Printf("[Duplicates] Embeddings failed for map %s, checking by words: %v", mindMap.ID, err)
+		return nil
+	}
+	return embeddings
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"saas-server/models"
+	"saas-server/pkg/brainstorm"
+	"saas-server/pkg/pii"
+)
+
+// aiProviderLocal and aiModelTemplates name the provider and model of ideas suggested
+// from templates when no AI provider can generate them
+const (
+	aiProviderLocal  = "local"
+	aiModelTemplates = "templates"
+)
+
+// localIdeaConfidence is the confidence of ideas suggested without AI, which fit any topic
+// only loosely
+const localIdeaConfidence = 0.3
+
+// generateIdeas generates ideas with generateIdeasWithAI, falling back to ideas suggested
+// without AI when the AI provider is down, rate limiting or timing out, or when no
+// provider has an API key. Requests ruled out by the AI usage policy or the spend cap, or
+// rejected by the provider, still fail. A request for the local provider is answered
+// without AI directly, so a job that degraded stays local
+func (h *IdeaGenerationHandler) generateIdeas(ctx context.Context, req GenerationRequest, redactor *pii.Redactor) ([]Idea, string, string, aiUsage, error) {
+	if req.Provider == aiProviderLocal {
+		return typedIdeas(req, localIdeas(req)), aiProviderLocal, aiModelTemplates, aiUsage{}, nil
+	}
+
+	ideas, provider, model, usage, err := h.generateIdeasWithAI(ctx, req, redactor)
+	if err == nil || !degradeGeneration(err) {
+		return typedIdeas(req, ideas), provider, model, usage, err
+	}
+	log.Printf("[AI] Suggesting ideas for map %s without AI: %v", req.MindMapID, err)
+	return typedIdeas(req, localIdeas(req)), aiProviderLocal, aiModelTemplates, usage, nil
+}
+
+// typedIdeas sets the node type of the ideas of a critique, so the nodes created from them
+// are critique nodes
+func typedIdeas(req GenerationRequest, ideas []Idea) []Idea {
+	if req.Type == brainstorm.KindCritique {
+		for i := range ideas {
+			ideas[i].NodeType = models.NodeTypeCritique
+		}
+	}
+	return ideas
+}
+
+// degradeGeneration reports whether a failed generation should fall back to templates
+func degradeGeneration(err error) bool {
+	if errors.Is(err, errNoAIKey) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	_, response := classifyAIError(err)
+	return response.Retryable
+}
+
+// localIdeas suggests ideas for a generation request without AI. New ideas mix starters
+// from the idea bank category of the topic with question prompts and SCAMPER stems filled
+// in with it; frameworks use the questions of each of their categories, and other types
+// use the prompts and stems alone
+func localIdeas(req GenerationRequest) []Idea {
+	if framework, ok := brainstorm.FrameworkByKind(req.Type); ok {
+		return frameworkIdeas(framework.Suggest(req.Topic, req.Count))
+	}
+	if req.Type != "" && req.Type != brainstorm.KindNew {
+		return suggestedIdeas(brainstorm.Suggest(req.Topic, req.Type, req.Count))
+	}
+	starters := brainstorm.Classify(req.Topic).Pick(req.Topic, (req.Count+1)/2)
+	stems := brainstorm.Suggest(req.Topic, brainstorm.KindNew, req.Count-len(starters))
+	return suggestedIdeas(append(starters, stems...))
+}
+
+// suggestedIdeas turns suggestions made without AI into ideas
+func suggestedIdeas(suggestions []brainstorm.Suggestion) []Idea {
+	ideas := make([]Idea, len(suggestions))
+	for i, suggestion := range suggestions {
+		ideas[i] = Idea{
+			Title:      suggestion.Title,
+			Content:    suggestion.Content,
+			Confidence: localIdeaConfidence,
+		}
+	}
+	return ideas
+}
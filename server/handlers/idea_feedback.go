@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Idea feedback limits
+const (
+	maxIdeaFeedbackTitleLength   = 255
+	maxIdeaFeedbackContentLength = 2000
+	ideaFeedbackExamples         = 5 // Examples of each verdict sent with a generation
+)
+
+// SaveIdeaFeedback handles POST /api/generate/feedback, recording whether the user
+// accepted or rejected a generated idea. The map's recent feedback steers its next
+// generations; a new verdict on the same idea replaces the user's earlier one
+func (h *IdeaGenerationHandler) SaveIdeaFeedback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.IdeaFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(req.MindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+	if req.GenerationID != "" {
+		if _, err := uuid.Parse(req.GenerationID); err != nil {
+			http.Error(w, "Invalid generation ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Verdict != models.IdeaAccepted && req.Verdict != models.IdeaRejected {
+		http.Error(w, "Verdict must be 'accepted' or 'rejected'", http.StatusBadRequest)
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" {
+		http.Error(w, "Idea content is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Title) > maxIdeaFeedbackTitleLength || len(req.Content) > maxIdeaFeedbackContentLength {
+		http.Error(w, fmt.Sprintf("Idea title and content must be at most %d and %d characters", maxIdeaFeedbackTitleLength, maxIdeaFeedbackContentLength), http.StatusBadRequest)
+		return
+	}
+
+	// Check if user may generate in the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	feedback, err := h.DB.SaveIdeaFeedback(userID, req)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Generation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save feedback: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return saved feedback
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedback)
+}
+
+// ideaFeedbackPrompt lists the map's recently accepted and rejected ideas, redacted, for
+// the delimited input of a generation. Returns "" if the map has no feedback or it cannot
+// be read, as feedback only steers generation
+func ideaFeedbackPrompt(db *database.DB, mindMapID string, redactor *pii.Redactor) string {
+	var b strings.Builder
+	for _, verdict := range []string{models.IdeaAccepted, models.IdeaRejected} {
+		feedback, err := db.GetRecentIdeaFeedback(mindMapID, verdict, ideaFeedbackExamples)
+		if err != nil {
+			log.Printf("[IdeaFeedback] Error getting %s ideas for map %s: %v", verdict, mindMapID, err)
+			return ""
+		}
+		if len(feedback) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nIdeas the user %s:", verdict)
+		for _, item := range feedback {
+			idea := item.Content
+			if item.Title != "" {
+				idea = item.Title + ": " + idea
+			}
+			b.WriteString("\n- " + prompt.Line(redactor.Redact(idea)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"saas-server/models"
+	"saas-server/pkg/brainstorm"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxFrameworkIdeasPerCategory caps the ideas of each category of a framework
+	// generation, whose count is per category
+	maxFrameworkIdeasPerCategory = 3
+	// frameworkMaxTokens is the reply budget of a framework generation, which has ideas
+	// for every category
+	frameworkMaxTokens = 2000
+)
+
+// frameworkMetadata is the metadata of a framework category node
+type frameworkMetadata struct {
+	Framework string `json:"framework"`
+	Category  string `json:"framework_category"`
+}
+
+// frameworkTask is the instruction of a framework generation, listing the categories of
+// the framework with what their ideas are about
+func frameworkTask(framework brainstorm.Framework, count int) string {
+	categories := make([]string, len(framework.Categories))
+	for i, category := range framework.Categories {
+		categories[i] = fmt.Sprintf("- %s (%s): %s", category.Key, category.Name, category.Description)
+	}
+	return fmt.Sprintf("Apply the %s framework to the topic. For each of its categories below, generate %d ideas and give each idea the key of its category.\n%s",
+		framework.Name, count, strings.Join(categories, "\n"))
+}
+
+// frameworkIdeasSchema is the JSON reply a framework generation asks providers for: the
+// ideas of ideasSchema with the key of their category
+func frameworkIdeasSchema(framework brainstorm.Framework) jsonSchema {
+	keys := make([]string, len(framework.Categories))
+	for i, category := range framework.Categories {
+		keys[i] = category.Key
+	}
+	return jsonSchema{
+		name:        "submit_ideas",
+		description: "Submit the generated ideas with their framework categories",
+		schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"ideas": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"category":   map[string]interface{}{"type": "string", "enum": keys, "description": "The key of the framework category of the idea"},
+							"title":      map[string]interface{}{"type": "string", "description": "A short name for the idea"},
+							"content":    map[string]interface{}{"type": "string", "description": "The idea in one or two sentences"},
+							"confidence": map[string]interface{}{"type": "number", "description": "How well the idea fits the topic, from 0 to 1"},
+						},
+						"required":             []string{"category", "title", "content", "confidence"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			"required":             []string{"ideas"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// frameworkHasCategory reports whether key is the key of a category of the framework
+func frameworkHasCategory(framework brainstorm.Framework, key string) bool {
+	for _, category := range framework.Categories {
+		if category.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// frameworkIdeas turns framework suggestions made without AI into ideas
+func frameworkIdeas(suggestions []brainstorm.FrameworkSuggestion) []Idea {
+	ideas := make([]Idea, len(suggestions))
+	for i, suggestion := range suggestions {
+		ideas[i] = Idea{
+			Title:      suggestion.Title,
+			Content:    suggestion.Content,
+			Confidence: localIdeaConfidence,
+			Category:   suggestion.Category,
+		}
+	}
+	return ideas
+}
+
+// hasFrameworkIdeas reports whether any of the ideas has a framework category
+func hasFrameworkIdeas(ideas []Idea) bool {
+	for _, idea := range ideas {
+		if idea.Category != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// frameworkGroup is the ideas of one framework category sent to CreateNodesFromIdeas,
+// with the category node they go under: one the parent already has, or nil for a new one
+type frameworkGroup struct {
+	kind     string
+	category brainstorm.FrameworkCategory
+	node     *models.Node
+	ideas    []Idea
+}
+
+// groupFrameworkIdeas splits ideas into those of a known framework category, grouped by
+// category in the order they first appear, and the rest. Each group gets the category
+// node the parent already has, if any, so running a framework again fills the same
+// categories
+func groupFrameworkIdeas(ideas []Idea, nodes []models.Node, parentID string) ([]Idea, []*frameworkGroup) {
+	plain := make([]Idea, 0, len(ideas))
+	var groups []*frameworkGroup
+	byKey := make(map[string]*frameworkGroup)
+	for _, idea := range ideas {
+		category, kind, ok := brainstorm.FrameworkCategoryByKey(idea.Category)
+		if !ok {
+			plain = append(plain, idea)
+			continue
+		}
+		group, ok := byKey[category.Key]
+		if !ok {
+			group = &frameworkGroup{kind: kind, category: category, node: frameworkCategoryNode(nodes, parentID, category.Key)}
+			byKey[category.Key] = group
+			groups = append(groups, group)
+		}
+		group.ideas = append(group.ideas, idea)
+	}
+	return plain, groups
+}
+
+// frameworkCategoryNode returns the node of a framework category under a parent, or nil
+// if the parent has none
+func frameworkCategoryNode(nodes []models.Node, parentID, key string) *models.Node {
+	for i, node := range nodes {
+		if node.NodeType != models.NodeTypeGroup {
+			continue
+		}
+		if (node.ParentID == nil) != (parentID == "") || (node.ParentID != nil && *node.ParentID != parentID) {
+			continue
+		}
+		var metadata frameworkMetadata
+		if json.Unmarshal(node.Metadata, &metadata) == nil && metadata.Category == key {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// frameworkIdeaNodes lays out the category nodes the groups lack under the parent, at the
+// given positions, and the ideas of each group below its category node, categories first
+func frameworkIdeaNodes(parentID, userID string, groups []*frameworkGroup, existing []models.Node, positions []Position) ([]models.IdeaNode, error) {
+	var nodes []models.IdeaNode
+	placed := append([]models.Node{}, existing...)
+	for _, group := range groups {
+		category := group.node
+		if category == nil {
+			metadata, err := json.Marshal(frameworkMetadata{Framework: group.kind, Category: group.category.Key})
+			if err != nil {
+				return nil, err
+			}
+			node := models.IdeaNode{
+				ID: uuid.New().String(),
+				Node: models.NodeCreateRequest{
+					Content:   group.category.Name,
+					PositionX: positions[0].X,
+					PositionY: positions[0].Y,
+					NodeType:  models.NodeTypeGroup,
+					Metadata:  metadata,
+					CreatedBy: userID,
+				},
+				EdgeType: "default",
+			}
+			positions = positions[1:]
+			if parentID != "" {
+				node.Node.ParentID = &parentID
+			}
+			nodes = append(nodes, node)
+			created := placedNode(node)
+			category = &created
+			placed = append(placed, created)
+		}
+
+		for _, idea := range group.ideas {
+			x, y := nextChildPosition(placed, category)
+			node, err := ideaNode(category.ID, userID, idea, Position{X: x, Y: y})
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+			placed = append(placed, placedNode(node))
+		}
+	}
+	return nodes, nil
+}
+
+// placedNode returns a node yet to be created as far as laying out its siblings and
+// children needs it
+func placedNode(node models.IdeaNode) models.Node {
+	return models.Node{
+		ID:        node.ID,
+		ParentID:  node.Node.ParentID,
+		PositionX: node.Node.PositionX,
+		PositionY: node.Node.PositionY,
+	}
+}
@@ -1,27 +1,39 @@
 package handlers
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
-	"os"
 	"saas-server/database"
 	"saas-server/models"
+	"saas-server/pkg/brainstorm"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/realtime"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 // IdeaGenerationHandler handles AI-powered idea generation requests
 type IdeaGenerationHandler struct {
-	DB *database.DB
+	DB  *database.DB
+	Hub *realtime.Hub
+
+	jobQueued chan struct{} // Wakes a job worker when a generation job is queued
 }
 
 // NewIdeaGenerationHandler creates a new IdeaGenerationHandler
-func NewIdeaGenerationHandler(db *database.DB) *IdeaGenerationHandler {
-	return &IdeaGenerationHandler{DB: db}
+func NewIdeaGenerationHandler(db *database.DB, hub *realtime.Hub) *IdeaGenerationHandler {
+	return &IdeaGenerationHandler{DB: db, Hub: hub, jobQueued: make(chan struct{}, 1)}
 }
 
+// errNoAIKey is returned when no API key is available for the chosen AI provider
+var errNoAIKey = errors.New("no API key provided")
+
 // GenerationRequest represents a request to generate ideas
 type GenerationRequest struct {
 	Topic      string      `json:"topic"`      // The main topic for idea generation
@@ -29,20 +41,55 @@ type GenerationRequest struct {
 	NodeID     string      `json:"node_id"`    // ID of the node to expand (optional)
 	MindMapID  string      `json:"mind_map_id"` // ID of the mind map
 	Count      int         `json:"count"`      // Number of ideas to generate (default: 5)
-	Type       string      `json:"type"`       // Type of generation: "new", "expand", "improve", "branch"
-	APIKey     string      `json:"api_key"`    // User's OpenAI API key (optional)
+	Type       string      `json:"type"`       // Type of generation: "new", "expand", "improve", "branch", "grounded", "critique" or a framework: "swot", "five_whys", "six_hats"
+	Provider   string      `json:"provider"`   // AI provider: "openai", "anthropic", "openrouter" or "ollama" (optional)
+	Model      string      `json:"model"`      // Model of the provider, e.g. "gpt-4o" or "anthropic/claude-3.5-sonnet" (optional)
+	APIKey     string      `json:"api_key"`    // User's API key for the provider (optional)
 	UserID     interface{} `json:"-"`          // User ID (set internally, not from JSON)
 }
 
 // GenerationResponse represents the response from the idea generation
 type GenerationResponse struct {
-	Ideas []Idea `json:"ideas"`
+	Ideas        []Idea `json:"ideas"`
+	Provider     string `json:"provider"`                // AI provider that generated the ideas, or "local" for ideas suggested from templates
+	Model        string `json:"model"`                   // Model that generated the ideas
+	GenerationID string `json:"generation_id,omitempty"` // ID of the generation in the map's history
 }
 
 // Idea represents a generated idea
 type Idea struct {
+	Title      string  `json:"title,omitempty"` // Short name of the idea
 	Content    string  `json:"content"`
-	Confidence float64 `json:"confidence"`
+	Confidence float64 `json:"confidence"` // How well the idea fits the topic, from 0 to 1
+	Citations  []models.Citation `json:"citations,omitempty"` // Parts of the map's documents a grounded idea draws on
+	NodeType   string  `json:"node_type,omitempty"` // Type of the node created from the idea, "critique" for critiques; empty for plain ideas
+	Category   string  `json:"category,omitempty"`  // Key of the framework category of an idea of a framework type
+}
+
+// ideasSchema is the JSON reply idea generation asks providers for
+var ideasSchema = jsonSchema{
+	name:        "submit_ideas",
+	description: "Submit the generated ideas",
+	schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"ideas": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":      map[string]interface{}{"type": "string", "description": "A short name for the idea"},
+						"content":    map[string]interface{}{"type": "string", "description": "The idea in one or two sentences"},
+						"confidence": map[string]interface{}{"type": "number", "description": "How well the idea fits the topic, from 0 to 1"},
+					},
+					"required":             []string{"title", "content", "confidence"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"ideas"},
+		"additionalProperties": false,
+	},
 }
 
 // GenerateIdeas handles POST /api/generate
@@ -65,22 +112,127 @@ func (h *IdeaGenerationHandler) GenerateIdeas(w http.ResponseWriter, r *http.Req
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	mindMap, ok := h.validateGenerationRequest(w, r, userID, &req)
+	if !ok {
+		return
+	}
+
+	// Generate ideas using the chosen AI provider, or templates if it fails, putting back
+	// any redacted personal data
+	redactor := piiRedactor(h.DB, mindMap)
+	ideas, provider, model, usage, err := h.generateIdeas(r.Context(), req, redactor)
+	recordAIUsage(h.DB, userID, req.MindMapID, aiFeatureGenerate, usage)
+	if err != nil {
+		sendGenerationError(w, "Failed to generate ideas", err)
+		return
+	}
+	for i := range ideas {
+		ideas[i].Title = redactor.Restore(ideas[i].Title)
+		ideas[i].Content = redactor.Restore(ideas[i].Content)
+	}
+
+	// Keep the generation in the map's history, without the API key
+	historyReq := req
+	historyReq.APIKey = ""
+	generationID := recordGeneration(h.DB, userID, req.MindMapID, "", historyReq, ideas, provider, model)
+
+	// Return generated ideas
+	response := GenerationResponse{
+		Ideas:        ideas,
+		Provider:     provider,
+		Model:        model,
+		GenerationID: generationID,
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// sendGenerationError responds to a failed generation: requests the AI usage policy or the
+// map's spend cap rules out are refused, and failed AI calls are classified
+func sendGenerationError(w http.ResponseWriter, action string, err error) {
+	var policyErr *aiPolicyError
+	var capErr *aiSpendCapError
+	switch {
+	case errors.As(err, &policyErr):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &capErr):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, errNoAIKey):
+		http.Error(w, "No API key is available for the AI provider", http.StatusBadRequest)
+	default:
+		sendAIError(w, action, err)
+	}
+}
+
+// validateGenerationRequest checks a generation request and the user's access to its mind
+// map, responding with an error and returning false if either fails. Defaults the count
+// and sets the user ID
+func (h *IdeaGenerationHandler) validateGenerationRequest(w http.ResponseWriter, r *http.Request, userID string, req *GenerationRequest) (*models.MindMap, bool) {
 	// Validate request
 	if req.MindMapID == "" {
 		http.Error(w, "Mind map ID is required", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(req.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
-		return
+		return nil, false
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+		return nil, false
+	}
+
+	// Validate provider; an empty one is picked from the available keys
+	if req.Provider != "" && req.Provider != aiProviderOpenAI && req.Provider != aiProviderAnthropic && req.Provider != aiProviderOpenRouter && req.Provider != aiProviderOllama {
+		http.Error(w, "Provider must be 'openai', 'anthropic', 'openrouter' or 'ollama'", http.StatusBadRequest)
+		return nil, false
+	}
+	if req.Model != "" && req.Provider == "" {
+		http.Error(w, "A model can only be chosen together with a provider", http.StatusBadRequest)
+		return nil, false
+	}
+	if req.Model != "" {
+		if err := checkAIModel(req.Provider, req.Model); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	if req.Provider == aiProviderOllama && ollamaBaseURL() == "" {
+		http.Error(w, "Ollama is not configured on this server", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Grounded ideas need a document to be grounded in
+	if req.Type == "grounded" {
+		count, err := h.DB.CountDocuments(req.MindMapID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get documents: %v", err), http.StatusInternalServerError)
+			return nil, false
+		}
+		if count == 0 {
+			http.Error(w, "Upload a document to the mind map to generate grounded ideas", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	// Critiques are of a node of the map, whose content is the topic unless one is given
+	if req.Type == "critique" {
+		if req.NodeID == "" {
+			http.Error(w, "Node ID is required for a critique", http.StatusBadRequest)
+			return nil, false
+		}
+		node, err := tenantDB(h.DB, r).GetNodeByID(req.NodeID)
+		if err != nil || node.MindMapID != req.MindMapID {
+			http.Error(w, "Node not found", http.StatusNotFound)
+			return nil, false
+		}
+		if strings.TrimSpace(req.Topic) == "" {
+			req.Topic = node.Content
+		}
 	}
 
 	// Set default count if not provided
@@ -93,191 +245,175 @@ func (h *IdeaGenerationHandler) GenerateIdeas(w http.ResponseWriter, r *http.Req
 		req.Count = 10
 	}
 
+	// Framework counts are per category
+	if _, ok := brainstorm.FrameworkByKind(req.Type); ok {
+		req.Count = min(req.Count, maxFrameworkIdeasPerCategory)
+	}
+
 	// Set the user ID in the request
 	req.UserID = userID
 
-	// Generate ideas using OpenAI API
-	ideas, err := h.generateIdeasWithOpenAI(req)
+	return mindMap, true
+}
+
+// generationModel is the AI provider, model and API key a generation is made with
+type generationModel struct {
+	provider string
+	model    string
+	apiKey   string
+}
+
+// resolveGenerationModel determines the provider, API key and model a generation for req is
+// made with. The mind map's spend cap may swap the model for a cheaper one or refuse the
+// request with an aiSpendCapError
+func (h *IdeaGenerationHandler) resolveGenerationModel(req GenerationRequest) (generationModel, error) {
+	// Determine which provider and API key to use
+	userID, _ := req.UserID.(string)
+	provider, apiKey, err := resolveAIProvider(h.DB, userID, req.Provider, req.APIKey)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to generate ideas: %v", err), http.StatusInternalServerError)
-		return
+		return generationModel{}, err
 	}
-
-	// Return generated ideas
-	response := GenerationResponse{
-		Ideas: ideas,
+	if apiKey == "" && provider != aiProviderOllama {
+		return generationModel{}, errNoAIKey
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// Keep within the map's spend cap, which may call for a cheaper model
+	model, err := enforceAISpendCap(h.DB, req.MindMapID, provider, aiModel(provider, req.Model))
+	if err != nil {
+		return generationModel{}, err
+	}
+	return generationModel{provider: provider, model: model, apiKey: apiKey}, nil
 }
 
-// generateIdeasWithOpenAI generates ideas using the OpenAI API
-func (h *IdeaGenerationHandler) generateIdeasWithOpenAI(req GenerationRequest) ([]Idea, error) {
-	// Determine which API key to use
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	
-	// If the request specifies to use the user's API key
-	if req.APIKey != "" {
-		// Use the provided API key directly
-		apiKey = req.APIKey
-	} else {
-		// Try to get the user's stored API key for OpenAI
-		userID, ok := req.UserID.(string)
-		if ok && userID != "" {
-			userAPIKey, err := h.DB.GetDecryptedAPIKey(userID, "openai")
-			if err == nil && userAPIKey != "" {
-				apiKey = userAPIKey
-			}
-		}
-	}
+// completeJSON asks the model for a reply that follows schema
+func (m generationModel) completeJSON(ctx context.Context, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+	complete := openAIEndpoint(m.model).completeJSON
+	switch m.provider {
+	case aiProviderAnthropic:
+		complete = anthropicJSON(m.model)
+	case aiProviderOpenRouter:
+		complete = openRouterEndpoint(m.model).completeJSON
+	case aiProviderOllama:
+		complete = ollamaJSON(m.model)
+	}
+	return complete(ctx, m.apiKey, systemPrompt, userPrompt, maxTokens, schema)
+}
 
-	if apiKey == "" {
-		return nil, fmt.Errorf("no API key provided")
+// generateIdeasWithAI generates ideas using the OpenAI chat completions API, the Anthropic
+// Messages API or a local Ollama instance, depending on the provider and keys available.
+// The topic and context are redacted with the given redactor before they are sent, and the
+// call is cancelled along with ctx. Also returns the provider and model used and the usage
+// of the request. The mind map's spend cap may swap the model for a cheaper one or refuse
+// the request with an aiSpendCapError
+func (h *IdeaGenerationHandler) generateIdeasWithAI(ctx context.Context, req GenerationRequest, redactor *pii.Redactor) ([]Idea, string, string, aiUsage, error) {
+	gen, err := h.resolveGenerationModel(req)
+	if err != nil {
+		return nil, "", "", aiUsage{}, err
 	}
 
-	// Construct the prompt based on the request type
-	var prompt string
+	// Construct the request based on the type; the topic and context are user content
+	var task string
 	switch req.Type {
 	case "expand":
-		prompt = fmt.Sprintf("Generate %d detailed sub-ideas that expand on this concept: %s. Context: %s", 
-			req.Count, req.Topic, req.Context)
+		task = fmt.Sprintf("Generate %d detailed sub-ideas that expand on the concept given as the topic.", req.Count)
 	case "improve":
-		prompt = fmt.Sprintf("Improve and refine this idea in %d different ways: %s. Context: %s", 
-			req.Count, req.Topic, req.Context)
+		task = fmt.Sprintf("Improve and refine the idea given as the topic in %d different ways.", req.Count)
 	case "branch":
-		prompt = fmt.Sprintf("Generate %d alternative approaches or directions for this concept: %s. Context: %s", 
-			req.Count, req.Topic, req.Context)
+		task = fmt.Sprintf("Generate %d alternative approaches or directions for the concept given as the topic.", req.Count)
+	case "grounded":
+		task = fmt.Sprintf("Generate %d ideas about the topic that build on the numbered sources, and list the numbers of the sources each idea draws on.", req.Count)
+	case "critique":
+		task = fmt.Sprintf("Play devil's advocate against the idea given as the topic: give %d distinct counterarguments, risks or weaknesses of it, each stated plainly, with your confidence that it applies.", req.Count)
 	default: // "new"
-		prompt = fmt.Sprintf("Generate %d creative ideas about: %s. Context: %s", 
-			req.Count, req.Topic, req.Context)
-	}
-
-	// Prepare the OpenAI API request
-	requestBody, err := json.Marshal(map[string]interface{}{
-		"model": "gpt-3.5-turbo",
-		"messages": []map[string]string{
-			{
-				"role":    "system",
-				"content": "You are a creative brainstorming assistant. Generate concise, innovative ideas for the given topic. Each idea should be clear, actionable, and directly relevant to the topic. Format your response as a JSON array of ideas.",
-			},
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"temperature": 0.7,
-		"max_tokens":  500,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	// Make the API request
-	client := &http.Client{}
-	apiReq, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, err
-	}
-
-	apiReq.Header.Set("Content-Type", "application/json")
-	apiReq.Header.Set("Authorization", "Bearer "+apiKey)
-
-	resp, err := client.Do(apiReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error: %s - %s", resp.Status, string(body))
-	}
-
-	// Parse the response
-	var apiResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, err
-	}
-
-	if len(apiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no ideas generated")
-	}
-
-	// Try to parse the response as JSON
-	content := apiResp.Choices[0].Message.Content
-	var rawIdeas []map[string]interface{}
-	
-	// First, try to parse as a JSON array directly
-	err = json.Unmarshal([]byte(content), &rawIdeas)
-	if err != nil {
-		// If that fails, try to extract JSON from the text
-		start := 0
-		end := len(content)
-		
-		// Look for JSON array start/end
-		startIdx := bytes.Index([]byte(content), []byte("["))
-		endIdx := bytes.LastIndex([]byte(content), []byte("]"))
-		
-		if startIdx >= 0 && endIdx > startIdx {
-			start = startIdx
-			end = endIdx + 1
-			err = json.Unmarshal([]byte(content[start:end]), &rawIdeas)
+		task = fmt.Sprintf("Generate %d creative ideas about the topic.", req.Count)
+	}
+	framework, isFramework := brainstorm.FrameworkByKind(req.Type)
+	maxTokens := 800
+	if isFramework {
+		task = frameworkTask(framework, req.Count)
+		maxTokens = frameworkMaxTokens
+	}
+	input := fmt.Sprintf("Topic: %s\nContext: %s", prompt.Line(redactor.Redact(req.Topic)), prompt.Limit(prompt.Clean(redactor.Redact(req.Context)), prompt.MaxContextLength))
+	// Ground the ideas in the closest chunks of the map's documents
+	var sources []models.DocumentChunk
+	schema := ideasSchema
+	if isFramework {
+		schema = frameworkIdeasSchema(framework)
+	}
+	if req.Type == "grounded" {
+		userID, _ := req.UserID.(string)
+		override := ""
+		if gen.provider == aiProviderOpenAI {
+			override = req.APIKey
 		}
-		
-		// If still failing, create a simple structure from the text
+		sources, err = retrieveDocumentChunks(ctx, h.DB, userID, override, req.MindMapID, req.Topic+"\n"+req.Context, redactor)
 		if err != nil {
-			// Split by newlines and create ideas
-			ideas := make([]Idea, 0, req.Count)
-			lines := bytes.Split([]byte(content), []byte("\n"))
-			
-			for _, line := range lines {
-				trimmed := bytes.TrimSpace(line)
-				if len(trimmed) > 0 {
-					ideas = append(ideas, Idea{
-						Content:    string(trimmed),
-						Confidence: 0.7,
-					})
-				}
-			}
-			
-			return ideas, nil
+			return nil, "", "", aiUsage{}, fmt.Errorf("failed to retrieve sources: %v", err)
 		}
-	}
-	
-	// Convert the raw ideas to our Idea struct
-	ideas := make([]Idea, 0, len(rawIdeas))
-	for _, raw := range rawIdeas {
-		idea := Idea{
-			Content:    fmt.Sprintf("%v", raw["idea"]),
-			Confidence: 0.7,
+		input += "\n" + sourcesPrompt(sources, redactor)
+		schema = groundedIdeasSchema
+	}
+	// Steer the ideas with the map's recent feedback, if any
+	if examples := ideaFeedbackPrompt(h.DB, req.MindMapID, redactor); examples != "" {
+		task += " Favor ideas like the ones the user accepted and avoid ideas like the ones they rejected, without repeating any of them."
+		input += "\n" + examples
+	}
+	message := task + "\n\n" + prompt.Delimit(input)
+
+	// Make the API request, asking for a reply that follows ideasSchema
+	content, usage, err := gen.completeJSON(
+		ctx,
+		prompt.System("You are a creative brainstorming assistant. Generate concise, innovative ideas for the given topic. Each idea should be clear, actionable, and directly relevant to the topic. Give each idea a short title, the idea itself as its content, and your confidence from 0 to 1 that it fits the topic."),
+		message,
+		maxTokens,
+		schema,
+	)
+	if err != nil {
+		return nil, "", "", usage, err
+	}
+
+	// Parse the reply into ideas, skipping empty ones and citing the sources they name
+	var reply struct {
+		Ideas []struct {
+			Idea
+			Sources []int `json:"sources"`
+		} `json:"ideas"`
+	}
+	if err := json.Unmarshal([]byte(content), &reply); err != nil {
+		return nil, "", "", usage, fmt.Errorf("invalid ideas reply: %v", err)
+	}
+	ideas := make([]Idea, 0, len(reply.Ideas))
+	for _, item := range reply.Ideas {
+		idea := item.Idea
+		idea.Citations = citations(item.Sources, sources)
+		idea.Title = strings.TrimSpace(idea.Title)
+		idea.Content = strings.TrimSpace(idea.Content)
+		if idea.Content == "" {
+			continue
 		}
-		
-		// Try to get the content from different possible fields
-		if idea.Content == "<nil>" {
-			if content, ok := raw["content"].(string); ok {
-				idea.Content = content
-			} else if text, ok := raw["text"].(string); ok {
-				idea.Content = text
-			} else if description, ok := raw["description"].(string); ok {
-				idea.Content = description
-			}
+		// Keep the categories of framework ideas only, and only the framework's
+		if !isFramework {
+			idea.Category = ""
+		} else if !frameworkHasCategory(framework, idea.Category) {
+			continue
 		}
-		
+		idea.Confidence = max(0, min(idea.Confidence, 1))
 		ideas = append(ideas, idea)
 	}
-	
-	return ideas, nil
+	if len(ideas) == 0 {
+		return nil, "", "", usage, fmt.Errorf("no ideas generated")
+	}
+
+	return ideas, gen.provider, gen.model, usage, nil
 }
 
-// CreateNodesFromIdeas handles POST /api/generate/nodes
+// CreateNodesFromIdeas handles POST /api/generate/nodes. With check_duplicates set, the
+// ideas are first compared with the map's nodes, and if any duplicates a node without a
+// resolution for it, nothing is created and the conflicts are returned with 409 so the
+// client can resend the ideas with resolutions that skip them, merge them into the nodes
+// they duplicate or create them anyway. Ideas of a framework generation are filed under a
+// node for each of their categories, reusing the parent's category nodes from an earlier
+// run
 func (h *IdeaGenerationHandler) CreateNodesFromIdeas(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -298,7 +434,9 @@ func (h *IdeaGenerationHandler) CreateNodesFromIdeas(w http.ResponseWriter, r *h
 		Ideas     []Idea `json:"ideas"`
 		StartX    float64 `json:"start_x"`
 		StartY    float64 `json:"start_y"`
-		Layout    string `json:"layout"` // "radial", "vertical", "horizontal"
+		Layout    string `json:"layout"` // "radial", "vertical", "horizontal", "split"
+		CheckDuplicates bool `json:"check_duplicates"`
+		Resolutions []IdeaResolution `json:"resolutions"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -312,79 +450,195 @@ func (h *IdeaGenerationHandler) CreateNodesFromIdeas(w http.ResponseWriter, r *h
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(req.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Create nodes for each idea
-	nodes := make([]models.Node, 0, len(req.Ideas))
-	edges := make([]models.Edge, 0, len(req.Ideas))
-
-	// Calculate positions based on layout
-	positions := h.calculateNodePositions(req.StartX, req.StartY, len(req.Ideas), req.Layout)
-
-	// Create nodes and edges
-	for i, idea := range req.Ideas {
-		// Create node
-		nodeReq := models.NodeCreateRequest{
-			MindMapID: req.MindMapID,
-			Content:   idea.Content,
-			PositionX: positions[i].X,
-			PositionY: positions[i].Y,
-			NodeType:  "idea",
+	// Validate resolutions
+	resolutions := make(map[int]IdeaResolution, len(req.Resolutions))
+	for _, resolution := range req.Resolutions {
+		if resolution.Index < 0 || resolution.Index >= len(req.Ideas) {
+			http.Error(w, "Resolution index out of range", http.StatusBadRequest)
+			return
 		}
-
-		// Set parent ID if provided
-		if req.ParentID != "" {
-			nodeReq.ParentID = &req.ParentID
+		if resolution.Action != IdeaResolutionCreate && resolution.Action != IdeaResolutionSkip && resolution.Action != IdeaResolutionMerge {
+			http.Error(w, "Action must be 'create', 'skip' or 'merge'", http.StatusBadRequest)
+			return
 		}
+		resolutions[resolution.Index] = resolution
+	}
 
-		node, err := h.DB.CreateNode(nodeReq)
+	// Check the ideas against the map's nodes, returning unresolved duplicates. The nodes
+	// also hold the category nodes framework ideas can be filed under
+	var existing []models.Node
+	if req.CheckDuplicates || len(resolutions) > 0 || hasFrameworkIdeas(req.Ideas) {
+		existing, err = h.DB.GetNodesByMindMapID(req.MindMapID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to create node: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
 			return
 		}
-
-		nodes = append(nodes, *node)
-
-		// Create edge if there's a parent
-		if req.ParentID != "" {
-			edgeReq := models.EdgeCreateRequest{
-				MindMapID: req.MindMapID,
-				SourceID:  req.ParentID,
-				TargetID:  node.ID,
-				EdgeType:  "idea",
+	}
+	conflicts := make(map[int]IdeaConflict)
+	if req.CheckDuplicates {
+		unresolved := []IdeaConflict{}
+		for _, conflict := range h.findIdeaConflicts(r.Context(), mindMap, userID, req.Ideas, existing, piiRedactor(h.DB, mindMap)) {
+			conflicts[conflict.Index] = conflict
+			if _, ok := resolutions[conflict.Index]; !ok {
+				unresolved = append(unresolved, conflict)
 			}
+		}
+		if len(unresolved) > 0 {
+			sendJSONResponse(w, http.StatusConflict, IdeaConflictsResponse{Conflicts: unresolved})
+			return
+		}
+	}
 
-			edge, err := h.DB.CreateEdge(edgeReq)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to create edge: %v", err), http.StatusInternalServerError)
+	// Set aside the ideas to skip or merge, checking the nodes they merge into
+	ideas := make([]Idea, 0, len(req.Ideas))
+	skipped := []int{}
+	merges := make(map[string][]string)
+	var mergeOrder []string
+	for i, idea := range req.Ideas {
+		resolution, ok := resolutions[i]
+		switch {
+		case !ok || resolution.Action == IdeaResolutionCreate:
+			ideas = append(ideas, idea)
+		case resolution.Action == IdeaResolutionSkip:
+			skipped = append(skipped, i)
+		default:
+			nodeID := resolution.NodeID
+			if nodeID == "" {
+				nodeID = conflicts[i].NodeID
+			}
+			if nodeID == "" {
+				http.Error(w, fmt.Sprintf("Node ID is required to merge idea %d", i), http.StatusBadRequest)
 				return
 			}
+			found := false
+			for _, node := range existing {
+				if node.ID == nodeID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				http.Error(w, fmt.Sprintf("Node to merge idea %d into not found", i), http.StatusNotFound)
+				return
+			}
+			if _, ok := merges[nodeID]; !ok {
+				mergeOrder = append(mergeOrder, nodeID)
+			}
+			merges[nodeID] = append(merges[nodeID], idea.Content)
+		}
+	}
+
+	// Merge ideas into the nodes they duplicate, appending them as paragraphs
+	ideaMerges := make([]models.IdeaMerge, 0, len(mergeOrder))
+	for _, nodeID := range mergeOrder {
+		var content string
+		for _, node := range existing {
+			if node.ID == nodeID {
+				content = node.Content
+				break
+			}
+		}
+		ideaMerges = append(ideaMerges, models.IdeaMerge{
+			NodeID:  nodeID,
+			Content: strings.Join(append([]string{content}, merges[nodeID]...), "\n\n"),
+		})
+	}
+
+	// Set aside framework ideas, which go under the categories of their framework
+	ideas, groups := groupFrameworkIdeas(ideas, existing, req.ParentID)
+	newCategories := 0
+	for _, group := range groups {
+		if group.node == nil {
+			newCategories++
+		}
+	}
+
+	// Calculate positions based on layout, making room for new category nodes
+	positions := h.calculateNodePositions(req.StartX, req.StartY, len(ideas)+newCategories, req.Layout)
 
-			edges = append(edges, *edge)
+	// Lay out a node for each idea, then the framework ideas under their category nodes
+	batch := make([]models.IdeaNode, 0, len(ideas))
+	for i, idea := range ideas {
+		node, err := ideaNode(req.ParentID, userID, idea, positions[i])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to create nodes: %v", err), http.StatusInternalServerError)
+			return
 		}
+		batch = append(batch, node)
+	}
+	if len(groups) > 0 {
+		frameworkNodes, err := frameworkIdeaNodes(req.ParentID, userID, groups, existing, positions[len(ideas):])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to create nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		batch = append(batch, frameworkNodes...)
+	}
+
+	// Apply the merges and create the nodes together, so a failure changes nothing
+	merged, nodes, edges, err := h.DB.ApplyIdeas(req.MindMapID, ideaMerges, batch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create nodes: %v", err), http.StatusInternalServerError)
+		return
 	}
 
 	// Return created nodes and edges
 	response := struct {
-		Nodes []models.Node `json:"nodes"`
-		Edges []models.Edge `json:"edges"`
+		Nodes   []models.Node `json:"nodes"`
+		Edges   []models.Edge `json:"edges"`
+		Merged  []models.Node `json:"merged"`  // Nodes ideas were merged into
+		Skipped []int         `json:"skipped"` // Positions of the ideas left out
 	}{
-		Nodes: nodes,
-		Edges: edges,
+		Nodes:   nodes,
+		Edges:   edges,
+		Merged:  merged,
+		Skipped: skipped,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// ideaNode lays out the node of an idea at a position, with an edge from its parent when
+// it has one. Grounded ideas keep the sources they cite in their metadata
+func ideaNode(parentID, userID string, idea Idea, position Position) (models.IdeaNode, error) {
+	node := models.IdeaNode{
+		ID: uuid.New().String(),
+		Node: models.NodeCreateRequest{
+			Content:   idea.Content,
+			PositionX: position.X,
+			PositionY: position.Y,
+			NodeType:  "idea",
+			CreatedBy: userID,
+		},
+		EdgeType: "idea",
+	}
+	if idea.NodeType == models.NodeTypeCritique {
+		node.Node.NodeType = models.NodeTypeCritique
+	}
+	if len(idea.Citations) > 0 {
+		metadata, err := json.Marshal(map[string]interface{}{"citations": idea.Citations})
+		if err != nil {
+			return models.IdeaNode{}, err
+		}
+		node.Node.Metadata = metadata
+	}
+	if parentID != "" {
+		node.Node.ParentID = &parentID
+	}
+	return node, nil
+}
+
 // Position represents a 2D position
 type Position struct {
 	X float64
@@ -429,6 +683,21 @@ func (h *IdeaGenerationHandler) calculateNodePositions(startX, startY float64, c
 				Y: startY + float64(i-count/2) * verticalSpacing,
 			}
 		}
+	case "split":
+		// Alternate nodes between the right and the left of the start position,
+		// stacking each side vertically around it
+		for i := 0; i < count; i++ {
+			side := 1.0
+			if i%2 == 1 {
+				side = -1.0
+			}
+			sideCount := (count + 1 - i%2) / 2
+			row := i / 2
+			positions[i] = Position{
+				X: startX + side * horizontalSpacing,
+				Y: startY + (float64(row) - float64(sideCount-1)/2) * verticalSpacing,
+			}
+		}
 	default:
 		// Default to grid layout
 		cols := int(math.Ceil(math.Sqrt(float64(count))))
@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/inbound"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/realtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxInboundBytes caps the size of an incoming message
+	maxInboundBytes = 1 << 20
+	// maxAllowedSenders caps how many senders an inbound address may admit
+	maxAllowedSenders = 20
+	// inboundSignatureMaxAge is how old a signed inbound email may be before it is refused
+	inboundSignatureMaxAge = 5 * time.Minute
+)
+
+// Placement of nodes created from a message
+const (
+	inboundLevelGap = 250
+	inboundRowGap   = 70
+	inboundGroupGap = 150
+)
+
+// InboundHandler creates maps and nodes from incoming emails and webhook payloads
+type InboundHandler struct {
+	DB  *database.DB
+	Hub *realtime.Hub
+}
+
+// NewInboundHandler creates a new InboundHandler
+func NewInboundHandler(db *database.DB, hub *realtime.Hub) *InboundHandler {
+	return &InboundHandler{DB: db, Hub: hub}
+}
+
+// GetInboundAddress handles GET /api/inbound
+func (h *InboundHandler) GetInboundAddress(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	address, err := h.DB.GetInboundAddress(userID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Inbound address not set up", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbound address: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return inbound address
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(describeInboundAddress(address))
+}
+
+// SaveInboundAddress handles PUT /api/inbound, setting up the user's inbound address or
+// replacing its settings
+func (h *InboundHandler) SaveInboundAddress(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.InboundAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate settings
+	if req.ParseMode == "" {
+		req.ParseMode = models.InboundParseLines
+	}
+	if !inbound.ValidParseMode(req.ParseMode) {
+		http.Error(w, "Parse mode must be 'lines' or 'single'", http.StatusBadRequest)
+		return
+	}
+	if len(req.AllowedSenders) > maxAllowedSenders {
+		http.Error(w, fmt.Sprintf("At most %d allowed senders are supported", maxAllowedSenders), http.StatusBadRequest)
+		return
+	}
+	for i, sender := range req.AllowedSenders {
+		sender = strings.ToLower(strings.TrimSpace(sender))
+		if !strings.Contains(sender, "@") {
+			http.Error(w, fmt.Sprintf("Allowed sender %q must be an email address or @domain", sender), http.StatusBadRequest)
+			return
+		}
+		req.AllowedSenders[i] = sender
+	}
+	if req.AllowedSenders == nil {
+		req.AllowedSenders = []string{}
+	}
+	if req.MindMapID != nil && *req.MindMapID == "" {
+		req.MindMapID = nil
+	}
+	if req.MindMapID != nil {
+		if _, ok := h.editableMindMap(w, *req.MindMapID, userID); !ok {
+			return
+		}
+	}
+
+	token, err := newInboundToken()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	address, err := h.DB.SaveInboundAddress(userID, token, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save inbound address: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return inbound address
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(describeInboundAddress(address))
+}
+
+// DeleteInboundAddress handles DELETE /api/inbound, turning the inbound address off
+func (h *InboundHandler) DeleteInboundAddress(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.DB.DeleteInboundAddress(userID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Inbound address not set up", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete inbound address: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Inbound address deleted successfully"})
+}
+
+// RotateInboundToken handles POST /api/inbound/rotate, replacing the secret token so the
+// old address and webhook URL stop working
+func (h *InboundHandler) RotateInboundToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := newInboundToken()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	address, err := h.DB.RotateInboundToken(userID, token)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Inbound address not set up", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to rotate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return inbound address
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(describeInboundAddress(address))
+}
+
+// ReceiveWebhook handles POST /api/inbound/hooks/{token}. The secret token authenticates
+// the payload; when it names a sender, that sender must be allowed as for emails
+func (h *InboundHandler) ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract token from URL
+	token := strings.TrimPrefix(r.URL.Path, "/api/inbound/hooks/")
+	if token == r.URL.Path || token == "" || strings.Contains(token, "/") {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var msg models.InboundMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInboundBytes)).Decode(&msg); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	address, ok := h.inboundAddress(w, token)
+	if !ok {
+		return
+	}
+	h.deliver(w, address, msg, msg.From != "")
+}
+
+// ReceiveEmail handles POST /api/inbound/email, the inbound route of the email provider.
+// The form carries the recipient, sender, subject and plain text body, signed Mailgun
+// style with INBOUND_EMAIL_SIGNING_KEY. The recipient's local part holds the token,
+// optionally after a plus sign, and the sender must be allowed
+func (h *InboundHandler) ReceiveEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	signingKey := os.Getenv("INBOUND_EMAIL_SIGNING_KEY")
+	if signingKey == "" {
+		http.Error(w, "Inbound email is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Parse the form, which providers send either URL encoded or as multipart
+	r.Body = http.MaxBytesReader(w, r.Body, maxInboundBytes)
+	if err := r.ParseMultipartForm(maxInboundBytes); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	// Verify the provider's signature and refuse stale or replayed deliveries
+	timestamp := r.FormValue("timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || math.Abs(time.Since(time.Unix(seconds, 0)).Seconds()) > inboundSignatureMaxAge.Seconds() {
+		http.Error(w, "Invalid timestamp", http.StatusForbidden)
+		return
+	}
+	deliveryToken := r.FormValue("token")
+	if deliveryToken == "" || !validateWebhookSignature([]byte(timestamp+deliveryToken), r.FormValue("signature"), signingKey) {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+
+	// The timestamp may be off either way, so a signature is accepted for twice the max age
+	fresh, err := h.DB.RecordInboundDelivery(deliveryToken, 2*inboundSignatureMaxAge)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record delivery: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !fresh {
+		http.Error(w, "Delivery was already received", http.StatusConflict)
+		return
+	}
+
+	// The token is the local part of the recipient, after any plus sign
+	recipient := strings.ToLower(strings.TrimSpace(r.FormValue("recipient")))
+	local, _, found := strings.Cut(recipient, "@")
+	if !found {
+		http.Error(w, "Invalid recipient", http.StatusBadRequest)
+		return
+	}
+	if i := strings.LastIndex(local, "+"); i >= 0 {
+		local = local[i+1:]
+	}
+
+	address, ok := h.inboundAddress(w, local)
+	if !ok {
+		return
+	}
+
+	msg := models.InboundMessage{
+		From:    firstFormValue(r, "from", "sender"),
+		Subject: r.FormValue("subject"),
+		Text:    firstFormValue(r, "body-plain", "text", "stripped-text"),
+	}
+	h.deliver(w, address, msg, true)
+}
+
+// inboundAddress looks up the inbound address with the given token, writing an error
+// response and returning false if there is none
+func (h *InboundHandler) inboundAddress(w http.ResponseWriter, token string) (*models.InboundAddress, bool) {
+	address, err := h.DB.GetInboundAddressByToken(token)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Unknown inbound address", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbound address: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return address, true
+}
+
+// deliver verifies the sender of a message when required, parses it and creates its nodes
+// in the target map, or in a new map when there is none
+func (h *InboundHandler) deliver(w http.ResponseWriter, address *models.InboundAddress, msg models.InboundMessage, verifySender bool) {
+	if verifySender {
+		user, err := h.DB.GetUserByID(address.UserID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get user: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !inbound.SenderAllowed(msg.From, user.Email, address.AllowedSenders) {
+			http.Error(w, "Sender is not allowed to send to this address", http.StatusForbidden)
+			return
+		}
+	}
+
+	items := inbound.Parse(msg.Text, address.ParseMode)
+	if len(items) == 0 && strings.TrimSpace(msg.Subject) == "" {
+		http.Error(w, "Message has no content", http.StatusBadRequest)
+		return
+	}
+	title := inbound.Title(msg.Subject, time.Now())
+
+	// Find the target map: the one named by the message, else the address's default
+	targetID := msg.MindMapID
+	if targetID == "" && address.MindMapID != nil {
+		targetID = *address.MindMapID
+	}
+	result := models.InboundResult{MindMapID: targetID}
+	var existing []models.Node
+	if targetID != "" {
+		if _, ok := h.editableMindMap(w, targetID, address.UserID); !ok {
+			return
+		}
+		var err error
+		existing, err = h.DB.GetNodesByMindMapID(targetID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		mindMap, err := h.DB.CreateMindMap(address.UserID, models.MindMapCreateRequest{
+			Title:       title,
+			Description: "Created from an inbound message",
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to create mind map: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.MindMapID, result.Created = mindMap.ID, true
+	}
+
+	rows := inboundRows(existing, title, items)
+	if err := h.DB.ImportNodes(result.MindMapID, address.UserID, rows); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	result.NodeIDs = make([]string, 0, len(rows))
+	for _, row := range rows {
+		result.NodeIDs = append(result.NodeIDs, row.NodeID)
+	}
+
+	// Return created nodes
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// inboundRows turns a parsed message into import rows. The title becomes a node holding
+// the message's items: the root of a new or empty map, or otherwise a branch of the first
+// root placed below everything already on the canvas
+func inboundRows(existing []models.Node, title string, items []inbound.Item) []models.NodeImportRow {
+	group := models.NodeImportRow{Row: 1, Content: title, NodeType: "root"}
+	if roots, err := outline.Build(existing, outline.OrderPosition); err == nil && len(roots) > 0 {
+		root := roots[0].Node
+		bottom := math.Inf(-1)
+		for _, node := range existing {
+			bottom = math.Max(bottom, node.PositionY)
+		}
+		group.ParentID = &root.ID
+		group.NodeType = "idea"
+		group.PositionX = root.PositionX + inboundLevelGap
+		group.PositionY = bottom + inboundGroupGap
+	}
+
+	rows := make([]models.NodeImportRow, 0, len(items)+1)
+	rows = append(rows, group)
+
+	// Each item hangs from the closest row above it that is one level shallower
+	parents := []int{group.Row}
+	for i, item := range items {
+		row := models.NodeImportRow{
+			Row:       i + 2,
+			Content:   item.Content,
+			NodeType:  "idea",
+			PositionX: group.PositionX + float64(item.Depth+1)*inboundLevelGap,
+			PositionY: group.PositionY + float64(i)*inboundRowGap,
+		}
+		parents = append(parents[:item.Depth+1], row.Row)
+		row.ParentRow = parents[item.Depth]
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// editableMindMap checks that a mind map exists and the user can edit it, writing an
+// error response and returning false otherwise
+func (h *InboundHandler) editableMindMap(w http.ResponseWriter, mindMapID, userID string) (*models.MindMap, bool) {
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return nil, false
+	}
+	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, "Mind map not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return mindMap, true
+}
+
+// describeInboundAddress fills in the email address and webhook path of an inbound address
+func describeInboundAddress(address *models.InboundAddress) *models.InboundAddress {
+	if domain := os.Getenv("INBOUND_EMAIL_DOMAIN"); domain != "" {
+		address.Email = address.Token + "@" + domain
+	}
+	address.WebhookPath = "/api/inbound/hooks/" + address.Token
+	return address
+}
+
+// newInboundToken generates a random token that is safe as an email local part
+func newInboundToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// firstFormValue returns the first non-empty form value among the given keys
+func firstFormValue(r *http.Request, keys ...string) string {
+	for _, key := range keys {
+		if value := r.FormValue(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/realtime"
+
+	"github.com/google/uuid"
+)
+
+// maxInboxMoveNodes caps how many nodes a single move may name
+const maxInboxMoveNodes = 500
+
+// InboxHandler serves each user's inbox, the default map quick captures go to
+type InboxHandler struct {
+	DB  *database.DB
+	Hub *realtime.Hub
+}
+
+// NewInboxHandler creates a new InboxHandler
+func NewInboxHandler(db *database.DB, hub *realtime.Hub) *InboxHandler {
+	return &InboxHandler{DB: db, Hub: hub}
+}
+
+// GetInbox handles GET /api/inbox, returning the user's inbox with its nodes and edges and
+// creating it the first time
+func (h *InboxHandler) GetInbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	inbox, _, err := h.DB.GetOrCreateInbox(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbox: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Read from the primary since the inbox may have just been created
+	mindMap, err := tenantDB(h.DB, r).GetMindMapWithDetails(inbox.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbox: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return inbox
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mindMap)
+}
+
+// MoveInboxNodes handles POST /api/inbox/move, filing inbox nodes and their subtrees into
+// another map the user can edit
+func (h *InboxHandler) MoveInboxNodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.InboxMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if len(req.NodeIDs) == 0 || len(req.NodeIDs) > maxInboxMoveNodes {
+		http.Error(w, fmt.Sprintf("node_ids must list between 1 and %d nodes", maxInboxMoveNodes), http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.NodeIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			http.Error(w, "Invalid node ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := uuid.Parse(req.MindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	inbox, _, err := h.DB.GetOrCreateInbox(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbox: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if req.MindMapID == inbox.ID {
+		http.Error(w, "Nodes are already in the inbox", http.StatusBadRequest)
+		return
+	}
+
+	// Check if user can edit the target mind map
+	target, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, target, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	inboxNodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(inbox.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbox nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	targetNodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(target.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Find the parent in the target map
+	var parent *models.Node
+	if req.ParentID != nil {
+		for i := range targetNodes {
+			if targetNodes[i].ID == *req.ParentID {
+				parent = &targetNodes[i]
+			}
+		}
+		if parent == nil {
+			http.Error(w, "Parent node not found in the target mind map", http.StatusBadRequest)
+			return
+		}
+	} else if roots, err := outline.Build(targetNodes, outline.OrderPosition); err == nil && len(roots) > 0 {
+		parent = &roots[0].Node
+	}
+
+	branches := selectedBranches(inboxNodes, req.NodeIDs)
+	if len(branches) == 0 {
+		http.Error(w, "None of the nodes are in the inbox", http.StatusBadRequest)
+		return
+	}
+
+	moved, err := h.moveBranches(inbox, target, parent, targetNodes, branches)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to move nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return moved nodes
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.InboxMoveResult{
+		InboxID:   inbox.ID,
+		MindMapID: target.ID,
+		NodeIDs:   moved,
+	})
+}
+
+// moveBranches moves inbox branches under parent in the target map, or makes them roots
+// when parent is nil, and notifies both maps. The branches are stacked below the parent's
+// other children, each keeping its shape. Returns the IDs of every moved node
+func (h *InboxHandler) moveBranches(inbox, target *models.MindMap, parent *models.Node, targetNodes []models.Node, branches []*outline.Item) ([]string, error) {
+	x, y := nextChildPosition(targetNodes, parent)
+	nodeIDs := make([]string, 0, len(branches))
+	var positions []models.NodePositionUpdateRequest
+	for _, branch := range branches {
+		nodeIDs = append(nodeIDs, branch.Node.ID)
+		dx, dy := x-branch.Node.PositionX, y-branch.Node.PositionY
+		bottom := y
+		for _, item := range outline.Flatten([]*outline.Item{branch}) {
+			position := models.NodePositionUpdateRequest{
+				ID:        item.Node.ID,
+				PositionX: item.Node.PositionX + dx,
+				PositionY: item.Node.PositionY + dy,
+			}
+			positions = append(positions, position)
+			bottom = max(bottom, position.PositionY)
+		}
+		y = bottom + captureRowGap
+	}
+
+	var parentID *string
+	if parent != nil {
+		parentID = &parent.ID
+	}
+	return h.DB.MoveNodesToMindMap(inbox.ID, target.ID, nodeIDs, parentID, positions)
+}
+
+// selectedBranches returns the outline items of the selected nodes, leaving out nodes whose
+// ancestor is selected too since they move along with it
+func selectedBranches(nodes []models.Node, nodeIDs []string) []*outline.Item {
+	selected := make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		selected[id] = true
+	}
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil {
+		return nil
+	}
+
+	var branches []*outline.Item
+	var walk func(items []*outline.Item)
+	walk = func(items []*outline.Item) {
+		for _, item := range items {
+			if selected[item.Node.ID] {
+				branches = append(branches, item)
+				continue
+			}
+			walk(item.Children)
+		}
+	}
+	walk(roots)
+	return branches
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/triage"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultTriageThreshold is the confidence below which a suggestion needs confirming
+	defaultTriageThreshold = 0.8
+	// maxTriageNodes caps how many inbox nodes one triage request handles
+	maxTriageNodes = 50
+	// maxTriageMaps caps how many of the user's most recently updated maps are considered
+	maxTriageMaps = 30
+	// maxTriageCandidates caps how many branches are embedded and ranked
+	maxTriageCandidates = 300
+	// triageShortlist is how many of the closest branches the model chooses between
+	triageShortlist = 5
+)
+
+// triageChoice is the branch picked for an inbox node; candidate is -1 when none fits
+type triageChoice struct {
+	candidate  int
+	confidence float64
+	reason     string
+}
+
+// TriageInbox handles POST /api/inbox/triage. Each inbox node is compared with the roots and
+// top-level branches of the user's other maps, using embeddings to shortlist branches and
+// the model to choose between them. Without an API key the branches are ranked by shared
+// words. Suggestions at or above the threshold are moved when apply is set; the rest are
+// returned for the user to confirm
+func (h *InboxHandler) TriageInbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.InboxTriageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	threshold := defaultTriageThreshold
+	if req.Threshold != nil {
+		if *req.Threshold < 0 || *req.Threshold > 1 {
+			http.Error(w, "threshold must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		threshold = *req.Threshold
+	}
+	if len(req.NodeIDs) > maxTriageNodes {
+		http.Error(w, fmt.Sprintf("Cannot triage more than %d nodes at once", maxTriageNodes), http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.NodeIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			http.Error(w, "Invalid node ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	inbox, _, err := h.DB.GetOrCreateInbox(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbox: %v", err), http.StatusInternalServerError)
+		return
+	}
+	inboxNodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(inbox.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inbox nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Triage the named nodes, or every capture filed under the inbox root
+	var items []*outline.Item
+	if len(req.NodeIDs) > 0 {
+		items = selectedBranches(inboxNodes, req.NodeIDs)
+	} else if roots, err := outline.Build(inboxNodes, outline.OrderPosition); err == nil {
+		for _, root := range roots {
+			if root.Node.NodeType == "root" {
+				items = append(items, root.Children...)
+			} else {
+				items = append(items, root)
+			}
+		}
+		if len(items) > maxTriageNodes {
+			items = items[:maxTriageNodes]
+		}
+	}
+
+	result := models.InboxTriageResult{
+		InboxID:     inbox.ID,
+		Threshold:   threshold,
+		Suggestions: []models.InboxTriageSuggestion{},
+	}
+	if len(items) == 0 {
+		// Return empty triage
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	candidates, mindMaps, err := h.triageCandidates(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = strings.Join(strings.Fields(item.Node.Content), " ")
+	}
+	choices, aiTriage := h.chooseBranches(r.Context(), userID, req.APIKey, texts, candidates, piiRedactor(h.DB, inbox))
+	result.AITriage = aiTriage
+
+	for i, item := range items {
+		suggestion := models.InboxTriageSuggestion{
+			NodeID:            item.Node.ID,
+			Content:           item.Node.Content,
+			Confidence:        choices[i].confidence,
+			Reason:            choices[i].reason,
+			NeedsConfirmation: true,
+		}
+		if choices[i].candidate >= 0 {
+			candidate := candidates[choices[i].candidate]
+			suggestion.MindMapID = candidate.MindMapID
+			suggestion.MindMapTitle = candidate.MindMapTitle
+			suggestion.ParentID = candidate.NodeID
+			suggestion.ParentContent = candidate.Content
+			suggestion.NeedsConfirmation = suggestion.Confidence < threshold
+		}
+		result.Suggestions = append(result.Suggestions, suggestion)
+	}
+
+	if req.Apply {
+		if err := h.applyTriage(inbox, mindMaps, items, result.Suggestions); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to move nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Return triage suggestions
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// triageCandidates lists the roots and top-level branches of the user's most recently
+// updated active maps, leaving out the inbox. Also returns the maps by ID
+func (h *InboxHandler) triageCandidates(userID string) ([]triage.Candidate, map[string]*models.MindMap, error) {
+	summaries, err := h.DB.GetMindMapsByUserID(userID, models.MindMapStatusActive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var candidates []triage.Candidate
+	mindMaps := make(map[string]*models.MindMap)
+	for i := range summaries {
+		mindMap := &summaries[i].MindMap
+		if mindMap.IsInbox {
+			continue
+		}
+		if len(mindMaps) == maxTriageMaps || len(candidates) >= maxTriageCandidates {
+			break
+		}
+		mindMaps[mindMap.ID] = mindMap
+
+		nodes, err := h.DB.GetNodesByMindMapID(mindMap.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		roots, err := outline.Build(nodes, outline.OrderPosition)
+		if err != nil {
+			continue
+		}
+		for _, root := range roots {
+			for _, item := range append([]*outline.Item{root}, root.Children...) {
+				if len(candidates) == maxTriageCandidates {
+					break
+				}
+				candidates = append(candidates, triage.Candidate{
+					MindMapID:    mindMap.ID,
+					MindMapTitle: mindMap.Title,
+					NodeID:       item.Node.ID,
+					Content:      item.Node.Content,
+				})
+			}
+		}
+	}
+	return candidates, mindMaps, nil
+}
+
+// chooseBranches picks a candidate for every text. With an API key the closest candidates
+// by embedding are shortlisted and the model chooses among them with a confidence; if that
+// fails, or there is no key, the most similar candidate is taken with its similarity as
+// the confidence. Reports whether the model made the choices
+func (h *InboxHandler) chooseBranches(ctx context.Context, userID, apiKeyOverride string, texts []string, candidates []triage.Candidate, redactor *pii.Redactor) ([]triageChoice, bool) {
+	choices := make([]triageChoice, len(texts))
+	for i := range choices {
+		choices[i].candidate = -1
+	}
+	if len(candidates) == 0 {
+		return choices, false
+	}
+
+	// Score every candidate against every text
+	scores := make([][]float64, len(texts))
+	embedded := false
+	apiKey, err := resolveOpenAIKey(h.DB, userID, apiKeyOverride)
+	if err != nil {
+		log.Printf("[Inbox Triage] Using word similarity: %v", err)
+	}
+	if apiKey != "" {
+		var inputs []string
+		for _, text := range texts {
+			inputs = append(inputs, redactor.Redact(text))
+		}
+		for _, candidate := range candidates {
+			inputs = append(inputs, redactor.Redact(candidate.Text()))
+		}
+		embeddings, usage, err := openAIEmbeddings(ctx, apiKey, inputs)
+		recordAIUsage(h.DB, userID, "", aiFeatureTriage, usage)
+		if err != nil {
+			log.Printf("[Inbox Triage] Embeddings failed, falling back to word similarity: %v", err)
+		} else {
+			for i := range texts {
+				scores[i] = make([]float64, len(candidates))
+				for j := range candidates {
+					scores[i][j] = triage.Cosine(embeddings[i], embeddings[len(texts)+j])
+				}
+			}
+			embedded = true
+		}
+	}
+	if !embedded {
+		for i, text := range texts {
+			scores[i] = make([]float64, len(candidates))
+			for j, candidate := range candidates {
+				scores[i][j] = triage.WordSimilarity(text, candidate.Text())
+			}
+		}
+	}
+
+	shortlists := make([][]int, len(texts))
+	for i := range texts {
+		shortlists[i] = triage.Top(scores[i], triageShortlist)
+	}
+
+	if apiKey != "" {
+		aiChoices, usage, err := chooseBranchesWithAI(ctx, apiKey, texts, candidates, shortlists, redactor)
+		recordAIUsage(h.DB, userID, "", aiFeatureTriage, usage)
+		if err == nil {
+			return aiChoices, true
+		}
+		log.Printf("[Inbox Triage] AI triage failed, falling back to similarity: %v", err)
+	}
+
+	for i := range texts {
+		best := shortlists[i][0]
+		if scores[i][best] <= 0 {
+			continue
+		}
+		choices[i] = triageChoice{
+			candidate:  best,
+			confidence: min(scores[i][best], 1),
+			reason:     fmt.Sprintf("Most similar branch: %s", candidates[best].Text()),
+		}
+	}
+	return choices, false
+}
+
+// chooseBranchesWithAI asks the model to pick the best branch for each text from its
+// shortlist, with a confidence between 0 and 1. Texts the model leaves out get no branch.
+// Also returns the usage of the request
+func chooseBranchesWithAI(ctx context.Context, apiKey string, texts []string, candidates []triage.Candidate, shortlists [][]int, redactor *pii.Redactor) ([]triageChoice, aiUsage, error) {
+	var list strings.Builder
+	for i, text := range texts {
+		fmt.Fprintf(&list, "Note %d: %s\n", i+1, prompt.Line(redactor.Redact(text)))
+		for j, candidate := range shortlists[i] {
+			fmt.Fprintf(&list, "  %d. %s\n", j+1, prompt.Line(redactor.Redact(candidates[candidate].Text())))
+		}
+	}
+
+	content, usage, err := openAIChatCompletion(
+		ctx,
+		apiKey,
+		prompt.System("You are sorting quick notes from an inbox into existing mind maps. For each note, choose the branch it belongs under from its numbered options, or 0 if none fits. Respond only with a JSON array of objects with \"note\" (the note number), \"option\" (the option number or 0), \"confidence\" (between 0 and 1) and a short \"reason\"."),
+		prompt.Delimit(prompt.Limit(list.String(), prompt.MaxContextLength)),
+		1500,
+	)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, usage, fmt.Errorf("no JSON array in triage response")
+	}
+
+	var answers []struct {
+		Note       int     `json:"note"`
+		Option     int     `json:"option"`
+		Confidence float64 `json:"confidence"`
+		Reason     string  `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &answers); err != nil {
+		return nil, usage, err
+	}
+
+	choices := make([]triageChoice, len(texts))
+	for i := range choices {
+		choices[i].candidate = -1
+	}
+	for _, answer := range answers {
+		i := answer.Note - 1
+		if i < 0 || i >= len(texts) {
+			continue
+		}
+		choices[i].reason = strings.TrimSpace(redactor.Restore(answer.Reason))
+		if answer.Option < 1 || answer.Option > len(shortlists[i]) {
+			continue
+		}
+		choices[i].candidate = shortlists[i][answer.Option-1]
+		choices[i].confidence = max(0, min(answer.Confidence, 1))
+	}
+	return choices, usage, nil
+}
+
+// applyTriage moves the nodes whose suggestion needs no confirmation, grouped by the
+// branch they go under, and marks those suggestions applied
+func (h *InboxHandler) applyTriage(inbox *models.MindMap, mindMaps map[string]*models.MindMap, items []*outline.Item, suggestions []models.InboxTriageSuggestion) error {
+	type destination struct{ mindMapID, parentID string }
+	var order []destination
+	groups := make(map[destination][]int)
+	for i, suggestion := range suggestions {
+		if suggestion.NeedsConfirmation {
+			continue
+		}
+		key := destination{suggestion.MindMapID, suggestion.ParentID}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range order {
+		// Reload the target so branches filed earlier in this request are stacked below
+		targetNodes, err := h.DB.GetNodesByMindMapID(key.mindMapID)
+		if err != nil {
+			return err
+		}
+		var parent *models.Node
+		for i := range targetNodes {
+			if targetNodes[i].ID == key.parentID {
+				parent = &targetNodes[i]
+			}
+		}
+		if parent == nil {
+			continue
+		}
+
+		branches := make([]*outline.Item, 0, len(groups[key]))
+		for _, i := range groups[key] {
+			branches = append(branches, items[i])
+		}
+		if _, err := h.moveBranches(inbox, mindMaps[key.mindMapID], parent, targetNodes, branches); err != nil {
+			return err
+		}
+		for _, i := range groups[key] {
+			suggestions[i].Applied = true
+		}
+	}
+	return nil
+}
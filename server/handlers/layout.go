@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/layout"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Spacing kept between node boxes by the de-overlap endpoint, in pixels
+const (
+	defaultDeoverlapGap = 20
+	maxDeoverlapGap     = 200
+)
+
+// layoutModeError is the response to an unknown layout mode
+const layoutModeError = "Layout mode must be one of 'tree', 'split', 'timeline' or 'layered'"
+
+// DeoverlapMindMap handles POST /api/mindmaps/{id}/deoverlap?gap=20, nudging overlapping
+// nodes apart and saving their new positions in one batch. ?dry_run=true returns the
+// positions without saving them
+func (h *MindMapHandler) DeoverlapMindMap(w http.ResponseWriter, r *http.Request) {
+	mindMap, ok := h.layoutMindMap(w, r, "/deoverlap")
+	if !ok {
+		return
+	}
+
+	// Parse spacing
+	gap := float64(defaultDeoverlapGap)
+	if value := r.URL.Query().Get("gap"); value != "" {
+		var err error
+		gap, err = strconv.ParseFloat(value, 64)
+		if err != nil || gap < 0 || gap > maxDeoverlapGap {
+			http.Error(w, fmt.Sprintf("gap must be between 0 and %d", maxDeoverlapGap), http.StatusBadRequest)
+			return
+		}
+	}
+
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	positions, remaining := layout.Deoverlap(nodes, gap)
+	h.saveLayout(w, r, mindMap.ID, positions, remaining)
+}
+
+// LayoutMindMap handles POST /api/mindmaps/{id}/layout?mode=tree|split|timeline|layered,
+// re-laying out the whole map while leaving pinned nodes where the user put them. Without
+// a mode the map's own layout mode is used, falling back to tree. ?dry_run=true returns
+// the positions without saving them
+func (h *MindMapHandler) LayoutMindMap(w http.ResponseWriter, r *http.Request) {
+	mindMap, ok := h.layoutMindMap(w, r, "/layout")
+	if !ok {
+		return
+	}
+
+	// Parse layout mode
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = mindMap.LayoutMode
+	}
+	if mode == "" {
+		mode = layout.ModeTree
+	}
+	if !layout.ValidMode(mode) {
+		http.Error(w, layoutModeError, http.StatusBadRequest)
+		return
+	}
+
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	edges, err := tenantDB(h.DB, r).GetEdgesByMindMapID(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get edges: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	positions, remaining := layout.Apply(mode, nodes, edges)
+	h.saveLayout(w, r, mindMap.ID, positions, remaining)
+}
+
+// applyLayout returns a copy of nodes moved to the positions of the given layout mode,
+// for drawing a layout without saving it
+func applyLayout(mode string, nodes []models.Node, edges []models.Edge) []models.Node {
+	positions, _ := layout.Apply(mode, nodes, edges)
+	moved := make(map[string]models.NodePositionUpdateRequest, len(positions))
+	for _, position := range positions {
+		moved[position.ID] = position
+	}
+
+	result := make([]models.Node, len(nodes))
+	for i, node := range nodes {
+		if position, ok := moved[node.ID]; ok {
+			node.PositionX, node.PositionY = position.PositionX, position.PositionY
+		}
+		result[i] = node
+	}
+	return result
+}
+
+// layoutMindMap extracts the mind map ID of a layout request and checks that the user can
+// edit the map, writing an error response and returning false if the request cannot proceed
+func (h *MindMapHandler) layoutMindMap(w http.ResponseWriter, r *http.Request, suffix string) (*models.MindMap, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, suffix)
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// Check if user can edit the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return mindMap, true
+}
+
+// saveLayout persists the positions a layout computed as one batch, broadcasts the move
+// and returns the result. Dry runs only return the result
+func (h *MindMapHandler) saveLayout(w http.ResponseWriter, r *http.Request, mindMapID string, positions []models.NodePositionUpdateRequest, remaining int) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	if len(positions) > 0 && !dryRun {
+		if err := h.DB.BatchUpdateNodePositions(mindMapID, positions); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update node positions: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Return moved nodes
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.LayoutResult{
+		DryRun:    dryRun,
+		MindMapID: mindMapID,
+		Moved:     len(positions),
+		Positions: positions,
+		Remaining: remaining,
+	})
+}
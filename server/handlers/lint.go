@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/lint"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxAILintNodes caps how many nodes are sent to the model for AI suggestions
+const maxAILintNodes = 300
+
+// LintMindMap handles GET /api/mindmaps/{id}/lint. Rule checks always run; with ai=true
+// the outline is also reviewed by the model and its suggestions are added to the findings
+func (h *MindMapHandler) LintMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/lint")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	useAI, _ := strconv.ParseBool(r.URL.Query().Get("ai"))
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get nodes and run the rule checks
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := models.LintReport{
+		MindMapID: mindMapID,
+		Findings:  lint.Check(roots),
+	}
+
+	// AI suggestions are best effort; rule findings are returned even if the model fails
+	if useAI {
+		apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+		if err != nil {
+			log.Printf("[Lint] Skipping AI suggestions for map %s: %v", mindMapID, err)
+		}
+		items := outline.Flatten(roots)
+		if apiKey != "" && len(items) > 0 && len(items) <= maxAILintNodes {
+			aiFindings, usage, err := lintWithAI(r.Context(), apiKey, mindMap.Title, items, piiRedactor(h.DB, mindMap))
+			recordAIUsage(h.DB, userID, mindMapID, aiFeatureLint, usage)
+			if err != nil {
+				log.Printf("[Lint] AI suggestions failed for map %s: %v", mindMapID, err)
+			} else {
+				report.Findings = append(report.Findings, aiFindings...)
+				report.AISuggestions = true
+			}
+		}
+	}
+
+	// Return lint report
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// lintWithAI asks the model to review the outline and maps the node numbers
+// in its answer back to node IDs. Also returns the usage of the request
+func lintWithAI(ctx context.Context, apiKey, title string, items []*outline.Item, redactor *pii.Redactor) ([]models.LintFinding, aiUsage, error) {
+	var list strings.Builder
+	fmt.Fprintf(&list, "Mind map: %s\n", prompt.Line(redactor.Redact(title)))
+	for i, item := range items {
+		fmt.Fprintf(&list, "%s%d. %s\n", strings.Repeat("  ", item.Depth), i+1, prompt.Line(redactor.Redact(item.Node.Content)))
+	}
+
+	content, usage, err := openAIChatCompletion(
+		ctx,
+		apiKey,
+		prompt.System("You review mind maps for clarity and structure. Point out vague or overlapping ideas, misplaced nodes and missing topics. Respond only with a JSON array of at most 10 objects with a \"message\" describing the issue, a \"suggestion\" for fixing it and \"nodes\", the list of node numbers involved."),
+		prompt.Delimit(prompt.Limit(list.String(), prompt.MaxContextLength)),
+		1000,
+	)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, usage, fmt.Errorf("no JSON array in lint response")
+	}
+
+	var suggestions []struct {
+		Message    string `json:"message"`
+		Suggestion string `json:"suggestion"`
+		Nodes      []int  `json:"nodes"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &suggestions); err != nil {
+		return nil, usage, err
+	}
+
+	var findings []models.LintFinding
+	for _, suggestion := range suggestions {
+		if strings.TrimSpace(suggestion.Message) == "" {
+			continue
+		}
+		nodeIDs := []string{}
+		for _, number := range suggestion.Nodes {
+			if number >= 1 && number <= len(items) {
+				nodeIDs = append(nodeIDs, items[number-1].Node.ID)
+			}
+		}
+		findings = append(findings, models.LintFinding{
+			Rule:       "ai_review",
+			Severity:   models.LintSeverityInfo,
+			Message:    redactor.Restore(suggestion.Message),
+			Suggestion: redactor.Restore(suggestion.Suggestion),
+			NodeIDs:    nodeIDs,
+			Source:     "ai",
+		})
+	}
+	return findings, usage, nil
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strconv"
+)
+
+// liteProfile is the value of the X-API-Profile header or profile query parameter that
+// selects compact responses for clients on slow or metered connections
+const liteProfile = "lite"
+
+// liteContentRunes is how much of a node's content the lite profile returns
+const liteContentRunes = 280
+
+// maxLiteDeltaChanges bounds how many change log entries a delta is built from. Clients
+// that are further behind get a full snapshot instead
+const maxLiteDeltaChanges = 1000
+
+// wantsLiteProfile reports whether the request asked for the lite API profile
+func wantsLiteProfile(r *http.Request) bool {
+	return r.Header.Get("X-API-Profile") == liteProfile || r.URL.Query().Get("profile") == liteProfile
+}
+
+// toLiteNodes converts nodes to their lite form
+func toLiteNodes(nodes []models.Node) []models.LiteNode {
+	lite := make([]models.LiteNode, 0, len(nodes))
+	for _, node := range nodes {
+		content, hasMore := node.Content, false
+		if runes := []rune(content); len(runes) > liteContentRunes {
+			content, hasMore = string(runes[:liteContentRunes]), true
+		}
+		lite = append(lite, models.LiteNode{
+			ID:        node.ID,
+			ParentID:  node.ParentID,
+			Content:   content,
+			HasMore:   hasMore,
+			PositionX: node.PositionX,
+			PositionY: node.PositionY,
+			NodeType:  node.NodeType,
+			UpdatedAt: node.UpdatedAt,
+		})
+	}
+	return lite
+}
+
+// toLiteEdges converts edges to their lite form
+func toLiteEdges(edges []models.Edge) []models.LiteEdge {
+	lite := make([]models.LiteEdge, 0, len(edges))
+	for _, edge := range edges {
+		lite = append(lite, models.LiteEdge{ID: edge.ID, SourceID: edge.SourceID, TargetID: edge.TargetID})
+	}
+	return lite
+}
+
+// changePayload holds the identifying fields of the change log payloads
+type changePayload struct {
+	ID      string   `json:"id"`
+	NodeID  string   `json:"node_id"`
+	NodeIDs []string `json:"node_ids"`
+}
+
+// liteDelta is what changed in a mind map since a client's last sync
+type liteDelta struct {
+	changedNodes map[string]bool
+	deletedNodes []string
+	edgesChanged bool
+}
+
+// collectLiteDelta reads the changes after since from the change log. It reports false
+// when the client is too far behind for a delta to be worthwhile, or behind changes a
+// retention policy has already trimmed from the log
+func collectLiteDelta(db *database.DB, mindMapID string, since int64) (*liteDelta, bool, error) {
+	changes, err := db.GetChangesSince(mindMapID, since, maxLiteDeltaChanges+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(changes) > maxLiteDeltaChanges || (len(changes) > 0 && changes[0].Seq != since+1) {
+		return nil, false, nil
+	}
+
+	delta := &liteDelta{changedNodes: make(map[string]bool)}
+	deleted := make(map[string]bool)
+	for _, change := range changes {
+		switch change.Type {
+		case "node.created", "node.updated":
+			var payload changePayload
+			if json.Unmarshal(change.Payload, &payload) == nil {
+				delta.changedNodes[payload.ID] = true
+			}
+		case "vote.cast", "vote.removed":
+			var payload changePayload
+			if json.Unmarshal(change.Payload, &payload) == nil {
+				delta.changedNodes[payload.NodeID] = true
+			}
+		case "nodes.updated":
+			var payload changePayload
+			if json.Unmarshal(change.Payload, &payload) == nil {
+				for _, id := range payload.NodeIDs {
+					delta.changedNodes[id] = true
+				}
+			}
+		case "nodes.imported":
+			var payload changePayload
+			if json.Unmarshal(change.Payload, &payload) == nil {
+				for _, id := range payload.NodeIDs {
+					delta.changedNodes[id] = true
+				}
+			}
+			delta.edgesChanged = true
+		case "nodes.moved":
+			var positions []models.NodePositionUpdateRequest
+			if json.Unmarshal(change.Payload, &positions) == nil {
+				for _, position := range positions {
+					delta.changedNodes[position.ID] = true
+				}
+			}
+		case "node.deleted":
+			var payload changePayload
+			if json.Unmarshal(change.Payload, &payload) == nil && !deleted[payload.ID] {
+				deleted[payload.ID] = true
+				delta.deletedNodes = append(delta.deletedNodes, payload.ID)
+			}
+			// Deleting a node removes its edges too
+			delta.edgesChanged = true
+		case "edge.created", "edge.deleted":
+			delta.edgesChanged = true
+		}
+	}
+	return delta, true, nil
+}
+
+// getLiteMindMap writes a mind map in the lite profile, as a delta when the request passes
+// the change_seq of an earlier response as since. It reads from the primary and takes the
+// sequence number before the snapshot, so anything the snapshot misses is in the next delta
+func (h *MindMapHandler) getLiteMindMap(w http.ResponseWriter, r *http.Request, mindMapID, userID string) {
+	since := int64(-1)
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		since, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "since must be a non-negative sequence number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	primary := h.DB.Primary()
+	latest, err := primary.GetLatestChangeSeq(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Get mind map with details
+	mindMap, err := primary.GetMindMapWithDetails(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user has access
+	if !canViewMindMap(h.DB, &mindMap.MindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lite := models.LiteMindMap{
+		ID:        mindMap.ID,
+		Title:     mindMap.Title,
+		Status:    mindMap.Status,
+		UpdatedAt: mindMap.UpdatedAt,
+		ChangeSeq: latest,
+		Nodes:     toLiteNodes(mindMap.Nodes),
+		Edges:     toLiteEdges(mindMap.Edges),
+	}
+
+	// Fall back to the full snapshot when the client is too far behind or ahead of the log
+	if since >= 0 && since <= latest {
+		delta, ok, err := collectLiteDelta(primary, mindMapID, since)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get changes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if ok {
+			var changed []models.Node
+			for _, node := range mindMap.Nodes {
+				if delta.changedNodes[node.ID] {
+					changed = append(changed, node)
+				}
+			}
+			lite.Delta = true
+			lite.Since = since
+			lite.Nodes = toLiteNodes(changed)
+			lite.DeletedNodeIDs = delta.deletedNodes
+			if !delta.edgesChanged {
+				lite.Edges = nil
+			}
+		}
+	}
+
+	// Return compact mind map
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lite)
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/layout"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"strconv"
+	"strings"
+)
+
+// Limits of the branches of a generated map
+const (
+	defaultMapBranches    = 5
+	maxMapBranches        = 8
+	defaultMapSubBranches = 3
+	maxMapSubBranches     = 5
+)
+
+// mapRootGap is the space left between a map's existing content and a generated tree
+const mapRootGap = 150
+
+// MapGenerationRequest represents a request to generate a whole mind map for a topic: a
+// root, branches under it and sub-branches under each branch. The count and type of the
+// embedded request are not used
+type MapGenerationRequest struct {
+	GenerationRequest
+	Branches    int `json:"branches"`     // Branches under the root (default: 5, max: 8)
+	SubBranches int `json:"sub_branches"` // Sub-branches under each branch (default: 3, max: 5)
+}
+
+// GeneratedBranch is a branch of a generated map with its sub-branches
+type GeneratedBranch struct {
+	Content     string   `json:"content"`
+	SubBranches []string `json:"sub_branches"`
+}
+
+// GeneratedMap is the tree of a generated map
+type GeneratedMap struct {
+	Root     string            `json:"root"`
+	Branches []GeneratedBranch `json:"branches"`
+}
+
+// MapGenerationResponse represents the response to a generated map
+type MapGenerationResponse struct {
+	Map          GeneratedMap           `json:"map"`
+	Nodes        []models.NodeImportRow `json:"nodes"`                   // The created nodes, parents before their children
+	Provider     string                 `json:"provider"`                // AI provider that generated the map
+	Model        string                 `json:"model"`                   // Model that generated the map
+	GenerationID string                 `json:"generation_id,omitempty"` // ID of the generation in the map's history
+}
+
+// mapSchema is the JSON reply map generation asks providers for
+var mapSchema = jsonSchema{
+	name:        "submit_map",
+	description: "Submit the generated mind map",
+	schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"root": map[string]interface{}{"type": "string", "description": "The central node, naming the topic in a few words"},
+			"branches": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"content": map[string]interface{}{"type": "string", "description": "A main branch in a few words"},
+						"sub_branches": map[string]interface{}{
+							"type":        "array",
+							"description": "The sub-branches of the branch, each in a few words",
+							"items":       map[string]interface{}{"type": "string"},
+						},
+					},
+					"required":             []string{"content", "sub_branches"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"root", "branches"},
+		"additionalProperties": false,
+	},
+}
+
+// GenerateMap handles POST /api/generate/map, generating a root, branches and sub-branches
+// for a topic in one AI call and adding them to the mind map as a tree in one transaction.
+// The tree is laid out left to right below the map's existing content
+func (h *IdeaGenerationHandler) GenerateMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req MapGenerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Topic = strings.TrimSpace(req.Topic)
+	if req.Topic == "" {
+		http.Error(w, "Topic is required", http.StatusBadRequest)
+		return
+	}
+	mindMap, ok := h.validateGenerationRequest(w, r, userID, &req.GenerationRequest)
+	if !ok {
+		return
+	}
+
+	// Validate the size of the tree
+	if req.Branches <= 0 {
+		req.Branches = defaultMapBranches
+	}
+	if req.Branches > maxMapBranches {
+		http.Error(w, fmt.Sprintf("Branches must be between 1 and %d", maxMapBranches), http.StatusBadRequest)
+		return
+	}
+	if req.SubBranches <= 0 {
+		req.SubBranches = defaultMapSubBranches
+	}
+	if req.SubBranches > maxMapSubBranches {
+		http.Error(w, fmt.Sprintf("Sub-branches must be between 1 and %d", maxMapSubBranches), http.StatusBadRequest)
+		return
+	}
+
+	// Generate the tree, putting back any redacted personal data
+	redactor := piiRedactor(h.DB, mindMap)
+	generated, gen, usage, err := h.generateMapWithAI(r.Context(), req, redactor)
+	recordAIUsage(h.DB, userID, req.MindMapID, aiFeatureGenerate, usage)
+	if err != nil {
+		sendGenerationError(w, "Failed to generate map", err)
+		return
+	}
+	generated.Root = redactor.Restore(generated.Root)
+	for i := range generated.Branches {
+		generated.Branches[i].Content = redactor.Restore(generated.Branches[i].Content)
+		for j := range generated.Branches[i].SubBranches {
+			generated.Branches[i].SubBranches[j] = redactor.Restore(generated.Branches[i].SubBranches[j])
+		}
+	}
+
+	// Lay the tree out below the existing content and create it
+	existing, err := tenantDB(h.DB, r).GetNodesByMindMapID(req.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	rows := generatedMapRows(generated, len(existing) == 0)
+	layoutGeneratedMap(rows, existing)
+	if err := h.DB.ImportNodes(req.MindMapID, userID, rows); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Keep the generation in the map's history, without the API key
+	historyReq := req
+	historyReq.APIKey = ""
+	generationID := recordGeneration(h.DB, userID, req.MindMapID, "", historyReq, generated, gen.provider, gen.model)
+
+	// Return generated map
+	sendJSONResponse(w, http.StatusCreated, MapGenerationResponse{
+		Map:          generated,
+		Nodes:        rows,
+		Provider:     gen.provider,
+		Model:        gen.model,
+		GenerationID: generationID,
+	})
+}
+
+// generateMapWithAI generates the tree of a map for the topic of req in one AI call. The
+// topic and context are redacted with the given redactor before they are sent, and the
+// reply is cut down to the requested branches and sub-branches. Also returns the model
+// used and the usage of the request
+func (h *IdeaGenerationHandler) generateMapWithAI(ctx context.Context, req MapGenerationRequest, redactor *pii.Redactor) (GeneratedMap, generationModel, aiUsage, error) {
+	gen, err := h.resolveGenerationModel(req.GenerationRequest)
+	if err != nil {
+		return GeneratedMap{}, gen, aiUsage{}, err
+	}
+
+	// The topic and context are user content
+	task := fmt.Sprintf("Create a mind map of the topic: a root node, %d main branches under it and %d sub-branches under each branch.", req.Branches, req.SubBranches)
+	input := fmt.Sprintf("Topic: %s\nContext: %s", prompt.Line(redactor.Redact(req.Topic)), prompt.Limit(prompt.Clean(redactor.Redact(req.Context)), prompt.MaxContextLength))
+	if examples := ideaFeedbackPrompt(h.DB, req.MindMapID, redactor); examples != "" {
+		task += " Favor ideas like the ones the user accepted and avoid ideas like the ones they rejected."
+		input += "\n" + examples
+	}
+	content, usage, err := gen.completeJSON(
+		ctx,
+		prompt.System("You are a brainstorming assistant that structures topics into mind maps. Branches cover distinct aspects of the topic and sub-branches break their branch down further. Every node is a short phrase of a few words."),
+		task+"\n\n"+prompt.Delimit(input),
+		2000,
+		mapSchema,
+	)
+	if err != nil {
+		return GeneratedMap{}, gen, usage, err
+	}
+
+	// Parse the reply, skipping empty nodes and any beyond the requested counts
+	var reply GeneratedMap
+	if err := json.Unmarshal([]byte(content), &reply); err != nil {
+		return GeneratedMap{}, gen, usage, fmt.Errorf("invalid map reply: %v", err)
+	}
+	generated := GeneratedMap{Root: strings.TrimSpace(reply.Root), Branches: []GeneratedBranch{}}
+	if generated.Root == "" {
+		generated.Root = req.Topic
+	}
+	for _, branch := range reply.Branches {
+		branch.Content = strings.TrimSpace(branch.Content)
+		if branch.Content == "" || len(generated.Branches) == req.Branches {
+			continue
+		}
+		subBranches := []string{}
+		for _, subBranch := range branch.SubBranches {
+			if subBranch = strings.TrimSpace(subBranch); subBranch != "" && len(subBranches) < req.SubBranches {
+				subBranches = append(subBranches, subBranch)
+			}
+		}
+		branch.SubBranches = subBranches
+		generated.Branches = append(generated.Branches, branch)
+	}
+	if len(generated.Branches) == 0 {
+		return GeneratedMap{}, gen, usage, fmt.Errorf("no branches generated")
+	}
+	return generated, gen, usage, nil
+}
+
+// generatedMapRows turns a generated tree into node rows, parents before their children.
+// The root is a root node when the map has no other nodes, and an idea otherwise
+func generatedMapRows(generated GeneratedMap, emptyMap bool) []models.NodeImportRow {
+	rootType := "idea"
+	if emptyMap {
+		rootType = "root"
+	}
+	rows := []models.NodeImportRow{{Row: 1, Content: generated.Root, NodeType: rootType, Tags: []string{}}}
+	for _, branch := range generated.Branches {
+		branchRow := len(rows) + 1
+		rows = append(rows, models.NodeImportRow{Row: branchRow, Content: branch.Content, ParentRow: 1, NodeType: "idea", Tags: []string{}})
+		for _, subBranch := range branch.SubBranches {
+			rows = append(rows, models.NodeImportRow{Row: len(rows) + 1, Content: subBranch, ParentRow: branchRow, NodeType: "idea", Tags: []string{}})
+		}
+	}
+	return rows
+}
+
+// layoutGeneratedMap lays the generated rows out as a left-to-right tree whose top lines
+// up with the bottom of the map's existing content
+func layoutGeneratedMap(rows []models.NodeImportRow, existing []models.Node) {
+	top := 0.0
+	for i, node := range existing {
+		if bottom := node.PositionY + layout.NodeHeight + mapRootGap; i == 0 || bottom > top {
+			top = bottom
+		}
+	}
+
+	// Lay out placeholder nodes, stacked in row order so siblings keep their order
+	nodes := make([]models.Node, len(rows))
+	for i, row := range rows {
+		nodes[i] = models.Node{ID: "row:" + strconv.Itoa(row.Row), PositionY: float64(i)}
+		if row.ParentRow > 0 {
+			parentID := "row:" + strconv.Itoa(row.ParentRow)
+			nodes[i].ParentID = &parentID
+		}
+	}
+	positions, _ := layout.Tree(nodes)
+	for _, position := range positions {
+		row, _ := strconv.Atoi(strings.TrimPrefix(position.ID, "row:"))
+		nodes[row-1].PositionX, nodes[row-1].PositionY = position.PositionX, position.PositionY
+	}
+
+	// Move the tree down to the top
+	minY := math.Inf(1)
+	for _, node := range nodes {
+		minY = math.Min(minY, node.PositionY)
+	}
+	for i := range rows {
+		rows[i].PositionX = nodes[i].PositionX
+		rows[i].PositionY = nodes[i].PositionY - minY + top
+	}
+}
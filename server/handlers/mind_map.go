@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"saas-server/database"
 	"saas-server/models"
+	"saas-server/pkg/layout"
+	"saas-server/pkg/realtime"
+	"saas-server/pkg/validation"
 	"strings"
 
 	"github.com/google/uuid"
@@ -13,12 +16,13 @@ import (
 
 // MindMapHandler handles mind map-related requests
 type MindMapHandler struct {
-	DB *database.DB
+	DB  *database.DB
+	Hub *realtime.Hub
 }
 
 // NewMindMapHandler creates a new MindMapHandler
-func NewMindMapHandler(db *database.DB) *MindMapHandler {
-	return &MindMapHandler{DB: db}
+func NewMindMapHandler(db *database.DB, hub *realtime.Hub) *MindMapHandler {
+	return &MindMapHandler{DB: db, Hub: hub}
 }
 
 // CreateMindMap handles POST /api/mindmaps
@@ -47,6 +51,10 @@ func (h *MindMapHandler) CreateMindMap(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Title is required", http.StatusBadRequest)
 		return
 	}
+	if req.Visibility != "" && !models.ValidVisibility(req.Visibility) {
+		http.Error(w, visibilityError, http.StatusBadRequest)
+		return
+	}
 
 	// Create mind map
 	mindMap, err := h.DB.CreateMindMap(userID, req)
@@ -75,8 +83,15 @@ func (h *MindMapHandler) GetMindMaps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optionally only list active or archived maps
+	status := r.URL.Query().Get("status")
+	if status != "" && status != models.MindMapStatusActive && status != models.MindMapStatusArchived {
+		http.Error(w, "Status must be 'active' or 'archived'", http.StatusBadRequest)
+		return
+	}
+
 	// Get mind maps
-	mindMaps, err := h.DB.GetMindMapsByUserID(userID)
+	mindMaps, err := readDB(h.DB, r).GetMindMapsByUserID(userID, status)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind maps: %v", err), http.StatusInternalServerError)
 		return
@@ -122,35 +137,46 @@ func (h *MindMapHandler) GetMindMap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isDetails && wantsLiteProfile(r) {
+		h.getLiteMindMap(w, r, mindMapID, userID)
+		return
+	}
+
 	if isDetails {
 		// Get mind map with details
-		mindMapWithDetails, err := h.DB.GetMindMapWithDetails(mindMapID)
+		mindMapWithDetails, err := readDB(h.DB, r).GetMindMapWithDetails(mindMapID)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Check if user has access
-		if mindMapWithDetails.UserID != userID && !mindMapWithDetails.IsPublic {
+		if !canViewMindMap(h.DB, &mindMapWithDetails.MindMap, userID) {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		// Return mind map with details
+		// Return mind map with details, streamed in batches for large maps when the client
+		// accepts NDJSON
+		models.MaskNodeAttribution(mindMapWithDetails.Nodes)
+		if wantsDetailsStream(r, mindMapWithDetails) {
+			streamDetails(w, mindMapWithDetails)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(mindMapWithDetails)
 		return
 	}
 
 	// Get mind map
-	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access
-	if mindMap.UserID != userID && !mindMap.IsPublic {
+	if !canViewMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -188,7 +214,7 @@ func (h *MindMapHandler) UpdateMindMap(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get mind map to check ownership
-	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
@@ -207,6 +233,50 @@ func (h *MindMapHandler) UpdateMindMap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate vote limit
+	if req.VoteLimit != nil && (*req.VoteLimit < 0 || *req.VoteLimit > maxVoteLimit) {
+		http.Error(w, fmt.Sprintf("Vote limit must be between 0 and %d", maxVoteLimit), http.StatusBadRequest)
+		return
+	}
+
+	// Validate visibility
+	if req.Visibility != nil && !models.ValidVisibility(*req.Visibility) {
+		http.Error(w, visibilityError, http.StatusBadRequest)
+		return
+	}
+
+	// Validate status and its transition
+	if req.Status != "" {
+		if !models.ValidMindMapStatus(req.Status) {
+			http.Error(w, "Status must be one of 'active', 'archived' or 'deleted'", http.StatusBadRequest)
+			return
+		}
+		if !models.CanTransitionMindMapStatus(mindMap.Status, req.Status) {
+			http.Error(w, fmt.Sprintf("Cannot change status from '%s' to '%s'", mindMap.Status, req.Status), http.StatusConflict)
+			return
+		}
+	}
+
+	// Validate icon and cover image; empty strings clear them
+	if req.Icon != nil && *req.Icon != "" {
+		if err := validation.ValidateEmoji(*req.Icon); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.CoverImage != nil && *req.CoverImage != "" {
+		if err := validation.ValidateImageURL(*req.CoverImage); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Validate layout mode; an empty string clears it
+	if req.LayoutMode != nil && *req.LayoutMode != "" && !layout.ValidMode(*req.LayoutMode) {
+		http.Error(w, layoutModeError, http.StatusBadRequest)
+		return
+	}
+
 	// Update mind map
 	if err := h.DB.UpdateMindMap(mindMapID, req); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update mind map: %v", err), http.StatusInternalServerError)
@@ -246,7 +316,7 @@ func (h *MindMapHandler) DeleteMindMap(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get mind map to check ownership
-	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"log"
+	"saas-server/database"
+	"saas-server/models"
+)
+
+// canViewMindMap reports whether the user owns the mind map, its visibility lets the user
+// see it, or the map has been shared with the user. Link and public maps are open to
+// anyone; workspace maps to the members of the owner's organization
+func canViewMindMap(db *database.DB, mindMap *models.MindMap, userID string) bool {
+	switch {
+	case mindMap.UserID == userID:
+		return true
+	case mindMap.Visibility == models.VisibilityLink || mindMap.Visibility == models.VisibilityPublic:
+		return true
+	case mindMap.Visibility == models.VisibilityWorkspace:
+		member, err := db.InMindMapWorkspace(mindMap.ID, userID)
+		if err != nil {
+			log.Printf("[MindMap Access] Error checking workspace of map %s: %v", mindMap.ID, err)
+		}
+		if member {
+			return true
+		}
+	}
+
+	permission, err := db.GetMindMapSharePermission(mindMap.ID, userID)
+	if err != nil {
+		log.Printf("[MindMap Access] Error checking share for map %s: %v", mindMap.ID, err)
+		return false
+	}
+	return permission != ""
+}
+
+// canEditMindMap reports whether the user owns the mind map or has been granted edit access to it
+func canEditMindMap(db *database.DB, mindMap *models.MindMap, userID string) bool {
+	if mindMap.UserID == userID {
+		return true
+	}
+
+	permission, err := db.GetMindMapSharePermission(mindMap.ID, userID)
+	if err != nil {
+		log.Printf("[MindMap Access] Error checking share for map %s: %v", mindMap.ID, err)
+		return false
+	}
+	return permission == "edit"
+}
+
+// canExportMindMap reports whether the user may export or duplicate the mind map. Owners
+// always can; anyone else who can view it only while the owner allows exports
+func canExportMindMap(db *database.DB, mindMap *models.MindMap, userID string) bool {
+	if mindMap.UserID == userID {
+		return true
+	}
+	return mindMap.AllowExport && canViewMindMap(db, mindMap, userID)
+}
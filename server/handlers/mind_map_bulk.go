@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxBulkMindMaps caps how many mind maps a single bulk request may name
+const maxBulkMindMaps = 200
+
+// BulkUpdateMindMaps handles POST /api/mindmaps/bulk, archiving, deleting or moving to a
+// folder a list of mind maps owned by the user. Either every map changes or none does
+func (h *MindMapHandler) BulkUpdateMindMaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.MindMapBulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if !models.ValidMindMapBulkOperation(req.Operation) {
+		http.Error(w, "Operation must be one of 'archive', 'delete' or 'move-to-folder'", http.StatusBadRequest)
+		return
+	}
+	if len(req.MindMapIDs) == 0 || len(req.MindMapIDs) > maxBulkMindMaps {
+		http.Error(w, fmt.Sprintf("mind_map_ids must list between 1 and %d mind maps", maxBulkMindMaps), http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(req.MindMapIDs))
+	ids := make([]string, 0, len(req.MindMapIDs))
+	for _, id := range req.MindMapIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	req.MindMapIDs = ids
+	req.Folder = strings.Trim(strings.TrimSpace(req.Folder), "/")
+	if len(req.Folder) > 255 {
+		http.Error(w, "Folder must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	// Apply the operation
+	result, err := h.DB.BulkUpdateMindMaps(userID, req)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Mind map not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, database.ErrNotMindMapOwner) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return bulk result
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
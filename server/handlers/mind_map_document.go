@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/export"
+	"strconv"
+)
+
+const (
+	// maxDocumentBytes caps the size of an imported JSON document
+	maxDocumentBytes = 20 << 20
+	// maxDocumentNodes caps how many nodes an imported JSON document may contain
+	maxDocumentNodes = 5000
+)
+
+// ImportMindMap handles POST /api/mindmaps/import. The body is a document produced by
+// GET /api/mindmaps/{id}/export?format=json; a new private map owned by the user is created
+// from it. The response reports whether the imported map matches the document exactly.
+// With ?dry_run=true the document is only validated and counted
+func (h *MindMapHandler) ImportMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	r.Body = http.MaxBytesReader(w, r.Body, maxDocumentBytes)
+	var doc models.MindMapDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate document
+	if len(doc.Nodes) > maxDocumentNodes {
+		http.Error(w, fmt.Sprintf("Document cannot contain more than %d nodes", maxDocumentNodes), http.StatusBadRequest)
+		return
+	}
+	if len(doc.MindMap.Title) > 255 {
+		http.Error(w, "Title must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+	for i := range doc.Nodes {
+		if len(doc.Nodes[i].Content) > maxImportContentLength {
+			http.Error(w, fmt.Sprintf("Node %s content must be at most %d characters", doc.Nodes[i].ID, maxImportContentLength), http.StatusBadRequest)
+			return
+		}
+		if doc.Nodes[i].NodeType == "" {
+			doc.Nodes[i].NodeType = "default"
+		}
+	}
+	for i := range doc.Edges {
+		if doc.Edges[i].EdgeType == "" {
+			doc.Edges[i].EdgeType = "default"
+		}
+	}
+	if err := export.ValidateDocument(doc); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid document: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Report what would be created once the document is known to import cleanly
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.MindMapDocumentImportResult{
+			DryRun:   true,
+			Nodes:    len(doc.Nodes),
+			Edges:    len(doc.Edges),
+			Fidelity: models.DocumentFidelity{Differences: []string{}},
+		})
+		return
+	}
+
+	// Import document
+	mindMap, idMap, err := h.DB.ImportMindMapDocument(userID, doc)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to import mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Export the new map again and compare it with the document
+	imported, err := tenantDB(h.DB, r).GetMindMapWithDetails(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	differences := export.CompareDocuments(doc, export.Document(imported), idMap)
+	if differences == nil {
+		differences = []string{}
+	}
+
+	// Return imported mind map
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.MindMapDocumentImportResult{
+		MindMap: mindMap,
+		Nodes:   len(doc.Nodes),
+		Edges:   len(doc.Edges),
+		Fidelity: models.DocumentFidelity{
+			Identical:   len(differences) == 0,
+			Differences: differences,
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"saas-server/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DuplicateMindMap handles POST /api/mindmaps/{id}/duplicate, copying a map the user can
+// view, with all of its nodes and edges, into a new private map owned by the user. Maps
+// shared with the user can only be copied while their owner allows exports
+func (h *MindMapHandler) DuplicateMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/duplicate")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body; an empty body keeps the source title
+	var req models.MindMapDuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if len(req.Title) > 255 {
+		http.Error(w, "Title must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	// Check if user may copy the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !canExportMindMap(h.DB, mindMap, userID) {
+		http.Error(w, exportDisabledError, http.StatusForbidden)
+		return
+	}
+
+	// Duplicate mind map
+	duplicate, err := h.DB.CloneMindMap(mindMapID, userID, req.Title)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to duplicate mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created mind map
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(duplicate)
+}
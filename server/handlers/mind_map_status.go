@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ArchiveMindMap handles POST /api/mindmaps/{id}/archive
+func (h *MindMapHandler) ArchiveMindMap(w http.ResponseWriter, r *http.Request) {
+	h.changeMindMapStatus(w, r, "/archive", models.MindMapStatusArchived)
+}
+
+// UnarchiveMindMap handles POST /api/mindmaps/{id}/unarchive
+func (h *MindMapHandler) UnarchiveMindMap(w http.ResponseWriter, r *http.Request) {
+	h.changeMindMapStatus(w, r, "/unarchive", models.MindMapStatusActive)
+}
+
+// changeMindMapStatus moves a mind map owned by the user to the given status
+func (h *MindMapHandler) changeMindMapStatus(w http.ResponseWriter, r *http.Request, suffix, status string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, suffix)
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get mind map to check ownership
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !models.CanTransitionMindMapStatus(mindMap.Status, status) {
+		http.Error(w, fmt.Sprintf("Cannot change status from '%s' to '%s'", mindMap.Status, status), http.StatusConflict)
+		return
+	}
+
+	// Update status
+	updated, err := h.DB.SetMindMapStatus(mindMapID, status)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Mind map not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to update mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated mind map
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"saas-server/database"
 	"saas-server/models"
+	"saas-server/pkg/fields"
+	"saas-server/pkg/realtime"
+	"saas-server/pkg/validation"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -13,12 +19,13 @@ import (
 
 // NodeHandler handles node-related requests
 type NodeHandler struct {
-	DB *database.DB
+	DB  *database.DB
+	Hub *realtime.Hub
 }
 
 // NewNodeHandler creates a new NodeHandler
-func NewNodeHandler(db *database.DB) *NodeHandler {
-	return &NodeHandler{DB: db}
+func NewNodeHandler(db *database.DB, hub *realtime.Hub) *NodeHandler {
+	return &NodeHandler{DB: db, Hub: hub}
 }
 
 // CreateNode handles POST /api/nodes
@@ -42,6 +49,27 @@ func (h *NodeHandler) CreateNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Start from the node template, if one was given
+	if req.TemplateID != "" {
+		if _, err := uuid.Parse(req.TemplateID); err != nil {
+			http.Error(w, "Invalid node template ID", http.StatusBadRequest)
+			return
+		}
+		template, err := h.DB.GetNodeTemplate(req.TemplateID, userID)
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "Node template not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get node template: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if err := applyNodeTemplate(&req, template); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Validate request
 	if req.MindMapID == "" {
 		http.Error(w, "Mind map ID is required", http.StatusBadRequest)
@@ -51,17 +79,42 @@ func (h *NodeHandler) CreateNode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Content is required", http.StatusBadRequest)
 		return
 	}
+	if err := validateNodeIconAndPriority(req.Icon, req.Priority); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(req.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(req.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if !checkNodeCustomFields(w, h.DB, mindMap.ID, req.Metadata) {
+		return
+	}
+
+	// Record the author server-side and tie the node to any running session
+	req.CreatedBy = userID
+	session, err := h.DB.GetActiveSession(mindMap.ID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if session != nil {
+		req.SessionID = session.ID
+		if session.Anonymous {
+			req.Anonymous = true
+		}
+	}
+	if req.Anonymous && (session == nil || !session.Anonymous) {
+		http.Error(w, "Anonymous contributions are only allowed during an anonymous session", http.StatusBadRequest)
+		return
+	}
 
 	// Create node
 	node, err := h.DB.CreateNode(req)
@@ -70,13 +123,17 @@ func (h *NodeHandler) CreateNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	node.MaskAttribution()
+
 	// Return created node
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(node)
 }
 
-// GetNodesByMindMap handles GET /api/mindmaps/{id}/nodes
+// GetNodesByMindMap handles GET /api/mindmaps/{id}/nodes. ?priority=high,urgent keeps the
+// nodes with one of the listed priorities, "none" matching nodes without one, and
+// ?sort=priority lists the most urgent first
 func (h *NodeHandler) GetNodesByMindMap(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -97,6 +154,23 @@ func (h *NodeHandler) GetNodesByMindMap(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Validate filter and sort options
+	var priorities []string
+	if value := r.URL.Query().Get("priority"); value != "" {
+		priorities = strings.Split(value, ",")
+		for _, priority := range priorities {
+			if priority != "none" && (priority == "" || !models.ValidNodePriority(priority)) {
+				http.Error(w, "Priority must be 'low', 'medium', 'high', 'urgent' or 'none'", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "priority" {
+		http.Error(w, "Sort must be 'priority'", http.StatusBadRequest)
+		return
+	}
+
 	// Get user ID from context
 	userID, ok := r.Context().Value("userID").(string)
 	if !ok {
@@ -105,24 +179,43 @@ func (h *NodeHandler) GetNodesByMindMap(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID && !mindMap.IsPublic {
+	if !canViewMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get nodes
-	nodes, err := h.DB.GetNodesByMindMapID(mindMapID)
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if !applyNodeRollups(w, h.DB, mindMapID, nodes) {
+		return
+	}
 
-	// Return nodes
+	// Filter and sort after rollups so parents still count their filtered-out children
+	if priorities != nil {
+		nodes = filterNodesByPriority(nodes, priorities)
+	}
+	if sortBy == "priority" {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return models.NodePriorityRank(nodes[i].Priority) > models.NodePriorityRank(nodes[j].Priority)
+		})
+	}
+
+	// Return nodes, in compact form when the lite profile is requested
+	if wantsLiteProfile(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toLiteNodes(nodes))
+		return
+	}
+	models.MaskNodeAttribution(nodes)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(nodes)
 }
@@ -155,24 +248,45 @@ func (h *NodeHandler) GetNode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get node
-	node, err := h.DB.GetNodeByID(nodeID)
+	node, err := tenantDB(h.DB, r).GetNodeByID(nodeID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(node.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID && !mindMap.IsPublic {
+	if !canViewMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	// Compute the node's rollup fields, which needs the rest of its map
+	schema, err := h.DB.GetMindMapCustomFields(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get custom fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if fields.HasRollups(schema) {
+		nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMap.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		fields.ApplyRollups(schema, nodes)
+		for _, mapNode := range nodes {
+			if mapNode.ID == node.ID {
+				node.Rollups = mapNode.Rollups
+			}
+		}
+	}
+
 	// Return node
+	node.MaskAttribution()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(node)
 }
@@ -205,19 +319,19 @@ func (h *NodeHandler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get node
-	node, err := h.DB.GetNodeByID(nodeID)
+	node, err := tenantDB(h.DB, r).GetNodeByID(nodeID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(node.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -228,9 +342,29 @@ func (h *NodeHandler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.Icon != nil || req.Priority != nil {
+		var icon, priority string
+		if req.Icon != nil {
+			icon = *req.Icon
+		}
+		if req.Priority != nil {
+			priority = *req.Priority
+		}
+		if err := validateNodeIconAndPriority(icon, priority); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !checkNodeCustomFields(w, h.DB, node.MindMapID, req.Metadata) {
+		return
+	}
 
 	// Update node
 	if err := h.DB.UpdateNode(nodeID, req); err != nil {
+		if errors.Is(err, database.ErrWriteConflict) {
+			h.rejectConflictingUpdate(w, node.MindMapID, nodeID, userID, req)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to update node: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -240,7 +374,34 @@ func (h *NodeHandler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Node updated successfully"})
 }
 
-// DeleteNode handles DELETE /api/nodes/{id}
+// rejectConflictingUpdate answers an update that lost an optimistic concurrency check. The
+// current node is sent as a node.conflict realtime event, so an editor that autosaved in the
+// background learns about it, and in the 409 response body
+func (h *NodeHandler) rejectConflictingUpdate(w http.ResponseWriter, mindMapID, nodeID, userID string, req models.NodeUpdateRequest) {
+	current, err := h.DB.GetNodeByID(nodeID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
+		return
+	}
+	current.MaskAttribution()
+
+	conflict := models.WriteConflict{
+		Entity:   "node",
+		EntityID: nodeID,
+		UserID:   userID,
+		Current:  current,
+		Rejected: req,
+	}
+	h.Hub.Publish(mindMapID, "node.conflict", conflict)
+
+	// Return conflict with the current version
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(conflict)
+}
+
+// DeleteNode handles DELETE /api/nodes/{id}. With ?dry_run=true it only reports the nodes,
+// edges, votes and links the delete would remove
 func (h *NodeHandler) DeleteNode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -268,23 +429,38 @@ func (h *NodeHandler) DeleteNode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get node
-	node, err := h.DB.GetNodeByID(nodeID)
+	node, err := tenantDB(h.DB, r).GetNodeByID(nodeID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Check if user has access to the mind map
-	mindMap, err := h.DB.GetMindMapByID(node.MindMapID)
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if mindMap.UserID != userID {
+	if !canEditMindMap(h.DB, mindMap, userID) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	// Report what the delete would cascade to without deleting anything
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		preview, err := h.DB.PreviewDeleteNode(nodeID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to preview node delete: %v", err), http.StatusInternalServerError)
+			return
+		}
+		preview.DryRun = true
+
+		// Return delete preview
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preview)
+		return
+	}
+
 	// Delete node
 	if err := h.DB.DeleteNode(nodeID); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete node: %v", err), http.StatusInternalServerError)
@@ -324,28 +500,26 @@ func (h *NodeHandler) BatchUpdateNodePositions(w http.ResponseWriter, r *http.Re
 	}
 
 	// Get the first node to check mind map ownership
-	if len(req.Positions) > 0 {
-		firstNodeID := req.Positions[0].ID
-		node, err := h.DB.GetNodeByID(firstNodeID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
-			return
-		}
+	firstNodeID := req.Positions[0].ID
+	node, err := tenantDB(h.DB, r).GetNodeByID(firstNodeID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-		// Check if user has access to the mind map
-		mindMap, err := h.DB.GetMindMapByID(node.MindMapID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
-			return
-		}
-		if mindMap.UserID != userID {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
 	// Update node positions
-	if err := h.DB.BatchUpdateNodePositions(req.Positions); err != nil {
+	if err := h.DB.BatchUpdateNodePositions(mindMap.ID, req.Positions); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update node positions: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -354,3 +528,36 @@ func (h *NodeHandler) BatchUpdateNodePositions(w http.ResponseWriter, r *http.Re
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Node positions updated successfully"})
 }
+
+// validateNodeIconAndPriority checks the icon and priority given for a node; empty values
+// clear them
+func validateNodeIconAndPriority(icon, priority string) error {
+	if icon != "" {
+		if err := validation.ValidateEmoji(icon); err != nil {
+			return err
+		}
+	}
+	if !models.ValidNodePriority(priority) {
+		return fmt.Errorf("priority must be one of 'low', 'medium', 'high' or 'urgent'")
+	}
+	return nil
+}
+
+// filterNodesByPriority keeps the nodes with one of the given priorities, where "none"
+// stands for nodes without one
+func filterNodesByPriority(nodes []models.Node, priorities []string) []models.Node {
+	wanted := make(map[string]bool, len(priorities))
+	for _, priority := range priorities {
+		if priority == "none" {
+			priority = ""
+		}
+		wanted[priority] = true
+	}
+	filtered := make([]models.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if wanted[node.Priority] {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
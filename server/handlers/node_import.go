@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"saas-server/models"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxImportRows caps how many nodes a single CSV import may create
+	maxImportRows = 1000
+	// maxImportBytes caps the size of an uploaded CSV file
+	maxImportBytes = 5 << 20
+	// maxImportContentLength caps the length of a single imported node's content
+	maxImportContentLength = 5000
+)
+
+// importColumnAliases lists the header names recognised for each import field, in order of preference
+var importColumnAliases = map[string][]string{
+	"content": {"content", "text", "title", "idea"},
+	"ref":     {"id", "ref", "key"},
+	"parent":  {"parent_id", "parent", "parent_ref"},
+	"type":    {"node_type", "type"},
+	"tags":    {"tags", "tag"},
+}
+
+// ImportNodesCSV handles POST /api/mindmaps/{id}/import/csv. The CSV must have a header row;
+// columns are matched by name or chosen with the content_column, ref_column, parent_column,
+// type_column and tags_column query parameters. With dry_run=true nothing is written and
+// the response previews the nodes that would be created
+func (h *NodeHandler) ImportNodesCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/import/csv")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user can edit the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	// Read the CSV either from a multipart "file" field or from the raw request body
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "CSV file is required in the 'file' field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid CSV: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(records) < 2 {
+		http.Error(w, "CSV must contain a header row and at least one data row", http.StatusBadRequest)
+		return
+	}
+	if len(records)-1 > maxImportRows {
+		http.Error(w, fmt.Sprintf("CSV cannot contain more than %d rows", maxImportRows), http.StatusBadRequest)
+		return
+	}
+
+	// Map header columns to node fields
+	columns, err := resolveImportColumns(records[0], r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Existing nodes can be referenced as parents by ID
+	existing, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	result := models.NodeImportResult{
+		DryRun:  dryRun,
+		Columns: make(map[string]string),
+		Nodes:   []models.NodeImportRow{},
+		Errors:  []models.NodeImportError{},
+	}
+	for field, index := range columns {
+		result.Columns[field] = records[0][index]
+	}
+
+	rows, importErrors := buildImportRows(records, columns, existing)
+	result.Errors = append(result.Errors, importErrors...)
+	if len(result.Errors) == 0 {
+		rows, importErrors = orderImportRows(rows)
+		result.Errors = append(result.Errors, importErrors...)
+	}
+
+	if len(result.Errors) > 0 {
+		result.Nodes = rows
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	layoutImportRows(rows, existing)
+
+	// Create nodes unless this is a preview
+	if !dryRun {
+		if err := h.DB.ImportNodes(mindMapID, userID, rows); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to import nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.Created = len(rows)
+	}
+	result.Nodes = rows
+
+	// Return import result
+	w.Header().Set("Content-Type", "application/json")
+	if !dryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// resolveImportColumns finds the column index of each import field, honouring explicit
+// <field>_column query parameters before falling back to well-known header names
+func resolveImportColumns(header []string, r *http.Request) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for field, aliases := range importColumnAliases {
+		if override := r.URL.Query().Get(field + "_column"); override != "" {
+			i, ok := index[strings.ToLower(strings.TrimSpace(override))]
+			if !ok {
+				return nil, fmt.Errorf("Column %q not found in CSV header", override)
+			}
+			columns[field] = i
+			continue
+		}
+		for _, alias := range aliases {
+			if i, ok := index[alias]; ok {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	if _, ok := columns["content"]; !ok {
+		return nil, errors.New("CSV must have a content column")
+	}
+	return columns, nil
+}
+
+// buildImportRows validates each record and resolves parent references. A parent reference
+// may name another row's ref, the content of another row, or the ID of an existing node
+func buildImportRows(records [][]string, columns map[string]int, existing []models.Node) ([]models.NodeImportRow, []models.NodeImportError) {
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	existingIDs := make(map[string]bool, len(existing))
+	for _, node := range existing {
+		existingIDs[node.ID] = true
+	}
+
+	var rows []models.NodeImportRow
+	var importErrors []models.NodeImportError
+	refs := make(map[string]int)
+	contents := make(map[string]int)
+
+	for i, record := range records[1:] {
+		rowNumber := i + 2 // 1-based, counting the header row
+		row := models.NodeImportRow{
+			Row:       rowNumber,
+			Ref:       field(record, "ref"),
+			Content:   field(record, "content"),
+			ParentRef: field(record, "parent"),
+			NodeType:  strings.ToLower(field(record, "type")),
+			Tags:      splitImportTags(field(record, "tags")),
+		}
+
+		if row.Content == "" {
+			importErrors = append(importErrors, models.NodeImportError{Row: rowNumber, Message: "Content is required"})
+		} else if len(row.Content) > maxImportContentLength {
+			importErrors = append(importErrors, models.NodeImportError{Row: rowNumber, Message: fmt.Sprintf("Content cannot exceed %d characters", maxImportContentLength)})
+		}
+		if row.NodeType == "" {
+			row.NodeType = "idea"
+		}
+		if row.Ref != "" {
+			if other, ok := refs[row.Ref]; ok {
+				importErrors = append(importErrors, models.NodeImportError{Row: rowNumber, Message: fmt.Sprintf("Duplicate id %q, already used on row %d", row.Ref, other)})
+			} else {
+				refs[row.Ref] = rowNumber
+			}
+		}
+		if _, ok := contents[row.Content]; ok {
+			contents[row.Content] = -1 // Ambiguous, cannot be used as a parent reference
+		} else {
+			contents[row.Content] = rowNumber
+		}
+
+		rows = append(rows, row)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.ParentRef == "" {
+			continue
+		}
+		if parentRow, ok := refs[row.ParentRef]; ok {
+			row.ParentRow = parentRow
+		} else if existingIDs[row.ParentRef] {
+			parentID := row.ParentRef
+			row.ParentID = &parentID
+		} else if parentRow, ok := contents[row.ParentRef]; ok && parentRow > 0 {
+			row.ParentRow = parentRow
+		} else {
+			importErrors = append(importErrors, models.NodeImportError{Row: row.Row, Message: fmt.Sprintf("Parent %q does not match any row or existing node", row.ParentRef)})
+			continue
+		}
+		if row.ParentRow == row.Row {
+			importErrors = append(importErrors, models.NodeImportError{Row: row.Row, Message: "A node cannot be its own parent"})
+		}
+	}
+
+	return rows, importErrors
+}
+
+// orderImportRows sorts rows so every parent is created before its children, reporting rows caught in cycles
+func orderImportRows(rows []models.NodeImportRow) ([]models.NodeImportRow, []models.NodeImportError) {
+	byRow := make(map[int]models.NodeImportRow, len(rows))
+	children := make(map[int][]int)
+	var queue []int
+	for _, row := range rows {
+		byRow[row.Row] = row
+		if row.ParentRow > 0 {
+			children[row.ParentRow] = append(children[row.ParentRow], row.Row)
+		} else {
+			queue = append(queue, row.Row)
+		}
+	}
+
+	ordered := make([]models.NodeImportRow, 0, len(rows))
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		ordered = append(ordered, byRow[current])
+		queue = append(queue, children[current]...)
+	}
+
+	if len(ordered) == len(rows) {
+		return ordered, nil
+	}
+
+	placed := make(map[int]bool, len(ordered))
+	for _, row := range ordered {
+		placed[row.Row] = true
+	}
+	var importErrors []models.NodeImportError
+	for _, row := range rows {
+		if !placed[row.Row] {
+			importErrors = append(importErrors, models.NodeImportError{Row: row.Row, Message: "Parent references form a cycle"})
+		}
+	}
+	return rows, importErrors
+}
+
+// layoutImportRows places imported nodes to the right of their parents, and new top-level
+// nodes in a column below the existing content of the map
+func layoutImportRows(rows []models.NodeImportRow, existing []models.Node) {
+	positions := make(map[string][2]float64, len(existing))
+	childCount := make(map[string]int)
+	bottom := 0.0
+	for _, node := range existing {
+		positions[node.ID] = [2]float64{node.PositionX, node.PositionY}
+		if node.ParentID != nil {
+			childCount[*node.ParentID]++
+		}
+		if node.PositionY > bottom {
+			bottom = node.PositionY
+		}
+	}
+	if len(existing) > 0 {
+		bottom += 150
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		key := "row:" + strconv.Itoa(row.Row)
+
+		parentKey := ""
+		if row.ParentRow > 0 {
+			parentKey = "row:" + strconv.Itoa(row.ParentRow)
+		} else if row.ParentID != nil {
+			parentKey = *row.ParentID
+		}
+
+		if parent, ok := positions[parentKey]; ok {
+			row.PositionX = parent[0] + 250
+			row.PositionY = parent[1] + float64(childCount[parentKey])*80
+			childCount[parentKey]++
+		} else {
+			row.PositionX = 0
+			row.PositionY = bottom
+			bottom += 150
+		}
+		positions[key] = [2]float64{row.PositionX, row.PositionY}
+	}
+}
+
+// splitImportTags splits a tags cell on semicolons or commas
+func splitImportTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxSummaryNodes caps how many descendants are sent to the model for a summary, nearest
+// levels first
+const maxSummaryNodes = 300
+
+// SummarizeNode handles POST /api/nodes/{id}/summarize. The node's descendants are sent to
+// the model as an indented outline and the summary it writes is stored, by default as a
+// summary node under the node, which a later summary refreshes. With {"target": "metadata"}
+// it is stored under "summary" in the node's metadata instead. Summary nodes are left out
+// of the outline
+func (h *NodeHandler) SummarizeNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract node ID from URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/nodes/")
+	if path == r.URL.Path || !strings.HasSuffix(path, "/summarize") {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	nodeID := strings.TrimSuffix(path, "/summarize")
+
+	// Parse node ID
+	if _, err := uuid.Parse(nodeID); err != nil {
+		http.Error(w, "Invalid node ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body, which is optional
+	var req models.NodeSummaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Target == "" {
+		req.Target = models.SummaryTargetChild
+	}
+	if req.Target != models.SummaryTargetChild && req.Target != models.SummaryTargetMetadata {
+		http.Error(w, "Target must be 'child' or 'metadata'", http.StatusBadRequest)
+		return
+	}
+
+	// Get node
+	node, err := tenantDB(h.DB, r).GetNodeByID(nodeID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user can edit the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Collect the descendants, remembering an earlier summary node to refresh
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(node.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	subtree := findOutlineItem(nodes, nodeID)
+	if subtree == nil {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+	var existing *models.Node
+	for _, child := range subtree.Children {
+		if child.Node.NodeType == models.NodeTypeSummary {
+			existing = &child.Node
+			break
+		}
+	}
+	lines, count := summaryOutline(subtree)
+	if count == 0 {
+		http.Error(w, "Node has no descendants to summarize", http.StatusBadRequest)
+		return
+	}
+
+	// Summarize with OpenAI, within the map's spend cap
+	apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+	if err == nil && apiKey == "" {
+		err = errNoAIKey
+	}
+	if err != nil {
+		sendGenerationError(w, "Failed to summarize", err)
+		return
+	}
+	model, err := enforceAISpendCap(h.DB, mindMap.ID, aiProviderOpenAI, openAIModel())
+	if err != nil {
+		sendGenerationError(w, "Failed to summarize", err)
+		return
+	}
+	redactor := piiRedactor(h.DB, mindMap)
+	text, usage, err := summarizeWithAI(r.Context(), model, apiKey, node.Content, lines, redactor)
+	recordAIUsage(h.DB, userID, mindMap.ID, aiFeatureSummarize, usage)
+	if err != nil {
+		sendAIError(w, "Failed to summarize", err)
+		return
+	}
+	summary := models.NodeSummary{Summary: text, NodeCount: count, Model: model, CreatedAt: time.Now()}
+	result := models.NodeSummaryResult{NodeID: nodeID, Target: req.Target, Summary: summary}
+
+	// Store the summary
+	switch {
+	case req.Target == models.SummaryTargetMetadata:
+		result.Node, err = h.DB.SetNodeSummary(nodeID, summary)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save summary: %v", err), http.StatusInternalServerError)
+			return
+		}
+	case existing != nil:
+		if err := h.DB.UpdateNode(existing.ID, models.NodeUpdateRequest{Content: text}); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save summary: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.Node, err = h.DB.GetNodeByID(existing.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
+			return
+		}
+	default:
+		x, y := nextChildPosition(nodes, node)
+		result.Node, err = h.DB.CreateNode(models.NodeCreateRequest{
+			MindMapID: mindMap.ID,
+			ParentID:  &node.ID,
+			Content:   text,
+			PositionX: x,
+			PositionY: y,
+			NodeType:  models.NodeTypeSummary,
+			CreatedBy: userID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save summary: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.Edge, err = h.DB.CreateEdge(models.EdgeCreateRequest{
+			MindMapID: mindMap.ID,
+			SourceID:  node.ID,
+			TargetID:  result.Node.ID,
+			EdgeType:  "default",
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to create edge: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.Created = true
+	}
+
+	// Return summary
+	status := http.StatusOK
+	if result.Created {
+		status = http.StatusCreated
+	}
+	sendJSONResponse(w, status, result)
+}
+
+// findOutlineItem returns the outline item of a node with its descendants, or nil if the
+// node is not among nodes
+func findOutlineItem(nodes []models.Node, nodeID string) *outline.Item {
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil {
+		return nil
+	}
+	for _, item := range outline.Flatten(roots) {
+		if item.Node.ID == nodeID {
+			return item
+		}
+	}
+	return nil
+}
+
+// summaryOutline lists the descendants of an item as an indented outline, level by level
+// up to maxSummaryNodes of them, leaving out summary nodes and their branches. Returns
+// the lines in outline order and how many descendants they cover
+func summaryOutline(item *outline.Item) ([]string, int) {
+	// Pick the descendants nearest the item first, so a large subtree keeps its shape
+	included := make(map[string]bool)
+	level := item.Children
+	for len(level) > 0 && len(included) < maxSummaryNodes {
+		var next []*outline.Item
+		for _, child := range level {
+			if child.Node.NodeType == models.NodeTypeSummary || len(included) == maxSummaryNodes {
+				continue
+			}
+			included[child.Node.ID] = true
+			next = append(next, child.Children...)
+		}
+		level = next
+	}
+
+	var lines []string
+	var walk func(children []*outline.Item, depth int)
+	walk = func(children []*outline.Item, depth int) {
+		for _, child := range children {
+			if !included[child.Node.ID] {
+				continue
+			}
+			lines = append(lines, strings.Repeat("  ", depth)+"- "+prompt.Line(child.Node.Content))
+			walk(child.Children, depth+1)
+		}
+	}
+	walk(item.Children, 0)
+	return lines, len(included)
+}
+
+// summarizeWithAI asks the model for a concise summary of the outline under a node, with
+// the node's content and the outline redacted, and returns it with any redacted personal
+// data put back
+func summarizeWithAI(ctx context.Context, model, apiKey, topic string, lines []string, redactor *pii.Redactor) (string, aiUsage, error) {
+	input := fmt.Sprintf("Node: %s\nBranches:\n%s",
+		prompt.Line(redactor.Redact(topic)),
+		prompt.Limit(redactor.Redact(strings.Join(lines, "\n")), prompt.MaxContextLength))
+
+	content, usage, err := openAIEndpoint(model).complete(
+		ctx,
+		apiKey,
+		prompt.System("You summarize a branch of a mind map. You are given the branch's node and the nodes under it as an indented outline. Write a concise summary of at most four sentences that captures the main themes and how they relate, without listing every node. Respond with the summary only, as plain text."),
+		prompt.Delimit(input),
+		400,
+	)
+	if err != nil {
+		return "", usage, err
+	}
+	summary := strings.TrimSpace(redactor.Restore(content))
+	if summary == "" {
+		return "", usage, fmt.Errorf("empty summary")
+	}
+	return summary, usage, nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxBulkTagNodes caps how many nodes a single bulk tag request may name
+const maxBulkTagNodes = 1000
+
+// maxTagLength caps the length of a tag in characters
+const maxTagLength = 50
+
+// AddNodeTag handles POST /api/mindmaps/{id}/tags/add, adding a tag to a set of nodes
+func (h *NodeHandler) AddNodeTag(w http.ResponseWriter, r *http.Request) {
+	h.bulkNodeTags(w, r, "/tags/add", h.DB.AddNodeTag)
+}
+
+// RemoveNodeTag handles POST /api/mindmaps/{id}/tags/remove, removing a tag from a set of nodes
+func (h *NodeHandler) RemoveNodeTag(w http.ResponseWriter, r *http.Request) {
+	h.bulkNodeTags(w, r, "/tags/remove", h.DB.RemoveNodeTag)
+}
+
+// RenameTag handles POST /api/mindmaps/{id}/tags/rename, renaming or merging a tag map-wide
+func (h *NodeHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	mindMapID, ok := h.tagMindMap(w, r, "/tags/rename")
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.TagRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	from, err := validateTag(req.From)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid from tag: %v", err), http.StatusBadRequest)
+		return
+	}
+	to, err := validateTag(req.To)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid to tag: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Rename tag
+	nodeIDs, err := h.DB.RenameTag(mindMapID, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to rename tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeTagOperationResult(w, mindMapID, nodeIDs)
+}
+
+// bulkNodeTags applies a tag operation to the nodes named in the request body
+func (h *NodeHandler) bulkNodeTags(w http.ResponseWriter, r *http.Request, suffix string, apply func(mindMapID string, nodeIDs []string, tag string) ([]string, error)) {
+	mindMapID, ok := h.tagMindMap(w, r, suffix)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.NodeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	tag, err := validateTag(req.Tag)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid tag: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.NodeIDs) == 0 || len(req.NodeIDs) > maxBulkTagNodes {
+		http.Error(w, fmt.Sprintf("node_ids must list between 1 and %d nodes", maxBulkTagNodes), http.StatusBadRequest)
+		return
+	}
+	for _, nodeID := range req.NodeIDs {
+		if _, err := uuid.Parse(nodeID); err != nil {
+			http.Error(w, "Invalid node ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Apply tag operation
+	nodeIDs, err := apply(mindMapID, req.NodeIDs, tag)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeTagOperationResult(w, mindMapID, nodeIDs)
+}
+
+// tagMindMap extracts the mind map ID of a tag operation and checks that the user can edit
+// the map. It writes the error response and reports false when the request cannot proceed
+func (h *NodeHandler) tagMindMap(w http.ResponseWriter, r *http.Request, suffix string) (string, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", false
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, suffix)
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return "", false
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return "", false
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+
+	// Check if user can edit the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return "", false
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+
+	return mindMapID, true
+}
+
+// writeTagOperationResult returns the nodes a tag operation changed
+func (h *NodeHandler) writeTagOperationResult(w http.ResponseWriter, mindMapID string, nodeIDs []string) {
+	// Return changed nodes
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TagOperationResult{
+		MindMapID: mindMapID,
+		NodeIDs:   nodeIDs,
+		Updated:   len(nodeIDs),
+	})
+}
+
+// validateTag trims a tag and checks it can be stored and round-tripped through imports,
+// which split tags on commas and semicolons
+func validateTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", fmt.Errorf("tag is required")
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("tag must be at most %d characters", maxTagLength)
+	}
+	if strings.ContainsAny(tag, ",;") {
+		return "", fmt.Errorf("tag must not contain commas or semicolons")
+	}
+	return tag, nil
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// defaultNodeType is the node type templates get when none is given
+const defaultNodeType = "default"
+
+// NodeTemplateHandler handles node template requests
+type NodeTemplateHandler struct {
+	DB *database.DB
+}
+
+// NewNodeTemplateHandler creates a new NodeTemplateHandler
+func NewNodeTemplateHandler(db *database.DB) *NodeTemplateHandler {
+	return &NodeTemplateHandler{DB: db}
+}
+
+// GetNodeTemplates handles GET /api/node-templates
+func (h *NodeTemplateHandler) GetNodeTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templates, err := h.DB.GetNodeTemplatesByUserID(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return node templates
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// CreateNodeTemplate handles POST /api/node-templates
+func (h *NodeTemplateHandler) CreateNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.NodeTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateNodeTemplate(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid node template: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.DB.CreateNodeTemplate(userID, req)
+	if errors.Is(err, database.ErrNodeTemplateNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create node template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created node template
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// GetNodeTemplate handles GET /api/node-templates/{id}
+func (h *NodeTemplateHandler) GetNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	template, ok := h.loadNodeTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	// Return node template
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// UpdateNodeTemplate handles PUT /api/node-templates/{id}
+func (h *NodeTemplateHandler) UpdateNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	template, ok := h.loadNodeTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body; fields left out keep their current value
+	req := models.NodeTemplateRequest{
+		Name:        template.Name,
+		Description: template.Description,
+		Content:     template.Content,
+		NodeType:    template.NodeType,
+		StyleData:   template.StyleData,
+		Metadata:    template.Metadata,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateNodeTemplate(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid node template: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.DB.UpdateNodeTemplate(template.ID, template.UserID, req)
+	if errors.Is(err, database.ErrNodeTemplateNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update node template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated node template
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteNodeTemplate handles DELETE /api/node-templates/{id}
+func (h *NodeTemplateHandler) DeleteNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	template, ok := h.loadNodeTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.DeleteNodeTemplate(template.ID, template.UserID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete node template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Node template deleted successfully"})
+}
+
+// loadNodeTemplate extracts the node template ID from the URL and loads the user's template,
+// writing an error response and returning false if that fails
+func (h *NodeTemplateHandler) loadNodeTemplate(w http.ResponseWriter, r *http.Request) (*models.NodeTemplate, bool) {
+	// Extract node template ID from URL
+	templateID := strings.TrimPrefix(r.URL.Path, "/api/node-templates/")
+	if templateID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Parse node template ID
+	if _, err := uuid.Parse(templateID); err != nil {
+		http.Error(w, "Invalid node template ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	template, err := h.DB.GetNodeTemplate(templateID, userID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Node template not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node template: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return template, true
+}
+
+// validateNodeTemplate trims and checks a node template request, filling in defaults
+func validateNodeTemplate(req *models.NodeTemplateRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.NodeType = strings.TrimSpace(req.NodeType)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if len(req.NodeType) > 50 {
+		return fmt.Errorf("node_type must be at most 50 characters")
+	}
+	if req.NodeType == "" {
+		req.NodeType = defaultNodeType
+	}
+	if _, err := jsonObjectFields(req.StyleData); err != nil {
+		return fmt.Errorf("style_data must be a JSON object")
+	}
+	if _, err := jsonObjectFields(req.Metadata); err != nil {
+		return fmt.Errorf("metadata must be a JSON object")
+	}
+	return nil
+}
+
+// applyNodeTemplate fills a node create request from a template. Type and content come from
+// the template when the request leaves them empty, and style data and metadata start from the
+// template's fields with the request's fields layered on top
+func applyNodeTemplate(req *models.NodeCreateRequest, template *models.NodeTemplate) error {
+	if req.NodeType == "" {
+		req.NodeType = template.NodeType
+	}
+	if req.Content == "" {
+		req.Content = template.Content
+	}
+
+	var err error
+	if req.StyleData, err = mergeJSONObjects(template.StyleData, req.StyleData); err != nil {
+		return fmt.Errorf("style_data must be a JSON object")
+	}
+	if req.Metadata, err = mergeJSONObjects(template.Metadata, req.Metadata); err != nil {
+		return fmt.Errorf("metadata must be a JSON object")
+	}
+	return nil
+}
+
+// jsonObjectFields decodes a JSON object, treating missing data as an empty object
+func jsonObjectFields(data json.RawMessage) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(data) == 0 || string(data) == "null" {
+		return fields, nil
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+	return fields, nil
+}
+
+// mergeJSONObjects returns base with the top-level fields of override replacing its own
+func mergeJSONObjects(base, override json.RawMessage) (json.RawMessage, error) {
+	merged, err := jsonObjectFields(base)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := jsonObjectFields(override)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// aiProviderOllama is a self-hosted Ollama instance, configured with OLLAMA_BASE_URL
+const aiProviderOllama = "ollama"
+
+// defaultOllamaModel is the model used unless OLLAMA_MODEL overrides it
+const defaultOllamaModel = "llama3.2"
+
+// ollamaBaseURL returns the address of the configured Ollama instance, or an empty string
+// when there is none
+func ollamaBaseURL() string {
+	return strings.TrimRight(os.Getenv("OLLAMA_BASE_URL"), "/")
+}
+
+// ollamaModel returns the model Ollama requests are made with by default
+func ollamaModel() string {
+	if model := os.Getenv("OLLAMA_MODEL"); model != "" {
+		return model
+	}
+	return defaultOllamaModel
+}
+
+// ollamaJSON returns a function that sends prompts to the given model on the configured
+// Ollama instance and returns a reply that is JSON following a schema, and its usage
+func ollamaJSON(model string) func(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+	return func(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+		return ollamaStructured(ctx, model, apiKey, systemPrompt, userPrompt, maxTokens, schema)
+	}
+}
+
+// ollamaStructured sends a system and user prompt to a model through the chat API of the
+// configured Ollama instance, constraining the reply to JSON following the schema, and
+// returns the reply and the usage of the request. Ollama needs no API key; one given, such
+// as OLLAMA_API_KEY for an instance behind an authenticating proxy, is sent as a bearer
+// token
+func ollamaStructured(ctx context.Context, model, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+	baseURL := ollamaBaseURL()
+	if baseURL == "" {
+		return "", aiUsage{}, fmt.Errorf("OLLAMA_BASE_URL is not set")
+	}
+
+	// Prepare the Ollama API request
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{
+				"role":    "system",
+				"content": systemPrompt,
+			},
+			{
+				"role":    "user",
+				"content": userPrompt,
+			},
+		},
+		"format": schema.schema,
+		"stream": false,
+		"options": map[string]interface{}{
+			"temperature": 0.7,
+			"num_predict": maxTokens,
+		},
+	})
+	if err != nil {
+		return "", aiUsage{}, err
+	}
+
+	// Make the API request
+	client := &http.Client{}
+	ctx, cancel := aiContext(ctx)
+	defer cancel()
+	apiReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/chat", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", aiUsage{}, err
+	}
+
+	apiReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		apiReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	resp, err := doAIRequest(client, apiReq)
+	if err != nil {
+		return "", aiUsage{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", aiUsage{}, &apiError{provider: "Ollama", status: resp.Status, statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	// Parse the response
+	var apiResp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return "", aiUsage{}, err
+	}
+
+	usage := aiUsage{
+		provider:         aiProviderOllama,
+		model:            model,
+		promptTokens:     apiResp.PromptEvalCount,
+		completionTokens: apiResp.EvalCount,
+		latency:          time.Since(start),
+	}
+
+	if apiResp.Message.Content == "" {
+		return "", usage, fmt.Errorf("no ideas generated")
+	}
+
+	return apiResp.Message.Content, usage, nil
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"saas-server/database"
+	"strings"
+	"time"
+)
+
+// resolveOpenAIKey picks the OpenAI API key for a request: an explicitly provided key wins,
+// then the user's stored key, falling back to the server-wide key. Returns an
+// aiPolicyError if the AI usage policy rules out OpenAI or the provided key
+func resolveOpenAIKey(db *database.DB, userID, override string) (string, error) {
+	return resolveProviderKey(db, userID, aiProviderOpenAI, override)
+}
+
+// resolveProviderKey picks the API key of a provider for a request: an explicitly provided
+// key wins, then the user's key stored for the provider's service, falling back to the
+// server-wide <PROVIDER>_API_KEY. Returns an aiPolicyError if the AI usage policy rules
+// out the provider or the provided key
+func resolveProviderKey(db *database.DB, userID, provider, override string) (string, error) {
+	policy, err := loadAIPolicy(db, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.checkProvider(provider); err != nil {
+		return "", err
+	}
+	if err := policy.checkInlineKey(override); err != nil {
+		return "", err
+	}
+
+	if override != "" {
+		return override, nil
+	}
+
+	if userID != "" {
+		userAPIKey, err := db.GetDecryptedAPIKey(userID, provider)
+		if err == nil && userAPIKey != "" {
+			return userAPIKey, nil
+		}
+	}
+
+	return os.Getenv(strings.ToUpper(provider) + "_API_KEY"), nil
+}
+
+// defaultOpenAIModel is the model OpenAI chat completions are made with unless the request
+// or OPENAI_MODEL names one
+const defaultOpenAIModel = "gpt-4o-mini"
+
+// openAIModel returns the model OpenAI chat completions are made with by default
+func openAIModel() string {
+	if model := os.Getenv("OPENAI_MODEL"); model != "" {
+		return model
+	}
+	return defaultOpenAIModel
+}
+
+// chatEndpoint is an OpenAI-compatible chat completions API: OpenAI itself or a gateway
+// such as OpenRouter
+type chatEndpoint struct {
+	name     string // Used in error messages
+	provider string // Recorded with usage
+	url      string
+	model    string
+	headers  map[string]string // Extra headers sent with every request
+}
+
+// jsonSchema describes the JSON object a structured reply must be. Every property of its
+// objects is required and no others are allowed, as strict structured outputs demand
+type jsonSchema struct {
+	name        string
+	description string
+	schema      map[string]interface{}
+}
+
+// apiError is an error response from an AI provider's API
+type apiError struct {
+	provider   string
+	status     string
+	statusCode int
+	body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s API error: %s - %s", e.provider, e.status, e.body)
+}
+
+// chatMessage is the reply message of a chat completion
+type chatMessage struct {
+	Content   string `json:"content"`
+	ToolCalls []struct {
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// openAIChatCompletion sends a system and user prompt to the OpenAI chat completions API
+// with the default model and returns the content of the first choice and its usage
+func openAIChatCompletion(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int) (string, aiUsage, error) {
+	return openAIEndpoint(openAIModel()).complete(ctx, apiKey, systemPrompt, userPrompt, maxTokens)
+}
+
+// openAIEndpoint returns the OpenAI chat completions API for the given model
+func openAIEndpoint(model string) chatEndpoint {
+	return chatEndpoint{name: "OpenAI", provider: aiProviderOpenAI, url: "https://api.openai.com/v1/chat/completions", model: model}
+}
+
+// complete sends a system and user prompt to the endpoint and returns the content of the
+// first choice and its usage
+func (e chatEndpoint) complete(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int) (string, aiUsage, error) {
+	message, usage, err := e.send(ctx, apiKey, e.request(systemPrompt, userPrompt, maxTokens))
+	if err != nil {
+		return "", usage, err
+	}
+	return message.Content, usage, nil
+}
+
+// completeJSON sends a system and user prompt to the endpoint and returns a reply that is
+// JSON following the schema, and its usage, using structured outputs. Models without them
+// reject the request, which is then repeated with the reply requested as a forced function
+// call; the latency then covers both requests
+func (e chatEndpoint) completeJSON(ctx context.Context, apiKey, systemPrompt, userPrompt string, maxTokens int, schema jsonSchema) (string, aiUsage, error) {
+	start := time.Now()
+	body := e.request(systemPrompt, userPrompt, maxTokens)
+	body["response_format"] = map[string]interface{}{
+		"type": "json_schema",
+		"json_schema": map[string]interface{}{
+			"name":   schema.name,
+			"strict": true,
+			"schema": schema.schema,
+		},
+	}
+	message, usage, err := e.send(ctx, apiKey, body)
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.statusCode == http.StatusBadRequest {
+		body = e.request(systemPrompt, userPrompt, maxTokens)
+		body["tools"] = []map[string]interface{}{
+			{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        schema.name,
+					"description": schema.description,
+					"parameters":  schema.schema,
+				},
+			},
+		}
+		body["tool_choice"] = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": schema.name},
+		}
+		message, usage, err = e.send(ctx, apiKey, body)
+		if err != nil {
+			return "", usage, err
+		}
+		usage.latency = time.Since(start)
+		for _, call := range message.ToolCalls {
+			if call.Function.Name == schema.name {
+				return call.Function.Arguments, usage, nil
+			}
+		}
+		return "", usage, fmt.Errorf("%s did not call %s", e.name, schema.name)
+	}
+	if err != nil {
+		return "", usage, err
+	}
+	return message.Content, usage, nil
+}
+
+// request builds the body of a chat completions request
+func (e chatEndpoint) request(systemPrompt, userPrompt string, maxTokens int) map[string]interface{} {
+	return map[string]interface{}{
+		"model": e.model,
+		"messages": []map[string]string{
+			{
+				"role":    "system",
+				"content": systemPrompt,
+			},
+			{
+				"role":    "user",
+				"content": userPrompt,
+			},
+		},
+		"temperature": 0.7,
+		"max_tokens":  maxTokens,
+	}
+}
+
+// send makes a chat completions request and returns the message of the first choice and
+// the usage of the request. The usage is zero unless the API replied. The request is
+// cancelled along with ctx and once the AI request timeout passes
+func (e chatEndpoint) send(ctx context.Context, apiKey string, body map[string]interface{}) (*chatMessage, aiUsage, error) {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	// Make the API request
+	client := &http.Client{}
+	ctx, cancel := aiContext(ctx)
+	defer cancel()
+	apiReq, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	apiReq.Header.Set("Content-Type", "application/json")
+	apiReq.Header.Set("Authorization", "Bearer "+apiKey)
+	for name, value := range e.headers {
+		apiReq.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := doAIRequest(client, apiReq)
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, aiUsage{}, &apiError{provider: e.name, status: resp.Status, statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	// Parse the response
+	var apiResp struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	usage := aiUsage{
+		provider:         e.provider,
+		model:            e.model,
+		promptTokens:     apiResp.Usage.PromptTokens,
+		completionTokens: apiResp.Usage.CompletionTokens,
+		latency:          time.Since(start),
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return nil, usage, fmt.Errorf("no ideas generated")
+	}
+
+	return &apiResp.Choices[0].Message, usage, nil
+}
+
+// openAIEmbeddingModel is the model embeddings are made with
+const openAIEmbeddingModel = "text-embedding-3-small"
+
+// openAIEmbeddings returns an embedding vector for each input, in input order, and the
+// usage of the request
+func openAIEmbeddings(ctx context.Context, apiKey string, inputs []string) ([][]float64, aiUsage, error) {
+	// Prepare the OpenAI API request
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model": openAIEmbeddingModel,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	// Make the API request
+	client := &http.Client{}
+	ctx, cancel := aiContext(ctx)
+	defer cancel()
+	apiReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	apiReq.Header.Set("Content-Type", "application/json")
+	apiReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	start := time.Now()
+	resp, err := doAIRequest(client, apiReq)
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, aiUsage{}, &apiError{provider: "OpenAI", status: resp.Status, statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	// Parse the response
+	var apiResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	usage := aiUsage{
+		provider:     aiProviderOpenAI,
+		model:        openAIEmbeddingModel,
+		promptTokens: apiResp.Usage.PromptTokens,
+		latency:      time.Since(start),
+	}
+
+	embeddings := make([][]float64, len(inputs))
+	for _, item := range apiResp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) {
+			return nil, usage, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, usage, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+
+	return embeddings, usage, nil
+}
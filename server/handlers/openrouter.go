@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"os"
+	"regexp"
+)
+
+// aiProviderOpenRouter routes requests to many hosted models through one OpenRouter key
+const aiProviderOpenRouter = "openrouter"
+
+// defaultOpenRouterModel is the model used unless the request or OPENROUTER_MODEL names one
+const defaultOpenRouterModel = "openai/gpt-4o-mini"
+
+// openRouterModelPattern matches OpenRouter model IDs, such as "anthropic/claude-3.5-sonnet"
+// or "meta-llama/llama-3.1-8b-instruct:free"
+var openRouterModelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*/[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// openRouterModel returns the model requests are routed to by default
+func openRouterModel() string {
+	if model := os.Getenv("OPENROUTER_MODEL"); model != "" {
+		return model
+	}
+	return defaultOpenRouterModel
+}
+
+// openRouterEndpoint returns OpenRouter's OpenAI-compatible chat completions API for the
+// given model
+func openRouterEndpoint(model string) chatEndpoint {
+	headers := map[string]string{"X-Title": "IdeaVisualMap"}
+	if referer := os.Getenv("FRONTEND_URL"); referer != "" {
+		headers["HTTP-Referer"] = referer
+	}
+	return chatEndpoint{
+		name:     "OpenRouter",
+		provider: aiProviderOpenRouter,
+		url:      "https://openrouter.ai/api/v1/chat/completions",
+		model:    model,
+		headers:  headers,
+	}
+}
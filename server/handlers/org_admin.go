@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/middleware"
+	"saas-server/models"
+)
+
+// requireOrgAdmin checks that the user is an admin of an organization and returns its
+// tenant, responding with an error and returning false if not. Users of the default tenant
+// belong to no organization
+func requireOrgAdmin(db *database.DB, w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	tenantID := middleware.GetTenantID(r.Context())
+	if tenantID == "" || tenantID == database.DefaultTenantID {
+		http.Error(w, "You do not belong to an organization", http.StatusForbidden)
+		return "", false
+	}
+
+	role, err := db.GetUserTenantRole(userID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get role: %v", err), http.StatusInternalServerError)
+		return "", false
+	}
+	if role != models.TenantRoleAdmin {
+		http.Error(w, "Only organization admins can do this", http.StatusForbidden)
+		return "", false
+	}
+	return tenantID, true
+}
+
+// auditEvent describes what an organization admin is doing to their organization's data
+// for its audit log, along with the client the request came from. Details are encoded as
+// JSON
+func auditEvent(r *http.Request, tenantID, action, targetType, targetID string, details interface{}) (*models.AuditEvent, error) {
+	userID, _ := r.Context().Value("userID").(string)
+	userAgent, ipAddress := getDeviceInfo(r)
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuditEvent{
+		TenantID:   tenantID,
+		ActorID:    &userID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    data,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+	}, nil
+}
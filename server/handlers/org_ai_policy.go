@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strings"
+)
+
+// AIPolicyHandler lets organization admins manage their organization's AI usage policy
+type AIPolicyHandler struct {
+	DB *database.DB
+}
+
+// NewAIPolicyHandler creates a new AIPolicyHandler
+func NewAIPolicyHandler(db *database.DB) *AIPolicyHandler {
+	return &AIPolicyHandler{DB: db}
+}
+
+// GetPolicy handles GET /api/org/ai-policy
+func (h *AIPolicyHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	policy, err := h.DB.GetAIPolicy(tenantID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get AI usage policy: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return AI usage policy
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdatePolicy handles PUT /api/org/ai-policy
+func (h *AIPolicyHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.TenantAIPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the lists that are set, storing them lowercase as the policy compares them
+	if req.AllowedProviders != nil {
+		providers := make([]string, 0, len(req.AllowedProviders))
+		for _, provider := range req.AllowedProviders {
+			provider = strings.ToLower(strings.TrimSpace(provider))
+			if provider != aiProviderOpenAI && provider != aiProviderAnthropic && provider != aiProviderOpenRouter && provider != aiProviderOllama {
+				http.Error(w, "Providers must be 'openai', 'anthropic', 'openrouter' or 'ollama'", http.StatusBadRequest)
+				return
+			}
+			providers = append(providers, provider)
+		}
+		req.AllowedProviders = providers
+	}
+	if req.AllowedRegions != nil {
+		regions := make([]string, 0, len(req.AllowedRegions))
+		for _, region := range req.AllowedRegions {
+			region = strings.ToLower(strings.TrimSpace(region))
+			if region == "" {
+				http.Error(w, "Regions must not be empty", http.StatusBadRequest)
+				return
+			}
+			regions = append(regions, region)
+		}
+		req.AllowedRegions = regions
+	}
+
+	userID, _ := r.Context().Value("userID").(string)
+	policy, err := h.DB.SetAIPolicy(tenantID, userID, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update AI usage policy: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated AI usage policy
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/export"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Organization AI report limits
+const (
+	defaultOrgReportDays     = 30
+	maxOrgReportDays         = 366
+	defaultOrgReportTopUsers = 10
+	maxOrgReportTopUsers     = 100
+)
+
+// orgReportDateLayout is the layout of the report's from and to dates
+const orgReportDateLayout = "2006-01-02"
+
+// GetOrgAIReport handles GET /api/org/{id}/ai-report?from=2024-01-01&to=2024-01-31&top=10&format=json|csv,
+// returning the organization's AI requests, tokens and estimated cost overall, by model,
+// by day and for its top users by cost. The dates are inclusive UTC days and default to
+// the last 30 days. The CSV has a row per day, user and model, for chargeback
+func (h *UsageHandler) GetOrgAIReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract organization ID from URL
+	orgID := strings.TrimPrefix(r.URL.Path, "/api/org/")
+	orgID = strings.TrimSuffix(orgID, "/ai-report")
+	if orgID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Only admins of the organization may see its report
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+	if orgID != tenantID {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+
+	// Parse report window
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := r.URL.Query().Get("to"); value != "" {
+		var err error
+		to, err = time.Parse(orgReportDateLayout, value)
+		if err != nil {
+			http.Error(w, "to must be a date like 2024-01-31", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.AddDate(0, 0, 1-defaultOrgReportDays)
+	if value := r.URL.Query().Get("from"); value != "" {
+		var err error
+		from, err = time.Parse(orgReportDateLayout, value)
+		if err != nil {
+			http.Error(w, "from must be a date like 2024-01-01", http.StatusBadRequest)
+			return
+		}
+	}
+	if from.After(to) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) >= maxOrgReportDays*24*time.Hour {
+		http.Error(w, fmt.Sprintf("The report can cover at most %d days", maxOrgReportDays), http.StatusBadRequest)
+		return
+	}
+	top := defaultOrgReportTopUsers
+	if value := r.URL.Query().Get("top"); value != "" {
+		var err error
+		top, err = strconv.Atoi(value)
+		if err != nil || top < 1 || top > maxOrgReportTopUsers {
+			http.Error(w, fmt.Sprintf("top must be between 1 and %d", maxOrgReportTopUsers), http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatJSON
+	}
+	if format != export.FormatJSON && format != export.FormatCSV {
+		http.Error(w, "Format must be 'json' or 'csv'", http.StatusBadRequest)
+		return
+	}
+
+	// Get usage up to the end of the last day
+	buckets, err := readDB(h.DB, r).GetTenantAIUsage(tenantID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format == export.FormatCSV {
+		var buf bytes.Buffer
+		if err := export.CSV(&buf, orgAIReportRows(buckets)); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to export report: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Return report as a download
+		filename := fmt.Sprintf("ai-report-%s-to-%s.csv", from.Format(orgReportDateLayout), to.Format(orgReportDateLayout))
+		w.Header().Set("Content-Type", export.ContentTypes[export.FormatCSV])
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Write(buf.Bytes())
+		return
+	}
+
+	// Return report
+	report := buildOrgAIReport(buckets, top)
+	report.TenantID = tenantID
+	report.From = from.Format(orgReportDateLayout)
+	report.To = to.Format(orgReportDateLayout)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// buildOrgAIReport prices the usage buckets and sums them overall, by model, by day and by
+// user, keeping the top users by estimated cost
+func buildOrgAIReport(buckets []models.TenantAIUsageBucket, top int) models.OrgAIReport {
+	report := models.OrgAIReport{
+		ByModel:  []models.AIUsageModel{},
+		TopUsers: []models.AIUsageUser{},
+		ByDay:    []models.AIUsageDay{},
+	}
+	modelIndex := make(map[string]int)
+	userIndex := make(map[string]int)
+	dayIndex := make(map[string]int)
+
+	for _, bucket := range buckets {
+		totals := bucketUsage(bucket.Provider, bucket.Model, bucket.Requests, bucket.PromptTokens, bucket.CompletionTokens)
+		addUsage(&report.Totals, totals)
+
+		key := bucket.Provider + "/" + bucket.Model
+		i, ok := modelIndex[key]
+		if !ok {
+			i = len(report.ByModel)
+			modelIndex[key] = i
+			report.ByModel = append(report.ByModel, models.AIUsageModel{Provider: bucket.Provider, Model: bucket.Model})
+		}
+		addUsage(&report.ByModel[i].AIUsageTotals, totals)
+
+		i, ok = userIndex[bucket.UserID]
+		if !ok {
+			i = len(report.TopUsers)
+			userIndex[bucket.UserID] = i
+			report.TopUsers = append(report.TopUsers, models.AIUsageUser{UserID: bucket.UserID, Email: bucket.Email})
+		}
+		addUsage(&report.TopUsers[i].AIUsageTotals, totals)
+
+		i, ok = dayIndex[bucket.Date]
+		if !ok {
+			i = len(report.ByDay)
+			dayIndex[bucket.Date] = i
+			report.ByDay = append(report.ByDay, models.AIUsageDay{Date: bucket.Date})
+		}
+		addUsage(&report.ByDay[i].AIUsageTotals, totals)
+	}
+
+	for i := range report.ByModel {
+		if report.Totals.Requests > 0 {
+			report.ByModel[i].RequestShare = float64(report.ByModel[i].Requests) / float64(report.Totals.Requests)
+		}
+	}
+	sort.SliceStable(report.ByModel, func(i, j int) bool {
+		return report.ByModel[i].EstimatedCostUSD > report.ByModel[j].EstimatedCostUSD
+	})
+	sort.SliceStable(report.TopUsers, func(i, j int) bool {
+		return report.TopUsers[i].EstimatedCostUSD > report.TopUsers[j].EstimatedCostUSD
+	})
+	if len(report.TopUsers) > top {
+		report.TopUsers = report.TopUsers[:top]
+	}
+	return report
+}
+
+// orgAIReportRows lays out the usage buckets as CSV rows with a header, pricing each
+func orgAIReportRows(buckets []models.TenantAIUsageBucket) [][]string {
+	rows := [][]string{{"date", "user_id", "email", "provider", "model", "requests", "prompt_tokens", "completion_tokens", "estimated_cost_usd", "priced"}}
+	for _, bucket := range buckets {
+		totals := bucketUsage(bucket.Provider, bucket.Model, bucket.Requests, bucket.PromptTokens, bucket.CompletionTokens)
+		rows = append(rows, []string{
+			bucket.Date,
+			bucket.UserID,
+			bucket.Email,
+			bucket.Provider,
+			bucket.Model,
+			strconv.Itoa(bucket.Requests),
+			strconv.Itoa(bucket.PromptTokens),
+			strconv.Itoa(bucket.CompletionTokens),
+			strconv.FormatFloat(totals.EstimatedCostUSD, 'f', 6, 64),
+			strconv.FormatBool(totals.UnpricedRequests == 0),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"strconv"
+)
+
+// piiRedactor returns the redactor for content of the given maps that is about to be sent
+// to an AI provider, or nil when it can be sent as is. Content is redacted when the
+// AI_REDACT_PII policy is on for the whole server or any of the maps' owners turned it on.
+// The names of the maps' owners and collaborators are redacted along with emails and
+// phone numbers
+func piiRedactor(db *database.DB, mindMaps ...*models.MindMap) *pii.Redactor {
+	redact, _ := strconv.ParseBool(os.Getenv("AI_REDACT_PII"))
+	ids := make([]string, 0, len(mindMaps))
+	for _, mindMap := range mindMaps {
+		redact = redact || mindMap.RedactPII
+		ids = append(ids, mindMap.ID)
+	}
+	if !redact {
+		return nil
+	}
+
+	// Without the names, emails and phone numbers are still redacted
+	names, err := db.GetMindMapPeopleNames(ids)
+	if err != nil {
+		log.Printf("[PII] Failed to get the people of maps %v: %v", ids, err)
+	}
+	return pii.NewRedactor(names)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetPresentation handles GET /api/mindmaps/{id}/presentation
+func (h *MindMapHandler) GetPresentation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/presentation")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Slide order defaults to canvas position
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = outline.OrderPosition
+	}
+	if !outline.ValidOrder(order) {
+		http.Error(w, "Order must be one of 'position', 'created_at', 'content' or 'order'", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get nodes and arrange them into slides
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	roots, err := outline.Build(nodes, order)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slides := outline.Slides(roots)
+	if slides == nil {
+		slides = []models.PresentationSlide{}
+	}
+
+	// Return presentation
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.Presentation{
+		MindMapID: mindMapID,
+		Title:     mindMap.Title,
+		Order:     order,
+		Slides:    slides,
+	})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/proofread"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxAIProofreadNodes caps how many nodes are sent to the model for proofreading
+const maxAIProofreadNodes = 300
+
+// ProofreadMindMap handles POST /api/mindmaps/{id}/proofread. Node contents are checked by
+// the model when a key is available, falling back to the local spell checker, and the
+// suggested corrections are returned per node. With apply set they are also saved, which
+// needs edit access
+func (h *MindMapHandler) ProofreadMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/proofread")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body; an empty body uses the defaults
+	var req models.ProofreadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Engine != "" && req.Engine != models.ProofreadEngineAI && req.Engine != models.ProofreadEngineLocal {
+		http.Error(w, "engine must be ai or local", http.StatusBadRequest)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) || (req.Apply && !canEditMindMap(h.DB, mindMap, userID)) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var texts []models.Node
+	for _, node := range nodes {
+		if strings.TrimSpace(node.Content) != "" {
+			texts = append(texts, node)
+		}
+	}
+
+	report := models.ProofreadReport{
+		MindMapID:   mindMapID,
+		Engine:      models.ProofreadEngineLocal,
+		Suggestions: []models.ProofreadSuggestion{},
+	}
+
+	// The model is best effort; the local checker runs if it is unavailable or fails
+	aiDone := false
+	if req.Engine != models.ProofreadEngineLocal {
+		apiKey, err := resolveOpenAIKey(h.DB, userID, req.APIKey)
+		switch {
+		case err != nil:
+			if req.Engine == models.ProofreadEngineAI {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			log.Printf("[Proofread] Using the local checker for map %s: %v", mindMapID, err)
+		case apiKey == "" || len(texts) > maxAIProofreadNodes:
+			if req.Engine == models.ProofreadEngineAI {
+				http.Error(w, fmt.Sprintf("AI proofreading needs an OpenAI API key and at most %d nodes", maxAIProofreadNodes), http.StatusBadRequest)
+				return
+			}
+		case len(texts) > 0:
+			suggestions, usage, err := proofreadWithAI(r.Context(), apiKey, texts, piiRedactor(h.DB, mindMap))
+			recordAIUsage(h.DB, userID, mindMapID, aiFeatureProofread, usage)
+			if err != nil {
+				log.Printf("[Proofread] AI proofreading failed for map %s, using the local checker: %v", mindMapID, err)
+			} else {
+				report.Suggestions = suggestions
+				report.Engine = models.ProofreadEngineAI
+				aiDone = true
+			}
+		}
+	}
+	if !aiDone {
+		for _, node := range texts {
+			suggested, changes := proofread.Check(node.Content)
+			if len(changes) == 0 || suggested == node.Content {
+				continue
+			}
+			report.Suggestions = append(report.Suggestions, models.ProofreadSuggestion{
+				NodeID:    node.ID,
+				Original:  node.Content,
+				Suggested: suggested,
+				Changes:   changes,
+			})
+		}
+	}
+
+	if req.Apply && len(report.Suggestions) > 0 {
+		updated, err := h.DB.ApplyProofreadSuggestions(mindMapID, report.Suggestions)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to apply suggestions: %v", err), http.StatusInternalServerError)
+			return
+		}
+		applied := make(map[string]bool, len(updated))
+		for i := range updated {
+			applied[updated[i].ID] = true
+		}
+		for i := range report.Suggestions {
+			report.Suggestions[i].Applied = applied[report.Suggestions[i].NodeID]
+		}
+		report.Applied = len(updated)
+	}
+
+	// Return proofread report
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// proofreadWithAI asks the model to correct spelling and grammar node by node and maps the
+// node numbers in its answer back to node IDs. Nodes the model leaves unchanged are skipped.
+// Also returns the usage of the request
+func proofreadWithAI(ctx context.Context, apiKey string, nodes []models.Node, redactor *pii.Redactor) ([]models.ProofreadSuggestion, aiUsage, error) {
+	var list strings.Builder
+	for i, node := range nodes {
+		fmt.Fprintf(&list, "%d. %s\n", i+1, prompt.Line(redactor.Redact(node.Content)))
+	}
+
+	content, usage, err := openAIChatCompletion(
+		ctx,
+		apiKey,
+		prompt.System("You proofread the labels of a mind map. Fix spelling, grammar and punctuation only, keeping the wording, tone, language and brevity of each label. Respond only with a JSON array containing an object for each label that needs fixing, with \"node\" (the label number), \"corrected\" (the full corrected label) and \"changes\", a list of objects with \"from\", \"to\" and a short \"reason\"."),
+		prompt.Delimit(prompt.Limit(list.String(), prompt.MaxContextLength)),
+		2000,
+	)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, usage, fmt.Errorf("no JSON array in proofread response")
+	}
+
+	var corrections []struct {
+		Node      int                      `json:"node"`
+		Corrected string                   `json:"corrected"`
+		Changes   []models.ProofreadChange `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &corrections); err != nil {
+		return nil, usage, err
+	}
+
+	suggestions := []models.ProofreadSuggestion{}
+	seen := make(map[int]bool)
+	for _, correction := range corrections {
+		i := correction.Node - 1
+		if i < 0 || i >= len(nodes) || seen[i] {
+			continue
+		}
+		seen[i] = true
+		original := nodes[i].Content
+		// Labels that were flattened, filtered or shortened for the prompt can't be compared
+		// against the model's answer as written, so only those sent unchanged are. Answers
+		// with placeholders that can't be restored are dropped rather than applied
+		corrected := strings.TrimSpace(redactor.Restore(correction.Corrected))
+		if corrected == "" || corrected == original || prompt.Line(original) != original || pii.HasPlaceholder(corrected) {
+			continue
+		}
+		changes := correction.Changes
+		if changes == nil {
+			changes = []models.ProofreadChange{}
+		}
+		for j := range changes {
+			changes[j].From = redactor.Restore(changes[j].From)
+			changes[j].To = redactor.Restore(changes[j].To)
+		}
+		suggestions = append(suggestions, models.ProofreadSuggestion{
+			NodeID:    nodes[i].ID,
+			Original:  original,
+			Suggested: corrected,
+			Changes:   changes,
+		})
+	}
+	return suggestions, usage, nil
+}
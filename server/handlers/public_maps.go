@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// visibilityError is the response to an unknown visibility level
+const visibilityError = "Visibility must be one of 'private', 'link', 'workspace' or 'public'"
+
+// GetPublicMindMaps handles GET /api/mindmaps/public, the gallery of public maps, most
+// recently updated first. ?limit sets how many to return, 50 by default and at most 100.
+// Maps only visible to people with the link are not listed
+func (h *MindMapHandler) GetPublicMindMaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse limit
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 100)
+	}
+
+	// Get public mind maps
+	mindMaps, err := readDB(h.DB, r).GetPublicMindMaps(limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get public mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return mind maps
+	sendJSONResponse(w, http.StatusOK, mindMaps)
+}
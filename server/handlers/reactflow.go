@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/layout"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetReactFlow handles GET /api/mindmaps/{id}/reactflow
+func (h *MindMapHandler) GetReactFlow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/reactflow")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get mind map with details
+	mindMap, err := readDB(h.DB, r).GetMindMapWithDetails(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user has access
+	if !canViewMindMap(h.DB, &mindMap.MindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	models.MaskNodeAttribution(mindMap.Nodes)
+
+	// Return React Flow graph
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toReactFlow(mindMap))
+}
+
+// toReactFlow converts a mind map into the node and edge shapes React Flow expects, with
+// bundling hints for the cross-links of dense maps
+func toReactFlow(mindMap *models.MindMapWithDetails) models.ReactFlowGraph {
+	graph := models.ReactFlowGraph{
+		MindMap: mindMap.MindMap,
+		Nodes:   make([]models.ReactFlowNode, 0, len(mindMap.Nodes)),
+		Edges:   make([]models.ReactFlowEdge, 0, len(mindMap.Edges)),
+		Bundles: layout.Bundles(mindMap.Nodes, mindMap.Edges),
+	}
+
+	positions := make(map[string]models.ReactFlowPosition, len(mindMap.Nodes))
+	for _, node := range mindMap.Nodes {
+		position := models.ReactFlowPosition{X: node.PositionX, Y: node.PositionY}
+		positions[node.ID] = position
+
+		nodeType := node.NodeType
+		if nodeType == "" {
+			nodeType = "default"
+		}
+
+		graph.Nodes = append(graph.Nodes, models.ReactFlowNode{
+			ID:       node.ID,
+			Type:     nodeType,
+			Position: position,
+			Data: models.ReactFlowNodeData{
+				Label:     node.Content,
+				NodeType:  node.NodeType,
+				ParentID:  node.ParentID,
+				Metadata:  node.Metadata,
+				VoteCount: node.VoteCount,
+				CreatedBy: node.CreatedBy,
+				Anonymous: node.Anonymous,
+			},
+			Style: nonEmptyJSON(node.StyleData),
+		})
+	}
+
+	for _, edge := range mindMap.Edges {
+		sourceHandle, targetHandle := edgeHandles(edge, positions)
+
+		edgeType := edge.EdgeType
+		if edgeType == "" {
+			edgeType = "default"
+		}
+
+		graph.Edges = append(graph.Edges, models.ReactFlowEdge{
+			ID:           edge.ID,
+			Source:       edge.SourceID,
+			Target:       edge.TargetID,
+			SourceHandle: sourceHandle,
+			TargetHandle: targetHandle,
+			Type:         edgeType,
+			Style:        nonEmptyJSON(edge.StyleData),
+		})
+	}
+
+	return graph
+}
+
+// edgeHandles picks the source and target handles for an edge. Handles stored in the edge's
+// style data win; otherwise the sides facing each other are chosen from the node positions
+func edgeHandles(edge models.Edge, positions map[string]models.ReactFlowPosition) (string, string) {
+	var style struct {
+		SourceHandle string `json:"sourceHandle"`
+		TargetHandle string `json:"targetHandle"`
+	}
+	json.Unmarshal(edge.StyleData, &style)
+	if style.SourceHandle != "" && style.TargetHandle != "" {
+		return style.SourceHandle, style.TargetHandle
+	}
+
+	source, target := positions[edge.SourceID], positions[edge.TargetID]
+	dx, dy := target.X-source.X, target.Y-source.Y
+
+	sourceHandle, targetHandle := "right", "left"
+	switch {
+	case math.Abs(dx) >= math.Abs(dy) && dx < 0:
+		sourceHandle, targetHandle = "left", "right"
+	case math.Abs(dy) > math.Abs(dx) && dy >= 0:
+		sourceHandle, targetHandle = "bottom", "top"
+	case math.Abs(dy) > math.Abs(dx):
+		sourceHandle, targetHandle = "top", "bottom"
+	}
+
+	if style.SourceHandle != "" {
+		sourceHandle = style.SourceHandle
+	}
+	if style.TargetHandle != "" {
+		targetHandle = style.TargetHandle
+	}
+	return sourceHandle, targetHandle
+}
+
+// nonEmptyJSON returns nil for empty or empty-object JSON so it is omitted from responses
+func nonEmptyJSON(data json.RawMessage) json.RawMessage {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" || trimmed == "{}" || trimmed == "null" {
+		return nil
+	}
+	return data
+}
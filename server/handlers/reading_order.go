@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetReadingOrder handles GET /api/mindmaps/{id}/reading-order, returning the map as a
+// linear sequence of nodes with depth and branch markers for screen-reader clients
+func (h *MindMapHandler) GetReadingOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/reading-order")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Reading order defaults to canvas position
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = outline.OrderPosition
+	}
+	if !outline.ValidOrder(order) {
+		http.Error(w, "Order must be one of 'position', 'created_at', 'content' or 'order'", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get mind map with details
+	mindMap, err := readDB(h.DB, r).GetMindMapWithDetails(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user has access
+	if !canViewMindMap(h.DB, &mindMap.MindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	items, err := outline.Linearize(mindMap.Nodes, mindMap.Edges, order)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Return reading order
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ReadingOrder{
+		MindMapID: mindMapID,
+		Title:     mindMap.Title,
+		Order:     order,
+		Items:     items,
+	})
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/realtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// realtimeKeepAlive is how often an idle event stream sends a comment to keep proxies from closing it
+	realtimeKeepAlive = 30 * time.Second
+	// defaultChangesLimit is how many changes a resync returns when no limit is given
+	defaultChangesLimit = 500
+	// maxChangesLimit caps how many changes a single resync request may return
+	maxChangesLimit = 1000
+)
+
+// RealtimeHandler streams mind map events to connected clients
+type RealtimeHandler struct {
+	DB  *database.DB
+	Hub *realtime.Hub
+}
+
+// NewRealtimeHandler creates a new RealtimeHandler
+func NewRealtimeHandler(db *database.DB, hub *realtime.Hub) *RealtimeHandler {
+	return &RealtimeHandler{DB: db, Hub: hub}
+}
+
+// StreamEvents handles GET /api/mindmaps/{id}/events as a Server-Sent Events stream
+func (h *RealtimeHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/events")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := h.Hub.Subscribe(mindMapID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(realtimeKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if event.Seq > 0 {
+				fmt.Fprintf(w, "id: %d\n", event.Seq)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// GetChanges handles GET /api/mindmaps/{id}/changes?since=seq, returning the changes a client
+// missed so it can resync after detecting a gap in sequence numbers
+func (h *RealtimeHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/changes")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse resync window
+	since, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil || since < 0 {
+		http.Error(w, "since must be a non-negative sequence number", http.StatusBadRequest)
+		return
+	}
+	limit := defaultChangesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxChangesLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxChangesLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get changes, fetching one extra to know whether more remain
+	changes, err := h.DB.GetChangesSince(mindMapID, since, limit+1)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get changes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	latest, err := h.DB.GetLatestChangeSeq(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get changes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	changeLog := models.MindMapChangeLog{
+		MindMapID: mindMapID,
+		Since:     since,
+		LatestSeq: latest,
+		Changes:   changes,
+	}
+	if len(changes) > limit {
+		changeLog.Changes = changes[:limit]
+		changeLog.HasMore = true
+	}
+
+	// Return changes
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changeLog)
+}
+
+// GetSyncState handles GET /api/mindmaps/{id}/sync-state, returning the latest sequence
+// number, timestamps and counts of a map. Clients compare them with their copy to decide
+// whether to pull changes or a full refresh without downloading the map
+func (h *RealtimeHandler) GetSyncState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/sync-state")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	state, err := h.DB.GetSyncState(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get sync state: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return sync state
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"saas-server/pkg/realtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// realtimeClientMessage is a message sent by a WebSocket client
+type realtimeClientMessage struct {
+	Type  string `json:"type"`  // "resync" or "ping"
+	Since int64  `json:"since"` // Last sequence number the client applied, for resync
+}
+
+// StreamWebSocket handles GET /api/mindmaps/{id}/ws. After connecting, the server sends a
+// "hello" message carrying the latest sequence number, replays any changes after the optional
+// since query parameter, and then streams live events strictly in sequence order. A client
+// that still detects a gap can send {"type":"resync","since":<seq>} to have the window replayed
+func (h *RealtimeHandler) StreamWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/ws")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Without since, the client only wants changes from now on
+	since := int64(-1)
+	if value := r.URL.Query().Get("since"); value != "" {
+		since, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "since must be a non-negative sequence number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	server := websocket.Server{
+		Handshake: checkWebSocketOrigin,
+		Handler: func(conn *websocket.Conn) {
+			h.serveWebSocket(conn, mindMapID, since)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// serveWebSocket runs the delivery loop of a single WebSocket connection
+func (h *RealtimeHandler) serveWebSocket(conn *websocket.Conn, mindMapID string, since int64) {
+	defer conn.Close()
+
+	// Subscribe before reading the change log so nothing published in between is missed
+	events, unsubscribe := h.Hub.Subscribe(mindMapID)
+	defer unsubscribe()
+
+	latest, err := h.DB.GetLatestChangeSeq(mindMapID)
+	if err != nil {
+		log.Printf("[Realtime] Error getting latest change for map %s: %v", mindMapID, err)
+		return
+	}
+	if err := websocket.JSON.Send(conn, realtime.Event{
+		Type:      "hello",
+		MindMapID: mindMapID,
+		Payload:   map[string]int64{"latest_seq": latest},
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return
+	}
+
+	lastSent := latest
+	if since >= 0 && since < latest {
+		if lastSent, err = h.replayChanges(conn, mindMapID, since); err != nil {
+			return
+		}
+	}
+
+	// Client messages are read on their own goroutine so the loop below can also wait on events
+	done := make(chan struct{})
+	defer close(done)
+	requests := make(chan realtimeClientMessage)
+	go func() {
+		defer close(requests)
+		for {
+			var msg realtimeClientMessage
+			if err := websocket.JSON.Receive(conn, &msg); err != nil {
+				return
+			}
+			select {
+			case requests <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(realtimeKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := websocket.JSON.Send(conn, realtime.Event{Type: "ping", MindMapID: mindMapID, CreatedAt: time.Now()}); err != nil {
+				return
+			}
+		case msg, ok := <-requests:
+			if !ok {
+				return
+			}
+			switch msg.Type {
+			case "resync":
+				if msg.Since < 0 {
+					msg.Since = 0
+				}
+				if lastSent, err = h.replayChanges(conn, mindMapID, msg.Since); err != nil {
+					return
+				}
+			case "ping":
+				if err := websocket.JSON.Send(conn, realtime.Event{Type: "pong", MindMapID: mindMapID, CreatedAt: time.Now()}); err != nil {
+					return
+				}
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch {
+			case event.Seq == 0:
+				// Unsequenced events are not part of the change log
+				err = websocket.JSON.Send(conn, event)
+			case event.Seq <= lastSent:
+				// Already delivered through a replay
+				continue
+			case event.Seq == lastSent+1:
+				err = websocket.JSON.Send(conn, event)
+				lastSent = event.Seq
+			default:
+				// Events were published out of order or dropped; fill the gap from the log
+				lastSent, err = h.replayChanges(conn, mindMapID, lastSent)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// replayChanges sends every logged change after since and returns the last sequence number sent
+func (h *RealtimeHandler) replayChanges(conn *websocket.Conn, mindMapID string, since int64) (int64, error) {
+	lastSent := since
+	for {
+		changes, err := h.DB.GetChangesSince(mindMapID, lastSent, maxChangesLimit)
+		if err != nil {
+			log.Printf("[Realtime] Error replaying changes for map %s: %v", mindMapID, err)
+			return lastSent, err
+		}
+
+		for _, change := range changes {
+			if err := websocket.JSON.Send(conn, realtime.Event{
+				Type:      change.Type,
+				MindMapID: change.MindMapID,
+				Seq:       change.Seq,
+				Payload:   change.Payload,
+				CreatedAt: change.CreatedAt,
+			}); err != nil {
+				return lastSent, err
+			}
+			lastSent = change.Seq
+		}
+
+		if len(changes) < maxChangesLimit {
+			return lastSent, nil
+		}
+	}
+}
+
+// checkWebSocketOrigin only accepts WebSocket connections from the configured frontends
+func checkWebSocketOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allowed := range []string{os.Getenv("FRONTEND_URL"), os.Getenv("ADMIN_CLIENT_URL")} {
+		if allowed != "" && origin == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s not allowed", origin)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+)
+
+// Limits of the retention rules
+const (
+	maxRetentionDays    = 3650
+	maxRetentionHistory = 100000
+)
+
+// RetentionHandler lets organization admins manage their organization's retention policy
+type RetentionHandler struct {
+	DB *database.DB
+}
+
+// NewRetentionHandler creates a new RetentionHandler
+func NewRetentionHandler(db *database.DB) *RetentionHandler {
+	return &RetentionHandler{DB: db}
+}
+
+// GetPolicy handles GET /api/org/retention-policy
+func (h *RetentionHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	policy, err := h.DB.GetRetentionPolicy(tenantID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get retention policy: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return retention policy
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdatePolicy handles PUT /api/org/retention-policy
+func (h *RetentionHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireOrgAdmin(h.DB, w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.RetentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the rules that are on
+	if req.PurgeTrashAfterDays != nil && (*req.PurgeTrashAfterDays < 1 || *req.PurgeTrashAfterDays > maxRetentionDays) {
+		http.Error(w, fmt.Sprintf("purge_trash_after_days must be between 1 and %d", maxRetentionDays), http.StatusBadRequest)
+		return
+	}
+	if req.ArchiveAfterDays != nil && (*req.ArchiveAfterDays < 1 || *req.ArchiveAfterDays > maxRetentionDays) {
+		http.Error(w, fmt.Sprintf("archive_after_days must be between 1 and %d", maxRetentionDays), http.StatusBadRequest)
+		return
+	}
+	if req.HistoryDepth != nil && (*req.HistoryDepth < 1 || *req.HistoryDepth > maxRetentionHistory) {
+		http.Error(w, fmt.Sprintf("history_depth must be between 1 and %d", maxRetentionHistory), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(string)
+	policy, err := h.DB.SetRetentionPolicy(tenantID, userID, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update retention policy: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated retention policy
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/filter"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxFilterResults caps how many matching nodes a filter run returns
+const maxFilterResults = 500
+
+// SavedFilterHandler handles saved filter requests
+type SavedFilterHandler struct {
+	DB *database.DB
+}
+
+// NewSavedFilterHandler creates a new SavedFilterHandler
+func NewSavedFilterHandler(db *database.DB) *SavedFilterHandler {
+	return &SavedFilterHandler{DB: db}
+}
+
+// GetSavedFilters handles GET /api/filters
+func (h *SavedFilterHandler) GetSavedFilters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filters, err := h.DB.GetSavedFiltersByUserID(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get saved filters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return saved filters
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filters)
+}
+
+// CreateSavedFilter handles POST /api/filters
+func (h *SavedFilterHandler) CreateSavedFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.SavedFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateSavedFilter(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid saved filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.DB.CreateSavedFilter(userID, req)
+	if errors.Is(err, database.ErrSavedFilterNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create saved filter: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created saved filter
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// GetSavedFilter handles GET /api/filters/{id}
+func (h *SavedFilterHandler) GetSavedFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	saved, ok := h.loadSavedFilter(w, r, "")
+	if !ok {
+		return
+	}
+
+	// Return saved filter
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// UpdateSavedFilter handles PUT /api/filters/{id}
+func (h *SavedFilterHandler) UpdateSavedFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	saved, ok := h.loadSavedFilter(w, r, "")
+	if !ok {
+		return
+	}
+
+	// Parse request body; fields left empty keep their current value
+	var req models.SavedFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		req.Name = saved.Name
+	}
+	if req.Query == "" {
+		req.Query = saved.Query
+	}
+	if err := validateSavedFilter(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid saved filter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.DB.UpdateSavedFilter(saved.ID, saved.UserID, req)
+	if errors.Is(err, database.ErrSavedFilterNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update saved filter: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated saved filter
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteSavedFilter handles DELETE /api/filters/{id}
+func (h *SavedFilterHandler) DeleteSavedFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	saved, ok := h.loadSavedFilter(w, r, "")
+	if !ok {
+		return
+	}
+
+	if err := h.DB.DeleteSavedFilter(saved.ID, saved.UserID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete saved filter: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Saved filter deleted successfully"})
+}
+
+// RunSavedFilter handles GET /api/filters/{id}/run
+func (h *SavedFilterHandler) RunSavedFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	saved, ok := h.loadSavedFilter(w, r, "/run")
+	if !ok {
+		return
+	}
+	h.runFilter(w, r, saved.UserID, saved.Query)
+}
+
+// RunFilter handles GET /api/filters/run?q= to try out a query before saving it
+func (h *SavedFilterHandler) RunFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.runFilter(w, r, userID, r.URL.Query().Get("q"))
+}
+
+// runFilter evaluates a query over every mind map the user can access
+func (h *SavedFilterHandler) runFilter(w http.ResponseWriter, r *http.Request, userID, rawQuery string) {
+	query, err := filter.Parse(rawQuery, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	db := readDB(h.DB, r)
+	mindMaps, err := db.GetAccessibleMindMaps(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind maps: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	result := models.FilterResult{Query: rawQuery, Maps: []models.FilterMapResult{}}
+	if len(mindMaps) > 0 {
+		ids := make([]string, len(mindMaps))
+		for i, mindMap := range mindMaps {
+			ids[i] = mindMap.ID
+		}
+		nodes, err := db.GetNodesByMindMapIDs(ids)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Group matches by map, keeping the maps in their listed order
+		matches := make(map[string][]models.Node)
+		for _, node := range nodes {
+			if !query.Match(node) {
+				continue
+			}
+			result.Total++
+			if result.Total > maxFilterResults {
+				result.Truncated = true
+				continue
+			}
+			node.MaskAttribution()
+			matches[node.MindMapID] = append(matches[node.MindMapID], node)
+		}
+		for _, mindMap := range mindMaps {
+			if len(matches[mindMap.ID]) > 0 {
+				result.Maps = append(result.Maps, models.FilterMapResult{
+					MindMapID: mindMap.ID,
+					Title:     mindMap.Title,
+					Nodes:     matches[mindMap.ID],
+				})
+			}
+		}
+	}
+
+	// Return filter results
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// loadSavedFilter extracts the saved filter ID from the URL and loads the user's filter,
+// writing an error response and returning false if that fails
+func (h *SavedFilterHandler) loadSavedFilter(w http.ResponseWriter, r *http.Request, suffix string) (*models.SavedFilter, bool) {
+	// Extract saved filter ID from URL
+	filterID := strings.TrimPrefix(r.URL.Path, "/api/filters/")
+	filterID = strings.TrimSuffix(filterID, suffix)
+	if filterID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Parse saved filter ID
+	if _, err := uuid.Parse(filterID); err != nil {
+		http.Error(w, "Invalid saved filter ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	saved, err := h.DB.GetSavedFilter(filterID, userID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Saved filter not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get saved filter: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return saved, true
+}
+
+// validateSavedFilter trims and checks a saved filter's name and query
+func validateSavedFilter(req *models.SavedFilterRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if len(req.Query) > 1000 {
+		return fmt.Errorf("query must be at most 1000 characters")
+	}
+	if _, err := filter.Parse(req.Query, time.Now()); err != nil {
+		return fmt.Errorf("invalid query: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,878 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"saas-server/database"
+	"saas-server/middleware"
+	"saas-server/models"
+	"saas-server/pkg/validation"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Page sizes of SCIM list responses
+const (
+	defaultSCIMPageSize = 100
+	maxSCIMPageSize     = 200
+)
+
+// scimFilterPattern matches the only filters identity providers need to look resources up:
+// an attribute equal to a quoted value
+var scimFilterPattern = regexp.MustCompile(`^\s*(\w+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimMemberPathPattern matches the path of a PATCH removing one member from a group
+var scimMemberPathPattern = regexp.MustCompile(`^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+// SCIMHandler implements SCIM 2.0 for tenants' identity providers, which create, update
+// and deactivate the tenant's users and manage its groups. Users can only be provisioned
+// with an email in a domain of one of the tenant's SSO connections. Deactivated users, or
+// deleted ones, keep their data but lose access straight away, and group members get the
+// role the tenant's SSO connection maps their groups to
+type SCIMHandler struct {
+	DB *database.DB
+}
+
+// NewSCIMHandler creates a new SCIMHandler
+func NewSCIMHandler(db *database.DB) *SCIMHandler {
+	return &SCIMHandler{DB: db}
+}
+
+// CreateToken handles POST /admin/tenants/{id}/scim-token, issuing the token the tenant's
+// identity provider authenticates with. Any previous token stops working
+func (h *SCIMHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract tenant ID from URL
+	tenantID := strings.TrimPrefix(r.URL.Path, "/admin/tenants/")
+	tenantID = strings.TrimSuffix(tenantID, "/scim-token")
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	token := "scim_" + hex.EncodeToString(b)
+
+	err := h.DB.SetSCIMToken(tenantID, middleware.HashSCIMToken(token))
+	if err == database.ErrNotFound {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to store token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return the token, which is not shown again
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.SCIMTokenResponse{Token: token})
+}
+
+// GetServiceProviderConfig handles GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	sendSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{models.SCIMServiceConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxSCIMPageSize},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{
+			{"type": "oauthbearertoken", "name": "Bearer token", "description": "Token issued to the tenant by an admin"},
+		},
+	})
+}
+
+// ListUsers handles GET /scim/v2/Users, supporting the filter userName eq "..."
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	email, ok := scimFilter(w, r, "userName")
+	if !ok {
+		return
+	}
+	startIndex, count := scimPage(r)
+
+	members, total, err := h.DB.GetTenantMembers(tenantID, email, startIndex-1, count)
+	if err != nil {
+		log.Printf("[SCIM] Error listing users of tenant %s: %v", tenantID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to list users")
+		return
+	}
+
+	resources := make([]models.SCIMUser, len(members))
+	for i := range members {
+		resources[i] = scimUserResource(&members[i])
+	}
+
+	// Return the page of users
+	sendSCIM(w, http.StatusOK, models.SCIMListResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// CreateUser handles POST /scim/v2/Users. A user who already has an account with the
+// email is moved into the tenant rather than created again
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	var req models.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	email, name := scimUserEmail(&req), scimUserName(&req)
+	if !h.checkSCIMEmail(w, tenantID, email) {
+		return
+	}
+
+	user, err := h.DB.GetUserByEmail(email)
+	if err == nil {
+		if _, err := h.DB.GetTenantMember(tenantID, user.ID); err == nil {
+			sendSCIMError(w, http.StatusConflict, "uniqueness", "A user with this userName already exists")
+			return
+		}
+	} else {
+		if user, err = h.DB.CreateUser(email, "", name, true); err != nil {
+			log.Printf("[SCIM] Error creating user in tenant %s: %v", tenantID, err)
+			sendSCIMError(w, http.StatusInternalServerError, "", "Failed to create user")
+			return
+		}
+
+		// Track user signup with Plunk for new users
+		if err := trackUserSignup(user.Email, user.Name); err != nil {
+			log.Printf("[SCIM] Error tracking user signup: %v", err)
+		}
+	}
+
+	if err := h.DB.MoveUserToTenant(user.ID, tenantID); err != nil {
+		log.Printf("[SCIM] Error moving user %s to tenant %s: %v", user.ID, tenantID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+	active := req.Active == nil || *req.Active
+	if err := h.DB.SetTenantMemberActive(tenantID, user.ID, active); err != nil {
+		log.Printf("[SCIM] Error setting whether user %s is active: %v", user.ID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+	log.Printf("[SCIM] Provisioned user %s in tenant %s", user.ID, tenantID)
+
+	h.sendUser(w, http.StatusCreated, tenantID, user.ID)
+}
+
+// GetUser handles GET /scim/v2/Users/{id}
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := scimResourceID(w, r, "/scim/v2/Users/")
+	if !ok {
+		return
+	}
+	h.sendUser(w, http.StatusOK, middleware.GetTenantID(r.Context()), userID)
+}
+
+// ReplaceUser handles PUT /scim/v2/Users/{id}
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	userID, ok := scimResourceID(w, r, "/scim/v2/Users/")
+	if !ok {
+		return
+	}
+	var req models.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	member, err := h.DB.GetTenantMember(tenantID, userID)
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error getting user %s: %v", userID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to get user")
+		return
+	}
+
+	member.Email, member.Name = scimUserEmail(&req), scimUserName(&req)
+	if req.Active != nil {
+		member.Active = *req.Active
+	}
+	h.updateUser(w, tenantID, member)
+}
+
+// PatchUser handles PATCH /scim/v2/Users/{id}. It changes the active flag, the userName
+// or email and the name; other attributes are ignored
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	userID, ok := scimResourceID(w, r, "/scim/v2/Users/")
+	if !ok {
+		return
+	}
+	var req models.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	member, err := h.DB.GetTenantMember(tenantID, userID)
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error getting user %s: %v", userID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to get user")
+		return
+	}
+
+	for _, op := range req.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			continue
+		}
+		// A missing path means the value holds attributes by name
+		changes := map[string]interface{}{op.Path: op.Value}
+		if op.Path == "" {
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				sendSCIMError(w, http.StatusBadRequest, "invalidValue", "Operation without a path needs an object value")
+				return
+			}
+			changes = values
+		}
+		for path, value := range changes {
+			applySCIMUserChange(member, path, value)
+		}
+	}
+	h.updateUser(w, tenantID, member)
+}
+
+// applySCIMUserChange applies a change of one attribute to a user
+func applySCIMUserChange(member *models.TenantMember, path string, value interface{}) {
+	switch strings.ToLower(path) {
+	case "active":
+		if active, ok := scimBool(value); ok {
+			member.Active = active
+		}
+	case "username", `emails[type eq "work"].value`, "emails[primary eq true].value":
+		if email, ok := value.(string); ok {
+			member.Email = email
+		}
+	case "displayname", "name.formatted":
+		if name, ok := value.(string); ok && strings.TrimSpace(name) != "" {
+			member.Name = strings.TrimSpace(name)
+		}
+	case "name":
+		if name, ok := value.(map[string]interface{}); ok {
+			if formatted, ok := name["formatted"].(string); ok && strings.TrimSpace(formatted) != "" {
+				member.Name = strings.TrimSpace(formatted)
+			}
+		}
+	}
+}
+
+// updateUser stores a user's changed email, name and active flag and responds with the user
+func (h *SCIMHandler) updateUser(w http.ResponseWriter, tenantID string, member *models.TenantMember) {
+	member.Email = strings.ToLower(strings.TrimSpace(member.Email))
+	if !h.checkSCIMEmail(w, tenantID, member.Email) {
+		return
+	}
+	if member.Name == "" {
+		member.Name = strings.Split(member.Email, "@")[0]
+	}
+
+	err := h.DB.UpdateTenantMember(tenantID, member.ID, member.Name, member.Email)
+	if err == nil {
+		err = h.DB.SetTenantMemberActive(tenantID, member.ID, member.Active)
+	}
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error updating user %s: %v", member.ID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to update user")
+		return
+	}
+	if !member.Active {
+		log.Printf("[SCIM] Deactivated user %s in tenant %s", member.ID, tenantID)
+	}
+
+	h.sendUser(w, http.StatusOK, tenantID, member.ID)
+}
+
+// DeleteUser handles DELETE /scim/v2/Users/{id}. The user is deactivated rather than
+// deleted, so the maps they own stay available to the rest of the tenant
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	userID, ok := scimResourceID(w, r, "/scim/v2/Users/")
+	if !ok {
+		return
+	}
+
+	err := h.DB.SetTenantMemberActive(tenantID, userID, false)
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error deactivating user %s: %v", userID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to delete user")
+		return
+	}
+	log.Printf("[SCIM] Deactivated user %s in tenant %s", userID, tenantID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendUser responds with a user of the tenant
+func (h *SCIMHandler) sendUser(w http.ResponseWriter, status int, tenantID, userID string) {
+	member, err := h.DB.GetTenantMember(tenantID, userID)
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error getting user %s: %v", userID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to get user")
+		return
+	}
+
+	// Return the user
+	sendSCIM(w, status, scimUserResource(member))
+}
+
+// checkSCIMEmail responds with an error and returns false unless the email is valid and in
+// a domain of one of the tenant's SSO connections
+func (h *SCIMHandler) checkSCIMEmail(w http.ResponseWriter, tenantID, email string) bool {
+	if !validation.ValidateEmail(email) {
+		sendSCIMError(w, http.StatusBadRequest, "invalidValue", "userName must be an email address")
+		return false
+	}
+	connection, err := h.DB.GetSSOConnectionByEmail(email)
+	if err != nil && err != database.ErrNotFound {
+		log.Printf("[SCIM] Error finding connection for %s: %v", database.EmailDomain(email), err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Internal server error")
+		return false
+	}
+	if err == database.ErrNotFound || connection.TenantID != tenantID {
+		sendSCIMError(w, http.StatusBadRequest, "invalidValue", "The email domain does not belong to this organization")
+		return false
+	}
+	return true
+}
+
+// ListGroups handles GET /scim/v2/Groups, supporting the filter displayName eq "..."
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	displayName, ok := scimFilter(w, r, "displayName")
+	if !ok {
+		return
+	}
+	startIndex, count := scimPage(r)
+
+	groups, total, err := h.DB.GetSCIMGroups(tenantID, displayName, startIndex-1, count)
+	if err != nil {
+		log.Printf("[SCIM] Error listing groups of tenant %s: %v", tenantID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to list groups")
+		return
+	}
+
+	resources := make([]models.SCIMGroupResource, len(groups))
+	for i := range groups {
+		resources[i] = scimGroupResource(&groups[i])
+	}
+
+	// Return the page of groups
+	sendSCIM(w, http.StatusOK, models.SCIMListResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// CreateGroup handles POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	var req models.SCIMGroupResource
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	if req.DisplayName == "" || len(req.DisplayName) > 255 {
+		sendSCIMError(w, http.StatusBadRequest, "invalidValue", "displayName is required and must be at most 255 characters")
+		return
+	}
+
+	group, err := h.DB.CreateSCIMGroup(tenantID, req.DisplayName, req.ExternalID)
+	if err == database.ErrSCIMGroupNameTaken {
+		sendSCIMError(w, http.StatusConflict, "uniqueness", err.Error())
+		return
+	}
+	if err == nil {
+		err = h.DB.AddSCIMGroupMembers(group.ID, scimMemberIDs(req.Members))
+	}
+	if err == nil {
+		err = h.syncRoles(tenantID, scimMemberIDs(req.Members))
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error creating group in tenant %s: %v", tenantID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to create group")
+		return
+	}
+
+	h.sendGroup(w, http.StatusCreated, tenantID, group.ID)
+}
+
+// GetGroup handles GET /scim/v2/Groups/{id}
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := scimResourceID(w, r, "/scim/v2/Groups/")
+	if !ok {
+		return
+	}
+	h.sendGroup(w, http.StatusOK, middleware.GetTenantID(r.Context()), groupID)
+}
+
+// ReplaceGroup handles PUT /scim/v2/Groups/{id}, replacing its name and members
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	groupID, ok := scimResourceID(w, r, "/scim/v2/Groups/")
+	if !ok {
+		return
+	}
+	var req models.SCIMGroupResource
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	group, ok := h.getGroup(w, tenantID, groupID)
+	if !ok {
+		return
+	}
+	displayName := strings.TrimSpace(req.DisplayName)
+	if displayName == "" {
+		displayName = group.DisplayName
+	}
+	if !h.renameGroup(w, group, displayName, req.ExternalID) {
+		return
+	}
+	h.replaceMembers(w, group, scimMemberIDs(req.Members))
+}
+
+// PatchGroup handles PATCH /scim/v2/Groups/{id}: renaming it, and adding, removing or
+// replacing its members
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	groupID, ok := scimResourceID(w, r, "/scim/v2/Groups/")
+	if !ok {
+		return
+	}
+	var req models.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendSCIMError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	group, ok := h.getGroup(w, tenantID, groupID)
+	if !ok {
+		return
+	}
+
+	// Collect the members to end up with, so roles are synced once
+	members := make(map[string]bool)
+	for _, member := range group.Members {
+		members[member.UserID] = true
+	}
+	affected := scimGroupUserIDs(group)
+	displayName, externalID := group.DisplayName, group.ExternalID
+	for _, op := range req.Operations {
+		opName := strings.ToLower(op.Op)
+		changes := map[string]interface{}{op.Path: op.Value}
+		if op.Path == "" && opName != "remove" {
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				sendSCIMError(w, http.StatusBadRequest, "invalidValue", "Operation without a path needs an object value")
+				return
+			}
+			changes = values
+		}
+
+		for path, value := range changes {
+			if match := scimMemberPathPattern.FindStringSubmatch(path); match != nil && opName == "remove" {
+				delete(members, match[1])
+				affected = append(affected, match[1])
+				continue
+			}
+			switch strings.ToLower(path) {
+			case "displayname":
+				if name, ok := value.(string); ok && strings.TrimSpace(name) != "" && opName != "remove" {
+					displayName = strings.TrimSpace(name)
+				}
+			case "externalid":
+				if id, ok := value.(string); ok && opName != "remove" {
+					externalID = id
+				}
+			case "members":
+				ids := scimValueIDs(value)
+				switch opName {
+				case "add":
+					for _, id := range ids {
+						members[id] = true
+					}
+				case "remove":
+					if value == nil {
+						members = make(map[string]bool)
+					}
+					for _, id := range ids {
+						delete(members, id)
+					}
+				case "replace":
+					members = make(map[string]bool)
+					for _, id := range ids {
+						members[id] = true
+					}
+				}
+				affected = append(affected, ids...)
+			}
+		}
+	}
+
+	if !h.renameGroup(w, group, displayName, externalID) {
+		return
+	}
+	var userIDs []string
+	for id := range members {
+		userIDs = append(userIDs, id)
+	}
+	h.replaceMembers(w, group, userIDs, affected...)
+}
+
+// DeleteGroup handles DELETE /scim/v2/Groups/{id}
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID := middleware.GetTenantID(r.Context())
+	groupID, ok := scimResourceID(w, r, "/scim/v2/Groups/")
+	if !ok {
+		return
+	}
+
+	group, ok := h.getGroup(w, tenantID, groupID)
+	if !ok {
+		return
+	}
+	err := h.DB.DeleteSCIMGroup(tenantID, groupID)
+	if err == nil {
+		err = h.syncRoles(tenantID, scimGroupUserIDs(group))
+	}
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "Group not found")
+		return
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error deleting group %s: %v", groupID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to delete group")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getGroup returns a group of the tenant, responding with an error if there is none
+func (h *SCIMHandler) getGroup(w http.ResponseWriter, tenantID, groupID string) (*models.SCIMGroup, bool) {
+	group, err := h.DB.GetSCIMGroup(tenantID, groupID)
+	if err == database.ErrNotFound {
+		sendSCIMError(w, http.StatusNotFound, "", "Group not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error getting group %s: %v", groupID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to get group")
+		return nil, false
+	}
+	return group, true
+}
+
+// renameGroup stores a group's name and external ID if they changed, responding with an
+// error and returning false if that fails. The roles of its members, which may map
+// differently under the new name, are synced when its members are replaced
+func (h *SCIMHandler) renameGroup(w http.ResponseWriter, group *models.SCIMGroup, displayName, externalID string) bool {
+	if displayName == group.DisplayName && externalID == group.ExternalID {
+		return true
+	}
+	if len(displayName) > 255 {
+		sendSCIMError(w, http.StatusBadRequest, "invalidValue", "displayName must be at most 255 characters")
+		return false
+	}
+	err := h.DB.RenameSCIMGroup(group.TenantID, group.ID, displayName, externalID)
+	if err == database.ErrSCIMGroupNameTaken {
+		sendSCIMError(w, http.StatusConflict, "uniqueness", err.Error())
+		return false
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error renaming group %s: %v", group.ID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to update group")
+		return false
+	}
+	return true
+}
+
+// replaceMembers sets the members of a group, syncs the roles of everyone who was or is in
+// it and of the other affected users, and responds with the group
+func (h *SCIMHandler) replaceMembers(w http.ResponseWriter, group *models.SCIMGroup, userIDs []string, affected ...string) {
+	err := h.DB.ClearSCIMGroupMembers(group.ID)
+	if err == nil {
+		err = h.DB.AddSCIMGroupMembers(group.ID, userIDs)
+	}
+	if err == nil {
+		affected = append(affected, scimGroupUserIDs(group)...)
+		err = h.syncRoles(group.TenantID, append(affected, userIDs...))
+	}
+	if err != nil {
+		log.Printf("[SCIM] Error updating members of group %s: %v", group.ID, err)
+		sendSCIMError(w, http.StatusInternalServerError, "", "Failed to update group")
+		return
+	}
+
+	h.sendGroup(w, http.StatusOK, group.TenantID, group.ID)
+}
+
+// syncRoles sets the tenant role of each user to the one the tenant's SSO connection maps
+// their groups to. Tenants without an SSO connection have no mapping, so roles are kept
+func (h *SCIMHandler) syncRoles(tenantID string, userIDs []string) error {
+	connection, err := h.DB.GetSSOConnectionByTenant(tenantID)
+	if err == database.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	synced := make(map[string]bool)
+	for _, userID := range userIDs {
+		if synced[userID] {
+			continue
+		}
+		synced[userID] = true
+		if _, err := h.DB.GetTenantMember(tenantID, userID); err == database.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		groups, err := h.DB.GetUserSCIMGroupNames(userID)
+		if err != nil {
+			return err
+		}
+		if err := h.DB.SetUserTenantRole(userID, ssoRole(connection, groups)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendGroup responds with a group of the tenant
+func (h *SCIMHandler) sendGroup(w http.ResponseWriter, status int, tenantID, groupID string) {
+	group, ok := h.getGroup(w, tenantID, groupID)
+	if !ok {
+		return
+	}
+
+	// Return the group
+	sendSCIM(w, status, scimGroupResource(group))
+}
+
+// scimUserResource returns the SCIM representation of a user
+func scimUserResource(member *models.TenantMember) models.SCIMUser {
+	active := member.Active
+	return models.SCIMUser{
+		Schemas:     []string{models.SCIMUserSchema},
+		ID:          member.ID,
+		UserName:    member.Email,
+		Name:        &models.SCIMName{Formatted: member.Name},
+		DisplayName: member.Name,
+		Emails:      []models.SCIMEmail{{Value: member.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &models.SCIMMeta{
+			ResourceType: "User",
+			Created:      member.CreatedAt,
+			LastModified: member.UpdatedAt,
+			Location:     "/scim/v2/Users/" + member.ID,
+		},
+	}
+}
+
+// scimGroupResource returns the SCIM representation of a group
+func scimGroupResource(group *models.SCIMGroup) models.SCIMGroupResource {
+	members := make([]models.SCIMMemberRef, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = models.SCIMMemberRef{
+			Value:   member.UserID,
+			Display: member.Email,
+			Ref:     "/scim/v2/Users/" + member.UserID,
+		}
+	}
+	return models.SCIMGroupResource{
+		Schemas:     []string{models.SCIMGroupSchema},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     members,
+		Meta: &models.SCIMMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     "/scim/v2/Groups/" + group.ID,
+		},
+	}
+}
+
+// scimUserEmail returns the email of a SCIM user: its primary email, else its userName
+func scimUserEmail(user *models.SCIMUser) string {
+	for _, email := range user.Emails {
+		if email.Primary {
+			return strings.ToLower(strings.TrimSpace(email.Value))
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(user.UserName))
+}
+
+// scimUserName returns the display name of a SCIM user, or an empty string if it has none
+func scimUserName(user *models.SCIMUser) string {
+	if user.Name != nil {
+		if name := strings.TrimSpace(user.Name.Formatted); name != "" {
+			return name
+		}
+		if name := strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName); name != "" {
+			return name
+		}
+	}
+	return strings.TrimSpace(user.DisplayName)
+}
+
+// scimMemberIDs returns the user IDs of group member references
+func scimMemberIDs(members []models.SCIMMemberRef) []string {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.Value)
+	}
+	return ids
+}
+
+// scimValueIDs returns the user IDs of a PATCH value listing members
+func scimValueIDs(value interface{}) []string {
+	items, _ := value.([]interface{})
+	var ids []string
+	for _, item := range items {
+		if member, ok := item.(map[string]interface{}); ok {
+			if id, ok := member["value"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// scimGroupUserIDs returns the user IDs of a group's members
+func scimGroupUserIDs(group *models.SCIMGroup) []string {
+	ids := make([]string, len(group.Members))
+	for i, member := range group.Members {
+		ids[i] = member.UserID
+	}
+	return ids
+}
+
+// scimBool reads a boolean PATCH value; some identity providers send "True" and "False"
+func scimBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.ToLower(v))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// scimResourceID extracts the resource ID from a SCIM URL, responding with a 404 if it is
+// not a UUID, as no resource can have it
+func scimResourceID(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+	id := strings.TrimPrefix(r.URL.Path, prefix)
+	if _, err := uuid.Parse(id); err != nil {
+		sendSCIMError(w, http.StatusNotFound, "", "Resource not found")
+		return "", false
+	}
+	return id, true
+}
+
+// scimFilter returns the value an attribute is filtered on, or an empty string without a
+// filter. Other filters get a 400
+func scimFilter(w http.ResponseWriter, r *http.Request, attribute string) (string, bool) {
+	filter := r.URL.Query().Get("filter")
+	if filter == "" {
+		return "", true
+	}
+	match := scimFilterPattern.FindStringSubmatch(filter)
+	if match == nil || !strings.EqualFold(match[1], attribute) {
+		sendSCIMError(w, http.StatusBadRequest, "invalidFilter", fmt.Sprintf("Only %s eq \"...\" filters are supported", attribute))
+		return "", false
+	}
+	value, err := strconv.Unquote(`"` + match[2] + `"`)
+	if err != nil {
+		value = match[2]
+	}
+	return value, true
+}
+
+// scimPage returns the 1-based start index and the page size of a SCIM list request
+func scimPage(r *http.Request) (int, int) {
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 {
+		count = defaultSCIMPageSize
+	}
+	return startIndex, min(count, maxSCIMPageSize)
+}
+
+// sendSCIM responds with a SCIM resource or message
+func sendSCIM(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// sendSCIMError responds with a SCIM error
+func sendSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	sendSCIM(w, status, models.SCIMError{
+		Schemas:  []string{models.SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
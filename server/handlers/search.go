@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultSearchLimit is how many matches are returned when no limit is given
+	defaultSearchLimit = 50
+	// maxSearchLimit caps how many matches a single search returns
+	maxSearchLimit = 200
+	// maxSearchTerms caps how many words a search query may contain
+	maxSearchTerms = 10
+)
+
+// SearchMindMap handles GET /api/mindmaps/{id}/search?q=&limit=. A node matches when its content
+// contains every word of q, ignoring case; each match carries the ranges to highlight
+func (h *MindMapHandler) SearchMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/search")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse query
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > 200 {
+		http.Error(w, "Query must be at most 200 characters", http.StatusBadRequest)
+		return
+	}
+	terms := searchTerms(query)
+	if len(terms) > maxSearchTerms {
+		http.Error(w, fmt.Sprintf("Query cannot contain more than %d words", maxSearchTerms), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Search nodes
+	nodes, err := readDB(h.DB, r).SearchNodes(mindMapID, terms, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	result := models.NodeSearchResult{
+		Query:   query,
+		Terms:   terms,
+		Matches: make([]models.NodeSearchMatch, 0, len(nodes)),
+	}
+	for _, node := range nodes {
+		result.Matches = append(result.Matches, models.NodeSearchMatch{
+			NodeID:  node.ID,
+			Content: node.Content,
+			Ranges:  matchRanges(node.Content, terms),
+		})
+	}
+
+	// Return search results
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// searchTerms splits a query into its distinct words
+func searchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(query) {
+		key := strings.ToLower(term)
+		if !seen[key] {
+			seen[key] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// matchRanges finds every case-insensitive occurrence of the terms in content and returns
+// them sorted and merged where they overlap, as UTF-16 offsets
+func matchRanges(content string, terms []string) []models.MatchRange {
+	text := foldRunes(content)
+
+	var spans [][2]int
+	for _, term := range terms {
+		pattern := foldRunes(term)
+		if len(pattern) == 0 {
+			continue
+		}
+		for i := 0; i+len(pattern) <= len(text); {
+			if runesEqual(text[i:i+len(pattern)], pattern) {
+				spans = append(spans, [2]int{i, i + len(pattern)})
+				i += len(pattern)
+				continue
+			}
+			i++
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	// Offsets are counted in runes above; convert them to UTF-16 code units
+	offsets := make([]int, len(text)+1)
+	for i, r := range []rune(content) {
+		offsets[i+1] = offsets[i] + len(utf16.Encode([]rune{r}))
+	}
+
+	ranges := []models.MatchRange{}
+	for _, span := range spans {
+		start, end := offsets[span[0]], offsets[span[1]]
+		if last := len(ranges) - 1; last >= 0 && start <= ranges[last].End {
+			if end > ranges[last].End {
+				ranges[last].End = end
+			}
+			continue
+		}
+		ranges = append(ranges, models.MatchRange{Start: start, End: end})
+	}
+	return ranges
+}
+
+// foldRunes lowercases text rune by rune so offsets stay aligned with the original
+func foldRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// runesEqual reports whether two rune slices are identical
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
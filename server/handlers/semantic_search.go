@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// embeddingIndexInterval is how often the index job looks for nodes to embed
+	embeddingIndexInterval = 30 * time.Second
+	// embeddingIndexBatch is how many nodes the index job embeds at a time
+	embeddingIndexBatch = 100
+	// maxSearchBackfill caps how many nodes of the user's own maps a search embeds first
+	maxSearchBackfill = 200
+	// maxSemanticQueryLength caps the length of a semantic search query
+	maxSemanticQueryLength = 500
+)
+
+// SemanticSearchHandler finds nodes by meaning rather than by their words, comparing
+// embeddings of node content stored with pgvector
+type SemanticSearchHandler struct {
+	DB *database.DB
+}
+
+// NewSemanticSearchHandler creates a new SemanticSearchHandler
+func NewSemanticSearchHandler(db *database.DB) *SemanticSearchHandler {
+	return &SemanticSearchHandler{DB: db}
+}
+
+// StartIndexJob starts the background job that embeds the content of new and changed
+// nodes with the OpenAI key of their map's owner. Nodes of owners without a key, or of
+// maps over their spend cap, are recorded as not embedded and picked up again when their
+// content changes or their owner searches
+func (h *SemanticSearchHandler) StartIndexJob() {
+	go func() {
+		ticker := time.NewTicker(embeddingIndexInterval)
+		defer ticker.Stop()
+		for {
+			h.indexEmbeddings()
+			<-ticker.C
+		}
+	}()
+}
+
+// indexEmbeddings embeds batches of nodes until none are left, or embedding fails in a way
+// worth retrying on the next run
+func (h *SemanticSearchHandler) indexEmbeddings() {
+	for {
+		nodes, err := h.DB.GetNodesToEmbed(embeddingIndexBatch)
+		if err != nil {
+			log.Printf("[Semantic Search] Error getting nodes to embed: %v", err)
+			return
+		}
+		if len(nodes) == 0 {
+			return
+		}
+		if _, err := h.embedNodes(context.Background(), nodes); err != nil {
+			log.Printf("[Semantic Search] Error embedding nodes: %v", err)
+			return
+		}
+		if len(nodes) < embeddingIndexBatch {
+			return
+		}
+	}
+}
+
+// embedNodes embeds and stores the content of nodes, one request per mind map, with the
+// map owner's OpenAI key and personal data redacted as the map requires. Nodes that cannot
+// be embedded are stored without an embedding, unless the provider failed in a way worth
+// retrying, which stops embedding with the error. Returns how many nodes were embedded
+func (h *SemanticSearchHandler) embedNodes(ctx context.Context, nodes []models.NodeToEmbed) (int, error) {
+	var order []string
+	byMap := make(map[string][]models.NodeToEmbed)
+	for _, node := range nodes {
+		if _, ok := byMap[node.MindMapID]; !ok {
+			order = append(order, node.MindMapID)
+		}
+		byMap[node.MindMapID] = append(byMap[node.MindMapID], node)
+	}
+
+	embedded := 0
+	for _, mindMapID := range order {
+		mapNodes := byMap[mindMapID]
+		results := make([]models.NodeEmbedding, len(mapNodes))
+		for i, node := range mapNodes {
+			results[i] = models.NodeEmbedding{NodeID: node.ID, Content: node.Content}
+		}
+
+		vectors, err := h.embedMapNodes(ctx, mindMapID, mapNodes)
+		if err != nil {
+			if _, response := classifyAIError(err); response.Retryable {
+				return embedded, err
+			}
+			log.Printf("[Semantic Search] Not embedding %d nodes of map %s: %v", len(mapNodes), mindMapID, err)
+		}
+		for i := range vectors {
+			results[i].Model = openAIEmbeddingModel
+			results[i].Embedding = vectors[i]
+		}
+
+		if err := h.DB.SaveNodeEmbeddings(results); err != nil {
+			return embedded, err
+		}
+		embedded += len(vectors)
+	}
+	return embedded, nil
+}
+
+// embedMapNodes embeds the content of nodes of one mind map, within the map's spend cap
+func (h *SemanticSearchHandler) embedMapNodes(ctx context.Context, mindMapID string, nodes []models.NodeToEmbed) ([][]float64, error) {
+	mindMap, err := h.DB.GetMindMapByID(mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := resolveOpenAIKey(h.DB, mindMap.UserID, "")
+	if err == nil && apiKey == "" {
+		err = errNoAIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := enforceAISpendCap(h.DB, mindMap.ID, aiProviderOpenAI, openAIEmbeddingModel); err != nil {
+		return nil, err
+	}
+
+	redactor := piiRedactor(h.DB, mindMap)
+	inputs := make([]string, len(nodes))
+	for i, node := range nodes {
+		inputs[i] = redactor.Redact(node.Content)
+	}
+	vectors, usage, err := openAIEmbeddings(ctx, apiKey, inputs)
+	recordAIUsage(h.DB, mindMap.UserID, mindMap.ID, aiFeatureSearch, usage)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(nodes) {
+		return nil, fmt.Errorf("got %d embeddings for %d nodes", len(vectors), len(nodes))
+	}
+	return vectors, nil
+}
+
+// SemanticSearch handles GET /api/search/semantic?q=, finding the nodes across the maps the
+// user owns or has been shared with whose content is closest in meaning to the query.
+// ?limit sets how many nodes to return, 20 by default and at most 50. Nodes of the user's
+// own maps the index job has not reached yet are embedded first, up to a limit; the
+// response counts those still waiting
+func (h *SemanticSearchHandler) SemanticSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse query parameters
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > maxSemanticQueryLength {
+		http.Error(w, fmt.Sprintf("Query must be at most %d characters", maxSemanticQueryLength), http.StatusBadRequest)
+		return
+	}
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 50)
+	}
+
+	apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+	if err == nil && apiKey == "" {
+		err = errNoAIKey
+	}
+	if err != nil {
+		sendGenerationError(w, "Failed to search", err)
+		return
+	}
+
+	// Catch up on the user's own nodes the index job has not embedded yet
+	db := tenantDB(h.DB, r)
+	pending, total, err := h.DB.GetUserNodesToEmbed(userID, maxSearchBackfill)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes to embed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(pending) > 0 {
+		embedded, err := h.embedNodes(r.Context(), pending)
+		if err != nil {
+			log.Printf("[Semantic Search] Error embedding nodes of user %s: %v", userID, err)
+		}
+		total -= embedded
+		if embedded > 0 {
+			// Search what was just stored rather than a replica that may not have it yet
+			db = db.Primary()
+		}
+	}
+
+	// Embed the query and find the closest nodes
+	embeddings, usage, err := openAIEmbeddings(r.Context(), apiKey, []string{query})
+	recordAIUsage(h.DB, userID, "", aiFeatureSearch, usage)
+	if err != nil {
+		sendAIError(w, "Failed to search", err)
+		return
+	}
+	if len(embeddings) == 0 {
+		sendAIError(w, "Failed to search", errors.New("no embedding returned for the query"))
+		return
+	}
+	results, err := db.SemanticSearch(userID, embeddings[0], limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return results
+	sendJSONResponse(w, http.StatusOK, models.SemanticSearchResponse{
+		Query:   query,
+		Results: results,
+		Pending: total,
+	})
+}
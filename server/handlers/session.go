@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/realtime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxSessionMinutes caps how long a timed brainstorm session can run
+const maxSessionMinutes = 24 * 60
+
+// SessionHandler handles timed brainstorm sessions on mind maps
+type SessionHandler struct {
+	DB  *database.DB
+	Hub *realtime.Hub
+}
+
+// NewSessionHandler creates a new SessionHandler
+func NewSessionHandler(db *database.DB, hub *realtime.Hub) *SessionHandler {
+	return &SessionHandler{DB: db, Hub: hub}
+}
+
+// GetSession handles GET /api/mindmaps/{id}/session
+func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/session")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the running session
+	session, err := h.DB.GetActiveSession(mindMapID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "No active session", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return session
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// StartSession handles POST /api/mindmaps/{id}/session/start
+func (h *SessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/session/start")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Only the owner can facilitate a session
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.SessionStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if req.Mode == "" {
+		req.Mode = models.SessionModeIdeation
+	}
+	if req.Mode != models.SessionModeIdeation && req.Mode != models.SessionModeVoting {
+		http.Error(w, "Mode must be 'ideation' or 'voting'", http.StatusBadRequest)
+		return
+	}
+	if req.DurationMinutes < 0 || req.DurationMinutes > maxSessionMinutes {
+		http.Error(w, fmt.Sprintf("Duration must be between 0 and %d minutes", maxSessionMinutes), http.StatusBadRequest)
+		return
+	}
+
+	// Only one session may run on a map at a time
+	if _, err := h.DB.GetActiveSession(mindMapID); err == nil {
+		http.Error(w, "A session is already running on this mind map", http.StatusConflict)
+		return
+	} else if !errors.Is(err, database.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Create session
+	session, err := h.DB.CreateSession(mindMapID, userID, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to start session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if session.EndsAt != nil {
+		h.scheduleSessionEnd(session.ID, mindMapID, time.Until(*session.EndsAt))
+	}
+
+	// Return created session
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// UpdateSession handles PUT /api/mindmaps/{id}/session
+func (h *SessionHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/session")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Only the owner can change the session
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req models.SessionUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if req.Mode != "" && req.Mode != models.SessionModeIdeation && req.Mode != models.SessionModeVoting {
+		http.Error(w, "Mode must be 'ideation' or 'voting'", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.DB.GetActiveSession(mindMapID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "No active session", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Update session
+	session, err := h.DB.UpdateSession(current.ID, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated session
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// StopSession handles POST /api/mindmaps/{id}/session/stop
+func (h *SessionHandler) StopSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/session/stop")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Only the owner can stop the session
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	current, err := h.DB.GetActiveSession(mindMapID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "No active session", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Stop session
+	session, err := h.DB.StopSession(current.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to stop session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return stopped session
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// RevealAuthorship handles POST /api/mindmaps/{id}/session/reveal
+func (h *SessionHandler) RevealAuthorship(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/session/reveal")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Only the facilitator can reveal who wrote what
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse optional request body; without a session ID the latest session is revealed
+	var req models.SessionRevealRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var target *models.BrainstormSession
+	if req.SessionID != "" {
+		target, err = h.DB.GetSessionByID(req.SessionID)
+	} else {
+		target, err = h.DB.GetLatestSession(mindMapID)
+	}
+	if errors.Is(err, database.ErrNotFound) || (err == nil && target.MindMapID != mindMapID) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Reveal authorship
+	session, err := h.DB.RevealSessionAuthorship(target.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reveal authorship: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated session
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// scheduleSessionEnd notifies subscribers when a timed session runs out, unless it was stopped early
+func (h *SessionHandler) scheduleSessionEnd(sessionID, mindMapID string, after time.Duration) {
+	time.AfterFunc(after, func() {
+		if _, err := h.DB.EndSession(sessionID); err != nil && !errors.Is(err, database.ErrNotFound) {
+			log.Printf("[Session] Error ending session %s of map %s: %v", sessionID, mindMapID, err)
+		}
+	})
+}
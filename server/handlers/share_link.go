@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/export"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// shareSessionTTL is how long a view session of a password-protected link lasts
+	shareSessionTTL = time.Hour
+	// shareSessionHeader is the header the token of a view session is sent in
+	shareSessionHeader = "X-Share-Session"
+	// maxShareLinkPasswordLength is the longest password bcrypt can hash
+	maxShareLinkPasswordLength = 72
+)
+
+// ShareLinkHandler handles share links, which let anyone with the link view a mind map
+// without signing in, optionally behind a password and until an expiry
+type ShareLinkHandler struct {
+	DB *database.DB
+}
+
+// NewShareLinkHandler creates a new ShareLinkHandler
+func NewShareLinkHandler(db *database.DB) *ShareLinkHandler {
+	return &ShareLinkHandler{DB: db}
+}
+
+// hashShareToken returns the hash a share link or view session token is stored and looked
+// up by
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newShareToken generates a random token with the given prefix
+func newShareToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// HandleShareLinks handles the routes under /api/mindmaps/{id}/share-links, where the owner
+// of a map manages its share links: GET lists them, POST creates one, DELETE on
+// /{linkID} revokes one and GET on /{linkID}/analytics reports how one has been used
+func (h *ShareLinkHandler) HandleShareLinks(w http.ResponseWriter, r *http.Request) {
+	// Extract mind map ID, link ID and the rest of the URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID, rest, found := strings.Cut(path, "/share-links")
+	if path == r.URL.Path || !found {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	linkID, suffix, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+
+	// Parse mind map and link IDs
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+	if linkID != "" {
+		if _, err := uuid.Parse(linkID); err != nil {
+			http.Error(w, "Invalid share link ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check that the user owns the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if mindMap.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case linkID == "" && r.Method == http.MethodGet:
+		h.listShareLinks(w, mindMapID)
+	case linkID == "" && r.Method == http.MethodPost:
+		h.createShareLink(w, r, mindMapID, userID)
+	case linkID != "" && suffix == "" && r.Method == http.MethodDelete:
+		h.revokeShareLink(w, mindMapID, linkID)
+	case linkID != "" && suffix == "analytics" && r.Method == http.MethodGet:
+		h.getShareLinkAnalytics(w, r, mindMapID, linkID)
+	case suffix == "" || suffix == "analytics":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// listShareLinks returns the share links of a map
+func (h *ShareLinkHandler) listShareLinks(w http.ResponseWriter, mindMapID string) {
+	links, err := h.DB.GetShareLinks(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get share links: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return share links
+	sendJSONResponse(w, http.StatusOK, links)
+}
+
+// createShareLink creates a share link of a map, returning its token once
+func (h *ShareLinkHandler) createShareLink(w http.ResponseWriter, r *http.Request, mindMapID, userID string) {
+	// Parse request body
+	var req models.ShareLinkCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if len(req.Password) > maxShareLinkPasswordLength {
+		http.Error(w, fmt.Sprintf("Password must be at most %d characters", maxShareLinkPasswordLength), http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	var passwordHash string
+	if req.Password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to hash password: %v", err), http.StatusInternalServerError)
+			return
+		}
+		passwordHash = string(hashed)
+	}
+	token, err := newShareToken("share_")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	link, err := h.DB.CreateShareLink(mindMapID, userID, hashShareToken(token), passwordHash, req.ExpiresAt)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create share link: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created link with its token
+	sendJSONResponse(w, http.StatusCreated, models.ShareLinkCreated{
+		ShareLink: *link,
+		Token:     token,
+		URL:       os.Getenv("FRONTEND_URL") + "/shared/" + token,
+	})
+}
+
+// revokeShareLink revokes a share link so its token stops working, keeping its analytics
+func (h *ShareLinkHandler) revokeShareLink(w http.ResponseWriter, mindMapID, linkID string) {
+	link, err := h.DB.RevokeShareLink(mindMapID, linkID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to revoke share link: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return revoked link
+	sendJSONResponse(w, http.StatusOK, link)
+}
+
+// getShareLinkAnalytics reports the opens and exports of a share link over the last ?days
+// days, 30 by default and at most 365, in all, by referrer and by day
+func (h *ShareLinkHandler) getShareLinkAnalytics(w http.ResponseWriter, r *http.Request, mindMapID, linkID string) {
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		days = min(parsed, 365)
+	}
+
+	link, err := h.DB.GetShareLink(mindMapID, linkID)
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get share link: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	analytics, err := h.DB.GetShareLinkAnalytics(*link, since)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get share link analytics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return analytics
+	sendJSONResponse(w, http.StatusOK, analytics)
+}
+
+// HandleSharedMap handles the public routes of share links: GET /api/shared/{token} returns
+// the shared map with its nodes and edges, GET /api/shared/{token}/export exports it when
+// its owner allows exports, and POST /api/shared/{token}/session trades the password of a
+// protected link for a view session. The map of a protected link is only returned or
+// exported with a view session in the X-Share-Session header. Opens and exports are
+// recorded for the link's analytics
+func (h *ShareLinkHandler) HandleSharedMap(w http.ResponseWriter, r *http.Request) {
+	// Extract token and the rest of the URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/shared/")
+	if path == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	token, rest, _ := strings.Cut(path, "/")
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		h.getSharedMap(w, r, token)
+	case rest == "export" && r.Method == http.MethodGet:
+		h.exportSharedMap(w, r, token)
+	case rest == "session" && r.Method == http.MethodPost:
+		h.createShareSession(w, r, token)
+	case rest == "" || rest == "export" || rest == "session":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// shareLink returns the link with the given token, responding with an error and returning
+// nil if there is none or it has expired
+func (h *ShareLinkHandler) shareLink(w http.ResponseWriter, token string) *models.ShareLink {
+	link, err := h.DB.GetShareLinkByToken(hashShareToken(token))
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Share link not found or expired", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get share link: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+	return link
+}
+
+// authorizeShareLink returns the link with the given token, checking the view session of a
+// protected one. Responds with an error and returns nil if the link cannot be used
+func (h *ShareLinkHandler) authorizeShareLink(w http.ResponseWriter, r *http.Request, token string) *models.ShareLink {
+	link := h.shareLink(w, token)
+	if link == nil || !link.HasPassword {
+		return link
+	}
+
+	session := r.Header.Get(shareSessionHeader)
+	valid := false
+	if session != "" {
+		var err error
+		valid, err = h.DB.HasShareLinkSession(link.ID, hashShareToken(session))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to check session: %v", err), http.StatusInternalServerError)
+			return nil
+		}
+	}
+	if !valid {
+		http.Error(w, "This link is password protected; POST the password to /session", http.StatusUnauthorized)
+		return nil
+	}
+	return link
+}
+
+// sharedMindMap returns the map of a share link with its nodes and edges, without who wrote
+// what. Responds with an error and returns nil if it cannot be read
+func (h *ShareLinkHandler) sharedMindMap(w http.ResponseWriter, link *models.ShareLink) *models.MindMapWithDetails {
+	mindMap, err := h.DB.GetMindMapWithDetails(link.MindMapID)
+	if errors.Is(err, database.ErrNotFound) || errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Mind map not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return nil
+	}
+	models.MaskNodeAttribution(mindMap.Nodes)
+	return mindMap
+}
+
+// recordShareLinkEvent records a use of a share link with the host of its referrer
+func (h *ShareLinkHandler) recordShareLinkEvent(r *http.Request, link *models.ShareLink, eventType string) {
+	referrer := ""
+	if parsed, err := url.Parse(r.Referer()); err == nil {
+		referrer = strings.ToLower(parsed.Hostname())
+	}
+	if len(referrer) > 255 {
+		referrer = referrer[:255]
+	}
+	if err := h.DB.RecordShareLinkEvent(link.ID, eventType, referrer); err != nil {
+		log.Printf("[Share Links] Error recording %s of link %s: %v", eventType, link.ID, err)
+	}
+}
+
+// getSharedMap returns the map of a share link
+func (h *ShareLinkHandler) getSharedMap(w http.ResponseWriter, r *http.Request, token string) {
+	link := h.authorizeShareLink(w, r, token)
+	if link == nil {
+		return
+	}
+	mindMap := h.sharedMindMap(w, link)
+	if mindMap == nil {
+		return
+	}
+	h.recordShareLinkEvent(r, link, models.ShareEventOpen)
+
+	// Return the shared map
+	sendJSONResponse(w, http.StatusOK, mindMap)
+}
+
+// exportSharedMap exports the map of a share link with the options of the signed-in
+// export, except mp3, which needs an account's speech provider. Drawings carry the
+// watermark of the owner's plan
+func (h *ShareLinkHandler) exportSharedMap(w http.ResponseWriter, r *http.Request, token string) {
+	// Validate export options
+	opts, ok := parseExportOptions(w, r)
+	if !ok {
+		return
+	}
+	if opts.format == export.FormatMP3 {
+		http.Error(w, "Shared maps cannot be exported as mp3", http.StatusBadRequest)
+		return
+	}
+
+	link := h.authorizeShareLink(w, r, token)
+	if link == nil {
+		return
+	}
+	mindMap := h.sharedMindMap(w, link)
+	if mindMap == nil {
+		return
+	}
+	if !mindMap.AllowExport {
+		http.Error(w, exportDisabledError, http.StatusForbidden)
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	if err := renderExport(&buf, mindMap, opts, exportWatermark(h.DB, mindMap.UserID), nil); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.recordShareLinkEvent(r, link, models.ShareEventExport)
+
+	// Return export as a download
+	w.Header().Set("Content-Type", opts.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename(mindMap.Title, opts.format)))
+	w.Write(buf.Bytes())
+}
+
+// createShareSession checks the password of a protected share link and returns a view
+// session, which expires after an hour or with the link, whichever comes first
+func (h *ShareLinkHandler) createShareSession(w http.ResponseWriter, r *http.Request, token string) {
+	link := h.shareLink(w, token)
+	if link == nil {
+		return
+	}
+	if !link.HasPassword {
+		http.Error(w, "This link has no password", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req models.ShareSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(req.Password)); err != nil {
+		http.Error(w, "Incorrect password", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := newShareToken("view_")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(shareSessionTTL)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expiresAt) {
+		expiresAt = *link.ExpiresAt
+	}
+	if err := h.DB.CreateShareLinkSession(link.ID, hashShareToken(session), expiresAt); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return the session
+	sendJSONResponse(w, http.StatusCreated, models.ShareSession{Token: session, ExpiresAt: expiresAt})
+}
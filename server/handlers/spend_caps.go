@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// aiSpendCapError is returned when a generation would go beyond a mind map's AI spend cap
+type aiSpendCapError struct {
+	reason string
+}
+
+func (e *aiSpendCapError) Error() string {
+	return "AI spend cap reached: " + e.reason
+}
+
+// GetSpendCap handles GET /api/mindmaps/{id}/spend-cap, returning the map's AI spend cap
+// with the usage counted against it
+func (h *MindMapHandler) GetSpendCap(w http.ResponseWriter, r *http.Request) {
+	mindMap, _, ok := h.spendCapMindMap(w, r, false)
+	if !ok {
+		return
+	}
+
+	spendCap, err := h.DB.GetAISpendCap(mindMap.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get spend cap: %v", err), http.StatusInternalServerError)
+		return
+	}
+	status := models.AISpendCapStatus{AISpendCap: *spendCap}
+	status.Usage, err = mindMapAIUsage(h.DB, mindMap.ID, time.Time{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+	session, err := h.DB.GetActiveSession(mindMap.ID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if session != nil {
+		sessionUsage, err := mindMapAIUsage(h.DB, mindMap.ID, session.StartedAt)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get usage: %v", err), http.StatusInternalServerError)
+			return
+		}
+		status.SessionID = &session.ID
+		status.SessionUsage = &sessionUsage
+	}
+
+	// Return spend cap
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// UpdateSpendCap handles PUT /api/mindmaps/{id}/spend-cap, replacing the map's AI spend
+// cap. Only the owner may change it
+func (h *MindMapHandler) UpdateSpendCap(w http.ResponseWriter, r *http.Request) {
+	mindMap, userID, ok := h.spendCapMindMap(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var req models.AISpendCapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, limit := range []*float64{req.MaxCostUSD, req.SessionMaxCostUSD} {
+		if limit != nil && *limit <= 0 {
+			http.Error(w, "Cost limits must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, limit := range []*int{req.MaxTokens, req.SessionMaxTokens} {
+		if limit != nil && *limit <= 0 {
+			http.Error(w, "Token limits must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.OnExceed == "" {
+		req.OnExceed = models.SpendCapRefuse
+	}
+	if req.OnExceed != models.SpendCapRefuse && req.OnExceed != models.SpendCapDowngrade {
+		http.Error(w, "on_exceed must be 'refuse' or 'downgrade'", http.StatusBadRequest)
+		return
+	}
+
+	spendCap, err := h.DB.SetAISpendCap(mindMap.ID, userID, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update spend cap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return updated spend cap
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spendCap)
+}
+
+// DeleteSpendCap handles DELETE /api/mindmaps/{id}/spend-cap, lifting every limit of the
+// map. Only the owner may remove it
+func (h *MindMapHandler) DeleteSpendCap(w http.ResponseWriter, r *http.Request) {
+	mindMap, _, ok := h.spendCapMindMap(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.DeleteAISpendCap(mindMap.ID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete spend cap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// spendCapMindMap gets the mind map of /api/mindmaps/{id}/spend-cap and the user, checking
+// the user may generate in it or, with ownerOnly, owns it. Responds with an error and
+// returns false if either fails
+func (h *MindMapHandler) spendCapMindMap(w http.ResponseWriter, r *http.Request, ownerOnly bool) (*models.MindMap, string, bool) {
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/spend-cap")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return nil, "", false
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+	if ownerOnly && mindMap.UserID != userID {
+		http.Error(w, "Only the owner can change the spend cap", http.StatusForbidden)
+		return nil, "", false
+	}
+	return mindMap, userID, true
+}
+
+// mindMapAIUsage sums and prices the AI usage of a mind map since a time
+func mindMapAIUsage(db *database.DB, mindMapID string, since time.Time) (models.AIUsageTotals, error) {
+	var totals models.AIUsageTotals
+	buckets, err := db.GetMindMapAIUsage(mindMapID, since)
+	if err != nil {
+		return totals, err
+	}
+	for _, bucket := range buckets {
+		addUsage(&totals, bucketUsage(bucket.Provider, bucket.Model, bucket.Requests, bucket.PromptTokens, bucket.CompletionTokens))
+	}
+	return totals, nil
+}
+
+// spendCapReached describes which of the limits the usage has reached, or returns "" if
+// it has reached none. scope names whose usage it is
+func spendCapReached(scope string, usage models.AIUsageTotals, maxCostUSD *float64, maxTokens *int) string {
+	if maxCostUSD != nil && usage.EstimatedCostUSD >= *maxCostUSD {
+		return fmt.Sprintf("%s has used an estimated $%.2f of its $%.2f budget", scope, usage.EstimatedCostUSD, *maxCostUSD)
+	}
+	if tokens := usage.PromptTokens + usage.CompletionTokens; maxTokens != nil && tokens >= *maxTokens {
+		return fmt.Sprintf("%s has used %d of its %d tokens", scope, tokens, *maxTokens)
+	}
+	return ""
+}
+
+// enforceAISpendCap returns the model a generation for a mind map is made with under the
+// map's spend cap. Once the map or its running brainstorm session has reached a limit,
+// returns an aiSpendCapError, or with a downgrading cap the provider's cheapest model.
+// Maps without a cap keep the model
+func enforceAISpendCap(db *database.DB, mindMapID, provider, model string) (string, error) {
+	spendCap, err := db.GetAISpendCap(mindMapID)
+	if err != nil {
+		return "", err
+	}
+
+	// Check the map's limits, then those of the running session
+	var reason string
+	if spendCap.MaxCostUSD != nil || spendCap.MaxTokens != nil {
+		usage, err := mindMapAIUsage(db, mindMapID, time.Time{})
+		if err != nil {
+			return "", err
+		}
+		reason = spendCapReached("the mind map", usage, spendCap.MaxCostUSD, spendCap.MaxTokens)
+	}
+	if reason == "" && (spendCap.SessionMaxCostUSD != nil || spendCap.SessionMaxTokens != nil) {
+		session, err := db.GetActiveSession(mindMapID)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return "", err
+		}
+		if session != nil {
+			usage, err := mindMapAIUsage(db, mindMapID, session.StartedAt)
+			if err != nil {
+				return "", err
+			}
+			reason = spendCapReached("the brainstorm session", usage, spendCap.SessionMaxCostUSD, spendCap.SessionMaxTokens)
+		}
+	}
+	if reason == "" {
+		return model, nil
+	}
+
+	// Carry on with the cheapest model if the cap allows it and there is one
+	if spendCap.OnExceed == models.SpendCapDowngrade {
+		if cheapest := cheapestAIModel(provider); cheapest != "" {
+			if cheapest != model {
+				log.Printf("[SpendCap] Map %s reached its cap (%s); using %s instead of %s", mindMapID, reason, cheapest, model)
+			}
+			return cheapest, nil
+		}
+	}
+	return "", &aiSpendCapError{reason: reason}
+}
+
+// cheapestAIModel returns the priced model of a provider with the lowest list price that
+// requests may pick, or "" if none is priced
+func cheapestAIModel(provider string) string {
+	if provider == aiProviderOllama {
+		return ""
+	}
+	cheapest := ""
+	var lowest float64
+	for _, model := range allowedAIModels(provider) {
+		price, ok := aiPriceOf(provider, model)
+		if !ok {
+			continue
+		}
+		if cost := price.cost(1e6, 1e6); cheapest == "" || cost < lowest {
+			cheapest, lowest = model, cost
+		}
+	}
+	return cheapest
+}
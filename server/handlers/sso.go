@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/oidc"
+	"saas-server/pkg/saml"
+	"saas-server/pkg/validation"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ssoStateCookie carries a sign-in in progress from its start to the callback, as a
+// short-lived token signed with the JWT secret: the state with the nonce and PKCE verifier
+// of an OpenID Connect sign-in, or the request ID of a SAML sign-in and, once its
+// assertion is verified, the identity it carries
+const ssoStateCookie = "sso_state"
+
+// ssoStateTTL is how long a user has to sign in at their identity provider
+const ssoStateTTL = 10 * time.Minute
+
+// ssoRoleRank orders tenant roles so a user in several mapped groups gets the strongest
+var ssoRoleRank = map[string]int{
+	models.TenantRoleViewer: 1,
+	models.TenantRoleMember: 2,
+	models.TenantRoleAdmin:  3,
+}
+
+// SSOHandler signs users in through their tenant's OpenID Connect or SAML identity
+// provider and lets admins manage the connections. Users are provisioned on their first
+// sign-in, moved into the connection's tenant and given the role their provider groups
+// map to. SAML providers post their assertion to SAMLACS, which hands the verified
+// identity to the callback with a one-time code, so both protocols finish the same way
+type SSOHandler struct {
+	DB          *database.DB
+	auth        *AuthHandler
+	redirectURL string
+	saml        *saml.Config // Nil when SAML sign-in is not configured
+}
+
+// NewSSOHandler creates a new SSOHandler that issues sessions through the auth handler
+func NewSSOHandler(db *database.DB, auth *AuthHandler) *SSOHandler {
+	samlConfig, err := saml.ConfigFromEnv()
+	if err != nil {
+		log.Printf("[SSO] SAML sign-in is off: %v", err)
+	}
+	return &SSOHandler{
+		DB:          db,
+		auth:        auth,
+		redirectURL: os.Getenv("SSO_REDIRECT_URL"),
+		saml:        samlConfig,
+	}
+}
+
+// ssoState is the content of the state cookie
+type ssoState struct {
+	ConnectionID string       `json:"connection_id"`
+	State        string       `json:"state"`
+	Nonce        string       `json:"nonce"` // ID of the authentication request for SAML
+	Verifier     string       `json:"verifier,omitempty"`
+	Code         string       `json:"code,omitempty"`     // One-time code of a verified SAML assertion
+	Identity     *ssoIdentity `json:"identity,omitempty"` // Identity of a verified SAML assertion
+	jwt.RegisteredClaims
+}
+
+// ssoIdentity is who an identity provider signed in, with the role their groups map to
+type ssoIdentity struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+}
+
+// setStateCookie signs a sign-in in progress into the state cookie
+func (h *SSOHandler) setStateCookie(w http.ResponseWriter, state ssoState, sameSite http.SameSite) error {
+	value, err := jwt.NewWithClaims(jwt.SigningMethodHS256, state).SignedString(h.auth.jwtSecret)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    value,
+		Path:     "/auth/sso",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: sameSite,
+		Expires:  state.ExpiresAt.Time,
+	})
+	return nil
+}
+
+// takeStateCookie returns the sign-in in progress of the state cookie and clears the
+// cookie, so a state is used up by the first request that reads it
+func (h *SSOHandler) takeStateCookie(w http.ResponseWriter, r *http.Request) (*ssoState, error) {
+	cookie, err := r.Cookie(ssoStateCookie)
+	if err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    "",
+		Path:     "/auth/sso",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(-1 * time.Hour),
+	})
+	var state ssoState
+	_, err = jwt.ParseWithClaims(cookie.Value, &state, func(token *jwt.Token) (interface{}, error) {
+		return h.auth.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Start handles POST /auth/sso/start. It finds the connection the email's domain signs in
+// through and returns the URL of its identity provider to send the user to
+func (h *SSOHandler) Start(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.SSOStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Email = validation.SanitizeInput(req.Email, 255)
+	if !validation.ValidateEmail(req.Email) {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid email format")
+		return
+	}
+
+	connection, err := h.DB.GetSSOConnectionByEmail(req.Email)
+	if err == database.ErrNotFound {
+		sendErrorResponse(w, http.StatusNotFound, "Single sign-on is not set up for this email domain")
+		return
+	}
+	if err != nil {
+		log.Printf("[SSO] Error finding connection for %s: %v", database.EmailDomain(req.Email), err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	state := ssoState{
+		ConnectionID: connection.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ssoStateTTL)),
+		},
+	}
+	if state.State, err = oidc.RandomString(); err != nil {
+		log.Printf("[SSO] Error generating state: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	var authorizationURL string
+	sameSite := http.SameSiteLaxMode
+	if connection.Protocol == models.SSOProtocolSAML {
+		if h.saml == nil {
+			log.Printf("[SSO] Connection %s uses SAML, which is not configured", connection.ID)
+			sendErrorResponse(w, http.StatusServiceUnavailable, "Single sign-on is unavailable")
+			return
+		}
+		authorizationURL, state.Nonce, err = h.saml.AuthenticationURL([]byte(connection.IDPMetadata), state.State)
+		if err != nil {
+			log.Printf("[SSO] Error building SAML request of connection %s: %v", connection.ID, err)
+			sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		// The provider posts its response back from its own site
+		sameSite = http.SameSiteNoneMode
+	} else {
+		provider, err := oidc.Discover(r.Context(), connection.Issuer)
+		if err != nil {
+			log.Printf("[SSO] Error discovering provider of connection %s: %v", connection.ID, err)
+			sendErrorResponse(w, http.StatusBadGateway, "Identity provider is unavailable")
+			return
+		}
+		for _, value := range []*string{&state.Nonce, &state.Verifier} {
+			if *value, err = oidc.RandomString(); err != nil {
+				log.Printf("[SSO] Error generating state: %v", err)
+				sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+		}
+		config := provider.OAuth2Config(connection.ClientID, "", h.redirectURL)
+		authorizationURL = oidc.AuthCodeURL(config, state.State, state.Nonce, state.Verifier)
+	}
+
+	if err := h.setStateCookie(w, state, sameSite); err != nil {
+		log.Printf("[SSO] Error signing state: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return where to sign in
+	sendJSONResponse(w, http.StatusOK, models.SSOStartResponse{AuthorizationURL: authorizationURL})
+}
+
+// Callback handles POST /auth/sso/callback with the code and state the user was
+// redirected back with. It verifies the OpenID Connect ID token, or takes the identity of
+// the SAML assertion the code was issued for, provisions the user on their first sign-in,
+// assigns them to the connection's tenant and role, and starts their session
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.SSOCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// The state must match the one issued to this browser, which is then used up
+	state, err := h.takeStateCookie(w, r)
+	if err != nil || req.State == "" || req.State != state.State {
+		sendErrorResponse(w, http.StatusBadRequest, "Single sign-on was not started or has expired")
+		return
+	}
+
+	connection, err := h.DB.GetSSOConnectionByID(state.ConnectionID)
+	if err == database.ErrNotFound {
+		sendErrorResponse(w, http.StatusBadRequest, "Single sign-on is no longer set up for this email domain")
+		return
+	}
+	if err != nil {
+		log.Printf("[SSO] Error getting connection %s: %v", state.ConnectionID, err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	identity := state.Identity
+	if connection.Protocol == models.SSOProtocolSAML {
+		// The assertion was verified when it was posted; the code ties this request to it
+		if identity == nil || state.Code == "" || subtle.ConstantTimeCompare([]byte(req.Code), []byte(state.Code)) != 1 {
+			sendErrorResponse(w, http.StatusBadRequest, "Single sign-on was not started or has expired")
+			return
+		}
+	} else if identity = h.oidcIdentity(w, r, connection, state, req.Code); identity == nil {
+		return
+	}
+
+	user, err := h.provisionUser(connection, identity.Subject, identity.Email, identity.Name)
+	if err != nil {
+		log.Printf("[SSO] Error provisioning user through connection %s: %v", connection.ID, err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+
+	if err := h.DB.MoveUserToTenant(user.ID, connection.TenantID); err != nil {
+		log.Printf("[SSO] Error moving user %s to tenant %s: %v", user.ID, connection.TenantID, err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if err := h.DB.SetUserTenantRole(user.ID, identity.Role); err != nil {
+		log.Printf("[SSO] Error setting role of user %s: %v", user.ID, err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if err := h.auth.GenerateAuthResponse(w, r, user); err != nil {
+		log.Printf("[SSO] Error generating auth response: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Error processing single sign-on")
+		return
+	}
+}
+
+// oidcIdentity exchanges the code of an OpenID Connect sign-in and returns the identity
+// its ID token carries, or writes the error response and returns nil
+func (h *SSOHandler) oidcIdentity(w http.ResponseWriter, r *http.Request, connection *models.SSOConnection, state *ssoState, code string) *ssoIdentity {
+	provider, err := oidc.Discover(r.Context(), connection.Issuer)
+	if err != nil {
+		log.Printf("[SSO] Error discovering provider of connection %s: %v", connection.ID, err)
+		sendErrorResponse(w, http.StatusBadGateway, "Identity provider is unavailable")
+		return nil
+	}
+	clientSecret, err := h.DB.GetSSOClientSecret(connection)
+	if err != nil {
+		log.Printf("[SSO] Error decrypting client secret of connection %s: %v", connection.ID, err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return nil
+	}
+	config := provider.OAuth2Config(connection.ClientID, clientSecret, h.redirectURL)
+	claims, err := provider.Exchange(r.Context(), config, code, state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("[SSO] Failed to sign in through connection %s: %v", connection.ID, err)
+		sendErrorResponse(w, http.StatusUnauthorized, "Failed to authenticate with your identity provider")
+		return nil
+	}
+
+	// A provider may only sign in users of the domains it was set up for
+	email := strings.ToLower(claims.String("email"))
+	if !inSSODomains(connection, email) {
+		log.Printf("[SSO] Connection %s returned an email outside its domains", connection.ID)
+		sendErrorResponse(w, http.StatusForbidden, "Your identity provider returned an email outside your organization's domains")
+		return nil
+	}
+
+	// The email links the identity to an existing account and is trusted as verified, so
+	// the provider has to vouch for it
+	if !claims.Bool("email_verified") {
+		log.Printf("[SSO] Connection %s returned an unverified email", connection.ID)
+		sendErrorResponse(w, http.StatusForbidden, "Your identity provider has not verified your email")
+		return nil
+	}
+
+	return &ssoIdentity{
+		Subject: claims.String("sub"),
+		Email:   email,
+		Name:    claims.String("name"),
+		Role:    ssoRole(connection, claims.Strings(connection.GroupsClaim)),
+	}
+}
+
+// inSSODomains reports whether email is a valid address in one of the connection's domains
+func inSSODomains(connection *models.SSOConnection, email string) bool {
+	return validation.ValidateEmail(email) && containsString(connection.Domains, database.EmailDomain(email))
+}
+
+// provisionUser returns the user a provider's subject signs in as: the user it signed in
+// as before, else the existing user with the email, else a new user with a verified email
+func (h *SSOHandler) provisionUser(connection *models.SSOConnection, subject, email, name string) (*models.User, error) {
+	userID, err := h.DB.GetSSOIdentityUserID(connection.ID, subject)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+
+	var user *models.User
+	if err == nil {
+		if user, err = h.DB.GetUserByID(userID); err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = h.DB.GetUserByEmail(email)
+		if err != nil {
+			if name == "" {
+				name = strings.Split(email, "@")[0]
+			}
+			if user, err = h.DB.CreateUser(email, "", name, true); err != nil {
+				return nil, err
+			}
+			log.Printf("[SSO] Provisioned user %s through connection %s", user.ID, connection.ID)
+
+			// Track user signup with Plunk for new users
+			if err := trackUserSignup(user.Email, user.Name); err != nil {
+				log.Printf("[SSO] Error tracking user signup: %v", err)
+			}
+		}
+	}
+
+	if err := h.DB.LinkSSOIdentity(connection.ID, subject, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ssoRole returns the strongest role the user's groups map to, or the connection's
+// default role if none of them is mapped
+func ssoRole(connection *models.SSOConnection, groups []string) string {
+	role := ""
+	for _, group := range groups {
+		if mapped, ok := connection.GroupRoles[group]; ok && ssoRoleRank[mapped] > ssoRoleRank[role] {
+			role = mapped
+		}
+	}
+	if role == "" {
+		return connection.DefaultRole
+	}
+	return role
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConnections handles GET /admin/sso/connections
+func (h *SSOHandler) GetConnections(w http.ResponseWriter, r *http.Request) {
+	connections, err := h.DB.GetSSOConnections()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get SSO connections: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return connections
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(connections)
+}
+
+// CreateConnection handles POST /admin/sso/connections. The issuer of an OpenID Connect
+// connection must publish its configuration. A SAML connection's metadata is fetched from
+// its URL if given and stored, and its issuer is the provider's entity ID
+func (h *SSOHandler) CreateConnection(w http.ResponseWriter, r *http.Request) {
+	var req models.SSOConnectionCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if message := normalizeSSOConnection(&req); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	if req.Protocol == models.SSOProtocolSAML {
+		if h.saml == nil {
+			http.Error(w, "SAML sign-in is not configured: set SAML_ACS_URL", http.StatusBadRequest)
+			return
+		}
+		if req.IDPMetadataURL != "" {
+			metadata, err := saml.FetchMetadata(r.Context(), req.IDPMetadataURL)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid metadata URL: %v", err), http.StatusBadRequest)
+				return
+			}
+			req.IDPMetadata = string(metadata)
+		}
+		idp, err := saml.ParseMetadata([]byte(req.IDPMetadata))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid metadata: %v", err), http.StatusBadRequest)
+			return
+		}
+		req.Issuer = idp.EntityID
+	} else if _, err := oidc.Discover(r.Context(), req.Issuer); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid issuer: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	connection, err := h.DB.CreateSSOConnection(req)
+	if err == database.ErrNotFound {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	if err == database.ErrSSODomainTaken {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create SSO connection: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created connection
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(connection)
+}
+
+// normalizeSSOConnection trims and defaults a connection request and returns why it is
+// invalid, or an empty string if it is valid
+func normalizeSSOConnection(req *models.SSOConnectionCreateRequest) string {
+	if _, err := uuid.Parse(req.TenantID); err != nil {
+		return "Invalid tenant ID"
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 255 {
+		return "Name is required and must be at most 255 characters"
+	}
+
+	switch req.Protocol {
+	case "", models.SSOProtocolOIDC:
+		req.Protocol = models.SSOProtocolOIDC
+		req.Issuer = strings.TrimRight(strings.TrimSpace(req.Issuer), "/")
+		if !absoluteURL(req.Issuer) {
+			return "Issuer must be an absolute URL"
+		}
+		req.ClientID = strings.TrimSpace(req.ClientID)
+		if req.ClientID == "" || req.ClientSecret == "" {
+			return "Client ID and client secret are required"
+		}
+		req.IDPMetadataURL, req.IDPMetadata = "", ""
+	case models.SSOProtocolSAML:
+		req.IDPMetadataURL = strings.TrimSpace(req.IDPMetadataURL)
+		req.IDPMetadata = strings.TrimSpace(req.IDPMetadata)
+		if req.IDPMetadataURL == "" && req.IDPMetadata == "" {
+			return "Metadata URL or metadata is required"
+		}
+		if req.IDPMetadataURL != "" && !absoluteURL(req.IDPMetadataURL) {
+			return "Metadata URL must be an absolute URL"
+		}
+		req.Issuer, req.ClientID, req.ClientSecret = "", "", ""
+	default:
+		return fmt.Sprintf("Invalid protocol %q", req.Protocol)
+	}
+
+	if len(req.Domains) == 0 {
+		return "At least one domain is required"
+	}
+	for i, domain := range req.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@/ ") || len(domain) > 255 {
+			return fmt.Sprintf("Invalid domain %q", domain)
+		}
+		req.Domains[i] = domain
+	}
+
+	req.GroupsClaim = strings.TrimSpace(req.GroupsClaim)
+	if req.GroupsClaim == "" {
+		req.GroupsClaim = "groups"
+	}
+	if req.GroupRoles == nil {
+		req.GroupRoles = map[string]string{}
+	}
+	for group, role := range req.GroupRoles {
+		if !models.ValidTenantRole(role) {
+			return fmt.Sprintf("Invalid role %q for group %q", role, group)
+		}
+	}
+	if req.DefaultRole == "" {
+		req.DefaultRole = models.TenantRoleMember
+	}
+	if !models.ValidTenantRole(req.DefaultRole) {
+		return fmt.Sprintf("Invalid default role %q", req.DefaultRole)
+	}
+	return ""
+}
+
+// absoluteURL reports whether value is an absolute http or https URL
+func absoluteURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
+
+// DeleteConnection handles DELETE /admin/sso/connections/{id}
+func (h *SSOHandler) DeleteConnection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract connection ID from URL
+	connectionID := strings.TrimPrefix(r.URL.Path, "/admin/sso/connections/")
+	if _, err := uuid.Parse(connectionID); err != nil {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	err := h.DB.DeleteSSOConnection(connectionID)
+	if err == database.ErrNotFound {
+		http.Error(w, "SSO connection not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete SSO connection: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
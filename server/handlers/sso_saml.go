@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/oidc"
+	"strings"
+)
+
+// SAMLMetadata handles GET /auth/sso/saml/metadata with the service provider metadata
+// admins register at their SAML identity provider
+func (h *SSOHandler) SAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.saml == nil {
+		http.Error(w, "SAML sign-in is not configured", http.StatusNotFound)
+		return
+	}
+
+	metadata, err := h.saml.Metadata()
+	if err != nil {
+		log.Printf("[SSO] Error building SAML metadata: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(metadata)
+}
+
+// SAMLACS handles POST /auth/sso/saml/acs, where SAML identity providers post their
+// response. It verifies the assertion answers the request this browser started and checks
+// its email like the OpenID Connect callback does, then sends the user to
+// SSO_REDIRECT_URL with a one-time code and the state, which the client posts to the
+// callback as it does for OpenID Connect. Failures go there as error and
+// error_description, as OAuth providers report them
+func (h *SSOHandler) SAMLACS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, err := h.takeStateCookie(w, r)
+	if err != nil || state.State == "" || r.PostFormValue("RelayState") != state.State {
+		h.samlRedirect(w, r, url.Values{
+			"error":             {"invalid_request"},
+			"error_description": {"Single sign-on was not started or has expired"},
+		})
+		return
+	}
+	fail := func(description string) {
+		h.samlRedirect(w, r, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {description},
+			"state":             {state.State},
+		})
+	}
+
+	connection, err := h.DB.GetSSOConnectionByID(state.ConnectionID)
+	if err == database.ErrNotFound || (err == nil && connection.Protocol != models.SSOProtocolSAML) {
+		fail("Single sign-on is no longer set up for this email domain")
+		return
+	}
+	if err != nil {
+		log.Printf("[SSO] Error getting connection %s: %v", state.ConnectionID, err)
+		fail("Internal server error")
+		return
+	}
+	if h.saml == nil {
+		log.Printf("[SSO] Connection %s uses SAML, which is not configured", connection.ID)
+		fail("Single sign-on is unavailable")
+		return
+	}
+
+	assertion, err := h.saml.ParseResponse(r, []byte(connection.IDPMetadata), state.Nonce)
+	if err != nil {
+		log.Printf("[SSO] Failed to sign in through connection %s: %v", connection.ID, err)
+		fail("Failed to authenticate with your identity provider")
+		return
+	}
+
+	// A provider may only sign in users of the domains it was set up for. Its signature
+	// over the assertion vouches for the email as email_verified does for OpenID Connect
+	email := strings.ToLower(assertion.Email())
+	if !inSSODomains(connection, email) {
+		log.Printf("[SSO] Connection %s returned an email outside its domains", connection.ID)
+		fail("Your identity provider returned an email outside your organization's domains")
+		return
+	}
+
+	state.Identity = &ssoIdentity{
+		Subject: assertion.Subject,
+		Email:   email,
+		Name:    assertion.Name(),
+		Role:    ssoRole(connection, assertion.Strings(connection.GroupsClaim)),
+	}
+	if state.Code, err = oidc.RandomString(); err == nil {
+		err = h.setStateCookie(w, *state, http.SameSiteLaxMode)
+	}
+	if err != nil {
+		log.Printf("[SSO] Error signing state: %v", err)
+		fail("Internal server error")
+		return
+	}
+
+	h.samlRedirect(w, r, url.Values{"code": {state.Code}, "state": {state.State}})
+}
+
+// samlRedirect sends the user from the assertion consumer service to SSO_REDIRECT_URL
+// with the given query
+func (h *SSOHandler) samlRedirect(w http.ResponseWriter, r *http.Request, query url.Values) {
+	target, err := url.Parse(h.redirectURL)
+	if err != nil || h.redirectURL == "" {
+		log.Printf("[SSO] SSO_REDIRECT_URL is not a valid URL")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusSeeOther)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stats history window limits, in days
+const (
+	defaultStatsHistoryDays = 90
+	maxStatsHistoryDays     = 365
+)
+
+// GetMindMapStatsHistory handles GET /api/mindmaps/{id}/stats/history?days=90, returning
+// the map's daily node and edge counts so clients can chart its growth
+func (h *MindMapHandler) GetMindMapStatsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/stats/history")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse history window
+	days := defaultStatsHistoryDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxStatsHistoryDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxStatsHistoryDays), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get history, counting today as one of the days
+	since := time.Now().UTC().AddDate(0, 0, 1-days)
+	points, err := readDB(h.DB, r).GetMindMapStatsHistory(mindMapID, since)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get stats history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return stats history
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.MindMapStatsHistory{MindMapID: mindMapID, Points: points})
+}
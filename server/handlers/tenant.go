@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// TenantHandler lets admins manage tenants, the organizations whose data is kept apart
+type TenantHandler struct {
+	DB *database.DB
+}
+
+// NewTenantHandler creates a new TenantHandler
+func NewTenantHandler(db *database.DB) *TenantHandler {
+	return &TenantHandler{DB: db}
+}
+
+// GetTenants handles GET /admin/tenants
+func (h *TenantHandler) GetTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.DB.GetTenants()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get tenants: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return tenants
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenants)
+}
+
+// CreateTenant handles POST /admin/tenants
+func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req models.TenantCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 255 {
+		http.Error(w, "Name is required and must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	tenant, err := h.DB.CreateTenant(req.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create tenant: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created tenant
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tenant)
+}
+
+// AddTenantUser handles POST /admin/tenants/{id}/users, moving a user into the tenant
+// together with the mind maps and API keys they own
+func (h *TenantHandler) AddTenantUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract tenant ID from URL
+	tenantID := strings.TrimPrefix(r.URL.Path, "/admin/tenants/")
+	tenantID = strings.TrimSuffix(tenantID, "/users")
+	if tenantID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.TenantMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err := h.DB.MoveUserToTenant(req.UserID, tenantID)
+	if err == database.ErrNotFound {
+		http.Error(w, "Tenant or user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to move user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
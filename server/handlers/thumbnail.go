@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/pkg/thumbnail"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetThumbnail handles GET /api/mindmaps/{id}/thumbnail. It serves the stored SVG preview,
+// which a background job keeps up to date, and renders it on the spot the first time
+func (h *MindMapHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/thumbnail")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the stored preview, rendering it if the map has none yet
+	preview, err := h.DB.GetThumbnail(mindMapID)
+	if errors.Is(err, database.ErrNotFound) {
+		preview, err = thumbnail.Generate(h.DB, mindMapID)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get thumbnail: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// The preview only changes when the map does, so let clients revalidate by sequence number
+	etag := fmt.Sprintf(`"%d"`, preview.ChangeSeq)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// Return preview
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Write([]byte(preview.SVG))
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/models"
+	"saas-server/pkg/links"
+	"saas-server/pkg/outline"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Table of contents content and placement: the index branch sits left of the root with
+// its entries stacked in a column further left
+const (
+	tocTitle        = "Table of contents"
+	tocOffsetX      = -250
+	tocEntryOffsetX = -250
+	tocEntrySpacing = 70
+)
+
+// CreateTableOfContents handles POST /api/mindmaps/{id}/toc. It creates an index branch
+// under the root, or refreshes the existing one, listing a link to every top-level branch
+// with the number of nodes in it
+func (h *MindMapHandler) CreateTableOfContents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/toc")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user can edit the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canEditMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	toc, entries, ok := buildTableOfContents(mindMapID, userID, nodes)
+	if !ok {
+		http.Error(w, "Mind map has no branches to list", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.DB.SaveTableOfContents(toc, entries)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save table of contents: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return table of contents
+	w.Header().Set("Content-Type", "application/json")
+	if result.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// buildTableOfContents prepares the table of contents node and one link node per
+// top-level branch, that is every child of a root other than the table of contents
+// itself. The index branch keeps its place when it already exists and is otherwise put
+// next to the first root. Returns false when the map has no branches
+func buildTableOfContents(mindMapID, userID string, nodes []models.Node) (models.NodeCreateRequest, []models.NodeCreateRequest, bool) {
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil || len(roots) == 0 {
+		return models.NodeCreateRequest{}, nil, false
+	}
+
+	var existing *models.Node
+	var branches []*outline.Item
+	for _, root := range roots {
+		if root.Node.NodeType == models.NodeTypeTOC {
+			continue
+		}
+		for _, child := range root.Children {
+			if child.Node.NodeType == models.NodeTypeTOC {
+				if existing == nil {
+					existing = &child.Node
+				}
+				continue
+			}
+			branches = append(branches, child)
+		}
+	}
+	if len(branches) == 0 {
+		return models.NodeCreateRequest{}, nil, false
+	}
+
+	root := roots[0].Node
+	toc := models.NodeCreateRequest{
+		MindMapID: mindMapID,
+		ParentID:  &root.ID,
+		Content:   fmt.Sprintf("%s (%d branches)", tocTitle, len(branches)),
+		PositionX: root.PositionX + tocOffsetX,
+		PositionY: root.PositionY,
+		NodeType:  models.NodeTypeTOC,
+		CreatedBy: userID,
+	}
+	if existing != nil {
+		toc.PositionX, toc.PositionY = existing.PositionX, existing.PositionY
+	}
+
+	entries := make([]models.NodeCreateRequest, 0, len(branches))
+	for i, branch := range branches {
+		count := len(outline.Flatten([]*outline.Item{branch}))
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"target_mind_map_id": mindMapID,
+			"target_node_id":     branch.Node.ID,
+			"node_count":         count,
+		})
+		entries = append(entries, models.NodeCreateRequest{
+			MindMapID: mindMapID,
+			Content:   fmt.Sprintf("%s (%d)", branch.Node.Content, count),
+			PositionX: toc.PositionX + tocEntryOffsetX,
+			PositionY: toc.PositionY + (float64(i)-float64(len(branches)-1)/2)*tocEntrySpacing,
+			NodeType:  links.NodeTypeLink,
+			Metadata:  metadata,
+			CreatedBy: userID,
+		})
+	}
+	return toc, entries, true
+}
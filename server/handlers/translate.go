@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/pii"
+	"saas-server/pkg/prompt"
+	"saas-server/pkg/validation"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// translationBatchSize is how many texts are sent to the model per request
+const translationBatchSize = 40
+
+// TranslateMindMap handles POST /api/mindmaps/{id}/translate?lang=es. The title and every
+// node's content are translated, reusing cached translations and sending the rest to the
+// model in batches. By default the translation goes into a copy of the map owned by the
+// user; with in_place=true the map itself is changed, which needs edit access. Structure
+// and styles are kept as they are
+func (h *MindMapHandler) TranslateMindMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/translate")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	language := r.URL.Query().Get("lang")
+	if err := validation.ValidateLanguage(language); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inPlace, _ := strconv.ParseBool(r.URL.Query().Get("in_place"))
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) || (inPlace && !canEditMindMap(h.DB, mindMap, userID)) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !inPlace && !canExportMindMap(h.DB, mindMap, userID) {
+		http.Error(w, exportDisabledError, http.StatusForbidden)
+		return
+	}
+
+	nodes, err := tenantDB(h.DB, r).GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get nodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Collect each distinct text once
+	texts := []string{mindMap.Title}
+	seen := map[string]bool{mindMap.Title: true}
+	for _, node := range nodes {
+		if strings.TrimSpace(node.Content) != "" && !seen[node.Content] {
+			seen[node.Content] = true
+			texts = append(texts, node.Content)
+		}
+	}
+
+	hashes := make([]string, len(texts))
+	for i, text := range texts {
+		hashes[i] = database.TranslationHash(text)
+	}
+	cached, err := h.DB.GetCachedTranslations(language, hashes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get cached translations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	translations := make(map[string]string, len(texts))
+	var missing []string
+	for i, text := range texts {
+		if translated, ok := cached[hashes[i]]; ok {
+			translations[text] = translated
+		} else {
+			missing = append(missing, text)
+		}
+	}
+
+	if len(missing) > 0 {
+		apiKey, err := resolveOpenAIKey(h.DB, userID, "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if apiKey == "" {
+			http.Error(w, "Translation needs an OpenAI API key", http.StatusBadRequest)
+			return
+		}
+
+		redactor := piiRedactor(h.DB, mindMap)
+		fresh := make(map[string]string, len(missing))
+		for start := 0; start < len(missing); start += translationBatchSize {
+			batch := missing[start:min(start+translationBatchSize, len(missing))]
+			translated, usage, err := translateWithAI(r.Context(), apiKey, language, batch, redactor)
+			recordAIUsage(h.DB, userID, mindMapID, aiFeatureTranslate, usage)
+			if err != nil {
+				sendAIError(w, "Failed to translate", err)
+				return
+			}
+			for i, text := range batch {
+				translations[text] = translated[i]
+				fresh[database.TranslationHash(text)] = translated[i]
+			}
+		}
+
+		// The cache only saves work next time, so a failure does not fail the request
+		if err := h.DB.SaveCachedTranslations(language, fresh); err != nil {
+			log.Printf("[Translate] Error caching translations for map %s: %v", mindMapID, err)
+		}
+	}
+
+	title := translations[mindMap.Title]
+	if title == "" {
+		title = mindMap.Title
+	}
+	translatedMap, nodeIDs, err := h.DB.TranslateMindMap(mindMapID, userID, title, translations, !inPlace)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save translation: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	if inPlace {
+		status = http.StatusOK
+	}
+
+	// Return translated map
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.TranslationResult{
+		SourceMindMapID: mindMapID,
+		MindMap:         translatedMap,
+		Language:        language,
+		InPlace:         inPlace,
+		NodeIDs:         nodeIDs,
+		Translated:      len(missing),
+		Cached:          len(texts) - len(missing),
+	})
+}
+
+// translateWithAI asks the model to translate a batch of texts, returning the translations
+// in the same order and the usage of the request
+func translateWithAI(ctx context.Context, apiKey, language string, texts []string, redactor *pii.Redactor) ([]string, aiUsage, error) {
+	cleaned := make([]string, len(texts))
+	for i, text := range texts {
+		cleaned[i] = prompt.Clean(redactor.Redact(text))
+	}
+	input, err := json.Marshal(cleaned)
+	if err != nil {
+		return nil, aiUsage{}, err
+	}
+
+	content, usage, err := openAIChatCompletion(
+		ctx,
+		apiKey,
+		prompt.System(fmt.Sprintf("You translate the labels of a mind map into the language with the code %q. Keep each label's meaning, tone, brevity, line breaks, emoji, URLs and markdown link targets unchanged apart from the translated words. You are given a JSON array of labels; respond only with a JSON array of the translated labels, in the same order and of the same length.", language)),
+		prompt.Delimit(string(input)),
+		3000,
+	)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, usage, fmt.Errorf("no JSON array in translation response")
+	}
+
+	var translated []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &translated); err != nil {
+		return nil, usage, err
+	}
+	if len(translated) != len(texts) {
+		return nil, usage, fmt.Errorf("expected %d translations, got %d", len(texts), len(translated))
+	}
+	for i := range translated {
+		translated[i] = redactor.Restore(translated[i])
+		// Keep the original rather than blanking a label the model dropped
+		if strings.TrimSpace(translated[i]) == "" {
+			translated[i] = texts[i]
+		}
+	}
+	return translated, usage, nil
+}
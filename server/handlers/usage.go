@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// AI usage report window limits, in days
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 365
+)
+
+// UsageHandler reports each user's consumption of AI features
+type UsageHandler struct {
+	DB *database.DB
+}
+
+// NewUsageHandler creates a new UsageHandler
+func NewUsageHandler(db *database.DB) *UsageHandler {
+	return &UsageHandler{DB: db}
+}
+
+// GetUsage handles GET /api/usage?days=30, returning the user's AI requests, tokens and
+// estimated cost by day and by mind map
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse report window
+	days := defaultUsageDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxUsageDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxUsageDays), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Get usage, counting today as one of the days
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days)
+	buckets, err := readDB(h.DB, r).GetAIUsage(userID, since)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return usage report
+	report := buildUsageReport(buckets)
+	report.Since = since.Format("2006-01-02")
+	report.Days = days
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// buildUsageReport prices the usage buckets and sums them overall, by day and by mind map.
+// Days come oldest first and mind maps by estimated cost, most expensive first
+func buildUsageReport(buckets []models.AIUsageBucket) models.AIUsageReport {
+	report := models.AIUsageReport{
+		ByDay:     []models.AIUsageDay{},
+		ByMindMap: []models.AIUsageMindMap{},
+	}
+	dayIndex := make(map[string]int)
+	mindMapIndex := make(map[string]int)
+
+	for _, bucket := range buckets {
+		totals := bucketUsage(bucket.Provider, bucket.Model, bucket.Requests, bucket.PromptTokens, bucket.CompletionTokens)
+		addUsage(&report.Totals, totals)
+
+		i, ok := dayIndex[bucket.Date]
+		if !ok {
+			i = len(report.ByDay)
+			dayIndex[bucket.Date] = i
+			report.ByDay = append(report.ByDay, models.AIUsageDay{Date: bucket.Date})
+		}
+		addUsage(&report.ByDay[i].AIUsageTotals, totals)
+
+		if bucket.MindMapID == nil {
+			continue
+		}
+		i, ok = mindMapIndex[*bucket.MindMapID]
+		if !ok {
+			i = len(report.ByMindMap)
+			mindMapIndex[*bucket.MindMapID] = i
+			report.ByMindMap = append(report.ByMindMap, models.AIUsageMindMap{MindMapID: *bucket.MindMapID, Title: bucket.MindMapTitle})
+		}
+		addUsage(&report.ByMindMap[i].AIUsageTotals, totals)
+	}
+
+	sort.SliceStable(report.ByMindMap, func(i, j int) bool {
+		return report.ByMindMap[i].EstimatedCostUSD > report.ByMindMap[j].EstimatedCostUSD
+	})
+	return report
+}
+
+// addUsage adds usage to a running total
+func addUsage(total *models.AIUsageTotals, usage models.AIUsageTotals) {
+	total.Requests += usage.Requests
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.EstimatedCostUSD += usage.EstimatedCostUSD
+	total.UnpricedRequests += usage.UnpricedRequests
+}
+
+// bucketUsage prices the usage of a model, counting it as unpriced if the model has no
+// known price
+func bucketUsage(provider, model string, requests, promptTokens, completionTokens int) models.AIUsageTotals {
+	totals := models.AIUsageTotals{
+		Requests:         requests,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}
+	if price, ok := aiPriceOf(provider, model); ok {
+		totals.EstimatedCostUSD = price.cost(promptTokens, completionTokens)
+	} else {
+		totals.UnpricedRequests = requests
+	}
+	return totals
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/realtime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxVoteLimit caps how many votes a map owner can give each participant
+const maxVoteLimit = 100
+
+// VoteHandler handles dot voting on mind map nodes
+type VoteHandler struct {
+	DB  *database.DB
+	Hub *realtime.Hub
+}
+
+// NewVoteHandler creates a new VoteHandler
+func NewVoteHandler(db *database.DB, hub *realtime.Hub) *VoteHandler {
+	return &VoteHandler{DB: db, Hub: hub}
+}
+
+// CastVote handles POST /api/nodes/{id}/vote
+func (h *VoteHandler) CastVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract node ID from URL
+	nodeID := strings.TrimPrefix(r.URL.Path, "/api/nodes/")
+	nodeID = strings.TrimSuffix(nodeID, "/vote")
+	if nodeID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse node ID
+	if _, err := uuid.Parse(nodeID); err != nil {
+		http.Error(w, "Invalid node ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get node
+	node, err := tenantDB(h.DB, r).GetNodeByID(nodeID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get node: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Anyone who can see the map can vote on it
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(node.MindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// While a session is running, votes are only accepted during its voting phase
+	session, err := h.DB.GetActiveSession(mindMap.ID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to get session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if session != nil && !session.VotingOpen(time.Now()) {
+		http.Error(w, "Voting is closed for the current session", http.StatusForbidden)
+		return
+	}
+
+	// Cast vote
+	vote, err := h.DB.CastVote(nodeID, mindMap.ID, userID, mindMap.VoteLimit)
+	if errors.Is(err, database.ErrVoteLimitReached) {
+		http.Error(w, fmt.Sprintf("Vote limit of %d reached for this mind map", mindMap.VoteLimit), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to cast vote: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return created vote
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(vote)
+}
+
+// RemoveVote handles DELETE /api/nodes/{id}/vote
+func (h *VoteHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract node ID from URL
+	nodeID := strings.TrimPrefix(r.URL.Path, "/api/nodes/")
+	nodeID = strings.TrimSuffix(nodeID, "/vote")
+	if nodeID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse node ID
+	if _, err := uuid.Parse(nodeID); err != nil {
+		http.Error(w, "Invalid node ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Remove the most recent of the user's votes on this node
+	if err := h.DB.RemoveVote(nodeID, userID); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.Error(w, "No vote to remove", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to remove vote: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Vote removed successfully"})
+}
+
+// GetVoteResults handles GET /api/mindmaps/{id}/votes
+func (h *VoteHandler) GetVoteResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract mind map ID from URL
+	mindMapID := strings.TrimPrefix(r.URL.Path, "/api/mindmaps/")
+	mindMapID = strings.TrimSuffix(mindMapID, "/votes")
+	if mindMapID == r.URL.Path {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	// Parse mind map ID
+	if _, err := uuid.Parse(mindMapID); err != nil {
+		http.Error(w, "Invalid mind map ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if user has access to the mind map
+	mindMap, err := tenantDB(h.DB, r).GetMindMapByID(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get mind map: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canViewMindMap(h.DB, mindMap, userID) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get ranked results and the caller's remaining votes
+	results, err := h.DB.GetVoteResults(mindMapID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get vote results: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	used, err := h.DB.CountUserVotes(mindMapID, userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count votes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	remaining := mindMap.VoteLimit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	// Return results
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.VoteResults{
+		MindMapID:      mindMapID,
+		VoteLimit:      mindMap.VoteLimit,
+		VotesUsed:      used,
+		VotesRemaining: remaining,
+		Results:        results,
+	})
+}
@@ -9,14 +9,21 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"saas-server/database"
 	"saas-server/handlers"
 	"saas-server/middleware"
+	"saas-server/pkg/outbox"
+	"saas-server/pkg/realtime"
+	"saas-server/pkg/retention"
+	"saas-server/pkg/stats"
+	"saas-server/pkg/thumbnail"
 
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/cors"
 )
 
@@ -54,6 +61,14 @@ func main() {
 	}
 	defer db.Close()
 
+	// Serve read-heavy queries from a read replica when configured
+	if replicaURL := os.Getenv("DB_READ_REPLICA_URL"); replicaURL != "" {
+		if err := db.AttachReplica(replicaURL); err != nil {
+			log.Fatal("Error connecting to read replica:", err)
+		}
+		log.Println("Connected to read replica")
+	}
+
 	// Run database migrations
 	migrationManager := database.NewMigrationManager(db)
 	if err := migrationManager.RunMigrations(); err != nil {
@@ -61,6 +76,21 @@ func main() {
 	}
 	log.Println("Database migrations applied successfully")
 
+	// Connect to Redis when configured; it is needed to run more than one server instance
+	var redisClient *redis.Client
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		redisOptions, err := redis.ParseURL(redisURL)
+		if err != nil {
+			log.Fatal("Error parsing REDIS_URL:", err)
+		}
+		redisClient = redis.NewClient(redisOptions)
+		defer redisClient.Close()
+		log.Println("Connected to Redis")
+
+		// Keep rate limit and quota counters in Redis so they are shared by every instance
+		middleware.UseCounterStore(middleware.NewRedisCounterStore(redisClient))
+	}
+
 	// Initialize handlers and middleware
 	authHandler := handlers.NewAuthHandler(db, os.Getenv("JWT_SECRET"))
 	authMiddleware := middleware.NewAuthMiddleware(db, os.Getenv("JWT_SECRET"))
@@ -81,6 +111,13 @@ func main() {
 	mux.HandleFunc("/auth/refresh", authHandler.RefreshToken)
 	mux.HandleFunc("/auth/verify", authHandler.VerifyEmail)
 
+	// Single sign-on routes (public)
+	ssoHandler := handlers.NewSSOHandler(db, authHandler)
+	mux.HandleFunc("/auth/sso/start", ssoHandler.Start)
+	mux.HandleFunc("/auth/sso/callback", ssoHandler.Callback)
+	mux.HandleFunc("/auth/sso/saml/acs", ssoHandler.SAMLACS)
+	mux.HandleFunc("/auth/sso/saml/metadata", ssoHandler.SAMLMetadata)
+
 	// Auth Routes (protected)
 	mux.Handle("/auth/verify-email", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.SendVerificationEmail)))
 	mux.Handle("/auth/logout", authMiddleware.RequireAuth(http.HandlerFunc(authHandler.Logout)))
@@ -117,6 +154,92 @@ func main() {
 	mux.HandleFunc("/admin/login", adminHandler.Login)
 	mux.Handle("/admin/users", adminMiddleware.RequireAdmin(http.HandlerFunc(adminHandler.GetUsers)))
 
+	// Tenant routes (admin)
+	tenantHandler := handlers.NewTenantHandler(db)
+	mux.Handle("/admin/tenants", adminMiddleware.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			tenantHandler.GetTenants(w, r)
+		case http.MethodPost:
+			tenantHandler.CreateTenant(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	scimHandler := handlers.NewSCIMHandler(db)
+	mux.Handle("/admin/tenants/", adminMiddleware.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/scim-token") {
+			scimHandler.CreateToken(w, r)
+		} else {
+			tenantHandler.AddTenantUser(w, r)
+		}
+	})))
+
+	// SCIM routes (authenticated by the tenant's SCIM token)
+	scimAuth := middleware.NewSCIMAuth(db)
+	mux.Handle("/scim/v2/ServiceProviderConfig", scimAuth.RequireToken(http.HandlerFunc(scimHandler.GetServiceProviderConfig)))
+	mux.Handle("/scim/v2/Users", scimAuth.RequireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			scimHandler.ListUsers(w, r)
+		case http.MethodPost:
+			scimHandler.CreateUser(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/scim/v2/Users/", scimAuth.RequireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			scimHandler.GetUser(w, r)
+		case http.MethodPut:
+			scimHandler.ReplaceUser(w, r)
+		case http.MethodPatch:
+			scimHandler.PatchUser(w, r)
+		case http.MethodDelete:
+			scimHandler.DeleteUser(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/scim/v2/Groups", scimAuth.RequireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			scimHandler.ListGroups(w, r)
+		case http.MethodPost:
+			scimHandler.CreateGroup(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/scim/v2/Groups/", scimAuth.RequireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			scimHandler.GetGroup(w, r)
+		case http.MethodPut:
+			scimHandler.ReplaceGroup(w, r)
+		case http.MethodPatch:
+			scimHandler.PatchGroup(w, r)
+		case http.MethodDelete:
+			scimHandler.DeleteGroup(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// SSO connection routes (admin)
+	mux.Handle("/admin/sso/connections", adminMiddleware.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ssoHandler.GetConnections(w, r)
+		case http.MethodPost:
+			ssoHandler.CreateConnection(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/admin/sso/connections/", adminMiddleware.RequireAdmin(http.HandlerFunc(ssoHandler.DeleteConnection)))
+
 	// Admin health check endpoint (for connection testing)
 	mux.HandleFunc("/admin/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -128,7 +251,7 @@ func main() {
 	mux.Handle("/admin/send-email", adminMiddleware.RequireAdmin(http.HandlerFunc(emailHandler.AdminSendEmailHandler)))
 
 	// Rate limiter for public endpoints (e.g., 5 requests per minute)
-	publicRateLimiter := middleware.NewRateLimiter(1*time.Minute, 5)
+	publicRateLimiter := middleware.NewRateLimiter("public", 1*time.Minute, 5)
 
 	// Contact form route - public, rate-limited only (no CSRF)
 	contactHandler := handlers.NewContactHandler()
@@ -149,9 +272,31 @@ func main() {
 	mux.Handle("/admin/newsletter", adminMiddleware.RequireAdmin(http.HandlerFunc(newsletterHandler.GetAllNewsletterSubscriptions)))
 
 	// Mind Map routes
-	mindMapHandler := handlers.NewMindMapHandler(db)
-	nodeHandler := handlers.NewNodeHandler(db)
-	edgeHandler := handlers.NewEdgeHandler(db)
+	realtimeHub := realtime.NewHub()
+	if redisClient != nil {
+		// Fan realtime events out through Redis so clients on any instance receive them
+		realtimeHub = realtime.NewHubWithBroker(realtime.NewRedisBroker(redisClient))
+	}
+	defer realtimeHub.Close()
+
+	// Deliver recorded changes to realtime subscribers from the outbox
+	outbox.NewDispatcher(db, realtimeHub).StartJob()
+
+	// Keep map previews for the dashboard up to date in the background
+	thumbnail.NewService(db).StartJob()
+	// Snapshot map sizes nightly for the growth charts
+	stats.NewService(db).StartJob()
+	// Purge trash, archive inactive maps and trim history per the organizations' policies
+	retention.NewService(db).StartJob()
+	mindMapHandler := handlers.NewMindMapHandler(db, realtimeHub)
+	nodeHandler := handlers.NewNodeHandler(db, realtimeHub)
+	edgeHandler := handlers.NewEdgeHandler(db, realtimeHub)
+	classroomHandler := handlers.NewClassroomHandler(db)
+	voteHandler := handlers.NewVoteHandler(db, realtimeHub)
+	realtimeHandler := handlers.NewRealtimeHandler(db, realtimeHub)
+	sessionHandler := handlers.NewSessionHandler(db, realtimeHub)
+	documentHandler := handlers.NewDocumentHandler(db)
+	shareLinkHandler := handlers.NewShareLinkHandler(db)
 
 	// Mind Map routes (protected)
 	mux.Handle("/api/mindmaps", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -165,6 +310,33 @@ func main() {
 		}
 	})))
 
+	mux.Handle("/api/mindmaps/import", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			mindMapHandler.ImportMindMap(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/mindmaps/aggregate", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			mindMapHandler.AggregateMindMaps(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Gallery of public mind maps
+	mux.Handle("/api/mindmaps/public", authMiddleware.RequireAuth(http.HandlerFunc(mindMapHandler.GetPublicMindMaps)))
+
+	// Bulk archive, delete and move-to-folder for dashboard multi-select
+	mux.Handle("/api/mindmaps/bulk", authMiddleware.RequireAuth(http.HandlerFunc(mindMapHandler.BulkUpdateMindMaps)))
+
+	// Knowledge graph export across all of a user's mind maps
+	mux.Handle("/api/graph/export", authMiddleware.RequireAuth(http.HandlerFunc(mindMapHandler.ExportKnowledgeGraph)))
+
 	mux.Handle("/api/mindmaps/", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 		if strings.HasSuffix(path, "/nodes") {
@@ -179,6 +351,187 @@ func main() {
 			// Handle /api/mindmaps/{id}/details
 			mindMapHandler.GetMindMap(w, r)
 			return
+		} else if strings.HasSuffix(path, "/votes") {
+			// Handle /api/mindmaps/{id}/votes
+			voteHandler.GetVoteResults(w, r)
+			return
+		} else if strings.HasSuffix(path, "/reactflow") {
+			// Handle /api/mindmaps/{id}/reactflow
+			mindMapHandler.GetReactFlow(w, r)
+			return
+		} else if strings.HasSuffix(path, "/toc") {
+			// Handle /api/mindmaps/{id}/toc
+			mindMapHandler.CreateTableOfContents(w, r)
+			return
+		} else if strings.HasSuffix(path, "/layout") {
+			// Handle /api/mindmaps/{id}/layout
+			mindMapHandler.LayoutMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/deoverlap") {
+			// Handle /api/mindmaps/{id}/deoverlap
+			mindMapHandler.DeoverlapMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/generations") {
+			// Handle /api/mindmaps/{id}/generations
+			mindMapHandler.GetGenerations(w, r)
+			return
+		} else if strings.HasSuffix(path, "/stats/history") {
+			// Handle /api/mindmaps/{id}/stats/history
+			mindMapHandler.GetMindMapStatsHistory(w, r)
+			return
+		} else if strings.HasSuffix(path, "/fields") {
+			// Handle /api/mindmaps/{id}/fields
+			switch r.Method {
+			case http.MethodGet:
+				mindMapHandler.GetCustomFields(w, r)
+			case http.MethodPut:
+				mindMapHandler.UpdateCustomFields(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		} else if strings.HasSuffix(path, "/tags/add") {
+			// Handle /api/mindmaps/{id}/tags/add
+			nodeHandler.AddNodeTag(w, r)
+			return
+		} else if strings.HasSuffix(path, "/tags/remove") {
+			// Handle /api/mindmaps/{id}/tags/remove
+			nodeHandler.RemoveNodeTag(w, r)
+			return
+		} else if strings.HasSuffix(path, "/tags/rename") {
+			// Handle /api/mindmaps/{id}/tags/rename
+			nodeHandler.RenameTag(w, r)
+			return
+		} else if strings.HasSuffix(path, "/unarchive") {
+			// Handle /api/mindmaps/{id}/unarchive
+			mindMapHandler.UnarchiveMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/archive") {
+			// Handle /api/mindmaps/{id}/archive
+			mindMapHandler.ArchiveMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/lint") {
+			// Handle /api/mindmaps/{id}/lint
+			mindMapHandler.LintMindMap(w, r)
+			return
+		} else if strings.Contains(path, "/share-links") {
+			// Handle /api/mindmaps/{id}/share-links and the links under it
+			shareLinkHandler.HandleShareLinks(w, r)
+			return
+		} else if strings.HasSuffix(path, "/cluster") {
+			// Handle /api/mindmaps/{id}/cluster
+			mindMapHandler.ClusterMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/proofread") {
+			// Handle /api/mindmaps/{id}/proofread
+			mindMapHandler.ProofreadMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/translate") {
+			// Handle /api/mindmaps/{id}/translate
+			mindMapHandler.TranslateMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/search") {
+			// Handle /api/mindmaps/{id}/search
+			mindMapHandler.SearchMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/thumbnail") {
+			// Handle /api/mindmaps/{id}/thumbnail
+			mindMapHandler.GetThumbnail(w, r)
+			return
+		} else if strings.HasSuffix(path, "/reading-order") {
+			// Handle /api/mindmaps/{id}/reading-order
+			mindMapHandler.GetReadingOrder(w, r)
+			return
+		} else if strings.HasSuffix(path, "/presentation") {
+			// Handle /api/mindmaps/{id}/presentation
+			mindMapHandler.GetPresentation(w, r)
+			return
+		} else if strings.HasSuffix(path, "/import/csv") {
+			// Handle /api/mindmaps/{id}/import/csv
+			nodeHandler.ImportNodesCSV(w, r)
+			return
+		} else if strings.HasSuffix(path, "/duplicate") {
+			// Handle /api/mindmaps/{id}/duplicate
+			mindMapHandler.DuplicateMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/export") {
+			// Handle /api/mindmaps/{id}/export
+			mindMapHandler.ExportMindMap(w, r)
+			return
+		} else if strings.HasSuffix(path, "/events") {
+			// Handle /api/mindmaps/{id}/events
+			realtimeHandler.StreamEvents(w, r)
+			return
+		} else if strings.HasSuffix(path, "/ws") {
+			// Handle /api/mindmaps/{id}/ws
+			realtimeHandler.StreamWebSocket(w, r)
+			return
+		} else if strings.HasSuffix(path, "/changes") {
+			// Handle /api/mindmaps/{id}/changes
+			realtimeHandler.GetChanges(w, r)
+			return
+		} else if strings.HasSuffix(path, "/sync-state") {
+			// Handle /api/mindmaps/{id}/sync-state
+			realtimeHandler.GetSyncState(w, r)
+			return
+		} else if strings.HasSuffix(path, "/session/start") {
+			// Handle /api/mindmaps/{id}/session/start
+			sessionHandler.StartSession(w, r)
+			return
+		} else if strings.HasSuffix(path, "/session/stop") {
+			// Handle /api/mindmaps/{id}/session/stop
+			sessionHandler.StopSession(w, r)
+			return
+		} else if strings.HasSuffix(path, "/session/reveal") {
+			// Handle /api/mindmaps/{id}/session/reveal
+			sessionHandler.RevealAuthorship(w, r)
+			return
+		} else if strings.HasSuffix(path, "/session") {
+			// Handle /api/mindmaps/{id}/session
+			switch r.Method {
+			case http.MethodGet:
+				sessionHandler.GetSession(w, r)
+			case http.MethodPut:
+				sessionHandler.UpdateSession(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		} else if strings.HasSuffix(path, "/spend-cap") {
+			// Handle /api/mindmaps/{id}/spend-cap
+			switch r.Method {
+			case http.MethodGet:
+				mindMapHandler.GetSpendCap(w, r)
+			case http.MethodPut:
+				mindMapHandler.UpdateSpendCap(w, r)
+			case http.MethodDelete:
+				mindMapHandler.DeleteSpendCap(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		} else if strings.HasSuffix(path, "/documents") {
+			// Handle /api/mindmaps/{id}/documents
+			switch r.Method {
+			case http.MethodGet:
+				documentHandler.GetDocuments(w, r)
+			case http.MethodPost:
+				documentHandler.UploadDocument(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		} else if strings.HasSuffix(path, "/classroom") {
+			// Handle /api/mindmaps/{id}/classroom
+			switch r.Method {
+			case http.MethodGet:
+				classroomHandler.GetClassroomDashboard(w, r)
+			case http.MethodPost:
+				classroomHandler.CreateClassroom(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
 		}
 
 		// Handle /api/mindmaps/{id}
@@ -214,6 +567,31 @@ func main() {
 	})))
 
 	mux.Handle("/api/nodes/", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/vote") {
+			// Handle /api/nodes/{id}/vote
+			switch r.Method {
+			case http.MethodPost:
+				voteHandler.CastVote(w, r)
+			case http.MethodDelete:
+				voteHandler.RemoveVote(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/backlinks") {
+			// Handle /api/nodes/{id}/backlinks
+			nodeHandler.GetNodeBacklinks(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/summarize") {
+			// Handle /api/nodes/{id}/summarize
+			nodeHandler.SummarizeNode(w, r)
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			nodeHandler.GetNode(w, r)
@@ -256,6 +634,96 @@ func main() {
 		}
 	})))
 
+	// Saved filter routes (protected)
+	savedFilterHandler := handlers.NewSavedFilterHandler(db)
+	mux.Handle("/api/filters", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			savedFilterHandler.GetSavedFilters(w, r)
+		case http.MethodPost:
+			savedFilterHandler.CreateSavedFilter(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/filters/run", authMiddleware.RequireAuth(http.HandlerFunc(savedFilterHandler.RunFilter)))
+
+	mux.Handle("/api/filters/", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/run") {
+			// Handle /api/filters/{id}/run
+			savedFilterHandler.RunSavedFilter(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			savedFilterHandler.GetSavedFilter(w, r)
+		case http.MethodPut:
+			savedFilterHandler.UpdateSavedFilter(w, r)
+		case http.MethodDelete:
+			savedFilterHandler.DeleteSavedFilter(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Node template routes (protected)
+	nodeTemplateHandler := handlers.NewNodeTemplateHandler(db)
+	mux.Handle("/api/node-templates", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			nodeTemplateHandler.GetNodeTemplates(w, r)
+		case http.MethodPost:
+			nodeTemplateHandler.CreateNodeTemplate(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/node-templates/", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			nodeTemplateHandler.GetNodeTemplate(w, r)
+		case http.MethodPut:
+			nodeTemplateHandler.UpdateNodeTemplate(w, r)
+		case http.MethodDelete:
+			nodeTemplateHandler.DeleteNodeTemplate(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Capture route for the web clipper (protected)
+	captureHandler := handlers.NewCaptureHandler(db, realtimeHub)
+	mux.Handle("/api/capture", authMiddleware.RequireAuth(http.HandlerFunc(captureHandler.Capture)))
+
+	// Inbox routes (protected)
+	inboxHandler := handlers.NewInboxHandler(db, realtimeHub)
+	mux.Handle("/api/inbox", authMiddleware.RequireAuth(http.HandlerFunc(inboxHandler.GetInbox)))
+	mux.Handle("/api/inbox/move", authMiddleware.RequireAuth(http.HandlerFunc(inboxHandler.MoveInboxNodes)))
+	mux.Handle("/api/inbox/triage", authMiddleware.RequireAuth(http.HandlerFunc(inboxHandler.TriageInbox)))
+
+	// Inbound routes: address settings are protected, while deliveries are authenticated by
+	// the address token and, for emails, the provider's signature
+	inboundHandler := handlers.NewInboundHandler(db, realtimeHub)
+	mux.Handle("/api/inbound", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			inboundHandler.GetInboundAddress(w, r)
+		case http.MethodPut:
+			inboundHandler.SaveInboundAddress(w, r)
+		case http.MethodDelete:
+			inboundHandler.DeleteInboundAddress(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/inbound/rotate", authMiddleware.RequireAuth(http.HandlerFunc(inboundHandler.RotateInboundToken)))
+	inboundRateLimiter := middleware.NewRateLimiter("inbound", 1*time.Minute, 60)
+	mux.Handle("/api/inbound/hooks/", inboundRateLimiter.Limit(http.HandlerFunc(inboundHandler.ReceiveWebhook)))
+	mux.Handle("/api/inbound/email", inboundRateLimiter.Limit(http.HandlerFunc(inboundHandler.ReceiveEmail)))
+
 	// API Key routes (protected)
 	apiKeyHandler := handlers.NewAPIKeyHandler(db)
 	mux.Handle("/api/apikeys", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -304,15 +772,63 @@ func main() {
 	})))
 
 	// Idea Generation routes (protected)
-	ideaGenerationHandler := handlers.NewIdeaGenerationHandler(db)
-	mux.Handle("/api/generate", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	ideaGenerationHandler := handlers.NewIdeaGenerationHandler(db, realtimeHub)
+
+	// Optional cap on AI generations per user per day; unset or 0 means unlimited
+	aiGenerationLimit, _ := strconv.Atoi(os.Getenv("AI_DAILY_GENERATION_QUOTA"))
+	aiGenerationQuota := middleware.NewUserQuota("ai-generation", 24*time.Hour, aiGenerationLimit)
+
+	// Per-user rate of AI generations, so one user cannot exhaust the shared provider keys;
+	// throttled requests do not count against the daily quota
+	aiGenerationRate, aiGenerationBurst := 10, 5
+	if rate, err := strconv.Atoi(os.Getenv("AI_GENERATION_RATE_PER_MINUTE")); err == nil {
+		aiGenerationRate = rate
+	}
+	if burst, err := strconv.Atoi(os.Getenv("AI_GENERATION_BURST")); err == nil {
+		aiGenerationBurst = burst
+	}
+	aiGenerationRateLimiter := middleware.NewUserRateLimiter("ai-generation", aiGenerationRate, aiGenerationBurst)
+	mux.Handle("/api/generate", authMiddleware.RequireAuth(aiGenerationRateLimiter.Limit(aiGenerationQuota.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
 			ideaGenerationHandler.GenerateIdeas(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	})))
+	})))))
+
+	// Generation jobs run in the background, so long multi-level generations do not hold a
+	// request open; clients poll the job until it has finished
+	ideaGenerationHandler.StartJobWorker()
+	mux.Handle("/api/generate/async", authMiddleware.RequireAuth(aiGenerationRateLimiter.Limit(aiGenerationQuota.Limit(http.HandlerFunc(ideaGenerationHandler.GenerateIdeasAsync)))))
+	mux.Handle("/api/generate/map", authMiddleware.RequireAuth(aiGenerationRateLimiter.Limit(aiGenerationQuota.Limit(http.HandlerFunc(ideaGenerationHandler.GenerateMap)))))
+	mux.Handle("/api/generate/jobs/", authMiddleware.RequireAuth(http.HandlerFunc(ideaGenerationHandler.GetGenerationJob)))
+	mux.Handle("/api/generate/feedback", authMiddleware.RequireAuth(http.HandlerFunc(ideaGenerationHandler.SaveIdeaFeedback)))
+	mux.Handle("/api/documents/", authMiddleware.RequireAuth(http.HandlerFunc(documentHandler.DeleteDocument)))
+
+	// Idea bank routes (public, no AI)
+	ideaBankHandler := handlers.NewIdeaBankHandler()
+	ideaBankRateLimiter := middleware.NewRateLimiter("idea-bank", 1*time.Minute, 60)
+	mux.Handle("/api/ideas/categories", ideaBankRateLimiter.Limit(http.HandlerFunc(ideaBankHandler.GetCategories)))
+	mux.Handle("/api/ideas/starters", ideaBankRateLimiter.Limit(http.HandlerFunc(ideaBankHandler.GetStarters)))
+
+	// Demo routes (public): ephemeral maps for trying the app without signing up
+	demoHandler := handlers.NewDemoHandler(db)
+	demoHandler.StartPurgeJob()
+	demoRateLimiter := middleware.NewRateLimiter("demo", 1*time.Minute, 60)
+	mux.Handle("/api/demo/maps", publicRateLimiter.Limit(http.HandlerFunc(demoHandler.CreateDemoMap)))
+	mux.Handle("/api/demo/maps/", demoRateLimiter.Limit(http.HandlerFunc(demoHandler.HandleDemoMap)))
+
+	// Share link routes (public): viewing maps shared by link, and trading the password of a
+	// protected link for a view session
+	sharedRateLimiter := middleware.NewRateLimiter("shared", 1*time.Minute, 30)
+	mux.Handle("/api/shared/", sharedRateLimiter.Limit(http.HandlerFunc(shareLinkHandler.HandleSharedMap)))
+
+	// Semantic search across a user's maps; node embeddings are kept up to date in the
+	// background
+	semanticSearchHandler := handlers.NewSemanticSearchHandler(db)
+	semanticSearchHandler.StartIndexJob()
+	mux.Handle("/api/search/semantic", authMiddleware.RequireAuth(aiGenerationRateLimiter.Limit(http.HandlerFunc(semanticSearchHandler.SemanticSearch))))
 
 	mux.Handle("/api/generate/nodes", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -323,6 +839,73 @@ func main() {
 		}
 	})))
 
+	// Organization retention policy routes (protected, organization admins only)
+	retentionHandler := handlers.NewRetentionHandler(db)
+	mux.Handle("/api/org/retention-policy", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			retentionHandler.GetPolicy(w, r)
+		case http.MethodPut:
+			retentionHandler.UpdatePolicy(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Organization AI usage policy routes (protected, organization admins only)
+	aiPolicyHandler := handlers.NewAIPolicyHandler(db)
+	mux.Handle("/api/org/ai-policy", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			aiPolicyHandler.GetPolicy(w, r)
+		case http.MethodPut:
+			aiPolicyHandler.UpdatePolicy(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Organization compliance routes (protected, organization admins only)
+	complianceHandler := handlers.NewComplianceHandler(db)
+	mux.Handle("/api/org/legal-holds", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			complianceHandler.GetLegalHolds(w, r)
+		case http.MethodPost:
+			complianceHandler.CreateLegalHold(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/org/legal-holds/", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/export") {
+			// Handle /api/org/legal-holds/{id}/export
+			complianceHandler.ExportLegalHold(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			complianceHandler.GetLegalHold(w, r)
+		case http.MethodDelete:
+			complianceHandler.ReleaseLegalHold(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/org/audit-log", authMiddleware.RequireAuth(http.HandlerFunc(complianceHandler.GetAuditLog)))
+
+	// AI usage routes (protected)
+	usageHandler := handlers.NewUsageHandler(db)
+	mux.Handle("/api/usage", authMiddleware.RequireAuth(http.HandlerFunc(usageHandler.GetUsage)))
+	mux.Handle("/api/org/", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/ai-report") {
+			// Handle /api/org/{id}/ai-report
+			usageHandler.GetOrgAIReport(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	})))
+
 	// Analytics routes (protected)
 	mux.Handle("/admin/analytics/user-journey", adminMiddleware.RequireAdmin(http.HandlerFunc(analyticsHandler.GetUserJourney)))
 	mux.Handle("/admin/analytics/visitor-journey", adminMiddleware.RequireAdmin(http.HandlerFunc(analyticsHandler.GetVisitorJourney)))
@@ -334,14 +917,32 @@ func main() {
 		w.Write([]byte(`{"message": "Admin dashboard data"}`))
 	})))
 
-	// Configure CORS
+	// Give every request a deadline so a stuck query or AI call cannot hold server resources
+	// indefinitely. AI, import and export routes get longer, and the event streams none
+	requestTimeout := 30 * time.Second
+	if ms, err := strconv.Atoi(os.Getenv("REQUEST_TIMEOUT_MS")); err == nil {
+		requestTimeout = time.Duration(ms) * time.Millisecond
+	}
+	longRequestTimeout := 2 * time.Minute
+	if ms, err := strconv.Atoi(os.Getenv("LONG_REQUEST_TIMEOUT_MS")); err == nil {
+		longRequestTimeout = time.Duration(ms) * time.Millisecond
+	}
+	requestDeadline := middleware.NewDeadline(requestTimeout).
+		Route("/events", 0).
+		Route("/ws", 0)
+	for _, suffix := range []string{"/api/generate", "/api/generate/map", "/api/generate/nodes", "/api/search/semantic", "/aggregate", "/cluster", "/triage", "/lint", "/proofread", "/translate", "/summarize", "/export", "/import", "/import/csv", "/documents"} {
+		requestDeadline.Route(suffix, longRequestTimeout)
+	}
+
+	// Configure CORS. The marketing site calls the demo endpoints from its own origin
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins: []string{
 			os.Getenv("ADMIN_CLIENT_URL"),
 			os.Getenv("FRONTEND_URL"),
+			os.Getenv("MARKETING_SITE_URL"),
 		},
 		AllowedMethods:      []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:      []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With"},
+		AllowedHeaders:      []string{"Accept", "Authorization", "Content-Type", "X-API-Profile", "X-CSRF-Token", "X-Demo-Token", "X-Read-Consistency", "X-Requested-With", "X-Share-Session"},
 		ExposedHeaders:      []string{"Link"},
 		AllowCredentials:    true,
 		MaxAge:              300, // Maximum value not ignored by any of major browsers
@@ -355,7 +956,7 @@ func main() {
 	}
 
 	log.Printf("Server starting on port %s", port)
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", port), corsHandler.Handler(mux)); err != nil {
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", port), corsHandler.Handler(requestDeadline.Limit(mux))); err != nil {
 		log.Fatal("Error starting server:", err)
 	}
 }
@@ -21,6 +21,9 @@ const UserIDKey contextKey = "userID"
 // UserIDContextKey is the exported string version of UserIDKey for external use
 const UserIDContextKey = "userID"
 
+// TenantIDKey is the context key for storing the tenant of the authenticated user
+const TenantIDKey contextKey = "tenantID"
+
 // AuthMiddleware handles JWT authentication for protected routes
 type AuthMiddleware struct {
 	db        *database.DB // Database connection for user operations
@@ -36,7 +39,7 @@ func NewAuthMiddleware(db *database.DB, jwtSecret string) *AuthMiddleware {
 }
 
 // RequireAuth is a middleware that checks for a valid JWT token in the cookie
-// If the token is valid, it adds the user ID to the request context
+// If the token is valid, it adds the user ID and the user's tenant to the request context
 func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Log request details
@@ -112,8 +115,22 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Add user ID to context using the typed key only
+		// Look up the user's tenant, which scopes the data the request can read
+		tenantID, err := m.db.GetUserTenantID(userID)
+		if err == database.ErrNotFound {
+			log.Printf("[Auth Middleware] User %s no longer exists or is deactivated", userID)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("[Auth Middleware] Error getting tenant of user %s: %v", userID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// Add user ID and tenant to context using the typed keys only
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, TenantIDKey, tenantID)
 
 		log.Printf("[Auth Middleware] Token validated successfully for user: %v", userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -133,3 +150,10 @@ func GetUserID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// GetTenantID retrieves the tenant of the authenticated user from the context
+// Returns an empty string if the request is not authenticated
+func GetTenantID(ctx context.Context) string {
+	tenantID, _ := ctx.Value(TenantIDKey).(string)
+	return tenantID
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// counterKeyPrefix namespaces rate limit and quota counters in Redis
+const counterKeyPrefix = "ratelimit:"
+
+// CounterStore counts hits per key within fixed windows, and keeps token buckets
+type CounterStore interface {
+	// Increment records a hit for key and returns the number of hits in the current
+	// window along with the time left until the window resets
+	Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+
+	// TakeToken takes a token from the bucket of key, which holds up to capacity tokens and
+	// regains one every interval. Reports whether a token was taken and, if not, the time
+	// until the next one is available
+	TakeToken(ctx context.Context, key string, capacity int, interval time.Duration) (bool, time.Duration, error)
+}
+
+// defaultCounterStore backs every rate limiter and quota created after it is set
+var defaultCounterStore CounterStore = NewMemoryCounterStore()
+
+// UseCounterStore sets the store used by rate limiters and quotas created afterwards.
+// It must be called before the handlers are set up
+func UseCounterStore(store CounterStore) {
+	defaultCounterStore = store
+}
+
+// counterWindow tracks the hits of a single key in memory
+type counterWindow struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// tokenBucket tracks the tokens of a single key in memory
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time // When the bucket is full again and can be forgotten
+}
+
+// MemoryCounterStore keeps counters in process memory. Limits are per instance and reset on restart
+type MemoryCounterStore struct {
+	windows         map[string]*counterWindow
+	buckets         map[string]*tokenBucket
+	mutex           sync.Mutex
+	cleanupInterval time.Duration
+}
+
+// NewMemoryCounterStore creates a new MemoryCounterStore instance
+func NewMemoryCounterStore() *MemoryCounterStore {
+	s := &MemoryCounterStore{
+		windows:         make(map[string]*counterWindow),
+		buckets:         make(map[string]*tokenBucket),
+		cleanupInterval: time.Hour,
+	}
+
+	// Start cleanup routine
+	go s.cleanup()
+
+	return s
+}
+
+// Increment records a hit for key in memory
+func (s *MemoryCounterStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	w, exists := s.windows[key]
+	if !exists || !now.Before(w.expiresAt) {
+		w = &counterWindow{expiresAt: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+	return w.count, w.expiresAt.Sub(now), nil
+}
+
+// TakeToken takes a token from the bucket of key in memory
+func (s *MemoryCounterStore) TakeToken(ctx context.Context, key string, capacity int, interval time.Duration) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: float64(capacity), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.tokens = min(float64(capacity), b.tokens+float64(now.Sub(b.updatedAt))/float64(interval))
+	b.updatedAt = now
+
+	taken := b.tokens >= 1
+	var wait time.Duration
+	if taken {
+		b.tokens--
+	} else {
+		wait = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	b.fullAt = now.Add(time.Duration((float64(capacity) - b.tokens) * float64(interval)))
+	return taken, wait, nil
+}
+
+// cleanup periodically removes expired windows
+func (s *MemoryCounterStore) cleanup() {
+	for {
+		time.Sleep(s.cleanupInterval)
+		s.mutex.Lock()
+		now := time.Now()
+		for key, w := range s.windows {
+			if !now.Before(w.expiresAt) {
+				delete(s.windows, key)
+			}
+		}
+		for key, b := range s.buckets {
+			if !now.Before(b.fullAt) {
+				delete(s.buckets, key)
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// incrementScript increments a counter and starts its window on the first hit in one atomic
+// step, so concurrent requests on different instances can never leave a counter without expiry
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if count == 1 or ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// takeTokenScript refills a token bucket for the time passed since it was last used and
+// takes a token in one atomic step. Time is taken from the Redis server so instances with
+// skewed clocks share one view of the bucket, which expires once it would be full again
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + (now - updated) / interval)
+local taken = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	taken = 1
+else
+	wait = math.ceil((1 - tokens) * interval)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) * interval) + 1000)
+return {taken, wait}
+`)
+
+// RedisCounterStore keeps counters in Redis so limits hold across instances and restarts
+type RedisCounterStore struct {
+	client *redis.Client
+}
+
+// NewRedisCounterStore creates a new RedisCounterStore using the given client
+func NewRedisCounterStore(client *redis.Client) *RedisCounterStore {
+	return &RedisCounterStore{client: client}
+}
+
+// Increment records a hit for key in Redis
+func (s *RedisCounterStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	result, err := incrementScript.Run(ctx, s.client, []string{counterKeyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// TakeToken takes a token from the bucket of key in Redis
+func (s *RedisCounterStore) TakeToken(ctx context.Context, key string, capacity int, interval time.Duration) (bool, time.Duration, error) {
+	result, err := takeTokenScript.Run(ctx, s.client, []string{counterKeyPrefix + "bucket:" + key}, capacity, interval.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// statementTimeoutMessage is part of the error Postgres returns when a statement runs past
+// statement_timeout, which handlers pass on in their error responses
+var statementTimeoutMessage = []byte("canceling statement due to statement timeout")
+
+// Deadline gives every request a deadline on its context, so database queries and outbound
+// calls made with it are cancelled once it passes. Routes can have their own timeout, such
+// as a longer one for AI generation or none for long-lived streams. A request whose
+// handler fails after the deadline, or because a database statement timed out, gets a 504
+// with a JSON error body instead of the handler's error
+type Deadline struct {
+	timeout time.Duration
+	routes  []deadlineRoute
+}
+
+// deadlineRoute is a timeout for the paths ending in suffix
+type deadlineRoute struct {
+	suffix  string
+	timeout time.Duration
+}
+
+// NewDeadline creates a new deadline middleware with a default timeout. A timeout of zero
+// or less leaves requests without a deadline
+func NewDeadline(timeout time.Duration) *Deadline {
+	return &Deadline{timeout: timeout}
+}
+
+// Route sets the timeout of the paths ending in suffix, overriding the default. Routes are
+// matched in the order they were added
+func (d *Deadline) Route(suffix string, timeout time.Duration) *Deadline {
+	d.routes = append(d.routes, deadlineRoute{suffix: suffix, timeout: timeout})
+	return d
+}
+
+// timeoutFor returns the timeout of a request path
+func (d *Deadline) timeoutFor(path string) time.Duration {
+	for _, route := range d.routes {
+		if strings.HasSuffix(path, route.suffix) {
+			return route.timeout
+		}
+	}
+	return d.timeout
+}
+
+// Limit is middleware that applies the deadline of each request's route
+func (d *Deadline) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := d.timeoutFor(r.URL.Path)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		tw := &deadlineWriter{ResponseWriter: w, ctx: ctx}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		tw.finish()
+		if tw.timedOut {
+			log.Printf("[Deadline] %s %s timed out after %s", r.Method, r.URL.Path, timeout)
+		}
+	})
+}
+
+// deadlineWriter holds back a server error status until the first write, so that the
+// error can be replaced with a 504 when it was caused by a timeout
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	pending  int  // Server error status not yet written
+	started  bool // Whether the status has been written
+	timedOut bool // Whether the response was replaced with a 504
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	if w.started || w.pending != 0 {
+		return
+	}
+	if status >= http.StatusInternalServerError {
+		w.pending = status
+		return
+	}
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if w.timedOut {
+		return len(b), nil
+	}
+	if w.pending != 0 {
+		if w.ctx.Err() == context.DeadlineExceeded || bytes.Contains(b, statementTimeoutMessage) {
+			w.writeTimeout()
+			return len(b), nil
+		}
+		w.started = true
+		w.ResponseWriter.WriteHeader(w.pending)
+		w.pending = 0
+	}
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the writer
+func (w *deadlineWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish writes a held back status the handler never wrote a body for, or a 504 when the
+// deadline passed before the handler responded at all
+func (w *deadlineWriter) finish() {
+	switch {
+	case w.timedOut:
+	case w.pending != 0 && w.ctx.Err() == context.DeadlineExceeded:
+		w.writeTimeout()
+	case w.pending != 0:
+		w.ResponseWriter.WriteHeader(w.pending)
+	case !w.started && w.ctx.Err() == context.DeadlineExceeded:
+		w.writeTimeout()
+	}
+}
+
+// writeTimeout responds with a 504 and the JSON error format
+func (w *deadlineWriter) writeTimeout() {
+	w.timedOut = true
+	w.started = true
+	w.pending = 0
+	w.ResponseWriter.Header().Del("Content-Length")
+	w.ResponseWriter.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w.ResponseWriter).Encode(map[string]string{"error": "Request timed out"})
+}
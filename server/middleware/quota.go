@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// UserQuota limits how many requests each authenticated user can make within a window,
+// such as AI generations per day. Counters live in the same store as the rate limiters
+type UserQuota struct {
+	name   string
+	window time.Duration
+	limit  int
+	store  CounterStore
+}
+
+// NewUserQuota creates a new quota instance. A limit of zero or less disables the quota
+func NewUserQuota(name string, window time.Duration, limit int) *UserQuota {
+	return &UserQuota{
+		name:   name,
+		window: window,
+		limit:  limit,
+		store:  defaultCounterStore,
+	}
+}
+
+// Limit is middleware that enforces the quota per user. It must run after RequireAuth
+func (q *UserQuota) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := GetUserID(r.Context())
+		if q.limit <= 0 || userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !allowRequest(w, r, q.store, "quota:"+q.name+":"+userID, q.window, q.limit) {
+			http.Error(w, "Quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,86 +2,72 @@
 package middleware
 
 import (
+	"context"
+	"log"
+	"net"
 	"net/http"
-	"sync"
 	"time"
 )
 
-// RateLimiter implements rate limiting for API endpoints
-type RateLimiter struct {
-	window    time.Duration
-	limit     int
-	attempts  map[string]*ClientAttempts
-	mutex     sync.RWMutex
-	cleanupInterval time.Duration
-}
-
-// ClientAttempts tracks rate limiting data for a client
-type ClientAttempts struct {
-	count    int
-	windowStart time.Time
-}
-
-// NewRateLimiter creates a new rate limiter instance
-func NewRateLimiter(window time.Duration, limit int) *RateLimiter {
-	rl := &RateLimiter{
-		window:    window,
-		limit:     limit,
-		attempts:  make(map[string]*ClientAttempts),
-		cleanupInterval: time.Hour,
-	}
+// counterTimeout bounds each call a limiter makes to its counter store
+const counterTimeout = 2 * time.Second
 
-	// Start cleanup routine
-	go rl.cleanup()
-
-	return rl
+// RateLimiter implements fixed window rate limiting for API endpoints. Counters live
+// in a CounterStore, so with Redis the limit holds across every server instance
+type RateLimiter struct {
+	name   string
+	window time.Duration
+	limit  int
+	store  CounterStore
 }
 
-// cleanup periodically removes expired entries
-func (rl *RateLimiter) cleanup() {
-	for {
-		time.Sleep(rl.cleanupInterval)
-		rl.mutex.Lock()
-		now := time.Now()
-		for ip, attempts := range rl.attempts {
-			if now.Sub(attempts.windowStart) > rl.window {
-				delete(rl.attempts, ip)
-			}
-		}
-		rl.mutex.Unlock()
+// NewRateLimiter creates a new rate limiter instance. The name keeps its counters
+// apart from other limiters sharing the same store
+func NewRateLimiter(name string, window time.Duration, limit int) *RateLimiter {
+	return &RateLimiter{
+		name:   name,
+		window: window,
+		limit:  limit,
+		store:  defaultCounterStore,
 	}
 }
 
-// Limit is middleware that limits request rates by IP
-// RateLimiter middleware implements a token bucket algorithm for rate limiting
-// It tracks request rates per IP address and enforces configurable rate limits
-// The implementation uses a concurrent map to store bucket states
-// and periodically cleans up inactive buckets to prevent memory leaks
+// Limit is middleware that limits request rates by client IP
 func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
-
-		rl.mutex.Lock()
-		now := time.Now()
-
-		// Get or create client attempts
-		attempts, exists := rl.attempts[ip]
-		if !exists || now.Sub(attempts.windowStart) > rl.window {
-			rl.attempts[ip] = &ClientAttempts{
-				count:    1,
-				windowStart: now,
-			}
-		} else {
-			attempts.count++
-			if attempts.count > rl.limit {
-				rl.mutex.Unlock()
-				w.Header().Set("Retry-After", time.Now().Add(rl.window).Format(time.RFC1123))
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-				return
-			}
+		if !allowRequest(w, r, rl.store, rl.name+":"+ClientIP(r), rl.window, rl.limit) {
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
 		}
-
-		rl.mutex.Unlock()
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// allowRequest counts a hit for key and reports whether it is within limit. Once the limit
+// is exceeded it sets Retry-After to when the window resets. If the store is unavailable
+// the request is let through rather than failing every request
+func allowRequest(w http.ResponseWriter, r *http.Request, store CounterStore, key string, window time.Duration, limit int) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), counterTimeout)
+	defer cancel()
+
+	count, resetIn, err := store.Increment(ctx, key, window)
+	if err != nil {
+		log.Printf("[Rate Limiter] Error counting request for %s: %v", key, err)
+		return true
+	}
+	if count > int64(limit) {
+		w.Header().Set("Retry-After", time.Now().Add(resetIn).UTC().Format(http.TimeFormat))
+		return false
+	}
+	return true
+}
+
+// ClientIP returns the IP address of the client without the port, so every
+// connection from the same client shares one counter
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
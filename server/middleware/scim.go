@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"saas-server/database"
+	"saas-server/models"
+)
+
+// SCIMAuth authenticates a tenant's identity provider on the SCIM endpoints by the bearer
+// token issued to the tenant
+type SCIMAuth struct {
+	db *database.DB
+}
+
+// NewSCIMAuth creates a new SCIMAuth instance
+func NewSCIMAuth(db *database.DB) *SCIMAuth {
+	return &SCIMAuth{db: db}
+}
+
+// HashSCIMToken returns the hash a SCIM token is stored and looked up by
+func HashSCIMToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequireToken is middleware that checks the SCIM bearer token and adds the tenant it was
+// issued to to the request context. Failures get a SCIM error body
+func (m *SCIMAuth) RequireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || token == r.Header.Get("Authorization") {
+			sendSCIMAuthError(w, http.StatusUnauthorized, "Bearer token required")
+			return
+		}
+
+		tenantID, err := m.db.GetSCIMTokenTenantID(HashSCIMToken(token))
+		if err == database.ErrNotFound {
+			sendSCIMAuthError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+		if err != nil {
+			log.Printf("[SCIM Auth] Error looking up token: %v", err)
+			sendSCIMAuthError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), TenantIDKey, tenantID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sendSCIMAuthError responds with a SCIM error
+func sendSCIMAuthError(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.SCIMError{
+		Schemas: []string{models.SCIMErrorSchema},
+		Status:  strconv.Itoa(status),
+		Detail:  detail,
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UserRateLimiter limits how fast each authenticated user can make requests with a token
+// bucket: a user can make a burst of requests at once and then one per interval. Buckets
+// live in the same store as the rate limiters, so with Redis the limit holds across every
+// server instance
+type UserRateLimiter struct {
+	name     string
+	burst    int
+	interval time.Duration
+	store    CounterStore
+}
+
+// NewUserRateLimiter creates a new per-user rate limiter allowing rate requests per minute
+// with bursts of up to burst requests. A rate of zero or less disables the limiter, and a
+// burst below one allows one request at a time
+func NewUserRateLimiter(name string, rate, burst int) *UserRateLimiter {
+	limiter := &UserRateLimiter{name: name, burst: max(burst, 1), store: defaultCounterStore}
+	if rate > 0 {
+		limiter.interval = time.Minute / time.Duration(rate)
+	}
+	return limiter
+}
+
+// Limit is middleware that enforces the rate per user, responding with 429 and a
+// Retry-After in seconds once the user's bucket is empty. It must run after RequireAuth.
+// If the store is unavailable the request is let through rather than failing every request
+func (l *UserRateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := GetUserID(r.Context())
+		if l.interval <= 0 || userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), counterTimeout)
+		taken, wait, err := l.store.TakeToken(ctx, "user:"+l.name+":"+userID, l.burst, l.interval)
+		cancel()
+		if err != nil {
+			log.Printf("[Rate Limiter] Error taking token for %s of user %s: %v", l.name, userID, err)
+		} else if !taken {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
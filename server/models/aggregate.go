@@ -0,0 +1,31 @@
+// Package models contains the data models for the application
+package models
+
+// MindMapAggregateRequest represents the participant maps to merge into a consolidated map
+type MindMapAggregateRequest struct {
+	MindMapIDs  []string `json:"mind_map_ids" binding:"required"`
+	Title       string   `json:"title"`
+	Deduplicate bool     `json:"deduplicate"`
+	APIKey      string   `json:"api_key"`
+}
+
+// AggregateSource identifies a node in a participant map that contributed to a cluster
+type AggregateSource struct {
+	MindMapID string `json:"mind_map_id"`
+	NodeID    string `json:"node_id"`
+	Content   string `json:"content"`
+}
+
+// AggregateCluster represents a group of similar ideas merged into a single node
+type AggregateCluster struct {
+	Label   string            `json:"label"`
+	NodeID  string            `json:"node_id"`
+	Sources []AggregateSource `json:"sources"`
+}
+
+// MindMapAggregateResponse represents the consolidated map and how source ideas were grouped
+type MindMapAggregateResponse struct {
+	MindMap      MindMap            `json:"mind_map"`
+	Clusters     []AggregateCluster `json:"clusters"`
+	AIClustering bool               `json:"ai_clustering"`
+}
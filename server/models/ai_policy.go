@@ -0,0 +1,22 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// TenantAIPolicy restricts which AI providers a tenant's content may be sent to. A setting
+// left nil inherits the server's default from its AI_* environment variables
+type TenantAIPolicy struct {
+	TenantID         string     `json:"tenant_id"`
+	AllowedProviders []string   `json:"allowed_providers"`    // Empty allows any provider
+	AllowedRegions   []string   `json:"allowed_regions"`      // Empty allows any region
+	AllowInlineKeys  *bool      `json:"allow_inline_keys"`    // Whether requests may bring their own API key
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"` // Unset while the tenant has no policy
+}
+
+// TenantAIPolicyRequest is the body of PUT /api/org/ai-policy. It replaces the whole
+// policy, so a setting left out or null goes back to the server's default
+type TenantAIPolicyRequest struct {
+	AllowedProviders []string `json:"allowed_providers"`
+	AllowedRegions   []string `json:"allowed_regions"`
+	AllowInlineKeys  *bool    `json:"allow_inline_keys"`
+}
@@ -0,0 +1,140 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// AIUsage is what one AI call used, recorded for auditing and billing
+type AIUsage struct {
+	ID               int64     `json:"id"`
+	UserID           string    `json:"user_id"`
+	MindMapID        *string   `json:"mind_map_id,omitempty"` // Nil for calls not made for a map
+	Feature          string    `json:"feature"`               // e.g. "generate", "lint" or "translate"
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int       `json:"latency_ms"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// AIUsageBucket is the usage of one model by a user on one day for one mind map
+type AIUsageBucket struct {
+	Date             string  // YYYY-MM-DD, UTC
+	MindMapID        *string // Nil for calls not made for a map
+	MindMapTitle     *string // Nil when there is no map or it was deleted
+	Provider         string
+	Model            string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// AIUsageTotals sums AI usage. The cost is an estimate from list prices; calls to models
+// without a known price count as free and are counted as unpriced
+type AIUsageTotals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+	UnpricedRequests int     `json:"unpriced_requests"`
+}
+
+// AIUsageDay is a user's AI usage on one day
+type AIUsageDay struct {
+	Date string `json:"date"` // YYYY-MM-DD, UTC
+	AIUsageTotals
+}
+
+// AIUsageMindMap is a user's AI usage for one mind map
+type AIUsageMindMap struct {
+	MindMapID string  `json:"mind_map_id"`
+	Title     *string `json:"title"` // Nil when the map was deleted
+	AIUsageTotals
+}
+
+// AIUsageReport is returned by GET /api/usage. Days without usage are left out, and calls
+// not made for a map are only counted in the totals and by day
+type AIUsageReport struct {
+	Since     string           `json:"since"` // First day covered, YYYY-MM-DD, UTC
+	Days      int              `json:"days"`
+	Totals    AIUsageTotals    `json:"totals"`
+	ByDay     []AIUsageDay     `json:"by_day"`
+	ByMindMap []AIUsageMindMap `json:"by_mind_map"`
+}
+
+// TenantAIUsageBucket is the usage of one model by a user of a tenant on one day
+type TenantAIUsageBucket struct {
+	Date             string // YYYY-MM-DD, UTC
+	UserID           string
+	Email            string
+	Provider         string
+	Model            string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// AIUsageModel is an organization's usage of one model, with its share of the
+// organization's requests
+type AIUsageModel struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	RequestShare float64 `json:"request_share"` // From 0 to 1
+	AIUsageTotals
+}
+
+// AIUsageUser is one user's AI usage within their organization
+type AIUsageUser struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	AIUsageTotals
+}
+
+// OrgAIReport is returned by GET /api/org/{id}/ai-report. Models and users come by
+// estimated cost, most expensive first, and days without usage are left out
+type OrgAIReport struct {
+	TenantID string         `json:"tenant_id"`
+	From     string         `json:"from"` // First day covered, YYYY-MM-DD, UTC
+	To       string         `json:"to"`   // Last day covered, YYYY-MM-DD, UTC
+	Totals   AIUsageTotals  `json:"totals"`
+	ByModel  []AIUsageModel `json:"by_model"`
+	TopUsers []AIUsageUser  `json:"top_users"`
+	ByDay    []AIUsageDay   `json:"by_day"`
+}
+
+// What happens to generations once an AI spend cap is reached
+const (
+	SpendCapRefuse    = "refuse"
+	SpendCapDowngrade = "downgrade"
+)
+
+// AISpendCap limits the AI usage of a mind map, overall and per brainstorm session. A
+// limit left nil is off. Costs are estimates from list prices, as in the usage reports
+type AISpendCap struct {
+	MindMapID         string     `json:"mind_map_id"`
+	MaxCostUSD        *float64   `json:"max_cost_usd"`
+	MaxTokens         *int       `json:"max_tokens"`
+	SessionMaxCostUSD *float64   `json:"session_max_cost_usd"`
+	SessionMaxTokens  *int       `json:"session_max_tokens"`
+	OnExceed          string     `json:"on_exceed"`            // "refuse" or "downgrade"
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"` // Unset while the map has no cap
+}
+
+// AISpendCapRequest is the body of PUT /api/mindmaps/{id}/spend-cap. It replaces the
+// whole cap, so a limit left out or null is turned off
+type AISpendCapRequest struct {
+	MaxCostUSD        *float64 `json:"max_cost_usd"`
+	MaxTokens         *int     `json:"max_tokens"`
+	SessionMaxCostUSD *float64 `json:"session_max_cost_usd"`
+	SessionMaxTokens  *int     `json:"session_max_tokens"`
+	OnExceed          string   `json:"on_exceed"` // Defaults to "refuse"
+}
+
+// AISpendCapStatus is returned by GET /api/mindmaps/{id}/spend-cap: the cap and the usage
+// counted against it
+type AISpendCapStatus struct {
+	AISpendCap
+	Usage        AIUsageTotals  `json:"usage"`                   // Of the map overall
+	SessionID    *string        `json:"session_id,omitempty"`    // The running brainstorm session, if any
+	SessionUsage *AIUsageTotals `json:"session_usage,omitempty"` // Since the running session started
+}
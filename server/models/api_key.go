@@ -16,6 +16,22 @@ type APIKey struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// Services API keys can be stored for. OpenRouter keys reach many models through one key
+const (
+	APIKeyServiceOpenAI     = "openai"
+	APIKeyServiceAnthropic  = "anthropic"
+	APIKeyServiceOpenRouter = "openrouter"
+)
+
+// ValidAPIKeyService reports whether keys can be stored for the service
+func ValidAPIKeyService(service string) bool {
+	switch service {
+	case APIKeyServiceOpenAI, APIKeyServiceAnthropic, APIKeyServiceOpenRouter:
+		return true
+	}
+	return false
+}
+
 // APIKeyCreateRequest represents the data needed to create a new API key
 type APIKeyCreateRequest struct {
 	Service string `json:"service" binding:"required"`
@@ -0,0 +1,24 @@
+// Package models contains the data models for the application
+package models
+
+// Backlink is a node elsewhere whose content references the requested node
+type Backlink struct {
+	NodeID       string `json:"node_id"`
+	MindMapID    string `json:"mind_map_id"`
+	MindMapTitle string `json:"mind_map_title"`
+	Content      string `json:"content"`
+}
+
+// BacklinksResponse is returned by GET /api/nodes/{id}/backlinks
+type BacklinksResponse struct {
+	NodeID    string     `json:"node_id"`
+	Backlinks []Backlink `json:"backlinks"`
+}
+
+// NodeLink is an indexed reference from a node to another mind map or node
+type NodeLink struct {
+	SourceNodeID    string  `json:"source_node_id"`
+	SourceMindMapID string  `json:"source_mind_map_id"`
+	TargetMindMapID string  `json:"target_mind_map_id"`
+	TargetNodeID    *string `json:"target_node_id,omitempty"`
+}
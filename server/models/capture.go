@@ -0,0 +1,23 @@
+// Package models contains the data models for the application
+package models
+
+// NodeTypeResource is the node type of nodes captured from a web page, whose source is
+// kept in metadata
+const NodeTypeResource = "resource"
+
+// CaptureRequest is a snippet clipped from a web page. The node is added under ParentID
+// when given, otherwise under the root of MindMapID, or of the user's inbox without either
+type CaptureRequest struct {
+	URL       string  `json:"url"`
+	Title     string  `json:"title"`     // Title of the page
+	Selection string  `json:"selection"` // Text selected on the page
+	MindMapID string  `json:"mind_map_id"`
+	ParentID  *string `json:"parent_id"`
+}
+
+// CaptureResult is the resource node created from a capture, with the edge attaching it
+// to its parent
+type CaptureResult struct {
+	Node Node  `json:"node"`
+	Edge *Edge `json:"edge,omitempty"`
+}
@@ -0,0 +1,36 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MindMapChange is one entry of a mind map's ordered change log
+type MindMapChange struct {
+	MindMapID string          `json:"mind_map_id"`
+	Seq       int64           `json:"seq"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// MindMapChangeLog is a window of changes returned to clients that need to resync
+type MindMapChangeLog struct {
+	MindMapID string          `json:"mind_map_id"`
+	Since     int64           `json:"since"`
+	LatestSeq int64           `json:"latest_seq"`
+	HasMore   bool            `json:"has_more"`
+	Changes   []MindMapChange `json:"changes"`
+}
+
+// MindMapSyncState summarizes a mind map's latest state so clients can cheaply check
+// whether their copy has drifted and needs a full refresh
+type MindMapSyncState struct {
+	MindMapID      string     `json:"mind_map_id"`
+	LatestSeq      int64      `json:"latest_seq"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	NodesUpdatedAt *time.Time `json:"nodes_updated_at,omitempty"` // Latest node update, nil for an empty map
+	NodeCount      int        `json:"node_count"`
+	EdgeCount      int        `json:"edge_count"`
+}
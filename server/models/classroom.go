@@ -0,0 +1,40 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"time"
+)
+
+// MindMapShare grants a user, identified by email, access to a mind map they do not own
+type MindMapShare struct {
+	ID         string    `json:"id"`
+	MindMapID  string    `json:"mind_map_id"`
+	Email      string    `json:"email"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ClassroomCreateRequest represents the roster used to clone a template map per participant
+type ClassroomCreateRequest struct {
+	Emails []string `json:"emails" binding:"required"`
+}
+
+// ClassroomCopy represents a participant's copy of a classroom template map
+type ClassroomCopy struct {
+	ID               string    `json:"id"`
+	TemplateID       string    `json:"template_id"`
+	MindMapID        string    `json:"mind_map_id"`
+	ParticipantEmail string    `json:"participant_email"`
+	Title            string    `json:"title"`
+	NodeCount        int       `json:"node_count"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ClassroomDashboard aggregates the progress of every participant copy of a template map
+type ClassroomDashboard struct {
+	TemplateID     string          `json:"template_id"`
+	Participants   int             `json:"participants"`
+	TotalNodeCount int             `json:"total_node_count"`
+	Copies         []ClassroomCopy `json:"copies"`
+}
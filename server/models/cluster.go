@@ -0,0 +1,42 @@
+package models
+
+// NodeTypeGroup is the node type of a node grouping similar nodes, created by clustering
+const NodeTypeGroup = "group"
+
+// How the similarity of clustered nodes was measured
+const (
+	ClusterMethodEmbeddings = "embeddings" // Cosine similarity of embeddings of their content
+	ClusterMethodWords      = "words"      // Shared words, when embeddings are unavailable
+)
+
+// ClusterRequest is the optional body of POST /api/mindmaps/{id}/cluster
+type ClusterRequest struct {
+	NodeID    string   `json:"node_id"`   // Node whose children are clustered, the map's root by default
+	Threshold *float64 `json:"threshold"` // Similarity from 0 to 1 at which nodes are grouped; defaults depend on the method
+	Apply     bool     `json:"apply"`     // Create a group node for each cluster and move its nodes under it
+}
+
+// NodeCluster is a group of similar nodes
+type NodeCluster struct {
+	Label     string   `json:"label"`
+	NodeIDs   []string `json:"node_ids"`
+	GroupNode *Node    `json:"group_node,omitempty"` // Set when the cluster was applied
+}
+
+// NodeGroup is a group node to create with the nodes to move under it
+type NodeGroup struct {
+	Group   NodeCreateRequest
+	NodeIDs []string
+}
+
+// ClusterResult is returned when the children of a node are clustered. Clusters have at
+// least two nodes; nodes similar to no other are listed as unclustered
+type ClusterResult struct {
+	MindMapID   string        `json:"mind_map_id"`
+	ParentID    string        `json:"parent_id"`
+	Method      string        `json:"method"` // One of the ClusterMethod values
+	Threshold   float64       `json:"threshold"`
+	Clusters    []NodeCluster `json:"clusters"`
+	Unclustered []string      `json:"unclustered"`
+	Applied     bool          `json:"applied"`
+}
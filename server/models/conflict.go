@@ -0,0 +1,13 @@
+// Package models contains the data models for the application
+package models
+
+// WriteConflict describes an update rejected because the entity changed since the version the
+// client edited. It is returned with the 409 response and sent as a realtime conflict event,
+// so the client can show the current server version next to its own and offer a merge
+type WriteConflict struct {
+	Entity   string      `json:"entity"`
+	EntityID string      `json:"entity_id"`
+	UserID   string      `json:"user_id"` // The user whose write was rejected
+	Current  interface{} `json:"current"`
+	Rejected interface{} `json:"rejected"`
+}
@@ -0,0 +1,61 @@
+// Package models contains the data models for the application
+package models
+
+// Custom field types
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldDate        = "date" // YYYY-MM-DD
+	CustomFieldCheckbox    = "checkbox"
+	CustomFieldURL         = "url"
+	CustomFieldSelect      = "select"
+	CustomFieldMultiSelect = "multi_select"
+	CustomFieldRollup      = "rollup" // Computed from child nodes, see RollupDefinition
+)
+
+// Rollup functions
+const (
+	RollupCount          = "count"           // Number of nodes rolled up
+	RollupSum            = "sum"             // Sum of a number field
+	RollupAverage        = "avg"             // Average of a number field over nodes that have a value
+	RollupMin            = "min"             // Smallest value of a number field
+	RollupMax            = "max"             // Largest value of a number field
+	RollupPercentChecked = "percent_checked" // Share of nodes with a checkbox field ticked, 0 to 100
+)
+
+// Rollup scopes
+const (
+	RollupScopeChildren    = "children"
+	RollupScopeDescendants = "descendants"
+)
+
+// CustomFieldsMetadataKey is the node metadata key holding custom field values by field name
+const CustomFieldsMetadataKey = "fields"
+
+// CustomField defines a field that nodes of a mind map can fill in. Select fields
+// choose from Options; rollup fields are computed on read and cannot be written
+type CustomField struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Options []string          `json:"options,omitempty"`
+	Rollup  *RollupDefinition `json:"rollup,omitempty"`
+}
+
+// RollupDefinition defines how a rollup field is computed for a parent node from the
+// Field values of its children, or of all its descendants. Count needs no Field
+type RollupDefinition struct {
+	Function string `json:"function"`
+	Field    string `json:"field,omitempty"`
+	Scope    string `json:"scope,omitempty"` // Defaults to children
+}
+
+// CustomFieldsRequest replaces the custom field schema of a mind map
+type CustomFieldsRequest struct {
+	Fields []CustomField `json:"fields"`
+}
+
+// CustomFieldsResponse is the custom field schema of a mind map
+type CustomFieldsResponse struct {
+	MindMapID string        `json:"mind_map_id"`
+	Fields    []CustomField `json:"fields"`
+}
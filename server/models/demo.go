@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// DemoMap is an ephemeral map of an anonymous visitor trying the app without an account
+type DemoMap struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Nodes     []DemoNode `json:"nodes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"` // When the map is purged
+}
+
+// DemoNode is a node of a demo map
+type DemoNode struct {
+	ID        string    `json:"id"`
+	ParentID  *string   `json:"parent_id"`
+	Content   string    `json:"content"`
+	PositionX float64   `json:"position_x"`
+	PositionY float64   `json:"position_y"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DemoMapCreateRequest is the body of POST /api/demo/maps
+type DemoMapCreateRequest struct {
+	Title string `json:"title"`
+}
+
+// DemoMapCreated is returned when a demo map is created. The token is shown only once
+// and must be sent in the X-Demo-Token header to use the map
+type DemoMapCreated struct {
+	DemoMap
+	Token string `json:"token"`
+}
+
+// DemoNodeCreateRequest is the body of POST /api/demo/maps/{id}/nodes
+type DemoNodeCreateRequest struct {
+	ParentID  string  `json:"parent_id"` // Defaults to the root node
+	Content   string  `json:"content"`
+	PositionX float64 `json:"position_x"`
+	PositionY float64 `json:"position_y"`
+}
+
+// DemoSuggestRequest is the body of POST /api/demo/maps/{id}/suggest
+type DemoSuggestRequest struct {
+	NodeID string `json:"node_id"` // Node to suggest ideas for, defaulting to the root node
+	Type   string `json:"type"`    // "new", "expand", "improve" or "branch", like idea generation
+	Count  int    `json:"count"`
+}
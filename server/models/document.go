@@ -0,0 +1,36 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// Document is a document uploaded to a mind map to ground generation in
+type Document struct {
+	ID         string    `json:"id"`
+	MindMapID  string    `json:"mind_map_id"`
+	UserID     *string   `json:"user_id"` // Nil once the uploader is deleted
+	Filename   string    `json:"filename"`
+	Format     string    `json:"format"` // "txt", "pdf" or "docx"
+	SizeBytes  int       `json:"size_bytes"`
+	ChunkCount int       `json:"chunk_count"`
+	Embedded   bool      `json:"embedded"` // Whether its chunks have embeddings
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DocumentChunk is a piece of a document's text, the unit ideas are grounded in
+type DocumentChunk struct {
+	ID         int64
+	DocumentID string
+	Filename   string // Of the document
+	Index      int    // Position of the chunk in the document, from 0
+	Content    string
+	Embedding  []float32 // Nil when the chunk was not embedded
+}
+
+// Citation points a generated idea at the part of an uploaded document it draws on. Nodes
+// created from cited ideas keep their citations in their metadata
+type Citation struct {
+	DocumentID string `json:"document_id"`
+	Filename   string `json:"filename"`
+	ChunkIndex int    `json:"chunk_index"`
+	Excerpt    string `json:"excerpt"`
+}
@@ -0,0 +1,76 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NodeTypeCritique is the node type of the counterarguments, risks and weaknesses a critique
+// generation finds in a node, so they render apart from ideas
+const NodeTypeCritique = "critique"
+
+// Generation is a past idea generation of a mind map. Request is the generation request as
+// sent, without its API key, so it can be sent again to re-run the generation; Ideas are
+// the ideas it returned, each with its children for multi-level generations
+type Generation struct {
+	ID        string          `json:"id"`
+	MindMapID string          `json:"mind_map_id"`
+	UserID    *string         `json:"user_id"` // Unset once the user who made it is deleted
+	JobID     *string         `json:"job_id,omitempty"`
+	Request   json.RawMessage `json:"request"`
+	Ideas     json.RawMessage `json:"ideas"`
+	Provider  string          `json:"provider"`
+	Model     string          `json:"model"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// GenerationHistory is a page of a mind map's generations, newest first
+type GenerationHistory struct {
+	MindMapID   string       `json:"mind_map_id"`
+	Generations []Generation `json:"generations"`
+	HasMore     bool         `json:"has_more"` // Whether older generations remain
+}
+
+// Verdicts of idea feedback
+const (
+	IdeaAccepted = "accepted"
+	IdeaRejected = "rejected"
+)
+
+// IdeaFeedback is a user's verdict on a generated idea
+type IdeaFeedback struct {
+	ID           string    `json:"id"`
+	MindMapID    string    `json:"mind_map_id"`
+	UserID       string    `json:"user_id"`
+	GenerationID *string   `json:"generation_id,omitempty"` // The generation the idea came from, if known
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	Verdict      string    `json:"verdict"` // "accepted" or "rejected"
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// IdeaFeedbackRequest is the body of POST /api/generate/feedback
+type IdeaFeedbackRequest struct {
+	MindMapID    string `json:"mind_map_id"`
+	GenerationID string `json:"generation_id"` // Optional
+	Title        string `json:"title"`
+	Content      string `json:"content"`
+	Verdict      string `json:"verdict"`
+}
+
+// IdeaMerge is the content of a node after the ideas duplicating it were merged into it
+type IdeaMerge struct {
+	NodeID  string
+	Content string
+}
+
+// IdeaNode is a node to create for a generated idea, or for a framework category ideas are
+// filed under. ID is chosen up front so nodes later in the same batch can name the node as
+// their parent; EdgeType is the type of the edge from the parent
+type IdeaNode struct {
+	ID       string
+	Node     NodeCreateRequest
+	EdgeType string
+}
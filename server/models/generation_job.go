@@ -0,0 +1,33 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a generation job
+const (
+	GenerationJobQueued    = "queued"
+	GenerationJobRunning   = "running"
+	GenerationJobSucceeded = "succeeded"
+	GenerationJobFailed    = "failed"
+)
+
+// GenerationJob is an idea generation running in the background, which the client polls
+// until it has succeeded or failed
+type GenerationJob struct {
+	ID             string          `json:"id"`
+	UserID         string          `json:"-"`
+	MindMapID      string          `json:"mind_map_id"`
+	Status         string          `json:"status"`
+	Request        json.RawMessage `json:"-"`                // The generation request, without its API key
+	Result         json.RawMessage `json:"result,omitempty"` // Set once the job has succeeded
+	Error          string          `json:"error,omitempty"`  // Set once the job has failed
+	CompletedSteps int             `json:"completed_steps"`  // AI calls made so far
+	TotalSteps     int             `json:"total_steps"`      // AI calls the job needs
+	Attempts       int             `json:"-"`
+	CreatedAt      time.Time       `json:"created_at"`
+	StartedAt      *time.Time      `json:"started_at,omitempty"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// Inbound parse modes: every line of the message becomes a node, or the whole message
+// becomes a single node
+const (
+	InboundParseLines  = "lines"
+	InboundParseSingle = "single"
+)
+
+// InboundAddress is a user's secret address for creating maps and nodes from incoming
+// emails and webhook payloads. Messages go to MindMapID when it is set and otherwise each
+// create a new map. Only the account email and AllowedSenders may send to it
+type InboundAddress struct {
+	UserID         string    `json:"user_id"`
+	Token          string    `json:"token"`
+	Email          string    `json:"email,omitempty"` // Empty when inbound email is not configured
+	WebhookPath    string    `json:"webhook_path"`
+	MindMapID      *string   `json:"mind_map_id"`
+	AllowedSenders []string  `json:"allowed_senders"`
+	ParseMode      string    `json:"parse_mode"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// InboundAddressRequest is the body for setting up an inbound address
+type InboundAddressRequest struct {
+	MindMapID      *string  `json:"mind_map_id"` // Empty string creates a new map per message
+	AllowedSenders []string `json:"allowed_senders"`
+	ParseMode      string   `json:"parse_mode"`
+}
+
+// InboundMessage is an incoming email or webhook payload
+type InboundMessage struct {
+	From      string `json:"from"`
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`
+	MindMapID string `json:"mind_map_id,omitempty"` // Overrides the address's target map
+}
+
+// InboundResult reports what an incoming message created
+type InboundResult struct {
+	MindMapID string   `json:"mind_map_id"`
+	Created   bool     `json:"created"` // Whether a new mind map was created
+	NodeIDs   []string `json:"node_ids"`
+}
@@ -0,0 +1,49 @@
+// Package models contains the data models for the application
+package models
+
+// InboxMoveRequest moves inbox nodes, with their subtrees, into another mind map. They are
+// attached to ParentID when given, otherwise to the first root of the target map
+type InboxMoveRequest struct {
+	NodeIDs   []string `json:"node_ids"`
+	MindMapID string   `json:"mind_map_id"`
+	ParentID  *string  `json:"parent_id"`
+}
+
+// InboxMoveResult reports the nodes moved out of the inbox
+type InboxMoveResult struct {
+	InboxID   string   `json:"inbox_id"`
+	MindMapID string   `json:"mind_map_id"`
+	NodeIDs   []string `json:"node_ids"` // Every moved node, including descendants
+}
+
+// InboxTriageRequest asks where inbox nodes belong. NodeIDs defaults to every top-level
+// capture in the inbox. With Apply, suggestions at or above Threshold are moved right away
+type InboxTriageRequest struct {
+	NodeIDs   []string `json:"node_ids"`
+	Apply     bool     `json:"apply"`
+	Threshold *float64 `json:"threshold"`
+	APIKey    string   `json:"api_key"`
+}
+
+// InboxTriageSuggestion is where an inbox node should be filed. MindMapID is empty when no
+// map fits. Suggestions below the threshold need confirming through the move endpoint
+type InboxTriageSuggestion struct {
+	NodeID            string  `json:"node_id"`
+	Content           string  `json:"content"`
+	MindMapID         string  `json:"mind_map_id,omitempty"`
+	MindMapTitle      string  `json:"mind_map_title,omitempty"`
+	ParentID          string  `json:"parent_id,omitempty"`
+	ParentContent     string  `json:"parent_content,omitempty"`
+	Confidence        float64 `json:"confidence"`
+	Reason            string  `json:"reason,omitempty"`
+	NeedsConfirmation bool    `json:"needs_confirmation"`
+	Applied           bool    `json:"applied"`
+}
+
+// InboxTriageResult lists a suggestion for each triaged inbox node
+type InboxTriageResult struct {
+	InboxID     string                  `json:"inbox_id"`
+	Threshold   float64                 `json:"threshold"`
+	AITriage    bool                    `json:"ai_triage"`
+	Suggestions []InboxTriageSuggestion `json:"suggestions"`
+}
@@ -0,0 +1,39 @@
+// Package models contains the data models for the application
+package models
+
+// Knowledge graph node kinds
+const (
+	GraphNodeMindMap = "mind_map"
+	GraphNodeNode    = "node"
+)
+
+// Knowledge graph edge kinds
+const (
+	GraphEdgeContains = "contains" // A mind map to each of its root nodes
+	GraphEdgeEdge     = "edge"     // An edge drawn within a mind map
+	GraphEdgeLink     = "link"     // A reference from node content to another map or node
+)
+
+// KnowledgeGraph merges all of a user's mind maps into one graph, with each map as a super-node
+type KnowledgeGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a mind map or a node within one
+type GraphNode struct {
+	ID        string   `json:"id"`
+	Kind      string   `json:"kind"`
+	Label     string   `json:"label"`
+	MindMapID string   `json:"mind_map_id"`
+	NodeType  string   `json:"node_type,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// GraphEdge connects two graph nodes
+type GraphEdge struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
@@ -0,0 +1,26 @@
+// Package models contains the data models for the application
+package models
+
+// LayoutResult reports the nodes a layout operation moved. Remaining counts overlaps
+// that could not be resolved
+type LayoutResult struct {
+	DryRun    bool                        `json:"dry_run"`
+	MindMapID string                      `json:"mind_map_id"`
+	Moved     int                         `json:"moved"`
+	Positions []NodePositionUpdateRequest `json:"positions"`
+	Remaining int                         `json:"remaining_overlaps"`
+}
+
+// CanvasPoint is a point on the canvas, in the coordinates of node positions
+type CanvasPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// EdgeBundle is a group of cross-links that run side by side, drawn as curves through two
+// shared control points so dense maps do not turn into a tangle of crossing lines. Each
+// edge passes the control point nearer its source first
+type EdgeBundle struct {
+	EdgeIDs  []string       `json:"edge_ids"`
+	Controls [2]CanvasPoint `json:"controls"`
+}
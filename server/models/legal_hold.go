@@ -0,0 +1,73 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LegalHold keeps a user's mind maps from being deleted for good until it is released
+type LegalHold struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	UserID     string     `json:"user_id"`
+	UserEmail  string     `json:"user_email"` // Empty once the user is deleted
+	Reason     string     `json:"reason"`
+	CreatedBy  *string    `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedBy *string    `json:"released_by,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"` // Unset while the hold is active
+}
+
+// LegalHoldCreateRequest is the body of POST /api/org/legal-holds
+type LegalHoldCreateRequest struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// LegalHoldExportManifest describes a legal hold export: whose maps it holds, who made it
+// and which file holds each map
+type LegalHoldExportManifest struct {
+	HoldID     string                 `json:"hold_id"`
+	UserID     string                 `json:"user_id"`
+	UserEmail  string                 `json:"user_email"`
+	Reason     string                 `json:"reason"`
+	ExportedBy string                 `json:"exported_by"`
+	ExportedAt time.Time              `json:"exported_at"`
+	MindMaps   []LegalHoldExportEntry `json:"mind_maps"`
+}
+
+// LegalHoldExportEntry is a mind map in a legal hold export
+type LegalHoldExportEntry struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"` // Deleted maps still in the trash are exported too
+	File   string `json:"file"`
+}
+
+// Audit event actions
+const (
+	AuditLegalHoldCreated  = "legal_hold.created"
+	AuditLegalHoldReleased = "legal_hold.released"
+	AuditLegalHoldExported = "legal_hold.exported"
+)
+
+// AuditEvent is an entry of an organization's audit log
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	TenantID   string          `json:"tenant_id"`
+	ActorID    *string         `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"` // Kind of thing acted on, e.g. "legal_hold"
+	TargetID   string          `json:"target_id"`
+	Details    json.RawMessage `json:"details"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	UserAgent  string          `json:"user_agent,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditLog is a page of an organization's audit log, newest first
+type AuditLog struct {
+	Events  []AuditEvent `json:"events"`
+	HasMore bool         `json:"has_more"` // Whether older events remain
+}
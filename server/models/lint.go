@@ -0,0 +1,26 @@
+// Package models contains the data models for the application
+package models
+
+// Lint finding severities
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+// LintFinding is a single quality issue found in a mind map, pointing at the nodes involved
+type LintFinding struct {
+	Rule       string   `json:"rule"`
+	Severity   string   `json:"severity"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion"`
+	NodeIDs    []string `json:"node_ids"`
+	Source     string   `json:"source"` // "rule" or "ai"
+}
+
+// LintReport is returned by GET /api/mindmaps/{id}/lint
+type LintReport struct {
+	MindMapID     string        `json:"mind_map_id"`
+	AISuggestions bool          `json:"ai_suggestions"` // Set when AI findings are included
+	Findings      []LintFinding `json:"findings"`
+}
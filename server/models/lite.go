@@ -0,0 +1,42 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// LiteNode is the compact form of a node returned by the lite API profile. Style data and
+// metadata are omitted and long content is truncated, with HasMore set so the client can
+// fetch the full node when it is opened
+type LiteNode struct {
+	ID        string    `json:"id"`
+	ParentID  *string   `json:"parent_id,omitempty"`
+	Content   string    `json:"content"`
+	HasMore   bool      `json:"has_more,omitempty"`
+	PositionX float64   `json:"position_x"`
+	PositionY float64   `json:"position_y"`
+	NodeType  string    `json:"node_type"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LiteEdge is the compact form of an edge returned by the lite API profile
+type LiteEdge struct {
+	ID       string `json:"id"`
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+}
+
+// LiteMindMap is a mind map with its contents in the lite API profile. ChangeSeq is the
+// position in the change log the snapshot reflects; passing it back as since returns a
+// delta holding only the nodes changed or deleted after it. Edges are left out of a delta
+// when none changed, and are otherwise sent in full since they are small
+type LiteMindMap struct {
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Status         string     `json:"status"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ChangeSeq      int64      `json:"change_seq"`
+	Delta          bool       `json:"delta"`
+	Since          int64      `json:"since,omitempty"`
+	Nodes          []LiteNode `json:"nodes"`
+	Edges          []LiteEdge `json:"edges,omitempty"`
+	DeletedNodeIDs []string   `json:"deleted_node_ids,omitempty"`
+}
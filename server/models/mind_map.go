@@ -5,36 +5,118 @@ import (
 	"time"
 )
 
+// Mind map statuses
+const (
+	MindMapStatusActive   = "active"
+	MindMapStatusArchived = "archived"
+	MindMapStatusDeleted  = "deleted"
+)
+
+// Mind map visibility levels, from the narrowest to the widest
+const (
+	VisibilityPrivate   = "private"   // Owner and the people the map is shared with
+	VisibilityLink      = "link"      // Anyone signed in who has the link
+	VisibilityWorkspace = "workspace" // Everyone in the owner's organization
+	VisibilityPublic    = "public"    // Everyone signed in, listed in the public gallery
+)
+
+// ValidVisibility reports whether visibility is a known mind map visibility level
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPrivate, VisibilityLink, VisibilityWorkspace, VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+// mindMapStatusTransitions lists the statuses each status may change to.
+// Deleted maps are gone for good, so nothing leads out of deleted
+var mindMapStatusTransitions = map[string][]string{
+	MindMapStatusActive:   {MindMapStatusArchived, MindMapStatusDeleted},
+	MindMapStatusArchived: {MindMapStatusActive, MindMapStatusDeleted},
+}
+
+// ValidMindMapStatus reports whether status is a known mind map status
+func ValidMindMapStatus(status string) bool {
+	switch status {
+	case MindMapStatusActive, MindMapStatusArchived, MindMapStatusDeleted:
+		return true
+	}
+	return false
+}
+
+// CanTransitionMindMapStatus reports whether a mind map may move from one status to another.
+// Keeping the current status is always allowed
+func CanTransitionMindMapStatus(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, allowed := range mindMapStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
 // MindMap represents a mind map created by a user
 type MindMap struct {
 	ID          string    `json:"id"`
 	UserID      string    `json:"user_id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
-	IsPublic    bool      `json:"is_public"`
+	Visibility  string    `json:"visibility"` // One of the Visibility levels
 	Status      string    `json:"status"`
+	VoteLimit   int       `json:"vote_limit"`
+	Icon        string    `json:"icon"`         // Emoji shown next to the title
+	CoverImage  string    `json:"cover_image"`  // URL of the dashboard cover image
+	LayoutMode  string    `json:"layout_mode"`  // Layout used by re-layout and drawing exports, empty for none
+	IsInbox     bool      `json:"is_inbox"`     // Whether this is the user's default capture map
+	Folder      string    `json:"folder"`       // Dashboard folder path, empty for the top level
+	AllowExport bool      `json:"allow_export"` // Whether people the map is shared with may export or duplicate it
+	RedactPII   bool      `json:"redact_pii"`   // Whether personal data is redacted before content is sent to AI providers
+	ChangeSeq   int64     `json:"change_seq"`   // Sequence of the latest change, a cursor for GET /changes?since=
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-// MindMapWithDetails includes the mind map with its nodes and edges
+// MindMapWithDetails includes the mind map with its nodes, edges and custom field schema
 type MindMapWithDetails struct {
 	MindMap
-	Nodes []Node `json:"nodes"`
-	Edges []Edge `json:"edges"`
+	Nodes  []Node        `json:"nodes"`
+	Edges  []Edge        `json:"edges"`
+	Fields []CustomField `json:"fields"`
+}
+
+// MindMapSummary is a mind map as listed on the dashboard, with the sizes of its contents
+type MindMapSummary struct {
+	MindMap
+	NodeCount int `json:"node_count"`
+	EdgeCount int `json:"edge_count"`
 }
 
 // MindMapCreateRequest represents the data needed to create a new mind map
 type MindMapCreateRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
-	IsPublic    bool   `json:"is_public"`
+	Visibility  string `json:"visibility"` // Defaults to private
 }
 
 // MindMapUpdateRequest represents the data that can be updated for a mind map
 type MindMapUpdateRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	IsPublic    bool   `json:"is_public"`
-	Status      string `json:"status"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Visibility  *string `json:"visibility"`
+	Status      string  `json:"status"`
+	VoteLimit   *int    `json:"vote_limit"`
+	Icon        *string `json:"icon"`        // Empty string clears the icon
+	CoverImage  *string `json:"cover_image"` // Empty string clears the cover image
+	LayoutMode  *string `json:"layout_mode"` // Empty string clears the layout mode
+	AllowExport *bool   `json:"allow_export"`
+	RedactPII   *bool   `json:"redact_pii"`
+}
+
+// MindMapDuplicateRequest names the copy made by duplicating a mind map
+type MindMapDuplicateRequest struct {
+	Title string `json:"title"` // Defaults to the source title
 }
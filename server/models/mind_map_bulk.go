@@ -0,0 +1,33 @@
+// Package models contains the data models for the application
+package models
+
+// Operations a bulk mind map request can apply
+const (
+	MindMapBulkArchive      = "archive"
+	MindMapBulkDelete       = "delete"
+	MindMapBulkMoveToFolder = "move-to-folder"
+)
+
+// ValidMindMapBulkOperation reports whether operation is a bulk mind map operation
+func ValidMindMapBulkOperation(operation string) bool {
+	switch operation {
+	case MindMapBulkArchive, MindMapBulkDelete, MindMapBulkMoveToFolder:
+		return true
+	}
+	return false
+}
+
+// MindMapBulkRequest applies one operation to several mind maps owned by the user
+type MindMapBulkRequest struct {
+	Operation  string   `json:"operation"`
+	MindMapIDs []string `json:"mind_map_ids"`
+	Folder     string   `json:"folder"` // Target of move-to-folder, empty for the top level
+}
+
+// MindMapBulkResult reports the mind maps a bulk operation changed. Deleted maps are only
+// listed by ID
+type MindMapBulkResult struct {
+	Operation  string    `json:"operation"`
+	MindMapIDs []string  `json:"mind_map_ids"`
+	MindMaps   []MindMap `json:"mind_maps,omitempty"`
+}
@@ -0,0 +1,72 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MindMapDocument is the self-contained JSON export of a mind map. Importing it creates a
+// copy identical to the original apart from IDs, ownership and sharing settings
+type MindMapDocument struct {
+	FormatVersion int             `json:"format_version"`
+	ExportedAt    time.Time       `json:"exported_at"`
+	MindMap       DocumentMindMap `json:"mind_map"`
+	Nodes         []DocumentNode  `json:"nodes"`
+	Edges         []DocumentEdge  `json:"edges"`
+}
+
+// DocumentMindMap holds the exported settings of a mind map
+type DocumentMindMap struct {
+	ID          string `json:"id,omitempty"` // The exported map, so links to it can be pointed at the import
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	CoverImage  string `json:"cover_image"`
+	VoteLimit   int    `json:"vote_limit"`
+}
+
+// DocumentNode is an exported node. IDs are only meaningful within the document.
+// Tags and collapsed state travel in metadata and style data untouched
+type DocumentNode struct {
+	ID        string          `json:"id"`
+	ParentID  *string         `json:"parent_id"`
+	Content   string          `json:"content"`
+	PositionX float64         `json:"position_x"`
+	PositionY float64         `json:"position_y"`
+	NodeType  string          `json:"node_type"`
+	StyleData json.RawMessage `json:"style_data"`
+	Metadata  json.RawMessage `json:"metadata"`
+	Pinned    bool            `json:"pinned,omitempty"`
+	Icon      string          `json:"icon,omitempty"`
+	Priority  string          `json:"priority,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// DocumentEdge is an exported edge between two nodes of the document
+type DocumentEdge struct {
+	ID        string          `json:"id"`
+	SourceID  string          `json:"source_id"`
+	TargetID  string          `json:"target_id"`
+	EdgeType  string          `json:"edge_type"`
+	StyleData json.RawMessage `json:"style_data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// MindMapDocumentImportResult is returned after importing a document. Fidelity compares
+// the imported map against the document so lossy round trips are caught right away.
+// Dry runs only validate the document, leaving MindMap nil and Fidelity empty
+type MindMapDocumentImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	MindMap  *MindMap         `json:"mind_map"`
+	Nodes    int              `json:"nodes"` // Nodes created by the import
+	Edges    int              `json:"edges"` // Edges created by the import
+	Fidelity DocumentFidelity `json:"fidelity"`
+}
+
+// DocumentFidelity lists every difference between a document and the map imported from it
+type DocumentFidelity struct {
+	Identical   bool     `json:"identical"`
+	Differences []string `json:"differences"`
+}
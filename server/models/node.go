@@ -8,39 +8,119 @@ import (
 
 // Node represents a node in a mind map
 type Node struct {
-	ID         string          `json:"id"`
-	MindMapID  string          `json:"mind_map_id"`
-	ParentID   *string         `json:"parent_id"`
-	Content    string          `json:"content"`
-	PositionX  float64         `json:"position_x"`
-	PositionY  float64         `json:"position_y"`
-	NodeType   string          `json:"node_type"`
-	StyleData  json.RawMessage `json:"style_data"`
-	Metadata   json.RawMessage `json:"metadata"`
-	CreatedAt  time.Time       `json:"created_at"`
-	UpdatedAt  time.Time       `json:"updated_at"`
+	ID           string          `json:"id"`
+	MindMapID    string          `json:"mind_map_id"`
+	ParentID     *string         `json:"parent_id"`
+	Content      string          `json:"content"`
+	PositionX    float64         `json:"position_x"`
+	PositionY    float64         `json:"position_y"`
+	NodeType     string          `json:"node_type"`
+	StyleData    json.RawMessage `json:"style_data"`
+	Metadata     json.RawMessage `json:"metadata"`
+	VoteCount    int             `json:"vote_count"`
+	CreatedBy    *string         `json:"created_by"`
+	Anonymous    bool            `json:"anonymous"`
+	Pinned       bool            `json:"pinned"`   // Kept in place when the map is re-laid out
+	Icon         string          `json:"icon"`     // Emoji shown on the node, empty for none
+	Priority     string          `json:"priority"` // One of the NodePriority values, empty for none
+	AuthorHidden bool            `json:"-"`        // Set when an anonymous contribution's author has not been revealed yet
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+
+	// Rollups holds the computed rollup fields of a parent node by field name
+	Rollups map[string]float64 `json:"rollups,omitempty"`
+}
+
+// MaskAttribution hides the author of an anonymous contribution until the facilitator reveals it
+func (n *Node) MaskAttribution() {
+	if n.AuthorHidden {
+		n.CreatedBy = nil
+	}
+}
+
+// MaskNodeAttribution applies MaskAttribution to every node in the slice
+func MaskNodeAttribution(nodes []Node) {
+	for i := range nodes {
+		nodes[i].MaskAttribution()
+	}
+}
+
+// Node priorities, from lowest to highest
+const (
+	NodePriorityLow    = "low"
+	NodePriorityMedium = "medium"
+	NodePriorityHigh   = "high"
+	NodePriorityUrgent = "urgent"
+)
+
+// nodePriorityRanks orders the node priorities; no priority ranks lowest
+var nodePriorityRanks = map[string]int{
+	"":                 0,
+	NodePriorityLow:    1,
+	NodePriorityMedium: 2,
+	NodePriorityHigh:   3,
+	NodePriorityUrgent: 4,
+}
+
+// ValidNodePriority reports whether priority is a node priority or empty
+func ValidNodePriority(priority string) bool {
+	_, ok := nodePriorityRanks[priority]
+	return ok
+}
+
+// NodePriorityRank returns the rank of a priority for sorting, higher being more urgent,
+// or -1 when it is not a node priority
+func NodePriorityRank(priority string) int {
+	rank, ok := nodePriorityRanks[priority]
+	if !ok {
+		return -1
+	}
+	return rank
 }
 
 // NodeCreateRequest represents the data needed to create a new node
 type NodeCreateRequest struct {
-	MindMapID  string          `json:"mind_map_id" binding:"required"`
-	ParentID   *string         `json:"parent_id"`
-	Content    string          `json:"content" binding:"required"`
-	PositionX  float64         `json:"position_x" binding:"required"`
-	PositionY  float64         `json:"position_y" binding:"required"`
-	NodeType   string          `json:"node_type"`
-	StyleData  json.RawMessage `json:"style_data"`
-	Metadata   json.RawMessage `json:"metadata"`
+	MindMapID string          `json:"mind_map_id" binding:"required"`
+	ParentID  *string         `json:"parent_id"`
+	Content   string          `json:"content" binding:"required"`
+	PositionX float64         `json:"position_x" binding:"required"`
+	PositionY float64         `json:"position_y" binding:"required"`
+	NodeType  string          `json:"node_type"`
+	StyleData json.RawMessage `json:"style_data"`
+	Metadata  json.RawMessage `json:"metadata"`
+	Anonymous bool            `json:"anonymous"`
+	Icon      string          `json:"icon"`
+	Priority  string          `json:"priority"`
+	CreatedBy string          `json:"-"` // Set internally from the authenticated user
+	SessionID string          `json:"-"` // Set internally when created during a brainstorm session
+
+	// TemplateID optionally names a node template of the user to start the node from
+	TemplateID string `json:"template_id,omitempty"`
 }
 
 // NodeUpdateRequest represents the data that can be updated for a node
 type NodeUpdateRequest struct {
-	Content    string          `json:"content"`
-	PositionX  float64         `json:"position_x"`
-	PositionY  float64         `json:"position_y"`
-	NodeType   string          `json:"node_type"`
-	StyleData  json.RawMessage `json:"style_data"`
-	Metadata   json.RawMessage `json:"metadata"`
+	Content   string          `json:"content"`
+	PositionX float64         `json:"position_x"`
+	PositionY float64         `json:"position_y"`
+	NodeType  string          `json:"node_type"`
+	StyleData json.RawMessage `json:"style_data"`
+	Metadata  json.RawMessage `json:"metadata"`
+	// Pinned keeps the node in place when the map is re-laid out; nil leaves it unchanged
+	Pinned *bool `json:"pinned,omitempty"`
+	// Icon and Priority are left unchanged when nil; an empty string clears them
+	Icon     *string `json:"icon,omitempty"`
+	Priority *string `json:"priority,omitempty"`
+	// BaseUpdatedAt is the updated_at of the version the client edited. When set, the
+	// update is rejected if the node has changed since, instead of overwriting that change
+	BaseUpdatedAt *time.Time `json:"base_updated_at,omitempty"`
+}
+
+// PositionOnly reports whether the update only moves the node. Moves leave the node's
+// version alone, so dragging a node never conflicts with an edit of its content
+func (r NodeUpdateRequest) PositionOnly() bool {
+	return r.Content == "" && r.NodeType == "" && r.StyleData == nil && r.Metadata == nil &&
+		r.Pinned == nil && r.Icon == nil && r.Priority == nil
 }
 
 // NodePositionUpdateRequest represents the data needed to update a node's position
@@ -54,3 +134,16 @@ type NodePositionUpdateRequest struct {
 type NodeBatchPositionUpdateRequest struct {
 	Positions []NodePositionUpdateRequest `json:"positions" binding:"required"`
 }
+
+// NodeDeletePreview lists everything deleting a node removes: the node with its whole
+// subtree, the edges touching any of them, their votes, and the nodes elsewhere whose
+// links to the subtree would stop resolving
+type NodeDeletePreview struct {
+	DryRun         bool     `json:"dry_run"`
+	NodeID         string   `json:"node_id"`
+	MindMapID      string   `json:"mind_map_id"`
+	DeletedNodeIDs []string `json:"deleted_node_ids"`
+	DeletedEdgeIDs []string `json:"deleted_edge_ids"`
+	DeletedVotes   int      `json:"deleted_votes"`
+	BrokenLinkIDs  []string `json:"broken_link_node_ids"` // Nodes outside the subtree linking into it
+}
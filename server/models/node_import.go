@@ -0,0 +1,32 @@
+// Package models contains the data models for the application
+package models
+
+// NodeImportRow is one CSV row resolved into a node that will be created
+type NodeImportRow struct {
+	Row       int      `json:"row"`
+	Ref       string   `json:"ref,omitempty"`
+	Content   string   `json:"content"`
+	ParentRef string   `json:"parent_ref,omitempty"`
+	ParentRow int      `json:"parent_row,omitempty"` // Row number of a parent created by the same import
+	ParentID  *string  `json:"parent_id,omitempty"`  // Existing node the row is attached to
+	NodeType  string   `json:"node_type"`
+	Tags      []string `json:"tags"`
+	PositionX float64  `json:"position_x"`
+	PositionY float64  `json:"position_y"`
+	NodeID    string   `json:"node_id,omitempty"`
+}
+
+// NodeImportError describes why a CSV row could not be imported
+type NodeImportError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// NodeImportResult is returned by CSV imports, both for dry runs and real imports
+type NodeImportResult struct {
+	DryRun  bool              `json:"dry_run"`
+	Columns map[string]string `json:"columns"`
+	Created int               `json:"created"`
+	Nodes   []NodeImportRow   `json:"nodes"`
+	Errors  []NodeImportError `json:"errors"`
+}
@@ -0,0 +1,22 @@
+// Package models contains the data models for the application
+package models
+
+// NodeTagsRequest adds or removes a tag on a set of nodes
+type NodeTagsRequest struct {
+	Tag     string   `json:"tag"`
+	NodeIDs []string `json:"node_ids"`
+}
+
+// TagRenameRequest renames a tag on every node of a mind map. Renaming to a tag a node
+// already has merges the two
+type TagRenameRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TagOperationResult lists the nodes a bulk tag operation changed
+type TagOperationResult struct {
+	MindMapID string   `json:"mind_map_id"`
+	NodeIDs   []string `json:"node_ids"`
+	Updated   int      `json:"updated"`
+}
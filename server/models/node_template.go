@@ -0,0 +1,32 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NodeTemplate is a reusable prefab node, such as "Experiment" or "User Story". Nodes created
+// from it start with its type, style and metadata fields, and its content as a placeholder
+type NodeTemplate struct {
+	ID          string          `json:"id"`
+	UserID      string          `json:"user_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Content     string          `json:"content"`
+	NodeType    string          `json:"node_type"`
+	StyleData   json.RawMessage `json:"style_data"`
+	Metadata    json.RawMessage `json:"metadata"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// NodeTemplateRequest is the body for creating or updating a node template
+type NodeTemplateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Content     string          `json:"content"`
+	NodeType    string          `json:"node_type"`
+	StyleData   json.RawMessage `json:"style_data"`
+	Metadata    json.RawMessage `json:"metadata"`
+}
@@ -0,0 +1,8 @@
+// Package models contains the data models for the application
+package models
+
+// Plans a user can be on, derived from their latest subscription
+const (
+	PlanFree = "free"
+	PlanPaid = "paid"
+)
@@ -0,0 +1,27 @@
+// Package models contains the data models for the application
+package models
+
+// PresentationBullet is a node shown as a bullet point on a slide
+type PresentationBullet struct {
+	NodeID  string `json:"node_id"`
+	Content string `json:"content"`
+	Level   int    `json:"level"`
+	Notes   string `json:"notes,omitempty"`
+}
+
+// PresentationSlide is one branch of a mind map rendered as a slide
+type PresentationSlide struct {
+	Index   int                  `json:"index"`
+	NodeID  string               `json:"node_id"`
+	Title   string               `json:"title"`
+	Notes   string               `json:"notes"`
+	Bullets []PresentationBullet `json:"bullets"`
+}
+
+// Presentation is the ordered slide sequence used by presentation mode
+type Presentation struct {
+	MindMapID string              `json:"mind_map_id"`
+	Title     string              `json:"title"`
+	Order     string              `json:"order"`
+	Slides    []PresentationSlide `json:"slides"`
+}
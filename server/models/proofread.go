@@ -0,0 +1,40 @@
+// Package models contains the data models for the application
+package models
+
+// Proofreading engines
+const (
+	ProofreadEngineAI    = "ai"
+	ProofreadEngineLocal = "local"
+)
+
+// ProofreadRequest represents the options for proofreading a mind map. Engine is "ai",
+// "local" or empty to use AI when a key is available. Apply writes every suggestion back
+type ProofreadRequest struct {
+	Engine string `json:"engine"`
+	Apply  bool   `json:"apply"`
+	APIKey string `json:"api_key"`
+}
+
+// ProofreadChange is a single correction within a node's content
+type ProofreadChange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// ProofreadSuggestion is the corrected content suggested for a node
+type ProofreadSuggestion struct {
+	NodeID    string            `json:"node_id"`
+	Original  string            `json:"original"`
+	Suggested string            `json:"suggested"`
+	Changes   []ProofreadChange `json:"changes"`
+	Applied   bool              `json:"applied"`
+}
+
+// ProofreadReport is returned by POST /api/mindmaps/{id}/proofread
+type ProofreadReport struct {
+	MindMapID   string                `json:"mind_map_id"`
+	Engine      string                `json:"engine"`
+	Suggestions []ProofreadSuggestion `json:"suggestions"`
+	Applied     int                   `json:"applied"`
+}
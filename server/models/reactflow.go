@@ -0,0 +1,52 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"encoding/json"
+)
+
+// ReactFlowPosition is a node position on the React Flow canvas
+type ReactFlowPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// ReactFlowNodeData is the payload React Flow hands to custom node components
+type ReactFlowNodeData struct {
+	Label     string          `json:"label"`
+	NodeType  string          `json:"nodeType"`
+	ParentID  *string         `json:"parentId"`
+	Metadata  json.RawMessage `json:"metadata"`
+	VoteCount int             `json:"voteCount"`
+	CreatedBy *string         `json:"createdBy"`
+	Anonymous bool            `json:"anonymous"`
+}
+
+// ReactFlowNode is a node shaped for React Flow
+type ReactFlowNode struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	Position ReactFlowPosition `json:"position"`
+	Data     ReactFlowNodeData `json:"data"`
+	Style    json.RawMessage   `json:"style,omitempty"`
+}
+
+// ReactFlowEdge is an edge shaped for React Flow
+type ReactFlowEdge struct {
+	ID           string          `json:"id"`
+	Source       string          `json:"source"`
+	Target       string          `json:"target"`
+	SourceHandle string          `json:"sourceHandle"`
+	TargetHandle string          `json:"targetHandle"`
+	Type         string          `json:"type"`
+	Style        json.RawMessage `json:"style,omitempty"`
+}
+
+// ReactFlowGraph is a whole mind map shaped for React Flow
+type ReactFlowGraph struct {
+	MindMap MindMap         `json:"mindMap"`
+	Nodes   []ReactFlowNode `json:"nodes"`
+	Edges   []ReactFlowEdge `json:"edges"`
+	// Bundles are hints for drawing the cross-links of dense maps as bundled curves
+	Bundles []EdgeBundle `json:"bundles"`
+}
@@ -0,0 +1,28 @@
+// Package models contains the data models for the application
+package models
+
+// ReadingOrderItem is a node in the linear reading order of a mind map, with the markers a
+// screen reader needs to convey where it sits in the hierarchy
+type ReadingOrderItem struct {
+	Index        int      `json:"index"`
+	NodeID       string   `json:"node_id"`
+	Content      string   `json:"content"`
+	NodeType     string   `json:"node_type"`
+	ParentID     *string  `json:"parent_id"`
+	Depth        int      `json:"depth"`
+	Branch       string   `json:"branch"`        // Outline number, such as "2.1.3"
+	Position     int      `json:"position"`      // 1-based position among its siblings
+	SiblingCount int      `json:"sibling_count"` // Number of siblings, including itself
+	ChildCount   int      `json:"child_count"`
+	EndsBranch   int      `json:"ends_branch"` // How many levels close after this item
+	RelatedIDs   []string `json:"related_ids"` // Nodes linked by edges other than the parent link
+	Label        string   `json:"label"`       // Spoken description, such as "Level 2, 1 of 3, 2 children"
+}
+
+// ReadingOrder is returned by GET /api/mindmaps/{id}/reading-order
+type ReadingOrder struct {
+	MindMapID string             `json:"mind_map_id"`
+	Title     string             `json:"title"`
+	Order     string             `json:"order"`
+	Items     []ReadingOrderItem `json:"items"`
+}
@@ -0,0 +1,22 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// RetentionPolicy is how long a tenant keeps its mind maps and their history. A rule left
+// nil is off, so nothing is removed or archived for it
+type RetentionPolicy struct {
+	TenantID            string     `json:"tenant_id"`
+	PurgeTrashAfterDays *int       `json:"purge_trash_after_days"` // Days deleted maps stay in the trash before they are purged
+	ArchiveAfterDays    *int       `json:"archive_after_days"`     // Days without activity after which maps are archived
+	HistoryDepth        *int       `json:"history_depth"`          // Latest change log entries kept per map
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"`   // Unset while the tenant has no policy
+}
+
+// RetentionPolicyRequest is the body of PUT /api/org/retention-policy. It replaces the
+// whole policy, so a rule left out or null is turned off
+type RetentionPolicyRequest struct {
+	PurgeTrashAfterDays *int `json:"purge_trash_after_days"`
+	ArchiveAfterDays    *int `json:"archive_after_days"`
+	HistoryDepth        *int `json:"history_depth"`
+}
@@ -0,0 +1,35 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// SavedFilter is a named smart filter query a user can run across their mind maps
+type SavedFilter struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavedFilterRequest is the body for creating or updating a saved filter
+type SavedFilterRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// FilterMapResult holds the matching nodes of one mind map
+type FilterMapResult struct {
+	MindMapID string `json:"mind_map_id"`
+	Title     string `json:"title"`
+	Nodes     []Node `json:"nodes"`
+}
+
+// FilterResult is returned when running a filter, with matches grouped by mind map
+type FilterResult struct {
+	Query     string            `json:"query"`
+	Total     int               `json:"total"`
+	Truncated bool              `json:"truncated"` // Set when more nodes matched than were returned
+	Maps      []FilterMapResult `json:"maps"`
+}
@@ -0,0 +1,131 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// SCIM schema URNs of the resources and messages the SCIM endpoints exchange
+const (
+	SCIMUserSchema          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMGroupSchema         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMListResponseSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchOpSchema       = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMServiceConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// TenantMember is a user as their tenant's identity provider manages them
+type TenantMember struct {
+	ID        string
+	Email     string
+	Name      string
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SCIMGroup is a group of a tenant's users pushed by its identity provider
+type SCIMGroup struct {
+	ID          string
+	TenantID    string
+	DisplayName string
+	ExternalID  string
+	Members     []SCIMGroupMember
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SCIMGroupMember is a user in a SCIM group
+type SCIMGroupMember struct {
+	UserID string
+	Email  string
+}
+
+// SCIMMeta is the metadata of a SCIM resource
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// SCIMName is the name of a SCIM user
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail is an email address of a SCIM user
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUser is the SCIM representation of a user, both as sent and as received. Active is
+// a pointer so a request that leaves it out keeps the user active
+type SCIMUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *SCIMName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []SCIMEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// SCIMMemberRef refers to a user in a SCIM group
+type SCIMMemberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMGroupResource is the SCIM representation of a group, both as sent and as received
+type SCIMGroupResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []SCIMMemberRef `json:"members"`
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMPatchRequest is the body of a SCIM PATCH
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is one change of a SCIM PATCH. Value is kept raw, as its shape
+// depends on the operation and path
+type SCIMPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// SCIMError is the body of a SCIM error response
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// SCIMTokenResponse is the body of POST /admin/tenants/{id}/scim-token. The token is only
+// shown once
+type SCIMTokenResponse struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,24 @@
+// Package models contains the data models for the application
+package models
+
+// MatchRange is a highlighted span of node content. Start and End are offsets in
+// UTF-16 code units, the same as JavaScript string indices, with End exclusive
+type MatchRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// NodeSearchMatch is a node whose content matches every search term
+type NodeSearchMatch struct {
+	NodeID  string       `json:"node_id"`
+	Content string       `json:"content"`
+	Ranges  []MatchRange `json:"ranges"`
+}
+
+// NodeSearchResult is returned by GET /api/mindmaps/{id}/search, with matches
+// in canvas order so clients can jump from one result to the next
+type NodeSearchResult struct {
+	Query   string            `json:"query"`
+	Terms   []string          `json:"terms"`
+	Matches []NodeSearchMatch `json:"matches"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// NodeToEmbed is a node whose content has no current embedding
+type NodeToEmbed struct {
+	ID        string
+	MindMapID string
+	Content   string
+}
+
+// NodeEmbedding is the embedding of a node's content. A nil Embedding records that the
+// content could not be embedded
+type NodeEmbedding struct {
+	NodeID    string
+	Content   string // The content that was embedded
+	Model     string
+	Embedding []float64
+}
+
+// SemanticSearchResult is a node found by semantic search, with the cosine similarity of
+// its content to the query
+type SemanticSearchResult struct {
+	NodeID       string  `json:"node_id"`
+	MindMapID    string  `json:"mind_map_id"`
+	MindMapTitle string  `json:"mind_map_title"`
+	Content      string  `json:"content"`
+	NodeType     string  `json:"node_type"`
+	Score        float64 `json:"score"`
+}
+
+// SemanticSearchResponse is the response of GET /api/search/semantic. Pending counts the
+// nodes of the user's own maps still waiting for an embedding, which search cannot find yet
+type SemanticSearchResponse struct {
+	Query   string                 `json:"query"`
+	Results []SemanticSearchResult `json:"results"`
+	Pending int                    `json:"pending"`
+}
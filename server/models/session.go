@@ -0,0 +1,62 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"time"
+)
+
+// Brainstorm session modes
+const (
+	SessionModeIdeation = "ideation"
+	SessionModeVoting   = "voting"
+)
+
+// BrainstormSession represents a timed facilitation session attached to a mind map
+type BrainstormSession struct {
+	ID           string     `json:"id"`
+	MindMapID    string     `json:"mind_map_id"`
+	CreatedBy    string     `json:"created_by"`
+	Mode         string     `json:"mode"`
+	Anonymous    bool       `json:"anonymous"`
+	Participants []string   `json:"participants"`
+	StartedAt    time.Time  `json:"started_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	StoppedAt    *time.Time `json:"stopped_at,omitempty"`
+	// AuthorshipRevealed is set once the facilitator unmasks anonymous contributions
+	AuthorshipRevealed bool       `json:"authorship_revealed"`
+	RevealedAt         *time.Time `json:"revealed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// IsActive reports whether the session has neither been stopped nor run out of time
+func (s *BrainstormSession) IsActive(now time.Time) bool {
+	if s.StoppedAt != nil {
+		return false
+	}
+	return s.EndsAt == nil || now.Before(*s.EndsAt)
+}
+
+// VotingOpen reports whether participants may currently vote during this session
+func (s *BrainstormSession) VotingOpen(now time.Time) bool {
+	return s.IsActive(now) && s.Mode == SessionModeVoting
+}
+
+// SessionStartRequest represents the data needed to start a brainstorm session
+type SessionStartRequest struct {
+	Mode            string   `json:"mode"`
+	Anonymous       bool     `json:"anonymous"`
+	Participants    []string `json:"participants"`
+	DurationMinutes int      `json:"duration_minutes"`
+}
+
+// SessionUpdateRequest represents the data that can be changed while a session is running
+type SessionUpdateRequest struct {
+	Mode      string `json:"mode"`
+	Anonymous *bool  `json:"anonymous"`
+}
+
+// SessionRevealRequest selects which session's anonymous contributions to unmask
+type SessionRevealRequest struct {
+	SessionID string `json:"session_id"`
+}
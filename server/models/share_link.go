@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// ShareLink is a link anyone can view a mind map with, without signing in. Its token is
+// only shown when the link is created
+type ShareLink struct {
+	ID           string     `json:"id"`
+	MindMapID    string     `json:"mind_map_id"`
+	CreatedBy    *string    `json:"created_by"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"has_password"`
+	ExpiresAt    *time.Time `json:"expires_at"` // Nil for a link that does not expire
+	RevokedAt    *time.Time `json:"revoked_at"` // Set once the owner has revoked the link
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ShareLinkCreateRequest is the body of POST /api/mindmaps/{id}/share-links
+type ShareLinkCreateRequest struct {
+	Password  string     `json:"password"`   // Optional; viewers must enter it to see the map
+	ExpiresAt *time.Time `json:"expires_at"` // Optional; must be in the future
+}
+
+// ShareLinkCreated is returned when a share link is created, with its token and URL
+type ShareLinkCreated struct {
+	ShareLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// ShareSessionRequest is the body of POST /api/shared/{token}/session
+type ShareSessionRequest struct {
+	Password string `json:"password"`
+}
+
+// ShareSession is a short-lived session to view a password-protected shared map with,
+// sent in the X-Share-Session header
+type ShareSession struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Share link events recorded for analytics
+const (
+	ShareEventOpen   = "open"   // The shared map was viewed
+	ShareEventExport = "export" // The shared map was exported
+)
+
+// ShareLinkReferrer counts the uses of a share link coming from one referring site
+type ShareLinkReferrer struct {
+	Referrer string `json:"referrer"` // Host of the referring page, empty for direct visits
+	Opens    int    `json:"opens"`
+	Exports  int    `json:"exports"`
+}
+
+// ShareLinkDay counts the uses of a share link on one day
+type ShareLinkDay struct {
+	Day     time.Time `json:"day"`
+	Opens   int       `json:"opens"`
+	Exports int       `json:"exports"`
+}
+
+// ShareLinkAnalytics is how a share link has been used since a time, busiest referrers
+// first and by day, oldest first. LastUsedAt covers the link's whole history
+type ShareLinkAnalytics struct {
+	ShareLink  ShareLink           `json:"share_link"`
+	Since      time.Time           `json:"since"`
+	Opens      int                 `json:"opens"`
+	Exports    int                 `json:"exports"`
+	LastUsedAt *time.Time          `json:"last_used_at"`
+	Referrers  []ShareLinkReferrer `json:"referrers"`
+	Daily      []ShareLinkDay      `json:"daily"`
+}
@@ -0,0 +1,85 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// Roles a user can hold in their tenant. Viewers cannot edit maps shared with them, even
+// when the share grants edit access
+const (
+	TenantRoleAdmin  = "admin"
+	TenantRoleMember = "member"
+	TenantRoleViewer = "viewer"
+)
+
+// ValidTenantRole reports whether role is a role a user can hold in their tenant
+func ValidTenantRole(role string) bool {
+	switch role {
+	case TenantRoleAdmin, TenantRoleMember, TenantRoleViewer:
+		return true
+	}
+	return false
+}
+
+// Protocols an SSO connection signs users in with
+const (
+	SSOProtocolOIDC = "oidc"
+	SSOProtocolSAML = "saml"
+)
+
+// SSOConnection is a tenant's OpenID Connect or SAML identity provider. Users with an
+// email in one of its domains sign in through it and are assigned to its tenant
+type SSOConnection struct {
+	ID                    string            `json:"id"`
+	TenantID              string            `json:"tenant_id"`
+	Name                  string            `json:"name"`
+	Protocol              string            `json:"protocol"`
+	Issuer                string            `json:"issuer"` // Entity ID of a SAML provider
+	ClientID              string            `json:"client_id,omitempty"`
+	EncryptedClientSecret string            `json:"-"` // Not exposed in JSON
+	IDPMetadataURL        string            `json:"idp_metadata_url,omitempty"`
+	IDPMetadata           string            `json:"-"` // SAML metadata of the provider
+	Domains               []string          `json:"domains"`
+	GroupsClaim           string            `json:"groups_claim"`
+	GroupRoles            map[string]string `json:"group_roles"`  // Tenant role by provider group
+	DefaultRole           string            `json:"default_role"` // Role of users in none of the groups
+	Enforced              bool              `json:"enforced"`     // Users of its domains may only sign in through it
+	CreatedAt             time.Time         `json:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at"`
+}
+
+// SSOConnectionCreateRequest is the body of POST /admin/sso/connections. OpenID Connect
+// connections take the issuer and client; SAML connections take the provider's metadata,
+// inline or by URL, and their issuer is read from it
+type SSOConnectionCreateRequest struct {
+	TenantID       string            `json:"tenant_id"`
+	Name           string            `json:"name"`
+	Protocol       string            `json:"protocol"` // Defaults to oidc
+	Issuer         string            `json:"issuer"`
+	ClientID       string            `json:"client_id"`
+	ClientSecret   string            `json:"client_secret"`
+	IDPMetadataURL string            `json:"idp_metadata_url"`
+	IDPMetadata    string            `json:"idp_metadata"`
+	Domains        []string          `json:"domains"`
+	GroupsClaim    string            `json:"groups_claim"`
+	GroupRoles     map[string]string `json:"group_roles"`
+	DefaultRole    string            `json:"default_role"`
+	Enforced       bool              `json:"enforced"`
+}
+
+// SSOStartRequest is the body of POST /auth/sso/start
+type SSOStartRequest struct {
+	Email string `json:"email"`
+}
+
+// SSOStartResponse tells the client where to send the user to sign in
+type SSOStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// SSOCallbackRequest is the body of POST /auth/sso/callback, carrying what the user was
+// redirected back with: the identity provider's code, or for SAML the one-time code of
+// the verified assertion
+type SSOCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
@@ -0,0 +1,16 @@
+// Package models contains the data models for the application
+package models
+
+// MindMapStatsPoint is the size of a mind map at the end of a day
+type MindMapStatsPoint struct {
+	Date      string `json:"date"` // YYYY-MM-DD, UTC
+	NodeCount int    `json:"node_count"`
+	EdgeCount int    `json:"edge_count"`
+}
+
+// MindMapStatsHistory is the growth of a mind map over time, oldest day first. The last
+// point is today's live count
+type MindMapStatsHistory struct {
+	MindMapID string              `json:"mind_map_id"`
+	Points    []MindMapStatsPoint `json:"points"`
+}
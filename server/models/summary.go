@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// NodeTypeSummary is the node type of a summary of a node's subtree
+const NodeTypeSummary = "summary"
+
+// Where a subtree summary is stored
+const (
+	SummaryTargetChild    = "child"    // As a summary node under the summarized node
+	SummaryTargetMetadata = "metadata" // Under "summary" in the summarized node's metadata
+)
+
+// NodeSummaryRequest is the optional body of POST /api/nodes/{id}/summarize
+type NodeSummaryRequest struct {
+	Target string `json:"target"` // One of the SummaryTarget values, "child" by default
+}
+
+// NodeSummary is a summary of a node's descendants
+type NodeSummary struct {
+	Summary   string    `json:"summary"`
+	NodeCount int       `json:"node_count"` // Descendants the summary covers
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NodeSummaryResult is returned when a node's subtree is summarized. With the child target,
+// Node is the summary node, created or refreshed, and Edge links a newly created one to the
+// summarized node; with the metadata target, Node is the summarized node
+type NodeSummaryResult struct {
+	NodeID  string      `json:"node_id"`
+	Target  string      `json:"target"`
+	Created bool        `json:"created"`
+	Summary NodeSummary `json:"summary"`
+	Node    *Node       `json:"node"`
+	Edge    *Edge       `json:"edge,omitempty"`
+}
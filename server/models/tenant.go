@@ -0,0 +1,23 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// Tenant is an organization whose users, mind maps and API keys are kept apart from
+// every other tenant's
+type Tenant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TenantCreateRequest is the body of POST /admin/tenants
+type TenantCreateRequest struct {
+	Name string `json:"name"`
+}
+
+// TenantMemberRequest is the body of POST /admin/tenants/{id}/users, moving a user and
+// everything they own into the tenant
+type TenantMemberRequest struct {
+	UserID string `json:"user_id"`
+}
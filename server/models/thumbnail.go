@@ -0,0 +1,13 @@
+// Package models contains the data models for the application
+package models
+
+import "time"
+
+// MindMapThumbnail is the rendered SVG preview of a mind map. ChangeSeq is the
+// change log position the preview was rendered at, so stale previews can be found
+type MindMapThumbnail struct {
+	MindMapID string    `json:"mind_map_id"`
+	SVG       string    `json:"-"`
+	ChangeSeq int64     `json:"change_seq"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
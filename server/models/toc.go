@@ -0,0 +1,15 @@
+// Package models contains the data models for the application
+package models
+
+// NodeTypeTOC is the node type of a map's table of contents branch
+const NodeTypeTOC = "toc"
+
+// TOCResult is returned when a table of contents is created or refreshed. Entries are the
+// link nodes listing the branches
+type TOCResult struct {
+	MindMapID string   `json:"mind_map_id"`
+	Created   bool     `json:"created"`
+	TOC       Node     `json:"toc"`
+	Entries   []Node   `json:"entries"`
+	Removed   []string `json:"removed"` // Entries of the previous table of contents
+}
@@ -0,0 +1,13 @@
+// Package models contains the data models for the application
+package models
+
+// TranslationResult is returned by POST /api/mindmaps/{id}/translate
+type TranslationResult struct {
+	SourceMindMapID string   `json:"source_mind_map_id"`
+	MindMap         *MindMap `json:"mind_map"` // The translated map, a copy unless in_place was set
+	Language        string   `json:"language"`
+	InPlace         bool     `json:"in_place"`
+	NodeIDs         []string `json:"node_ids"` // Nodes whose content changed
+	Translated      int      `json:"translated"`
+	Cached          int      `json:"cached"` // Texts served from the translation cache
+}
@@ -0,0 +1,32 @@
+// Package models contains the data models for the application
+package models
+
+import (
+	"time"
+)
+
+// NodeVote represents a single dot placed on a node by a user
+type NodeVote struct {
+	ID        string    `json:"id"`
+	NodeID    string    `json:"node_id"`
+	MindMapID string    `json:"mind_map_id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VoteResult represents a node's position in the ranked voting results
+type VoteResult struct {
+	Rank      int    `json:"rank"`
+	NodeID    string `json:"node_id"`
+	Content   string `json:"content"`
+	VoteCount int    `json:"vote_count"`
+}
+
+// VoteResults represents the ranked voting results for a mind map
+type VoteResults struct {
+	MindMapID      string       `json:"mind_map_id"`
+	VoteLimit      int          `json:"vote_limit"`
+	VotesUsed      int          `json:"votes_used"`
+	VotesRemaining int          `json:"votes_remaining"`
+	Results        []VoteResult `json:"results"`
+}
@@ -0,0 +1,244 @@
+package brainstorm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// GeneralCategory is the slug of the category of topics no other category matches
+const GeneralCategory = "general"
+
+// Category is a topic category of the idea bank with its curated idea starters
+type Category struct {
+	Slug     string
+	Name     string
+	Keywords []string // Words of a topic that place it in the category
+	Starters []Suggestion
+}
+
+// bank is the curated idea bank, with the general category last
+var bank = []Category{
+	{
+		Slug:     "business",
+		Name:     "Business & Startups",
+		Keywords: []string{"business", "startup", "company", "revenue", "profit", "sales", "customer", "customers", "pricing", "market", "shop", "store", "service", "client", "clients", "b2b", "saas", "entrepreneur"},
+		Starters: []Suggestion{
+			{"Subscription tier", "Offer the core service as a monthly subscription with a free entry tier."},
+			{"Niche first", "Start with one narrow customer segment and become the obvious choice for it."},
+			{"Partner channel", "Sell through a partner who already has the customers you want."},
+			{"Service to product", "Turn a repeated custom service into a packaged product with a fixed price."},
+			{"Referral loop", "Reward existing customers for every new customer they bring in."},
+			{"Unbundle", "Split the offer into smaller parts customers can buy separately."},
+			{"Pilot customer", "Run a discounted pilot with one customer in exchange for feedback and a case study."},
+			{"Cost teardown", "List every cost of delivering the offer and remove the one customers value least."},
+			{"Waitlist launch", "Open a waitlist before building to measure real demand."},
+			{"Local to online", "Take a local offer online so it can reach customers anywhere."},
+		},
+	},
+	{
+		Slug:     "product",
+		Name:     "Product & Design",
+		Keywords: []string{"product", "feature", "features", "design", "ux", "ui", "user", "users", "prototype", "onboarding", "usability", "interface", "roadmap", "mvp"},
+		Starters: []Suggestion{
+			{"Jobs to be done", "Write down the job users hire the product for and cut features that do not serve it."},
+			{"First five minutes", "Redesign the first five minutes so a new user reaches a small success."},
+			{"Paper prototype", "Sketch the flow on paper and test it with three users before building."},
+			{"Default wins", "Pick smart defaults so most users never need to open the settings."},
+			{"Remove a step", "Find the step users abandon most often and remove or automate it."},
+			{"Power user mode", "Add keyboard shortcuts or bulk actions for the most active users."},
+			{"Empty states", "Make empty screens explain what to do next with an example."},
+			{"Feedback in context", "Ask for feedback right after a user finishes a key action."},
+			{"Accessible by default", "Check contrast, keyboard navigation and screen reader labels for every screen."},
+			{"Progressive disclosure", "Show advanced options only when a user asks for them."},
+		},
+	},
+	{
+		Slug:     "marketing",
+		Name:     "Marketing & Growth",
+		Keywords: []string{"marketing", "brand", "branding", "campaign", "audience", "social", "content", "seo", "newsletter", "advertising", "ads", "growth", "launch", "promotion", "influencer"},
+		Starters: []Suggestion{
+			{"Teach, don't sell", "Publish a free guide that solves a small problem your audience has."},
+			{"Customer stories", "Share short stories of real customers and the results they got."},
+			{"Behind the scenes", "Show how the work gets made to build trust and curiosity."},
+			{"Community challenge", "Run a week-long challenge your audience can join and share."},
+			{"Collaborate", "Co-create content with a brand that serves the same audience."},
+			{"Repurpose", "Turn one long piece of content into ten short posts for different channels."},
+			{"Launch countdown", "Build anticipation with a countdown and early access for subscribers."},
+			{"Search questions", "Answer the exact questions people type into search engines."},
+			{"Free tool", "Offer a small free tool or calculator related to what you sell."},
+			{"Word of mouth", "Give customers something surprising that is worth telling friends about."},
+		},
+	},
+	{
+		Slug:     "education",
+		Name:     "Education & Learning",
+		Keywords: []string{"education", "learning", "learn", "teaching", "teach", "school", "class", "classroom", "course", "lesson", "students", "student", "study", "curriculum", "training", "workshop"},
+		Starters: []Suggestion{
+			{"Learn by teaching", "Have learners explain a concept to a peer in their own words."},
+			{"Project based", "Build the lesson around a real project with a visible result."},
+			{"Spaced review", "Revisit key ideas after a day, a week and a month."},
+			{"Flipped lesson", "Share the material before class and use class time for practice."},
+			{"Real world link", "Connect each concept to a situation from the learners' own lives."},
+			{"Quick checks", "Use short low-stakes quizzes to find gaps early."},
+			{"Choice board", "Let learners choose how they show what they have learned."},
+			{"Guest expert", "Invite someone who uses the skill at work to share their experience."},
+			{"Gamify progress", "Turn milestones into badges or levels learners can track."},
+			{"Reflection journal", "Ask learners to note what surprised them at the end of each session."},
+		},
+	},
+	{
+		Slug:     "events",
+		Name:     "Events & Community",
+		Keywords: []string{"event", "events", "party", "conference", "meetup", "community", "festival", "wedding", "celebration", "gathering", "volunteer", "volunteers", "club", "fundraiser", "charity", "neighborhood"},
+		Starters: []Suggestion{
+			{"Theme it", "Pick a simple theme that shapes the invitations, food and activities."},
+			{"Icebreaker", "Open with an activity that gets strangers talking within five minutes."},
+			{"Co-host", "Share the work and the guest list with a partner group."},
+			{"Small groups", "Split attendees into small groups so everyone gets a voice."},
+			{"Give back", "Tie the gathering to a local cause attendees can support."},
+			{"Skill swap", "Let members teach each other one thing they are good at."},
+			{"Regular rhythm", "Meet on a fixed day each month so it becomes a habit."},
+			{"Follow up", "Send a short recap with photos and the next date right after."},
+			{"Open mic", "Give attendees a few minutes each to share a project or story."},
+			{"Feedback wall", "Put up a board where attendees leave ideas for the next event."},
+		},
+	},
+	{
+		Slug:     "technology",
+		Name:     "Technology & Software",
+		Keywords: []string{"technology", "tech", "software", "app", "apps", "code", "coding", "api", "data", "ai", "automation", "website", "platform", "cloud", "security", "developer", "developers", "system"},
+		Starters: []Suggestion{
+			{"Automate the chore", "Automate the most repetitive manual task people do every week."},
+			{"Start with a script", "Solve the problem with a small script before building a full system."},
+			{"Measure first", "Add metrics to see where time or errors actually go."},
+			{"Integrate", "Connect to a tool people already use instead of replacing it."},
+			{"Offline mode", "Make the core features work without a network connection."},
+			{"Open an API", "Let others build on the platform through a simple API."},
+			{"Secure by default", "Turn on the safest settings and make users opt out, not in."},
+			{"Self-service", "Let users fix common problems themselves without contacting support."},
+			{"Smaller release", "Ship the smallest useful version and iterate on real usage."},
+			{"Data export", "Let users export their data in an open format."},
+		},
+	},
+	{
+		Slug:     "health",
+		Name:     "Health & Wellbeing",
+		Keywords: []string{"health", "fitness", "wellness", "wellbeing", "exercise", "diet", "nutrition", "sleep", "mental", "stress", "meditation", "workout", "healthy", "habit", "habits"},
+		Starters: []Suggestion{
+			{"Tiny habit", "Attach a two-minute healthy action to something done every day."},
+			{"Buddy system", "Pair up with someone to keep each other accountable."},
+			{"Track one thing", "Track a single measure for a month and look for patterns."},
+			{"Design the space", "Change the environment so the healthy choice is the easy one."},
+			{"Rest days", "Plan recovery as deliberately as effort."},
+			{"Morning routine", "Start the day with a short routine that sets the tone."},
+			{"Walk and talk", "Turn meetings or calls into walks."},
+			{"Prep ahead", "Prepare meals or gear the evening before."},
+			{"Screen curfew", "Switch off screens an hour before sleep."},
+			{"Celebrate streaks", "Mark every week the habit holds to build momentum."},
+		},
+	},
+	{
+		Slug:     "creative",
+		Name:     "Creative Projects",
+		Keywords: []string{"story", "writing", "write", "book", "novel", "art", "music", "film", "video", "podcast", "creative", "design", "photography", "game", "poem", "comic", "blog"},
+		Starters: []Suggestion{
+			{"Constraint", "Set a strict constraint, like one color or one hundred words, and work within it."},
+			{"Swap the point of view", "Tell the same story from the side of a minor character."},
+			{"Mash-up", "Combine two genres or styles that rarely meet."},
+			{"Daily sketch", "Make one small piece every day for thirty days."},
+			{"Start at the end", "Begin with the final scene or image and work backwards."},
+			{"Borrow a structure", "Reuse the structure of a work you admire with new content."},
+			{"Collaborate", "Invite another creator to respond to your piece with their own."},
+			{"Public draft", "Share an unfinished version and ask what people want more of."},
+			{"Change the medium", "Turn the idea into a different medium, like a song into a comic."},
+			{"Personal memory", "Build the piece around a specific memory only you have."},
+		},
+	},
+	{
+		Slug:     "productivity",
+		Name:     "Productivity & Planning",
+		Keywords: []string{"productivity", "plan", "planning", "goal", "goals", "time", "focus", "project", "projects", "career", "task", "tasks", "organize", "organization", "workflow", "team", "meeting", "meetings"},
+		Starters: []Suggestion{
+			{"One big thing", "Pick the single most important task each day and do it first."},
+			{"Time box", "Give each task a fixed time slot and stop when it ends."},
+			{"Weekly review", "Spend thirty minutes each week reviewing goals and the next steps."},
+			{"Batch similar work", "Group similar tasks, like email or calls, into one block."},
+			{"Define done", "Write down what finished looks like before starting."},
+			{"Say no", "Decline or delegate one recurring commitment that does not serve the goal."},
+			{"Break it down", "Split the project into steps that take less than an hour each."},
+			{"Visible board", "Track work on a board everyone can see."},
+			{"Fewer meetings", "Replace a status meeting with a short written update."},
+			{"Deadline buddy", "Share the deadline with someone who will ask about it."},
+		},
+	},
+	{
+		Slug: GeneralCategory,
+		Name: "General",
+		Starters: []Suggestion{
+			{"Ask the users", "Talk to five people affected and note the problems they mention most."},
+			{"Opposite approach", "Try doing the opposite of the usual approach and see what changes."},
+			{"Borrow from nature", "Look at how nature solves a similar problem."},
+			{"Cheapest version", "Find a version that could be done this week with no budget."},
+			{"Ten times bigger", "Imagine the idea at ten times the scale and note what must change."},
+			{"Remove the constraint", "List the biggest constraint and imagine it gone."},
+			{"Combine two ideas", "Combine the idea with an unrelated one from a different field."},
+			{"Future view", "Picture the idea five years from now and work back to today."},
+			{"Worst idea", "List the worst possible ideas, then flip each into a good one."},
+			{"Explain it simply", "Describe the idea to a child and keep the parts that survive."},
+		},
+	},
+}
+
+// Categories returns the categories of the idea bank, with the general category last
+func Categories() []Category {
+	return append([]Category{}, bank...)
+}
+
+// CategoryBySlug returns the category with the given slug, and false if there is none
+func CategoryBySlug(slug string) (Category, bool) {
+	for _, category := range bank {
+		if category.Slug == slug {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
+
+// Classify returns the category whose keywords a topic mentions most, or the general
+// category if it mentions none. Ties go to the category listed first
+func Classify(topic string) Category {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(topic), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+
+	best, bestScore := bank[len(bank)-1], 0
+	for _, category := range bank {
+		score := 0
+		for _, keyword := range category.Keywords {
+			if words[keyword] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = category, score
+		}
+	}
+	return best
+}
+
+// Pick returns count idea starters of the category for a topic. The same topic always
+// gets the same starters, while different topics start at different ones
+func (c Category) Pick(topic string, count int) []Suggestion {
+	if count <= 0 || len(c.Starters) == 0 {
+		return nil
+	}
+	offset := topicOffset(topic, len(c.Starters))
+	starters := make([]Suggestion, 0, min(count, len(c.Starters)))
+	for i := 0; i < len(c.Starters) && len(starters) < count; i++ {
+		starters = append(starters, c.Starters[(offset+i)%len(c.Starters)])
+	}
+	return starters
+}
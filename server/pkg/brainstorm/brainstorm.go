@@ -0,0 +1,163 @@
+// Package brainstorm suggests ideas for a topic without AI, from question prompts and
+// SCAMPER stems filled in with the topic and from a curated bank of idea starters by
+// category. It stands in for an AI provider that cannot be reached and serves visitors
+// without an account, so suggestions are always available offline
+package brainstorm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of suggestions, matching the types of idea generation
+const (
+	KindNew     = "new"
+	KindExpand  = "expand"
+	KindImprove = "improve"
+	KindBranch  = "branch"
+	// KindCritique plays devil's advocate, raising counterarguments, risks and weaknesses
+	KindCritique = "critique"
+)
+
+// maxTopicLength caps how much of the topic is filled into a stem
+const maxTopicLength = 80
+
+// Suggestion is an idea suggested for a topic
+type Suggestion struct {
+	Title   string
+	Content string
+}
+
+// stem is a template of a suggestion; %s is replaced with the quoted topic
+type stem struct {
+	title   string
+	content string
+}
+
+// questionStems break a topic down with the questions journalists ask
+var questionStems = []stem{
+	{"Who", "Who is %s for, and who else is affected by it?"},
+	{"Why", "Why does %s matter, and what problem does it solve?"},
+	{"What", "What are the essential parts of %s?"},
+	{"How", "How would %s work step by step?"},
+	{"When", "When is %s needed most, and what has to happen first?"},
+	{"Where", "Where would %s be used or have the most impact?"},
+	{"Risks", "What could go wrong with %s, and how could it be prevented?"},
+	{"Measure", "How would you know %s is succeeding?"},
+}
+
+// scamperStems change a topic in the ways of the SCAMPER method
+var scamperStems = []stem{
+	{"Substitute", "What part of %s could be replaced with something better or cheaper?"},
+	{"Combine", "What could %s be combined with to create something new?"},
+	{"Adapt", "What idea from another field could be adapted to %s?"},
+	{"Modify", "What could be made bigger, smaller or different about %s?"},
+	{"Put to another use", "Who else could use %s, or for what other purpose?"},
+	{"Eliminate", "What could be removed from %s to make it simpler?"},
+	{"Reverse", "What if %s were done in the opposite order or from the other side?"},
+}
+
+// critiqueStems question a topic the way a devil's advocate would
+var critiqueStems = []stem{
+	{"Counterargument", "What is the strongest argument against %s?"},
+	{"Weakest assumption", "Which assumption behind %s is most likely to be wrong?"},
+	{"Biggest risk", "What is the biggest risk of %s, and what would it cost if it happened?"},
+	{"Failure story", "Imagine %s has failed a year from now. What most likely caused it?"},
+	{"Hidden cost", "What time, money or effort does %s need that is easy to overlook?"},
+	{"Who objects", "Who would push back against %s, and why?"},
+	{"Simpler option", "Why might doing nothing, or something simpler, beat %s?"},
+	{"Missing evidence", "What evidence is missing that %s would actually work?"},
+}
+
+// Suggest returns count suggestions of the given kind for a topic. Expanding favors
+// questions that break the topic down, improving and branching favor SCAMPER, critiques
+// use devil's advocate questions only, and new ideas mix questions and SCAMPER. The same topic always gets the same suggestions, while different topics
+// start at different stems. The topic is quoted in the suggestions. Asking for more
+// suggestions than there are stems returns them all
+func Suggest(topic, kind string, count int) []Suggestion {
+	if count <= 0 {
+		return nil
+	}
+	if topic = cleanTopic(topic); topic != "" {
+		topic = `"` + topic + `"`
+	} else {
+		topic = "the idea"
+	}
+
+	var stems []stem
+	switch kind {
+	case KindExpand:
+		stems = append(rotate(questionStems, topic), rotate(scamperStems, topic)...)
+	case KindImprove:
+		stems = append(rotate(scamperStems, topic), rotate(questionStems, topic)...)
+	case KindCritique:
+		stems = rotate(critiqueStems, topic)
+	case KindBranch:
+		stems = append(rotate(scamperStems, topic), rotate(questionStems, topic)...)
+		// Branches are alternatives, so lead with the stems that change direction
+		stems = append([]stem{scamperStems[4], scamperStems[6], scamperStems[2]}, stems...)
+	default:
+		stems = interleave(rotate(questionStems, topic), rotate(scamperStems, topic))
+	}
+
+	seen := make(map[string]bool)
+	suggestions := make([]Suggestion, 0, count)
+	for _, s := range stems {
+		if len(suggestions) == count {
+			break
+		}
+		if seen[s.title] {
+			continue
+		}
+		seen[s.title] = true
+		suggestions = append(suggestions, Suggestion{
+			Title:   s.title,
+			Content: fmt.Sprintf(s.content, topic),
+		})
+	}
+	return suggestions
+}
+
+// cleanTopic collapses the white space of a topic and shortens it to maxTopicLength,
+// between words where possible
+func cleanTopic(topic string) string {
+	topic = strings.Join(strings.Fields(topic), " ")
+	if utf8.RuneCountInString(topic) <= maxTopicLength {
+		return topic
+	}
+	runes := []rune(topic)
+	cut := string(runes[:maxTopicLength])
+	if space := strings.LastIndex(cut, " "); space > maxTopicLength/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "..."
+}
+
+// rotate returns stems starting at an offset derived from the topic
+func rotate(stems []stem, topic string) []stem {
+	offset := topicOffset(topic, len(stems))
+	return append(append([]stem{}, stems[offset:]...), stems[:offset]...)
+}
+
+// topicOffset derives an offset below n from a topic, ignoring case
+func topicOffset(topic string, n int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(topic)))
+	return int(hash.Sum32() % uint32(n))
+}
+
+// interleave alternates the stems of a and b
+func interleave(a, b []stem) []stem {
+	stems := make([]stem, 0, len(a)+len(b))
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i < len(a) {
+			stems = append(stems, a[i])
+		}
+		if i < len(b) {
+			stems = append(stems, b[i])
+		}
+	}
+	return stems
+}
@@ -0,0 +1,161 @@
+package brainstorm
+
+import "fmt"
+
+// Kinds of framework suggestions, which sort ideas into the categories of a thinking
+// framework
+const (
+	KindSWOT     = "swot"
+	KindFiveWhys = "five_whys"
+	KindSixHats  = "six_hats"
+)
+
+// Framework is a thinking framework whose categories ideas are sorted into
+type Framework struct {
+	Kind       string
+	Name       string
+	Categories []FrameworkCategory
+}
+
+// FrameworkCategory is a category of a framework. Keys are unique across frameworks, so
+// an idea's category also names its framework
+type FrameworkCategory struct {
+	Key         string
+	Name        string
+	Description string // What ideas of the category are about, for prompting
+	stems       []stem
+}
+
+// frameworks are the supported thinking frameworks
+var frameworks = []Framework{
+	{
+		Kind: KindSWOT,
+		Name: "SWOT analysis",
+		Categories: []FrameworkCategory{
+			{"strengths", "Strengths", "Internal advantages the topic already has", []stem{
+				{"Core advantage", "What does %s do better than the alternatives?"},
+				{"Assets", "Which skills, resources or relationships give %s an edge?"},
+				{"Proof", "What results already show that %s works?"},
+			}},
+			{"weaknesses", "Weaknesses", "Internal limitations that hold the topic back", []stem{
+				{"Gaps", "What is %s missing that the alternatives have?"},
+				{"Bottleneck", "Which part of %s is slowest, costliest or most fragile?"},
+				{"Complaints", "What do people most often criticize about %s?"},
+			}},
+			{"opportunities", "Opportunities", "External conditions the topic could take advantage of", []stem{
+				{"Trend", "Which trend could carry %s further?"},
+				{"Unserved group", "Who is not yet served by %s but could be?"},
+				{"Partnership", "Who could %s team up with to grow faster?"},
+			}},
+			{"threats", "Threats", "External conditions that could harm the topic", []stem{
+				{"Competition", "Who could make %s unnecessary, and how?"},
+				{"Change", "What change in rules, markets or technology would hurt %s?"},
+				{"Dependency", "What does %s depend on that could disappear?"},
+			}},
+		},
+	},
+	{
+		Kind: KindFiveWhys,
+		Name: "5 Whys",
+		Categories: []FrameworkCategory{
+			{"why_1", "Why 1", "Why the problem in the topic happens", []stem{
+				{"Immediate cause", "Why does %s happen?"},
+			}},
+			{"why_2", "Why 2", "Why the cause found at Why 1 happens", []stem{
+				{"Underlying cause", "Why does the immediate cause of %s happen?"},
+			}},
+			{"why_3", "Why 3", "Why the cause found at Why 2 happens", []stem{
+				{"Deeper cause", "What process or habit lets that cause of %s go on?"},
+			}},
+			{"why_4", "Why 4", "Why the cause found at Why 3 happens", []stem{
+				{"System cause", "Why was that process or habit behind %s never changed?"},
+			}},
+			{"why_5", "Why 5", "The root cause, and what would remove it", []stem{
+				{"Root cause", "What root cause, if removed, would stop %s from coming back?"},
+			}},
+		},
+	},
+	{
+		Kind: KindSixHats,
+		Name: "Six Thinking Hats",
+		Categories: []FrameworkCategory{
+			{"white_hat", "White Hat: Facts", "Facts, figures and the information still missing", []stem{
+				{"Known facts", "What facts do we already have about %s?"},
+				{"Missing data", "What information about %s is missing, and how could we get it?"},
+			}},
+			{"red_hat", "Red Hat: Feelings", "Gut feelings, intuitions and emotional reactions", []stem{
+				{"Gut feeling", "What is your gut feeling about %s?"},
+				{"Reactions", "How will people feel when they first meet %s?"},
+			}},
+			{"black_hat", "Black Hat: Caution", "Risks, weaknesses and reasons for caution", []stem{
+				{"What could fail", "What could go wrong with %s?"},
+				{"Weak spot", "Where is %s weakest?"},
+			}},
+			{"yellow_hat", "Yellow Hat: Benefits", "Benefits, value and reasons for optimism", []stem{
+				{"Best case", "What is the best thing that could come of %s?"},
+				{"Value", "Who benefits from %s, and how?"},
+			}},
+			{"green_hat", "Green Hat: Creativity", "New possibilities, alternatives and creative ideas", []stem{
+				{"Alternative", "What is a completely different way to approach %s?"},
+				{"Provocation", "What if %s had no limits at all?"},
+			}},
+			{"blue_hat", "Blue Hat: Process", "Next steps and how to manage the thinking", []stem{
+				{"Next step", "What is the next step for %s, and who takes it?"},
+				{"Decision", "What needs to be decided about %s, and by when?"},
+			}},
+		},
+	},
+}
+
+// FrameworkByKind returns the framework of the given kind, and false if it is not one
+func FrameworkByKind(kind string) (Framework, bool) {
+	for _, framework := range frameworks {
+		if framework.Kind == kind {
+			return framework, true
+		}
+	}
+	return Framework{}, false
+}
+
+// FrameworkCategoryByKey returns the framework category with the given key and the kind
+// of its framework, and false if there is none
+func FrameworkCategoryByKey(key string) (FrameworkCategory, string, bool) {
+	for _, framework := range frameworks {
+		for _, category := range framework.Categories {
+			if category.Key == key {
+				return category, framework.Kind, true
+			}
+		}
+	}
+	return FrameworkCategory{}, "", false
+}
+
+// FrameworkSuggestion is a suggestion in a category of a framework
+type FrameworkSuggestion struct {
+	Suggestion
+	Category string // Key of the category
+}
+
+// Suggest returns up to count suggestions for a topic in each category of the framework,
+// category by category. The topic is quoted in the suggestions
+func (f Framework) Suggest(topic string, count int) []FrameworkSuggestion {
+	if topic = cleanTopic(topic); topic != "" {
+		topic = `"` + topic + `"`
+	} else {
+		topic = "the idea"
+	}
+
+	var suggestions []FrameworkSuggestion
+	for _, category := range f.Categories {
+		for i, s := range category.stems {
+			if i == count {
+				break
+			}
+			suggestions = append(suggestions, FrameworkSuggestion{
+				Suggestion: Suggestion{Title: s.title, Content: fmt.Sprintf(s.content, topic)},
+				Category:   category.Key,
+			})
+		}
+	}
+	return suggestions
+}
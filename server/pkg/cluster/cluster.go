@@ -0,0 +1,112 @@
+// Package cluster groups similar items by their pairwise similarity and labels the groups
+package cluster
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Group clusters items by average linkage: starting with every item on its own, the two
+// groups with the highest average similarity between their items are merged for as long as
+// that similarity is at least threshold. similarity is a symmetric matrix with a row per
+// item. Groups are returned in the order of their first item, with their items ascending
+func Group(similarity [][]float64, threshold float64) [][]int {
+	groups := make([][]int, len(similarity))
+	for i := range groups {
+		groups[i] = []int{i}
+	}
+
+	for len(groups) > 1 {
+		bestA, bestB, best := -1, -1, math.Inf(-1)
+		for a := range groups {
+			for b := a + 1; b < len(groups); b++ {
+				if s := average(similarity, groups[a], groups[b]); s > best {
+					bestA, bestB, best = a, b, s
+				}
+			}
+		}
+		if best < threshold {
+			break
+		}
+		groups[bestA] = append(groups[bestA], groups[bestB]...)
+		sort.Ints(groups[bestA])
+		groups = append(groups[:bestB], groups[bestB+1:]...)
+	}
+	return groups
+}
+
+// average returns the average similarity between the items of two groups
+func average(similarity [][]float64, a, b []int) float64 {
+	var sum float64
+	for _, i := range a {
+		for _, j := range b {
+			sum += similarity[i][j]
+		}
+	}
+	return sum / float64(len(a)*len(b))
+}
+
+// stopWords are common words that never make a label
+var stopWords = map[string]bool{
+	"about": true, "after": true, "also": true, "been": true, "before": true, "being": true,
+	"could": true, "does": true, "each": true, "from": true, "have": true, "into": true,
+	"just": true, "like": true, "made": true, "make": true, "more": true, "most": true,
+	"much": true, "only": true, "other": true, "over": true, "should": true, "some": true,
+	"such": true, "than": true, "that": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "those": true, "through": true,
+	"using": true, "very": true, "what": true, "when": true, "where": true, "which": true,
+	"while": true, "will": true, "with": true, "without": true, "would": true, "your": true,
+}
+
+// Label names a group of texts after the one or two words most of them share, ignoring
+// case, stop words and words shorter than four letters. Without a shared word the group
+// is named after the first words of its first text
+func Label(texts []string) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, text := range texts {
+		seen := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(word)) < 4 || stopWords[word] || seen[word] {
+				continue
+			}
+			seen[word] = true
+			if counts[word] == 0 {
+				order = append(order, word)
+			}
+			counts[word]++
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return counts[order[a]] > counts[order[b]] })
+
+	var words []string
+	for _, word := range order {
+		if counts[word] < 2 || len(words) == 2 {
+			break
+		}
+		words = append(words, capitalize(word))
+	}
+	if len(words) > 0 {
+		return strings.Join(words, " & ")
+	}
+
+	if len(texts) == 0 {
+		return ""
+	}
+	fields := strings.Fields(texts[0])
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+	return strings.Join(fields, " ")
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	runes := []rune(word)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,63 @@
+package document
+
+import "strings"
+
+// Chunk splits text into chunks of at most size bytes for embedding, packing whole
+// paragraphs together where they fit. Paragraphs longer than size are split between
+// words, and words longer than size are cut. Whitespace within paragraphs is collapsed
+func Chunk(text string, size int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			continue
+		}
+		paragraph = strings.Join(words, " ")
+		if current.Len() > 0 && current.Len()+2+len(paragraph) > size {
+			flush()
+		}
+		if len(paragraph) <= size {
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+			current.WriteString(paragraph)
+			continue
+		}
+
+		// Split a long paragraph between words
+		for _, word := range words {
+			for len(word) > size {
+				flush()
+				cut := size
+				for cut > 0 && !isRuneStart(word[cut]) {
+					cut--
+				}
+				chunks = append(chunks, word[:cut])
+				word = word[cut:]
+			}
+			if current.Len() > 0 && current.Len()+1+len(word) > size {
+				flush()
+			}
+			if current.Len() > 0 {
+				current.WriteString(" ")
+			}
+			current.WriteString(word)
+		}
+		flush()
+	}
+	flush()
+	return chunks
+}
+
+// isRuneStart reports whether b starts a UTF-8 encoded rune, so text is never cut within one
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
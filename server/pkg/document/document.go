@@ -0,0 +1,62 @@
+// Package document extracts the text of uploaded documents and splits it into chunks for
+// retrieval
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document formats that text can be extracted from
+const (
+	FormatText = "txt"
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+)
+
+// ErrNoText is returned when a document has no text that can be extracted, such as a
+// scanned PDF
+var ErrNoText = errors.New("no text found in document")
+
+// FormatOf returns the format of a document from its file name, and false if the format is
+// not supported. Markdown counts as text
+func FormatOf(filename string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".md", ".markdown":
+		return FormatText, true
+	case ".pdf":
+		return FormatPDF, true
+	case ".docx":
+		return FormatDOCX, true
+	}
+	return "", false
+}
+
+// Extract returns the text of a document in the given format, with paragraphs separated
+// by blank lines
+func Extract(format string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch format {
+	case FormatText:
+		text = strings.ToValidUTF8(string(bytes.TrimPrefix(data, []byte("\ufeff"))), "")
+	case FormatPDF:
+		text, err = extractPDF(data)
+	case FormatDOCX:
+		text, err = extractDOCX(data)
+	default:
+		return "", fmt.Errorf("unsupported document format %q", format)
+	}
+	if err != nil {
+		return "", err
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.TrimSpace(text) == "" || !utf8.ValidString(text) {
+		return "", ErrNoText
+	}
+	return text, nil
+}
@@ -0,0 +1,74 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDOCXXMLBytes caps the uncompressed size of a DOCX body, so a zip bomb cannot exhaust
+// memory
+const maxDOCXXMLBytes = 50 << 20
+
+// extractDOCX returns the text of the body of a Word document: the runs of each paragraph,
+// with tabs and line breaks kept
+func extractDOCX(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX file: %v", err)
+	}
+	var body *zip.File
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			body = file
+			break
+		}
+	}
+	if body == nil {
+		return "", fmt.Errorf("invalid DOCX file: no document body")
+	}
+	reader, err := body.Open()
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX file: %v", err)
+	}
+	defer reader.Close()
+
+	var text strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(reader, maxDOCXXMLBytes))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid DOCX file: %v", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(token)
+			}
+		}
+	}
+	return text.String(), nil
+}
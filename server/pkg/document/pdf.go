@@ -0,0 +1,367 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFStreamBytes caps the inflated size of a PDF stream, so a zip bomb cannot exhaust
+// memory
+const maxPDFStreamBytes = 20 << 20
+
+// pdfSkippedStreams mark the dictionaries of streams that hold no page text: images,
+// fonts, color profiles, metadata and cross-reference or object streams
+var pdfSkippedStreams = [][]byte{
+	[]byte("/Image"), []byte("/FontFile"), []byte("/Length1"), []byte("/CMap"), []byte("/Alternate"),
+	[]byte("/Metadata"), []byte("/XRef"), []byte("/ObjStm"),
+}
+
+// extractPDF returns the text shown by the content streams of a PDF, a paragraph per
+// stream. Streams must be uncompressed or Flate compressed. Only text in fonts with a
+// standard single-byte or UTF-16 encoding reads back as written; scanned pages have none
+func extractPDF(data []byte) (string, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return "", fmt.Errorf("invalid PDF file")
+	}
+
+	var text strings.Builder
+	rest := data
+	for {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		if start >= 3 && string(rest[start-3:start]) == "end" {
+			rest = rest[start+len("stream"):]
+			continue
+		}
+		dict := rest[:start]
+		if obj := bytes.LastIndex(dict, []byte("obj")); obj >= 0 {
+			dict = dict[obj:]
+		}
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := body[:end]
+		rest = body[end+len("endstream"):]
+
+		if skipPDFStream(dict) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			inflated, err := inflatePDFStream(stream)
+			if err != nil {
+				continue
+			}
+			stream = inflated
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		if shown := pdfContentText(stream); strings.TrimSpace(shown) != "" {
+			text.WriteString(shown)
+			text.WriteString("\n\n")
+		}
+	}
+	return text.String(), nil
+}
+
+// skipPDFStream reports whether a stream dictionary marks a stream without page text
+func skipPDFStream(dict []byte) bool {
+	for _, marker := range pdfSkippedStreams {
+		if bytes.Contains(dict, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// inflatePDFStream decompresses a Flate compressed stream, keeping what could be read of
+// a truncated one
+func inflatePDFStream(stream []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxPDFStreamBytes))
+	if err != nil && len(inflated) == 0 {
+		return nil, err
+	}
+	return inflated, nil
+}
+
+// pdfOperand is an operand of a content stream operator: a string, a number or an array
+type pdfOperand struct {
+	text     string
+	isText   bool
+	number   float64
+	isNumber bool
+	array    []pdfOperand
+}
+
+// pdfContentText returns the text a content stream shows, starting a new line where the
+// text moves down the page
+func pdfContentText(content []byte) string {
+	var text strings.Builder
+	var operands []pdfOperand
+	var arrays [][]pdfOperand
+	lineY, haveLine := 0.0, false
+	push := func(operand pdfOperand) {
+		if len(arrays) > 0 {
+			arrays[len(arrays)-1] = append(arrays[len(arrays)-1], operand)
+		} else {
+			operands = append(operands, operand)
+		}
+	}
+	newLine := func() {
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteString("\n")
+		}
+	}
+	space := func() {
+		if s := text.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			text.WriteString(" ")
+		}
+	}
+	moveTo := func(y float64) {
+		if haveLine && y != lineY {
+			newLine()
+		} else {
+			space()
+		}
+		lineY, haveLine = y, true
+	}
+	number := func(fromEnd int) float64 {
+		if i := len(operands) - fromEnd; i >= 0 && operands[i].isNumber {
+			return operands[i].number
+		}
+		return 0
+	}
+	lastText := func() (string, bool) {
+		if len(operands) > 0 && operands[len(operands)-1].isText {
+			return operands[len(operands)-1].text, true
+		}
+		return "", false
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			value, next := pdfLiteralString(content, i)
+			push(pdfOperand{text: decodePDFText(value), isText: true})
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			value, next := pdfHexString(content, i)
+			push(pdfOperand{text: decodePDFText(value), isText: true})
+			i = next
+		case c == '[':
+			arrays = append(arrays, nil)
+			i++
+		case c == ']':
+			if len(arrays) > 0 {
+				array := arrays[len(arrays)-1]
+				arrays = arrays[:len(arrays)-1]
+				push(pdfOperand{array: array})
+			}
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			push(pdfOperand{})
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			word := string(content[start:i])
+			if value, err := strconv.ParseFloat(word, 64); err == nil {
+				push(pdfOperand{number: value, isNumber: true})
+				continue
+			}
+
+			switch word {
+			case "Tj":
+				if shown, ok := lastText(); ok {
+					text.WriteString(shown)
+				}
+			case "'", "\"":
+				newLine()
+				if shown, ok := lastText(); ok {
+					text.WriteString(shown)
+				}
+			case "TJ":
+				if len(operands) > 0 {
+					for _, item := range operands[len(operands)-1].array {
+						if item.isText {
+							text.WriteString(item.text)
+						} else if item.isNumber && item.number < -250 {
+							space()
+						}
+					}
+				}
+			case "Td", "TD":
+				if dy := number(1); dy != 0 {
+					newLine()
+				} else {
+					space()
+				}
+			case "Tm":
+				moveTo(number(1))
+			case "T*", "ET":
+				newLine()
+			case "BI":
+				// Skip inline image data up to its end marker
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + len("EI")
+				} else {
+					i = len(content)
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return text.String()
+}
+
+// pdfLiteralString reads the literal string starting with the parenthesis at start,
+// returning its bytes and the index after it
+func pdfLiteralString(content []byte, start int) ([]byte, int) {
+	var value []byte
+	depth := 0
+	i := start
+	for i < len(content) {
+		c := content[i]
+		i++
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return value, i
+			}
+		case '\\':
+			if i >= len(content) {
+				return value, i
+			}
+			escaped := content[i]
+			i++
+			switch escaped {
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'b':
+				value = append(value, '\b')
+			case 'f':
+				value = append(value, '\f')
+			case '\r':
+				if i < len(content) && content[i] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if escaped >= '0' && escaped <= '7' {
+					code := int(escaped - '0')
+					for digits := 1; digits < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; digits++ {
+						code = code*8 + int(content[i]-'0')
+						i++
+					}
+					value = append(value, byte(code))
+				} else {
+					value = append(value, escaped)
+				}
+			}
+			continue
+		}
+		value = append(value, c)
+	}
+	return value, i
+}
+
+// pdfHexString reads the hex string starting with the angle bracket at start, returning
+// its bytes and the index after it
+func pdfHexString(content []byte, start int) ([]byte, int) {
+	var digits []byte
+	i := start + 1
+	for i < len(content) && content[i] != '>' {
+		if isHexDigit(content[i]) {
+			digits = append(digits, content[i])
+		}
+		i++
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	value := make([]byte, len(digits)/2)
+	for j := range value {
+		n, _ := strconv.ParseUint(string(digits[2*j:2*j+2]), 16, 8)
+		value[j] = byte(n)
+	}
+	return value, i + 1
+}
+
+// decodePDFText turns the bytes of a shown string into text: UTF-16 when it starts with a
+// byte order mark, and otherwise one character per byte, dropping control characters
+func decodePDFText(value []byte) string {
+	if len(value) >= 2 && value[0] == 0xFE && value[1] == 0xFF {
+		units := make([]uint16, 0, len(value)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			units = append(units, uint16(value[i])<<8|uint16(value[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var text strings.Builder
+	for _, b := range value {
+		if b >= 0x20 && b != 0x7F {
+			text.WriteRune(rune(b))
+		}
+	}
+	return text.String()
+}
+
+// isPDFSpace reports whether c is PDF white space
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether c is a PDF delimiter
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,293 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"saas-server/models"
+	"saas-server/pkg/graphclone"
+	"saas-server/pkg/validation"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentVersion is the current version of the JSON export format. Bump it whenever
+// the format changes in a way older importers cannot read
+const DocumentVersion = 1
+
+// Document converts a mind map into its JSON export document
+func Document(mindMap *models.MindMapWithDetails) models.MindMapDocument {
+	doc := models.MindMapDocument{
+		FormatVersion: DocumentVersion,
+		ExportedAt:    time.Now().UTC(),
+		MindMap: models.DocumentMindMap{
+			ID:          mindMap.ID,
+			Title:       mindMap.Title,
+			Description: mindMap.Description,
+			Icon:        mindMap.Icon,
+			CoverImage:  mindMap.CoverImage,
+			VoteLimit:   mindMap.VoteLimit,
+		},
+		Nodes: make([]models.DocumentNode, 0, len(mindMap.Nodes)),
+		Edges: make([]models.DocumentEdge, 0, len(mindMap.Edges)),
+	}
+
+	for _, node := range mindMap.Nodes {
+		doc.Nodes = append(doc.Nodes, models.DocumentNode{
+			ID:        node.ID,
+			ParentID:  node.ParentID,
+			Content:   node.Content,
+			PositionX: node.PositionX,
+			PositionY: node.PositionY,
+			NodeType:  node.NodeType,
+			StyleData: jsonObject(node.StyleData),
+			Metadata:  jsonObject(node.Metadata),
+			Pinned:    node.Pinned,
+			Icon:      node.Icon,
+			Priority:  node.Priority,
+			CreatedAt: node.CreatedAt.UTC(),
+			UpdatedAt: node.UpdatedAt.UTC(),
+		})
+	}
+	for _, edge := range mindMap.Edges {
+		doc.Edges = append(doc.Edges, models.DocumentEdge{
+			ID:        edge.ID,
+			SourceID:  edge.SourceID,
+			TargetID:  edge.TargetID,
+			EdgeType:  edge.EdgeType,
+			StyleData: jsonObject(edge.StyleData),
+			CreatedAt: edge.CreatedAt.UTC(),
+		})
+	}
+
+	return doc
+}
+
+// ValidateDocument checks that a document can be imported: a known format version,
+// unique node IDs, parents and edge ends that exist in the document, and no parent cycles
+func ValidateDocument(doc models.MindMapDocument) error {
+	if doc.FormatVersion < 1 || doc.FormatVersion > DocumentVersion {
+		return fmt.Errorf("unsupported format version %d, expected 1 to %d", doc.FormatVersion, DocumentVersion)
+	}
+	if doc.MindMap.Title == "" {
+		return fmt.Errorf("mind map title is required")
+	}
+
+	parents := make(map[string]*string, len(doc.Nodes))
+	for i, node := range doc.Nodes {
+		if node.ID == "" {
+			return fmt.Errorf("node %d has no id", i)
+		}
+		if _, exists := parents[node.ID]; exists {
+			return fmt.Errorf("node id %s is used more than once", node.ID)
+		}
+		if !models.ValidNodePriority(node.Priority) {
+			return fmt.Errorf("node %s has invalid priority %q", node.ID, node.Priority)
+		}
+		if node.Icon != "" && validation.ValidateEmoji(node.Icon) != nil {
+			return fmt.Errorf("node %s icon must be a single emoji", node.ID)
+		}
+		for _, data := range []json.RawMessage{node.StyleData, node.Metadata} {
+			if len(data) > 0 && !json.Valid(data) {
+				return fmt.Errorf("node %s has invalid style data or metadata", node.ID)
+			}
+		}
+		parents[node.ID] = node.ParentID
+	}
+
+	for id, parentID := range parents {
+		if parentID == nil {
+			continue
+		}
+		if _, ok := parents[*parentID]; !ok {
+			return fmt.Errorf("node %s has unknown parent %s", id, *parentID)
+		}
+		// Walk up the parent chain; a chain longer than the node count must loop
+		current := parentID
+		for steps := 0; current != nil; steps++ {
+			if steps > len(parents) {
+				return fmt.Errorf("node %s is part of a parent cycle", id)
+			}
+			current = parents[*current]
+		}
+	}
+
+	seenEdges := make(map[[2]string]bool, len(doc.Edges))
+	for i, edge := range doc.Edges {
+		if _, ok := parents[edge.SourceID]; !ok {
+			return fmt.Errorf("edge %d has unknown source %s", i, edge.SourceID)
+		}
+		if _, ok := parents[edge.TargetID]; !ok {
+			return fmt.Errorf("edge %d has unknown target %s", i, edge.TargetID)
+		}
+		key := [2]string{edge.SourceID, edge.TargetID}
+		if seenEdges[key] {
+			return fmt.Errorf("edge %d duplicates the connection from %s to %s", i, edge.SourceID, edge.TargetID)
+		}
+		seenEdges[key] = true
+		if len(edge.StyleData) > 0 && !json.Valid(edge.StyleData) {
+			return fmt.Errorf("edge %d has invalid style data", i)
+		}
+	}
+
+	return nil
+}
+
+// ImportDocument returns a validated document as it is imported into the map targetMapID.
+// Nodes and edges get new IDs, and references to the document's nodes and map in parents,
+// edges, links and metadata are pointed at the import. Nodes are ordered parents first, so
+// each can be written with its parent set. Also returns the new ID of every node by its ID
+// in the document
+func ImportDocument(doc models.MindMapDocument, targetMapID string) (models.MindMapDocument, map[string]string) {
+	nodeIDs := make([]string, len(doc.Nodes))
+	for i, node := range doc.Nodes {
+		nodeIDs[i] = node.ID
+	}
+	remap := graphclone.ForMap(doc.MindMap.ID, targetMapID, nodeIDs)
+
+	imported := doc
+	imported.MindMap.ID = targetMapID
+	imported.Nodes = make([]models.DocumentNode, 0, len(doc.Nodes))
+	for _, node := range parentsFirst(doc.Nodes) {
+		node.ID, _ = remap.NodeID(node.ID)
+		node.ParentID = remap.ParentID(node.ParentID)
+		node.Content = remap.Content(node.Content)
+		node.StyleData = remap.Metadata(node.StyleData)
+		node.Metadata = remap.Metadata(node.Metadata)
+		imported.Nodes = append(imported.Nodes, node)
+	}
+	imported.Edges = make([]models.DocumentEdge, 0, len(doc.Edges))
+	for _, edge := range doc.Edges {
+		sourceID, targetID, ok := remap.Edge(edge.SourceID, edge.TargetID)
+		if !ok {
+			continue
+		}
+		edge.ID = uuid.New().String()
+		edge.SourceID, edge.TargetID = sourceID, targetID
+		imported.Edges = append(imported.Edges, edge)
+	}
+	return imported, remap.IDs()
+}
+
+// parentsFirst orders document nodes so every parent comes before its children, keeping the
+// document order otherwise. Documents are validated to have no parent cycles
+func parentsFirst(nodes []models.DocumentNode) []models.DocumentNode {
+	byID := make(map[string]models.DocumentNode, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	ordered := make([]models.DocumentNode, 0, len(nodes))
+	placed := make(map[string]bool, len(nodes))
+	var place func(node models.DocumentNode)
+	place = func(node models.DocumentNode) {
+		if placed[node.ID] {
+			return
+		}
+		placed[node.ID] = true
+		if node.ParentID != nil {
+			if parent, ok := byID[*node.ParentID]; ok {
+				place(parent)
+			}
+		}
+		ordered = append(ordered, node)
+	}
+	for _, node := range nodes {
+		place(node)
+	}
+	return ordered
+}
+
+// CompareDocuments lists the differences between an original document and the export of the
+// map imported from it. idMap maps the original node IDs to the IDs assigned on import.
+// References to the document's nodes and map are expected to point at the import
+func CompareDocuments(original, imported models.MindMapDocument, idMap map[string]string) []string {
+	var diffs []string
+	settings := original.MindMap
+	settings.ID = imported.MindMap.ID
+	if settings != imported.MindMap {
+		diffs = append(diffs, "mind map settings differ")
+	}
+	remap := graphclone.WithIDs(original.MindMap.ID, imported.MindMap.ID, idMap)
+
+	importedNodes := make(map[string]models.DocumentNode, len(imported.Nodes))
+	for _, node := range imported.Nodes {
+		importedNodes[node.ID] = node
+	}
+	if len(original.Nodes) != len(imported.Nodes) {
+		diffs = append(diffs, fmt.Sprintf("expected %d nodes, found %d", len(original.Nodes), len(imported.Nodes)))
+	}
+	for _, node := range original.Nodes {
+		copied, ok := importedNodes[idMap[node.ID]]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("node %s is missing", node.ID))
+			continue
+		}
+		var parentID *string
+		if node.ParentID != nil {
+			mapped := idMap[*node.ParentID]
+			parentID = &mapped
+		}
+		switch {
+		case !reflect.DeepEqual(parentID, copied.ParentID):
+			diffs = append(diffs, fmt.Sprintf("node %s has a different parent", node.ID))
+		case remap.Content(node.Content) != copied.Content:
+			diffs = append(diffs, fmt.Sprintf("node %s has different content", node.ID))
+		case node.PositionX != copied.PositionX || node.PositionY != copied.PositionY:
+			diffs = append(diffs, fmt.Sprintf("node %s has a different position", node.ID))
+		case node.NodeType != copied.NodeType:
+			diffs = append(diffs, fmt.Sprintf("node %s has a different type", node.ID))
+		case !jsonEqual(remap.Metadata(node.StyleData), copied.StyleData):
+			diffs = append(diffs, fmt.Sprintf("node %s has different style data", node.ID))
+		case !jsonEqual(remap.Metadata(node.Metadata), copied.Metadata):
+			diffs = append(diffs, fmt.Sprintf("node %s has different metadata", node.ID))
+		case !sameTime(node.CreatedAt, copied.CreatedAt) || !sameTime(node.UpdatedAt, copied.UpdatedAt):
+			diffs = append(diffs, fmt.Sprintf("node %s has different timestamps", node.ID))
+		}
+	}
+
+	importedEdges := make(map[[2]string]models.DocumentEdge, len(imported.Edges))
+	for _, edge := range imported.Edges {
+		importedEdges[[2]string{edge.SourceID, edge.TargetID}] = edge
+	}
+	if len(original.Edges) != len(imported.Edges) {
+		diffs = append(diffs, fmt.Sprintf("expected %d edges, found %d", len(original.Edges), len(imported.Edges)))
+	}
+	for _, edge := range original.Edges {
+		copied, ok := importedEdges[[2]string{idMap[edge.SourceID], idMap[edge.TargetID]}]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("edge from %s to %s is missing", edge.SourceID, edge.TargetID))
+		case edge.EdgeType != copied.EdgeType || !jsonEqual(edge.StyleData, copied.StyleData):
+			diffs = append(diffs, fmt.Sprintf("edge from %s to %s differs", edge.SourceID, edge.TargetID))
+		}
+	}
+
+	return diffs
+}
+
+// jsonObject returns data, or an empty object for missing or null data, so exports
+// always carry an object that imports back unchanged
+func jsonObject(data json.RawMessage) json.RawMessage {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return json.RawMessage("{}")
+	}
+	return data
+}
+
+// jsonEqual compares two JSON values by meaning, ignoring key order and whitespace
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(jsonObject(a), &va) != nil || json.Unmarshal(jsonObject(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// sameTime compares timestamps at the microsecond precision the database stores
+func sameTime(a, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
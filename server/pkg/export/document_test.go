@@ -0,0 +1,294 @@
+package export
+
+import (
+	"encoding/json"
+	"saas-server/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// IDs of the exported map and its nodes
+const (
+	exportedMapID = "11111111-1111-4111-8111-111111111111"
+	importMapID   = "22222222-2222-4222-8222-222222222222"
+	rootID        = "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"
+	branchID      = "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"
+	noteID        = "cccccccc-cccc-4ccc-8ccc-cccccccccccc"
+	linkID        = "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+)
+
+// fidelityMap returns a map using what an export has to carry: styles, metadata with tags,
+// collapsed state, parents listed after their children, a link node pointing into the map,
+// links in content and edges with and without style data
+func fidelityMap() *models.MindMapWithDetails {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC)
+	updated := created.Add(36 * time.Hour)
+	branch, root := branchID, rootID
+	return &models.MindMapWithDetails{
+		MindMap: models.MindMap{
+			ID:          exportedMapID,
+			Title:       "Launch plan",
+			Description: "Everything for the spring launch",
+			Icon:        "🚀",
+			CoverImage:  "https://example.com/cover.png",
+			VoteLimit:   3,
+		},
+		Nodes: []models.Node{
+			{
+				ID:        noteID,
+				ParentID:  &branch,
+				Content:   "Depends on [the launch](/mindmaps/" + exportedMapID + "?node=" + rootID + ") and [the map](/mindmaps/" + exportedMapID + ")",
+				PositionX: 420.5,
+				PositionY: -80.25,
+				NodeType:  "default",
+				StyleData: json.RawMessage(`{"color": "#ffcc00", "collapsed": false}`),
+				Metadata:  json.RawMessage(`{"tags": ["risk", "legal"], "votes": {"up": 2}, "related": [{"node": "` + branchID + `"}]}`),
+				Icon:      "⚠️",
+				Priority:  models.NodePriorityHigh,
+				CreatedAt: created.Add(2 * time.Minute),
+				UpdatedAt: updated,
+			},
+			{
+				ID:        rootID,
+				Content:   "Launch",
+				NodeType:  "root",
+				StyleData: json.RawMessage(`{"shape": "ellipse", "font": {"size": 18, "bold": true}}`),
+				Pinned:    true,
+				CreatedAt: created,
+				UpdatedAt: updated,
+			},
+			{
+				ID:        branchID,
+				ParentID:  &root,
+				Content:   "Marketing",
+				PositionX: 250,
+				PositionY: 40,
+				NodeType:  "default",
+				StyleData: json.RawMessage(`{"collapsed": true, "width": 180}`),
+				Metadata:  json.RawMessage(`{"tags": ["q2"]}`),
+				CreatedAt: created.Add(time.Minute),
+				UpdatedAt: updated,
+			},
+			{
+				ID:        linkID,
+				ParentID:  &branch,
+				Content:   "Legal review",
+				PositionX: 420,
+				PositionY: 120,
+				NodeType:  "link",
+				StyleData: json.RawMessage(`null`),
+				Metadata:  json.RawMessage(`{"target_mind_map_id": "` + exportedMapID + `", "target_node_id": "` + noteID + `", "url": "https://app.example.com/mindmaps/` + exportedMapID + `#` + noteID + `"}`),
+				CreatedAt: created.Add(3 * time.Minute),
+				UpdatedAt: updated,
+			},
+		},
+		Edges: []models.Edge{
+			{ID: "e1", SourceID: rootID, TargetID: branchID, EdgeType: "default", CreatedAt: created},
+			{ID: "e2", SourceID: branchID, TargetID: noteID, EdgeType: "default", StyleData: json.RawMessage(`{}`), CreatedAt: created},
+			{ID: "e3", SourceID: branchID, TargetID: linkID, EdgeType: "default", StyleData: json.RawMessage(`null`), CreatedAt: created},
+			{ID: "e4", SourceID: linkID, TargetID: rootID, EdgeType: "cross", StyleData: json.RawMessage(`{"dashed": true, "label": "blocks"}`), CreatedAt: created.Add(time.Hour)},
+		},
+	}
+}
+
+// roundTrip exports a map, sends the document through JSON as a client would, imports it
+// and returns the document, the map as the database would read it back and the new node IDs
+func roundTrip(t *testing.T, mindMap *models.MindMapWithDetails) (models.MindMapDocument, *models.MindMapWithDetails, map[string]string) {
+	t.Helper()
+	body, err := json.Marshal(Document(mindMap))
+	if err != nil {
+		t.Fatalf("encoding document: %v", err)
+	}
+	var doc models.MindMapDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("decoding document: %v", err)
+	}
+	if err := ValidateDocument(doc); err != nil {
+		t.Fatalf("ValidateDocument() = %v", err)
+	}
+
+	imported, idMap := ImportDocument(doc, importMapID)
+	return doc, stored(imported), idMap
+}
+
+// stored returns an imported document as the database reads it back: timestamps at
+// microsecond precision and JSON reformatted the way JSONB does
+func stored(doc models.MindMapDocument) *models.MindMapWithDetails {
+	mindMap := &models.MindMapWithDetails{
+		MindMap: models.MindMap{
+			ID:          doc.MindMap.ID,
+			Title:       doc.MindMap.Title,
+			Description: doc.MindMap.Description,
+			Icon:        doc.MindMap.Icon,
+			CoverImage:  doc.MindMap.CoverImage,
+			VoteLimit:   doc.MindMap.VoteLimit,
+		},
+	}
+	for _, node := range doc.Nodes {
+		mindMap.Nodes = append(mindMap.Nodes, models.Node{
+			ID:        node.ID,
+			MindMapID: doc.MindMap.ID,
+			ParentID:  node.ParentID,
+			Content:   node.Content,
+			PositionX: node.PositionX,
+			PositionY: node.PositionY,
+			NodeType:  node.NodeType,
+			StyleData: reformatJSON(node.StyleData),
+			Metadata:  reformatJSON(node.Metadata),
+			Pinned:    node.Pinned,
+			Icon:      node.Icon,
+			Priority:  node.Priority,
+			CreatedAt: node.CreatedAt.Truncate(time.Microsecond),
+			UpdatedAt: node.UpdatedAt.Truncate(time.Microsecond),
+		})
+	}
+	for _, edge := range doc.Edges {
+		mindMap.Edges = append(mindMap.Edges, models.Edge{
+			ID:        edge.ID,
+			MindMapID: doc.MindMap.ID,
+			SourceID:  edge.SourceID,
+			TargetID:  edge.TargetID,
+			EdgeType:  edge.EdgeType,
+			StyleData: reformatJSON(edge.StyleData),
+			CreatedAt: edge.CreatedAt.Truncate(time.Microsecond),
+		})
+	}
+	return mindMap
+}
+
+// reformatJSON re-encodes JSON data with sorted keys and no spacing, storing an empty
+// object for missing data as the import does
+func reformatJSON(data json.RawMessage) json.RawMessage {
+	var value interface{}
+	if json.Unmarshal(jsonObject(data), &value) != nil {
+		return data
+	}
+	formatted, _ := json.Marshal(value)
+	return formatted
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, imported, idMap := roundTrip(t, fidelityMap())
+	if diffs := CompareDocuments(doc, Document(imported), idMap); len(diffs) != 0 {
+		t.Fatalf("CompareDocuments() = %q, want no differences", diffs)
+	}
+
+	// The import must be a copy, sharing no IDs with the original
+	for _, node := range imported.Nodes {
+		for _, text := range []string{node.ID, node.Content, string(node.Metadata), string(node.StyleData)} {
+			for _, old := range []string{exportedMapID, rootID, branchID, noteID, linkID} {
+				if strings.Contains(text, old) {
+					t.Errorf("imported node %s still refers to %s in %q", node.ID, old, text)
+				}
+			}
+		}
+	}
+	for i, node := range imported.Nodes {
+		if node.ParentID == nil {
+			continue
+		}
+		placed := false
+		for _, parent := range imported.Nodes[:i] {
+			placed = placed || parent.ID == *node.ParentID
+		}
+		if !placed {
+			t.Errorf("imported node %s comes before its parent %s", node.ID, *node.ParentID)
+		}
+	}
+}
+
+func TestDocumentRoundTripDifferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *models.MindMapWithDetails, idMap map[string]string)
+		want   string
+	}{
+		{
+			"settings",
+			func(m *models.MindMapWithDetails, _ map[string]string) { m.VoteLimit = 5 },
+			"mind map settings differ",
+		},
+		{
+			"parent",
+			func(m *models.MindMapWithDetails, idMap map[string]string) {
+				root := idMap[rootID]
+				nodeByID(m, idMap[noteID]).ParentID = &root
+			},
+			"node " + noteID + " has a different parent",
+		},
+		{
+			"link in content left pointing at the original",
+			func(m *models.MindMapWithDetails, idMap map[string]string) {
+				node := nodeByID(m, idMap[noteID])
+				node.Content = strings.Replace(node.Content, idMap[rootID], rootID, 1)
+			},
+			"node " + noteID + " has different content",
+		},
+		{
+			"collapsed state",
+			func(m *models.MindMapWithDetails, idMap map[string]string) {
+				nodeByID(m, idMap[branchID]).StyleData = json.RawMessage(`{"collapsed":false,"width":180}`)
+			},
+			"node " + branchID + " has different style data",
+		},
+		{
+			"tags",
+			func(m *models.MindMapWithDetails, idMap map[string]string) {
+				nodeByID(m, idMap[branchID]).Metadata = json.RawMessage(`{"tags":["q3"]}`)
+			},
+			"node " + branchID + " has different metadata",
+		},
+		{
+			"link target left pointing at the original",
+			func(m *models.MindMapWithDetails, idMap map[string]string) {
+				node := nodeByID(m, idMap[linkID])
+				node.Metadata = json.RawMessage(strings.Replace(string(node.Metadata), idMap[noteID], noteID, 1))
+			},
+			"node " + linkID + " has different metadata",
+		},
+		{
+			"timestamps",
+			func(m *models.MindMapWithDetails, idMap map[string]string) {
+				nodeByID(m, idMap[rootID]).UpdatedAt = time.Now()
+			},
+			"node " + rootID + " has different timestamps",
+		},
+		{
+			"missing edge",
+			func(m *models.MindMapWithDetails, _ map[string]string) { m.Edges = m.Edges[:len(m.Edges)-1] },
+			"edge from " + linkID + " to " + rootID + " is missing",
+		},
+		{
+			"edge style",
+			func(m *models.MindMapWithDetails, _ map[string]string) {
+				m.Edges[len(m.Edges)-1].StyleData = json.RawMessage(`{"dashed":false,"label":"blocks"}`)
+			},
+			"edge from " + linkID + " to " + rootID + " differs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, imported, idMap := roundTrip(t, fidelityMap())
+			tt.change(imported, idMap)
+			diffs := CompareDocuments(doc, Document(imported), idMap)
+			found := false
+			for _, diff := range diffs {
+				found = found || diff == tt.want
+			}
+			if !found {
+				t.Errorf("CompareDocuments() = %q, want it to report %q", diffs, tt.want)
+			}
+		})
+	}
+}
+
+// nodeByID returns the node of a map with the given ID
+func nodeByID(m *models.MindMapWithDetails, id string) *models.Node {
+	for i := range m.Nodes {
+		if m.Nodes[i].ID == id {
+			return &m.Nodes[i]
+		}
+	}
+	panic("no node " + id)
+}
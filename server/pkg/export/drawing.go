@@ -0,0 +1,291 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"saas-server/models"
+	"saas-server/pkg/layout"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Drawing exports
+const (
+	FormatSVG = "svg"
+	FormatPDF = "pdf"
+)
+
+// Drawing margins and node labels, in canvas pixels
+const (
+	drawingMargin   = 40
+	labelFontSize   = 12
+	labelLineHeight = 15
+	labelMaxLines   = 2
+	labelMaxChars   = 20
+
+	// footerHeight is the band added below the map for a watermark
+	footerHeight   = 24
+	footerFontSize = 10
+)
+
+// Drawing colors
+const (
+	drawingEdgeColor     = "#94a3b8"
+	drawingNodeColor     = "#ffffff"
+	drawingBorderColor   = "#cbd5e1"
+	drawingRootColor     = "#6366f1"
+	drawingCritiqueColor = "#fee2e2"
+	drawingTextColor     = "#1e293b"
+	drawingRootText      = "#ffffff"
+	drawingFooterText    = "#94a3b8"
+)
+
+// Drawing themes, which override the colors of a drawing for printing and accessibility
+const (
+	ThemeDefault      = "default"
+	ThemeMonochrome   = "monochrome"    // Shades of gray for black and white printers
+	ThemeHighContrast = "high-contrast" // Black on white with heavy lines
+	ThemeNoBackground = "no-background" // No canvas or box fills, only outlines and text
+)
+
+// theme is the colors of a drawing and the widths of its lines, in canvas pixels. An
+// empty background or fill is left unpainted. styled maps the background color set in a
+// node's style data to the fill and text color the node gets; nil ignores such colors
+type theme struct {
+	background  string
+	edge        string
+	edgeWidth   float64
+	border      string
+	borderWidth float64
+	node        string
+	root        string
+	rootBorder  string
+	rootText    string
+	critique    string
+	text        string
+	footer      string
+	styled      func(color string) (string, string)
+}
+
+// themes are the drawing themes by name
+var themes = map[string]theme{
+	ThemeDefault: {
+		background: "#ffffff", edge: drawingEdgeColor, edgeWidth: 1.5, border: drawingBorderColor, borderWidth: 1,
+		node: drawingNodeColor, root: drawingRootColor, rootBorder: drawingBorderColor, rootText: drawingRootText,
+		critique: drawingCritiqueColor, text: drawingTextColor, footer: drawingFooterText,
+		styled: func(color string) (string, string) { return color, drawingTextColor },
+	},
+	ThemeMonochrome: {
+		background: "#ffffff", edge: "#666666", edgeWidth: 1.5, border: "#4d4d4d", borderWidth: 1,
+		node: "#ffffff", root: "#333333", rootBorder: "#333333", rootText: "#ffffff",
+		critique: "#e6e6e6", text: "#000000", footer: "#808080",
+		styled: grayscale,
+	},
+	ThemeHighContrast: {
+		background: "#ffffff", edge: "#000000", edgeWidth: 2.5, border: "#000000", borderWidth: 2,
+		node: "#ffffff", root: "#000000", rootBorder: "#000000", rootText: "#ffffff",
+		critique: "#ffffff", text: "#000000", footer: "#000000",
+	},
+	ThemeNoBackground: {
+		edge: drawingEdgeColor, edgeWidth: 1.5, border: drawingBorderColor, borderWidth: 1,
+		rootBorder: drawingRootColor, rootText: drawingTextColor, text: drawingTextColor, footer: drawingFooterText,
+	},
+}
+
+// ValidTheme reports whether name is a supported drawing theme
+func ValidTheme(name string) bool {
+	_, ok := themes[name]
+	return ok
+}
+
+// hexColor matches the CSS hex colors accepted from a node's style data
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// drawing is a mind map prepared for the SVG and PDF exports, moved so its bounding box
+// starts at the margin
+type drawing struct {
+	width, height float64
+	theme         theme
+	edges         []drawnEdge
+	nodes         []drawnNode
+	footer        string // Watermark drawn below the map, empty for none
+}
+
+// drawnEdge is a line between the centers of two nodes, or for a bundled cross-link a
+// cubic curve through the control points of its bundle
+type drawnEdge struct {
+	x1, y1, x2, y2 float64
+	curved         bool
+	cx1, cy1       float64
+	cx2, cy2       float64
+}
+
+// drawnNode is a node box with its wrapped label
+type drawnNode struct {
+	x, y      float64
+	fill      string // Empty for an unfilled box
+	border    string
+	textColor string
+	lines     []string
+}
+
+// newDrawing lays out the boxes, edges and labels of a mind map for drawing in the named
+// theme, the default one if it is unknown, growing the canvas by a footer band when a
+// watermark is given
+func newDrawing(nodes []models.Node, edges []models.Edge, watermark, themeName string) drawing {
+	t, ok := themes[themeName]
+	if !ok {
+		t = themes[ThemeDefault]
+	}
+	d := layoutDrawing(nodes, edges, t)
+	d.theme = t
+	if watermark = strings.TrimSpace(watermark); watermark != "" {
+		d.footer = watermark
+		d.height += footerHeight
+	}
+	return d
+}
+
+// layoutDrawing moves the boxes, edges and labels of a mind map onto the canvas
+func layoutDrawing(nodes []models.Node, edges []models.Edge, t theme) drawing {
+	if len(nodes) == 0 {
+		return drawing{width: 2 * drawingMargin, height: 2 * drawingMargin}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, node := range nodes {
+		minX = math.Min(minX, node.PositionX)
+		minY = math.Min(minY, node.PositionY)
+		maxX = math.Max(maxX, node.PositionX+layout.NodeWidth)
+		maxY = math.Max(maxY, node.PositionY+layout.NodeHeight)
+	}
+	offsetX, offsetY := drawingMargin-minX, drawingMargin-minY
+
+	d := drawing{
+		width:  maxX - minX + 2*drawingMargin,
+		height: maxY - minY + 2*drawingMargin,
+		nodes:  make([]drawnNode, 0, len(nodes)),
+	}
+	centers := make(map[string][2]float64, len(nodes))
+	for _, node := range nodes {
+		x, y := node.PositionX+offsetX, node.PositionY+offsetY
+		centers[node.ID] = [2]float64{x + layout.NodeWidth/2, y + layout.NodeHeight/2}
+		fill, border, textColor := t.nodeColors(node)
+		d.nodes = append(d.nodes, drawnNode{x: x, y: y, fill: fill, border: border, textColor: textColor, lines: wrapLabel(node.Content)})
+	}
+	controls := make(map[string][2]models.CanvasPoint)
+	for _, bundle := range layout.Bundles(nodes, edges) {
+		for _, id := range bundle.EdgeIDs {
+			controls[id] = bundle.Controls
+		}
+	}
+	for _, edge := range edges {
+		source, ok := centers[edge.SourceID]
+		if !ok {
+			continue
+		}
+		target, ok := centers[edge.TargetID]
+		if !ok {
+			continue
+		}
+		drawn := drawnEdge{x1: source[0], y1: source[1], x2: target[0], y2: target[1]}
+		if points, ok := controls[edge.ID]; ok {
+			first, second := points[0], points[1]
+			if math.Hypot(second.X+offsetX-source[0], second.Y+offsetY-source[1]) < math.Hypot(first.X+offsetX-source[0], first.Y+offsetY-source[1]) {
+				first, second = second, first
+			}
+			drawn.curved = true
+			drawn.cx1, drawn.cy1 = first.X+offsetX, first.Y+offsetY
+			drawn.cx2, drawn.cy2 = second.X+offsetX, second.Y+offsetY
+		}
+		d.edges = append(d.edges, drawn)
+	}
+	return d
+}
+
+// nodeColors returns the fill, border and text colors of a node in the theme. The
+// background set in its style data is used, as the theme styles it, when it is a plain hex
+// color, so style data cannot inject markup
+func (t theme) nodeColors(node models.Node) (string, string, string) {
+	var style struct {
+		BackgroundColor string `json:"backgroundColor"`
+	}
+	if t.styled != nil && len(node.StyleData) > 0 && json.Unmarshal(node.StyleData, &style) == nil && hexColor.MatchString(style.BackgroundColor) {
+		fill, text := t.styled(style.BackgroundColor)
+		return fill, t.border, text
+	}
+	if node.ParentID == nil {
+		return t.root, t.rootBorder, t.rootText
+	}
+	if node.NodeType == models.NodeTypeCritique {
+		return t.critique, t.border, t.text
+	}
+	return t.node, t.border, t.text
+}
+
+// grayscale maps a color to the gray of the same luminance, with black or white text
+// depending on which reads better on it
+func grayscale(color string) (string, string) {
+	r, g, b := rgb(color)
+	luminance := 0.299*r + 0.587*g + 0.114*b
+	gray := int(math.Round(luminance * 255))
+	if luminance < 0.5 {
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray), "#ffffff"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray), "#000000"
+}
+
+// wrapLabel breaks node content into the lines drawn inside its box, ending with an
+// ellipsis when it does not fit
+func wrapLabel(content string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(content) {
+		// Words longer than a line are split across lines
+		for utf8.RuneCountInString(word) > labelMaxChars {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:labelMaxChars]))
+			word = string(runes[labelMaxChars:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= labelMaxChars:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > labelMaxLines {
+		lines = lines[:labelMaxLines]
+		last := []rune(lines[labelMaxLines-1])
+		if len(last) >= labelMaxChars {
+			last = last[:labelMaxChars-1]
+		}
+		lines[labelMaxLines-1] = string(last) + "…"
+	}
+	return lines
+}
+
+// rgb converts a hex color to its red, green and blue components between 0 and 1
+func rgb(color string) (float64, float64, float64) {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	value, _ := strconv.ParseUint(hex, 16, 32)
+	return float64(value>>16&0xff) / 255, float64(value>>8&0xff) / 255, float64(value&0xff) / 255
+}
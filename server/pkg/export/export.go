@@ -0,0 +1,88 @@
+// Package export renders mind maps into downloadable file formats
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// xmlHeader starts every XML part of the Office Open XML packages
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// Supported export formats
+const (
+	FormatJSON = "json"
+	FormatPPTX = "pptx"
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	// FormatObsidian is a zip of interlinked markdown notes
+	FormatObsidian = "obsidian"
+)
+
+// ContentTypes maps each export format to the MIME type it is served with
+var ContentTypes = map[string]string{
+	FormatJSON:      "application/json",
+	FormatPPTX:      "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	FormatCSV:       "text/csv",
+	FormatXLSX:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatObsidian:  "application/zip",
+	FormatSVG:       "image/svg+xml",
+	FormatPDF:       "application/pdf",
+	FormatNarration: "text/plain; charset=utf-8",
+	FormatMP3:       "audio/mpeg",
+}
+
+// fileExtensions maps export formats whose file extension differs from the format name
+var fileExtensions = map[string]string{
+	FormatObsidian:  "zip",
+	FormatNarration: "txt",
+}
+
+// unsafeFilenameChars matches characters that should not appear in a download filename
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Filename builds a safe attachment filename from a mind map title and format
+func Filename(title, format string) string {
+	name := strings.Trim(unsafeFilenameChars.ReplaceAllString(title, "-"), "-.")
+	if name == "" {
+		name = "mind-map"
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	if extension, ok := fileExtensions[format]; ok {
+		return name + "." + extension
+	}
+	return name + "." + format
+}
+
+// zipPart is a single file inside an Office Open XML package
+type zipPart struct {
+	name    string
+	content string
+}
+
+// writeZip writes the parts into a zip archive in order
+func writeZip(w io.Writer, parts []zipPart) error {
+	zw := zip.NewWriter(w)
+	for _, part := range parts {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, part.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// escapeXML escapes text for use inside XML element content or attributes
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"strings"
+)
+
+// FormatGraphML is the GraphML format of the knowledge graph export, readable by Gephi and yEd
+const FormatGraphML = "graphml"
+
+// GraphContentTypes maps each knowledge graph format to the MIME type it is served with
+var GraphContentTypes = map[string]string{
+	FormatJSON:    "application/json",
+	FormatGraphML: "application/graphml+xml",
+}
+
+// graphMapID is the graph ID of a mind map's super-node, kept apart from node IDs
+func graphMapID(mindMapID string) string {
+	return "map:" + mindMapID
+}
+
+// KnowledgeGraph merges mind maps into one graph. Each map becomes a super-node containing
+// its root nodes, map edges are kept, and links are resolved to the node or map they
+// reference. Edges and links pointing outside the given maps are dropped
+func KnowledgeGraph(mindMaps []models.MindMap, nodes []models.Node, edges []models.Edge, nodeLinks []models.NodeLink) models.KnowledgeGraph {
+	graph := models.KnowledgeGraph{
+		Nodes: make([]models.GraphNode, 0, len(mindMaps)+len(nodes)),
+		Edges: []models.GraphEdge{},
+	}
+
+	included := make(map[string]bool, len(mindMaps)+len(nodes))
+	for _, mindMap := range mindMaps {
+		id := graphMapID(mindMap.ID)
+		included[id] = true
+		graph.Nodes = append(graph.Nodes, models.GraphNode{
+			ID:        id,
+			Kind:      models.GraphNodeMindMap,
+			Label:     mindMap.Title,
+			MindMapID: mindMap.ID,
+		})
+	}
+
+	for _, node := range nodes {
+		if !included[graphMapID(node.MindMapID)] {
+			continue
+		}
+		included[node.ID] = true
+		graph.Nodes = append(graph.Nodes, models.GraphNode{
+			ID:        node.ID,
+			Kind:      models.GraphNodeNode,
+			Label:     node.Content,
+			MindMapID: node.MindMapID,
+			NodeType:  node.NodeType,
+			Tags:      outline.Tags(node),
+		})
+	}
+
+	for _, node := range nodes {
+		if included[node.ID] && (node.ParentID == nil || !included[*node.ParentID]) {
+			graph.Edges = append(graph.Edges, models.GraphEdge{
+				ID:     "contains:" + node.ID,
+				Kind:   models.GraphEdgeContains,
+				Source: graphMapID(node.MindMapID),
+				Target: node.ID,
+			})
+		}
+	}
+
+	for _, edge := range edges {
+		if included[edge.SourceID] && included[edge.TargetID] {
+			graph.Edges = append(graph.Edges, models.GraphEdge{
+				ID:     edge.ID,
+				Kind:   models.GraphEdgeEdge,
+				Source: edge.SourceID,
+				Target: edge.TargetID,
+			})
+		}
+	}
+
+	for _, link := range nodeLinks {
+		target := graphMapID(link.TargetMindMapID)
+		if link.TargetNodeID != nil {
+			target = *link.TargetNodeID
+		}
+		if included[link.SourceNodeID] && included[target] {
+			graph.Edges = append(graph.Edges, models.GraphEdge{
+				ID:     "link:" + link.SourceNodeID + ":" + target,
+				Kind:   models.GraphEdgeLink,
+				Source: link.SourceNodeID,
+				Target: target,
+			})
+		}
+	}
+
+	return graph
+}
+
+// graphMLKeys declares the attributes written for graph nodes and edges
+const graphMLKeys = `  <key id="kind" for="all" attr.name="kind" attr.type="string"/>
+  <key id="label" for="node" attr.name="label" attr.type="string"/>
+  <key id="mind_map_id" for="node" attr.name="mind_map_id" attr.type="string"/>
+  <key id="node_type" for="node" attr.name="node_type" attr.type="string"/>
+  <key id="tags" for="node" attr.name="tags" attr.type="string"/>
+`
+
+// GraphML writes a knowledge graph as a directed GraphML document
+func GraphML(w io.Writer, graph models.KnowledgeGraph) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(graphMLKeys)
+	b.WriteString(`  <graph id="knowledge-graph" edgedefault="directed">` + "\n")
+
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&b, "    <node id=\"%s\">\n", escapeXML(node.ID))
+		writeGraphMLData(&b, "kind", node.Kind)
+		writeGraphMLData(&b, "label", node.Label)
+		writeGraphMLData(&b, "mind_map_id", node.MindMapID)
+		writeGraphMLData(&b, "node_type", node.NodeType)
+		writeGraphMLData(&b, "tags", strings.Join(node.Tags, TagSeparator))
+		b.WriteString("    </node>\n")
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&b, "    <edge id=\"%s\" source=\"%s\" target=\"%s\">\n", escapeXML(edge.ID), escapeXML(edge.Source), escapeXML(edge.Target))
+		writeGraphMLData(&b, "kind", edge.Kind)
+		b.WriteString("    </edge>\n")
+	}
+
+	b.WriteString("  </graph>\n</graphml>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeGraphMLData writes a data element, skipping empty values
+func writeGraphMLData(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "      <data key=\"%s\">%s</data>\n", key, escapeXML(value))
+}
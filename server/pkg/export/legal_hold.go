@@ -0,0 +1,35 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"saas-server/models"
+)
+
+// LegalHoldArchive writes a zip of a held user's mind maps, each as the document of the
+// JSON export under maps/, led by a manifest.json listing them
+func LegalHoldArchive(w io.Writer, manifest models.LegalHoldExportManifest, mindMaps []*models.MindMapWithDetails) error {
+	parts := make([]zipPart, 0, len(mindMaps)+1)
+	manifest.MindMaps = make([]models.LegalHoldExportEntry, 0, len(mindMaps))
+	for _, mindMap := range mindMaps {
+		file := "maps/" + mindMap.ID + ".json"
+		data, err := json.MarshalIndent(Document(mindMap), "", "  ")
+		if err != nil {
+			return err
+		}
+		parts = append(parts, zipPart{file, string(data)})
+		manifest.MindMaps = append(manifest.MindMaps, models.LegalHoldExportEntry{
+			ID:     mindMap.ID,
+			Title:  mindMap.Title,
+			Status: mindMap.Status,
+			File:   file,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	parts = append([]zipPart{{"manifest.json", string(data)}}, parts...)
+	return writeZip(w, parts)
+}
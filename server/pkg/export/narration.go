@@ -0,0 +1,100 @@
+package export
+
+import (
+	"fmt"
+	"saas-server/pkg/outline"
+	"strings"
+)
+
+// Narrated walkthrough exports: the script as text, or synthesized speech
+const (
+	FormatNarration = "narration"
+	FormatMP3       = "mp3"
+)
+
+// Orders in which a walkthrough visits branches
+const (
+	// WalkDepth finishes each branch before moving on to the next
+	WalkDepth = "depth"
+	// WalkBreadth covers every branch of a level before going a level deeper
+	WalkBreadth = "breadth"
+)
+
+// ValidWalk reports whether walk is a supported walkthrough order
+func ValidWalk(walk string) bool {
+	return walk == WalkDepth || walk == WalkBreadth
+}
+
+// Narration writes a spoken walkthrough of a mind map: an introduction naming the roots,
+// then a sentence for every node with children listing them, in depth-first or
+// breadth-first order. Each sentence is on its own line so the script can be split for
+// speech synthesis
+func Narration(title string, roots []*outline.Item, walk string) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "%s.\n", spokenText(title))
+	switch len(roots) {
+	case 0:
+		script.WriteString("This mind map is empty.\n")
+		return script.String()
+	case 1:
+		fmt.Fprintf(&script, "The central idea is %s.\n", spokenText(roots[0].Node.Content))
+	default:
+		fmt.Fprintf(&script, "This mind map has %d central ideas: %s.\n", len(roots), spokenList(roots))
+	}
+
+	if walk == WalkBreadth {
+		queue := append([]*outline.Item(nil), roots...)
+		for len(queue) > 0 {
+			item := queue[0]
+			queue = queue[1:]
+			narrateChildren(&script, item)
+			queue = append(queue, item.Children...)
+		}
+		return script.String()
+	}
+
+	var visit func(item *outline.Item)
+	visit = func(item *outline.Item) {
+		narrateChildren(&script, item)
+		for _, child := range item.Children {
+			visit(child)
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	return script.String()
+}
+
+// narrateChildren writes the sentence introducing the children of item, if it has any
+func narrateChildren(script *strings.Builder, item *outline.Item) {
+	switch len(item.Children) {
+	case 0:
+	case 1:
+		fmt.Fprintf(script, "Under %s there is %s.\n", spokenText(item.Node.Content), spokenText(item.Children[0].Node.Content))
+	default:
+		fmt.Fprintf(script, "Under %s there are %d ideas: %s.\n", spokenText(item.Node.Content), len(item.Children), spokenList(item.Children))
+	}
+}
+
+// spokenList joins the content of items as a spoken list, such as "a, b and c"
+func spokenList(items []*outline.Item) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = spokenText(item.Node.Content)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// spokenText flattens node content onto one line and drops trailing sentence punctuation
+// so it reads naturally inside a sentence. Empty content is read as "an untitled idea"
+func spokenText(content string) string {
+	text := strings.TrimRight(spaceRun.ReplaceAllString(strings.TrimSpace(content), " "), ".!?;:")
+	if text == "" {
+		return "an untitled idea"
+	}
+	return text
+}
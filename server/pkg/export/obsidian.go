@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"saas-server/pkg/outline"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxNoteNameRunes caps the length of a note name taken from node content
+const maxNoteNameRunes = 80
+
+// unsafeNoteNameChars matches characters Obsidian and common file systems do not allow in note names
+var unsafeNoteNameChars = regexp.MustCompile(`[\\/:*?"<>|#^\[\]]+`)
+
+// spaceRun matches runs of whitespace, including line breaks
+var spaceRun = regexp.MustCompile(`\s+`)
+
+// Obsidian writes a mind map as a zip of interlinked markdown notes, one per node, that can be
+// opened as an Obsidian or Logseq vault. Notes sit in folders mirroring the node hierarchy and
+// link to their parent and children with [[wikilinks]]; an index note named after the map links
+// to the root nodes. Note names are unique across the vault so every wikilink resolves
+func Obsidian(w io.Writer, title string, roots []*outline.Item) error {
+	names := make(map[string]bool)
+	indexName := uniqueNoteName(names, title, "Mind Map")
+
+	var parts []zipPart
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n\n", title)
+	rootNames := obsidianNotes(&parts, names, roots, "", indexName)
+	for _, name := range rootNames {
+		fmt.Fprintf(&index, "- [[%s]]\n", name)
+	}
+	parts = append([]zipPart{{indexName + ".md", index.String()}}, parts...)
+
+	return writeZip(w, parts)
+}
+
+// obsidianNotes adds a note for each item and its descendants under dir, returning the note
+// names of the items themselves so the parent can link to them
+func obsidianNotes(parts *[]zipPart, names map[string]bool, items []*outline.Item, dir, parentName string) []string {
+	itemNames := make([]string, len(items))
+	for i, item := range items {
+		itemNames[i] = uniqueNoteName(names, item.Node.Content, "Untitled")
+	}
+
+	for i, item := range items {
+		name := itemNames[i]
+		childNames := obsidianNotes(parts, names, item.Children, path.Join(dir, name), name)
+
+		var note strings.Builder
+		note.WriteString("---\n")
+		fmt.Fprintf(&note, "id: %s\n", item.Node.ID)
+		if tags := outline.Tags(item.Node); len(tags) > 0 {
+			note.WriteString("tags:\n")
+			for _, tag := range tags {
+				fmt.Fprintf(&note, "  - %s\n", strconv.Quote(spaceRun.ReplaceAllString(strings.TrimSpace(tag), "-")))
+			}
+		}
+		fmt.Fprintf(&note, "created: %s\n", item.Node.CreatedAt.UTC().Format(time.RFC3339))
+		note.WriteString("---\n\n")
+		fmt.Fprintf(&note, "Up: [[%s]]\n\n", parentName)
+		note.WriteString(item.Node.Content)
+		note.WriteString("\n")
+		if len(childNames) > 0 {
+			note.WriteString("\n## Children\n\n")
+			for _, childName := range childNames {
+				fmt.Fprintf(&note, "- [[%s]]\n", childName)
+			}
+		}
+
+		*parts = append(*parts, zipPart{path.Join(dir, name+".md"), note.String()})
+	}
+	return itemNames
+}
+
+// uniqueNoteName derives a safe note name from text, adding a counter when the name is
+// already taken. Names are compared case-insensitively since file systems may be
+func uniqueNoteName(taken map[string]bool, text, fallback string) string {
+	name := strings.TrimSpace(spaceRun.ReplaceAllString(unsafeNoteNameChars.ReplaceAllString(text, "-"), " "))
+	name = strings.Trim(name, ".")
+	if runes := []rune(name); len(runes) > maxNoteNameRunes {
+		name = strings.TrimSpace(string(runes[:maxNoteNameRunes]))
+	}
+	if name == "" {
+		name = fallback
+	}
+
+	unique := name
+	for i := 2; taken[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+	taken[strings.ToLower(unique)] = true
+	return unique
+}
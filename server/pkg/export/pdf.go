@@ -0,0 +1,276 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"saas-server/models"
+	"saas-server/pkg/layout"
+	"strings"
+)
+
+// pointsPerPixel converts canvas pixels to PDF points
+const pointsPerPixel = 0.75
+
+// helveticaAverageWidth is the average glyph width of Helvetica relative to the font size,
+// used to center labels without embedding font metrics
+const helveticaAverageWidth = 0.5
+
+// Paper sizes of the PDF export. PaperFit sizes a single page to the map
+const (
+	PaperFit    = "fit"
+	PaperA4     = "a4"
+	PaperA3     = "a3"
+	PaperLetter = "letter"
+)
+
+// Orientations of the pages of the PDF export. OrientationAuto picks the one that suits
+// the map: the orientation of its longer side when it is scaled to fit, and the one
+// needing fewer pages when it is tiled
+const (
+	OrientationAuto      = "auto"
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// paperSizes are the portrait width and height of each paper size, in points
+var paperSizes = map[string][2]float64{
+	PaperA4:     {595.28, 841.89},
+	PaperA3:     {841.89, 1190.55},
+	PaperLetter: {612, 792},
+}
+
+// pageMargin is the margin kept clear on each side of a sized page, in points
+const pageMargin = 36
+
+// PDFPage sets how the PDF export lays the map out on paper. On a sized paper the map is
+// shrunk to fit a single page, never enlarged, unless Tile is set, in which case it is
+// printed at full size across as many pages as it needs, each labelled with its place
+type PDFPage struct {
+	Paper       string
+	Orientation string
+	Tile        bool
+}
+
+// ValidPaper reports whether paper is a supported paper size
+func ValidPaper(paper string) bool {
+	_, ok := paperSizes[paper]
+	return ok || paper == PaperFit
+}
+
+// ValidOrientation reports whether orientation is a supported page orientation
+func ValidOrientation(orientation string) bool {
+	return orientation == OrientationAuto || orientation == OrientationPortrait || orientation == OrientationLandscape
+}
+
+// pdfPlacement is a page of the PDF export with where the map is drawn on it: scaled by
+// scale and moved by x and y, clipped to the clip rectangle. Label names a tile
+type pdfPlacement struct {
+	width, height float64
+	scale, x, y   float64
+	clip          [4]float64
+	label         string
+}
+
+// PDF draws the mind map using the standard Helvetica font so nothing needs to be
+// embedded, by default on a single page sized to fit it. The cross-links of dense maps
+// are bundled into curves and colored in the named theme as in the SVG export. A non-empty
+// watermark is written in a footer below the map. The map is drawn once, as a form every
+// page places
+func PDF(w io.Writer, title string, nodes []models.Node, edges []models.Edge, watermark, theme string, page PDFPage) error {
+	d := newDrawing(nodes, edges, watermark, theme)
+	pageWidth, pageHeight := d.width*pointsPerPixel, d.height*pointsPerPixel
+
+	// PDF coordinates start at the bottom left of the page
+	point := func(x, y float64) (float64, float64) {
+		return x * pointsPerPixel, pageHeight - y*pointsPerPixel
+	}
+
+	var content strings.Builder
+	if d.theme.background != "" {
+		r, g, bl := rgb(d.theme.background)
+		fmt.Fprintf(&content, "%.3f %.3f %.3f rg 0 0 %.2f %.2f re f\n", r, g, bl, pageWidth, pageHeight)
+	}
+	r, g, bl := rgb(d.theme.edge)
+	fmt.Fprintf(&content, "%.3f %.3f %.3f RG %.2f w\n", r, g, bl, d.theme.edgeWidth*pointsPerPixel)
+	for _, edge := range d.edges {
+		x1, y1 := point(edge.x1, edge.y1)
+		x2, y2 := point(edge.x2, edge.y2)
+		if edge.curved {
+			cx1, cy1 := point(edge.cx1, edge.cy1)
+			cx2, cy2 := point(edge.cx2, edge.cy2)
+			fmt.Fprintf(&content, "%.2f %.2f m %.2f %.2f %.2f %.2f %.2f %.2f c S\n", x1, y1, cx1, cy1, cx2, cy2, x2, y2)
+			continue
+		}
+		fmt.Fprintf(&content, "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+	}
+
+	fontSize := labelFontSize * pointsPerPixel
+	fmt.Fprintf(&content, "%.2f w\n", d.theme.borderWidth*pointsPerPixel)
+	for _, node := range d.nodes {
+		x, y := point(node.x, node.y+layout.NodeHeight)
+		r, g, bl = rgb(node.border)
+		fmt.Fprintf(&content, "%.3f %.3f %.3f RG ", r, g, bl)
+		if node.fill == "" {
+			fmt.Fprintf(&content, "%.2f %.2f %.2f %.2f re S\n", x, y, layout.NodeWidth*pointsPerPixel, layout.NodeHeight*pointsPerPixel)
+		} else {
+			r, g, bl = rgb(node.fill)
+			fmt.Fprintf(&content, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re B\n",
+				r, g, bl, x, y, layout.NodeWidth*pointsPerPixel, layout.NodeHeight*pointsPerPixel)
+		}
+
+		r, g, bl = rgb(node.textColor)
+		top := node.y + layout.NodeHeight/2 - float64(len(node.lines)-1)*labelLineHeight/2
+		for i, line := range node.lines {
+			text := pdfText(line)
+			textWidth := float64(len(text)) * fontSize * helveticaAverageWidth
+			tx, ty := point(node.x+layout.NodeWidth/2, top+float64(i)*labelLineHeight)
+			fmt.Fprintf(&content, "BT /F1 %.1f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+				fontSize, r, g, bl, tx-textWidth/2, ty-fontSize/3, escapePDFString(text))
+		}
+	}
+
+	if d.footer != "" {
+		footerSize := footerFontSize * pointsPerPixel
+		text := pdfText(d.footer)
+		textWidth := float64(len(text)) * footerSize * helveticaAverageWidth
+		tx, ty := point(d.width-drawingMargin/2, d.height-footerHeight/2)
+		r, g, bl = rgb(d.theme.footer)
+		fmt.Fprintf(&content, "BT /F1 %.1f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+			footerSize, r, g, bl, tx-textWidth, ty-footerSize/3, escapePDFString(text))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // Pages, once the pages are known
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Length %d >>\nstream\n%sendstream",
+			pageWidth, pageHeight, content.Len(), content.String()),
+		fmt.Sprintf("<< /Title (%s) /Producer (IdeaVisualMap) >>", escapePDFString(pdfText(title))),
+	}
+	placements := pdfPlacements(pageWidth, pageHeight, page)
+	kids := make([]string, len(placements))
+	for i, placement := range placements {
+		var pageContent strings.Builder
+		fmt.Fprintf(&pageContent, "q %.2f %.2f %.2f %.2f re W n %.4f 0 0 %.4f %.2f %.2f cm /Map Do Q\n",
+			placement.clip[0], placement.clip[1], placement.clip[2], placement.clip[3],
+			placement.scale, placement.scale, placement.x, placement.y)
+		if placement.label != "" {
+			labelSize := footerFontSize * pointsPerPixel
+			r, g, bl := rgb(d.theme.footer)
+			fmt.Fprintf(&pageContent, "BT /F1 %.1f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+				labelSize, r, g, bl, float64(pageMargin), float64(pageMargin)/2, escapePDFString(pdfText(placement.label)))
+		}
+
+		pageObject := len(objects) + 1
+		kids[i] = fmt.Sprintf("%d 0 R", pageObject)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> /XObject << /Map 4 0 R >> >> /Contents %d 0 R >>",
+				placement.width, placement.height, pageObject+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", pageContent.Len(), pageContent.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	// Write the objects and the cross-reference table pointing at them
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfPlacements lays a map of the given size, in points, out on the pages of a PDF
+func pdfPlacements(width, height float64, page PDFPage) []pdfPlacement {
+	size, ok := paperSizes[page.Paper]
+	if !ok {
+		return []pdfPlacement{{width: width, height: height, scale: 1, clip: [4]float64{0, 0, width, height}}}
+	}
+	portrait := [2]float64{size[0], size[1]}
+	landscape := [2]float64{size[1], size[0]}
+
+	if !page.Tile {
+		paper := portrait
+		if page.Orientation == OrientationLandscape || (page.Orientation != OrientationPortrait && width > height) {
+			paper = landscape
+		}
+		availableWidth, availableHeight := paper[0]-2*pageMargin, paper[1]-2*pageMargin
+		scale := math.Min(1, math.Min(availableWidth/width, availableHeight/height))
+		return []pdfPlacement{{
+			width:  paper[0],
+			height: paper[1],
+			scale:  scale,
+			x:      (paper[0] - width*scale) / 2,
+			y:      (paper[1] - height*scale) / 2,
+			clip:   [4]float64{0, 0, paper[0], paper[1]},
+		}}
+	}
+
+	// Tile the map at full size, in the orientation needing fewer pages unless one is set
+	tiles := func(paper [2]float64) (int, int) {
+		return int(math.Ceil(width / (paper[0] - 2*pageMargin))), int(math.Ceil(height / (paper[1] - 2*pageMargin)))
+	}
+	paper := portrait
+	columns, rows := tiles(portrait)
+	if landscapeColumns, landscapeRows := tiles(landscape); page.Orientation == OrientationLandscape ||
+		(page.Orientation != OrientationPortrait && landscapeColumns*landscapeRows < columns*rows) {
+		paper, columns, rows = landscape, landscapeColumns, landscapeRows
+	}
+	availableWidth, availableHeight := paper[0]-2*pageMargin, paper[1]-2*pageMargin
+
+	placements := make([]pdfPlacement, 0, columns*rows)
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			label := fmt.Sprintf("Page %d of %d", len(placements)+1, columns*rows)
+			if columns*rows > 1 {
+				label += fmt.Sprintf(" (row %d, column %d)", row+1, column+1)
+			}
+			placements = append(placements, pdfPlacement{
+				width:  paper[0],
+				height: paper[1],
+				scale:  1,
+				x:      pageMargin - float64(column)*availableWidth,
+				y:      paper[1] - pageMargin - height + float64(row)*availableHeight,
+				clip:   [4]float64{pageMargin, pageMargin, availableWidth, availableHeight},
+				label:  label,
+			})
+		}
+	}
+	return placements
+}
+
+// pdfText converts text to the WinAnsi bytes of the standard fonts. Characters outside
+// Latin-1 have no glyph there and are replaced with a question mark
+func pdfText(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '…':
+			b = append(b, 0x85)
+		case r < 0x20:
+			b = append(b, ' ')
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			b = append(b, byte(r))
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+// escapePDFString escapes the characters that end or alter a PDF literal string
+func escapePDFString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
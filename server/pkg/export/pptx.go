@@ -0,0 +1,234 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"saas-server/models"
+	"strings"
+	"time"
+)
+
+// Slide geometry in EMUs for a 16:9 deck
+const (
+	pptxSlideWidth  = 12192000
+	pptxSlideHeight = 6858000
+	pptxMargin      = 609600
+	// pptxMaxLevel is the deepest bullet indentation PowerPoint supports
+	pptxMaxLevel = 8
+)
+
+// PPTX writes the slides as a PowerPoint presentation. Each slide gets a title and
+// its bullets as an indented list; a slide without bullets renders as a title slide
+func PPTX(w io.Writer, title string, slides []models.PresentationSlide) error {
+	files := []zipPart{
+		{"[Content_Types].xml", pptxContentTypes(len(slides))},
+		{"_rels/.rels", pptxRootRels},
+		{"docProps/app.xml", pptxApp(len(slides))},
+		{"docProps/core.xml", pptxCore(title)},
+		{"ppt/presentation.xml", pptxPresentation(len(slides))},
+		{"ppt/_rels/presentation.xml.rels", pptxPresentationRels(len(slides))},
+		{"ppt/slideMasters/slideMaster1.xml", pptxSlideMaster},
+		{"ppt/slideMasters/_rels/slideMaster1.xml.rels", pptxSlideMasterRels},
+		{"ppt/slideLayouts/slideLayout1.xml", pptxSlideLayout},
+		{"ppt/slideLayouts/_rels/slideLayout1.xml.rels", pptxSlideLayoutRels},
+		{"ppt/theme/theme1.xml", pptxTheme},
+	}
+	for i, slide := range slides {
+		files = append(files,
+			zipPart{fmt.Sprintf("ppt/slides/slide%d.xml", i+1), pptxSlide(slide)},
+			zipPart{fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1), pptxSlideRels},
+		)
+	}
+
+	return writeZip(w, files)
+}
+
+const pptxNamespaces = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+
+func pptxContentTypes(slideCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/ppt/presentation.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"/>`)
+	b.WriteString(`<Override PartName="/ppt/slideMasters/slideMaster1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"/>`)
+	b.WriteString(`<Override PartName="/ppt/slideLayouts/slideLayout1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"/>`)
+	b.WriteString(`<Override PartName="/ppt/theme/theme1.xml" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/>`)
+	b.WriteString(`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>`)
+	b.WriteString(`<Override PartName="/docProps/app.xml" ContentType="application/vnd.openxmlformats-officedocument.extended-properties+xml"/>`)
+	for i := 1; i <= slideCount; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/ppt/slides/slide%d.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+const pptxRootRels = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="ppt/presentation.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties" Target="docProps/app.xml"/>` +
+	`</Relationships>`
+
+func pptxApp(slideCount int) string {
+	return xmlHeader +
+		`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties">` +
+		`<Application>IdeaVisualMap</Application>` +
+		fmt.Sprintf(`<Slides>%d</Slides>`, slideCount) +
+		`</Properties>`
+}
+
+func pptxCore(title string) string {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return xmlHeader +
+		`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escapeXML(title) + `</dc:title>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + now + `</dcterms:created>` +
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">` + now + `</dcterms:modified>` +
+		`</cp:coreProperties>`
+}
+
+func pptxPresentation(slideCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<p:presentation ` + pptxNamespaces + `>`)
+	b.WriteString(`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>`)
+	if slideCount > 0 {
+		b.WriteString(`<p:sldIdLst>`)
+		for i := 1; i <= slideCount; i++ {
+			fmt.Fprintf(&b, `<p:sldId id="%d" r:id="rId%d"/>`, 255+i, i+2)
+		}
+		b.WriteString(`</p:sldIdLst>`)
+	}
+	fmt.Fprintf(&b, `<p:sldSz cx="%d" cy="%d"/>`, pptxSlideWidth, pptxSlideHeight)
+	b.WriteString(`<p:notesSz cx="6858000" cy="9144000"/>`)
+	b.WriteString(`</p:presentation>`)
+	return b.String()
+}
+
+func pptxPresentationRels(slideCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	b.WriteString(`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster" Target="slideMasters/slideMaster1.xml"/>`)
+	b.WriteString(`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="theme/theme1.xml"/>`)
+	for i := 1; i <= slideCount; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide%d.xml"/>`, i+2, i)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+const pptxEmptyTree = `<p:cSld><p:spTree>` +
+	`<p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>` +
+	`<p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>` +
+	`</p:spTree></p:cSld>`
+
+const pptxSlideMaster = xmlHeader +
+	`<p:sldMaster ` + pptxNamespaces + `>` +
+	pptxEmptyTree +
+	`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+	`<p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst>` +
+	`</p:sldMaster>`
+
+const pptxSlideMasterRels = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="../theme/theme1.xml"/>` +
+	`</Relationships>`
+
+const pptxSlideLayout = xmlHeader +
+	`<p:sldLayout ` + pptxNamespaces + ` type="blank" preserve="1">` +
+	pptxEmptyTree +
+	`<p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr>` +
+	`</p:sldLayout>`
+
+const pptxSlideLayoutRels = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster" Target="../slideMasters/slideMaster1.xml"/>` +
+	`</Relationships>`
+
+const pptxSlideRels = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/>` +
+	`</Relationships>`
+
+// pptxSlide renders one slide with a title text box and, if present, a bullet list below it
+func pptxSlide(slide models.PresentationSlide) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<p:sld ` + pptxNamespaces + `><p:cSld><p:spTree>`)
+	b.WriteString(`<p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>`)
+	b.WriteString(`<p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>`)
+
+	width := pptxSlideWidth - 2*pptxMargin
+	if len(slide.Bullets) == 0 {
+		// Title slide: one large centered heading
+		pptxTextBox(&b, 2, "Title", pptxMargin, pptxSlideHeight/2-914400, width, 1828800,
+			`<a:p><a:pPr algn="ctr"/><a:r><a:rPr lang="en-US" sz="4400" b="1"/><a:t>`+escapeXML(slide.Title)+`</a:t></a:r></a:p>`)
+	} else {
+		pptxTextBox(&b, 2, "Title", pptxMargin, 457200, width, 1143000,
+			`<a:p><a:r><a:rPr lang="en-US" sz="3600" b="1"/><a:t>`+escapeXML(slide.Title)+`</a:t></a:r></a:p>`)
+
+		var body strings.Builder
+		for _, bullet := range slide.Bullets {
+			level := bullet.Level
+			if level > pptxMaxLevel {
+				level = pptxMaxLevel
+			}
+			fmt.Fprintf(&body, `<a:p><a:pPr lvl="%d" marL="%d" indent="-285750"><a:buFont typeface="Arial"/><a:buChar char="&#8226;"/></a:pPr>`, level, 342900+level*457200)
+			fmt.Fprintf(&body, `<a:r><a:rPr lang="en-US" sz="%d"/><a:t>%s</a:t></a:r></a:p>`, pptxBulletSize(level), escapeXML(bullet.Content))
+		}
+		pptxTextBox(&b, 3, "Content", pptxMargin, 1752600, width, pptxSlideHeight-1752600-pptxMargin, body.String())
+	}
+
+	b.WriteString(`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`)
+	return b.String()
+}
+
+// pptxTextBox writes a positioned text box shape containing the given paragraphs
+func pptxTextBox(b *strings.Builder, id int, name string, x, y, cx, cy int, paragraphs string) {
+	fmt.Fprintf(b, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`, id, name)
+	fmt.Fprintf(b, `<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr>`, x, y, cx, cy)
+	b.WriteString(`<p:txBody><a:bodyPr wrap="square"><a:normAutofit/></a:bodyPr><a:lstStyle/>`)
+	b.WriteString(paragraphs)
+	b.WriteString(`</p:txBody></p:sp>`)
+}
+
+// pptxBulletSize shrinks the font size (in hundredths of a point) for deeper bullet levels
+func pptxBulletSize(level int) int {
+	size := 2400 - level*200
+	if size < 1400 {
+		size = 1400
+	}
+	return size
+}
+
+const pptxTheme = xmlHeader +
+	`<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="IdeaVisualMap">` +
+	`<a:themeElements>` +
+	`<a:clrScheme name="IdeaVisualMap">` +
+	`<a:dk1><a:srgbClr val="1F2937"/></a:dk1><a:lt1><a:srgbClr val="FFFFFF"/></a:lt1>` +
+	`<a:dk2><a:srgbClr val="374151"/></a:dk2><a:lt2><a:srgbClr val="F3F4F6"/></a:lt2>` +
+	`<a:accent1><a:srgbClr val="4F46E5"/></a:accent1><a:accent2><a:srgbClr val="0EA5E9"/></a:accent2>` +
+	`<a:accent3><a:srgbClr val="10B981"/></a:accent3><a:accent4><a:srgbClr val="F59E0B"/></a:accent4>` +
+	`<a:accent5><a:srgbClr val="EF4444"/></a:accent5><a:accent6><a:srgbClr val="8B5CF6"/></a:accent6>` +
+	`<a:hlink><a:srgbClr val="2563EB"/></a:hlink><a:folHlink><a:srgbClr val="7C3AED"/></a:folHlink>` +
+	`</a:clrScheme>` +
+	`<a:fontScheme name="IdeaVisualMap">` +
+	`<a:majorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:majorFont>` +
+	`<a:minorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:minorFont>` +
+	`</a:fontScheme>` +
+	`<a:fmtScheme name="IdeaVisualMap">` +
+	`<a:fillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:fillStyleLst>` +
+	`<a:lnStyleLst><a:ln w="9525"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="25400"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="38100"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln></a:lnStyleLst>` +
+	`<a:effectStyleLst><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle></a:effectStyleLst>` +
+	`<a:bgFillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:bgFillStyleLst>` +
+	`</a:fmtScheme>` +
+	`</a:themeElements>` +
+	`</a:theme>`
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"saas-server/models"
+	"saas-server/pkg/layout"
+	"strings"
+)
+
+// SVG draws the mind map at full size as an SVG document, with node content as labels and
+// the cross-links of dense maps bundled into curves, colored in the named theme. A
+// non-empty watermark is written in a footer below the map
+func SVG(w io.Writer, title string, nodes []models.Node, edges []models.Edge, watermark, theme string) error {
+	d := newDrawing(nodes, edges, watermark, theme)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif" font-size="%d">`,
+		d.width, d.height, d.width, d.height, labelFontSize)
+	fmt.Fprintf(&b, `<title>%s</title>`, escapeXML(title))
+	if d.theme.background != "" {
+		fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, d.theme.background)
+	}
+
+	// Edges go first so nodes are drawn on top of them
+	for _, edge := range d.edges {
+		if edge.curved {
+			fmt.Fprintf(&b, `<path d="M%.1f %.1f C%.1f %.1f %.1f %.1f %.1f %.1f" fill="none" stroke="%s" stroke-width="%g"/>`,
+				edge.x1, edge.y1, edge.cx1, edge.cy1, edge.cx2, edge.cy2, edge.x2, edge.y2, d.theme.edge, d.theme.edgeWidth)
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%g"/>`,
+			edge.x1, edge.y1, edge.x2, edge.y2, d.theme.edge, d.theme.edgeWidth)
+	}
+
+	for _, node := range d.nodes {
+		fill := node.fill
+		if fill == "" {
+			fill = "none"
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%d" height="%d" rx="8" fill="%s" stroke="%s" stroke-width="%g"/>`,
+			node.x, node.y, layout.NodeWidth, layout.NodeHeight, fill, node.border, d.theme.borderWidth)
+		top := node.y + layout.NodeHeight/2 - float64(len(node.lines)-1)*labelLineHeight/2
+		for i, line := range node.lines {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="middle" dominant-baseline="central">%s</text>`,
+				node.x+layout.NodeWidth/2, top+float64(i)*labelLineHeight, node.textColor, escapeXML(line))
+		}
+	}
+
+	if d.footer != "" {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s" font-size="%d" text-anchor="end">%s</text>`,
+			d.width-drawingMargin/2, d.height-footerHeight/2, d.theme.footer, footerFontSize, escapeXML(d.footer))
+	}
+
+	b.WriteString(`</svg>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
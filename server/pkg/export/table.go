@@ -0,0 +1,141 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"saas-server/pkg/outline"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableHeader is the column layout of tabular exports
+var TableHeader = []string{"id", "content", "parent_id", "depth", "node_type", "tags", "created_at"}
+
+// TagSeparator joins multiple tags within a single tabular cell
+const TagSeparator = "; "
+
+// NodeRows flattens an outline into one row per node, parents before their children
+func NodeRows(roots []*outline.Item) [][]string {
+	rows := [][]string{TableHeader}
+	for _, item := range outline.Flatten(roots) {
+		parentID := ""
+		if item.Node.ParentID != nil {
+			parentID = *item.Node.ParentID
+		}
+		rows = append(rows, []string{
+			item.Node.ID,
+			item.Node.Content,
+			parentID,
+			strconv.Itoa(item.Depth),
+			item.Node.NodeType,
+			strings.Join(outline.Tags(item.Node), TagSeparator),
+			item.Node.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+// CSV writes rows as comma-separated values. Cells a spreadsheet would run as a formula
+// are prefixed with a quote, since content comes from collaborators, AI and emails
+func CSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cells[i] = csvCell(value)
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell escapes a value that starts like a spreadsheet formula so it is read as text
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// XLSX writes rows as a single-sheet Excel workbook using inline strings
+func XLSX(w io.Writer, sheetName string, rows [][]string) error {
+	files := []zipPart{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(sheetName)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(rows)},
+	}
+	return writeZip(w, files)
+}
+
+const xlsxContentTypes = xmlHeader +
+	`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbookRels = xmlHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+// xlsxInvalidSheetChars are characters Excel does not allow in sheet names
+var xlsxInvalidSheetChars = strings.NewReplacer(":", " ", "\\", " ", "/", " ", "?", " ", "*", " ", "[", " ", "]", " ")
+
+func xlsxWorkbook(sheetName string) string {
+	name := strings.TrimSpace(xlsxInvalidSheetChars.Replace(sheetName))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Nodes"
+	}
+	return xmlHeader +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escapeXML(name) + `" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+}
+
+func xlsxSheet(rows [][]string) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, value := range row {
+			ref := xlsxColumn(c) + strconv.Itoa(r+1)
+			if r > 0 && TableHeader[c] == "depth" {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escapeXML(value))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxColumn converts a zero-based column index into a spreadsheet column name (A, B, ..., AA)
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,241 @@
+// Package fields validates the custom field schemas of mind maps and the node values filled in for them
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"saas-server/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema limits
+const (
+	MaxFields       = 30
+	MaxNameLength   = 50
+	MaxOptions      = 50
+	MaxOptionLength = 50
+	MaxTextLength   = 10000
+)
+
+// dateLayout is the format of date field values
+const dateLayout = "2006-01-02"
+
+// ValidType reports whether fieldType is a known custom field type
+func ValidType(fieldType string) bool {
+	switch fieldType {
+	case models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate, models.CustomFieldCheckbox,
+		models.CustomFieldURL, models.CustomFieldSelect, models.CustomFieldMultiSelect, models.CustomFieldRollup:
+		return true
+	}
+	return false
+}
+
+// ValidateSchema trims the field names and options of a schema in place and checks that names
+// are unique, types are known, select fields have distinct options and rollups are computable
+func ValidateSchema(schema []models.CustomField) error {
+	if len(schema) > MaxFields {
+		return fmt.Errorf("a mind map can have at most %d custom fields", MaxFields)
+	}
+
+	names := make(map[string]bool, len(schema))
+	for i := range schema {
+		field := &schema[i]
+		field.Name = strings.TrimSpace(field.Name)
+		if field.Name == "" {
+			return fmt.Errorf("field %d: name is required", i+1)
+		}
+		if utf8.RuneCountInString(field.Name) > MaxNameLength {
+			return fmt.Errorf("field %q: name must be at most %d characters", field.Name, MaxNameLength)
+		}
+		if names[strings.ToLower(field.Name)] {
+			return fmt.Errorf("field %q: name is used more than once", field.Name)
+		}
+		names[strings.ToLower(field.Name)] = true
+
+		if !ValidType(field.Type) {
+			return fmt.Errorf("field %q: unknown type %q", field.Name, field.Type)
+		}
+		if (field.Type == models.CustomFieldRollup) != (field.Rollup != nil) {
+			return fmt.Errorf("field %q: rollup fields, and only rollup fields, need a rollup definition", field.Name)
+		}
+
+		isSelect := field.Type == models.CustomFieldSelect || field.Type == models.CustomFieldMultiSelect
+		if !isSelect {
+			if len(field.Options) > 0 {
+				return fmt.Errorf("field %q: only select fields have options", field.Name)
+			}
+			continue
+		}
+		if len(field.Options) == 0 || len(field.Options) > MaxOptions {
+			return fmt.Errorf("field %q: select fields need between 1 and %d options", field.Name, MaxOptions)
+		}
+		options := make(map[string]bool, len(field.Options))
+		for j, option := range field.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || utf8.RuneCountInString(option) > MaxOptionLength {
+				return fmt.Errorf("field %q: options must be between 1 and %d characters", field.Name, MaxOptionLength)
+			}
+			if options[option] {
+				return fmt.Errorf("field %q: option %q is listed more than once", field.Name, option)
+			}
+			options[option] = true
+			field.Options[j] = option
+		}
+	}
+
+	for _, field := range schema {
+		if field.Rollup != nil {
+			if err := validateRollup(schema, field.Rollup); err != nil {
+				return fmt.Errorf("field %q: %v", field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRollup checks a rollup's function and scope, and that its source field has the type
+// the function needs. A missing scope is set to children
+func validateRollup(schema []models.CustomField, rollup *models.RollupDefinition) error {
+	switch rollup.Scope {
+	case "":
+		rollup.Scope = models.RollupScopeChildren
+	case models.RollupScopeChildren, models.RollupScopeDescendants:
+	default:
+		return fmt.Errorf("unknown rollup scope %q", rollup.Scope)
+	}
+
+	var sourceType string
+	switch rollup.Function {
+	case models.RollupCount:
+		if rollup.Field != "" {
+			return fmt.Errorf("count rollups do not take a field")
+		}
+		return nil
+	case models.RollupSum, models.RollupAverage, models.RollupMin, models.RollupMax:
+		sourceType = models.CustomFieldNumber
+	case models.RollupPercentChecked:
+		sourceType = models.CustomFieldCheckbox
+	default:
+		return fmt.Errorf("unknown rollup function %q", rollup.Function)
+	}
+
+	for _, source := range schema {
+		if source.Name == rollup.Field {
+			if source.Type != sourceType {
+				return fmt.Errorf("%s rollups need a %s field, but %q is a %s field", rollup.Function, sourceType, source.Name, source.Type)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("rollup field %q is not a custom field of this mind map", rollup.Field)
+}
+
+// ValidateValues checks the custom field values in a node's metadata against a schema. Values
+// live in an object under the "fields" metadata key; every key must be a field of the schema
+// and every value must suit its type. A null value clears the field
+func ValidateValues(schema []models.CustomField, metadata json.RawMessage) error {
+	if len(metadata) == 0 || string(metadata) == "null" {
+		return nil
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &wrapper); err != nil {
+		return fmt.Errorf("metadata must be a JSON object")
+	}
+	raw, ok := wrapper[models.CustomFieldsMetadataKey]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return fmt.Errorf("%s must be a JSON object", models.CustomFieldsMetadataKey)
+	}
+
+	byName := make(map[string]models.CustomField, len(schema))
+	for _, field := range schema {
+		byName[field.Name] = field
+	}
+	for name, value := range values {
+		field, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%q is not a custom field of this mind map", name)
+		}
+		if field.Type == models.CustomFieldRollup {
+			return fmt.Errorf("field %q is computed and cannot be set", name)
+		}
+		if err := validateValue(field, value); err != nil {
+			return fmt.Errorf("field %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// validateValue checks a single value against its field's type
+func validateValue(field models.CustomField, value json.RawMessage) error {
+	if string(bytes.TrimSpace(value)) == "null" {
+		return nil
+	}
+
+	switch field.Type {
+	case models.CustomFieldNumber:
+		var number float64
+		if json.Unmarshal(value, &number) != nil {
+			return fmt.Errorf("must be a number")
+		}
+	case models.CustomFieldCheckbox:
+		var checked bool
+		if json.Unmarshal(value, &checked) != nil {
+			return fmt.Errorf("must be true or false")
+		}
+	case models.CustomFieldMultiSelect:
+		var choices []string
+		if json.Unmarshal(value, &choices) != nil {
+			return fmt.Errorf("must be a list of options")
+		}
+		for _, choice := range choices {
+			if !hasOption(field, choice) {
+				return fmt.Errorf("%q is not one of its options", choice)
+			}
+		}
+	default:
+		var text string
+		if json.Unmarshal(value, &text) != nil {
+			return fmt.Errorf("must be a string")
+		}
+		switch field.Type {
+		case models.CustomFieldText:
+			if utf8.RuneCountInString(text) > MaxTextLength {
+				return fmt.Errorf("must be at most %d characters", MaxTextLength)
+			}
+		case models.CustomFieldDate:
+			if _, err := time.Parse(dateLayout, text); err != nil {
+				return fmt.Errorf("must be a date formatted as YYYY-MM-DD")
+			}
+		case models.CustomFieldURL:
+			parsed, err := url.Parse(text)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("must be an http or https URL")
+			}
+		case models.CustomFieldSelect:
+			if !hasOption(field, text) {
+				return fmt.Errorf("%q is not one of its options", text)
+			}
+		}
+	}
+	return nil
+}
+
+// hasOption reports whether choice is one of a select field's options
+func hasOption(field models.CustomField, choice string) bool {
+	for _, option := range field.Options {
+		if option == choice {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package fields
+
+import (
+	"encoding/json"
+	"math"
+	"saas-server/models"
+)
+
+// aggregate accumulates the values of one rollup's source field over a set of nodes
+type aggregate struct {
+	nodes   int     // Nodes aggregated
+	values  int     // Nodes that had a number value
+	sum     float64 // Sum of number values
+	min     float64
+	max     float64
+	checked int // Nodes with the checkbox ticked
+}
+
+// add merges another aggregate into a
+func (a *aggregate) add(b aggregate) {
+	if b.values > 0 {
+		if a.values == 0 || b.min < a.min {
+			a.min = b.min
+		}
+		if a.values == 0 || b.max > a.max {
+			a.max = b.max
+		}
+	}
+	a.nodes += b.nodes
+	a.values += b.values
+	a.sum += b.sum
+	a.checked += b.checked
+}
+
+// result computes the rollup function over the aggregate, reporting false when there is
+// nothing to compute, such as an average without values
+func (a aggregate) result(function string) (float64, bool) {
+	if a.nodes == 0 {
+		return 0, false
+	}
+	switch function {
+	case models.RollupCount:
+		return float64(a.nodes), true
+	case models.RollupSum:
+		return a.sum, true
+	case models.RollupAverage:
+		if a.values == 0 {
+			return 0, false
+		}
+		return a.sum / float64(a.values), true
+	case models.RollupMin:
+		return a.min, a.values > 0
+	case models.RollupMax:
+		return a.max, a.values > 0
+	case models.RollupPercentChecked:
+		return math.Round(float64(a.checked)*10000/float64(a.nodes)) / 100, true
+	}
+	return 0, false
+}
+
+// HasRollups reports whether a schema defines any rollup fields
+func HasRollups(schema []models.CustomField) bool {
+	for _, field := range schema {
+		if field.Type == models.CustomFieldRollup && field.Rollup != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyRollups computes the schema's rollup fields for every parent node and stores them in
+// the node's Rollups. Nodes without children get no rollups
+func ApplyRollups(schema []models.CustomField, nodes []models.Node) {
+	var rollups []models.CustomField
+	for _, field := range schema {
+		if field.Type == models.CustomFieldRollup && field.Rollup != nil {
+			rollups = append(rollups, field)
+		}
+	}
+	if len(rollups) == 0 || len(nodes) == 0 {
+		return
+	}
+
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node.ID] = i
+	}
+	children := make(map[int][]int)
+	var roots []int
+	for i, node := range nodes {
+		parent, ok := -1, false
+		if node.ParentID != nil && *node.ParentID != node.ID {
+			parent, ok = index[*node.ParentID]
+		}
+		if ok {
+			children[parent] = append(children[parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	// own holds each node's own contribution to every rollup
+	own := make([][]aggregate, len(nodes))
+	for i, node := range nodes {
+		values := nodeFieldValues(node)
+		own[i] = make([]aggregate, len(rollups))
+		for r, field := range rollups {
+			own[i][r] = ownAggregate(values[field.Rollup.Field], field.Rollup.Function)
+		}
+	}
+
+	// Walk each tree bottom-up, accumulating subtree aggregates. Nodes on a parent cycle
+	// are never reached from a root and get no rollups
+	subtree := make([][]aggregate, len(nodes))
+	visited := make([]bool, len(nodes))
+	var walk func(i int)
+	walk = func(i int) {
+		visited[i] = true
+		direct := make([]aggregate, len(rollups))
+		below := make([]aggregate, len(rollups))
+		for _, child := range children[i] {
+			if visited[child] {
+				continue
+			}
+			walk(child)
+			for r := range rollups {
+				direct[r].add(own[child][r])
+				below[r].add(subtree[child][r])
+			}
+		}
+
+		subtree[i] = make([]aggregate, len(rollups))
+		for r := range rollups {
+			subtree[i][r] = own[i][r]
+			subtree[i][r].add(below[r])
+		}
+
+		if len(children[i]) == 0 {
+			return
+		}
+		for r, field := range rollups {
+			source := direct[r]
+			if field.Rollup.Scope == models.RollupScopeDescendants {
+				source = below[r]
+			}
+			if value, ok := source.result(field.Rollup.Function); ok {
+				if nodes[i].Rollups == nil {
+					nodes[i].Rollups = make(map[string]float64, len(rollups))
+				}
+				nodes[i].Rollups[field.Name] = value
+			}
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+}
+
+// ownAggregate is a single node's contribution to a rollup
+func ownAggregate(value json.RawMessage, function string) aggregate {
+	a := aggregate{nodes: 1}
+	switch function {
+	case models.RollupPercentChecked:
+		var checked bool
+		if json.Unmarshal(value, &checked) == nil && checked {
+			a.checked = 1
+		}
+	case models.RollupSum, models.RollupAverage, models.RollupMin, models.RollupMax:
+		var number float64
+		if len(value) > 0 && json.Unmarshal(value, &number) == nil {
+			a.values, a.sum, a.min, a.max = 1, number, number, number
+		}
+	}
+	return a
+}
+
+// nodeFieldValues returns the custom field values stored in a node's metadata
+func nodeFieldValues(node models.Node) map[string]json.RawMessage {
+	var metadata struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if json.Unmarshal(node.Metadata, &metadata) != nil {
+		return nil
+	}
+	return metadata.Fields
+}
@@ -0,0 +1,309 @@
+// Package filter parses and evaluates the smart filter query language used by saved filters.
+//
+// A query is one or more clauses joined by AND, for example:
+//
+//	tag=risk AND type=task AND due<30d
+//
+// Supported fields and operators:
+//
+//	tag      =, !=           node has / lacks the tag
+//	type     =, !=           node type
+//	status   =, !=           "status" metadata field
+//	text     =, !=, ~        content equals / contains, ignoring case
+//	map      =, !=           mind map ID
+//	votes    =, !=, <, <=, >, >=
+//	priority =, !=, <, <=, >, >= low < medium < high < urgent; "none" for no priority
+//	icon     =, !=           node icon emoji; "none" for no icon
+//	due      =, <, <=, >, >= "due" metadata date; nodes without one never match
+//	created  =, <, <=, >, >= creation time
+//	updated  =, <, <=, >, >= last update time
+//
+// Dates are YYYY-MM-DD or RFC 3339 timestamps, or relative durations such as 30d, 2w or 12h.
+// A relative due date counts forward from now, so due<30d means due within 30 days;
+// relative created and updated times count backward, so updated>7d means changed this week.
+// Values containing spaces can be quoted with double quotes
+package filter
+
+import (
+	"fmt"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxClauses caps how many clauses a single query may contain
+const maxClauses = 20
+
+// Clause is a single field comparison
+type Clause struct {
+	Field string
+	Op    string
+	Value string
+
+	number float64
+	time   time.Time
+}
+
+// Query is a parsed filter; a node matches when every clause matches
+type Query struct {
+	Clauses []Clause
+}
+
+// fieldOps lists the operators each field accepts
+var fieldOps = map[string][]string{
+	"tag":      {"=", "!="},
+	"type":     {"=", "!="},
+	"status":   {"=", "!="},
+	"text":     {"=", "!=", "~"},
+	"map":      {"=", "!="},
+	"votes":    {"=", "!=", "<", "<=", ">", ">="},
+	"priority": {"=", "!=", "<", "<=", ">", ">="},
+	"icon":     {"=", "!="},
+	"due":      {"=", "<", "<=", ">", ">="},
+	"created":  {"=", "<", "<=", ">", ">="},
+	"updated":  {"=", "<", "<=", ">", ">="},
+}
+
+// operators is ordered so two-character operators are tried before their prefixes
+var operators = []string{"!=", "<=", ">=", "=", "<", ">", "~"}
+
+// Parse parses a query. Relative dates are resolved against now
+func Parse(input string, now time.Time) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	query := &Query{}
+	for i, token := range tokens {
+		if i%2 == 1 {
+			if !strings.EqualFold(token, "AND") {
+				return nil, fmt.Errorf("expected AND before %q", token)
+			}
+			continue
+		}
+		clause, err := parseClause(token, now)
+		if err != nil {
+			return nil, err
+		}
+		query.Clauses = append(query.Clauses, clause)
+	}
+	if len(tokens)%2 == 0 {
+		return nil, fmt.Errorf("query cannot end with AND")
+	}
+	if len(query.Clauses) > maxClauses {
+		return nil, fmt.Errorf("query cannot contain more than %d clauses", maxClauses)
+	}
+	return query, nil
+}
+
+// tokenize splits a query on whitespace outside double quotes, dropping the quotes
+func tokenize(input string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, inToken := false, false
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inToken = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// parseClause parses a single field comparison such as due<30d
+func parseClause(token string, now time.Time) (Clause, error) {
+	// The operator is the first one found in the token, so values may contain operator characters
+	var clause Clause
+	if i := strings.IndexAny(token, "!<>=~"); i > 0 {
+		for _, op := range operators {
+			if strings.HasPrefix(token[i:], op) {
+				clause = Clause{Field: strings.ToLower(token[:i]), Op: op, Value: token[i+len(op):]}
+				break
+			}
+		}
+	}
+	if clause.Op == "" {
+		return clause, fmt.Errorf("clause %q needs a field, an operator and a value", token)
+	}
+
+	ops, ok := fieldOps[clause.Field]
+	if !ok {
+		return clause, fmt.Errorf("unknown field %q", clause.Field)
+	}
+	if !containsString(ops, clause.Op) {
+		return clause, fmt.Errorf("field %s does not support %s", clause.Field, clause.Op)
+	}
+	if clause.Value == "" {
+		return clause, fmt.Errorf("clause %q has no value", token)
+	}
+
+	switch clause.Field {
+	case "votes":
+		number, err := strconv.ParseFloat(clause.Value, 64)
+		if err != nil {
+			return clause, fmt.Errorf("votes must be compared with a number")
+		}
+		clause.number = number
+	case "priority":
+		value := strings.ToLower(clause.Value)
+		if value == "none" {
+			value = ""
+		}
+		rank := models.NodePriorityRank(value)
+		if rank < 0 {
+			return clause, fmt.Errorf("priority must be low, medium, high, urgent or none")
+		}
+		clause.number = float64(rank)
+	case "due", "created", "updated":
+		// Relative due dates look ahead, relative creation and update times look back
+		direction := time.Duration(1)
+		if clause.Field != "due" {
+			direction = -1
+		}
+		t, err := parseTime(clause.Value, now, direction)
+		if err != nil {
+			return clause, fmt.Errorf("%s: %v", clause.Field, err)
+		}
+		clause.time = t
+	}
+	return clause, nil
+}
+
+// parseTime parses an absolute date or a duration relative to now
+func parseTime(value string, now time.Time, direction time.Duration) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(value) >= 2 {
+		if unit, ok := units[value[len(value)-1]]; ok {
+			if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+				return now.Add(direction * time.Duration(n) * unit), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD, RFC 3339 or a duration like 30d", value)
+}
+
+// Match reports whether a node satisfies every clause of the query
+func (q *Query) Match(node models.Node) bool {
+	for _, clause := range q.Clauses {
+		if !clause.match(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// match evaluates one clause against a node
+func (c Clause) match(node models.Node) bool {
+	switch c.Field {
+	case "tag":
+		has := false
+		for _, tag := range outline.Tags(node) {
+			if strings.EqualFold(tag, c.Value) {
+				has = true
+				break
+			}
+		}
+		return has == (c.Op == "=")
+	case "type":
+		return strings.EqualFold(node.NodeType, c.Value) == (c.Op == "=")
+	case "status":
+		return strings.EqualFold(outline.MetadataString(node, "status"), c.Value) == (c.Op == "=")
+	case "text":
+		if c.Op == "~" {
+			return strings.Contains(strings.ToLower(node.Content), strings.ToLower(c.Value))
+		}
+		return strings.EqualFold(node.Content, c.Value) == (c.Op == "=")
+	case "map":
+		return (node.MindMapID == c.Value) == (c.Op == "=")
+	case "votes":
+		return compare(float64(node.VoteCount)-c.number, c.Op)
+	case "priority":
+		return compare(float64(models.NodePriorityRank(node.Priority))-c.number, c.Op)
+	case "icon":
+		icon := c.Value
+		if strings.EqualFold(icon, "none") {
+			icon = ""
+		}
+		return (node.Icon == icon) == (c.Op == "=")
+	case "due":
+		due, err := parseTime(outline.MetadataString(node, "due"), time.Time{}, 0)
+		if err != nil || due.IsZero() {
+			return false
+		}
+		return compareTime(due, c.time, c.Op)
+	case "created":
+		return compareTime(node.CreatedAt, c.time, c.Op)
+	case "updated":
+		return compareTime(node.UpdatedAt, c.time, c.Op)
+	}
+	return false
+}
+
+// compareTime compares two times; = matches the same calendar day
+func compareTime(a, b time.Time, op string) bool {
+	if op == "=" {
+		ay, am, ad := a.UTC().Date()
+		by, bm, bd := b.UTC().Date()
+		return ay == by && am == bm && ad == bd
+	}
+	return compare(float64(a.Sub(b)), op)
+}
+
+// compare applies an operator to the difference between two values
+func compare(diff float64, op string) bool {
+	switch op {
+	case "=":
+		return diff == 0
+	case "!=":
+		return diff != 0
+	case "<":
+		return diff < 0
+	case "<=":
+		return diff <= 0
+	case ">":
+		return diff > 0
+	case ">=":
+		return diff >= 0
+	}
+	return false
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+// Package graphclone assigns fresh IDs to nodes copied between mind maps and rewrites every
+// reference to them, in parent IDs, edges, links in content and IDs inside metadata, so
+// copies never point back at the originals
+package graphclone
+
+import (
+	"bytes"
+	"encoding/json"
+	"saas-server/pkg/links"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Metadata fields a link node keeps its target in
+const (
+	targetMindMapField = "target_mind_map_id"
+	targetNodeField    = "target_node_id"
+)
+
+// Remap translates the IDs of copied nodes to the IDs of their copies
+type Remap struct {
+	sourceMapID string
+	targetMapID string
+	wholeMap    bool
+	nodes       map[string]string
+}
+
+// ForMap prepares the copy of a whole mind map. References to the source map itself are
+// redirected to the target map as well as those to its nodes
+func ForMap(sourceMapID, targetMapID string, nodeIDs []string) *Remap {
+	r := ForNodes(sourceMapID, targetMapID, nodeIDs)
+	r.wholeMap = true
+	return r
+}
+
+// ForNodes prepares the copy of some of a map's nodes into the target map. Only references
+// to the copied nodes change; links to the source map or its other nodes are kept
+func ForNodes(sourceMapID, targetMapID string, nodeIDs []string) *Remap {
+	r := &Remap{
+		sourceMapID: strings.ToLower(sourceMapID),
+		targetMapID: targetMapID,
+		nodes:       make(map[string]string, len(nodeIDs)),
+	}
+	for _, id := range nodeIDs {
+		r.nodes[id] = uuid.New().String()
+	}
+	return r
+}
+
+// WithIDs prepares a whole-map copy whose nodes already have new IDs, given by their
+// original IDs, such as to check a copy made earlier against its source
+func WithIDs(sourceMapID, targetMapID string, ids map[string]string) *Remap {
+	return &Remap{
+		sourceMapID: strings.ToLower(sourceMapID),
+		targetMapID: targetMapID,
+		wholeMap:    true,
+		nodes:       ids,
+	}
+}
+
+// IDs returns the new ID of every copied node by its original ID. The map must not be
+// modified
+func (r *Remap) IDs() map[string]string {
+	return r.nodes
+}
+
+// NodeID returns the new ID of a copied node, and whether the node is being copied
+func (r *Remap) NodeID(id string) (string, bool) {
+	if newID, ok := r.nodes[id]; ok {
+		return newID, true
+	}
+	newID, ok := r.nodes[strings.ToLower(id)]
+	return newID, ok
+}
+
+// ParentID returns the new parent of a copied node, or nil when it had none or its parent
+// is not being copied
+func (r *Remap) ParentID(parentID *string) *string {
+	if parentID == nil {
+		return nil
+	}
+	newID, ok := r.NodeID(*parentID)
+	if !ok {
+		return nil
+	}
+	return &newID
+}
+
+// Edge returns the new endpoints of an edge, and false when either end is not being copied
+func (r *Remap) Edge(sourceID, targetID string) (string, string, bool) {
+	newSource, ok := r.NodeID(sourceID)
+	if !ok {
+		return "", "", false
+	}
+	newTarget, ok := r.NodeID(targetID)
+	if !ok {
+		return "", "", false
+	}
+	return newSource, newTarget, true
+}
+
+// Reference redirects a reference to a copied node to its copy in the target map, whatever
+// map the reference names, since node IDs are unique. With a whole-map copy a reference to
+// the source map itself moves to the target map
+func (r *Remap) Reference(ref links.Reference) links.Reference {
+	if newID, ok := r.NodeID(ref.NodeID); ok && ref.NodeID != "" {
+		return links.Reference{MindMapID: r.targetMapID, NodeID: newID}
+	}
+	if r.wholeMap && ref.NodeID == "" && r.isSourceMap(ref.MindMapID) {
+		return links.Reference{MindMapID: r.targetMapID}
+	}
+	return ref
+}
+
+// Content rewrites the links in node content
+func (r *Remap) Content(content string) string {
+	return links.Rewrite(content, r.Reference)
+}
+
+// Metadata rewrites the references anywhere in a node's metadata or style data: link
+// targets, strings that are the ID of a copied node or, for a whole-map copy, of the source
+// map, and links inside text. Data that is not valid JSON or has no references is returned
+// unchanged
+func (r *Remap) Metadata(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return data
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return data
+	}
+
+	value, changed := r.rewriteValue(value)
+	if !changed {
+		return data
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return data
+	}
+	return json.RawMessage(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// rewriteValue rewrites the references in a decoded JSON value, reporting whether any changed
+func (r *Remap) rewriteValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		changed := false
+		// A link target names a map and a node together, so they are rewritten as a pair
+		mapID, hasMap := v[targetMindMapField].(string)
+		nodeID, hasNode := v[targetNodeField].(string)
+		if hasMap || hasNode {
+			ref := r.Reference(links.Reference{MindMapID: mapID, NodeID: nodeID})
+			if hasMap && ref.MindMapID != mapID {
+				v[targetMindMapField] = ref.MindMapID
+				changed = true
+			}
+			if hasNode && ref.NodeID != nodeID {
+				v[targetNodeField] = ref.NodeID
+				changed = true
+			}
+		}
+		for key, item := range v {
+			if (key == targetMindMapField && hasMap) || (key == targetNodeField && hasNode) {
+				continue
+			}
+			if rewritten, itemChanged := r.rewriteValue(item); itemChanged {
+				v[key] = rewritten
+				changed = true
+			}
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, item := range v {
+			if rewritten, itemChanged := r.rewriteValue(item); itemChanged {
+				v[i] = rewritten
+				changed = true
+			}
+		}
+		return v, changed
+	case string:
+		rewritten := r.rewriteString(v)
+		return rewritten, rewritten != v
+	}
+	return value, false
+}
+
+// rewriteString rewrites a string that is an ID, or the links inside one that is text
+func (r *Remap) rewriteString(s string) string {
+	if _, err := uuid.Parse(s); err == nil && len(s) == 36 {
+		if newID, ok := r.NodeID(s); ok {
+			return newID
+		}
+		if r.wholeMap && r.isSourceMap(s) {
+			return r.targetMapID
+		}
+		return s
+	}
+	return r.Content(s)
+}
+
+// isSourceMap reports whether id names the source map
+func (r *Remap) isSourceMap(id string) bool {
+	return r.sourceMapID != "" && strings.ToLower(id) == r.sourceMapID
+}
@@ -0,0 +1,289 @@
+package graphclone
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// IDs used by the tests. Nodes A and B are copied, C is a node of the source map left out
+// of the copy and Foreign is a node of another map
+const (
+	sourceMap   = "11111111-1111-4111-8111-111111111111"
+	targetMap   = "22222222-2222-4222-8222-222222222222"
+	otherMap    = "33333333-3333-4333-8333-333333333333"
+	nodeA       = "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"
+	nodeB       = "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"
+	nodeC       = "cccccccc-cccc-4ccc-8ccc-cccccccccccc"
+	nodeForeign = "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+)
+
+// expand fills in the placeholders of a test case: {src}, {tgt} and {other} for maps, {a},
+// {b}, {c} and {foreign} for the original nodes and {a'} and {b'} for the copies of A and B
+func expand(s string, r *Remap) string {
+	ids := r.IDs()
+	return strings.NewReplacer(
+		"{src}", sourceMap,
+		"{tgt}", targetMap,
+		"{other}", otherMap,
+		"{a}", nodeA,
+		"{b}", nodeB,
+		"{c}", nodeC,
+		"{foreign}", nodeForeign,
+		"{a'}", ids[nodeA],
+		"{b'}", ids[nodeB],
+	).Replace(s)
+}
+
+// remaps returns a whole-map and a partial copy of nodes A and B
+func remaps() map[string]*Remap {
+	return map[string]*Remap{
+		"ForMap":   ForMap(sourceMap, targetMap, []string{nodeA, nodeB}),
+		"ForNodes": ForNodes(sourceMap, targetMap, []string{nodeA, nodeB}),
+	}
+}
+
+func TestNewIDs(t *testing.T) {
+	r := ForNodes(sourceMap, targetMap, []string{nodeA, nodeB})
+	ids := r.IDs()
+	if len(ids) != 2 {
+		t.Fatalf("IDs() has %d entries, want 2", len(ids))
+	}
+	if ids[nodeA] == "" || ids[nodeA] == nodeA || ids[nodeA] == ids[nodeB] {
+		t.Fatalf("copies of A and B got IDs %q and %q", ids[nodeA], ids[nodeB])
+	}
+	if got, ok := r.NodeID(strings.ToUpper(nodeA)); !ok || got != ids[nodeA] {
+		t.Errorf("NodeID(upper case A) = %q, %v, want %q, true", got, ok, ids[nodeA])
+	}
+	if _, ok := r.NodeID(nodeC); ok {
+		t.Errorf("NodeID(C) reported a node that is not copied")
+	}
+}
+
+func TestWithIDs(t *testing.T) {
+	copied := ForMap(sourceMap, targetMap, []string{nodeA, nodeB})
+	content := expand("[A](/mindmaps/{src}?node={a}) in /mindmaps/{src}", copied)
+	metadata := json.RawMessage(expand(`{"target_mind_map_id":"{src}","target_node_id":"{b}"}`, copied))
+
+	// Rebuilt from the IDs of a copy, a remap rewrites references the same way
+	r := WithIDs(sourceMap, targetMap, copied.IDs())
+	if got, want := r.Content(content), copied.Content(content); got != want {
+		t.Errorf("Content() = %q, want %q", got, want)
+	}
+	assertJSONEqual(t, r.Metadata(metadata), string(copied.Metadata(metadata)))
+}
+
+func TestParentID(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent *string
+		want   string // Expanded; empty for nil
+	}{
+		{"no parent", nil, ""},
+		{"copied parent", strPtr(nodeA), "{a'}"},
+		{"parent left out of the copy", strPtr(nodeC), ""},
+		{"foreign parent", strPtr(nodeForeign), ""},
+	}
+	for name, r := range remaps() {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				got := r.ParentID(tt.parent)
+				want := expand(tt.want, r)
+				switch {
+				case want == "" && got != nil:
+					t.Errorf("ParentID() = %q, want nil", *got)
+				case want != "" && (got == nil || *got != want):
+					t.Errorf("ParentID() = %v, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestEdge(t *testing.T) {
+	tests := []struct {
+		name             string
+		source, target   string
+		wantSource, want string
+		ok               bool
+	}{
+		{"both ends copied", nodeA, nodeB, "{a'}", "{b'}", true},
+		{"target left out", nodeA, nodeC, "", "", false},
+		{"source left out", nodeC, nodeB, "", "", false},
+		{"foreign ends", nodeForeign, nodeC, "", "", false},
+	}
+	for name, r := range remaps() {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				source, target, ok := r.Edge(tt.source, tt.target)
+				if ok != tt.ok || source != expand(tt.wantSource, r) || target != expand(tt.want, r) {
+					t.Errorf("Edge() = %q, %q, %v, want %q, %q, %v",
+						source, target, ok, expand(tt.wantSource, r), expand(tt.want, r), tt.ok)
+				}
+			})
+		}
+	}
+}
+
+func TestContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		forMap   string // Expected of a whole-map copy
+		forNodes string // Expected of a partial copy
+	}{
+		{
+			"markdown link to a copied node",
+			"See [A](/mindmaps/{src}?node={a}) first",
+			"See [A](/mindmaps/{tgt}?node={a'}) first",
+			"See [A](/mindmaps/{tgt}?node={a'}) first",
+		},
+		{
+			"node path and fragment links",
+			"/mindmaps/{src}/nodes/{b} and https://app.example/mindmaps/{src}#{a}",
+			"/mindmaps/{tgt}/nodes/{b'} and https://app.example/mindmaps/{tgt}#{a'}",
+			"/mindmaps/{tgt}/nodes/{b'} and https://app.example/mindmaps/{tgt}#{a'}",
+		},
+		{
+			"copied node named under another map",
+			"[moved](/mindmaps/{other}?node={a})",
+			"[moved](/mindmaps/{tgt}?node={a'})",
+			"[moved](/mindmaps/{tgt}?node={a'})",
+		},
+		{
+			"self-map link",
+			"Back to [the map](/mindmaps/{src})",
+			"Back to [the map](/mindmaps/{tgt})",
+			"Back to [the map](/mindmaps/{src})",
+		},
+		{
+			"node left out of the copy",
+			"[C](/mindmaps/{src}?node={c})",
+			"[C](/mindmaps/{src}?node={c})",
+			"[C](/mindmaps/{src}?node={c})",
+		},
+		{
+			"foreign map and node",
+			"[elsewhere](/mindmaps/{other}?node={foreign}) and /mindmaps/{other}",
+			"[elsewhere](/mindmaps/{other}?node={foreign}) and /mindmaps/{other}",
+			"[elsewhere](/mindmaps/{other}?node={foreign}) and /mindmaps/{other}",
+		},
+		{
+			"bare IDs in text are not links",
+			"{a} and {src}",
+			"{a} and {src}",
+			"{a} and {src}",
+		},
+	}
+	for name, r := range remaps() {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				want := tt.forNodes
+				if name == "ForMap" {
+					want = tt.forMap
+				}
+				if got := r.Content(expand(tt.content, r)); got != expand(want, r) {
+					t.Errorf("Content() = %q, want %q", got, expand(want, r))
+				}
+			})
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		forMap   string // Expected of a whole-map copy
+		forNodes string // Expected of a partial copy
+	}{
+		{
+			"link target to a copied node",
+			`{"target_mind_map_id":"{src}","target_node_id":"{a}","url":"/mindmaps/{src}?node={a}"}`,
+			`{"target_mind_map_id":"{tgt}","target_node_id":"{a'}","url":"/mindmaps/{tgt}?node={a'}"}`,
+			`{"target_mind_map_id":"{tgt}","target_node_id":"{a'}","url":"/mindmaps/{tgt}?node={a'}"}`,
+		},
+		{
+			"link target to the source map",
+			`{"target_mind_map_id":"{src}"}`,
+			`{"target_mind_map_id":"{tgt}"}`,
+			`{"target_mind_map_id":"{src}"}`,
+		},
+		{
+			"link target to a node left out of the copy",
+			`{"target_mind_map_id":"{src}","target_node_id":"{c}"}`,
+			`{"target_mind_map_id":"{src}","target_node_id":"{c}"}`,
+			`{"target_mind_map_id":"{src}","target_node_id":"{c}"}`,
+		},
+		{
+			"IDs in objects inside arrays inside objects",
+			`{"groups":[{"members":["{a}","{foreign}"],"lead":{"node":"{b}","map":"{src}"}},{"note":"see /mindmaps/{src}?node={b}","count":3}]}`,
+			`{"groups":[{"members":["{a'}","{foreign}"],"lead":{"node":"{b'}","map":"{tgt}"}},{"note":"see /mindmaps/{tgt}?node={b'}","count":3}]}`,
+			`{"groups":[{"members":["{a'}","{foreign}"],"lead":{"node":"{b'}","map":"{src}"}},{"note":"see /mindmaps/{tgt}?node={b'}","count":3}]}`,
+		},
+		{
+			"style data with a nested reference",
+			`{"color":"#fff","anchors":[{"attach_to":"{a}","offset":[1.5,-2]}]}`,
+			`{"color":"#fff","anchors":[{"attach_to":"{a'}","offset":[1.5,-2]}]}`,
+			`{"color":"#fff","anchors":[{"attach_to":"{a'}","offset":[1.5,-2]}]}`,
+		},
+		{
+			"foreign IDs only",
+			`{"source":"{foreign}","maps":["{other}"],"nested":{"node":"{c}"}}`,
+			`{"source":"{foreign}","maps":["{other}"],"nested":{"node":"{c}"}}`,
+			`{"source":"{foreign}","maps":["{other}"],"nested":{"node":"{c}"}}`,
+		},
+	}
+	for name, r := range remaps() {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				want := tt.forNodes
+				if name == "ForMap" {
+					want = tt.forMap
+				}
+				got := r.Metadata(json.RawMessage(expand(tt.data, r)))
+				assertJSONEqual(t, got, expand(want, r))
+			})
+		}
+	}
+}
+
+func TestMetadataUnchanged(t *testing.T) {
+	r := ForMap(sourceMap, targetMap, []string{nodeA, nodeB})
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"invalid JSON", `{"node":"` + nodeA},
+		{"no references", `{"big":12345678901234567890,"html":"<b>&</b>","foreign":"` + nodeForeign + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Metadata(json.RawMessage(tt.data)); !bytes.Equal(got, []byte(tt.data)) {
+				t.Errorf("Metadata() = %s, want it unchanged", got)
+			}
+		})
+	}
+}
+
+// assertJSONEqual fails the test unless got and want hold the same JSON value
+func assertJSONEqual(t *testing.T, got json.RawMessage, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("Metadata() returned invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid expected JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("Metadata() = %s, want %s", got, want)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,149 @@
+// Package inbound turns incoming emails and webhook payloads into mind map nodes
+package inbound
+
+import (
+	"net/mail"
+	"regexp"
+	"saas-server/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on what a single message may create
+const (
+	MaxItems         = 200
+	maxContentLength = 5000
+	maxTitleLength   = 200
+)
+
+// Item is a node parsed from a message, nested Depth levels below the message's own node
+type Item struct {
+	Content string
+	Depth   int
+}
+
+var (
+	// bulletPattern matches list markers such as "-", "*", "•" or "1." at the start of a line
+	bulletPattern = regexp.MustCompile(`^(?:[-*+•]|\d+[.)])\s+`)
+	// replyHeaderPattern matches the line mail clients put above a quoted reply
+	replyHeaderPattern = regexp.MustCompile(`^On .+ wrote:$`)
+	// subjectPrefixPattern matches reply and forward prefixes of a subject
+	subjectPrefixPattern = regexp.MustCompile(`(?i)^\s*(?:re|fwd?|aw|wg)\s*:\s*`)
+)
+
+// ValidParseMode reports whether mode is one of the supported parse modes
+func ValidParseMode(mode string) bool {
+	return mode == models.InboundParseLines || mode == models.InboundParseSingle
+}
+
+// Parse applies the parsing rules to a message body. Quoted replies and everything after
+// the signature separator are dropped. In lines mode every remaining line becomes a node,
+// with list markers removed and indentation nesting it under the line above; in single
+// mode the whole body becomes one node
+func Parse(text, mode string) []Item {
+	lines := body(text)
+	if mode == models.InboundParseSingle {
+		content := truncate(strings.TrimSpace(strings.Join(lines, "\n")), maxContentLength)
+		if content == "" {
+			return nil
+		}
+		return []Item{{Content: content}}
+	}
+
+	var items []Item
+	for _, line := range lines {
+		content := strings.TrimSpace(bulletPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+		if content == "" {
+			continue
+		}
+
+		// A line can be nested at most one level deeper than the one before it
+		depth := indentation(line)
+		if len(items) == 0 {
+			depth = 0
+		} else if depth > items[len(items)-1].Depth+1 {
+			depth = items[len(items)-1].Depth + 1
+		}
+		items = append(items, Item{Content: truncate(content, maxContentLength), Depth: depth})
+		if len(items) == MaxItems {
+			break
+		}
+	}
+	return items
+}
+
+// body returns the lines of a message written by the sender, without the signature and
+// quoted replies
+func body(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || trimmed == "--" || strings.HasPrefix(trimmed, "Sent from my ") {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") || replyHeaderPattern.MatchString(trimmed) {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return lines
+}
+
+// indentation returns the nesting level of a line: one level per tab or two spaces
+func indentation(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 2
+		default:
+			return width / 2
+		}
+	}
+	return width / 2
+}
+
+// Title returns the map or branch title for a message subject, without reply and forward
+// prefixes, falling back to the date the message arrived
+func Title(subject string, received time.Time) string {
+	title := strings.TrimSpace(subject)
+	for subjectPrefixPattern.MatchString(title) {
+		title = subjectPrefixPattern.ReplaceAllString(title, "")
+	}
+	if title == "" {
+		return "Inbox " + received.Format("2006-01-02")
+	}
+	return truncate(title, maxTitleLength)
+}
+
+// SenderAllowed reports whether the From header of a message names the account email or
+// one of the allowed senders. An allowed sender starting with "@" admits a whole domain
+func SenderAllowed(from, accountEmail string, allowed []string) bool {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return false
+	}
+	sender := strings.ToLower(address.Address)
+	if sender == strings.ToLower(accountEmail) {
+		return true
+	}
+	for _, entry := range allowed {
+		entry = strings.ToLower(entry)
+		if sender == entry || (strings.HasPrefix(entry, "@") && strings.HasSuffix(sender, entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,148 @@
+package layout
+
+import (
+	"math"
+	"saas-server/models"
+	"sort"
+)
+
+// Edge bundling of cross-links, the edges that do not join a node to its parent
+const (
+	// MinBundledCrossLinks is how many cross-links a map needs before they are bundled;
+	// sparser maps stay readable with straight lines
+	MinBundledCrossLinks = 20
+	// bundleMinCosine is the cosine of the largest angle between edges of a bundle
+	bundleMinCosine = 0.87
+	// bundleMaxDistance is the largest distance between the midpoints of an edge and its
+	// bundle, in canvas pixels
+	bundleMaxDistance = 200
+	// bundleMinLengthRatio is the smallest ratio of the lengths of an edge and its bundle
+	bundleMinLengthRatio = 0.5
+)
+
+// segment is an edge between the centers of its nodes, oriented along its bundle
+type segment struct {
+	index          int
+	x1, y1, x2, y2 float64
+}
+
+func (s segment) length() float64 {
+	return math.Hypot(s.x2-s.x1, s.y2-s.y1)
+}
+
+// bundle is a group of segments with the sum of their oriented endpoints
+type bundle struct {
+	members                    []int
+	sumX1, sumY1, sumX2, sumY2 float64
+}
+
+// line returns the average line of a bundle's segments
+func (b *bundle) line() segment {
+	n := float64(len(b.members))
+	return segment{x1: b.sumX1 / n, y1: b.sumY1 / n, x2: b.sumX2 / n, y2: b.sumY2 / n}
+}
+
+// Bundles groups the cross-links of a map that run side by side: in about the same
+// direction, with nearby midpoints and similar lengths. Longer edges are placed first and
+// each edge joins the first bundle whose average line it is compatible with. Each bundle
+// of two or more edges gets control points a third of the way along its average line from
+// either end. Maps with fewer than MinBundledCrossLinks cross-links get no bundles
+func Bundles(nodes []models.Node, edges []models.Edge) []models.EdgeBundle {
+	bundles := []models.EdgeBundle{}
+
+	parents := make(map[string]string, len(nodes))
+	centers := make(map[string]models.CanvasPoint, len(nodes))
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			parents[node.ID] = *node.ParentID
+		}
+		centers[node.ID] = models.CanvasPoint{X: node.PositionX + NodeWidth/2, Y: node.PositionY + NodeHeight/2}
+	}
+
+	var segments []segment
+	for i, edge := range edges {
+		if parents[edge.TargetID] == edge.SourceID || parents[edge.SourceID] == edge.TargetID {
+			continue
+		}
+		source, ok := centers[edge.SourceID]
+		if !ok {
+			continue
+		}
+		target, ok := centers[edge.TargetID]
+		if !ok {
+			continue
+		}
+		s := segment{index: i, x1: source.X, y1: source.Y, x2: target.X, y2: target.Y}
+		if s.length() > 0 {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) < MinBundledCrossLinks {
+		return bundles
+	}
+	sort.SliceStable(segments, func(a, b int) bool { return segments[a].length() > segments[b].length() })
+
+	var groups []*bundle
+	for _, s := range segments {
+		var group *bundle
+		for _, candidate := range groups {
+			if oriented, ok := compatible(candidate.line(), s); ok {
+				group, s = candidate, oriented
+				break
+			}
+		}
+		if group == nil {
+			group = &bundle{}
+			groups = append(groups, group)
+		}
+		group.members = append(group.members, s.index)
+		group.sumX1 += s.x1
+		group.sumY1 += s.y1
+		group.sumX2 += s.x2
+		group.sumY2 += s.y2
+	}
+
+	// List bundles, and the edges of each, in the order of the map's edges
+	for _, group := range groups {
+		sort.Ints(group.members)
+	}
+	sort.Slice(groups, func(a, b int) bool { return groups[a].members[0] < groups[b].members[0] })
+	for _, group := range groups {
+		if len(group.members) < 2 {
+			continue
+		}
+		line := group.line()
+		result := models.EdgeBundle{EdgeIDs: make([]string, len(group.members))}
+		for i, member := range group.members {
+			result.EdgeIDs[i] = edges[member].ID
+		}
+		for i, t := range []float64{1.0 / 3, 2.0 / 3} {
+			result.Controls[i] = models.CanvasPoint{
+				X: math.Round(line.x1 + (line.x2-line.x1)*t),
+				Y: math.Round(line.y1 + (line.y2-line.y1)*t),
+			}
+		}
+		bundles = append(bundles, result)
+	}
+
+	return bundles
+}
+
+// compatible reports whether a segment can join a bundle with the given average line,
+// returning it turned to run the same way as the line
+func compatible(line, s segment) (segment, bool) {
+	lineLength, length := line.length(), s.length()
+	if lineLength == 0 {
+		return s, false
+	}
+	cosine := ((line.x2-line.x1)*(s.x2-s.x1) + (line.y2-line.y1)*(s.y2-s.y1)) / (lineLength * length)
+	if cosine < 0 {
+		s.x1, s.y1, s.x2, s.y2 = s.x2, s.y2, s.x1, s.y1
+		cosine = -cosine
+	}
+	if cosine < bundleMinCosine || math.Min(lineLength, length)/math.Max(lineLength, length) < bundleMinLengthRatio {
+		return s, false
+	}
+	distance := math.Hypot((line.x1+line.x2-s.x1-s.x2)/2, (line.y1+line.y2-s.y1-s.y2)/2)
+	return s, distance <= bundleMaxDistance
+}
@@ -0,0 +1,112 @@
+package layout
+
+import (
+	"math"
+	"saas-server/models"
+	"sort"
+)
+
+// separationSlack is added to each push so resolved pairs do not stay exactly touching,
+// which would let rounding bring them back into overlap
+const separationSlack = 1
+
+// maxDeoverlapPasses bounds the relaxation so dense clusters cannot loop forever
+const maxDeoverlapPasses = 1000
+
+// Deoverlap nudges apart nodes whose bounding boxes, grown by gap, overlap. Pinned nodes
+// stay where they are. Returns the updates for moved nodes and the number of overlaps left
+func Deoverlap(nodes []models.Node, gap float64) ([]models.NodePositionUpdateRequest, int) {
+	placed := boxes(nodes)
+	remaining := relax(placed, gap)
+	return moved(nodes, placed), remaining
+}
+
+// relax resolves overlaps between boxes in place. Each overlapping pair is treated as a
+// separation constraint and resolved along the axis that needs the smaller move, splitting
+// it between both boxes unless one is fixed, repeating until no constraint is violated or
+// the pass limit is reached. Returns the number of overlaps left
+func relax(placed []box, gap float64) int {
+	order := make([]int, len(placed))
+	for i := range order {
+		order[i] = i
+	}
+
+	width, height := NodeWidth+gap, NodeHeight+gap
+	remaining := 0
+	for pass := 0; pass < maxDeoverlapPasses; pass++ {
+		// Sweep along x so only boxes that can still overlap horizontally are compared
+		sort.SliceStable(order, func(a, b int) bool { return placed[order[a]].x < placed[order[b]].x })
+
+		remaining = 0
+		for a := 0; a < len(order); a++ {
+			for b := a + 1; b < len(order); b++ {
+				i, j := order[a], order[b]
+				if placed[j].x-placed[i].x >= width {
+					break
+				}
+				overlapX := width - math.Abs(placed[j].x-placed[i].x)
+				overlapY := height - math.Abs(placed[j].y-placed[i].y)
+				if overlapX <= 0 || overlapY <= 0 || (placed[i].fixed && placed[j].fixed) {
+					continue
+				}
+				remaining++
+				separate(&placed[i], &placed[j], overlapX, overlapY)
+			}
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+
+	// Count what is left after the last pass moved things around
+	if remaining > 0 {
+		remaining = countOverlaps(placed, width, height)
+	}
+	return remaining
+}
+
+// separate pushes two overlapping boxes apart along the axis with the smaller overlap,
+// moving only the other box when one is fixed. Boxes at the same spot are split using
+// their IDs to stay deterministic
+func separate(a, b *box, overlapX, overlapY float64) {
+	horizontal := overlapX <= overlapY
+	overlap, delta := overlapY, b.y-a.y
+	if horizontal {
+		overlap, delta = overlapX, b.x-a.x
+	}
+	push := overlap + separationSlack
+	if delta < 0 || (delta == 0 && b.id < a.id) {
+		push = -push
+	}
+
+	// Share the push unless one side cannot move
+	moveA, moveB := -push/2, push/2
+	if a.fixed {
+		moveA, moveB = 0, push
+	} else if b.fixed {
+		moveA, moveB = -push, 0
+	}
+	if horizontal {
+		a.x += moveA
+		b.x += moveB
+	} else {
+		a.y += moveA
+		b.y += moveB
+	}
+}
+
+// countOverlaps counts the pairs of boxes that overlap, ignoring pairs of fixed boxes
+func countOverlaps(placed []box, width, height float64) int {
+	count := 0
+	for i := range placed {
+		for j := i + 1; j < len(placed); j++ {
+			if placed[i].fixed && placed[j].fixed {
+				continue
+			}
+			if math.Abs(placed[i].x-placed[j].x) < width && math.Abs(placed[i].y-placed[j].y) < height {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,365 @@
+package layout
+
+import (
+	"math"
+	"saas-server/models"
+	"sort"
+)
+
+// Spacing of the layered layout, in pixels
+const (
+	layeredLayerGap   = 100
+	layeredNodeGap    = 40
+	layeredDummyWidth = 20
+)
+
+// Passes of the layered layout's crossing reduction and coordinate assignment
+const (
+	layeredOrderingPasses   = 12
+	layeredCoordinatePasses = 8
+)
+
+// layeredGraph is the proper layered graph the layout works on: real nodes come first,
+// followed by dummy vertices that split edges spanning more than one layer
+type layeredGraph struct {
+	real   int
+	layer  []int
+	upper  [][]int // neighbours in the layer above
+	lower  [][]int // neighbours in the layer below
+	layers [][]int // vertices of each layer, in their current order
+}
+
+// Layered arranges the map top-down in layers, Sugiyama style, which suits org charts and
+// process flows. Edges and parent links point downwards after cycles are broken, each node
+// sits one layer below its lowest predecessor, layers are reordered to reduce crossings
+// and nodes are centered over their neighbours. The layout starts at the top-left corner
+// of the current map, and pinned nodes stay in place. Returns the updates for moved nodes
+// and the number of overlaps left
+func Layered(nodes []models.Node, edges []models.Edge) ([]models.NodePositionUpdateRequest, int) {
+	if len(nodes) == 0 {
+		return []models.NodePositionUpdateRequest{}, 0
+	}
+
+	graph := buildLayeredGraph(nodes, edges)
+	graph.order(nodes)
+	centers := graph.coordinates()
+
+	originX, originY := math.Inf(1), math.Inf(1)
+	for _, node := range nodes {
+		originX = math.Min(originX, node.PositionX)
+		originY = math.Min(originY, node.PositionY)
+	}
+	minCenter := math.Inf(1)
+	for v := 0; v < graph.real; v++ {
+		minCenter = math.Min(minCenter, centers[v])
+	}
+
+	placed := boxes(nodes)
+	for i := range placed {
+		if placed[i].fixed {
+			continue
+		}
+		placed[i].x = originX + centers[i] - minCenter
+		placed[i].y = originY + float64(graph.layer[i])*(NodeHeight+layeredLayerGap)
+	}
+	remaining := relax(placed, treeRelaxGap)
+	return moved(nodes, placed), remaining
+}
+
+// buildLayeredGraph collects the edges and parent links between nodes, reverses the ones
+// closing a cycle, assigns longest-path layers and splits long edges with dummy vertices
+func buildLayeredGraph(nodes []models.Node, edges []models.Edge) *layeredGraph {
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node.ID] = i
+	}
+
+	successors := make([][]int, len(nodes))
+	seen := make(map[[2]int]bool)
+	link := func(sourceID, targetID string) {
+		source, ok := index[sourceID]
+		if !ok {
+			return
+		}
+		target, ok := index[targetID]
+		if !ok || source == target || seen[[2]int{source, target}] {
+			return
+		}
+		seen[[2]int{source, target}] = true
+		successors[source] = append(successors[source], target)
+	}
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			link(*node.ParentID, node.ID)
+		}
+	}
+	for _, edge := range edges {
+		link(edge.SourceID, edge.TargetID)
+	}
+
+	// Break cycles by reversing the edges a depth-first search finds going back up its path
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, len(nodes))
+	var arcs [][2]int
+	var visit func(v int)
+	visit = func(v int) {
+		state[v] = active
+		for _, w := range successors[v] {
+			switch state[w] {
+			case active:
+				arcs = append(arcs, [2]int{w, v})
+			case unvisited:
+				arcs = append(arcs, [2]int{v, w})
+				visit(w)
+			default:
+				arcs = append(arcs, [2]int{v, w})
+			}
+		}
+		state[v] = done
+	}
+	for v := range nodes {
+		if state[v] == unvisited {
+			visit(v)
+		}
+	}
+
+	// Longest-path layering in topological order
+	indegree := make([]int, len(nodes))
+	outgoing := make([][]int, len(nodes))
+	kept := make(map[[2]int]bool, len(arcs))
+	for _, arc := range arcs {
+		// A reversed edge may duplicate one already going the same way
+		if kept[arc] {
+			continue
+		}
+		kept[arc] = true
+		outgoing[arc[0]] = append(outgoing[arc[0]], arc[1])
+		indegree[arc[1]]++
+	}
+	layer := make([]int, len(nodes))
+	queue := make([]int, 0, len(nodes))
+	for v := range nodes {
+		if indegree[v] == 0 {
+			queue = append(queue, v)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range outgoing[v] {
+			layer[w] = max(layer[w], layer[v]+1)
+			if indegree[w]--; indegree[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+
+	graph := &layeredGraph{
+		real:  len(nodes),
+		layer: layer,
+		upper: make([][]int, len(nodes)),
+		lower: make([][]int, len(nodes)),
+	}
+	addVertex := func(l int) int {
+		graph.layer = append(graph.layer, l)
+		graph.upper = append(graph.upper, nil)
+		graph.lower = append(graph.lower, nil)
+		return len(graph.layer) - 1
+	}
+	for v := range nodes {
+		for _, w := range outgoing[v] {
+			previous := v
+			for l := layer[v] + 1; l < layer[w]; l++ {
+				dummy := addVertex(l)
+				graph.lower[previous] = append(graph.lower[previous], dummy)
+				graph.upper[dummy] = append(graph.upper[dummy], previous)
+				previous = dummy
+			}
+			graph.lower[previous] = append(graph.lower[previous], w)
+			graph.upper[w] = append(graph.upper[w], previous)
+		}
+	}
+	return graph
+}
+
+// order sorts each layer to reduce edge crossings, starting from the nodes' canvas order
+// and sweeping down and up with the barycenter heuristic, keeping the best order found
+func (g *layeredGraph) order(nodes []models.Node) {
+	// Dummies start where the node they hang from is
+	start := make([]float64, len(g.layer))
+	for v := range g.layer {
+		if v < g.real {
+			start[v] = nodes[v].PositionX
+		} else {
+			start[v] = start[g.upper[v][0]]
+		}
+	}
+	depth := 0
+	for _, l := range g.layer {
+		depth = max(depth, l+1)
+	}
+	g.layers = make([][]int, depth)
+	for v, l := range g.layer {
+		g.layers[l] = append(g.layers[l], v)
+	}
+	for _, vertices := range g.layers {
+		sort.SliceStable(vertices, func(a, b int) bool { return start[vertices[a]] < start[vertices[b]] })
+	}
+
+	best := g.snapshot()
+	bestCrossings := g.crossings()
+	for pass := 0; pass < layeredOrderingPasses && bestCrossings > 0; pass++ {
+		if pass%2 == 0 {
+			for l := 1; l < len(g.layers); l++ {
+				g.sortByBarycenter(l, g.upper)
+			}
+		} else {
+			for l := len(g.layers) - 2; l >= 0; l-- {
+				g.sortByBarycenter(l, g.lower)
+			}
+		}
+		if crossings := g.crossings(); crossings < bestCrossings {
+			best, bestCrossings = g.snapshot(), crossings
+		}
+	}
+	g.layers = best
+}
+
+// sortByBarycenter orders a layer by the mean position of each vertex's neighbours in the
+// adjacent layer. Vertices without neighbours keep their place
+func (g *layeredGraph) sortByBarycenter(l int, neighbours [][]int) {
+	position := g.positions()
+	vertices := g.layers[l]
+	barycenter := make(map[int]float64, len(vertices))
+	for i, v := range vertices {
+		if len(neighbours[v]) == 0 {
+			barycenter[v] = float64(i)
+			continue
+		}
+		sum := 0.0
+		for _, w := range neighbours[v] {
+			sum += float64(position[w])
+		}
+		barycenter[v] = sum / float64(len(neighbours[v]))
+	}
+	sort.SliceStable(vertices, func(a, b int) bool { return barycenter[vertices[a]] < barycenter[vertices[b]] })
+}
+
+// positions returns the index of each vertex within its layer
+func (g *layeredGraph) positions() []int {
+	position := make([]int, len(g.layer))
+	for _, vertices := range g.layers {
+		for i, v := range vertices {
+			position[v] = i
+		}
+	}
+	return position
+}
+
+// snapshot copies the current order of every layer
+func (g *layeredGraph) snapshot() [][]int {
+	layers := make([][]int, len(g.layers))
+	for l, vertices := range g.layers {
+		layers[l] = append([]int(nil), vertices...)
+	}
+	return layers
+}
+
+// crossings counts the pairs of edges between adjacent layers that cross
+func (g *layeredGraph) crossings() int {
+	position := g.positions()
+	count := 0
+	for l := 0; l+1 < len(g.layers); l++ {
+		var segments [][2]int
+		for _, v := range g.layers[l] {
+			for _, w := range g.lower[v] {
+				segments = append(segments, [2]int{position[v], position[w]})
+			}
+		}
+		for a := 0; a < len(segments); a++ {
+			for b := a + 1; b < len(segments); b++ {
+				if (segments[a][0]-segments[b][0])*(segments[a][1]-segments[b][1]) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// coordinates assigns the horizontal center of every vertex. Layers start packed side by
+// side, then each layer is pulled towards the mean position of its neighbours, alternating
+// between the layer above and below, while keeping its order and spacing
+func (g *layeredGraph) coordinates() []float64 {
+	x := make([]float64, len(g.layer))
+	for _, vertices := range g.layers {
+		for i, v := range vertices {
+			if i > 0 {
+				x[v] = x[vertices[i-1]] + g.separation(vertices[i-1], v)
+			}
+		}
+	}
+
+	for pass := 0; pass < layeredCoordinatePasses; pass++ {
+		if pass%2 == 0 {
+			for l := 1; l < len(g.layers); l++ {
+				g.align(x, g.layers[l], g.upper)
+			}
+		} else {
+			for l := len(g.layers) - 2; l >= 0; l-- {
+				g.align(x, g.layers[l], g.lower)
+			}
+		}
+	}
+	return x
+}
+
+// align moves the vertices of a layer towards the mean position of their neighbours. The
+// layer is packed left to right without letting vertices come closer than their separation,
+// then shifted as a whole so it sits centered on where its vertices want to be
+func (g *layeredGraph) align(x []float64, vertices []int, neighbours [][]int) {
+	if len(vertices) == 0 {
+		return
+	}
+	desired := make([]float64, len(vertices))
+	for i, v := range vertices {
+		desired[i] = x[v]
+		if len(neighbours[v]) > 0 {
+			sum := 0.0
+			for _, w := range neighbours[v] {
+				sum += x[w]
+			}
+			desired[i] = sum / float64(len(neighbours[v]))
+		}
+	}
+
+	shift := 0.0
+	for i, v := range vertices {
+		x[v] = desired[i]
+		if i > 0 {
+			x[v] = math.Max(x[v], x[vertices[i-1]]+g.separation(vertices[i-1], v))
+		}
+		shift += desired[i] - x[v]
+	}
+	shift /= float64(len(vertices))
+	for _, v := range vertices {
+		x[v] += shift
+	}
+}
+
+// separation is the distance kept between the centers of two neighbouring vertices
+func (g *layeredGraph) separation(a, b int) float64 {
+	return (g.width(a)+g.width(b))/2 + layeredNodeGap
+}
+
+// width is the horizontal space a vertex takes up
+func (g *layeredGraph) width(v int) float64 {
+	if v < g.real {
+		return NodeWidth
+	}
+	return layeredDummyWidth
+}
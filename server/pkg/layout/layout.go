@@ -0,0 +1,84 @@
+// Package layout computes node positions for whole mind maps
+package layout
+
+import (
+	"math"
+	"saas-server/models"
+)
+
+// Node size assumed when checking for overlaps, matching the default node on the canvas
+const (
+	NodeWidth  = 150
+	NodeHeight = 50
+)
+
+// Layout modes accepted by the layout endpoint
+const (
+	// ModeTree rebalances the map as a left-to-right tree, keeping pinned nodes in place
+	ModeTree = "tree"
+	// ModeSplit centers each root and alternates its branches between the right and left side
+	ModeSplit = "split"
+	// ModeTimeline arranges nodes with a date along a horizontal time axis
+	ModeTimeline = "timeline"
+	// ModeLayered arranges the map top-down in layers, like an org chart or process flow
+	ModeLayered = "layered"
+)
+
+// ValidMode reports whether mode is one of the supported layout modes
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeTree, ModeSplit, ModeTimeline, ModeLayered:
+		return true
+	}
+	return false
+}
+
+// Apply computes the positions of nodes for the given layout mode. Returns the updates
+// for moved nodes and the number of overlaps left
+func Apply(mode string, nodes []models.Node, edges []models.Edge) ([]models.NodePositionUpdateRequest, int) {
+	switch mode {
+	case ModeTree:
+		return Tree(nodes)
+	case ModeSplit:
+		return Split(nodes)
+	case ModeTimeline:
+		return Timeline(nodes)
+	case ModeLayered:
+		return Layered(nodes, edges)
+	}
+	return []models.NodePositionUpdateRequest{}, 0
+}
+
+// positionEpsilon is the smallest movement that counts as moving a node
+const positionEpsilon = 0.5
+
+// box is a node's position while a layout is being computed. Fixed boxes belong to
+// pinned nodes and never move
+type box struct {
+	id    string
+	x, y  float64
+	fixed bool
+}
+
+// boxes copies the positions of nodes
+func boxes(nodes []models.Node) []box {
+	result := make([]box, len(nodes))
+	for i, node := range nodes {
+		result[i] = box{id: node.ID, x: node.PositionX, y: node.PositionY, fixed: node.Pinned}
+	}
+	return result
+}
+
+// moved returns the position updates for the boxes that ended up away from their node's
+// original position, rounded to whole pixels
+func moved(nodes []models.Node, placed []box) []models.NodePositionUpdateRequest {
+	updates := []models.NodePositionUpdateRequest{}
+	for i, node := range nodes {
+		x, y := math.Round(placed[i].x), math.Round(placed[i].y)
+		if math.Abs(x-node.PositionX) < positionEpsilon && math.Abs(y-node.PositionY) < positionEpsilon {
+			continue
+		}
+		updates = append(updates, models.NodePositionUpdateRequest{ID: node.ID, PositionX: x, PositionY: y})
+	}
+	return updates
+}
@@ -0,0 +1,59 @@
+package layout
+
+import (
+	"math"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+)
+
+// Split lays the map out in the classic mind map style: each root stays in the center and
+// its branches alternate between the right and the left side, each side growing outwards
+// as a tree. Both sides are centered on the root. Pinned nodes are handled as in Tree.
+// Returns the updates for moved nodes and the number of overlaps left
+func Split(nodes []models.Node) ([]models.NodePositionUpdateRequest, int) {
+	if len(nodes) == 0 {
+		return []models.NodePositionUpdateRequest{}, 0
+	}
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil {
+		return []models.NodePositionUpdateRequest{}, 0
+	}
+
+	placer := newTreePlacer(len(nodes))
+	for _, root := range roots {
+		var right, left []*outline.Item
+		for i, child := range root.Children {
+			if i%2 == 0 {
+				right = append(right, child)
+			} else {
+				left = append(left, child)
+			}
+		}
+
+		top := placer.nextY
+		rightEnd := placer.side(right, 1)
+		placer.nextY = top
+		leftEnd := placer.side(left, -1)
+
+		// Center the shorter side against the taller one
+		end := math.Max(math.Max(rightEnd, leftEnd), top+treeSlot)
+		for _, branch := range right {
+			placer.offset(branch, (end-rightEnd)/2)
+		}
+		for _, branch := range left {
+			placer.offset(branch, (end-leftEnd)/2)
+		}
+
+		placer.positions[root.Node.ID] = box{id: root.Node.ID, y: (top + end - treeSlot) / 2}
+		placer.nextY = end + treeRootGap
+	}
+	return placer.finish(nodes, roots)
+}
+
+// side places the branches of one side of a root and returns where the side ends
+func (p *treePlacer) side(branches []*outline.Item, direction float64) float64 {
+	for _, branch := range branches {
+		p.place(branch, 1, direction)
+	}
+	return p.nextY
+}
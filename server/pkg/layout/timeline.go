@@ -0,0 +1,118 @@
+package layout
+
+import (
+	"math"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"sort"
+	"time"
+)
+
+// Spacing of the timeline layout, in pixels
+const (
+	timelineDayWidth   = 40
+	timelineMaxWidth   = 6000
+	timelineGap        = 20
+	timelineUndatedGap = 150
+)
+
+// timelineDateKeys are the metadata fields read as a node's date, in order of preference
+var timelineDateKeys = []string{"due_date", "due", "date"}
+
+// Timeline arranges dated nodes along a horizontal time axis, earliest on the left, with
+// nodes that would collide stacked in lanes below each other. The axis is scaled by day
+// and squeezed when the dates span too long a range. Nodes without a date are lined up
+// in a separate row under the timeline. The layout starts at the top-left corner of the
+// current map, and pinned nodes stay in place. Returns the updates for moved nodes and
+// the number of overlaps left
+func Timeline(nodes []models.Node) ([]models.NodePositionUpdateRequest, int) {
+	if len(nodes) == 0 {
+		return []models.NodePositionUpdateRequest{}, 0
+	}
+
+	type dated struct {
+		index int
+		date  time.Time
+	}
+	var withDate []dated
+	var undated []int
+	originX, originY := math.Inf(1), math.Inf(1)
+	for i, node := range nodes {
+		originX = math.Min(originX, node.PositionX)
+		originY = math.Min(originY, node.PositionY)
+		if node.Pinned {
+			continue
+		}
+		if date, ok := NodeDate(node); ok {
+			withDate = append(withDate, dated{index: i, date: date})
+		} else {
+			undated = append(undated, i)
+		}
+	}
+	sort.SliceStable(withDate, func(a, b int) bool { return withDate[a].date.Before(withDate[b].date) })
+
+	placed := boxes(nodes)
+
+	// Scale the axis so the whole range fits
+	dayWidth := float64(timelineDayWidth)
+	if len(withDate) > 1 {
+		days := withDate[len(withDate)-1].date.Sub(withDate[0].date).Hours() / 24
+		if days*dayWidth > timelineMaxWidth {
+			dayWidth = timelineMaxWidth / days
+		}
+	}
+
+	// Put each dated node in the first lane with room for it
+	var laneEnds []float64
+	for _, entry := range withDate {
+		x := originX + entry.date.Sub(withDate[0].date).Hours()/24*dayWidth
+		lane := 0
+		for lane < len(laneEnds) && laneEnds[lane] > x {
+			lane++
+		}
+		if lane == len(laneEnds) {
+			laneEnds = append(laneEnds, 0)
+		}
+		laneEnds[lane] = x + NodeWidth + timelineGap
+		placed[entry.index].x = x
+		placed[entry.index].y = originY + float64(lane)*(NodeHeight+timelineGap)
+	}
+
+	// Line up undated nodes in canvas order below the lanes
+	sort.SliceStable(undated, func(a, b int) bool {
+		first, second := nodes[undated[a]], nodes[undated[b]]
+		if first.PositionY != second.PositionY {
+			return first.PositionY < second.PositionY
+		}
+		return first.PositionX < second.PositionX
+	})
+	undatedY := originY + float64(len(laneEnds))*(NodeHeight+timelineGap)
+	if len(laneEnds) > 0 {
+		undatedY += timelineUndatedGap
+	}
+	for i, index := range undated {
+		placed[index].x = originX + float64(i)*(NodeWidth+timelineGap)
+		placed[index].y = undatedY
+	}
+
+	remaining := relax(placed, timelineGap)
+	return moved(nodes, placed), remaining
+}
+
+// NodeDate returns the date stored in a node's metadata under due_date, due or date, in
+// YYYY-MM-DD or RFC 3339 form
+func NodeDate(node models.Node) (time.Time, bool) {
+	for _, key := range timelineDateKeys {
+		value := outline.MetadataString(node, key)
+		if value == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
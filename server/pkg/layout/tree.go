@@ -0,0 +1,111 @@
+package layout
+
+import (
+	"saas-server/models"
+	"saas-server/pkg/outline"
+)
+
+// Spacing of the tree layouts, in pixels
+const (
+	treeLevelGap   = 250
+	treeSiblingGap = 30
+	treeRootGap    = 80
+	treeRelaxGap   = 20
+)
+
+// treeSlot is the vertical space a leaf takes up in a tree layout
+const treeSlot = NodeHeight + treeSiblingGap
+
+// Tree lays the map out as a left-to-right tree: each level is a column, leaves are stacked
+// in sibling order and parents are centered on their children. The layout is anchored on
+// the first root's current position. Pinned nodes keep their position and carry their
+// subtree with them, and anything they end up overlapping is nudged aside. Returns the
+// updates for moved nodes and the number of overlaps left
+func Tree(nodes []models.Node) ([]models.NodePositionUpdateRequest, int) {
+	if len(nodes) == 0 {
+		return []models.NodePositionUpdateRequest{}, 0
+	}
+	roots, err := outline.Build(nodes, outline.OrderPosition)
+	if err != nil {
+		return []models.NodePositionUpdateRequest{}, 0
+	}
+
+	placer := newTreePlacer(len(nodes))
+	for _, root := range roots {
+		placer.place(root, 0, 1)
+		placer.nextY += treeRootGap
+	}
+	return placer.finish(nodes, roots)
+}
+
+// treePlacer assigns tree positions relative to the origin, stacking leaves downwards
+type treePlacer struct {
+	positions map[string]box
+	nextY     float64
+}
+
+// newTreePlacer creates a placer sized for the given number of nodes
+func newTreePlacer(size int) *treePlacer {
+	return &treePlacer{positions: make(map[string]box, size)}
+}
+
+// place positions item and its subtree with item at the given depth, growing to the right
+// when direction is 1 and to the left when it is -1. Returns the y of item
+func (p *treePlacer) place(item *outline.Item, depth int, direction float64) float64 {
+	y := p.nextY
+	if len(item.Children) == 0 {
+		p.nextY += treeSlot
+	} else {
+		first := p.place(item.Children[0], depth+1, direction)
+		last := first
+		for _, child := range item.Children[1:] {
+			last = p.place(child, depth+1, direction)
+		}
+		y = (first + last) / 2
+	}
+	p.positions[item.Node.ID] = box{id: item.Node.ID, x: direction * float64(depth) * treeLevelGap, y: y}
+	return y
+}
+
+// offset moves item and its subtree down by dy
+func (p *treePlacer) offset(item *outline.Item, dy float64) {
+	position := p.positions[item.Node.ID]
+	position.y += dy
+	p.positions[item.Node.ID] = position
+	for _, child := range item.Children {
+		p.offset(child, dy)
+	}
+}
+
+// finish anchors the placed tree on the first root's current position, lets pinned nodes
+// pull their subtree along and nudges apart whatever then overlaps. Returns the updates
+// for moved nodes and the number of overlaps left
+func (p *treePlacer) finish(nodes []models.Node, roots []*outline.Item) ([]models.NodePositionUpdateRequest, int) {
+	var shift func(item *outline.Item, dx, dy float64)
+	shift = func(item *outline.Item, dx, dy float64) {
+		position := p.positions[item.Node.ID]
+		if item.Node.Pinned {
+			dx, dy = item.Node.PositionX-position.x, item.Node.PositionY-position.y
+		}
+		position.x += dx
+		position.y += dy
+		p.positions[item.Node.ID] = position
+		for _, child := range item.Children {
+			shift(child, dx, dy)
+		}
+	}
+	anchor := roots[0].Node
+	origin := p.positions[anchor.ID]
+	for _, root := range roots {
+		shift(root, anchor.PositionX-origin.x, anchor.PositionY-origin.y)
+	}
+
+	placed := boxes(nodes)
+	for i := range placed {
+		if position, ok := p.positions[placed[i].id]; ok && !placed[i].fixed {
+			placed[i].x, placed[i].y = position.x, position.y
+		}
+	}
+	remaining := relax(placed, treeRelaxGap)
+	return moved(nodes, placed), remaining
+}
@@ -0,0 +1,88 @@
+// Package links finds references from node content to other mind maps and nodes
+package links
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// uuidPattern matches a UUID in its canonical textual form
+const uuidPattern = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+
+// mapLinkPattern matches a mind map reference such as /mindmaps/{id}, capturing the rest of
+// the URL so a node reference like ?node={id}, /nodes/{id} or #{id} can be picked out of it.
+// Markdown links are matched through their URL
+var mapLinkPattern = regexp.MustCompile(`mindmaps/(` + uuidPattern + `)([^\s)\]>"']*)`)
+
+// uuidRegexp matches any UUID
+var uuidRegexp = regexp.MustCompile(uuidPattern)
+
+// exactUUIDRegexp matches a string that is a single UUID
+var exactUUIDRegexp = regexp.MustCompile(`^` + uuidPattern + `$`)
+
+// NodeTypeLink is the node type of link nodes, whose target is kept in metadata
+const NodeTypeLink = "link"
+
+// Reference points at a mind map and, optionally, one of its nodes
+type Reference struct {
+	MindMapID string
+	NodeID    string // Empty when the whole map is referenced
+}
+
+// Extract returns the distinct references made by a node: links in its content and,
+// for link nodes, the url, target_mind_map_id and target_node_id metadata fields
+func Extract(content, nodeType string, metadata json.RawMessage) []Reference {
+	var refs []Reference
+	seen := make(map[Reference]bool)
+	add := func(ref Reference) {
+		ref.MindMapID = strings.ToLower(ref.MindMapID)
+		ref.NodeID = strings.ToLower(ref.NodeID)
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	texts := []string{content}
+	if nodeType == NodeTypeLink && len(metadata) > 0 {
+		var target struct {
+			URL       string `json:"url"`
+			MindMapID string `json:"target_mind_map_id"`
+			NodeID    string `json:"target_node_id"`
+		}
+		if json.Unmarshal(metadata, &target) == nil {
+			texts = append(texts, target.URL)
+			if exactUUIDRegexp.MatchString(target.MindMapID) {
+				ref := Reference{MindMapID: target.MindMapID}
+				if exactUUIDRegexp.MatchString(target.NodeID) {
+					ref.NodeID = target.NodeID
+				}
+				add(ref)
+			}
+		}
+	}
+
+	for _, text := range texts {
+		for _, match := range mapLinkPattern.FindAllStringSubmatch(text, -1) {
+			add(Reference{MindMapID: match[1], NodeID: uuidRegexp.FindString(match[2])})
+		}
+	}
+	return refs
+}
+
+// Rewrite replaces the mind map and node references in the links of text, such as node
+// content or a link node's url. rewrite receives each reference and returns the one to use
+// instead; the rest of each link is kept as it was
+func Rewrite(text string, rewrite func(Reference) Reference) string {
+	return mapLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		match := mapLinkPattern.FindStringSubmatch(link)
+		ref := Reference{MindMapID: match[1], NodeID: uuidRegexp.FindString(match[2])}
+		next := rewrite(ref)
+		rest := match[2]
+		if ref.NodeID != "" && next.NodeID != "" && next.NodeID != ref.NodeID {
+			rest = strings.Replace(rest, ref.NodeID, next.NodeID, 1)
+		}
+		return "mindmaps/" + next.MindMapID + rest
+	})
+}
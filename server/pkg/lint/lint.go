@@ -0,0 +1,98 @@
+// Package lint runs rule-based quality checks over a mind map's outline
+package lint
+
+import (
+	"fmt"
+	"saas-server/models"
+	"saas-server/pkg/outline"
+	"strings"
+)
+
+// Thresholds for the structural rules
+const (
+	// MaxDepth is how many levels below a root a branch may go before it is hard to follow
+	MaxDepth = 6
+	// MaxChildren is how many direct children a node may have before it needs grouping
+	MaxChildren = 12
+)
+
+// Rule names reported on findings
+const (
+	RuleEmptyContent      = "empty_content"
+	RuleDuplicateSiblings = "duplicate_siblings"
+	RuleDeepBranch        = "deep_branch"
+	RuleGiantBranch       = "giant_branch"
+)
+
+// Check runs every rule over an outline built with outline.Build
+func Check(roots []*outline.Item) []models.LintFinding {
+	findings := []models.LintFinding{}
+	findings = append(findings, checkSiblings(roots)...)
+
+	for _, item := range outline.Flatten(roots) {
+		if strings.TrimSpace(item.Node.Content) == "" {
+			findings = append(findings, finding(RuleEmptyContent, models.LintSeverityError,
+				"Node has no content",
+				"Give the node a short label or delete it",
+				item.Node.ID))
+		}
+
+		// Only report the first node past the limit so a deep branch yields one finding
+		if item.Depth == MaxDepth+1 {
+			findings = append(findings, finding(RuleDeepBranch, models.LintSeverityWarning,
+				fmt.Sprintf("Branch is nested more than %d levels deep", MaxDepth),
+				"Flatten the branch or move its details into a separate map",
+				item.Node.ID))
+		}
+
+		if len(item.Children) > MaxChildren {
+			findings = append(findings, finding(RuleGiantBranch, models.LintSeverityWarning,
+				fmt.Sprintf("Node has %d direct children", len(item.Children)),
+				"Group related children under a few intermediate topics",
+				item.Node.ID))
+		}
+
+		findings = append(findings, checkSiblings(item.Children)...)
+	}
+
+	return findings
+}
+
+// checkSiblings reports siblings whose content is the same apart from case and spacing
+func checkSiblings(siblings []*outline.Item) []models.LintFinding {
+	groups := make(map[string][]string)
+	var keys []string
+	for _, item := range siblings {
+		key := strings.Join(strings.Fields(strings.ToLower(item.Node.Content)), " ")
+		if key == "" {
+			continue
+		}
+		if _, seen := groups[key]; !seen {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], item.Node.ID)
+	}
+
+	var findings []models.LintFinding
+	for _, key := range keys {
+		if ids := groups[key]; len(ids) > 1 {
+			findings = append(findings, finding(RuleDuplicateSiblings, models.LintSeverityWarning,
+				fmt.Sprintf("%d sibling nodes share the same content", len(ids)),
+				"Merge the duplicates into one node",
+				ids...))
+		}
+	}
+	return findings
+}
+
+// finding builds a rule-based finding
+func finding(rule, severity, message, suggestion string, nodeIDs ...string) models.LintFinding {
+	return models.LintFinding{
+		Rule:       rule,
+		Severity:   severity,
+		Message:    message,
+		Suggestion: suggestion,
+		NodeIDs:    nodeIDs,
+		Source:     "rule",
+	}
+}
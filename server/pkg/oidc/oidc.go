@@ -0,0 +1,301 @@
+// Package oidc implements the parts of OpenID Connect a relying party needs to sign users
+// in with an identity provider: discovering the provider's endpoints, building the
+// authorization URL with PKCE and verifying the ID token returned by the code exchange
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// discoveryTTL is how long a provider's configuration and keys are cached
+const discoveryTTL = time.Hour
+
+// Provider is the configuration an identity provider publishes at
+// /.well-known/openid-configuration
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	keys      map[string]interface{} // Signing keys by key ID
+	fetchedAt time.Time
+}
+
+var (
+	providers     = make(map[string]*Provider)
+	providerMutex sync.Mutex
+	httpClient    = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Discover returns the configuration and signing keys of the provider at issuer, fetching
+// them unless they were fetched within the last hour
+func Discover(ctx context.Context, issuer string) (*Provider, error) {
+	issuer = strings.TrimRight(issuer, "/")
+
+	providerMutex.Lock()
+	cached, ok := providers[issuer]
+	providerMutex.Unlock()
+	if ok && time.Since(cached.fetchedAt) < discoveryTTL {
+		return cached, nil
+	}
+
+	var provider Provider
+	if err := getJSON(ctx, issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", issuer, err)
+	}
+	if strings.TrimRight(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("provider reports issuer %q instead of %q", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("provider %s is missing endpoints", issuer)
+	}
+
+	keys, err := fetchKeys(ctx, provider.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys of %s: %w", issuer, err)
+	}
+	provider.keys = keys
+	provider.fetchedAt = time.Now()
+
+	providerMutex.Lock()
+	providers[issuer] = &provider
+	providerMutex.Unlock()
+	return &provider, nil
+}
+
+// OAuth2Config returns the OAuth 2 configuration of a client of the provider, asking for
+// the openid, email and profile scopes
+func (p *Provider) OAuth2Config(clientID, clientSecret, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthorizationEndpoint,
+			TokenURL: p.TokenEndpoint,
+		},
+	}
+}
+
+// RandomString returns a URL-safe random string, for states, nonces and PKCE verifiers
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthCodeURL returns the URL to send the user to for signing in, with the state, the
+// nonce the ID token must carry and the S256 challenge of the PKCE verifier
+func AuthCodeURL(config *oauth2.Config, state, nonce, verifier string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	return config.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// Claims are the verified claims of an ID token
+type Claims map[string]interface{}
+
+// String returns a string claim, or an empty string if it is missing
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Bool returns a boolean claim; some providers send booleans as strings
+func (c Claims) Bool(name string) bool {
+	switch value := c[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
+
+// Strings returns a claim holding a list of strings, such as groups. A single string is
+// returned as a list of one
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Exchange trades an authorization code and its PKCE verifier for tokens and returns the
+// verified claims of the ID token
+func (p *Provider) Exchange(ctx context.Context, config *oauth2.Config, code, verifier, nonce string) (Claims, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	token, err := config.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no ID token")
+	}
+	return p.Verify(ctx, rawIDToken, config.ClientID, nonce)
+}
+
+// Verify checks an ID token's signature against the provider's keys and its issuer,
+// audience, expiry and nonce, and returns its claims. Keys are fetched again once if the
+// token is signed with an unknown key, as providers rotate them
+func (p *Provider) Verify(ctx context.Context, rawIDToken, clientID, nonce string) (Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if key := p.key(kid); key != nil {
+			return key, nil
+		}
+		keys, err := fetchKeys(ctx, p.JWKSURI)
+		if err != nil {
+			return nil, err
+		}
+		providerMutex.Lock()
+		p.keys = keys
+		providerMutex.Unlock()
+		if key := p.key(kid); key != nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("invalid ID token: nonce does not match")
+	}
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return nil, errors.New("invalid ID token: no subject")
+	}
+	return Claims(claims), nil
+}
+
+// key returns the signing key with the given ID. Without a key ID the only key is used
+func (p *Provider) key(kid string) interface{} {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys fetches a JSON Web Key Set and returns its RSA and EC signing keys by key ID.
+// Keys of other types or for encryption are skipped
+func fetchKeys(ctx context.Context, url string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, url, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable signing keys")
+	}
+	return keys, nil
+}
+
+// getJSON fetches a URL and decodes its JSON body into v
+func getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,95 @@
+// Package outbox delivers the change events queued in the outbox table to realtime
+// subscribers
+package outbox
+
+import (
+	"log"
+	"time"
+
+	"saas-server/database"
+	"saas-server/models"
+	"saas-server/pkg/realtime"
+)
+
+// pollInterval is how often the dispatcher looks for events without being signalled, which
+// picks up events left behind by an instance that stopped before publishing them
+const pollInterval = 2 * time.Second
+
+// batchSize is how many events are published per claim
+const batchSize = 200
+
+// retention is how long published events stay in the outbox before they are pruned
+const retention = 24 * time.Hour
+
+// Dispatcher publishes outbox events to the realtime hub in the background
+type Dispatcher struct {
+	db  *database.DB
+	hub *realtime.Hub
+}
+
+// NewDispatcher creates a new instance of Dispatcher
+func NewDispatcher(db *database.DB, hub *realtime.Hub) *Dispatcher {
+	return &Dispatcher{
+		db:  db,
+		hub: hub,
+	}
+}
+
+// StartJob starts publishing events as soon as their changes commit, polling for any missed
+// ones, and pruning published events once an hour
+func (d *Dispatcher) StartJob() {
+	go func() {
+		poll := time.NewTicker(pollInterval)
+		defer poll.Stop()
+		prune := time.NewTicker(time.Hour)
+		defer prune.Stop()
+
+		for {
+			select {
+			case <-d.db.ChangeSignal():
+			case <-poll.C:
+			case <-prune.C:
+				d.prune()
+				continue
+			}
+			d.dispatch()
+		}
+	}()
+}
+
+// dispatch publishes pending events until none are left
+func (d *Dispatcher) dispatch() {
+	for {
+		published, err := d.db.PublishPendingChanges(batchSize, d.publish)
+		if err != nil {
+			log.Printf("[Outbox] Error publishing events: %v", err)
+			return
+		}
+		if published < batchSize {
+			return
+		}
+	}
+}
+
+// publish hands one event to the hub
+func (d *Dispatcher) publish(change models.MindMapChange) {
+	d.hub.PublishEvent(realtime.Event{
+		Type:      change.Type,
+		MindMapID: change.MindMapID,
+		Seq:       change.Seq,
+		Payload:   change.Payload,
+		CreatedAt: change.CreatedAt,
+	})
+}
+
+// prune deletes events published longer ago than the retention, logging the outcome
+func (d *Dispatcher) prune() {
+	pruned, err := d.db.PrunePublishedChanges(time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("[Outbox] Error pruning published events: %v", err)
+		return
+	}
+	if pruned > 0 {
+		log.Printf("[Outbox] Pruned %d published events", pruned)
+	}
+}
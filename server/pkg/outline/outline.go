@@ -0,0 +1,173 @@
+// Package outline turns the flat node list of a mind map into an ordered tree
+// that presentation and export code can walk branch by branch
+package outline
+
+import (
+	"encoding/json"
+	"fmt"
+	"saas-server/models"
+	"sort"
+	"strings"
+)
+
+// Supported sibling orderings
+const (
+	// OrderPosition sorts siblings top-to-bottom, then left-to-right on the canvas
+	OrderPosition = "position"
+	// OrderCreated sorts siblings by creation time
+	OrderCreated = "created_at"
+	// OrderContent sorts siblings alphabetically by content
+	OrderContent = "content"
+	// OrderCustom sorts siblings by the numeric "order" metadata field, falling back to position
+	OrderCustom = "order"
+)
+
+// Item is a node placed in the outline together with its ordered children
+type Item struct {
+	Node     models.Node
+	Depth    int
+	Children []*Item
+}
+
+// ValidOrder reports whether order is one of the supported sibling orderings
+func ValidOrder(order string) bool {
+	switch order {
+	case OrderPosition, OrderCreated, OrderContent, OrderCustom:
+		return true
+	}
+	return false
+}
+
+// Build arranges nodes into a forest using their parent IDs. Nodes whose parent is
+// missing from the list become roots, and every sibling list is sorted by order
+func Build(nodes []models.Node, order string) ([]*Item, error) {
+	if order == "" {
+		order = OrderPosition
+	}
+	if !ValidOrder(order) {
+		return nil, fmt.Errorf("unsupported order %q", order)
+	}
+
+	items := make(map[string]*Item, len(nodes))
+	for _, node := range nodes {
+		items[node.ID] = &Item{Node: node}
+	}
+
+	var roots []*Item
+	for _, node := range nodes {
+		item := items[node.ID]
+		if node.ParentID != nil {
+			if parent, ok := items[*node.ParentID]; ok && *node.ParentID != node.ID {
+				parent.Children = append(parent.Children, item)
+				continue
+			}
+		}
+		roots = append(roots, item)
+	}
+
+	// Walk from the roots so depths are set and nodes caught in parent cycles are not lost
+	visited := make(map[string]bool, len(nodes))
+	var walk func(list []*Item, depth int)
+	walk = func(list []*Item, depth int) {
+		sortItems(list, order)
+		for _, item := range list {
+			visited[item.Node.ID] = true
+			item.Depth = depth
+			walk(item.Children, depth+1)
+		}
+	}
+	walk(roots, 0)
+
+	for _, node := range nodes {
+		if !visited[node.ID] {
+			item := items[node.ID]
+			roots = append(roots, item)
+			breakCycle(item, visited)
+			walk([]*Item{item}, 0)
+		}
+	}
+
+	return roots, nil
+}
+
+// breakCycle drops child links that point back into an already visited part of the tree
+func breakCycle(item *Item, visited map[string]bool) {
+	visited[item.Node.ID] = true
+	children := item.Children[:0]
+	for _, child := range item.Children {
+		if visited[child.Node.ID] {
+			continue
+		}
+		children = append(children, child)
+		breakCycle(child, visited)
+	}
+	item.Children = children
+}
+
+// Flatten returns the items of the forest in depth-first order
+func Flatten(items []*Item) []*Item {
+	var flat []*Item
+	for _, item := range items {
+		flat = append(flat, item)
+		flat = append(flat, Flatten(item.Children)...)
+	}
+	return flat
+}
+
+// MetadataString returns a string field from a node's metadata, or "" if it is missing
+func MetadataString(node models.Node, key string) string {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(node.Metadata, &metadata); err != nil {
+		return ""
+	}
+	value, _ := metadata[key].(string)
+	return value
+}
+
+// Tags returns the tags stored in a node's metadata
+func Tags(node models.Node) []string {
+	var metadata struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(node.Metadata, &metadata); err != nil {
+		return nil
+	}
+	return metadata.Tags
+}
+
+// sortItems sorts sibling items in place
+func sortItems(list []*Item, order string) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Node, list[j].Node
+		switch order {
+		case OrderCreated:
+			return a.CreatedAt.Before(b.CreatedAt)
+		case OrderContent:
+			return strings.ToLower(a.Content) < strings.ToLower(b.Content)
+		case OrderCustom:
+			ai, aok := customOrder(a)
+			bi, bok := customOrder(b)
+			if aok && bok && ai != bi {
+				return ai < bi
+			}
+			if aok != bok {
+				return aok
+			}
+		}
+		if a.PositionY != b.PositionY {
+			return a.PositionY < b.PositionY
+		}
+		return a.PositionX < b.PositionX
+	})
+}
+
+// customOrder reads the numeric "order" metadata field of a node
+func customOrder(node models.Node) (float64, bool) {
+	var metadata struct {
+		Order *float64 `json:"order"`
+	}
+	if err := json.Unmarshal(node.Metadata, &metadata); err != nil || metadata.Order == nil {
+		return 0, false
+	}
+	return *metadata.Order, true
+}
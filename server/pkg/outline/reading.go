@@ -0,0 +1,104 @@
+package outline
+
+import (
+	"fmt"
+	"saas-server/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Linearize returns the nodes of a map as a single depth-first sequence for screen
+// readers. Siblings follow order, with ties broken by creation time and then ID so the
+// sequence is the same on every request. Each item carries its outline number, position
+// among its siblings, how many branches close after it and the nodes it is related to
+// through edges besides its parent link
+func Linearize(nodes []models.Node, edges []models.Edge, order string) ([]models.ReadingOrderItem, error) {
+	sorted := append([]models.Node(nil), nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	roots, err := Build(sorted, order)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			parents[node.ID] = *node.ParentID
+		}
+	}
+	related := make(map[string][]string)
+	relate := func(a, b string) {
+		for _, id := range related[a] {
+			if id == b {
+				return
+			}
+		}
+		related[a] = append(related[a], b)
+	}
+	for _, edge := range edges {
+		if edge.SourceID == edge.TargetID || parents[edge.TargetID] == edge.SourceID || parents[edge.SourceID] == edge.TargetID {
+			continue
+		}
+		relate(edge.SourceID, edge.TargetID)
+		relate(edge.TargetID, edge.SourceID)
+	}
+
+	items := []models.ReadingOrderItem{}
+	var walk func(list []*Item, prefix string)
+	walk = func(list []*Item, prefix string) {
+		for i, item := range list {
+			branch := prefix + strconv.Itoa(i+1)
+			relatedIDs := related[item.Node.ID]
+			if relatedIDs == nil {
+				relatedIDs = []string{}
+			}
+			items = append(items, models.ReadingOrderItem{
+				Index:        len(items),
+				NodeID:       item.Node.ID,
+				Content:      item.Node.Content,
+				NodeType:     item.Node.NodeType,
+				ParentID:     item.Node.ParentID,
+				Depth:        item.Depth,
+				Branch:       branch,
+				Position:     i + 1,
+				SiblingCount: len(list),
+				ChildCount:   len(item.Children),
+				RelatedIDs:   relatedIDs,
+				Label:        readingLabel(item, i+1, len(list), len(relatedIDs)),
+			})
+			walk(item.Children, branch+".")
+			if i == len(list)-1 && item.Depth > 0 {
+				// The last sibling closes its parent's branch after whatever it closed itself
+				items[len(items)-1].EndsBranch++
+			}
+		}
+	}
+	walk(roots, "")
+	return items, nil
+}
+
+// readingLabel describes where an item sits, for example "Level 2, 1 of 3, 2 children,
+// 1 related"
+func readingLabel(item *Item, position, siblings, related int) string {
+	parts := []string{
+		fmt.Sprintf("Level %d", item.Depth+1),
+		fmt.Sprintf("%d of %d", position, siblings),
+	}
+	switch len(item.Children) {
+	case 0:
+	case 1:
+		parts = append(parts, "1 child")
+	default:
+		parts = append(parts, fmt.Sprintf("%d children", len(item.Children)))
+	}
+	if related > 0 {
+		parts = append(parts, fmt.Sprintf("%d related", related))
+	}
+	return strings.Join(parts, ", ")
+}
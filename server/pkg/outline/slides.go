@@ -0,0 +1,48 @@
+package outline
+
+import "saas-server/models"
+
+// Slides turns an outline into a slide sequence. A single root becomes a title slide
+// followed by one slide per top-level branch; with several roots each root is a slide.
+// Descendants of a branch become its bullets, and "notes" metadata becomes speaker notes
+func Slides(roots []*Item) []models.PresentationSlide {
+	var branches []*Item
+	var slides []models.PresentationSlide
+
+	if len(roots) == 1 {
+		root := roots[0]
+		slides = append(slides, models.PresentationSlide{
+			NodeID:  root.Node.ID,
+			Title:   root.Node.Content,
+			Notes:   MetadataString(root.Node, "notes"),
+			Bullets: []models.PresentationBullet{},
+		})
+		branches = root.Children
+	} else {
+		branches = roots
+	}
+
+	for _, branch := range branches {
+		slide := models.PresentationSlide{
+			NodeID:  branch.Node.ID,
+			Title:   branch.Node.Content,
+			Notes:   MetadataString(branch.Node, "notes"),
+			Bullets: []models.PresentationBullet{},
+		}
+		for _, item := range Flatten(branch.Children) {
+			slide.Bullets = append(slide.Bullets, models.PresentationBullet{
+				NodeID:  item.Node.ID,
+				Content: item.Node.Content,
+				Level:   item.Depth - branch.Depth - 1,
+				Notes:   MetadataString(item.Node, "notes"),
+			})
+		}
+		slides = append(slides, slide)
+	}
+
+	for i := range slides {
+		slides[i].Index = i
+	}
+
+	return slides
+}
@@ -0,0 +1,146 @@
+// Package pii finds personal data in text sent to external models and swaps it for
+// placeholders, keeping the mapping so the placeholders in the model's answer can be
+// swapped back
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kinds of personal data, used as placeholder prefixes
+const (
+	KindEmail  = "EMAIL"
+	KindPhone  = "PHONE"
+	KindPerson = "PERSON"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`)
+
+	// phonePattern matches international or grouped numbers of 7 to 15 digits; plain
+	// runs of digits such as years and amounts are left alone by requiring a + or
+	// separators
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{1,4}\)[\s.\-]?)?\d{2,4}(?:[\s.\-]\d{2,4}){1,4}|\+\d{7,15}`)
+
+	// datePattern matches ISO dates, which look like grouped numbers
+	datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+	// titledNamePattern matches names introduced by a title, such as "Dr. Jane Doe"
+	titledNamePattern = regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Mx|Dr|Prof)\.?\s+\p{Lu}[\p{L}'\-]+(?:\s+\p{Lu}[\p{L}'\-]+)?`)
+
+	placeholderPattern = regexp.MustCompile(`\[(?:` + KindEmail + `|` + KindPhone + `|` + KindPerson + `)_\d+\]`)
+)
+
+// Redactor replaces personal data with numbered placeholders such as [EMAIL_1]. The same
+// value always gets the same placeholder, so a model can still tell people apart. A nil
+// Redactor leaves text unchanged, for content that doesn't need redacting
+type Redactor struct {
+	names        *regexp.Regexp
+	placeholders map[string]string // value -> placeholder
+	values       map[string]string // placeholder -> value
+	counts       map[string]int
+}
+
+// NewRedactor creates a redactor that, besides emails, phone numbers and titled names,
+// also treats the given names of known people as personal data
+func NewRedactor(names []string) *Redactor {
+	r := &Redactor{
+		placeholders: make(map[string]string),
+		values:       make(map[string]string),
+		counts:       make(map[string]int),
+	}
+
+	// Match full names and their parts, longest first so "Jane Doe" wins over "Jane"
+	seen := make(map[string]bool)
+	var parts []string
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		candidates := append([]string{name}, strings.Fields(name)...)
+		for _, part := range candidates {
+			key := strings.ToLower(part)
+			if len([]rune(part)) < 3 || seen[key] || !unicode.IsLetter([]rune(part)[0]) {
+				continue
+			}
+			seen[key] = true
+			parts = append(parts, regexp.QuoteMeta(part))
+		}
+	}
+	if len(parts) > 0 {
+		sort.Slice(parts, func(i, j int) bool { return len(parts[i]) > len(parts[j]) })
+		r.names = regexp.MustCompile(`(?i)\b(?:` + strings.Join(parts, "|") + `)\b`)
+	}
+	return r
+}
+
+// Redact replaces the personal data in text with placeholders
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	text = emailPattern.ReplaceAllStringFunc(text, func(value string) string {
+		return r.placeholder(KindEmail, value)
+	})
+	text = phonePattern.ReplaceAllStringFunc(text, func(value string) string {
+		if n := digits(value); n < 7 || n > 15 || datePattern.MatchString(value) {
+			return value
+		}
+		return r.placeholder(KindPhone, value)
+	})
+	text = titledNamePattern.ReplaceAllStringFunc(text, func(value string) string {
+		return r.placeholder(KindPerson, value)
+	})
+	if r.names != nil {
+		text = r.names.ReplaceAllStringFunc(text, func(value string) string {
+			return r.placeholder(KindPerson, value)
+		})
+	}
+	return text
+}
+
+// Restore puts the original values back in place of the placeholders in text. Unknown
+// placeholders are left as they are
+func (r *Redactor) Restore(text string) string {
+	if r == nil {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := r.values[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// HasPlaceholder reports whether text still contains a placeholder, for example one the
+// model made up or changed so it can't be restored
+func HasPlaceholder(text string) bool {
+	return placeholderPattern.MatchString(text)
+}
+
+// placeholder returns the placeholder for a value, numbering new ones per kind
+func (r *Redactor) placeholder(kind, value string) string {
+	key := kind + "\x00" + strings.ToLower(value)
+	if placeholder, ok := r.placeholders[key]; ok {
+		return placeholder
+	}
+	r.counts[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", kind, r.counts[kind])
+	r.placeholders[key] = placeholder
+	r.values[placeholder] = value
+	return placeholder
+}
+
+// digits counts the digits in s
+func digits(s string) int {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
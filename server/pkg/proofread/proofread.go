@@ -0,0 +1,148 @@
+// Package proofread suggests spelling, repetition and spacing fixes for node content
+// without calling out to a model
+package proofread
+
+import (
+	"regexp"
+	"saas-server/models"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reasons reported on changes
+const (
+	ReasonSpelling       = "Spelling"
+	ReasonRepeatedWord   = "Repeated word"
+	ReasonCapitalization = "Capitalization"
+	ReasonSpacing        = "Spacing"
+)
+
+var (
+	// wordPattern matches words, including contractions
+	wordPattern = regexp.MustCompile(`[\p{L}]+(?:'[\p{L}]+)*`)
+	// spaceBeforePunctuation matches spaces left before a punctuation mark
+	spaceBeforePunctuation = regexp.MustCompile(`[ \t]+([,.;:!?])`)
+	// repeatedSpaces matches runs of spaces or tabs within a line
+	repeatedSpaces = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// misspellings maps common English misspellings, in lower case, to their correction
+var misspellings = map[string]string{
+	"accomodate":     "accommodate",
+	"acheive":        "achieve",
+	"acknowlege":     "acknowledge",
+	"adress":         "address",
+	"alot":           "a lot",
+	"basicly":        "basically",
+	"begining":       "beginning",
+	"beleive":        "believe",
+	"buisness":       "business",
+	"calender":       "calendar",
+	"commited":       "committed",
+	"definately":     "definitely",
+	"dependant":      "dependent",
+	"dissapoint":     "disappoint",
+	"embarass":       "embarrass",
+	"enviroment":     "environment",
+	"existance":      "existence",
+	"familar":        "familiar",
+	"finaly":         "finally",
+	"foward":         "forward",
+	"goverment":      "government",
+	"grammer":        "grammar",
+	"happend":        "happened",
+	"immediatly":     "immediately",
+	"independant":    "independent",
+	"knowlege":       "knowledge",
+	"maintainance":   "maintenance",
+	"managment":      "management",
+	"neccessary":     "necessary",
+	"necessery":      "necessary",
+	"noticable":      "noticeable",
+	"occured":        "occurred",
+	"occurence":      "occurrence",
+	"posession":      "possession",
+	"prefered":       "preferred",
+	"priviledge":     "privilege",
+	"probaly":        "probably",
+	"publically":     "publicly",
+	"recieve":        "receive",
+	"recomend":       "recommend",
+	"refered":        "referred",
+	"relevent":       "relevant",
+	"responsability": "responsibility",
+	"seperate":       "separate",
+	"sucess":         "success",
+	"succesful":      "successful",
+	"suprise":        "surprise",
+	"teh":            "the",
+	"tommorow":       "tomorrow",
+	"tomorow":        "tomorrow",
+	"truely":         "truly",
+	"untill":         "until",
+	"wich":           "which",
+	"wierd":          "weird",
+	"writting":       "writing",
+}
+
+// Check returns text with its corrections applied along with the changes made. Misspelled
+// words are replaced keeping their capitalization, a word repeated right after itself is
+// dropped, a lone "i" is capitalized and stray spaces are removed
+func Check(text string) (string, []models.ProofreadChange) {
+	var changes []models.ProofreadChange
+	var out strings.Builder
+	last := 0
+	previous := ""
+	for _, match := range wordPattern.FindAllStringIndex(text, -1) {
+		word := text[match[0]:match[1]]
+		gap := text[last:match[0]]
+		lower := strings.ToLower(word)
+
+		if previous != "" && lower == strings.ToLower(previous) && strings.Trim(gap, " \t") == "" && gap != "" {
+			changes = append(changes, models.ProofreadChange{From: previous + gap + word, To: previous, Reason: ReasonRepeatedWord})
+			last = match[1]
+			continue
+		}
+
+		out.WriteString(gap)
+		replacement := word
+		if fix, ok := misspellings[lower]; ok {
+			replacement = matchCase(fix, word)
+			changes = append(changes, models.ProofreadChange{From: word, To: replacement, Reason: ReasonSpelling})
+		} else if word == "i" {
+			replacement = "I"
+			changes = append(changes, models.ProofreadChange{From: word, To: replacement, Reason: ReasonCapitalization})
+		}
+		out.WriteString(replacement)
+		previous = replacement
+		last = match[1]
+	}
+	out.WriteString(text[last:])
+
+	corrected := out.String()
+	for _, match := range spaceBeforePunctuation.FindAllStringSubmatch(corrected, -1) {
+		changes = append(changes, models.ProofreadChange{From: match[0], To: match[1], Reason: ReasonSpacing})
+	}
+	corrected = spaceBeforePunctuation.ReplaceAllString(corrected, "$1")
+	for _, match := range repeatedSpaces.FindAllString(corrected, -1) {
+		changes = append(changes, models.ProofreadChange{From: match, To: " ", Reason: ReasonSpacing})
+	}
+	corrected = repeatedSpaces.ReplaceAllString(corrected, " ")
+
+	return corrected, changes
+}
+
+// matchCase gives a correction the capitalization of the word it replaces: all upper case,
+// a leading capital or as written
+func matchCase(fix, word string) string {
+	if utf8.RuneCountInString(word) > 1 && strings.ToUpper(word) == word {
+		return strings.ToUpper(fix)
+	}
+	first, _ := utf8.DecodeRuneInString(word)
+	if unicode.IsUpper(first) {
+		fixFirst, fixSize := utf8.DecodeRuneInString(fix)
+		return string(unicode.ToUpper(fixFirst)) + fix[fixSize:]
+	}
+	return fix
+}
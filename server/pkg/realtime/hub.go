@@ -0,0 +1,184 @@
+// Package realtime provides fan-out of mind map events to connected clients
+package realtime
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before events are dropped
+const subscriberBuffer = 32
+
+// brokerTimeout bounds each call the hub makes to its broker
+const brokerTimeout = 5 * time.Second
+
+// Event represents a change to a mind map that connected clients should know about.
+// Seq is the change's position in the map's change log; clients use it to apply
+// events in order and to detect gaps that require a resync
+type Event struct {
+	Type      string      `json:"type"`
+	MindMapID string      `json:"mind_map_id"`
+	Seq       int64       `json:"seq,omitempty"`
+	Payload   interface{} `json:"payload"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Broker relays events between server instances. Every event published through a broker,
+// including by this instance, comes back on Events for the maps this instance subscribed to
+type Broker interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(ctx context.Context, mindMapID string) error
+	Unsubscribe(ctx context.Context, mindMapID string) error
+	Events() <-chan Event
+	Close() error
+}
+
+// Hub keeps track of subscribers per mind map and delivers published events to them.
+// With a broker, events are routed through it so clients connected to any instance see them
+type Hub struct {
+	subscribers map[string]map[chan Event]struct{}
+	mutex       sync.RWMutex
+
+	broker           Broker
+	brokerSubscribed map[string]bool
+	brokerMutex      sync.Mutex
+}
+
+// NewHub creates a new Hub instance that only delivers events within this process
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// NewHubWithBroker creates a new Hub instance that fans events out through a broker
+func NewHubWithBroker(broker Broker) *Hub {
+	h := &Hub{
+		subscribers:      make(map[string]map[chan Event]struct{}),
+		broker:           broker,
+		brokerSubscribed: make(map[string]bool),
+	}
+	go func() {
+		for event := range broker.Events() {
+			h.deliver(event)
+		}
+	}()
+	return h
+}
+
+// Subscribe registers a listener for a mind map's events. The returned function
+// must be called to unsubscribe once the listener goes away
+func (h *Hub) Subscribe(mindMapID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mutex.Lock()
+	if h.subscribers[mindMapID] == nil {
+		h.subscribers[mindMapID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[mindMapID][ch] = struct{}{}
+	h.mutex.Unlock()
+	h.syncBrokerSubscription(mindMapID)
+
+	unsubscribe := func() {
+		h.mutex.Lock()
+		if subs, ok := h.subscribers[mindMapID]; ok {
+			if _, ok := subs[ch]; ok {
+				delete(subs, ch)
+				close(ch)
+			}
+			if len(subs) == 0 {
+				delete(h.subscribers, mindMapID)
+			}
+		}
+		h.mutex.Unlock()
+		h.syncBrokerSubscription(mindMapID)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an unsequenced event to every subscriber of a mind map
+func (h *Hub) Publish(mindMapID, eventType string, payload interface{}) {
+	h.PublishEvent(Event{
+		Type:      eventType,
+		MindMapID: mindMapID,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// PublishEvent delivers an event to every subscriber of its mind map, on every instance
+// when a broker is configured. If the broker is unavailable, local subscribers still get it
+func (h *Hub) PublishEvent(event Event) {
+	if h.broker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+		defer cancel()
+		if err := h.broker.Publish(ctx, event); err != nil {
+			log.Printf("[Realtime] Error publishing %s event for map %s through broker: %v", event.Type, event.MindMapID, err)
+			h.deliver(event)
+		}
+		return
+	}
+
+	h.deliver(event)
+}
+
+// deliver hands an event to this instance's subscribers. Subscribers that
+// are too far behind miss the event rather than blocking the publisher
+func (h *Hub) deliver(event Event) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for ch := range h.subscribers[event.MindMapID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("[Realtime] Dropping %s event for slow subscriber on map %s", event.Type, event.MindMapID)
+		}
+	}
+}
+
+// syncBrokerSubscription subscribes to a map's broker channel while it has local
+// subscribers and unsubscribes once the last one leaves
+func (h *Hub) syncBrokerSubscription(mindMapID string) {
+	if h.broker == nil {
+		return
+	}
+
+	h.brokerMutex.Lock()
+	defer h.brokerMutex.Unlock()
+
+	h.mutex.RLock()
+	wanted := len(h.subscribers[mindMapID]) > 0
+	h.mutex.RUnlock()
+	if wanted == h.brokerSubscribed[mindMapID] {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), brokerTimeout)
+	defer cancel()
+
+	if wanted {
+		if err := h.broker.Subscribe(ctx, mindMapID); err != nil {
+			log.Printf("[Realtime] Error subscribing to map %s through broker: %v", mindMapID, err)
+			return
+		}
+		h.brokerSubscribed[mindMapID] = true
+		return
+	}
+
+	if err := h.broker.Unsubscribe(ctx, mindMapID); err != nil {
+		log.Printf("[Realtime] Error unsubscribing from map %s through broker: %v", mindMapID, err)
+		return
+	}
+	delete(h.brokerSubscribed, mindMapID)
+}
+
+// Close shuts down the hub's broker, if any
+func (h *Hub) Close() error {
+	if h.broker == nil {
+		return nil
+	}
+	return h.broker.Close()
+}
@@ -0,0 +1,106 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChannelPrefix namespaces the Redis pub/sub channels, one per mind map
+const redisChannelPrefix = "realtime:mindmap:"
+
+// redisEventBuffer is how many received events may queue up before the hub picks them up
+const redisEventBuffer = 256
+
+// redisEvent is the wire format of an event on Redis. The payload stays raw so it is
+// forwarded to clients exactly as the publishing instance encoded it
+type redisEvent struct {
+	Type      string          `json:"type"`
+	MindMapID string          `json:"mind_map_id"`
+	Seq       int64           `json:"seq,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// RedisBroker relays events between server instances over Redis pub/sub
+type RedisBroker struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	events chan Event
+}
+
+// NewRedisBroker creates a new RedisBroker using the given client
+func NewRedisBroker(client *redis.Client) *RedisBroker {
+	b := &RedisBroker{
+		client: client,
+		pubsub: client.Subscribe(context.Background()),
+		events: make(chan Event, redisEventBuffer),
+	}
+	go b.receive()
+	return b
+}
+
+// Publish sends an event to every instance subscribed to its mind map
+func (b *RedisBroker) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(redisEvent{
+		Type:      event.Type,
+		MindMapID: event.MindMapID,
+		Seq:       event.Seq,
+		Payload:   payload,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, redisChannelPrefix+event.MindMapID, data).Err()
+}
+
+// Subscribe starts receiving events for a mind map
+func (b *RedisBroker) Subscribe(ctx context.Context, mindMapID string) error {
+	return b.pubsub.Subscribe(ctx, redisChannelPrefix+mindMapID)
+}
+
+// Unsubscribe stops receiving events for a mind map
+func (b *RedisBroker) Unsubscribe(ctx context.Context, mindMapID string) error {
+	return b.pubsub.Unsubscribe(ctx, redisChannelPrefix+mindMapID)
+}
+
+// Events returns the events received from Redis
+func (b *RedisBroker) Events() <-chan Event {
+	return b.events
+}
+
+// Close stops receiving events
+func (b *RedisBroker) Close() error {
+	return b.pubsub.Close()
+}
+
+// receive decodes messages from Redis until the subscription is closed
+func (b *RedisBroker) receive() {
+	defer close(b.events)
+	for msg := range b.pubsub.Channel() {
+		var event redisEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			log.Printf("[Realtime] Error decoding event from %s: %v", msg.Channel, err)
+			continue
+		}
+		if event.MindMapID == "" {
+			event.MindMapID = strings.TrimPrefix(msg.Channel, redisChannelPrefix)
+		}
+		b.events <- Event{
+			Type:      event.Type,
+			MindMapID: event.MindMapID,
+			Seq:       event.Seq,
+			Payload:   event.Payload,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+}
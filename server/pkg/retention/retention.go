@@ -0,0 +1,87 @@
+// Package retention applies the retention policies of tenants: purging their trash,
+// archiving inactive mind maps and trimming mind map history
+package retention
+
+import (
+	"log"
+	"time"
+
+	"saas-server/database"
+	"saas-server/models"
+)
+
+// runInterval is how often the retention policies are applied
+const runInterval = time.Hour
+
+// day is the length of a day in retention rules
+const day = 24 * time.Hour
+
+// Service applies the retention policies in the background
+type Service struct {
+	db *database.DB
+}
+
+// NewService creates a new instance of Service
+func NewService(db *database.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// StartJob starts the hourly job that applies every tenant's retention policy, running it
+// once right away
+func (s *Service) StartJob() {
+	go func() {
+		ticker := time.NewTicker(runInterval)
+		defer ticker.Stop()
+		for {
+			s.applyPolicies()
+			<-ticker.C
+		}
+	}()
+}
+
+// applyPolicies applies each retention policy that has a rule on, logging the outcome
+func (s *Service) applyPolicies() {
+	policies, err := s.db.GetRetentionPolicies()
+	if err != nil {
+		log.Printf("[Retention] Error getting retention policies: %v", err)
+		return
+	}
+	for _, policy := range policies {
+		s.applyPolicy(policy)
+	}
+}
+
+// applyPolicy applies the rules of one tenant's retention policy. A failing rule is logged
+// and does not keep the others from running
+func (s *Service) applyPolicy(policy models.RetentionPolicy) {
+	now := time.Now()
+
+	if policy.PurgeTrashAfterDays != nil {
+		purged, err := s.db.PurgeDeletedMindMaps(policy.TenantID, now.Add(-time.Duration(*policy.PurgeTrashAfterDays)*day))
+		if err != nil {
+			log.Printf("[Retention] Error purging trash of tenant %s: %v", policy.TenantID, err)
+		} else if purged > 0 {
+			log.Printf("[Retention] Purged %d deleted mind maps of tenant %s", purged, policy.TenantID)
+		}
+	}
+
+	if policy.ArchiveAfterDays != nil {
+		archived, err := s.db.ArchiveInactiveMindMaps(policy.TenantID, now.Add(-time.Duration(*policy.ArchiveAfterDays)*day))
+		if err != nil {
+			log.Printf("[Retention] Error archiving mind maps of tenant %s: %v", policy.TenantID, err)
+		} else if archived > 0 {
+			log.Printf("[Retention] Archived %d inactive mind maps of tenant %s", archived, policy.TenantID)
+		}
+	}
+
+	if policy.HistoryDepth != nil {
+		trimmed, err := s.db.TrimMindMapChanges(policy.TenantID, *policy.HistoryDepth)
+		if err != nil {
+			log.Printf("[Retention] Error trimming history of tenant %s: %v", policy.TenantID, err)
+		} else if trimmed > 0 {
+			log.Printf("[Retention] Trimmed %d history entries of tenant %s", trimmed, policy.TenantID)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Package saml implements the parts of SAML 2.0 a service provider needs to sign users in
+// with an identity provider: reading the provider's metadata, building the redirect
+// authentication request and verifying the signed assertion the provider posts back. The
+// XML and signature handling is crewjam/saml's
+package saml
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// maxMetadataSize caps the identity provider metadata that is downloaded
+const maxMetadataSize = 1 << 20
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Attribute names identity providers commonly send a user's email and name under
+var (
+	emailAttributes = []string{
+		"email",
+		"mail",
+		"emailaddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+		"urn:oid:1.2.840.113549.1.9.1",
+	}
+	nameAttributes = []string{
+		"name",
+		"displayname",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+		"cn",
+		"urn:oid:2.5.4.3",
+	}
+)
+
+// Config is this server as a service provider: the entity ID identity providers know it
+// by, the URL they post assertions to, and optionally the key and certificate it signs
+// requests and decrypts encrypted assertions with
+type Config struct {
+	EntityID    string
+	ACSURL      url.URL
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// ConfigFromEnv reads the service provider from SAML_ACS_URL, SAML_SP_ENTITY_ID and the
+// PEM files SAML_SP_CERT_FILE and SAML_SP_KEY_FILE. The entity ID defaults to the URL of
+// the metadata next to the assertion consumer service. Returns nil without SAML_ACS_URL,
+// which leaves SAML sign-in off
+func ConfigFromEnv() (*Config, error) {
+	acs := os.Getenv("SAML_ACS_URL")
+	if acs == "" {
+		return nil, nil
+	}
+	acsURL, err := url.Parse(acs)
+	if err != nil || !acsURL.IsAbs() {
+		return nil, fmt.Errorf("SAML_ACS_URL must be an absolute URL")
+	}
+
+	config := &Config{
+		EntityID: os.Getenv("SAML_SP_ENTITY_ID"),
+		ACSURL:   *acsURL,
+	}
+	if config.EntityID == "" {
+		config.EntityID = acsURL.ResolveReference(&url.URL{Path: "metadata"}).String()
+	}
+
+	certFile, keyFile := os.Getenv("SAML_SP_CERT_FILE"), os.Getenv("SAML_SP_KEY_FILE")
+	if certFile == "" && keyFile == "" {
+		return config, nil
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading SAML service provider key: %w", err)
+	}
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("SAML service provider key must be an RSA key")
+	}
+	if config.Certificate, err = x509.ParseCertificate(pair.Certificate[0]); err != nil {
+		return nil, fmt.Errorf("parsing SAML service provider certificate: %w", err)
+	}
+	config.Key = key
+	return config, nil
+}
+
+// Metadata returns the service provider metadata to register at identity providers. Only
+// the HTTP-POST binding of the assertion consumer service is listed
+func (c *Config) Metadata() ([]byte, error) {
+	metadata := c.serviceProvider(nil).Metadata()
+	for i := range metadata.SPSSODescriptors {
+		descriptor := &metadata.SPSSODescriptors[i]
+		var services []saml.IndexedEndpoint
+		for _, service := range descriptor.AssertionConsumerServices {
+			if service.Binding == saml.HTTPPostBinding {
+				services = append(services, service)
+			}
+		}
+		descriptor.AssertionConsumerServices = services
+	}
+	return xml.MarshalIndent(metadata, "", "  ")
+}
+
+// FetchMetadata downloads an identity provider's metadata and checks it as ParseMetadata
+// does
+func FetchMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: status %d", metadataURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMetadataSize {
+		return nil, fmt.Errorf("metadata at %s is larger than %d bytes", metadataURL, maxMetadataSize)
+	}
+	if _, err := ParseMetadata(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ParseMetadata parses an identity provider's metadata, which must have an entity ID, a
+// signing certificate and a single sign-on endpoint with the HTTP-Redirect binding
+func ParseMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	if err := xrv.Validate(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	var metadata saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if metadata.EntityID == "" {
+		return nil, errors.New("metadata has no entity ID")
+	}
+
+	sp := saml.ServiceProvider{IDPMetadata: &metadata}
+	if sp.GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+		return nil, errors.New("metadata has no single sign-on endpoint with the HTTP-Redirect binding")
+	}
+	hasCertificate := false
+	for _, descriptor := range metadata.IDPSSODescriptors {
+		for _, key := range descriptor.KeyDescriptors {
+			if key.Use != "encryption" && len(key.KeyInfo.X509Data.X509Certificates) > 0 {
+				hasCertificate = true
+			}
+		}
+	}
+	if !hasCertificate {
+		return nil, errors.New("metadata has no signing certificate")
+	}
+	return &metadata, nil
+}
+
+// serviceProvider returns the service provider as crewjam/saml configures it for the
+// identity provider with the given metadata
+func (c *Config) serviceProvider(idp *saml.EntityDescriptor) *saml.ServiceProvider {
+	sp := &saml.ServiceProvider{
+		EntityID:          c.EntityID,
+		Key:               c.Key,
+		Certificate:       c.Certificate,
+		AcsURL:            c.ACSURL,
+		IDPMetadata:       idp,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		HTTPClient:        httpClient,
+	}
+	if c.Key != nil {
+		sp.SignatureMethod = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	}
+	return sp
+}
+
+// AuthenticationURL returns the URL to send the user to for signing in at the identity
+// provider with the given metadata, and the ID of the request its response must answer.
+// The relay state comes back with the response and must be URL-safe
+func (c *Config) AuthenticationURL(metadata []byte, relayState string) (string, string, error) {
+	idp, err := ParseMetadata(metadata)
+	if err != nil {
+		return "", "", err
+	}
+	sp := c.serviceProvider(idp)
+	req, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+	redirect, err := req.Redirect(relayState, sp)
+	if err != nil {
+		return "", "", err
+	}
+	return redirect.String(), req.ID, nil
+}
+
+// ParseResponse verifies the response an identity provider posted to the assertion
+// consumer service: its signature against the provider's metadata, that it answers
+// requestID, and its audience, recipient and validity period. Artifact responses are not
+// supported
+func (c *Config) ParseResponse(r *http.Request, metadata []byte, requestID string) (*Assertion, error) {
+	idp, err := ParseMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	if r.Form.Get("SAMLart") != "" || r.PostForm.Get("SAMLResponse") == "" {
+		return nil, errors.New("no SAML response was posted")
+	}
+
+	assertion, err := c.serviceProvider(idp).ParseResponse(r, []string{requestID})
+	var invalid *saml.InvalidResponseError
+	if errors.As(err, &invalid) {
+		return nil, fmt.Errorf("invalid SAML response: %w", invalid.PrivateErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, errors.New("assertion has no subject")
+	}
+
+	result := &Assertion{
+		Subject:      assertion.Subject.NameID.Value,
+		NameIDFormat: assertion.Subject.NameID.Format,
+		Attributes:   make(map[string][]string),
+	}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			for _, name := range []string{attribute.Name, attribute.FriendlyName} {
+				if name == "" {
+					continue
+				}
+				name = strings.ToLower(name)
+				for _, value := range attribute.Values {
+					result.Attributes[name] = append(result.Attributes[name], value.Value)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// Assertion is the identity a verified assertion carries: the subject's name ID and the
+// attributes, by name and friendly name, lowercased
+type Assertion struct {
+	Subject      string
+	NameIDFormat string
+	Attributes   map[string][]string
+}
+
+// String returns the first value of the first of the named attributes the assertion has,
+// or an empty string if it has none
+func (a *Assertion) String(names ...string) string {
+	for _, name := range names {
+		for _, value := range a.Attributes[strings.ToLower(name)] {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// Strings returns the values of an attribute, such as the user's groups
+func (a *Assertion) Strings(name string) []string {
+	return a.Attributes[strings.ToLower(name)]
+}
+
+// Email returns the user's email from the common email attributes, falling back to the
+// name ID when its format is an email address
+func (a *Assertion) Email() string {
+	if email := a.String(emailAttributes...); email != "" {
+		return email
+	}
+	if a.NameIDFormat == string(saml.EmailAddressNameIDFormat) {
+		return a.Subject
+	}
+	return ""
+}
+
+// Name returns the user's display name from the common name attributes
+func (a *Assertion) Name() string {
+	return a.String(nameAttributes...)
+}
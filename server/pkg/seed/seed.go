@@ -0,0 +1,176 @@
+// Package seed generates realistic mind maps of any size as importable documents, for demo
+// data and for measuring performance against large maps. Output is deterministic for a
+// given random source, so runs can be compared
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"saas-server/models"
+	"strings"
+	"time"
+)
+
+// Options controls the shape of a generated map
+type Options struct {
+	Title string
+	Nodes int // Total nodes including the root
+	// MaxChildren caps how many children a node gets before the next level is filled
+	MaxChildren int
+	// LinkRatio is the share of nodes that also get a cross-link edge to another branch
+	LinkRatio float64
+}
+
+// DefaultOptions returns options for a map of the given size with a typical fan-out
+func DefaultOptions(title string, nodes int) Options {
+	return Options{Title: title, Nodes: nodes, MaxChildren: 8, LinkRatio: 0.02}
+}
+
+// Words the generated ideas are built from
+var (
+	topics = []string{
+		"Product launch", "Customer onboarding", "Q3 roadmap", "Hiring plan", "Marketing campaign",
+		"Platform migration", "Community growth", "Pricing strategy", "Mobile app", "Support workflow",
+	}
+	verbs = []string{
+		"Improve", "Automate", "Measure", "Simplify", "Test", "Launch", "Review", "Document",
+		"Prototype", "Interview", "Localize", "Refactor", "Prioritize", "Benchmark", "Share",
+	}
+	subjects = []string{
+		"signup flow", "pricing page", "email sequence", "search results", "dashboard", "API docs",
+		"release notes", "referral program", "churn survey", "onboarding checklist", "billing emails",
+		"feature flags", "support macros", "landing page", "analytics events", "partner program",
+	}
+	qualifiers = []string{
+		"for new users", "before the launch", "with the design team", "across regions", "on mobile",
+		"for enterprise accounts", "using last quarter's data", "in the next sprint", "for power users",
+		"with a small pilot", "", "", "",
+	}
+	icons      = []string{"", "", "", "", "💡", "🚀", "✅", "🔥", "📌", "❓"}
+	priorities = []string{"", "", "", "", models.NodePriorityLow, models.NodePriorityMedium, models.NodePriorityHigh, models.NodePriorityUrgent}
+	tags       = []string{"research", "design", "engineering", "marketing", "sales", "support", "blocked", "quick-win"}
+)
+
+// Document generates a map as a document ready for import. Nodes are laid out as a radial
+// tree filled breadth first, each parent linked to its children by an edge, with a few
+// extra edges between branches
+func Document(rng *rand.Rand, opts Options) models.MindMapDocument {
+	if opts.Nodes < 1 {
+		opts.Nodes = 1
+	}
+	if opts.MaxChildren < 1 {
+		opts.MaxChildren = 1
+	}
+	if opts.Title == "" {
+		opts.Title = topics[rng.Intn(len(topics))]
+	}
+	now := time.Now().UTC()
+
+	doc := models.MindMapDocument{
+		FormatVersion: 1,
+		ExportedAt:    now,
+		MindMap: models.DocumentMindMap{
+			Title:       opts.Title,
+			Description: fmt.Sprintf("Generated map with %d nodes", opts.Nodes),
+		},
+	}
+
+	type placed struct {
+		id          string
+		depth       int
+		angle, span float64
+	}
+	root := placed{id: "n1", span: 2 * math.Pi}
+	doc.Nodes = append(doc.Nodes, models.DocumentNode{
+		ID:        root.id,
+		Content:   opts.Title,
+		NodeType:  "root",
+		StyleData: json.RawMessage(`{}`),
+		Metadata:  json.RawMessage(`{}`),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	// Fill the tree breadth first, giving each parent a random number of children
+	queue := []placed{root}
+	for len(doc.Nodes) < opts.Nodes && len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		children := 1 + rng.Intn(opts.MaxChildren)
+		children = min(children, opts.Nodes-len(doc.Nodes))
+		for i := 0; i < children; i++ {
+			child := placed{
+				id:    fmt.Sprintf("n%d", len(doc.Nodes)+1),
+				depth: parent.depth + 1,
+				span:  parent.span / float64(children),
+			}
+			child.angle = parent.angle - parent.span/2 + child.span*(float64(i)+0.5)
+			radius := 250 * float64(child.depth)
+			created := now.Add(-time.Duration(rng.Intn(30*24*60)) * time.Minute)
+
+			parentID := parent.id
+			doc.Nodes = append(doc.Nodes, models.DocumentNode{
+				ID:        child.id,
+				ParentID:  &parentID,
+				Content:   Idea(rng),
+				PositionX: math.Round(radius * math.Cos(child.angle)),
+				PositionY: math.Round(radius * math.Sin(child.angle)),
+				NodeType:  "idea",
+				StyleData: json.RawMessage(`{}`),
+				Metadata:  metadata(rng),
+				Icon:      icons[rng.Intn(len(icons))],
+				Priority:  priorities[rng.Intn(len(priorities))],
+				CreatedAt: created,
+				UpdatedAt: created,
+			})
+			doc.Edges = append(doc.Edges, models.DocumentEdge{
+				ID:        fmt.Sprintf("e%d", len(doc.Edges)+1),
+				SourceID:  parent.id,
+				TargetID:  child.id,
+				EdgeType:  "default",
+				StyleData: json.RawMessage(`{}`),
+				CreatedAt: created,
+			})
+			queue = append(queue, child)
+		}
+	}
+
+	// Cross-link a few nodes to others anywhere in the map
+	if len(doc.Nodes) > 2 {
+		links := int(float64(len(doc.Nodes)) * opts.LinkRatio)
+		for i := 0; i < links; i++ {
+			source := doc.Nodes[1+rng.Intn(len(doc.Nodes)-1)]
+			target := doc.Nodes[1+rng.Intn(len(doc.Nodes)-1)]
+			if source.ID == target.ID {
+				continue
+			}
+			doc.Edges = append(doc.Edges, models.DocumentEdge{
+				ID:        fmt.Sprintf("e%d", len(doc.Edges)+1),
+				SourceID:  source.ID,
+				TargetID:  target.ID,
+				EdgeType:  "default",
+				StyleData: json.RawMessage(`{}`),
+				CreatedAt: now,
+			})
+		}
+	}
+
+	return doc
+}
+
+// Idea returns a random short idea, such as "Automate billing emails for power users"
+func Idea(rng *rand.Rand) string {
+	parts := []string{verbs[rng.Intn(len(verbs))], subjects[rng.Intn(len(subjects))], qualifiers[rng.Intn(len(qualifiers))]}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// metadata returns node metadata with a random tag now and then
+func metadata(rng *rand.Rand) json.RawMessage {
+	if rng.Intn(4) != 0 {
+		return json.RawMessage(`{}`)
+	}
+	data, _ := json.Marshal(map[string]interface{}{"tags": []string{tags[rng.Intn(len(tags))]}})
+	return data
+}
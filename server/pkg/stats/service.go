@@ -0,0 +1,51 @@
+// Package stats records daily snapshots of mind map sizes for growth charts
+package stats
+
+import (
+	"log"
+	"time"
+
+	"saas-server/database"
+)
+
+// runAfterMidnight is how long after midnight UTC the nightly snapshot runs
+const runAfterMidnight = 5 * time.Minute
+
+// Service records the daily mind map statistics in the background
+type Service struct {
+	db *database.DB
+}
+
+// NewService creates a new instance of Service
+func NewService(db *database.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// StartJob starts the nightly job that snapshots every mind map's size for the day that
+// just ended. On start it also fills in yesterday, with the current counts, in case the
+// server was down at midnight
+func (s *Service) StartJob() {
+	go func() {
+		s.recordDay(time.Now().UTC().AddDate(0, 0, -1))
+		for {
+			now := time.Now().UTC()
+			next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1).Add(runAfterMidnight)
+			time.Sleep(next.Sub(now))
+			s.recordDay(next.AddDate(0, 0, -1))
+		}
+	}()
+}
+
+// recordDay stores the snapshot for a day, logging the outcome
+func (s *Service) recordDay(day time.Time) {
+	recorded, err := s.db.RecordDailyStats(day)
+	if err != nil {
+		log.Printf("[Stats] Error recording daily stats for %s: %v", day.Format("2006-01-02"), err)
+		return
+	}
+	if recorded > 0 {
+		log.Printf("[Stats] Recorded daily stats of %d mind maps for %s", recorded, day.Format("2006-01-02"))
+	}
+}
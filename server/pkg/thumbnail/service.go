@@ -0,0 +1,82 @@
+package thumbnail
+
+import (
+	"log"
+	"time"
+
+	"saas-server/database"
+	"saas-server/models"
+)
+
+// Job settings: how often stale previews are looked for, how long a map must go without
+// changes before it is rendered, and how many maps are rendered per run
+const (
+	jobInterval = time.Minute
+	quietPeriod = 30 * time.Second
+	batchSize   = 50
+)
+
+// Generate renders the current state of a mind map and stores the preview
+func Generate(db *database.DB, mindMapID string) (*models.MindMapThumbnail, error) {
+	// Read the sequence number first so changes made while rendering mark the preview stale
+	seq, err := db.GetLatestChangeSeq(mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := db.GetNodesByMindMapID(mindMapID)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := db.GetEdgesByMindMapID(mindMapID)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail := &models.MindMapThumbnail{
+		MindMapID: mindMapID,
+		SVG:       Render(nodes, edges),
+		ChangeSeq: seq,
+	}
+	if err := db.SaveThumbnail(thumbnail); err != nil {
+		return nil, err
+	}
+	return thumbnail, nil
+}
+
+// Service keeps mind map previews up to date in the background
+type Service struct {
+	db *database.DB
+}
+
+// NewService creates a new instance of Service
+func NewService(db *database.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// StartJob starts the background job that re-renders previews of changed mind maps
+func (s *Service) StartJob() {
+	ticker := time.NewTicker(jobInterval)
+	go func() {
+		for range ticker.C {
+			if err := s.refreshStale(); err != nil {
+				log.Printf("[Thumbnail] Error refreshing thumbnails: %v", err)
+			}
+		}
+	}()
+}
+
+// refreshStale renders the previews of maps that changed since they were last rendered
+func (s *Service) refreshStale() error {
+	ids, err := s.db.GetStaleThumbnailMapIDs(quietPeriod, batchSize)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := Generate(s.db, id); err != nil {
+			log.Printf("[Thumbnail] Error rendering thumbnail for map %s: %v", id, err)
+		}
+	}
+	return nil
+}
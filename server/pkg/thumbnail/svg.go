@@ -0,0 +1,108 @@
+// Package thumbnail renders small previews of mind maps for the dashboard
+package thumbnail
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"saas-server/models"
+)
+
+// Preview dimensions and the assumed size of a node on the canvas
+const (
+	Width      = 320
+	Height     = 200
+	padding    = 12
+	nodeWidth  = 150
+	nodeHeight = 50
+)
+
+// Preview colors
+const (
+	backgroundColor = "#f8fafc"
+	edgeColor       = "#94a3b8"
+	nodeColor       = "#e2e8f0"
+	rootColor       = "#6366f1"
+	critiqueColor   = "#fecaca"
+)
+
+// hexColor matches the CSS hex colors accepted from a node's style data
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Render draws the nodes and edges of a mind map as an SVG document, scaled
+// to fit the preview. Node text is left out since it would not be legible
+func Render(nodes []models.Node, edges []models.Edge) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, Width, Height, Width, Height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, Width, Height, backgroundColor)
+
+	if len(nodes) > 0 {
+		// Fit the bounding box of all nodes into the preview, keeping the aspect ratio
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, node := range nodes {
+			minX = math.Min(minX, node.PositionX)
+			minY = math.Min(minY, node.PositionY)
+			maxX = math.Max(maxX, node.PositionX+nodeWidth)
+			maxY = math.Max(maxY, node.PositionY+nodeHeight)
+		}
+		scale := math.Min((Width-2*padding)/(maxX-minX), (Height-2*padding)/(maxY-minY))
+		scale = math.Min(scale, 1)
+		offsetX := (Width - (maxX-minX)*scale) / 2
+		offsetY := (Height - (maxY-minY)*scale) / 2
+		project := func(x, y float64) (float64, float64) {
+			return offsetX + (x-minX)*scale, offsetY + (y-minY)*scale
+		}
+
+		centers := make(map[string][2]float64, len(nodes))
+		for _, node := range nodes {
+			x, y := project(node.PositionX+nodeWidth/2, node.PositionY+nodeHeight/2)
+			centers[node.ID] = [2]float64{x, y}
+		}
+
+		// Edges go first so nodes are drawn on top of them
+		for _, edge := range edges {
+			source, ok := centers[edge.SourceID]
+			if !ok {
+				continue
+			}
+			target, ok := centers[edge.TargetID]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="1"/>`,
+				source[0], source[1], target[0], target[1], edgeColor)
+		}
+
+		radius := math.Max(1, 6*scale)
+		for _, node := range nodes {
+			x, y := project(node.PositionX, node.PositionY)
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f" fill="%s"/>`,
+				x, y, nodeWidth*scale, nodeHeight*scale, radius, fillColor(node))
+		}
+	}
+
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// fillColor returns the background color set on a node, falling back to the default
+// colors. Only plain hex colors are used so style data cannot inject markup
+func fillColor(node models.Node) string {
+	var style struct {
+		BackgroundColor string `json:"backgroundColor"`
+	}
+	if len(node.StyleData) > 0 && json.Unmarshal(node.StyleData, &style) == nil && hexColor.MatchString(style.BackgroundColor) {
+		return style.BackgroundColor
+	}
+	if node.ParentID == nil {
+		return rootColor
+	}
+	if node.NodeType == models.NodeTypeCritique {
+		return critiqueColor
+	}
+	return nodeColor
+}
@@ -0,0 +1,87 @@
+// Package triage ranks where inbox captures could be filed among the branches of other maps
+package triage
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Candidate is a branch an inbox node could be filed under: a map's root or one of the
+// root's children
+type Candidate struct {
+	MindMapID    string
+	MindMapTitle string
+	NodeID       string
+	Content      string
+}
+
+// Text describes a candidate for embedding and prompting, prefixing the branch with its map
+func (c Candidate) Text() string {
+	content := strings.Join(strings.Fields(c.Content), " ")
+	if content == "" || strings.EqualFold(content, c.MindMapTitle) {
+		return c.MindMapTitle
+	}
+	return c.MindMapTitle + " › " + content
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 when either is empty or their
+// lengths differ
+func Cosine(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// WordSimilarity is the Jaccard similarity of the words of two texts, ignoring case and
+// words shorter than three letters. It stands in for embeddings when none are available
+func WordSimilarity(a, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// words returns the set of lower-cased words in text
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 3 {
+			set[word] = true
+		}
+	}
+	return set
+}
+
+// Top returns the indexes of the k highest scores, best first. Ties keep their original order
+func Top(scores []float64, k int) []int {
+	indexes := make([]int, len(scores))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool { return scores[indexes[a]] > scores[indexes[b]] })
+	if len(indexes) > k {
+		indexes = indexes[:k]
+	}
+	return indexes
+}
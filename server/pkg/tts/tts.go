@@ -0,0 +1,186 @@
+// Package tts synthesizes speech through a configurable text-to-speech provider
+package tts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Supported providers, chosen with TTS_PROVIDER
+const (
+	ProviderOpenAI     = "openai"
+	ProviderElevenLabs = "elevenlabs"
+)
+
+// Defaults used when TTS_MODEL or TTS_VOICE are not set
+const (
+	defaultOpenAIModel     = "tts-1"
+	defaultOpenAIVoice     = "alloy"
+	defaultElevenLabsModel = "eleven_multilingual_v2"
+	defaultElevenLabsVoice = "21m00Tcm4TlvDq8ikWAM"
+)
+
+// Provider turns text into MP3 audio
+type Provider interface {
+	// Synthesize returns MP3 audio of text, which must be at most MaxChars long
+	Synthesize(text string) ([]byte, error)
+	// MaxChars is the longest text accepted in one request
+	MaxChars() int
+}
+
+// httpClient is shared by the providers; synthesis of a long chunk can take a while
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// FromEnv returns the provider configured with TTS_PROVIDER, defaulting to OpenAI with the
+// given key. TTS_MODEL and TTS_VOICE override the provider's defaults. ElevenLabs reads its
+// key from ELEVENLABS_API_KEY. Returns an error when the provider is unknown or has no key
+func FromEnv(openAIKey string) (Provider, error) {
+	model, voice := os.Getenv("TTS_MODEL"), os.Getenv("TTS_VOICE")
+	switch provider := os.Getenv("TTS_PROVIDER"); provider {
+	case "", ProviderOpenAI:
+		if openAIKey == "" {
+			return nil, fmt.Errorf("text-to-speech needs an OpenAI API key")
+		}
+		return &openAI{apiKey: openAIKey, model: orDefault(model, defaultOpenAIModel), voice: orDefault(voice, defaultOpenAIVoice)}, nil
+	case ProviderElevenLabs:
+		apiKey := os.Getenv("ELEVENLABS_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("text-to-speech needs ELEVENLABS_API_KEY")
+		}
+		return &elevenLabs{apiKey: apiKey, model: orDefault(model, defaultElevenLabsModel), voice: orDefault(voice, defaultElevenLabsVoice)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported text-to-speech provider %q", provider)
+	}
+}
+
+// Speak synthesizes a script of any length, splitting it into chunks of whole lines that
+// fit the provider's limit and joining the MP3 streams
+func Speak(provider Provider, script string) ([]byte, error) {
+	var audio bytes.Buffer
+	for _, chunk := range chunks(script, provider.MaxChars()) {
+		part, err := provider.Synthesize(chunk)
+		if err != nil {
+			return nil, err
+		}
+		audio.Write(part)
+	}
+	return audio.Bytes(), nil
+}
+
+// chunks splits text at line breaks into pieces of at most limit bytes. A single line
+// longer than the limit is split at spaces, or cut when it has none
+func chunks(text string, limit int) []string {
+	var pieces []string
+	var current strings.Builder
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			pieces = append(pieces, current.String())
+		}
+		current.Reset()
+	}
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > limit {
+			cut := strings.LastIndex(line[:limit], " ")
+			if cut <= 0 {
+				cut = limit
+			}
+			flush()
+			pieces = append(pieces, line[:cut])
+			line = strings.TrimSpace(line[cut:])
+		}
+		if current.Len() > 0 && current.Len()+1+len(line) > limit {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(line)
+	}
+	flush()
+	return pieces
+}
+
+// openAI synthesizes speech with the OpenAI audio API
+type openAI struct {
+	apiKey, model, voice string
+}
+
+// MaxChars implements Provider
+func (p *openAI) MaxChars() int { return 4000 }
+
+// Synthesize implements Provider
+func (p *openAI) Synthesize(text string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"model":           p.model,
+		"voice":           p.voice,
+		"input":           text,
+		"response_format": "mp3",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", "https://api.openai.com/v1/audio/speech", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return do(req, "OpenAI")
+}
+
+// elevenLabs synthesizes speech with the ElevenLabs API
+type elevenLabs struct {
+	apiKey, model, voice string
+}
+
+// MaxChars implements Provider
+func (p *elevenLabs) MaxChars() int { return 2500 }
+
+// Synthesize implements Provider
+func (p *elevenLabs) Synthesize(text string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"text":     text,
+		"model_id": p.model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", "https://api.elevenlabs.io/v1/text-to-speech/"+url.PathEscape(p.voice), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "audio/mpeg")
+	req.Header.Set("xi-api-key", p.apiKey)
+	return do(req, "ElevenLabs")
+}
+
+// do sends a synthesis request and returns the audio in the response
+func do(req *http.Request, name string) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s API error: %s - %s", name, resp.Status, string(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// orDefault returns value, or fallback when it is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -3,8 +3,10 @@ package validation
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/microcosm-cc/bluemonday"
@@ -123,3 +125,60 @@ func ValidateToken(token string) bool {
 	uuidRegex := regexp.MustCompile(`^[0-9a-fA-F]{8}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{12}$`)
 	return uuidRegex.MatchString(token)
 }
+
+// ValidateEmoji validates that input is a single emoji, allowing the joiners,
+// variation selectors and skin tone modifiers that make up compound emoji
+func ValidateEmoji(input string) error {
+	count := utf8.RuneCountInString(input)
+	if count == 0 || count > 10 {
+		return fmt.Errorf("icon must be a single emoji")
+	}
+	for _, r := range input {
+		switch {
+		case unicode.Is(unicode.So, r): // Emoji and other symbols
+		case r == '\u200d', r == '\u20e3': // Zero width joiner and keycap
+		case r >= '\ufe00' && r <= '\ufe0f': // Variation selectors
+		case r >= 0x1f3fb && r <= 0x1f3ff: // Skin tone modifiers
+		case r >= 0xe0020 && r <= 0xe007f: // Tag characters used by subdivision flags
+		default:
+			return fmt.Errorf("icon must be a single emoji")
+		}
+	}
+	return nil
+}
+
+// ValidateImageURL validates an absolute https URL pointing at an image to embed
+func ValidateImageURL(input string) error {
+	if len(input) > 2048 {
+		return fmt.Errorf("image URL must be at most 2048 characters")
+	}
+	parsed, err := url.Parse(input)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil {
+		return fmt.Errorf("image URL must be an absolute https URL")
+	}
+	return nil
+}
+
+// ValidateSourceURL validates an absolute http or https URL of a page content was taken from
+func ValidateSourceURL(input string) error {
+	if len(input) > 2048 {
+		return fmt.Errorf("URL must be at most 2048 characters")
+	}
+	parsed, err := url.Parse(input)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.User != nil {
+		return fmt.Errorf("URL must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// languageTag matches a language code with an optional region or script, such as es,
+// pt-BR or zh-Hant
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,4})?$`)
+
+// ValidateLanguage validates a language tag to translate content into
+func ValidateLanguage(input string) error {
+	if !languageTag.MatchString(input) {
+		return fmt.Errorf("language must be a code such as es or pt-BR")
+	}
+	return nil
+}